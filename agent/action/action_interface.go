@@ -16,6 +16,17 @@ type Action interface {
 	//
 	// See Runner for more details
 
+	// Persistent actions that need arguments of the interrupted run
+	// may implement ResumeRun with the same arguments as Run instead
+	//
+	// ResumeRun(...) (interface{}, error)
+
 	Resume() (interface{}, error)
 	Cancel() error
 }
+
+// ProgressReporter is implemented by asynchronous actions
+// that report progress of their running tasks
+type ProgressReporter interface {
+	Progress() interface{}
+}
@@ -0,0 +1,53 @@
+package action
+
+import (
+	"errors"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// AddAliasAction assigns alias address to the interface of the network
+// so that HA jobs can take over a virtual IP on failover
+type AddAliasAction struct {
+	platform        boshplatform.Platform
+	settingsService boshsettings.Service
+}
+
+func NewAddAlias(
+	platform boshplatform.Platform,
+	settingsService boshsettings.Service,
+) AddAliasAction {
+	return AddAliasAction{
+		platform:        platform,
+		settingsService: settingsService,
+	}
+}
+
+func (a AddAliasAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a AddAliasAction) IsPersistent() bool {
+	return false
+}
+
+func (a AddAliasAction) Run(networkName, address string) (map[string]interface{}, error) {
+	networks := a.settingsService.GetSettings().Networks
+
+	err := a.platform.AddAlias(networks, networkName, address)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Adding alias '%s' of network '%s'", address, networkName)
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+func (a AddAliasAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a AddAliasAction) Cancel() error {
+	return errors.New("not supported")
+}
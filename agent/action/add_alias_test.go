@@ -0,0 +1,57 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("addAlias", func() {
+	var (
+		action          AddAliasAction
+		platform        *fakeplatform.FakePlatform
+		settingsService *fakesettings.FakeSettingsService
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		settingsService = &fakesettings.FakeSettingsService{}
+		action = NewAddAlias(platform, settingsService)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		It("adds alias of the network from current settings", func() {
+			settingsService.Settings.Networks = boshsettings.Networks{"fake-net": boshsettings.Network{IP: "10.0.0.6"}}
+
+			result, err := action.Run("fake-net", "10.0.0.100")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(map[string]interface{}{}))
+
+			Expect(platform.AddAliasNetworks).To(Equal(settingsService.Settings.Networks))
+			Expect(platform.AddAliasNetworkName).To(Equal("fake-net"))
+			Expect(platform.AddAliasAddress).To(Equal("10.0.0.100"))
+		})
+
+		It("returns error if adding alias fails", func() {
+			platform.AddAliasErr = errors.New("fake-alias-err")
+
+			_, err := action.Run("fake-net", "10.0.0.100")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Adding alias '10.0.0.100' of network 'fake-net': fake-alias-err"))
+		})
+	})
+})
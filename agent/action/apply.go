@@ -1,6 +1,7 @@
 package action
 
 import (
+	"encoding/json"
 	"errors"
 	"os"
 	"path"
@@ -99,6 +100,23 @@ func (a ApplyAction) writeInstanceData(spec boshas.V1ApplySpec) error {
 		return err
 	}
 
+	instanceInfo := spec.InstanceInfo()
+
+	instanceInfoJSON, err := json.Marshal(instanceInfo)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling instance info")
+	}
+
+	err = a.writeInstanceField(boshas.InstanceInfoFileName, string(instanceInfoJSON))
+	if err != nil {
+		return err
+	}
+
+	err = a.writeInstanceField(boshas.InstanceEnvFileName, instanceInfo.EnvFileContents())
+	if err != nil {
+		return err
+	}
+
 	err = a.fs.Chmod(a.instanceDir, userBaseDirPermissions)
 	if err != nil {
 		return err
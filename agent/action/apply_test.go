@@ -125,6 +125,29 @@ func init() {
 										Expect(err).ToNot(HaveOccurred())
 										Expect(deploymentName).To(Equal(desiredApplySpec.Deployment))
 									})
+
+									It("writes structured instance info and a sourceable env file to the instance directory", func() {
+										_, err := action.Run(desiredApplySpec)
+										Expect(err).ToNot(HaveOccurred())
+
+										instanceDir := dirProvider.InstanceDir()
+
+										instanceInfo, err := fs.ReadFileString(path.Join(instanceDir, "instance.json"))
+										Expect(err).ToNot(HaveOccurred())
+										Expect(instanceInfo).To(MatchJSON(`{
+											"id": "node-id01-123f-r2344",
+											"az": "ex-az",
+											"name": "instance-name",
+											"deployment": "deployment-name",
+											"index": null,
+											"tags": {}
+										}`))
+
+										env, err := fs.ReadFileString(path.Join(instanceDir, "env"))
+										Expect(err).ToNot(HaveOccurred())
+										Expect(env).To(ContainSubstring("export BOSH_INSTANCE_ID='node-id01-123f-r2344'\n"))
+										Expect(env).To(ContainSubstring("export BOSH_DEPLOYMENT='deployment-name'\n"))
+									})
 								})
 							})
 
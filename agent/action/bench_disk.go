@@ -0,0 +1,233 @@
+package action
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	benchDiskActionLogTag = "benchDiskAction"
+
+	benchDiskFileName      = ".bosh_bench_disk"
+	benchDiskSizeInMB      = 256
+	benchDiskRuntimeInSecs = 10
+)
+
+// ddSummaryRegexp matches dd's summary line, e.g.
+// "268435456 bytes (268 MB, 256 MiB) copied, 0.52 s, 516 MB/s"
+var ddSummaryRegexp = regexp.MustCompile(`(?m)^(\d+) bytes.* copied, ([\d.]+) s`)
+
+// BenchDiskAction runs a short, bounded benchmark against ephemeral or persistent
+// disk so that operators can validate IaaS storage before deploying stateful workloads.
+// fio is used when available; otherwise only dd throughput is measured.
+type BenchDiskAction struct {
+	platform    boshplatform.Platform
+	dirProvider boshdirs.Provider
+	cmdRunner   boshsys.CmdRunner
+	fs          boshsys.FileSystem
+	logger      boshlog.Logger
+}
+
+type DiskBenchmarkResult struct {
+	Tool                string `json:"tool"`
+	ReadThroughputKBps  uint64 `json:"read_throughput_kbps"`
+	WriteThroughputKBps uint64 `json:"write_throughput_kbps"`
+	ReadIOPS            uint64 `json:"read_iops,omitempty"`
+	WriteIOPS           uint64 `json:"write_iops,omitempty"`
+}
+
+type fioOutput struct {
+	Jobs []fioJob `json:"jobs"`
+}
+
+type fioJob struct {
+	Read  fioJobStats `json:"read"`
+	Write fioJobStats `json:"write"`
+}
+
+type fioJobStats struct {
+	BandwidthKBps uint64  `json:"bw"`
+	IOPS          float64 `json:"iops"`
+}
+
+func NewBenchDisk(
+	platform boshplatform.Platform,
+	dirProvider boshdirs.Provider,
+	logger boshlog.Logger,
+) BenchDiskAction {
+	return BenchDiskAction{
+		platform:    platform,
+		dirProvider: dirProvider,
+		cmdRunner:   platform.GetRunner(),
+		fs:          platform.GetFs(),
+		logger:      logger,
+	}
+}
+
+func (a BenchDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a BenchDiskAction) IsPersistent() bool {
+	return false
+}
+
+// Run benchmarks disk mounted at the data dir ("ephemeral") or the store dir ("persistent")
+func (a BenchDiskAction) Run(disk string) (DiskBenchmarkResult, error) {
+	var dir string
+
+	switch disk {
+	case "ephemeral":
+		dir = a.dirProvider.DataDir()
+	case "persistent":
+		dir = a.dirProvider.StoreDir()
+
+		_, isMountPoint, err := a.platform.IsMountPoint(dir)
+		if err != nil {
+			return DiskBenchmarkResult{}, bosherr.WrapError(err, "Checking for persistent disk mount point")
+		}
+
+		if !isMountPoint {
+			return DiskBenchmarkResult{}, bosherr.Error("Persistent disk is not mounted")
+		}
+	default:
+		return DiskBenchmarkResult{}, bosherr.Errorf("Unknown disk '%s', expected ephemeral or persistent", disk)
+	}
+
+	benchFilePath := path.Join(dir, benchDiskFileName)
+
+	defer func() {
+		if err := a.fs.RemoveAll(benchFilePath); err != nil {
+			a.logger.Warn(benchDiskActionLogTag, "Failed to remove benchmark file %s: %s", benchFilePath, err.Error())
+		}
+	}()
+
+	if a.cmdRunner.CommandExists("fio") {
+		return a.runFio(benchFilePath)
+	}
+
+	return a.runDd(benchFilePath)
+}
+
+func (a BenchDiskAction) runFio(benchFilePath string) (DiskBenchmarkResult, error) {
+	result := DiskBenchmarkResult{Tool: "fio"}
+
+	// Throughput is measured with large sequential IO, IOPS with small random IO
+	seqStats, err := a.fio(benchFilePath, "rw", "1M")
+	if err != nil {
+		return result, bosherr.WrapError(err, "Measuring throughput")
+	}
+
+	result.ReadThroughputKBps = seqStats.Read.BandwidthKBps
+	result.WriteThroughputKBps = seqStats.Write.BandwidthKBps
+
+	randStats, err := a.fio(benchFilePath, "randrw", "4k")
+	if err != nil {
+		return result, bosherr.WrapError(err, "Measuring IOPS")
+	}
+
+	result.ReadIOPS = uint64(randStats.Read.IOPS)
+	result.WriteIOPS = uint64(randStats.Write.IOPS)
+
+	return result, nil
+}
+
+func (a BenchDiskAction) fio(benchFilePath, rw, blockSize string) (fioJob, error) {
+	stdout, _, _, err := a.cmdRunner.RunCommand(
+		"fio",
+		"--name=bench_disk",
+		"--filename="+benchFilePath,
+		fmt.Sprintf("--size=%dM", benchDiskSizeInMB),
+		fmt.Sprintf("--runtime=%d", benchDiskRuntimeInSecs),
+		"--time_based",
+		"--direct=1",
+		"--ioengine=libaio",
+		"--iodepth=32",
+		"--rw="+rw,
+		"--bs="+blockSize,
+		"--output-format=json",
+	)
+	if err != nil {
+		return fioJob{}, bosherr.WrapError(err, "Running fio")
+	}
+
+	var output fioOutput
+
+	err = json.Unmarshal([]byte(stdout), &output)
+	if err != nil {
+		return fioJob{}, bosherr.WrapError(err, "Parsing fio output")
+	}
+
+	if len(output.Jobs) == 0 {
+		return fioJob{}, bosherr.Error("Parsing fio output: no jobs found")
+	}
+
+	return output.Jobs[0], nil
+}
+
+func (a BenchDiskAction) runDd(benchFilePath string) (DiskBenchmarkResult, error) {
+	result := DiskBenchmarkResult{Tool: "dd"}
+
+	writeThroughput, err := a.dd("if=/dev/zero", "of="+benchFilePath, "oflag=direct", fmt.Sprintf("count=%d", benchDiskSizeInMB))
+	if err != nil {
+		return result, bosherr.WrapError(err, "Measuring write throughput")
+	}
+
+	result.WriteThroughputKBps = writeThroughput
+
+	readThroughput, err := a.dd("if="+benchFilePath, "of=/dev/null", "iflag=direct")
+	if err != nil {
+		return result, bosherr.WrapError(err, "Measuring read throughput")
+	}
+
+	result.ReadThroughputKBps = readThroughput
+
+	return result, nil
+}
+
+// dd returns throughput in KB/s parsed from summary that dd prints to stderr
+func (a BenchDiskAction) dd(args ...string) (uint64, error) {
+	_, stderr, _, err := a.cmdRunner.RunCommand("dd", append(args, "bs=1M")...)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Running dd")
+	}
+
+	matches := ddSummaryRegexp.FindStringSubmatch(stderr)
+	if matches == nil {
+		return 0, bosherr.Errorf("Parsing dd output '%s'", stderr)
+	}
+
+	bytes, err := strconv.ParseUint(matches[1], 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Parsing copied bytes")
+	}
+
+	seconds, err := strconv.ParseFloat(matches[2], 64)
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Parsing elapsed time")
+	}
+
+	if seconds <= 0 {
+		return 0, bosherr.Errorf("Parsing dd output '%s'", stderr)
+	}
+
+	return uint64(float64(bytes) / seconds / 1024), nil
+}
+
+func (a BenchDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a BenchDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,174 @@
+package action_test
+
+import (
+	"errors"
+
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+)
+
+var _ = Describe("BenchDiskAction", func() {
+	var (
+		platform *fakeplatform.FakePlatform
+		runner   *fakesys.FakeCmdRunner
+		fs       *fakesys.FakeFileSystem
+		action   BenchDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		runner = platform.Runner
+		fs = platform.Fs
+		action = NewBenchDisk(platform, boshdirs.NewProvider("/fake-base-dir"), boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Context("when fio is available", func() {
+		BeforeEach(func() {
+			runner.CommandExistsValue = true
+		})
+
+		addFioResults := func() {
+			runner.AddCmdResult(
+				"fio --name=bench_disk --filename=/fake-base-dir/data/.bosh_bench_disk --size=256M --runtime=10 --time_based --direct=1 --ioengine=libaio --iodepth=32 --rw=rw --bs=1M --output-format=json",
+				fakesys.FakeCmdResult{Stdout: `{"jobs":[{"read":{"bw":204800,"iops":200.0},"write":{"bw":102400,"iops":100.0}}]}`},
+			)
+			runner.AddCmdResult(
+				"fio --name=bench_disk --filename=/fake-base-dir/data/.bosh_bench_disk --size=256M --runtime=10 --time_based --direct=1 --ioengine=libaio --iodepth=32 --rw=randrw --bs=4k --output-format=json",
+				fakesys.FakeCmdResult{Stdout: `{"jobs":[{"read":{"bw":12000,"iops":3000.6},"write":{"bw":6000,"iops":1500.2}}]}`},
+			)
+		}
+
+		It("returns throughput of sequential IO and IOPS of random IO", func() {
+			addFioResults()
+
+			result, err := action.Run("ephemeral")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(DiskBenchmarkResult{
+				Tool:                "fio",
+				ReadThroughputKBps:  204800,
+				WriteThroughputKBps: 102400,
+				ReadIOPS:            3000,
+				WriteIOPS:           1500,
+			}))
+		})
+
+		It("removes benchmark file", func() {
+			addFioResults()
+			fs.WriteFileString("/fake-base-dir/data/.bosh_bench_disk", "")
+
+			_, err := action.Run("ephemeral")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fs.FileExists("/fake-base-dir/data/.bosh_bench_disk")).To(BeFalse())
+		})
+
+		It("returns error if fio output cannot be parsed", func() {
+			runner.AddCmdResult(
+				"fio --name=bench_disk --filename=/fake-base-dir/data/.bosh_bench_disk --size=256M --runtime=10 --time_based --direct=1 --ioengine=libaio --iodepth=32 --rw=rw --bs=1M --output-format=json",
+				fakesys.FakeCmdResult{Stdout: "fake-garbage"},
+			)
+
+			_, err := action.Run("ephemeral")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing fio output"))
+		})
+
+		It("returns error if fio fails", func() {
+			runner.AddCmdResult(
+				"fio --name=bench_disk --filename=/fake-base-dir/data/.bosh_bench_disk --size=256M --runtime=10 --time_based --direct=1 --ioengine=libaio --iodepth=32 --rw=rw --bs=1M --output-format=json",
+				fakesys.FakeCmdResult{Error: errors.New("fake-fio-err")},
+			)
+
+			_, err := action.Run("ephemeral")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-fio-err"))
+		})
+	})
+
+	Context("when fio is not available", func() {
+		BeforeEach(func() {
+			runner.CommandExistsValue = false
+		})
+
+		It("returns throughput measured with dd", func() {
+			runner.AddCmdResult(
+				"dd if=/dev/zero of=/fake-base-dir/data/.bosh_bench_disk oflag=direct count=256 bs=1M",
+				fakesys.FakeCmdResult{Stderr: "256+0 records in\n256+0 records out\n268435456 bytes (268 MB, 256 MiB) copied, 0.5 s, 537 MB/s\n"},
+			)
+			runner.AddCmdResult(
+				"dd if=/fake-base-dir/data/.bosh_bench_disk of=/dev/null iflag=direct bs=1M",
+				fakesys.FakeCmdResult{Stderr: "256+0 records in\n256+0 records out\n268435456 bytes (268 MB) copied, 0.25 s, 1.1 GB/s\n"},
+			)
+
+			result, err := action.Run("ephemeral")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(DiskBenchmarkResult{
+				Tool:                "dd",
+				ReadThroughputKBps:  1048576,
+				WriteThroughputKBps: 524288,
+			}))
+		})
+
+		It("returns error if dd output cannot be parsed", func() {
+			runner.AddCmdResult(
+				"dd if=/dev/zero of=/fake-base-dir/data/.bosh_bench_disk oflag=direct count=256 bs=1M",
+				fakesys.FakeCmdResult{Stderr: "fake-garbage"},
+			)
+
+			_, err := action.Run("ephemeral")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing dd output 'fake-garbage'"))
+		})
+	})
+
+	Context("when benchmarking persistent disk", func() {
+		BeforeEach(func() {
+			runner.AddCmdResult(
+				"dd if=/dev/zero of=/fake-base-dir/store/.bosh_bench_disk oflag=direct count=256 bs=1M",
+				fakesys.FakeCmdResult{Stderr: "268435456 bytes (268 MB, 256 MiB) copied, 1 s, 268 MB/s\n"},
+			)
+			runner.AddCmdResult(
+				"dd if=/fake-base-dir/store/.bosh_bench_disk of=/dev/null iflag=direct bs=1M",
+				fakesys.FakeCmdResult{Stderr: "268435456 bytes (268 MB, 256 MiB) copied, 1 s, 268 MB/s\n"},
+			)
+		})
+
+		It("benchmarks disk mounted at store dir", func() {
+			platform.IsMountPointResult = true
+
+			result, err := action.Run("persistent")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.WriteThroughputKBps).To(Equal(uint64(262144)))
+			Expect(platform.IsMountPointPath).To(Equal("/fake-base-dir/store"))
+		})
+
+		It("returns error if persistent disk is not mounted", func() {
+			platform.IsMountPointResult = false
+
+			_, err := action.Run("persistent")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Persistent disk is not mounted"))
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+
+	It("returns error for unknown disk", func() {
+		_, err := action.Run("fake-disk")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown disk 'fake-disk'"))
+	})
+})
@@ -3,6 +3,7 @@ package action
 import (
 	boshappl "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
@@ -10,6 +11,7 @@ import (
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -31,6 +33,8 @@ func NewFactory(
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
 	jobScriptProvider boshscript.JobScriptProvider,
+	vitalsHistory boshvitals.History,
+	bootProfile boshbootprofile.Profile,
 	logger boshlog.Logger,
 ) (factory Factory) {
 	compressor := platform.GetCompressor()
@@ -52,13 +56,20 @@ func NewFactory(
 			"fetch_logs":      NewFetchLogs(compressor, copier, blobstore, dirProvider),
 			"update_settings": NewUpdateSettings(certManager, logger),
 
+			// Post-incident investigation
+			"fetch_vitals_history": NewFetchVitalsHistory(vitalsHistory),
+
+			// Agent certificate re-issuance
+			"generate_csr":        NewGenerateCSR(platform.GetFs(), settingsService, dirProvider),
+			"install_certificate": NewInstallCertificate(platform.GetFs(), dirProvider),
+
 			// Job management
 			"prepare":    NewPrepare(applier),
 			"apply":      NewApply(applier, specService, settingsService, dirProvider.InstanceDir(), platform.GetFs()),
-			"start":      NewStart(jobSupervisor, applier, specService),
-			"stop":       NewStop(jobSupervisor),
+			"start":      NewStart(jobSupervisor, applier, specService, platform),
+			"stop":       NewStop(jobSupervisor, platform),
 			"drain":      NewDrain(notifier, specService, jobScriptProvider, jobSupervisor, logger),
-			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService),
+			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService, bootProfile),
 			"run_errand": NewRunErrand(specService, dirProvider.JobsDir(), platform.GetRunner(), logger),
 			"run_script": NewRunScript(jobScriptProvider, specService, logger),
 
@@ -68,8 +79,10 @@ func NewFactory(
 
 			// Disk management
 			"list_disk":    NewListDisk(settingsService, platform, logger),
-			"migrate_disk": NewMigrateDisk(platform, dirProvider),
+			"migrate_disk": NewMigrateDisk(settingsService, platform, dirProvider),
 			"mount_disk":   NewMountDisk(settingsService, platform, dirProvider, logger),
+			"resize_disk":  NewResizeDisk(settingsService, platform),
+			"bench_disk":   NewBenchDisk(platform, dirProvider, logger),
 			"unmount_disk": NewUnmountDisk(settingsService, platform),
 
 			// ARP cache management
@@ -77,8 +90,11 @@ func NewFactory(
 
 			// Networkingconcrete_factory_test.go
 			"prepare_network_change":     NewPrepareNetworkChange(platform.GetFs(), settingsService, NewAgentKiller()),
+			"preview_network_change":     NewPreviewNetworkChange(platform, settingsService),
 			"prepare_configure_networks": NewPrepareConfigureNetworks(platform, settingsService),
 			"configure_networks":         NewConfigureNetworks(NewAgentKiller()),
+			"add_alias":                  NewAddAlias(platform, settingsService),
+			"remove_alias":               NewRemoveAlias(platform, settingsService),
 		},
 	}
 	return
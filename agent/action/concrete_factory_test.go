@@ -7,6 +7,7 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	fakeappl "github.com/cloudfoundry/bosh-agent/agent/applier/fakes"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 
@@ -16,10 +17,12 @@ import (
 	fakenotif "github.com/cloudfoundry/bosh-agent/notification/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
+	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
 )
 
 //go:generate counterfeiter -o fakes/fake_clock.go ../../vendor/github.com/pivotal-golang/clock Clock
@@ -36,6 +39,8 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor     *fakejobsuper.FakeJobSupervisor
 		specService       *fakeas.FakeV1Service
 		jobScriptProvider boshscript.JobScriptProvider
+		vitalsHistory     *fakevitals.FakeHistory
+		bootProfile       boshbootprofile.Profile
 		factory           Factory
 		logger            boshlog.Logger
 	)
@@ -51,6 +56,8 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
 		jobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		vitalsHistory = &fakevitals.FakeHistory{}
+		bootProfile = boshbootprofile.NewProfile(clock.NewClock())
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
 		factory = NewFactory(
@@ -64,6 +71,8 @@ var _ = Describe("concreteFactory", func() {
 			jobSupervisor,
 			specService,
 			jobScriptProvider,
+			vitalsHistory,
+			bootProfile,
 			logger,
 		)
 	})
@@ -93,6 +102,12 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewFetchLogs(platform.GetCompressor(), platform.GetCopier(), blobstore, platform.GetDirProvider())))
 	})
 
+	It("fetch_vitals_history", func() {
+		action, err := factory.Create("fetch_vitals_history")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewFetchVitalsHistory(vitalsHistory)))
+	})
+
 	It("get_task", func() {
 		action, err := factory.Create("get_task")
 		Expect(err).ToNot(HaveOccurred())
@@ -109,7 +124,7 @@ var _ = Describe("concreteFactory", func() {
 		ntpService := boshntp.NewConcreteService(platform.GetFs(), platform.GetDirProvider())
 		action, err := factory.Create("get_state")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewGetState(settingsService, specService, jobSupervisor, platform.GetVitalsService(), ntpService)))
+		Expect(action).To(Equal(NewGetState(settingsService, specService, jobSupervisor, platform.GetVitalsService(), ntpService, bootProfile)))
 	})
 
 	It("list_disk", func() {
@@ -121,7 +136,7 @@ var _ = Describe("concreteFactory", func() {
 	It("migrate_disk", func() {
 		action, err := factory.Create("migrate_disk")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewMigrateDisk(platform, platform.GetDirProvider())))
+		Expect(action).To(Equal(NewMigrateDisk(settingsService, platform, platform.GetDirProvider())))
 	})
 
 	It("mount_disk", func() {
@@ -142,6 +157,36 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewPrepareNetworkChange(platform.GetFs(), settingsService, NewAgentKiller())))
 	})
 
+	It("preview_network_change", func() {
+		action, err := factory.Create("preview_network_change")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewPreviewNetworkChange(platform, settingsService)))
+	})
+
+	It("generate_csr", func() {
+		action, err := factory.Create("generate_csr")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewGenerateCSR(platform.GetFs(), settingsService, boshdir.NewProvider("/var/vcap"))))
+	})
+
+	It("install_certificate", func() {
+		action, err := factory.Create("install_certificate")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewInstallCertificate(platform.GetFs(), boshdir.NewProvider("/var/vcap"))))
+	})
+
+	It("add_alias", func() {
+		action, err := factory.Create("add_alias")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewAddAlias(platform, settingsService)))
+	})
+
+	It("remove_alias", func() {
+		action, err := factory.Create("remove_alias")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewRemoveAlias(platform, settingsService)))
+	})
+
 	It("prepare_configure_networks", func() {
 		action, err := factory.Create("prepare_configure_networks")
 		Expect(err).ToNot(HaveOccurred())
@@ -163,13 +208,25 @@ var _ = Describe("concreteFactory", func() {
 	It("start", func() {
 		action, err := factory.Create("start")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewStart(jobSupervisor, applier, specService)))
+		Expect(action).To(Equal(NewStart(jobSupervisor, applier, specService, platform)))
 	})
 
 	It("stop", func() {
 		action, err := factory.Create("stop")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewStop(jobSupervisor)))
+		Expect(action).To(Equal(NewStop(jobSupervisor, platform)))
+	})
+
+	It("resize_disk", func() {
+		action, err := factory.Create("resize_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewResizeDisk(settingsService, platform)))
+	})
+
+	It("bench_disk", func() {
+		action, err := factory.Create("bench_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewBenchDisk(platform, platform.GetDirProvider(), logger)))
 	})
 
 	It("unmount_disk", func() {
@@ -0,0 +1,42 @@
+package action
+
+import (
+	"errors"
+
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type FetchVitalsHistoryAction struct {
+	vitalsHistory boshvitals.History
+}
+
+func NewFetchVitalsHistory(vitalsHistory boshvitals.History) FetchVitalsHistoryAction {
+	return FetchVitalsHistoryAction{vitalsHistory: vitalsHistory}
+}
+
+func (a FetchVitalsHistoryAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a FetchVitalsHistoryAction) IsPersistent() bool {
+	return false
+}
+
+// Run returns vitals sampled at each heartbeat from oldest to newest
+func (a FetchVitalsHistoryAction) Run() ([]boshvitals.HistorySample, error) {
+	samples, err := a.vitalsHistory.Samples()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting vitals history")
+	}
+
+	return samples, nil
+}
+
+func (a FetchVitalsHistoryAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a FetchVitalsHistoryAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,51 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
+)
+
+var _ = Describe("FetchVitalsHistory", func() {
+	var (
+		vitalsHistory *fakevitals.FakeHistory
+		action        FetchVitalsHistoryAction
+	)
+
+	BeforeEach(func() {
+		vitalsHistory = &fakevitals.FakeHistory{}
+		action = NewFetchVitalsHistory(vitalsHistory)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	It("returns samples of vitals history", func() {
+		vitalsHistory.HistorySamples = []boshvitals.HistorySample{
+			{Timestamp: 100, Vitals: boshvitals.Vitals{Load: []string{"1", "2", "3"}}},
+			{Timestamp: 160, Vitals: boshvitals.Vitals{Load: []string{"4", "5", "6"}}},
+		}
+
+		samples, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(samples).To(Equal(vitalsHistory.HistorySamples))
+	})
+
+	It("returns an error when history cannot be read", func() {
+		vitalsHistory.SamplesErr = errors.New("fake-samples-err")
+
+		_, err := action.Run()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-samples-err"))
+	})
+})
@@ -0,0 +1,101 @@
+package action
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"net"
+	"path"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const pendingPrivateKeyFileName = "agent.key.pending"
+
+// PendingPrivateKeyPath keeps private key of requested certificate until it is installed
+func PendingPrivateKeyPath(dirProvider boshdirs.Provider) string {
+	return path.Join(dirProvider.BoshDir(), pendingPrivateKeyFileName)
+}
+
+// GenerateCSRAction creates new private key of agent certificate and returns
+// certificate signing request for it so that the director can re-issue short-lived agent certificates
+type GenerateCSRAction struct {
+	fs              boshsys.FileSystem
+	settingsService boshsettings.Service
+	dirProvider     boshdirs.Provider
+}
+
+func NewGenerateCSR(
+	fs boshsys.FileSystem,
+	settingsService boshsettings.Service,
+	dirProvider boshdirs.Provider,
+) GenerateCSRAction {
+	return GenerateCSRAction{
+		fs:              fs,
+		settingsService: settingsService,
+		dirProvider:     dirProvider,
+	}
+}
+
+func (a GenerateCSRAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a GenerateCSRAction) IsPersistent() bool {
+	return false
+}
+
+func (a GenerateCSRAction) Run() (map[string]interface{}, error) {
+	settings := a.settingsService.GetSettings()
+
+	if settings.Env.GetIdentityKey().DeviceBacked() {
+		return nil, bosherr.Error("Generating CSR is not supported for device-backed identity keys")
+	}
+
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Generating private key")
+	}
+
+	template := &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: settings.AgentID},
+	}
+
+	for _, ip := range settings.Networks.IPs() {
+		template.IPAddresses = append(template.IPAddresses, net.ParseIP(ip))
+	}
+
+	csrDER, err := x509.CreateCertificateRequest(rand.Reader, template, privateKey)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Creating certificate signing request")
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(privateKey)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Marshalling private key")
+	}
+
+	err = replacePrivateFile(a.fs, PendingPrivateKeyPath(a.dirProvider), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Writing pending private key")
+	}
+
+	csrPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: csrDER})
+
+	return map[string]interface{}{"csr": string(csrPEM)}, nil
+}
+
+func (a GenerateCSRAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a GenerateCSRAction) Cancel() error {
+	return errors.New("not supported")
+}
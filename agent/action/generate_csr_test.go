@@ -0,0 +1,84 @@
+package action_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("generateCSR", func() {
+	var (
+		action          GenerateCSRAction
+		fs              *fakesys.FakeFileSystem
+		settingsService *fakesettings.FakeSettingsService
+		dirProvider     boshdirs.Provider
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		settingsService = &fakesettings.FakeSettingsService{}
+		settingsService.Settings.AgentID = "fake-agent-id"
+		settingsService.Settings.Networks = boshsettings.Networks{"fake-net": boshsettings.Network{IP: "10.0.0.6"}}
+		dirProvider = boshdirs.NewProvider("/var/vcap")
+		fs.MkdirAll("/var/vcap/bosh", 0755)
+		action = NewGenerateCSR(fs, settingsService, dirProvider)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	It("returns CSR for agent id and IPs signed by new pending private key", func() {
+		result, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		block, _ := pem.Decode([]byte(result["csr"].(string)))
+		Expect(block.Type).To(Equal("CERTIFICATE REQUEST"))
+
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(csr.CheckSignature()).To(Succeed())
+		Expect(csr.Subject.CommonName).To(Equal("fake-agent-id"))
+		Expect(csr.IPAddresses).To(Equal([]net.IP{net.ParseIP("10.0.0.6").To4()}))
+
+		Expect(PendingPrivateKeyPath(dirProvider)).To(Equal("/var/vcap/bosh/agent.key.pending"))
+
+		keyStat := fs.GetFileTestStat("/var/vcap/bosh/agent.key.pending")
+		Expect(keyStat).ToNot(BeNil())
+		Expect(keyStat.FileMode).To(Equal(os.FileMode(0600)))
+		Expect(string(keyStat.Content)).To(ContainSubstring("EC PRIVATE KEY"))
+	})
+
+	It("creates pending private key readable only by the agent before writing to it", func() {
+		fs.WriteFileString("/var/vcap/bosh/agent.key.pending.new", "fake-leftover")
+
+		_, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.RenameOldPaths).To(Equal([]string{"/var/vcap/bosh/agent.key.pending.new"}))
+		Expect(fs.FileExists("/var/vcap/bosh/agent.key.pending.new")).To(BeFalse())
+		Expect(fs.GetFileTestStat("/var/vcap/bosh/agent.key.pending").FileMode).To(Equal(os.FileMode(0600)))
+	})
+
+	It("returns an error when identity key is device-backed", func() {
+		settingsService.Settings.Env.Bosh.IdentityKey = boshsettings.IdentityKey{Type: "tpm"}
+
+		_, err := action.Run()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Generating CSR is not supported for device-backed identity keys"))
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"errors"
 
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -17,6 +18,7 @@ type GetStateAction struct {
 	jobSupervisor   boshjobsuper.JobSupervisor
 	vitalsService   boshvitals.Service
 	ntpService      boshntp.Service
+	bootProfile     boshbootprofile.Profile
 }
 
 func NewGetState(
@@ -25,12 +27,14 @@ func NewGetState(
 	jobSupervisor boshjobsuper.JobSupervisor,
 	vitalsService boshvitals.Service,
 	ntpService boshntp.Service,
+	bootProfile boshbootprofile.Profile,
 ) (action GetStateAction) {
 	action.settingsService = settingsService
 	action.specService = specService
 	action.jobSupervisor = jobSupervisor
 	action.vitalsService = vitalsService
 	action.ntpService = ntpService
+	action.bootProfile = bootProfile
 	return
 }
 
@@ -52,6 +56,9 @@ type GetStateV1ApplySpec struct {
 	Processes    []boshjobsuper.Process `json:"processes,omitempty"`
 	VM           boshsettings.VM        `json:"vm"`
 	Ntp          boshntp.Info           `json:"ntp"`
+
+	// Time bootstrap took after agent last started
+	Boot *boshbootprofile.Summary `json:"boot,omitempty"`
 }
 
 func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
@@ -87,6 +94,7 @@ func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
 		processes,
 		settings.VM,
 		a.ntpService.GetInfo(),
+		a.bootProfile.Summary(),
 	}
 
 	if value.NetworkSpecs == nil {
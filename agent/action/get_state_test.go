@@ -2,6 +2,7 @@ package action_test
 
 import (
 	"errors"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -9,6 +10,7 @@ import (
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	boshntp "github.com/cloudfoundry/bosh-agent/platform/ntp"
@@ -18,6 +20,7 @@ import (
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("GetState", func() {
@@ -26,6 +29,8 @@ var _ = Describe("GetState", func() {
 		specService     *fakeas.FakeV1Service
 		jobSupervisor   *fakejobsuper.FakeJobSupervisor
 		vitalsService   *fakevitals.FakeService
+		timeService     *fakeclock.FakeClock
+		bootProfile     boshbootprofile.Profile
 		action          GetStateAction
 	)
 
@@ -40,7 +45,9 @@ var _ = Describe("GetState", func() {
 				Timestamp: "12 Oct 17:37:58",
 			},
 		}
-		action = NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService)
+		timeService = fakeclock.NewFakeClock(time.Now())
+		bootProfile = boshbootprofile.NewProfile(timeService)
+		action = NewGetState(settingsService, specService, jobSupervisor, vitalsService, ntpService, bootProfile)
 	})
 
 	It("get state should be synchronous", func() {
@@ -92,6 +99,24 @@ var _ = Describe("GetState", func() {
 					Expect(state).To(Equal(expectedSpec))
 				})
 
+				It("returns time bootstrap took once it finished", func() {
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.Boot).To(BeNil())
+
+					endPhase := bootProfile.StartPhase("monit_start")
+					timeService.Increment(1500 * time.Millisecond)
+					endPhase()
+					bootProfile.Finish()
+
+					state, err = action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.Boot).To(Equal(&boshbootprofile.Summary{
+						TotalMs: 1500,
+						Phases:  []boshbootprofile.Phase{{Name: "monit_start", DurationMs: 1500}},
+					}))
+				})
+
 				It("returns state in full format", func() {
 					settingsService.Settings.AgentID = "my-agent-id"
 					settingsService.Settings.VM.Name = "vm-abc-def"
@@ -141,6 +166,38 @@ var _ = Describe("GetState", func() {
 					boshassert.MatchesJSONMap(GinkgoT(), state.VM, expectedVM)
 				})
 
+				It("returns restarts, last exit and health of processes when job supervisor reports them", func() {
+					restarts := 2
+
+					jobSupervisor.ProcessesStatus = []boshjobsuper.Process{
+						boshjobsuper.Process{
+							Name:     "fake-process-name-1",
+							State:    "failing",
+							Restarts: &restarts,
+							LastExit: &boshjobsuper.ExitVitals{Signal: "KILL"},
+							Health: &boshjobsuper.HealthVitals{
+								Failures:  3,
+								Reason:    "fake-probe-err",
+								CheckedAt: time.Date(2026, time.October, 14, 10, 5, 0, 0, time.UTC),
+							},
+						},
+						boshjobsuper.Process{
+							Name:  "fake-process-name-2",
+							State: "running",
+						},
+					}
+
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+
+					boshassert.MatchesJSONString(GinkgoT(), state.Processes[0].Restarts, `2`)
+					boshassert.MatchesJSONString(GinkgoT(), state.Processes[0].LastExit, `{"status":0,"signal":"KILL"}`)
+					boshassert.MatchesJSONString(GinkgoT(), state.Processes[0].Health,
+						`{"healthy":false,"failures":3,"reason":"fake-probe-err","checked_at":"2026-10-14T10:05:00Z"}`)
+					boshassert.MatchesJSONString(GinkgoT(), state.Processes[1],
+						`{"name":"fake-process-name-2","state":"running","uptime":{},"mem":{"percent":0},"cpu":{"total":0},"fd":{"open":0}}`)
+				})
+
 				Describe("non-populated field formatting", func() {
 					It("returns network as empty hash if not set", func() {
 						specService.Spec = boshas.V1ApplySpec{NetworkSpecs: nil}
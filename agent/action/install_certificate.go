@@ -0,0 +1,119 @@
+package action
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"time"
+
+	boshdispatcher "github.com/cloudfoundry/bosh-agent/httpsdispatcher"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// InstallCertificateAction replaces agent certificate with the certificate signed
+// for CSR from generate_csr action; https mbus picks it up without restarting
+type InstallCertificateAction struct {
+	fs          boshsys.FileSystem
+	dirProvider boshdirs.Provider
+}
+
+func NewInstallCertificate(fs boshsys.FileSystem, dirProvider boshdirs.Provider) InstallCertificateAction {
+	return InstallCertificateAction{fs: fs, dirProvider: dirProvider}
+}
+
+func (a InstallCertificateAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a InstallCertificateAction) IsPersistent() bool {
+	return false
+}
+
+func (a InstallCertificateAction) Run(certificate string) (string, error) {
+	pendingKeyPath := PendingPrivateKeyPath(a.dirProvider)
+
+	if !a.fs.FileExists(pendingKeyPath) {
+		return "", bosherr.Error("No certificate signing request was generated")
+	}
+
+	keyPEM, err := a.fs.ReadFile(pendingKeyPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Reading pending private key")
+	}
+
+	keyPair, err := tls.X509KeyPair([]byte(certificate), keyPEM)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Matching certificate with pending private key")
+	}
+
+	leaf, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return "", bosherr.WrapError(err, "Parsing certificate")
+	}
+
+	if time.Now().After(leaf.NotAfter) {
+		return "", bosherr.Errorf("Certificate expired at %s", leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	err = replacePrivateFile(a.fs, boshdispatcher.PrivateKeyPath, keyPEM)
+	if err != nil {
+		return "", err
+	}
+
+	err = replacePrivateFile(a.fs, boshdispatcher.CertificatePath, []byte(certificate))
+	if err != nil {
+		return "", err
+	}
+
+	err = a.fs.RemoveAll(pendingKeyPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Removing pending private key")
+	}
+
+	return "installed", nil
+}
+
+// replacePrivateFile renames new contents over path so that readers never see partial files;
+// new file is created readable only by the agent before any contents are written to it
+func replacePrivateFile(fs boshsys.FileSystem, path string, contents []byte) error {
+	newPath := path + ".new"
+
+	// Leftover file may have been created with other permissions
+	err := fs.RemoveAll(newPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing %s", newPath)
+	}
+
+	file, err := fs.OpenFile(newPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening %s for writing", newPath)
+	}
+
+	_, err = file.Write(contents)
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = fs.RemoveAll(newPath)
+		return bosherr.WrapErrorf(err, "Writing %s", newPath)
+	}
+
+	err = fs.Rename(newPath, path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Renaming %s to %s", newPath, path)
+	}
+
+	return nil
+}
+
+func (a InstallCertificateAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a InstallCertificateAction) Cancel() error {
+	return errors.New("not supported")
+}
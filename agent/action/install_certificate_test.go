@@ -0,0 +1,120 @@
+package action_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("installCertificate", func() {
+	var (
+		action      InstallCertificateAction
+		fs          *fakesys.FakeFileSystem
+		dirProvider boshdirs.Provider
+	)
+
+	signCSR := func(notAfter time.Time) string {
+		result, err := NewGenerateCSR(fs, &fakesettings.FakeSettingsService{}, dirProvider).Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		block, _ := pem.Decode([]byte(result["csr"].(string)))
+		csr, err := x509.ParseCertificateRequest(block.Bytes)
+		Expect(err).ToNot(HaveOccurred())
+
+		caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "fake-agent-id"},
+			NotBefore:    notAfter.Add(-48 * time.Hour),
+			NotAfter:     notAfter,
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, csr.PublicKey, caKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fs.MkdirAll(".", 0755)
+		dirProvider = boshdirs.NewProvider("/var/vcap")
+		fs.MkdirAll("/var/vcap/bosh", 0755)
+		action = NewInstallCertificate(fs, dirProvider)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	It("installs certificate with pending private key", func() {
+		cert := signCSR(time.Now().Add(24 * time.Hour))
+
+		pendingKey, err := fs.ReadFileString(PendingPrivateKeyPath(dirProvider))
+		Expect(err).ToNot(HaveOccurred())
+
+		result, err := action.Run(cert)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal("installed"))
+
+		Expect(fs.ReadFileString("agent.cert")).To(Equal(cert))
+		Expect(fs.ReadFileString("agent.key")).To(Equal(pendingKey))
+		Expect(fs.FileExists(PendingPrivateKeyPath(dirProvider))).To(BeFalse())
+	})
+
+	It("creates new certificate and key readable only by the agent before writing to them", func() {
+		cert := signCSR(time.Now().Add(24 * time.Hour))
+
+		_, err := action.Run(cert)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.RenameOldPaths).To(ContainElement("agent.key.new"))
+		Expect(fs.RenameOldPaths).To(ContainElement("agent.cert.new"))
+		Expect(fs.GetFileTestStat("agent.key").FileMode).To(Equal(os.FileMode(0600)))
+		Expect(fs.GetFileTestStat("agent.cert").FileMode).To(Equal(os.FileMode(0600)))
+	})
+
+	It("returns an error when certificate does not match pending private key", func() {
+		cert := signCSR(time.Now().Add(24 * time.Hour))
+		signCSR(time.Now().Add(24 * time.Hour))
+
+		_, err := action.Run(cert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Matching certificate with pending private key"))
+		Expect(fs.FileExists("agent.cert")).To(BeFalse())
+	})
+
+	It("returns an error when certificate expired", func() {
+		cert := signCSR(time.Now().Add(-time.Hour))
+
+		_, err := action.Run(cert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Certificate expired at"))
+	})
+
+	It("returns an error when no CSR was generated", func() {
+		_, err := action.Run("fake-cert")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("No certificate signing request was generated"))
+	})
+})
@@ -4,19 +4,23 @@ import (
 	"errors"
 
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
 type MigrateDiskAction struct {
-	platform    boshplatform.Platform
-	dirProvider boshdirs.Provider
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	dirProvider     boshdirs.Provider
 }
 
 func NewMigrateDisk(
+	settingsService boshsettings.Service,
 	platform boshplatform.Platform,
 	dirProvider boshdirs.Provider,
 ) (action MigrateDiskAction) {
+	action.settingsService = settingsService
 	action.platform = platform
 	action.dirProvider = dirProvider
 	return
@@ -26,14 +30,23 @@ func (a MigrateDiskAction) IsAsynchronous() bool {
 	return true
 }
 
+// IsPersistent is true so that migration interrupted by agent restart is resumed
 func (a MigrateDiskAction) IsPersistent() bool {
-	return false
+	return true
 }
 
-func (a MigrateDiskAction) Run() (value interface{}, err error) {
-	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir())
+// Run migrates data onto the new disk mounted next to the old disk's mount point.
+// Director passes old and new disk cids; the new disk's mount point hint picks
+// which mount point is migrated, older directors pass no arguments.
+func (a MigrateDiskAction) Run(diskCids ...string) (value interface{}, err error) {
+	mountPoint, err := a.migratedMountPoint(diskCids)
+	if err != nil {
+		return
+	}
+
+	err = a.platform.MigratePersistentDisk(mountPoint, a.dirProvider.MigrationDir(mountPoint))
 	if err != nil {
-		err = bosherr.WrapError(err, "Migrating persistent disk")
+		err = migrationError(err)
 		return
 	}
 
@@ -41,10 +54,84 @@ func (a MigrateDiskAction) Run() (value interface{}, err error) {
 	return
 }
 
+func (a MigrateDiskAction) migratedMountPoint(diskCids []string) (string, error) {
+	if len(diskCids) != 2 {
+		return a.dirProvider.StoreDir(), nil
+	}
+
+	diskSettings, found := a.settingsService.GetSettings().PersistentDiskSettings(diskCids[1])
+	if !found {
+		return "", bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCids[1])
+	}
+
+	return a.dirProvider.PersistentDiskMountPoint(diskSettings.MountPoint), nil
+}
+
+// ResumeRun continues migration of the mount point of the requested disk if it
+// still has the new disk mounted next to it; there is nothing to resume when
+// migration has finished. Without disk cids any interrupted migration is resumed.
+func (a MigrateDiskAction) ResumeRun(diskCids ...string) (interface{}, error) {
+	if len(diskCids) == 2 {
+		mountPoint, err := a.migratedMountPoint(diskCids)
+		if err != nil {
+			return nil, err
+		}
+
+		return a.resume([]string{mountPoint})
+	}
+
+	return a.Resume()
+}
+
+// Resume continues migration of any mount point which still has the new disk mounted next to it
 func (a MigrateDiskAction) Resume() (interface{}, error) {
-	return nil, errors.New("not supported")
+	mountPoints := []string{a.dirProvider.StoreDir()}
+
+	for diskID := range a.settingsService.GetSettings().Disks.Persistent {
+		diskSettings, _ := a.settingsService.GetSettings().PersistentDiskSettings(diskID)
+		mountPoints = append(mountPoints, a.dirProvider.PersistentDiskMountPoint(diskSettings.MountPoint))
+	}
+
+	return a.resume(mountPoints)
+}
+
+func (a MigrateDiskAction) resume(mountPoints []string) (interface{}, error) {
+	for _, mountPoint := range mountPoints {
+		_, isMountPoint, err := a.platform.IsMountPoint(a.dirProvider.MigrationDir(mountPoint))
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Checking for interrupted migration")
+		}
+
+		if isMountPoint {
+			err = a.platform.MigratePersistentDisk(mountPoint, a.dirProvider.MigrationDir(mountPoint))
+			if err != nil {
+				return nil, migrationError(err)
+			}
+			break
+		}
+	}
+
+	return map[string]string{}, nil
+}
+
+// Progress reports bytes copied onto the new disk while migration is running
+func (a MigrateDiskAction) Progress() interface{} {
+	progress, found := a.platform.GetPersistentDiskMigrationProgress()
+	if !found {
+		return nil
+	}
+	return progress
 }
 
 func (a MigrateDiskAction) Cancel() error {
 	return errors.New("not supported")
 }
+
+// migrationError keeps differences found by verification as is so that they reach the director
+func migrationError(err error) error {
+	if _, ok := err.(boshplatform.DiskMigrationVerificationError); ok {
+		return err
+	}
+
+	return bosherr.WrapError(err, "Migrating persistent disk")
+}
@@ -1,19 +1,34 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
 )
 
 func buildMigrateDiskAction() (platform *fakeplatform.FakePlatform, action MigrateDiskAction) {
 	platform = fakeplatform.NewFakePlatform()
 	dirProvider := boshdirs.NewProvider("/foo")
-	action = NewMigrateDisk(platform, dirProvider)
+	settingsService := &fakesettings.FakeSettingsService{
+		Settings: boshsettings.Settings{
+			Disks: boshsettings.Disks{
+				Persistent: map[string]interface{}{
+					"vol-old": map[string]interface{}{"path": "/dev/sdb", "mount_point": "/data/db"},
+					"vol-new": map[string]interface{}{"path": "/dev/sdc", "mount_point": "/data/db"},
+				},
+			},
+		},
+	}
+	action = NewMigrateDisk(settingsService, platform, dirProvider)
 	return
 }
 func init() {
@@ -23,9 +38,9 @@ func init() {
 			Expect(action.IsAsynchronous()).To(BeTrue())
 		})
 
-		It("is not persistent", func() {
+		It("is persistent so that interrupted migration is resumed", func() {
 			_, action := buildMigrateDiskAction()
-			Expect(action.IsPersistent()).To(BeFalse())
+			Expect(action.IsPersistent()).To(BeTrue())
 		})
 
 		It("migrate disk action run", func() {
@@ -39,5 +54,99 @@ func init() {
 			Expect(platform.MigratePersistentDiskFromMountPoint).To(Equal("/foo/store"))
 			Expect(platform.MigratePersistentDiskToMountPoint).To(Equal("/foo/store_migration_target"))
 		})
+
+		It("migrates mount point of the new disk when disk cids are given", func() {
+			platform, action := buildMigrateDiskAction()
+
+			_, err := action.Run("vol-old", "vol-new")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(Equal("/data/db"))
+			Expect(platform.MigratePersistentDiskToMountPoint).To(Equal("/data/db_migration_target"))
+		})
+
+		It("resumes migration of mount point that still has the new disk mounted next to it", func() {
+			platform, action := buildMigrateDiskAction()
+			platform.IsMountPointResult = true
+
+			value, err := action.Resume()
+			Expect(err).ToNot(HaveOccurred())
+			boshassert.MatchesJSONString(GinkgoT(), value, "{}")
+
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(Equal("/foo/store"))
+			Expect(platform.MigratePersistentDiskToMountPoint).To(Equal("/foo/store_migration_target"))
+		})
+
+		It("resumes migration of mount point of the requested disk", func() {
+			platform, action := buildMigrateDiskAction()
+			platform.IsMountPointResult = true
+
+			_, err := action.ResumeRun("vol-old", "vol-new")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(Equal("/data/db"))
+			Expect(platform.MigratePersistentDiskToMountPoint).To(Equal("/data/db_migration_target"))
+		})
+
+		It("does not resume migration of other mount points than the one of the requested disk", func() {
+			platform, action := buildMigrateDiskAction()
+
+			_, err := action.ResumeRun("vol-old", "vol-new")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(BeEmpty())
+		})
+
+		It("does nothing on resume when migration has finished", func() {
+			platform, action := buildMigrateDiskAction()
+
+			_, err := action.Resume()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.MigratePersistentDiskFromMountPoint).To(BeEmpty())
+		})
+
+		It("reports progress of running migration", func() {
+			platform, action := buildMigrateDiskAction()
+			Expect(action.Progress()).To(BeNil())
+
+			platform.GetPersistentDiskMigrationProgressFound = true
+			platform.GetPersistentDiskMigrationProgressProgress = boshplatform.DiskMigrationProgress{
+				BytesCopied:  100,
+				BytesTotal:   400,
+				ETAInSeconds: 30,
+			}
+
+			boshassert.MatchesJSONString(GinkgoT(), action.Progress(),
+				`{"bytes_copied":100,"bytes_total":400,"eta_in_seconds":30}`)
+		})
+
+		It("returns differences found by verification as is", func() {
+			platform, action := buildMigrateDiskAction()
+			verificationErr := boshplatform.DiskMigrationVerificationError{
+				FilesExpected: 2,
+				FilesCopied:   1,
+				MissingFiles:  []string{"a"},
+			}
+			platform.MigratePersistentDiskErr = verificationErr
+
+			_, err := action.Run()
+			Expect(err).To(Equal(verificationErr))
+		})
+
+		It("wraps other migration errors", func() {
+			platform, action := buildMigrateDiskAction()
+			platform.MigratePersistentDiskErr = errors.New("fake-migrate-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Migrating persistent disk: fake-migrate-err"))
+		})
+
+		It("returns error if new disk cid is unknown", func() {
+			_, action := buildMigrateDiskAction()
+
+			_, err := action.Run("vol-old", "vol-unknown")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("vol-unknown"))
+		})
 	})
 }
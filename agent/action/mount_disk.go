@@ -58,7 +58,7 @@ func (a MountDiskAction) Run(diskCid string) (interface{}, error) {
 		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
 	}
 
-	mountPoint := a.dirProvider.StoreDir()
+	mountPoint := a.dirProvider.PersistentDiskMountPoint(diskSettings.MountPoint)
 
 	err = a.diskMounter.MountPersistentDisk(diskSettings, mountPoint)
 	if err != nil {
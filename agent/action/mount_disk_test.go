@@ -63,6 +63,17 @@ var _ = Describe("MountDiskAction", func() {
 						}))
 						Expect(platform.MountPersistentDiskMountPoint).To(Equal("/fake-base-dir/store"))
 					})
+
+					It("mounts disk on the mount point from disk hints", func() {
+						settingsService.Settings.Disks.Persistent["fake-disk-cid"] = map[string]interface{}{
+							"path":        "fake-device-path",
+							"mount_point": "/data/db",
+						}
+
+						_, err := action.Run("fake-disk-cid")
+						Expect(err).NotTo(HaveOccurred())
+						Expect(platform.MountPersistentDiskMountPoint).To(Equal("/data/db"))
+					})
 				})
 
 				Context("when mounting fails", func() {
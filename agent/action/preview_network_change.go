@@ -0,0 +1,58 @@
+package action
+
+import (
+	"errors"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// PreviewNetworkChangeAction returns diff of network configuration
+// against current files and live state without applying it
+type PreviewNetworkChangeAction struct {
+	platform        boshplatform.Platform
+	settingsService boshsettings.Service
+}
+
+func NewPreviewNetworkChange(
+	platform boshplatform.Platform,
+	settingsService boshsettings.Service,
+) PreviewNetworkChangeAction {
+	return PreviewNetworkChangeAction{
+		platform:        platform,
+		settingsService: settingsService,
+	}
+}
+
+func (a PreviewNetworkChangeAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a PreviewNetworkChangeAction) IsPersistent() bool {
+	return false
+}
+
+// Run previews given networks or networks from current settings when none are given
+func (a PreviewNetworkChangeAction) Run(networks ...boshsettings.Networks) (boshnet.NetworkChanges, error) {
+	desiredNetworks := a.settingsService.GetSettings().Networks
+	if len(networks) > 0 {
+		desiredNetworks = networks[0]
+	}
+
+	changes, err := a.platform.PreviewNetworking(desiredNetworks)
+	if err != nil {
+		return boshnet.NetworkChanges{}, bosherr.WrapError(err, "Previewing network change")
+	}
+
+	return changes, nil
+}
+
+func (a PreviewNetworkChangeAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a PreviewNetworkChangeAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,72 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("previewNetworkChange", func() {
+	var (
+		action          PreviewNetworkChangeAction
+		platform        *fakeplatform.FakePlatform
+		settingsService *fakesettings.FakeSettingsService
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		settingsService = &fakesettings.FakeSettingsService{}
+		action = NewPreviewNetworkChange(platform, settingsService)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			platform.PreviewNetworkingChanges = boshnet.NetworkChanges{
+				Files:    map[string]string{"/etc/fake-file": "--- /dev/null\n+++ /etc/fake-file\n+fake-line\n"},
+				Commands: []string{"fake-command"},
+			}
+		})
+
+		It("returns changes of given networks", func() {
+			networks := boshsettings.Networks{"fake-net": boshsettings.Network{IP: "10.0.0.6"}}
+
+			changes, err := action.Run(networks)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changes).To(Equal(platform.PreviewNetworkingChanges))
+
+			Expect(platform.PreviewNetworkingNetworks).To(Equal(networks))
+		})
+
+		It("returns changes of networks from current settings when no networks are given", func() {
+			settingsService.Settings.Networks = boshsettings.Networks{"fake-net": boshsettings.Network{IP: "10.0.0.7"}}
+
+			_, err := action.Run()
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(platform.PreviewNetworkingNetworks).To(Equal(settingsService.Settings.Networks))
+		})
+
+		It("returns error if previewing fails", func() {
+			platform.PreviewNetworkingErr = errors.New("fake-preview-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-preview-err"))
+		})
+	})
+})
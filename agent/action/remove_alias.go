@@ -0,0 +1,53 @@
+package action
+
+import (
+	"errors"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// RemoveAliasAction unassigns alias address added with AddAliasAction
+// so that HA jobs can release a virtual IP on failover
+type RemoveAliasAction struct {
+	platform        boshplatform.Platform
+	settingsService boshsettings.Service
+}
+
+func NewRemoveAlias(
+	platform boshplatform.Platform,
+	settingsService boshsettings.Service,
+) RemoveAliasAction {
+	return RemoveAliasAction{
+		platform:        platform,
+		settingsService: settingsService,
+	}
+}
+
+func (a RemoveAliasAction) IsAsynchronous() bool {
+	return false
+}
+
+func (a RemoveAliasAction) IsPersistent() bool {
+	return false
+}
+
+func (a RemoveAliasAction) Run(networkName, address string) (map[string]interface{}, error) {
+	networks := a.settingsService.GetSettings().Networks
+
+	err := a.platform.RemoveAlias(networks, networkName, address)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Removing alias '%s' of network '%s'", address, networkName)
+	}
+
+	return map[string]interface{}{}, nil
+}
+
+func (a RemoveAliasAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a RemoveAliasAction) Cancel() error {
+	return errors.New("not supported")
+}
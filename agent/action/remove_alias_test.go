@@ -0,0 +1,57 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("removeAlias", func() {
+	var (
+		action          RemoveAliasAction
+		platform        *fakeplatform.FakePlatform
+		settingsService *fakesettings.FakeSettingsService
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		settingsService = &fakesettings.FakeSettingsService{}
+		action = NewRemoveAlias(platform, settingsService)
+	})
+
+	It("is synchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeFalse())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	Describe("Run", func() {
+		It("removes alias of the network from current settings", func() {
+			settingsService.Settings.Networks = boshsettings.Networks{"fake-net": boshsettings.Network{IP: "10.0.0.6"}}
+
+			result, err := action.Run("fake-net", "10.0.0.100")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(map[string]interface{}{}))
+
+			Expect(platform.RemoveAliasNetworks).To(Equal(settingsService.Settings.Networks))
+			Expect(platform.RemoveAliasNetworkName).To(Equal("fake-net"))
+			Expect(platform.RemoveAliasAddress).To(Equal("10.0.0.100"))
+		})
+
+		It("returns error if removing alias fails", func() {
+			platform.RemoveAliasErr = errors.New("fake-alias-err")
+
+			_, err := action.Run("fake-net", "10.0.0.100")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Removing alias '10.0.0.100' of network 'fake-net': fake-alias-err"))
+		})
+	})
+})
@@ -0,0 +1,62 @@
+package action
+
+import (
+	"errors"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// ResizeDiskAction grows partition and filesystem of a mounted persistent
+// disk after the IaaS has enlarged its device, without detaching it.
+type ResizeDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+}
+
+func NewResizeDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+) (resizeDisk ResizeDiskAction) {
+	resizeDisk.settingsService = settingsService
+	resizeDisk.platform = platform
+	return
+}
+
+func (a ResizeDiskAction) IsAsynchronous() bool {
+	return true
+}
+
+func (a ResizeDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a ResizeDiskAction) Run(diskCid string) (interface{}, error) {
+	err := a.settingsService.LoadSettings()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Refreshing the settings")
+	}
+
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	err = a.platform.ResizePersistentDisk(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Resizing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a ResizeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ResizeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,85 @@
+package action_test
+
+import (
+	"errors"
+
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+)
+
+var _ = Describe("ResizeDiskAction", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		platform        *fakeplatform.FakePlatform
+		action          ResizeDiskAction
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{
+			Settings: boshsettings.Settings{
+				Disks: boshsettings.Disks{
+					Persistent: map[string]interface{}{
+						"vol-123": map[string]interface{}{
+							"volume_id": "2",
+							"path":      "/dev/sdf",
+						},
+					},
+				},
+				Env: boshsettings.Env{
+					PersistentDiskFS: "ext4",
+				},
+			},
+		}
+		platform = fakeplatform.NewFakePlatform()
+		action = NewResizeDisk(settingsService, platform)
+	})
+
+	It("is asynchronous", func() {
+		Expect(action.IsAsynchronous()).To(BeTrue())
+	})
+
+	It("is not persistent", func() {
+		Expect(action.IsPersistent()).To(BeFalse())
+	})
+
+	It("resizes the persistent disk", func() {
+		result, err := action.Run("vol-123")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(result).To(Equal(map[string]string{}))
+
+		Expect(platform.ResizePersistentDiskSettings).To(Equal(boshsettings.DiskSettings{
+			ID:             "vol-123",
+			VolumeID:       "2",
+			Path:           "/dev/sdf",
+			FileSystemType: "ext4",
+		}))
+	})
+
+	It("returns error when settings cannot be loaded", func() {
+		settingsService.LoadSettingsError = errors.New("fake-load-error")
+
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-load-error"))
+	})
+
+	It("returns error when disk cid is unknown", func() {
+		_, err := action.Run("vol-456")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Persistent disk with volume id 'vol-456' could not be found"))
+	})
+
+	It("returns error when platform fails to resize the disk", func() {
+		platform.ResizePersistentDiskErr = errors.New("fake-resize-error")
+
+		_, err := action.Run("vol-123")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-resize-error"))
+	})
+})
@@ -71,6 +71,10 @@ func (a RunErrandAction) Run() (ErrandResult, error) {
 		},
 	}
 
+	for name, value := range currentSpec.InstanceInfo().Env() {
+		command.Env[name] = value
+	}
+
 	process, err := a.cmdRunner.RunComplexCommandAsync(command)
 	if err != nil {
 		return ErrandResult{}, bosherr.WrapError(err, "Running errand script")
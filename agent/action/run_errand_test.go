@@ -41,7 +41,7 @@ var _ = Describe("RunErrand", func() {
 		Context("when apply spec is successfully retrieved", func() {
 			Context("when current agent has a job spec template", func() {
 				BeforeEach(func() {
-					currentSpec := boshas.V1ApplySpec{}
+					currentSpec := boshas.V1ApplySpec{NodeID: "fake-id"}
 					currentSpec.JobSpec.Template = "fake-job-name"
 					specService.Spec = currentSpec
 				})
@@ -76,7 +76,8 @@ var _ = Describe("RunErrand", func() {
 							boshsys.Command{
 								Name: "/fake-jobs-dir/fake-job-name/bin/run",
 								Env: map[string]string{
-									"PATH": "/usr/sbin:/usr/bin:/sbin:/bin",
+									"PATH":             "/usr/sbin:/usr/bin:/sbin:/bin",
+									"BOSH_INSTANCE_ID": "fake-id",
 								},
 							},
 						}))
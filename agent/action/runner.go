@@ -20,6 +20,20 @@ func NewRunner() Runner {
 type concreteRunner struct{}
 
 func (r concreteRunner) Run(action Action, payloadBytes []byte) (value interface{}, err error) {
+	return r.call(action, "Run", payloadBytes)
+}
+
+// Resume calls ResumeRun with arguments of the interrupted run
+// when action implements it, otherwise Resume
+func (r concreteRunner) Resume(action Action, payloadBytes []byte) (value interface{}, err error) {
+	if reflect.ValueOf(action).MethodByName("ResumeRun").Kind() == reflect.Func {
+		return r.call(action, "ResumeRun", payloadBytes)
+	}
+
+	return action.Resume()
+}
+
+func (r concreteRunner) call(action Action, methodName string, payloadBytes []byte) (value interface{}, err error) {
 	payloadArgs, err := r.extractJSONArguments(payloadBytes)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting json arguments")
@@ -27,32 +41,28 @@ func (r concreteRunner) Run(action Action, payloadBytes []byte) (value interface
 	}
 
 	actionValue := reflect.ValueOf(action)
-	runMethodValue := actionValue.MethodByName("Run")
-	if runMethodValue.Kind() != reflect.Func {
-		err = bosherr.Error("Run method not found")
+	methodValue := actionValue.MethodByName(methodName)
+	if methodValue.Kind() != reflect.Func {
+		err = bosherr.Errorf("%s method not found", methodName)
 		return
 	}
 
-	runMethodType := runMethodValue.Type()
-	if r.invalidReturnTypes(runMethodType) {
-		err = bosherr.Error("Run method should return a value and an error")
+	methodType := methodValue.Type()
+	if r.invalidReturnTypes(methodType) {
+		err = bosherr.Errorf("%s method should return a value and an error", methodName)
 		return
 	}
 
-	methodArgs, err := r.extractMethodArgs(runMethodType, payloadArgs)
+	methodArgs, err := r.extractMethodArgs(methodType, payloadArgs)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting method arguments from payload")
 		return
 	}
 
-	values := runMethodValue.Call(methodArgs)
+	values := methodValue.Call(methodArgs)
 	return r.extractReturns(values)
 }
 
-func (r concreteRunner) Resume(action Action, payloadBytes []byte) (value interface{}, err error) {
-	return action.Resume()
-}
-
 func (r concreteRunner) extractJSONArguments(payloadBytes []byte) (args []interface{}, err error) {
 	type payloadType struct {
 		Arguments []interface{} `json:"arguments"`
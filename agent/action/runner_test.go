@@ -130,6 +130,35 @@ func (a *actionWithOptionalRunArgument) Cancel() error {
 	return nil
 }
 
+type actionWithResumeRunMethod struct {
+	ResumedArgs []string
+}
+
+func (a *actionWithResumeRunMethod) IsAsynchronous() bool {
+	return true
+}
+
+func (a *actionWithResumeRunMethod) IsPersistent() bool {
+	return true
+}
+
+func (a *actionWithResumeRunMethod) Run(args ...string) (interface{}, error) {
+	return nil, nil
+}
+
+func (a *actionWithResumeRunMethod) ResumeRun(args ...string) (interface{}, error) {
+	a.ResumedArgs = args
+	return "fake-resume-run-value", nil
+}
+
+func (a *actionWithResumeRunMethod) Resume() (interface{}, error) {
+	return nil, errors.New("fake-resume-err")
+}
+
+func (a *actionWithResumeRunMethod) Cancel() error {
+	return nil
+}
+
 type actionWithoutRunMethod struct{}
 
 func (a *actionWithoutRunMethod) IsAsynchronous() bool {
@@ -342,6 +371,16 @@ func init() {
 
 				Expect(testAction.Resumed).To(BeTrue())
 			})
+
+			It("calls ResumeRun() with arguments of the interrupted run when action implements it", func() {
+				runner := NewRunner()
+				testAction := &actionWithResumeRunMethod{}
+
+				value, err := runner.Resume(testAction, []byte(`{"arguments":["fake-arg-1", "fake-arg-2"]}`))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(value).To(Equal("fake-resume-run-value"))
+				Expect(testAction.ResumedArgs).To(Equal([]string{"fake-arg-1", "fake-arg-2"}))
+			})
 		})
 	})
 }
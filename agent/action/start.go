@@ -6,6 +6,7 @@ import (
 	boshappl "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
@@ -13,13 +14,20 @@ type StartAction struct {
 	jobSupervisor boshjobsuper.JobSupervisor
 	applier       boshappl.Applier
 	specService   boshas.V1Service
+	platform      boshplatform.Platform
 }
 
-func NewStart(jobSupervisor boshjobsuper.JobSupervisor, applier boshappl.Applier, specService boshas.V1Service) (start StartAction) {
+func NewStart(
+	jobSupervisor boshjobsuper.JobSupervisor,
+	applier boshappl.Applier,
+	specService boshas.V1Service,
+	platform boshplatform.Platform,
+) (start StartAction) {
 	start = StartAction{
 		jobSupervisor: jobSupervisor,
 		specService:   specService,
 		applier:       applier,
+		platform:      platform,
 	}
 	return
 }
@@ -51,6 +59,35 @@ func (a StartAction) Run() (value string, err error) {
 		return
 	}
 
+	// Processes that are not running yet are tracked by the agent's job processes ticker
+	allJobNames := []string{}
+	for _, job := range desiredApplySpec.Jobs() {
+		allJobNames = append(allJobNames, job.Name)
+	}
+
+	if len(allJobNames) > 0 {
+		err = a.platform.TrackJobProcessGroups(allJobNames)
+		if err != nil {
+			err = bosherr.WrapError(err, "Tracking job process groups")
+			return
+		}
+	}
+
+	// Job supervisors start processes in cgroups of their jobs; processes they
+	// cannot place are moved here or by the agent's job processes ticker
+	jobNames := []string{}
+	for jobName := range desiredApplySpec.JobResourceLimits() {
+		jobNames = append(jobNames, jobName)
+	}
+
+	if len(jobNames) > 0 {
+		err = a.platform.AddJobProcessesToCgroups(jobNames)
+		if err != nil {
+			err = bosherr.WrapError(err, "Adding job processes to cgroups")
+			return
+		}
+	}
+
 	value = "started"
 	return
 }
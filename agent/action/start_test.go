@@ -6,9 +6,11 @@ import (
 
 	"errors"
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	fakeappl "github.com/cloudfoundry/bosh-agent/agent/applier/fakes"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 )
 
 func init() {
@@ -17,6 +19,7 @@ func init() {
 			jobSupervisor *fakejobsuper.FakeJobSupervisor
 			applier       *fakeappl.FakeApplier
 			specService   *fakeas.FakeV1Service
+			platform      *fakeplatform.FakePlatform
 			action        StartAction
 		)
 
@@ -24,7 +27,8 @@ func init() {
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 			applier = fakeappl.NewFakeApplier()
 			specService = fakeas.NewFakeV1Service()
-			action = NewStart(jobSupervisor, applier, specService)
+			platform = fakeplatform.NewFakePlatform()
+			action = NewStart(jobSupervisor, applier, specService, platform)
 		})
 
 		It("is synchronous", func() {
@@ -60,5 +64,48 @@ func init() {
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("Configuring jobs"))
 		})
+
+		It("adds processes of jobs with resource limits to their cgroups", func() {
+			specService.Spec = boshas.V1ApplySpec{
+				PropertiesSpec: boshas.PropertiesSpec{
+					JobResourceLimits: map[string]boshas.ResourceLimitsSpec{"fake-job": {MemoryMB: 512}},
+				},
+			}
+
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.AddJobProcessesToCgroupsJobNames).To(Equal([]string{"fake-job"}))
+		})
+
+		It("tracks process groups of jobs", func() {
+			specService.Spec = boshas.V1ApplySpec{
+				JobSpec: boshas.JobSpec{
+					JobTemplateSpecs: []boshas.JobTemplateSpec{{Name: "fake-job-1"}, {Name: "fake-job-2"}},
+				},
+			}
+
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.TrackJobProcessGroupsJobNames).To(Equal([]string{"fake-job-1", "fake-job-2"}))
+		})
+
+		It("returns error when process groups of jobs cannot be tracked", func() {
+			specService.Spec = boshas.V1ApplySpec{
+				JobSpec: boshas.JobSpec{
+					JobTemplateSpecs: []boshas.JobTemplateSpec{{Name: "fake-job"}},
+				},
+			}
+			platform.TrackJobProcessGroupsErr = errors.New("fake-track-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-track-err"))
+		})
+
+		It("does not touch cgroups when no job has resource limits", func() {
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.AddJobProcessesToCgroupsJobNames).To(BeNil())
+		})
 	})
 }
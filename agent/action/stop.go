@@ -4,16 +4,19 @@ import (
 	"errors"
 
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
 type StopAction struct {
 	jobSupervisor boshjobsuper.JobSupervisor
+	platform      boshplatform.Platform
 }
 
-func NewStop(jobSupervisor boshjobsuper.JobSupervisor) (stop StopAction) {
+func NewStop(jobSupervisor boshjobsuper.JobSupervisor, platform boshplatform.Platform) (stop StopAction) {
 	stop = StopAction{
 		jobSupervisor: jobSupervisor,
+		platform:      platform,
 	}
 	return
 }
@@ -33,6 +36,13 @@ func (a StopAction) Run() (value string, err error) {
 		return
 	}
 
+	// Children that escaped job supervisor would otherwise keep ports and files in use
+	err = a.platform.KillOrphanedJobProcesses()
+	if err != nil {
+		err = bosherr.WrapError(err, "Killing orphaned job processes")
+		return
+	}
+
 	value = "stopped"
 	return
 }
@@ -1,23 +1,28 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 )
 
 func init() {
 	Describe("Stop", func() {
 		var (
 			jobSupervisor *fakejobsuper.FakeJobSupervisor
+			platform      *fakeplatform.FakePlatform
 			action        StopAction
 		)
 
 		BeforeEach(func() {
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
-			action = NewStop(jobSupervisor)
+			platform = fakeplatform.NewFakePlatform()
+			action = NewStop(jobSupervisor, platform)
 		})
 
 		It("is asynchronous", func() {
@@ -39,5 +44,27 @@ func init() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(jobSupervisor.Stopped).To(BeTrue())
 		})
+
+		It("kills orphaned job processes after stopping job supervisor services", func() {
+			_, err := action.Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.KillOrphanedJobProcessesCalled).To(BeTrue())
+		})
+
+		It("does not kill orphaned job processes when services cannot be stopped", func() {
+			jobSupervisor.StopErr = errors.New("fake-stop-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(platform.KillOrphanedJobProcessesCalled).To(BeFalse())
+		})
+
+		It("returns error when orphaned job processes cannot be killed", func() {
+			platform.KillOrphanedJobProcessesErr = errors.New("fake-kill-err")
+
+			_, err := action.Run()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-kill-err"))
+		})
 	})
 }
@@ -61,7 +61,7 @@ var _ = Describe("UnmountDiskAction", func() {
 
 		result, err := action.Run("vol-123")
 		Expect(err).ToNot(HaveOccurred())
-		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Unmounted partition of {ID:vol-123 DeviceID: VolumeID:2 Path:/dev/sdf FileSystemType:ext4}"}`)
+		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Unmounted partition of {ID:vol-123 DeviceID: VolumeID:2 Path:/dev/sdf FileSystemType:ext4 MkfsOptions:[] MkfsTuning:{ReservedBlocksPercent:\u003cnil\u003e BytesPerInode:0 LazyInit:\u003cnil\u003e} MountOptions:[] MountPoint: EncryptionKey: EncryptionKeyRef: ISCSISettings:\u003cnil\u003e Lun: HostDeviceID:}"}`)
 
 		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
 	})
@@ -71,7 +71,7 @@ var _ = Describe("UnmountDiskAction", func() {
 
 		result, err := action.Run("vol-123")
 		Expect(err).ToNot(HaveOccurred())
-		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Partition of {ID:vol-123 DeviceID: VolumeID:2 Path:/dev/sdf FileSystemType:ext4} is not mounted"}`)
+		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Partition of {ID:vol-123 DeviceID: VolumeID:2 Path:/dev/sdf FileSystemType:ext4 MkfsOptions:[] MkfsTuning:{ReservedBlocksPercent:\u003cnil\u003e BytesPerInode:0 LazyInit:\u003cnil\u003e} MountOptions:[] MountPoint: EncryptionKey: EncryptionKeyRef: ISCSISettings:\u003cnil\u003e Lun: HostDeviceID:} is not mounted"}`)
 
 		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
 	})
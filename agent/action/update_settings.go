@@ -31,11 +31,19 @@ func (a UpdateSettingsAction) IsPersistent() bool {
 func (a UpdateSettingsAction) Run(newSettings boshsettings.Settings) (string, error) {
 	a.logger.Info("update-settings-action", "Running Update Settings command")
 
-	err := a.trustedCertManager.UpdateCertificates(newSettings.TrustedCerts)
+	changes, err := a.trustedCertManager.UpdateCertificates(newSettings.TrustedCerts)
 	if err != nil {
 		return "", err
 	}
 
+	for _, fingerprint := range changes.Added {
+		a.logger.Info("update-settings-action", "Added trusted certificate with fingerprint '%s'", fingerprint)
+	}
+
+	for _, fingerprint := range changes.Removed {
+		a.logger.Info("update-settings-action", "Removed trusted certificate with fingerprint '%s'", fingerprint)
+	}
+
 	return "updated", nil
 }
 
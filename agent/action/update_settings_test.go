@@ -7,6 +7,7 @@ import (
 	"errors"
 
 	"github.com/cloudfoundry/bosh-agent/agent/action"
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
 	"github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	"github.com/cloudfoundry/bosh-utils/logger"
@@ -44,7 +45,7 @@ var _ = Describe("UpdateSettings", func() {
 		BeforeEach(func() {
 			log = logger.NewLogger(logger.LevelNone)
 			certManager = new(fakes.FakeManager)
-			certManager.UpdateCertificatesReturns(errors.New("Error"))
+			certManager.UpdateCertificatesReturns(cert.Changes{}, errors.New("Error"))
 			updateAction = action.NewUpdateSettings(certManager, log)
 		})
 
@@ -4,6 +4,7 @@ import (
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshtelemetry "github.com/cloudfoundry/bosh-agent/telemetry"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -21,6 +22,7 @@ type concreteActionDispatcher struct {
 	taskManager   boshtask.Manager
 	actionFactory boshaction.Factory
 	actionRunner  boshaction.Runner
+	telemetry     boshtelemetry.Telemetry
 }
 
 func NewActionDispatcher(
@@ -29,6 +31,7 @@ func NewActionDispatcher(
 	taskManager boshtask.Manager,
 	actionFactory boshaction.Factory,
 	actionRunner boshaction.Runner,
+	telemetry boshtelemetry.Telemetry,
 ) (dispatcher ActionDispatcher) {
 	return concreteActionDispatcher{
 		logger:        logger,
@@ -36,6 +39,7 @@ func NewActionDispatcher(
 		taskManager:   taskManager,
 		actionFactory: actionFactory,
 		actionRunner:  actionRunner,
+		telemetry:     telemetry,
 	}
 }
 
@@ -61,16 +65,19 @@ func (dispatcher concreteActionDispatcher) ResumePreviouslyDispatchedTasks() {
 		}
 
 		taskID := taskInfo.TaskID
+		method := taskInfo.Method
 		payload := taskInfo.Payload
 
 		task := dispatcher.taskService.CreateTaskWithID(
 			taskID,
-			func() (interface{}, error) { return dispatcher.actionRunner.Resume(action, payload) },
+			func() (interface{}, error) {
+				return dispatcher.traced(method, func() (interface{}, error) { return dispatcher.actionRunner.Resume(action, payload) })
+			},
 			func(_ boshtask.Task) error { return action.Cancel() },
 			dispatcher.removeInfo,
 		)
 
-		dispatcher.taskService.StartTask(task)
+		dispatcher.startTask(task, action)
 	}
 }
 
@@ -98,7 +105,7 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	var err error
 
 	runTask := func() (interface{}, error) {
-		return dispatcher.actionRunner.Run(action, req.GetPayload())
+		return dispatcher.traced(req.Method, func() (interface{}, error) { return dispatcher.actionRunner.Run(action, req.GetPayload()) })
 	}
 
 	cancelTask := func(_ boshtask.Task) error { return action.Cancel() }
@@ -136,7 +143,7 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 		}
 	}
 
-	dispatcher.taskService.StartTask(task)
+	dispatcher.startTask(task, action)
 
 	return boshhandler.NewValueResponse(boshtask.StateValue{
 		AgentTaskID: task.ID,
@@ -144,13 +151,21 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	})
 }
 
+func (dispatcher concreteActionDispatcher) startTask(task boshtask.Task, action boshaction.Action) {
+	if progressReporter, ok := action.(boshaction.ProgressReporter); ok {
+		task.ProgressFunc = progressReporter.Progress
+	}
+
+	dispatcher.taskService.StartTask(task)
+}
+
 func (dispatcher concreteActionDispatcher) dispatchSynchronousAction(
 	action boshaction.Action,
 	req boshhandler.Request,
 ) boshhandler.Response {
 	dispatcher.logger.Info(actionDispatcherLogTag, "Running sync action %s", req.Method)
 
-	value, err := dispatcher.actionRunner.Run(action, req.GetPayload())
+	value, err := dispatcher.traced(req.Method, func() (interface{}, error) { return dispatcher.actionRunner.Run(action, req.GetPayload()) })
 	if err != nil {
 		err = bosherr.WrapErrorf(err, "Action Failed %s", req.Method)
 		dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
@@ -160,6 +175,17 @@ func (dispatcher concreteActionDispatcher) dispatchSynchronousAction(
 	return boshhandler.NewValueResponse(value)
 }
 
+// traced records duration and result of given action run
+func (dispatcher concreteActionDispatcher) traced(method string, run func() (interface{}, error)) (interface{}, error) {
+	span := dispatcher.telemetry.StartAction(method)
+
+	value, err := run()
+
+	span.End(err)
+
+	return value, err
+}
+
 func (dispatcher concreteActionDispatcher) removeInfo(task boshtask.Task) {
 	err := dispatcher.taskManager.RemoveInfo(task.ID)
 	if err != nil {
@@ -13,6 +13,7 @@ import (
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	faketelemetry "github.com/cloudfoundry/bosh-agent/telemetry/fakes"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
@@ -25,6 +26,7 @@ func init() {
 			taskManager   *faketask.FakeManager
 			actionFactory *fakeaction.FakeFactory
 			actionRunner  *fakeaction.FakeRunner
+			telemetry     *faketelemetry.FakeExporter
 			dispatcher    ActionDispatcher
 		)
 
@@ -34,7 +36,8 @@ func init() {
 			taskManager = faketask.NewFakeManager()
 			actionFactory = fakeaction.NewFakeFactory()
 			actionRunner = &fakeaction.FakeRunner{}
-			dispatcher = NewActionDispatcher(logger, taskService, taskManager, actionFactory, actionRunner)
+			telemetry = &faketelemetry.FakeExporter{}
+			dispatcher = NewActionDispatcher(logger, taskService, taskManager, actionFactory, actionRunner, telemetry)
 		})
 
 		It("responds with exception when the method is unknown", func() {
@@ -70,6 +73,16 @@ func init() {
 				expectedJSON := fmt.Sprintf("{\"exception\":{\"message\":\"Action Failed %s: fake-run-error\"}}", req.Method)
 				boshassert.MatchesJSONString(GinkgoT(), resp, expectedJSON)
 			})
+
+			It("traces synchronous action with its result", func() {
+				actionRunner.RunErr = errors.New("fake-run-error")
+
+				dispatcher.Dispatch(req)
+				Expect(telemetry.StartedActions).To(Equal([]string{"fake-action"}))
+				Expect(telemetry.EndedActions).To(Equal([]faketelemetry.FakeEndedAction{
+					{Name: "fake-action", Err: actionRunner.RunErr},
+				}))
+			})
 		})
 
 		Context("when action is asynchronous", func() {
@@ -154,6 +167,16 @@ func init() {
 					Expect(string(actionRunner.RunPayload)).To(Equal("fake-payload"))
 				})
 
+				It("traces action once the task runs", func() {
+					dispatcher.Dispatch(req)
+					Expect(telemetry.StartedActions).To(BeEmpty())
+
+					taskService.StartedTasks["fake-generated-task-id"].Func()
+					Expect(telemetry.EndedActions).To(Equal([]faketelemetry.FakeEndedAction{
+						{Name: "fake-action"},
+					}))
+				})
+
 				ItAllowsToCancelTask()
 
 				It("does not add task to task manager since it should not be resumed if agent is restarted", func() {
@@ -166,6 +189,26 @@ func init() {
 					dispatcher.Dispatch(req)
 					Expect(taskService.StartedTasks["fake-generated-task-id"].EndFunc).To(BeNil())
 				})
+
+				It("does not report progress when action does not report it", func() {
+					dispatcher.Dispatch(req)
+					Expect(taskService.StartedTasks["fake-generated-task-id"].ProgressFunc).To(BeNil())
+				})
+
+				Context("when action reports progress", func() {
+					BeforeEach(func() {
+						progressAction := &fakeaction.TestProgressAction{
+							TestAction:    fakeaction.TestAction{Asynchronous: true},
+							ProgressValue: "fake-progress",
+						}
+						actionFactory.RegisterAction("fake-progress-action", progressAction)
+					})
+
+					It("reports action progress as task progress", func() {
+						dispatcher.Dispatch(boshhandler.NewRequest("fake-reply", "fake-progress-action", []byte("fake-payload")))
+						Expect(taskService.StartedTasks["fake-generated-task-id"].Progress()).To(Equal("fake-progress"))
+					})
+				})
 			})
 
 			Context("when action is persistent", func() {
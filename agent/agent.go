@@ -1,17 +1,27 @@
 package agent
 
 import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pivotal-golang/clock"
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshdispatcher "github.com/cloudfoundry/bosh-agent/httpsdispatcher"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
+	boshtelemetry "github.com/cloudfoundry/bosh-agent/telemetry"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
@@ -19,6 +29,9 @@ import (
 
 const (
 	agentLogTag = "agent"
+
+	// Job supervisor may (re)start processes at any time, independently of heartbeats
+	jobProcessesInterval = 5 * time.Second
 )
 
 type Agent struct {
@@ -30,9 +43,19 @@ type Agent struct {
 	jobSupervisor     boshjobsuper.JobSupervisor
 	specService       boshas.V1Service
 	syslogServer      boshsyslog.Server
+	metricsServer     boshmetrics.Server
+	telemetryExporter boshtelemetry.Exporter
+	vitalsHistory     boshvitals.History
+	bootProfile       boshbootprofile.Profile
 	settingsService   boshsettings.Service
 	uuidGenerator     boshuuid.Generator
 	timeService       clock.Clock
+
+	// Disks whose block or inode usage exceeded their threshold and were already alerted on
+	disksOverUsageThreshold map[string]bool
+
+	// Certificates that expire within warning threshold and were already alerted on
+	certificatesNearExpiry map[string]bool
 }
 
 func New(
@@ -43,6 +66,10 @@ func New(
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
 	syslogServer boshsyslog.Server,
+	metricsServer boshmetrics.Server,
+	telemetryExporter boshtelemetry.Exporter,
+	vitalsHistory boshvitals.History,
+	bootProfile boshbootprofile.Profile,
 	heartbeatInterval time.Duration,
 	settingsService boshsettings.Service,
 	uuidGenerator boshuuid.Generator,
@@ -57,9 +84,16 @@ func New(
 		jobSupervisor:     jobSupervisor,
 		specService:       specService,
 		syslogServer:      syslogServer,
+		metricsServer:     metricsServer,
+		telemetryExporter: telemetryExporter,
+		vitalsHistory:     vitalsHistory,
+		bootProfile:       bootProfile,
 		settingsService:   settingsService,
 		uuidGenerator:     uuidGenerator,
 		timeService:       timeService,
+
+		disksOverUsageThreshold: map[string]bool{},
+		certificatesNearExpiry:  map[string]bool{},
 	}
 }
 
@@ -72,6 +106,8 @@ func (a Agent) Run() error {
 
 	go a.generateHeartbeats(errCh)
 
+	go a.manageJobProcesses()
+
 	go func() {
 		err := a.jobSupervisor.MonitorJobFailures(a.handleJobFailure(errCh))
 		if err != nil {
@@ -86,6 +122,20 @@ func (a Agent) Run() error {
 		}
 	}()
 
+	go func() {
+		err := a.metricsServer.Start()
+		if err != nil {
+			a.logger.Warn(agentLogTag, "Failed to start metricsServer: %s", err.Error())
+		}
+	}()
+
+	go func() {
+		err := a.telemetryExporter.Start()
+		if err != nil {
+			a.logger.Warn(agentLogTag, "Failed to start telemetryExporter: %s", err.Error())
+		}
+	}()
+
 	select {
 	case err := <-errCh:
 		return err
@@ -107,20 +157,20 @@ func (a Agent) generateHeartbeats(errCh chan error) {
 	a.logger.Debug(agentLogTag, "Generating heartbeat")
 	defer a.logger.HandlePanic("Agent Generate Heartbeats")
 
-	// Send initial heartbeat
-	a.sendHeartbeat(errCh)
+	// Send initial heartbeat with time bootstrap took
+	a.sendHeartbeat(errCh, a.bootProfile.Summary())
 
 	tickChan := time.Tick(a.heartbeatInterval)
 
 	for {
 		select {
 		case <-tickChan:
-			a.sendHeartbeat(errCh)
+			a.sendHeartbeat(errCh, nil)
 		}
 	}
 }
 
-func (a Agent) sendHeartbeat(errCh chan error) {
+func (a Agent) sendHeartbeat(errCh chan error, boot *boshbootprofile.Summary) {
 	heartbeat, err := a.getHeartbeat()
 	if err != nil {
 		err = bosherr.WrapError(err, "Building heartbeat")
@@ -128,13 +178,285 @@ func (a Agent) sendHeartbeat(errCh chan error) {
 		return
 	}
 
+	heartbeat.Boot = boot
+
+	// Recorded before sending so that history covers periods when health monitor is unreachable
+	err = a.vitalsHistory.Record(heartbeat.Vitals)
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Recording vitals history: %s", err.Error())
+	}
+
 	err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Heartbeat, heartbeat)
 	if err != nil {
 		err = bosherr.WrapError(err, "Sending heartbeat")
 		errCh <- err
+		return
+	}
+
+	a.alertOnDiskUsage(heartbeat.Vitals, errCh)
+	a.alertOnCertificateExpiry(heartbeat.Vitals, errCh)
+}
+
+// manageJobProcesses periodically picks up processes (re)started by job supervisor
+// regardless of whether heartbeats can be sent
+func (a Agent) manageJobProcesses() {
+	defer a.logger.HandlePanic("Agent Manage Job Processes")
+
+	ticker := a.timeService.NewTicker(jobProcessesInterval)
+	defer ticker.Stop()
+
+	for {
+		a.addJobProcessesToCgroups()
+		a.trackJobProcessGroups()
+
+		<-ticker.C()
+	}
+}
+
+// trackJobProcessGroups records process groups of processes that were (re)started
+// by job supervisor since the last check so that their orphans can be killed on stop
+func (a Agent) trackJobProcessGroups() {
+	spec, err := a.specService.Get()
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Getting apply spec to track job process groups: %s", err.Error())
+		return
+	}
+
+	jobNames := []string{}
+	for _, job := range spec.Jobs() {
+		jobNames = append(jobNames, job.Name)
+	}
+
+	if len(jobNames) == 0 {
+		return
+	}
+
+	err = a.platform.TrackJobProcessGroups(jobNames)
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Tracking job process groups: %s", err.Error())
+	}
+}
+
+// addJobProcessesToCgroups is a backstop for job supervisors that cannot start processes
+// in cgroups of jobs with resource limits, e.g. monit, and for processes that escaped them
+func (a Agent) addJobProcessesToCgroups() {
+	spec, err := a.specService.Get()
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Getting apply spec to add job processes to cgroups: %s", err.Error())
+		return
+	}
+
+	jobNames := []string{}
+	for jobName := range spec.JobResourceLimits() {
+		jobNames = append(jobNames, jobName)
+	}
+
+	if len(jobNames) == 0 {
+		return
+	}
+
+	err = a.platform.AddJobProcessesToCgroups(jobNames)
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Adding job processes to cgroups: %s", err.Error())
+	}
+}
+
+// alertOnDiskUsage sends an alert once a disk's block or inode usage exceeds its configured
+// threshold; the disk is alerted on again only after its usage dropped below the threshold
+func (a Agent) alertOnDiskUsage(vitals boshvitals.Vitals, errCh chan error) {
+	thresholds := a.settingsService.GetSettings().Env.GetDiskUsageThresholds()
+
+	diskNames := []string{}
+	for diskName := range thresholds {
+		diskNames = append(diskNames, diskName)
+	}
+	sort.Strings(diskNames)
+
+	for _, diskName := range diskNames {
+		diskVitals, found := vitals.Disk[diskName]
+		if !found {
+			continue
+		}
+
+		usages := []struct {
+			percent string
+			inodes  bool
+		}{
+			{diskVitals.Percent, false},
+			{diskVitals.InodePercent, true},
+		}
+
+		for _, usage := range usages {
+			// Inode usage is not reported for file systems without fixed number of inodes
+			if usage.inodes && usage.percent == "" {
+				continue
+			}
+
+			percent, err := strconv.Atoi(usage.percent)
+			if err != nil {
+				a.logger.Warn(agentLogTag, "Parsing %s disk usage '%s': %s", diskName, usage.percent, err.Error())
+				continue
+			}
+
+			diskUsage := boshalert.DiskUsage{Disk: diskName, Percent: percent, Threshold: thresholds[diskName], Inodes: usage.inodes}
+
+			alertedKey := diskName
+			if usage.inodes {
+				alertedKey += " inodes"
+			}
+
+			alertAdapter := boshalert.NewDiskUsageAdapter(diskUsage, a.settingsService, a.uuidGenerator, a.timeService)
+
+			err = a.alertOnceOverThreshold(a.disksOverUsageThreshold, alertedKey, alertAdapter, "disk usage")
+			if err != nil {
+				errCh <- err
+				return
+			}
+		}
+	}
+}
+
+// alertOnceOverThreshold sends an alert when adapter is no longer ignorable and remembers it
+// in alerted so that it is sent again only after adapter became ignorable in between
+func (a Agent) alertOnceOverThreshold(alerted map[string]bool, key string, alertAdapter boshalert.Adapter, kind string) error {
+	if alertAdapter.IsIgnorable() {
+		delete(alerted, key)
+		return nil
+	}
+
+	if alerted[key] {
+		return nil
+	}
+
+	alert, err := alertAdapter.Alert()
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Adapting %s alert", kind)
+	}
+
+	err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Sending %s alert", kind)
+	}
+
+	alerted[key] = true
+
+	return nil
+}
+
+// alertOnCertificateExpiry sends an alert once a certificate expires within the warning
+// threshold; the certificate is alerted on again only after it was rotated
+func (a Agent) alertOnCertificateExpiry(vitals boshvitals.Vitals, errCh chan error) {
+	threshold := a.settingsService.GetSettings().Env.GetCertificateExpiryWarningDays()
+
+	certificateNames := []string{}
+	for certificateName := range vitals.Certificates {
+		certificateNames = append(certificateNames, certificateName)
+	}
+	sort.Strings(certificateNames)
+
+	for certificateName := range a.certificatesNearExpiry {
+		if _, found := vitals.Certificates[certificateName]; !found {
+			delete(a.certificatesNearExpiry, certificateName)
+		}
+	}
+
+	for _, certificateName := range certificateNames {
+		certificateVitals := vitals.Certificates[certificateName]
+
+		daysToExpiry, err := strconv.Atoi(certificateVitals.DaysToExpiry)
+		if err != nil {
+			a.logger.Warn(agentLogTag, "Parsing %s certificate days to expiry '%s': %s", certificateName, certificateVitals.DaysToExpiry, err.Error())
+			continue
+		}
+
+		certificateExpiry := boshalert.CertificateExpiry{
+			Certificate:  certificateName,
+			Subject:      certificateVitals.Subject,
+			DaysToExpiry: daysToExpiry,
+			Threshold:    threshold,
+		}
+
+		alertAdapter := boshalert.NewCertificateExpiryAdapter(certificateExpiry, a.settingsService, a.uuidGenerator, a.timeService)
+
+		err = a.alertOnceOverThreshold(a.certificatesNearExpiry, certificateName, alertAdapter, "certificate expiry")
+		if err != nil {
+			errCh <- err
+			return
+		}
 	}
 }
 
+// certificateVitals reports days until https mbus certificate
+// and trusted certificates from the director expire
+func (a Agent) certificateVitals() boshvitals.CertificateVitals {
+	settings := a.settingsService.GetSettings()
+	expiries := map[string]boshcert.Expiry{}
+
+	if strings.HasPrefix(settings.Mbus, "https://") && a.platform.GetFs().FileExists(boshdispatcher.CertificatePath) {
+		mbusCert, err := a.platform.GetFs().ReadFileString(boshdispatcher.CertificatePath)
+		if err != nil {
+			a.logger.Warn(agentLogTag, "Reading mbus certificate: %s", err.Error())
+		}
+
+		for _, expiry := range boshcert.Expiries(mbusCert) {
+			expiries["mbus"] = expiry
+			break
+		}
+	}
+
+	for _, expiry := range boshcert.Expiries(settings.TrustedCerts) {
+		expiries["trusted_"+expiry.Fingerprint[:16]] = expiry
+	}
+
+	if len(expiries) == 0 {
+		return nil
+	}
+
+	now := a.timeService.Now()
+	certificateVitals := boshvitals.CertificateVitals{}
+
+	for name, expiry := range expiries {
+		daysToExpiry := int(math.Floor(expiry.NotAfter.Sub(now).Hours() / 24))
+
+		certificateVitals[name] = boshvitals.SpecificCertificateVitals{
+			Subject:      expiry.Subject,
+			DaysToExpiry: strconv.Itoa(daysToExpiry),
+		}
+	}
+
+	return certificateVitals
+}
+
+// processVitals does not fail heartbeat since jobs may not be supervised yet
+func (a Agent) processVitals() boshvitals.ProcessVitals {
+	processes, err := a.jobSupervisor.Processes()
+	if err != nil {
+		a.logger.Warn(agentLogTag, "Getting processes: %s", err.Error())
+		return nil
+	}
+
+	if len(processes) == 0 {
+		return nil
+	}
+
+	processVitals := make(boshvitals.ProcessVitals, len(processes))
+
+	for _, process := range processes {
+		processVitals[process.Name] = boshvitals.SpecificProcessVitals{
+			State: process.State,
+			CPU:   strconv.FormatFloat(process.CPU.Total, 'f', 1, 64),
+			Mem: boshvitals.MemoryVitals{
+				Percent: strconv.FormatFloat(process.Memory.Percent, 'f', 1, 64),
+				Kb:      strconv.Itoa(process.Memory.Kb),
+			},
+			OpenFDs:    strconv.Itoa(process.FD.Open),
+			UptimeSecs: strconv.Itoa(process.Uptime.Secs),
+		}
+	}
+
+	return processVitals
+}
+
 func (a Agent) getHeartbeat() (Heartbeat, error) {
 	a.logger.Debug(agentLogTag, "Building heartbeat")
 	vitalsService := a.platform.GetVitalsService()
@@ -144,6 +466,9 @@ func (a Agent) getHeartbeat() (Heartbeat, error) {
 		return Heartbeat{}, bosherr.WrapError(err, "Getting job vitals")
 	}
 
+	vitals.Certificates = a.certificateVitals()
+	vitals.Processes = a.processVitals()
+
 	spec, err := a.specService.Get()
 	if err != nil {
 		return Heartbeat{}, bosherr.WrapError(err, "Getting job spec")
@@ -1,5 +1,13 @@
 package alert
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
 type SeverityLevel int
 
 const (
@@ -23,3 +31,15 @@ type Adapter interface {
 	Alert() (Alert, error)
 	IsIgnorable() bool
 }
+
+// instanceSubject adds IPs of the instance to the subject of an alert title, e.g. 'system disk (10.0.0.6)'
+func instanceSubject(settingsService boshsettings.Service, subject string) string {
+	ips := settingsService.GetSettings().Networks.IPs()
+	sort.Strings(ips)
+
+	if len(ips) > 0 {
+		subject = fmt.Sprintf("%s (%s)", subject, strings.Join(ips, ", "))
+	}
+
+	return subject
+}
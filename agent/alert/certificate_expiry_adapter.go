@@ -0,0 +1,78 @@
+package alert
+
+import (
+	"fmt"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
+)
+
+type CertificateExpiry struct {
+	// e.g. mbus, trusted_0123456789abcdef
+	Certificate  string
+	Subject      string
+	DaysToExpiry int
+	Threshold    int
+}
+
+type certificateExpiryAdapter struct {
+	certificateExpiry CertificateExpiry
+	settingsService   boshsettings.Service
+	uuidGenerator     boshuuid.Generator
+	timeService       clock.Clock
+}
+
+func NewCertificateExpiryAdapter(
+	certificateExpiry CertificateExpiry,
+	settingsService boshsettings.Service,
+	uuidGenerator boshuuid.Generator,
+	timeService clock.Clock,
+) Adapter {
+	return &certificateExpiryAdapter{
+		certificateExpiry: certificateExpiry,
+		settingsService:   settingsService,
+		uuidGenerator:     uuidGenerator,
+		timeService:       timeService,
+	}
+}
+
+func (m *certificateExpiryAdapter) IsIgnorable() bool {
+	return m.certificateExpiry.Threshold <= 0 || m.certificateExpiry.DaysToExpiry >= m.certificateExpiry.Threshold
+}
+
+func (m *certificateExpiryAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	return Alert{
+		ID:        uuid,
+		Severity:  SeverityWarning,
+		Title:     m.title(),
+		Summary:   m.summary(),
+		CreatedAt: m.timeService.Now().Unix(),
+	}, nil
+}
+
+func (m *certificateExpiryAdapter) summary() string {
+	certificate := fmt.Sprintf("%s certificate", m.certificateExpiry.Certificate)
+
+	if m.certificateExpiry.Subject != "" {
+		certificate = fmt.Sprintf("%s '%s'", certificate, m.certificateExpiry.Subject)
+	}
+
+	if m.certificateExpiry.DaysToExpiry < 0 {
+		return fmt.Sprintf("%s expired %d days ago", certificate, -m.certificateExpiry.DaysToExpiry)
+	}
+
+	return fmt.Sprintf("%s expires in %d days which is below threshold of %d days", certificate, m.certificateExpiry.DaysToExpiry, m.certificateExpiry.Threshold)
+}
+
+func (m *certificateExpiryAdapter) title() string {
+	certificate := instanceSubject(m.settingsService, m.certificateExpiry.Certificate+" certificate")
+
+	return fmt.Sprintf("%s - expiry threshold reached", certificate)
+}
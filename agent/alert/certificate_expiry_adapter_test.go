@@ -0,0 +1,89 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("certificateExpiryAdapter", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		timeService     *fakeclock.FakeClock
+		uuidGenerator   *fakeuuid.FakeGenerator
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{}
+		timeService = fakeclock.NewFakeClock(time.Now())
+		uuidGenerator = &fakeuuid.FakeGenerator{}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("does not ignore certificates that expire in fewer days than threshold", func() {
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "mbus", DaysToExpiry: 29, Threshold: 30}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+		})
+
+		It("ignores certificates that expire at or after threshold", func() {
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "mbus", DaysToExpiry: 30, Threshold: 30}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+
+		It("ignores certificates when threshold is disabled", func() {
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "mbus", DaysToExpiry: -1, Threshold: -1}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+	})
+
+	Describe("Alert", func() {
+		BeforeEach(func() {
+			uuidGenerator.GeneratedUUID = "fake-uuid"
+			settingsService.Settings.Networks = boshsettings.Networks{
+				"fake-net1": boshsettings.Network{IP: "10.0.0.2"},
+				"fake-net2": boshsettings.Network{IP: "10.0.0.1"},
+			}
+		})
+
+		It("returns warning alert describing certificate expiry", func() {
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "mbus", Subject: "fake-agent", DaysToExpiry: 7, Threshold: 30}, settingsService, uuidGenerator, timeService)
+
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "mbus certificate (10.0.0.1, 10.0.0.2) - expiry threshold reached",
+				Summary:   "mbus certificate 'fake-agent' expires in 7 days which is below threshold of 30 days",
+				CreatedAt: timeService.Now().Unix(),
+			}))
+		})
+
+		It("describes certificates that already expired", func() {
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "trusted_0123456789abcdef", DaysToExpiry: -2, Threshold: 30}, settingsService, uuidGenerator, timeService)
+
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert.Summary).To(Equal("trusted_0123456789abcdef certificate expired 2 days ago"))
+		})
+
+		It("returns error if generating alert id fails", func() {
+			uuidGenerator.GenerateError = errors.New("fake-generate-err")
+
+			adapter := NewCertificateExpiryAdapter(CertificateExpiry{Certificate: "mbus", DaysToExpiry: 7, Threshold: 30}, settingsService, uuidGenerator, timeService)
+
+			_, err := adapter.Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-generate-err"))
+		})
+	})
+})
@@ -0,0 +1,75 @@
+package alert
+
+import (
+	"fmt"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+	"github.com/pivotal-golang/clock"
+)
+
+type DiskUsage struct {
+	// e.g. system, ephemeral, persistent
+	Disk      string
+	Percent   int
+	Threshold int
+
+	// Percent is of inodes rather than blocks, e.g. when many small files fill a disk
+	Inodes bool
+}
+
+type diskUsageAdapter struct {
+	diskUsage       DiskUsage
+	settingsService boshsettings.Service
+	uuidGenerator   boshuuid.Generator
+	timeService     clock.Clock
+}
+
+func NewDiskUsageAdapter(
+	diskUsage DiskUsage,
+	settingsService boshsettings.Service,
+	uuidGenerator boshuuid.Generator,
+	timeService clock.Clock,
+) Adapter {
+	return &diskUsageAdapter{
+		diskUsage:       diskUsage,
+		settingsService: settingsService,
+		uuidGenerator:   uuidGenerator,
+		timeService:     timeService,
+	}
+}
+
+func (m *diskUsageAdapter) IsIgnorable() bool {
+	return m.diskUsage.Threshold <= 0 || m.diskUsage.Percent < m.diskUsage.Threshold
+}
+
+func (m *diskUsageAdapter) Alert() (Alert, error) {
+	uuid, err := m.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating uuid")
+	}
+
+	summary := fmt.Sprintf("%s disk is %d%% full which exceeds threshold of %d%%", m.diskUsage.Disk, m.diskUsage.Percent, m.diskUsage.Threshold)
+	if m.diskUsage.Inodes {
+		summary = fmt.Sprintf("%s disk has %d%% of inodes used which exceeds threshold of %d%%", m.diskUsage.Disk, m.diskUsage.Percent, m.diskUsage.Threshold)
+	}
+
+	return Alert{
+		ID:        uuid,
+		Severity:  SeverityWarning,
+		Title:     m.title(),
+		Summary:   summary,
+		CreatedAt: m.timeService.Now().Unix(),
+	}, nil
+}
+
+func (m *diskUsageAdapter) title() string {
+	disk := instanceSubject(m.settingsService, m.diskUsage.Disk+" disk")
+
+	if m.diskUsage.Inodes {
+		return fmt.Sprintf("%s - inode usage threshold exceeded", disk)
+	}
+
+	return fmt.Sprintf("%s - usage threshold exceeded", disk)
+}
@@ -0,0 +1,95 @@
+package alert_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("diskUsageAdapter", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		timeService     *fakeclock.FakeClock
+		uuidGenerator   *fakeuuid.FakeGenerator
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{}
+		timeService = fakeclock.NewFakeClock(time.Now())
+		uuidGenerator = &fakeuuid.FakeGenerator{}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("does not ignore usage at or above threshold", func() {
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "persistent", Percent: 90, Threshold: 90}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeFalse())
+		})
+
+		It("ignores usage below threshold", func() {
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "persistent", Percent: 89, Threshold: 90}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+
+		It("ignores usage when threshold is not set", func() {
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "persistent", Percent: 100}, settingsService, uuidGenerator, timeService)
+			Expect(adapter.IsIgnorable()).To(BeTrue())
+		})
+	})
+
+	Describe("Alert", func() {
+		It("returns warning alert describing disk usage", func() {
+			uuidGenerator.GeneratedUUID = "fake-uuid"
+			settingsService.Settings.Networks = boshsettings.Networks{
+				"fake-net1": boshsettings.Network{IP: "10.0.0.2"},
+				"fake-net2": boshsettings.Network{IP: "10.0.0.1"},
+			}
+
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "ephemeral", Percent: 85, Threshold: 80}, settingsService, uuidGenerator, timeService)
+
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "ephemeral disk (10.0.0.1, 10.0.0.2) - usage threshold exceeded",
+				Summary:   "ephemeral disk is 85% full which exceeds threshold of 80%",
+				CreatedAt: timeService.Now().Unix(),
+			}))
+		})
+
+		It("returns warning alert describing inode usage", func() {
+			uuidGenerator.GeneratedUUID = "fake-uuid"
+
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "persistent", Percent: 95, Threshold: 90, Inodes: true}, settingsService, uuidGenerator, timeService)
+
+			alert, err := adapter.Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "persistent disk - inode usage threshold exceeded",
+				Summary:   "persistent disk has 95% of inodes used which exceeds threshold of 90%",
+				CreatedAt: timeService.Now().Unix(),
+			}))
+		})
+
+		It("returns error if generating alert id fails", func() {
+			uuidGenerator.GenerateError = errors.New("fake-generate-err")
+
+			adapter := NewDiskUsageAdapter(DiskUsage{Disk: "ephemeral", Percent: 85, Threshold: 80}, settingsService, uuidGenerator, timeService)
+
+			_, err := adapter.Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-generate-err"))
+		})
+	})
+})
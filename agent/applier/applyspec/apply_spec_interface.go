@@ -2,10 +2,17 @@ package applyspec
 
 import (
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 )
 
 type ApplySpec interface {
 	Jobs() []models.Job
 	Packages() []models.Package
 	MaxLogFileSize() string
+	EphemeralDiskQuotas() map[string]uint64
+	FirewallPorts() map[string][]boshfirewall.Port
+	JobResourceLimits() map[string]boshcgroup.Limits
+	JobDependencies() map[string][]string
+	JobConcurrency() int
 }
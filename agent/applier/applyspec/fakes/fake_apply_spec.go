@@ -2,12 +2,19 @@ package fakes
 
 import (
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 )
 
 type FakeApplySpec struct {
-	JobResults           []models.Job
-	PackageResults       []models.Package
-	MaxLogFileSizeResult string
+	JobResults                []models.Job
+	PackageResults            []models.Package
+	MaxLogFileSizeResult      string
+	EphemeralDiskQuotasResult map[string]uint64
+	FirewallPortsResult       map[string][]boshfirewall.Port
+	JobResourceLimitsResult   map[string]boshcgroup.Limits
+	JobDependenciesResult     map[string][]string
+	JobConcurrencyResult      int
 }
 
 func (s FakeApplySpec) Jobs() []models.Job {
@@ -21,3 +28,23 @@ func (s FakeApplySpec) Packages() []models.Package {
 func (s FakeApplySpec) MaxLogFileSize() string {
 	return s.MaxLogFileSizeResult
 }
+
+func (s FakeApplySpec) EphemeralDiskQuotas() map[string]uint64 {
+	return s.EphemeralDiskQuotasResult
+}
+
+func (s FakeApplySpec) FirewallPorts() map[string][]boshfirewall.Port {
+	return s.FirewallPortsResult
+}
+
+func (s FakeApplySpec) JobResourceLimits() map[string]boshcgroup.Limits {
+	return s.JobResourceLimitsResult
+}
+
+func (s FakeApplySpec) JobDependencies() map[string][]string {
+	return s.JobDependenciesResult
+}
+
+func (s FakeApplySpec) JobConcurrency() int {
+	return s.JobConcurrencyResult
+}
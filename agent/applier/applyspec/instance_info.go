@@ -0,0 +1,86 @@
+package applyspec
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Both files are written into the instance directory on apply
+	InstanceInfoFileName = "instance.json"
+	InstanceEnvFileName  = "env"
+)
+
+// InstanceInfo is the subset of the apply spec that identifies
+// the instance to jobs running on it.
+type InstanceInfo struct {
+	ID         string            `json:"id"`
+	AZ         string            `json:"az"`
+	Name       string            `json:"name"`
+	Deployment string            `json:"deployment"`
+	Index      *int              `json:"index"`
+	Tags       map[string]string `json:"tags"`
+}
+
+func (s V1ApplySpec) InstanceInfo() InstanceInfo {
+	tags := s.Tags
+	if tags == nil {
+		tags = map[string]string{}
+	}
+
+	return InstanceInfo{
+		ID:         s.NodeID,
+		AZ:         s.AvailabilityZone,
+		Name:       s.Name,
+		Deployment: s.Deployment,
+		Index:      s.Index,
+		Tags:       tags,
+	}
+}
+
+// Env returns environment variables that are injected
+// into job scripts run by the agent.
+func (i InstanceInfo) Env() map[string]string {
+	env := map[string]string{}
+
+	fields := map[string]string{
+		"BOSH_INSTANCE_ID":   i.ID,
+		"BOSH_INSTANCE_NAME": i.Name,
+		"BOSH_DEPLOYMENT":    i.Deployment,
+		"BOSH_AZ":            i.AZ,
+	}
+
+	for name, value := range fields {
+		if value != "" {
+			env[name] = value
+		}
+	}
+
+	if i.Index != nil {
+		env["BOSH_INSTANCE_INDEX"] = strconv.Itoa(*i.Index)
+	}
+
+	return env
+}
+
+// EnvFileContents returns Env as a shell script
+// that can be sourced by job control scripts.
+func (i InstanceInfo) EnvFileContents() string {
+	env := i.Env()
+
+	names := []string{}
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lines := []string{}
+	for _, name := range names {
+		quoted := strings.Replace(env[name], "'", `'\''`, -1)
+		lines = append(lines, fmt.Sprintf("export %s='%s'", name, quoted))
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
@@ -0,0 +1,68 @@
+package applyspec_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+)
+
+var _ = Describe("InstanceInfo", func() {
+	var (
+		index int
+		spec  V1ApplySpec
+	)
+
+	BeforeEach(func() {
+		index = 2
+		spec = V1ApplySpec{
+			NodeID:           "fake-id",
+			AvailabilityZone: "fake-az",
+			Name:             "fake-name",
+			Deployment:       "fake-deployment",
+			Index:            &index,
+			Tags:             map[string]string{"team": "fake-team"},
+		}
+	})
+
+	It("is built from the apply spec", func() {
+		Expect(spec.InstanceInfo()).To(Equal(InstanceInfo{
+			ID:         "fake-id",
+			AZ:         "fake-az",
+			Name:       "fake-name",
+			Deployment: "fake-deployment",
+			Index:      &index,
+			Tags:       map[string]string{"team": "fake-team"},
+		}))
+	})
+
+	Describe("Env", func() {
+		It("returns identifying environment variables", func() {
+			Expect(spec.InstanceInfo().Env()).To(Equal(map[string]string{
+				"BOSH_INSTANCE_ID":    "fake-id",
+				"BOSH_INSTANCE_NAME":  "fake-name",
+				"BOSH_INSTANCE_INDEX": "2",
+				"BOSH_DEPLOYMENT":     "fake-deployment",
+				"BOSH_AZ":             "fake-az",
+			}))
+		})
+
+		It("omits fields that are not set", func() {
+			Expect(V1ApplySpec{NodeID: "fake-id"}.InstanceInfo().Env()).To(Equal(map[string]string{
+				"BOSH_INSTANCE_ID": "fake-id",
+			}))
+		})
+	})
+
+	Describe("EnvFileContents", func() {
+		It("returns sorted quoted exports", func() {
+			spec.Deployment = "it's-deployment"
+			Expect(spec.InstanceInfo().EnvFileContents()).To(Equal(`export BOSH_AZ='fake-az'
+export BOSH_DEPLOYMENT='it'\''s-deployment'
+export BOSH_INSTANCE_ID='fake-id'
+export BOSH_INSTANCE_INDEX='2'
+export BOSH_INSTANCE_NAME='fake-name'
+`))
+		})
+	})
+})
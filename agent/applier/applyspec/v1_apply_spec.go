@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 )
 
 type V1ApplySpec struct {
@@ -23,6 +25,8 @@ type V1ApplySpec struct {
 	NodeID           string `json:"id"`
 	AvailabilityZone string `json:"az"`
 
+	Tags map[string]string `json:"tags,omitempty"`
+
 	PersistentDisk int `json:"persistent_disk"`
 
 	RenderedTemplatesArchiveSpec RenderedTemplatesArchiveSpec `json:"rendered_templates_archive"`
@@ -30,6 +34,32 @@ type V1ApplySpec struct {
 
 type PropertiesSpec struct {
 	LoggingSpec LoggingSpec `json:"logging"`
+
+	// Maximum ephemeral disk usage in MB keyed by job name
+	EphemeralDiskQuotas map[string]uint64 `json:"ephemeral_disk_quotas,omitempty"`
+
+	// Inbound ports allowed through the firewall keyed by job name
+	FirewallPorts map[string][]boshfirewall.Port `json:"firewall_ports,omitempty"`
+
+	// cgroup limits of job processes keyed by job name
+	JobResourceLimits map[string]ResourceLimitsSpec `json:"job_resource_limits,omitempty"`
+
+	// Jobs that have to be healthy before the job is started keyed by job name
+	JobDependencies map[string][]string `json:"job_dependencies,omitempty"`
+
+	// Maximum number of jobs started or stopped at once when jobs do not depend on each other
+	JobConcurrency int `json:"job_concurrency,omitempty"`
+}
+
+type ResourceLimitsSpec struct {
+	// Relative CPU weight with cpu.shares semantics (2-262144)
+	CPUShares uint64 `json:"cpu_shares,omitempty"`
+
+	// Percent of a single CPU, e.g. 150 allows 1.5 CPUs
+	CPUQuotaPercent uint64 `json:"cpu_quota_percent,omitempty"`
+
+	MemoryMB uint64 `json:"memory_mb,omitempty"`
+	MaxPIDs  uint64 `json:"max_pids,omitempty"`
 }
 
 type LoggingSpec struct {
@@ -79,6 +109,38 @@ func (s V1ApplySpec) MaxLogFileSize() string {
 	return "50M"
 }
 
+func (s V1ApplySpec) EphemeralDiskQuotas() map[string]uint64 {
+	return s.PropertiesSpec.EphemeralDiskQuotas
+}
+
+func (s V1ApplySpec) FirewallPorts() map[string][]boshfirewall.Port {
+	return s.PropertiesSpec.FirewallPorts
+}
+
+func (s V1ApplySpec) JobResourceLimits() map[string]boshcgroup.Limits {
+	jobLimits := map[string]boshcgroup.Limits{}
+
+	for jobName, spec := range s.PropertiesSpec.JobResourceLimits {
+		jobLimits[jobName] = boshcgroup.Limits{
+			MemoryBytes:     spec.MemoryMB * 1024 * 1024,
+			CPUShares:       spec.CPUShares,
+			CPUQuotaPercent: spec.CPUQuotaPercent,
+			MaxPIDs:         spec.MaxPIDs,
+		}
+	}
+
+	return jobLimits
+}
+
+func (s V1ApplySpec) JobDependencies() map[string][]string {
+	return s.PropertiesSpec.JobDependencies
+}
+
+// JobConcurrency returns 0 when all jobs may be started or stopped at once
+func (s V1ApplySpec) JobConcurrency() int {
+	return s.PropertiesSpec.JobConcurrency
+}
+
 func (s NetworkSpec) PopulateIPInfo(ip, netmask, gateway string) NetworkSpec {
 	if s.Fields == nil {
 		s.Fields = map[string]interface{}{}
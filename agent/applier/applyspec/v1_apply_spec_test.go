@@ -8,6 +8,8 @@ import (
 
 	. "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 )
 
 var _ = Describe("V1ApplySpec", func() {
@@ -273,6 +275,85 @@ var _ = Describe("V1ApplySpec", func() {
 			Expect(spec.MaxLogFileSize()).To(Equal("fake-size"))
 		})
 	})
+
+	Describe("EphemeralDiskQuotas", func() {
+		It("returns quotas provided in properties", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{"properties": {"ephemeral_disk_quotas": {"router": 2048}}}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.EphemeralDiskQuotas()).To(Equal(map[string]uint64{"router": 2048}))
+		})
+
+		It("returns no quotas if they are not provided", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.EphemeralDiskQuotas()).To(BeEmpty())
+		})
+	})
+
+	Describe("FirewallPorts", func() {
+		It("returns ports provided in properties", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{"properties": {"firewall_ports": {"router": [{"port": 80}, {"protocol": "udp", "port": 53}]}}}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.FirewallPorts()).To(Equal(map[string][]boshfirewall.Port{
+				"router": {{Port: 80}, {Protocol: "udp", Port: 53}},
+			}))
+		})
+
+		It("returns no ports if they are not provided", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.FirewallPorts()).To(BeEmpty())
+		})
+	})
+
+	Describe("JobResourceLimits", func() {
+		It("returns cgroup limits provided in properties", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{"properties": {"job_resource_limits": {"router": {"cpu_shares": 512, "cpu_quota_percent": 150, "memory_mb": 256, "max_pids": 100}}}}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.JobResourceLimits()).To(Equal(map[string]boshcgroup.Limits{
+				"router": {MemoryBytes: 256 * 1024 * 1024, CPUShares: 512, CPUQuotaPercent: 150, MaxPIDs: 100},
+			}))
+		})
+
+		It("returns no limits if they are not provided", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.JobResourceLimits()).To(BeEmpty())
+		})
+	})
+
+	Describe("JobDependencies", func() {
+		It("returns job dependencies provided in properties", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{"properties": {"job_dependencies": {"web": ["db", "cache"]}}}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.JobDependencies()).To(Equal(map[string][]string{"web": {"db", "cache"}}))
+		})
+
+		It("returns no dependencies if they are not provided", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.JobDependencies()).To(BeEmpty())
+		})
+	})
+
+	Describe("JobConcurrency", func() {
+		It("returns job concurrency provided in properties", func() {
+			spec := V1ApplySpec{}
+			err := json.Unmarshal([]byte(`{"properties": {"job_concurrency": 4}}`), &spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(spec.JobConcurrency()).To(Equal(4))
+		})
+
+		It("returns 0 if it is not provided", func() {
+			spec := V1ApplySpec{}
+			Expect(spec.JobConcurrency()).To(Equal(0))
+		})
+	})
 })
 
 var _ = Describe("NetworkSpec", func() {
@@ -0,0 +1,9 @@
+package applier
+
+import (
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+)
+
+type CgroupDelegate interface {
+	SetupJobCgroups(jobLimits map[string]boshcgroup.Limits) (err error)
+}
@@ -1,6 +1,8 @@
 package applier
 
 import (
+	"sort"
+
 	as "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/jobs"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/packages"
@@ -14,6 +16,9 @@ type concreteApplier struct {
 	jobApplier        jobs.Applier
 	packageApplier    packages.Applier
 	logrotateDelegate LogrotateDelegate
+	diskQuotaDelegate DiskQuotaDelegate
+	firewallDelegate  FirewallDelegate
+	cgroupDelegate    CgroupDelegate
 	jobSupervisor     boshjobsuper.JobSupervisor
 	dirProvider       boshdirs.Provider
 }
@@ -22,6 +27,9 @@ func NewConcreteApplier(
 	jobApplier jobs.Applier,
 	packageApplier packages.Applier,
 	logrotateDelegate LogrotateDelegate,
+	diskQuotaDelegate DiskQuotaDelegate,
+	firewallDelegate FirewallDelegate,
+	cgroupDelegate CgroupDelegate,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	dirProvider boshdirs.Provider,
 ) Applier {
@@ -29,6 +37,9 @@ func NewConcreteApplier(
 		jobApplier:        jobApplier,
 		packageApplier:    packageApplier,
 		logrotateDelegate: logrotateDelegate,
+		diskQuotaDelegate: diskQuotaDelegate,
+		firewallDelegate:  firewallDelegate,
+		cgroupDelegate:    cgroupDelegate,
 		jobSupervisor:     jobSupervisor,
 		dirProvider:       dirProvider,
 	}
@@ -83,6 +94,42 @@ func (a *concreteApplier) Apply(currentApplySpec, desiredApplySpec as.ApplySpec)
 		return bosherr.WrapError(err, "Keeping only needed packages")
 	}
 
+	err = a.diskQuotaDelegate.SetupJobDiskQuotas(desiredApplySpec.EphemeralDiskQuotas())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up job disk quotas")
+	}
+
+	err = a.firewallDelegate.SetupJobFirewallPorts(desiredApplySpec.FirewallPorts())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up job firewall ports")
+	}
+
+	err = a.cgroupDelegate.SetupJobCgroups(desiredApplySpec.JobResourceLimits())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up job cgroups")
+	}
+
+	cgroupJobNames := []string{}
+	for jobName := range desiredApplySpec.JobResourceLimits() {
+		cgroupJobNames = append(cgroupJobNames, jobName)
+	}
+	sort.Strings(cgroupJobNames)
+
+	err = a.jobSupervisor.SetJobCgroups(cgroupJobNames)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job cgroups")
+	}
+
+	err = a.jobSupervisor.SetJobDependencies(desiredApplySpec.JobDependencies())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job dependencies")
+	}
+
+	err = a.jobSupervisor.SetJobConcurrency(desiredApplySpec.JobConcurrency())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job concurrency")
+	}
+
 	err = a.jobSupervisor.Reload()
 	if err != nil {
 		return bosherr.WrapError(err, "Reloading jobSupervisor")
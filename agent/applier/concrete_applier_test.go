@@ -14,6 +14,8 @@ import (
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	fakepackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages/fakes"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
@@ -35,6 +37,36 @@ func (d *FakeLogRotateDelegate) SetupLogrotate(groupName, basePath, size string)
 	return d.SetupLogrotateErr
 }
 
+type FakeDiskQuotaDelegate struct {
+	SetupJobDiskQuotasErr        error
+	SetupJobDiskQuotasQuotasInMB map[string]uint64
+}
+
+func (d *FakeDiskQuotaDelegate) SetupJobDiskQuotas(quotasInMB map[string]uint64) error {
+	d.SetupJobDiskQuotasQuotasInMB = quotasInMB
+	return d.SetupJobDiskQuotasErr
+}
+
+type FakeFirewallDelegate struct {
+	SetupJobFirewallPortsErr      error
+	SetupJobFirewallPortsJobPorts map[string][]boshfirewall.Port
+}
+
+func (d *FakeFirewallDelegate) SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) error {
+	d.SetupJobFirewallPortsJobPorts = jobPorts
+	return d.SetupJobFirewallPortsErr
+}
+
+type FakeCgroupDelegate struct {
+	SetupJobCgroupsErr       error
+	SetupJobCgroupsJobLimits map[string]boshcgroup.Limits
+}
+
+func (d *FakeCgroupDelegate) SetupJobCgroups(jobLimits map[string]boshcgroup.Limits) error {
+	d.SetupJobCgroupsJobLimits = jobLimits
+	return d.SetupJobCgroupsErr
+}
+
 func buildJob() models.Job {
 	uuidGen := boshuuid.NewGenerator()
 	uuid, err := uuidGen.Generate()
@@ -55,6 +87,9 @@ func init() {
 			jobApplier        *fakejobs.FakeApplier
 			packageApplier    *fakepackages.FakeApplier
 			logRotateDelegate *FakeLogRotateDelegate
+			diskQuotaDelegate *FakeDiskQuotaDelegate
+			firewallDelegate  *FakeFirewallDelegate
+			cgroupDelegate    *FakeCgroupDelegate
 			jobSupervisor     *fakejobsuper.FakeJobSupervisor
 			applier           Applier
 		)
@@ -63,11 +98,17 @@ func init() {
 			jobApplier = fakejobs.NewFakeApplier()
 			packageApplier = fakepackages.NewFakeApplier()
 			logRotateDelegate = &FakeLogRotateDelegate{}
+			diskQuotaDelegate = &FakeDiskQuotaDelegate{}
+			firewallDelegate = &FakeFirewallDelegate{}
+			cgroupDelegate = &FakeCgroupDelegate{}
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 			applier = NewConcreteApplier(
 				jobApplier,
 				packageApplier,
 				logRotateDelegate,
+				diskQuotaDelegate,
+				firewallDelegate,
+				cgroupDelegate,
 				jobSupervisor,
 				boshdirs.NewProvider("/fake-base-dir"),
 			)
@@ -319,6 +360,108 @@ func init() {
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-set-up-logrotate-error"))
 			})
+
+			It("apply sets up job disk quotas", func() {
+				err := applier.Apply(
+					&fakeas.FakeApplySpec{},
+					&fakeas.FakeApplySpec{EphemeralDiskQuotasResult: map[string]uint64{"fake-job": 1024}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskQuotaDelegate.SetupJobDiskQuotasQuotasInMB).To(Equal(map[string]uint64{"fake-job": 1024}))
+			})
+
+			It("apply sets up job disk quotas before reloading job supervisor", func() {
+				diskQuotaDelegate.SetupJobDiskQuotasErr = errors.New("fake-set-up-disk-quotas-error")
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-up-disk-quotas-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
+
+			It("apply allows job ports through the firewall", func() {
+				err := applier.Apply(
+					&fakeas.FakeApplySpec{},
+					&fakeas.FakeApplySpec{FirewallPortsResult: map[string][]boshfirewall.Port{"fake-job": {{Port: 8080}}}},
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(firewallDelegate.SetupJobFirewallPortsJobPorts).To(Equal(map[string][]boshfirewall.Port{"fake-job": {{Port: 8080}}}))
+			})
+
+			It("apply allows job ports through the firewall before reloading job supervisor", func() {
+				firewallDelegate.SetupJobFirewallPortsErr = errors.New("fake-set-up-firewall-error")
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-up-firewall-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
+
+			It("apply sets up job cgroups", func() {
+				jobLimits := map[string]boshcgroup.Limits{"fake-job": {MemoryBytes: 1024}}
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobResourceLimitsResult: jobLimits})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cgroupDelegate.SetupJobCgroupsJobLimits).To(Equal(jobLimits))
+			})
+
+			It("apply sets jobs with cgroups before reloading job supervisor", func() {
+				jobLimits := map[string]boshcgroup.Limits{"fake-job-2": {MemoryBytes: 1024}, "fake-job-1": {MaxPIDs: 10}}
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobResourceLimitsResult: jobLimits})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobSupervisor.SetJobCgroupsJobNames).To(Equal([]string{"fake-job-1", "fake-job-2"}))
+
+				jobSupervisor.SetJobCgroupsErr = errors.New("fake-set-cgroups-error")
+				jobSupervisor.Reloaded = false
+
+				err = applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobResourceLimitsResult: jobLimits})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-cgroups-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
+
+			It("apply sets job dependencies before reloading job supervisor", func() {
+				jobDependencies := map[string][]string{"fake-job": {"fake-dependency"}}
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobDependenciesResult: jobDependencies})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobSupervisor.SetJobDependenciesDependencies).To(Equal(jobDependencies))
+
+				jobSupervisor.SetJobDependenciesErr = errors.New("fake-set-dependencies-error")
+				jobSupervisor.Reloaded = false
+
+				err = applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobDependenciesResult: jobDependencies})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-dependencies-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
+
+			It("apply sets job concurrency before reloading job supervisor", func() {
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobConcurrencyResult: 2})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(jobSupervisor.SetJobConcurrencyLimit).To(Equal(2))
+
+				jobSupervisor.SetJobConcurrencyErr = errors.New("fake-set-concurrency-error")
+				jobSupervisor.Reloaded = false
+
+				err = applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{JobConcurrencyResult: 2})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-concurrency-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
+
+			It("apply sets up job cgroups before reloading job supervisor", func() {
+				cgroupDelegate.SetupJobCgroupsErr = errors.New("fake-set-up-cgroups-error")
+
+				err := applier.Apply(&fakeas.FakeApplySpec{}, &fakeas.FakeApplySpec{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-set-up-cgroups-error"))
+				Expect(jobSupervisor.Reloaded).To(BeFalse())
+			})
 		})
 	})
 }
@@ -0,0 +1,5 @@
+package applier
+
+type DiskQuotaDelegate interface {
+	SetupJobDiskQuotas(quotasInMB map[string]uint64) (err error)
+}
@@ -0,0 +1,9 @@
+package applier
+
+import (
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+)
+
+type FirewallDelegate interface {
+	SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) (err error)
+}
@@ -0,0 +1,13 @@
+package bootprofile_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestBootprofile(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Bootprofile Suite")
+}
@@ -0,0 +1,89 @@
+package bootprofile
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+)
+
+const (
+	PhaseSettingsFetch = "settings_fetch"
+	PhaseSystemSetup   = "system_setup"
+	PhaseNetworkSetup  = "network_setup"
+	PhaseTimeSync      = "time_sync"
+	PhaseDiskSetup     = "disk_setup"
+	PhaseKernelSetup   = "kernel_setup"
+	PhaseMonitStart    = "monit_start"
+)
+
+type Phase struct {
+	Name       string `json:"name"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+// Summary is time bootstrap took since agent started, broken down into phases
+type Summary struct {
+	TotalMs int64   `json:"total_ms"`
+	Phases  []Phase `json:"phases"`
+}
+
+type Profile interface {
+	// StartPhase returns function that records duration of the phase when it ends;
+	// phases that failed are not recorded
+	StartPhase(name string) (end func())
+
+	// Finish records total duration once bootstrap succeeded
+	Finish()
+
+	// Summary returns nil until bootstrap finished
+	Summary() *Summary
+}
+
+type profile struct {
+	clock     clock.Clock
+	startedAt time.Time
+
+	summary     *Summary
+	phases      []Phase
+	summaryLock sync.RWMutex
+}
+
+// NewProfile measures from its creation, i.e. agent start
+func NewProfile(clock clock.Clock) Profile {
+	return &profile{
+		clock:     clock,
+		startedAt: clock.Now(),
+	}
+}
+
+func (p *profile) StartPhase(name string) func() {
+	phaseStartedAt := p.clock.Now()
+
+	return func() {
+		p.summaryLock.Lock()
+		defer p.summaryLock.Unlock()
+
+		p.phases = append(p.phases, Phase{
+			Name:       name,
+			DurationMs: p.clock.Now().Sub(phaseStartedAt).Nanoseconds() / int64(time.Millisecond),
+		})
+	}
+}
+
+func (p *profile) Finish() {
+	p.summaryLock.Lock()
+	defer p.summaryLock.Unlock()
+
+	p.summary = &Summary{
+		TotalMs: p.clock.Now().Sub(p.startedAt).Nanoseconds() / int64(time.Millisecond),
+		Phases:  append([]Phase{}, p.phases...),
+	}
+}
+
+func (p *profile) Summary() *Summary {
+	p.summaryLock.RLock()
+	defer p.summaryLock.RUnlock()
+
+	return p.summary
+}
@@ -0,0 +1,54 @@
+package bootprofile_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("Profile", func() {
+	var (
+		clock   *fakeclock.FakeClock
+		profile Profile
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		profile = NewProfile(clock)
+	})
+
+	It("returns no summary until bootstrap finished", func() {
+		profile.StartPhase(PhaseSettingsFetch)()
+
+		Expect(profile.Summary()).To(BeNil())
+	})
+
+	It("returns durations of ended phases in order and total since profile was created", func() {
+		clock.Increment(500 * time.Millisecond)
+
+		endPhase := profile.StartPhase(PhaseSettingsFetch)
+		clock.Increment(2 * time.Second)
+		endPhase()
+
+		profile.StartPhase(PhaseNetworkSetup)
+		clock.Increment(1 * time.Second)
+
+		endPhase = profile.StartPhase(PhaseMonitStart)
+		clock.Increment(250 * time.Millisecond)
+		endPhase()
+
+		profile.Finish()
+
+		Expect(profile.Summary()).To(Equal(&Summary{
+			TotalMs: 3750,
+			Phases: []Phase{
+				{Name: "settings_fetch", DurationMs: 2000},
+				{Name: "monit_start", DurationMs: 250},
+			},
+		}))
+	})
+})
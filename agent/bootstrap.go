@@ -1,9 +1,10 @@
 package agent
 
 import (
-	"errors"
 	"path"
+	"sort"
 
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -12,6 +13,8 @@ import (
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+const bootstrapLogTag = "bootstrap"
+
 type Bootstrap interface {
 	Run() error
 }
@@ -21,6 +24,7 @@ type bootstrap struct {
 	platform        boshplatform.Platform
 	dirProvider     boshdir.Provider
 	settingsService boshsettings.Service
+	bootProfile     boshbootprofile.Profile
 	logger          boshlog.Logger
 }
 
@@ -28,6 +32,7 @@ func NewBootstrap(
 	platform boshplatform.Platform,
 	dirProvider boshdir.Provider,
 	settingsService boshsettings.Service,
+	bootProfile boshbootprofile.Profile,
 	logger boshlog.Logger,
 ) Bootstrap {
 	return bootstrap{
@@ -35,6 +40,7 @@ func NewBootstrap(
 		platform:        platform,
 		dirProvider:     dirProvider,
 		settingsService: settingsService,
+		bootProfile:     bootProfile,
 		logger:          logger,
 	}
 }
@@ -55,12 +61,18 @@ func (boot bootstrap) Run() (err error) {
 		}
 	}
 
+	endPhase := boot.bootProfile.StartPhase(boshbootprofile.PhaseSettingsFetch)
+
 	if err = boot.settingsService.LoadSettings(); err != nil {
 		return bosherr.WrapError(err, "Fetching settings")
 	}
 
+	endPhase()
+
 	settings := boot.settingsService.GetSettings()
 
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseSystemSetup)
+
 	if err = boot.setUserPasswords(settings.Env); err != nil {
 		return bosherr.WrapError(err, "Settings user password")
 	}
@@ -69,20 +81,71 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Setting up hostname")
 	}
 
-	if err = boot.platform.SetupNetworking(settings.Networks); err != nil {
+	if err = boot.platform.SetupHostsEntries(settings.Env.GetHostsEntries()); err != nil {
+		return bosherr.WrapError(err, "Setting up /etc/hosts entries")
+	}
+
+	if err = boot.platform.SetupTimezone(settings.Env.GetTimezone()); err != nil {
+		return bosherr.WrapError(err, "Setting up timezone")
+	}
+
+	if err = boot.platform.SetupLocale(settings.Env.GetLocale()); err != nil {
+		return bosherr.WrapError(err, "Setting up locale")
+	}
+
+	if err = boot.platform.SetupProxy(settings.ProxySettings()); err != nil {
+		return bosherr.WrapError(err, "Setting up proxy")
+	}
+
+	endPhase()
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseNetworkSetup)
+
+	if err = boot.runHooks(boshplatform.HookPhasePreNetwork); err != nil {
+		return err
+	}
+
+	// Networks resolve through dns cache when it is enabled
+	networks, err := boot.platform.SetupDNSCache(settings.Env.GetDNSCache(), settings.Networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up DNS cache")
+	}
+
+	if err = boot.platform.SetupNetworking(networks); err != nil {
 		return bosherr.WrapError(err, "Setting up networking")
 	}
 
+	if err = boot.platform.SetupFirewall(settings.FirewallSettings()); err != nil {
+		return bosherr.WrapError(err, "Setting up firewall")
+	}
+
+	if err = boot.runHooks(boshplatform.HookPhasePostNetwork); err != nil {
+		return err
+	}
+
+	networkValidation := settings.Env.GetNetworkValidation()
+	if networkValidation.Enabled {
+		if err = boot.platform.ValidateNetworking(settings.Networks, networkValidation.ProbeHostname); err != nil {
+			return bosherr.WrapError(err, "Validating networking")
+		}
+	}
+
+	endPhase()
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseTimeSync)
+
 	if err = boot.platform.SetTimeWithNtpServers(settings.Ntp); err != nil {
 		return bosherr.WrapError(err, "Setting up NTP servers")
 	}
 
+	endPhase()
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseDiskSetup)
+
 	if err = boot.platform.SetupRawEphemeralDisks(settings.RawEphemeralDiskSettings()); err != nil {
 		return bosherr.WrapError(err, "Setting up raw ephemeral disk")
 	}
 
-	ephemeralDiskPath := boot.platform.GetEphemeralDiskPath(settings.EphemeralDiskSettings())
-	if err = boot.platform.SetupEphemeralDiskWithPath(ephemeralDiskPath); err != nil {
+	ephemeralDiskSettings := settings.EphemeralDiskSettings()
+	ephemeralDiskPath := boot.platform.GetEphemeralDiskPath(ephemeralDiskSettings)
+	if err = boot.platform.SetupEphemeralDiskWithPath(ephemeralDiskPath, ephemeralDiskSettings); err != nil {
 		return bosherr.WrapError(err, "Setting up ephemeral disk")
 	}
 
@@ -98,12 +161,25 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Setting up tmp dir")
 	}
 
-	if len(settings.Disks.Persistent) > 1 {
-		return errors.New("Error mounting persistent disk, there is more than one persistent disk")
+	if err = boot.platform.SetupBindMounts(); err != nil {
+		return bosherr.WrapError(err, "Setting up bind mounts")
 	}
 
-	for diskID := range settings.Disks.Persistent {
-		diskSettings, _ := settings.PersistentDiskSettings(diskID)
+	persistentDisks, err := boot.persistentDisksByMountPoint(settings)
+	if err != nil {
+		return err
+	}
+
+	mountPoints := make([]string, 0, len(persistentDisks))
+	for mountPoint := range persistentDisks {
+		mountPoints = append(mountPoints, mountPoint)
+	}
+
+	// Parent mount points are mounted before the ones nested in them
+	sort.Strings(mountPoints)
+
+	for _, mountPoint := range mountPoints {
+		diskSettings := persistentDisks[mountPoint]
 
 		isPartitioned, err := boot.platform.IsPersistentDiskMountable(diskSettings)
 		if err != nil {
@@ -111,32 +187,97 @@ func (boot bootstrap) Run() (err error) {
 		}
 
 		if isPartitioned {
-			if err = boot.platform.MountPersistentDisk(diskSettings, boot.dirProvider.StoreDir()); err != nil {
+			if err = boot.platform.MountPersistentDisk(diskSettings, mountPoint); err != nil {
 				return bosherr.WrapError(err, "Mounting persistent disk")
 			}
 		}
 	}
 
+	if err = boot.runHooks(boshplatform.HookPhasePostDiskSetup); err != nil {
+		return err
+	}
+
+	endPhase()
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseKernelSetup)
+
+	if err = boot.platform.SetupFilesystemTrimming(); err != nil {
+		return bosherr.WrapError(err, "Setting up filesystem trimming")
+	}
+
+	rebootRequired, err := boot.platform.SetupKernelArgs(settings.Env.GetKernelArgs())
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up kernel args")
+	}
+
+	if rebootRequired {
+		boot.logger.Warn(bootstrapLogTag, "Kernel command line parameters changed; a reboot is required for them to take effect")
+	}
+
+	if err = boot.platform.SetupTuningProfile(settings.Env.GetTuningProfile()); err != nil {
+		return bosherr.WrapError(err, "Applying tuning profile")
+	}
+
+	if err = boot.platform.SetupHugePages(settings.Env.GetHugePages()); err != nil {
+		return bosherr.WrapError(err, "Setting up huge pages")
+	}
+
+	endPhase()
+	endPhase = boot.bootProfile.StartPhase(boshbootprofile.PhaseMonitStart)
+
 	if err = boot.platform.SetupMonitUser(); err != nil {
 		return bosherr.WrapError(err, "Setting up monit user")
 	}
 
+	if err = boot.runHooks(boshplatform.HookPhasePreMonit); err != nil {
+		return err
+	}
+
 	if err = boot.platform.StartMonit(); err != nil {
 		return bosherr.WrapError(err, "Starting monit")
 	}
 
+	endPhase()
+
 	if settings.Env.GetRemoveDevTools() {
 		packageFileListPath := path.Join(boot.dirProvider.EtcDir(), "dev_tools_file_list")
 
-		if !boot.fs.FileExists(packageFileListPath) {
-			return nil
+		if boot.fs.FileExists(packageFileListPath) {
+			if err = boot.platform.RemoveDevTools(packageFileListPath); err != nil {
+				return bosherr.WrapError(err, "Removing Development Tools Packages")
+			}
 		}
+	}
+
+	// Root is remounted read-only last since all steps above may still write to it
+	if err = boot.platform.SetupReadOnlyRoot(); err != nil {
+		return bosherr.WrapError(err, "Setting up read-only root filesystem")
+	}
+
+	return nil
+}
+
+func (boot bootstrap) persistentDisksByMountPoint(settings boshsettings.Settings) (map[string]boshsettings.DiskSettings, error) {
+	disks := map[string]boshsettings.DiskSettings{}
 
-		if err = boot.platform.RemoveDevTools(packageFileListPath); err != nil {
-			return bosherr.WrapError(err, "Removing Development Tools Packages")
+	for diskID := range settings.Disks.Persistent {
+		diskSettings, _ := settings.PersistentDiskSettings(diskID)
+		mountPoint := boot.dirProvider.PersistentDiskMountPoint(diskSettings.MountPoint)
+
+		if otherDisk, found := disks[mountPoint]; found {
+			return nil, bosherr.Errorf("Error mounting persistent disk, disks '%s' and '%s' are both mounted on %s", otherDisk.ID, diskID, mountPoint)
 		}
+
+		disks[mountPoint] = diskSettings
 	}
 
+	return disks, nil
+}
+
+func (boot bootstrap) runHooks(phase string) error {
+	err := boot.platform.RunHooks(phase)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Running %s hooks", phase)
+	}
 	return nil
 }
 
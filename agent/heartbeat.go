@@ -1,6 +1,7 @@
 package agent
 
 import (
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 )
 
@@ -11,6 +12,9 @@ type Heartbeat struct {
 	JobState   string            `json:"job_state"`
 	Vitals     boshvitals.Vitals `json:"vitals"`
 	NodeID     string            `json:"node_id"`
+
+	// Only included in first heartbeat after agent started
+	Boot *boshbootprofile.Summary `json:"boot,omitempty"`
 }
 
 //Heartbeat payload example:
@@ -28,6 +32,9 @@ type Heartbeat struct {
 //      "ephemeral": {"percent" => "5"},
 //      "persistent": {"percent" => "94"}
 //    },
+//    "processes": {
+//      "cloud_controller_ng": {"state":"running","cpu":"2.5","mem":{"percent":"1.2","kb":"51200"},"open_fds":"87","uptime_secs":"3600"}
+//    },
 //  "ntp": {
 //      "offset": "-0.06423",
 //      "timestamp": "14 Oct 11:13:19"
@@ -1,18 +1,22 @@
 package script
 
 import (
+	"encoding/json"
 	"fmt"
 	"path"
 	"path/filepath"
 
 	"github.com/pivotal-golang/clock"
 
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshdrain "github.com/cloudfoundry/bosh-agent/agent/script/drain"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+const concreteJobScriptProviderLogTag = "ConcreteJobScriptProvider"
+
 type ConcreteJobScriptProvider struct {
 	cmdRunner   boshsys.CmdRunner
 	fs          boshsys.FileSystem
@@ -46,15 +50,40 @@ func (p ConcreteJobScriptProvider) NewScript(jobName string, scriptName string)
 	stderrLogFilename := fmt.Sprintf("%s.stderr.log", scriptName)
 	stderrLogPath := filepath.Join(p.dirProvider.LogsDir(), jobName, stderrLogFilename)
 
-	return NewScript(p.fs, p.cmdRunner, jobName, path, stdoutLogPath, stderrLogPath)
+	return NewScript(p.fs, p.cmdRunner, jobName, path, p.instanceEnv(), stdoutLogPath, stderrLogPath)
 }
 
 func (p ConcreteJobScriptProvider) NewDrainScript(jobName string, params boshdrain.ScriptParams) CancellableScript {
 	path := path.Join(p.dirProvider.JobsDir(), jobName, "bin", "drain")
 
-	return boshdrain.NewConcreteScript(p.fs, p.cmdRunner, jobName, path, params, p.timeService, p.logger)
+	return boshdrain.NewConcreteScript(p.fs, p.cmdRunner, jobName, path, p.instanceEnv(), params, p.timeService, p.logger)
 }
 
 func (p ConcreteJobScriptProvider) NewParallelScript(scriptName string, scripts []Script) CancellableScript {
 	return NewParallelScript(scriptName, scripts, p.logger)
 }
+
+// instanceEnv returns environment identifying the instance
+// from the instance info written during the last apply.
+func (p ConcreteJobScriptProvider) instanceEnv() map[string]string {
+	path := filepath.Join(p.dirProvider.InstanceDir(), boshas.InstanceInfoFileName)
+	if !p.fs.FileExists(path) {
+		return map[string]string{}
+	}
+
+	contents, err := p.fs.ReadFile(path)
+	if err != nil {
+		p.logger.Warn(concreteJobScriptProviderLogTag, "Failed to read instance info: %s", err.Error())
+		return map[string]string{}
+	}
+
+	var instanceInfo boshas.InstanceInfo
+
+	err = json.Unmarshal(contents, &instanceInfo)
+	if err != nil {
+		p.logger.Warn(concreteJobScriptProviderLogTag, "Failed to unmarshal instance info: %s", err.Error())
+		return map[string]string{}
+	}
+
+	return instanceInfo.Env()
+}
@@ -17,12 +17,14 @@ import (
 var _ = Describe("ConcreteJobScriptProvider", func() {
 	var (
 		logger         boshlog.Logger
+		runner         *fakesys.FakeCmdRunner
+		fs             *fakesys.FakeFileSystem
 		scriptProvider boshscript.ConcreteJobScriptProvider
 	)
 
 	BeforeEach(func() {
-		runner := fakesys.NewFakeCmdRunner()
-		fs := fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		fs = fakesys.NewFakeFileSystem()
 		dirProvider := boshdir.NewProvider("/the/base/dir")
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		scriptProvider = boshscript.NewConcreteJobScriptProvider(
@@ -40,6 +42,30 @@ var _ = Describe("ConcreteJobScriptProvider", func() {
 			Expect(script.Tag()).To(Equal("myjob"))
 			Expect(script.Path()).To(Equal("/the/base/dir/jobs/myjob/bin/the-best-hook-ever"))
 		})
+
+		It("returns script that runs with instance environment from instance info", func() {
+			fs.WriteFileString("/the/base/dir/instance/instance.json", `{"id":"fake-id","deployment":"fake-deployment"}`)
+
+			err := scriptProvider.NewScript("myjob", "the-best-hook-ever").Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunComplexCommands).To(HaveLen(1))
+			Expect(runner.RunComplexCommands[0].Env).To(Equal(map[string]string{
+				"PATH":             "/usr/sbin:/usr/bin:/sbin:/bin",
+				"BOSH_INSTANCE_ID": "fake-id",
+				"BOSH_DEPLOYMENT":  "fake-deployment",
+			}))
+		})
+
+		It("returns script without instance environment when instance info is malformed", func() {
+			fs.WriteFileString("/the/base/dir/instance/instance.json", `malformed`)
+
+			err := scriptProvider.NewScript("myjob", "the-best-hook-ever").Run()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunComplexCommands[0].Env).To(Equal(map[string]string{
+				"PATH": "/usr/sbin:/usr/bin:/sbin:/bin",
+			}))
+		})
 	})
 
 	Describe("NewDrainScript", func() {
@@ -17,6 +17,7 @@ type ConcreteScript struct {
 
 	tag    string
 	path   string
+	env    map[string]string
 	params ScriptParams
 
 	timeService clock.Clock
@@ -31,6 +32,7 @@ func NewConcreteScript(
 	runner boshsys.CmdRunner,
 	tag string,
 	path string,
+	env map[string]string,
 	params ScriptParams,
 	timeService clock.Clock,
 	logger boshlog.Logger) ConcreteScript {
@@ -40,6 +42,7 @@ func NewConcreteScript(
 
 		tag:    tag,
 		path:   path,
+		env:    env,
 		params: params,
 
 		timeService: timeService,
@@ -93,6 +96,10 @@ func (s ConcreteScript) runOnce(params ScriptParams) (int, error) {
 		},
 	}
 
+	for name, value := range s.env {
+		command.Env[name] = value
+	}
+
 	jobState, err := params.JobState()
 	if err != nil {
 		return 0, bosherr.WrapError(err, "Getting job state")
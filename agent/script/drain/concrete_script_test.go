@@ -35,7 +35,7 @@ var _ = Describe("ConcreteScript", func() {
 
 	JustBeforeEach(func() {
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		script = NewConcreteScript(fs, runner, "my-tag", "/fake/script", params, fakeClock, logger)
+		script = NewConcreteScript(fs, runner, "my-tag", "/fake/script", map[string]string{"BOSH_INSTANCE_ID": "fake-id"}, params, fakeClock, logger)
 	})
 
 	Describe("Tag", func() {
@@ -93,6 +93,7 @@ var _ = Describe("ConcreteScript", func() {
 				Args: []string{"job_unchanged", "hash_unchanged", "bar", "foo"},
 				Env: map[string]string{
 					"PATH":                "/usr/sbin:/usr/bin:/sbin:/bin",
+					"BOSH_INSTANCE_ID":    "fake-id",
 					"BOSH_JOB_STATE":      "{\"persistent_disk\":42}",
 					"BOSH_JOB_NEXT_STATE": "{\"persistent_disk\":42}",
 				},
@@ -18,6 +18,7 @@ type GenericScript struct {
 
 	tag  string
 	path string
+	env  map[string]string
 
 	stdoutLogPath string
 	stderrLogPath string
@@ -28,6 +29,7 @@ func NewScript(
 	runner boshsys.CmdRunner,
 	tag string,
 	path string,
+	env map[string]string,
 	stdoutLogPath string,
 	stderrLogPath string,
 ) GenericScript {
@@ -37,6 +39,7 @@ func NewScript(
 
 		tag:  tag,
 		path: path,
+		env:  env,
 
 		stdoutLogPath: stdoutLogPath,
 		stderrLogPath: stderrLogPath,
@@ -83,6 +86,10 @@ func (s GenericScript) Run() error {
 		Stderr: stderrFile,
 	}
 
+	for name, value := range s.env {
+		command.Env[name] = value
+	}
+
 	_, _, _, err = s.runner.RunComplexCommand(command)
 
 	return err
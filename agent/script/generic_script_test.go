@@ -30,6 +30,7 @@ var _ = Describe("GenericScript", func() {
 			cmdRunner,
 			"my-tag",
 			"/path-to-script",
+			map[string]string{"BOSH_INSTANCE_ID": "fake-id"},
 			stdoutLogPath,
 			stderrLogPath,
 		)
@@ -62,6 +63,17 @@ var _ = Describe("GenericScript", func() {
 			Expect(err).ToNot(HaveOccurred())
 		})
 
+		It("runs command with given environment", func() {
+			err := genericScript.Run()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+			Expect(cmdRunner.RunComplexCommands[0].Env).To(Equal(map[string]string{
+				"PATH":             "/usr/sbin:/usr/bin:/sbin:/bin",
+				"BOSH_INSTANCE_ID": "fake-id",
+			}))
+		})
+
 		It("returns an error if it fails to create logs directory", func() {
 			fs.MkdirAllError = errors.New("fake-mkdir-all-error")
 
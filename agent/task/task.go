@@ -6,6 +6,8 @@ type CancelFunc func(task Task) error
 
 type EndFunc func(task Task)
 
+type ProgressFunc func() interface{}
+
 type State string
 
 const (
@@ -23,6 +25,9 @@ type Task struct {
 	Func       Func
 	CancelFunc CancelFunc
 	EndFunc    EndFunc
+
+	// Optional; reports progress while task is running
+	ProgressFunc ProgressFunc
 }
 
 func (t Task) Cancel() error {
@@ -32,7 +37,16 @@ func (t Task) Cancel() error {
 	return nil
 }
 
+func (t Task) Progress() interface{} {
+	if t.ProgressFunc != nil {
+		return t.ProgressFunc()
+	}
+	return nil
+}
+
 type StateValue struct {
 	AgentTaskID string `json:"agent_task_id"`
 	State       State  `json:"state"`
+
+	Progress interface{} `json:"progress,omitempty"`
 }
@@ -6,8 +6,6 @@ import (
 	"path/filepath"
 	"time"
 
-	sigar "github.com/cloudfoundry/gosigar"
-
 	boshagent "github.com/cloudfoundry/bosh-agent/agent"
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
 	boshapplier "github.com/cloudfoundry/bosh-agent/agent/applier"
@@ -15,20 +13,26 @@ import (
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
 	boshaj "github.com/cloudfoundry/bosh-agent/agent/applier/jobs"
 	boshap "github.com/cloudfoundry/bosh-agent/agent/applier/packages"
+	boshbootprofile "github.com/cloudfoundry/bosh-agent/agent/bootprofile"
 	boshrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
 	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	boshfips "github.com/cloudfoundry/bosh-agent/fips"
+	boshdispatcher "github.com/cloudfoundry/bosh-agent/httpsdispatcher"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 	boshmbus "github.com/cloudfoundry/bosh-agent/mbus"
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	boshpsutil "github.com/cloudfoundry/bosh-agent/psutil"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
-	boshsigar "github.com/cloudfoundry/bosh-agent/sigar"
 	boshsyslog "github.com/cloudfoundry/bosh-agent/syslog"
+	boshtelemetry "github.com/cloudfoundry/bosh-agent/telemetry"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -71,6 +75,11 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Loading config")
 	}
 
+	timeService := clock.NewClock()
+
+	// Boot time is measured from agent start
+	bootProfile := boshbootprofile.NewProfile(timeService)
+
 	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
 	app.logStemcellInfo()
 
@@ -79,11 +88,9 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Loading state")
 	}
 
-	// Pulled outside of the platform provider so bosh-init will not pull in
-	// sigar when cross compiling linux -> darwin
-	sigarCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
+	statsCollector := boshpsutil.NewPsutilStatsCollector(boshpsutil.ConcreteSource{}, app.fs)
 
-	platformProvider := boshplatform.NewProvider(app.logger, app.dirProvider, sigarCollector, app.fs, config.Platform, state)
+	platformProvider := boshplatform.NewProvider(app.logger, app.dirProvider, statsCollector, app.fs, config.Platform, state)
 	app.platform, err = platformProvider.Get(opts.PlatformName)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting platform")
@@ -106,6 +113,7 @@ func (app *app) Setup(args []string) error {
 		app.platform,
 		app.dirProvider,
 		settingsService,
+		bootProfile,
 		app.logger,
 	)
 
@@ -113,6 +121,8 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Running bootstrap")
 	}
 
+	bootProfile.Finish()
+
 	mbusHandlerProvider := boshmbus.NewHandlerProvider(settingsService, app.logger)
 
 	mbusHandler, err := mbusHandlerProvider.Get(app.platform, app.dirProvider)
@@ -120,7 +130,16 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Getting mbus handler")
 	}
 
-	blobstoreProvider := boshblob.NewProvider(app.platform.GetFs(), app.platform.GetRunner(), app.dirProvider.EtcDir(), app.logger)
+	var blobstoreProvider interface {
+		Get(storeType string, options map[string]interface{}) (boshblob.Blobstore, error)
+	}
+
+	if boshfips.Enabled(settingsService.GetSettings().Env.GetFIPS()) {
+		app.logger.Info(app.logTag, "FIPS mode enabled, restricting blob digests to SHA-2")
+		blobstoreProvider = boshfips.NewBlobstoreProvider(app.platform.GetFs(), app.platform.GetRunner(), app.dirProvider.EtcDir(), app.logger)
+	} else {
+		blobstoreProvider = boshblob.NewProvider(app.platform.GetFs(), app.platform.GetRunner(), app.dirProvider.EtcDir(), app.logger)
+	}
 
 	blobsettings := settingsService.GetSettings().Blobstore
 	blobstore, err := blobstoreProvider.Get(blobsettings.Type, blobsettings.Options)
@@ -128,6 +147,18 @@ func (app *app) Setup(args []string) error {
 		return bosherr.WrapError(err, "Getting blobstore")
 	}
 
+	metricsCollector := boshmetrics.NewCollector(statsCollector, app.dirProvider, app.platform.GetFs(), app.logger)
+
+	telemetryExporter := boshtelemetry.NewExporter(
+		settingsService.GetSettings().Env.GetTelemetry(),
+		settingsService.GetSettings().AgentID,
+		metricsCollector,
+		timeService,
+		app.logger,
+	)
+
+	blobstore = boshtelemetry.NewMeasuredBlobstore(blobstore, telemetryExporter, app.platform.GetFs(), timeService)
+
 	monitClientProvider := boshmonit.NewProvider(app.platform, app.logger)
 
 	monitClient, err := monitClientProvider.Get()
@@ -143,7 +174,12 @@ func (app *app) Setup(args []string) error {
 		mbusHandler,
 	)
 
-	jobSupervisor, err := jobSupervisorProvider.Get(opts.JobSupervisor)
+	jobSupervisorName := opts.JobSupervisor
+	if settingsService.GetSettings().Env.GetJobSupervisor() != "" {
+		jobSupervisorName = settingsService.GetSettings().Env.GetJobSupervisor()
+	}
+
+	jobSupervisor, err := jobSupervisorProvider.Get(jobSupervisorName)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting job supervisor")
 	}
@@ -168,8 +204,6 @@ func (app *app) Setup(args []string) error {
 		specFilePath,
 	)
 
-	timeService := clock.NewClock()
-
 	jobScriptProvider := boshscript.NewConcreteJobScriptProvider(
 		app.platform.GetRunner(),
 		app.platform.GetFs(),
@@ -178,6 +212,13 @@ func (app *app) Setup(args []string) error {
 		app.logger,
 	)
 
+	vitalsHistory := boshvitals.NewFileHistory(
+		app.platform.GetFs(),
+		filepath.Join(app.dirProvider.BoshDir(), "vitals_history.json"),
+		boshvitals.DefaultHistorySize,
+		timeService,
+	)
+
 	actionFactory := boshaction.NewFactory(
 		settingsService,
 		app.platform,
@@ -189,6 +230,8 @@ func (app *app) Setup(args []string) error {
 		jobSupervisor,
 		specService,
 		jobScriptProvider,
+		vitalsHistory,
+		bootProfile,
 		app.logger,
 	)
 
@@ -200,10 +243,18 @@ func (app *app) Setup(args []string) error {
 		taskManager,
 		actionFactory,
 		actionRunner,
+		telemetryExporter,
 	)
 
 	syslogServer := boshsyslog.NewServer(33331, net.Listen, app.logger)
 
+	metricsTLSConfig := boshdispatcher.DefaultTLSConfig()
+	if boshfips.Enabled(settingsService.GetSettings().Env.GetFIPS()) {
+		metricsTLSConfig = boshfips.TLSConfig()
+	}
+
+	metricsServer := boshmetrics.NewServer(settingsService.GetSettings().Env.GetMetrics(), metricsTLSConfig, metricsCollector, net.Listen, app.logger)
+
 	app.agent = boshagent.New(
 		app.logger,
 		mbusHandler,
@@ -212,6 +263,10 @@ func (app *app) Setup(args []string) error {
 		jobSupervisor,
 		specService,
 		syslogServer,
+		metricsServer,
+		telemetryExporter,
+		vitalsHistory,
+		bootProfile,
 		time.Minute,
 		settingsService,
 		uuidGen,
@@ -271,6 +326,9 @@ func (app *app) buildApplierAndCompiler(
 		jobApplier,
 		packageApplierProvider.Root(),
 		app.platform,
+		app.platform,
+		app.platform,
+		app.platform,
 		jobSupervisor,
 		dirProvider,
 	)
@@ -17,6 +17,14 @@ import (
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
+// multipathDelegate returns the resolver wrapped by multipath device path resolver
+func multipathDelegate(resolver devicepathresolver.DevicePathResolver) devicepathresolver.DevicePathResolver {
+	wrapping, ok := resolver.(devicepathresolver.WrappingDevicePathResolver)
+	Expect(ok).To(BeTrue())
+
+	return wrapping.Delegate()
+}
+
 func init() {
 	Describe("App", func() {
 		var (
@@ -118,7 +126,8 @@ func init() {
 			err := app.Setup([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir})
 			Expect(err).ToNot(HaveOccurred())
 
-			Expect(app.GetPlatform().GetDevicePathResolver()).To(Equal(devicepathresolver.NewIdentityDevicePathResolver()))
+			Expect(multipathDelegate(app.GetPlatform().GetDevicePathResolver())).To(
+				Equal(devicepathresolver.NewIdentityDevicePathResolver()))
 		})
 
 		Context("when DevicePathResolutionType is 'virtio'", func() {
@@ -135,7 +144,7 @@ func init() {
 				logLevel, err := boshlog.Levelify("DEBUG")
 				Expect(err).NotTo(HaveOccurred())
 
-				Expect(app.GetPlatform().GetDevicePathResolver()).To(
+				Expect(multipathDelegate(app.GetPlatform().GetDevicePathResolver())).To(
 					BeAssignableToTypeOf(devicepathresolver.NewVirtioDevicePathResolver(nil, nil, boshlog.NewLogger(logLevel))))
 			})
 		})
@@ -152,7 +161,7 @@ func init() {
 				err := app.Setup([]string{"bosh-agent", "-P", "dummy", "-C", agentConfPath, "-b", baseDir})
 				Expect(err).ToNot(HaveOccurred())
 
-				Expect(app.GetPlatform().GetDevicePathResolver()).To(
+				Expect(multipathDelegate(app.GetPlatform().GetDevicePathResolver())).To(
 					BeAssignableToTypeOf(devicepathresolver.NewScsiDevicePathResolver(nil, nil)))
 			})
 		})
@@ -27,6 +27,10 @@ var _ = Describe("LoadConfigFromPath", func() {
 					"BindMountPersistentDisk": true,
 					"SkipDiskSetup": true,
 					"DevicePathResolutionType": "virtio"
+				},
+				"Stats": {
+					"CollectionIntervalInSeconds": 30,
+					"DisabledMetricGroups": ["smart"]
 				}
 			},
 			"Infrastructure": {
@@ -71,6 +75,10 @@ var _ = Describe("LoadConfigFromPath", func() {
 					SkipDiskSetup:                 true,
 					DevicePathResolutionType:      "virtio",
 				},
+				Stats: boshplatform.StatsOptions{
+					CollectionIntervalInSeconds: 30,
+					DisabledMetricGroups:        []string{"smart"},
+				},
 			},
 			Infrastructure: boshinf.Options{
 				Settings: boshinf.SettingsOptions{
@@ -6,4 +6,5 @@ export PATH=/usr/local/ruby/bin:/usr/local/go/bin:$PATH
 export GOPATH=$(pwd)/gopath
 
 cd gopath/src/github.com/cloudfoundry/bosh-agent
+bin/require-go
 bin/test-integration --provider=aws
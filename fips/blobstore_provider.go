@@ -0,0 +1,96 @@
+package fips
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+// fips140GODEBUG turns on FIPS mode of the Go runtime in blobstore CLIs built with Go
+const fips140GODEBUG = "fips140=on"
+
+type BlobstoreProvider struct {
+	fs        boshsys.FileSystem
+	runner    boshsys.CmdRunner
+	configDir string
+	uuidGen   boshuuid.Generator
+	logger    boshlog.Logger
+}
+
+// NewBlobstoreProvider returns provider building blobstores like boshblob.Provider
+// but without its SHA-1 verification so that only SHA-2 digests are calculated.
+func NewBlobstoreProvider(
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	configDir string,
+	logger boshlog.Logger,
+) BlobstoreProvider {
+	return BlobstoreProvider{
+		fs:        fs,
+		runner:    runner,
+		configDir: configDir,
+		uuidGen:   boshuuid.NewGenerator(),
+		logger:    logger,
+	}
+}
+
+func (p BlobstoreProvider) Get(storeType string, options map[string]interface{}) (boshblob.Blobstore, error) {
+	var blobstore boshblob.Blobstore
+
+	switch storeType {
+	case boshblob.BlobstoreTypeDummy:
+		return nil, bosherr.Errorf("Blobstore type '%s' is not supported in FIPS mode", storeType)
+
+	case boshblob.BlobstoreTypeLocal:
+		blobstore = boshblob.NewLocalBlobstore(p.fs, p.uuidGen, options)
+
+	default:
+		// External blobstore CLIs transfer blobs with their own TLS stack
+		err := enableForChildProcesses()
+		if err != nil {
+			return nil, err
+		}
+
+		configFile := path.Join(p.configDir, fmt.Sprintf("blobstore-%s.json", storeType))
+		blobstore = boshblob.NewExternalBlobstore(storeType, options, p.fs, p.runner, p.uuidGen, configFile)
+	}
+
+	blobstore = NewVerifiableBlobstore(blobstore, p.fs)
+
+	blobstore = boshblob.NewRetryableBlobstore(blobstore, 3, p.logger)
+
+	err := blobstore.Validate()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Validating blobstore")
+	}
+
+	return blobstore, nil
+}
+
+// enableForChildProcesses makes processes started by the agent inherit FIPS mode
+func enableForChildProcesses() error {
+	godebug := os.Getenv("GODEBUG")
+
+	for _, setting := range strings.Split(godebug, ",") {
+		if strings.HasPrefix(setting, "fips140=") {
+			if setting == "fips140=off" {
+				return bosherr.Error("Refusing GODEBUG fips140=off in FIPS mode")
+			}
+
+			return nil
+		}
+	}
+
+	if godebug != "" {
+		godebug += ","
+	}
+
+	return os.Setenv("GODEBUG", godebug+fips140GODEBUG)
+}
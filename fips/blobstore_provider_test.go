@@ -0,0 +1,74 @@
+package fips_test
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/fips"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("BlobstoreProvider", func() {
+	var (
+		fs       *fakesys.FakeFileSystem
+		runner   *fakesys.FakeCmdRunner
+		provider BlobstoreProvider
+
+		originalGODEBUG string
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		provider = NewBlobstoreProvider(fs, runner, "/fake-config-dir", boshlog.NewLogger(boshlog.LevelNone))
+
+		originalGODEBUG = os.Getenv("GODEBUG")
+		os.Setenv("GODEBUG", "")
+	})
+
+	AfterEach(func() {
+		os.Setenv("GODEBUG", originalGODEBUG)
+	})
+
+	It("refuses dummy blobstore", func() {
+		_, err := provider.Get("dummy", map[string]interface{}{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("not supported in FIPS mode"))
+	})
+
+	It("returns error when local blobstore is not valid", func() {
+		_, err := provider.Get("local", map[string]interface{}{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Validating blobstore"))
+	})
+
+	Context("when blobstore is external", func() {
+		BeforeEach(func() {
+			runner.CommandExistsValue = true
+		})
+
+		It("runs blobstore CLI with FIPS mode of the Go runtime", func() {
+			_, err := provider.Get("fake-external", map[string]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.Getenv("GODEBUG")).To(Equal("fips140=on"))
+		})
+
+		It("keeps other GODEBUG settings", func() {
+			os.Setenv("GODEBUG", "http2client=0")
+
+			_, err := provider.Get("fake-external", map[string]interface{}{})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(os.Getenv("GODEBUG")).To(Equal("http2client=0,fips140=on"))
+		})
+
+		It("refuses GODEBUG turning FIPS mode of the Go runtime off", func() {
+			os.Setenv("GODEBUG", "fips140=off")
+
+			_, err := provider.Get("fake-external", map[string]interface{}{})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
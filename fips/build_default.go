@@ -0,0 +1,8 @@
+//go:build !fips
+// +build !fips
+
+package fips
+
+// buildEnabled forces FIPS mode regardless of settings when the agent
+// is compiled with the fips build tag.
+const buildEnabled = false
@@ -0,0 +1,155 @@
+package fips
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"io"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	AlgorithmMD5    = "md5"
+	AlgorithmSHA1   = "sha1"
+	AlgorithmSHA256 = "sha256"
+	AlgorithmSHA512 = "sha512"
+)
+
+// algorithmStrength orders algorithms so that the strongest digest
+// in a multi-digest fingerprint is the one verified.
+var algorithmStrength = map[string]int{
+	AlgorithmMD5:    0,
+	AlgorithmSHA1:   1,
+	AlgorithmSHA256: 2,
+	AlgorithmSHA512: 3,
+}
+
+type Digest struct {
+	Algorithm string
+	Value     string
+}
+
+func (d Digest) String() string {
+	if d.Algorithm == AlgorithmSHA1 {
+		return d.Value
+	}
+	return d.Algorithm + ":" + d.Value
+}
+
+// Approved returns true if the digest algorithm is allowed in FIPS mode.
+func (d Digest) Approved() bool {
+	return d.Algorithm == AlgorithmSHA256 || d.Algorithm == AlgorithmSHA512
+}
+
+// ParseDigest parses fingerprints of the form "sha256:<hex>" or
+// "sha256:<hex>;sha1:<hex>" and returns the strongest digest found.
+// Unprefixed values are treated as SHA-1 or MD5 based on their length.
+func ParseDigest(fingerprint string) (Digest, error) {
+	var strongest Digest
+	found := false
+
+	for _, part := range strings.Split(fingerprint, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		digest, err := parseSingleDigest(part)
+		if err != nil {
+			return Digest{}, err
+		}
+
+		if !found || algorithmStrength[digest.Algorithm] > algorithmStrength[strongest.Algorithm] {
+			strongest = digest
+			found = true
+		}
+	}
+
+	if !found {
+		return Digest{}, bosherr.Errorf("Parsing empty digest '%s'", fingerprint)
+	}
+
+	return strongest, nil
+}
+
+func parseSingleDigest(value string) (Digest, error) {
+	var digest Digest
+
+	pieces := strings.SplitN(value, ":", 2)
+	if len(pieces) == 2 {
+		digest = Digest{Algorithm: strings.ToLower(pieces[0]), Value: strings.ToLower(pieces[1])}
+		if _, found := algorithmStrength[digest.Algorithm]; !found {
+			return Digest{}, bosherr.Errorf("Unsupported digest algorithm '%s'", pieces[0])
+		}
+	} else {
+		digest = Digest{Value: strings.ToLower(value)}
+		switch len(value) {
+		case md5.Size * 2:
+			digest.Algorithm = AlgorithmMD5
+		case sha1.Size * 2:
+			digest.Algorithm = AlgorithmSHA1
+		default:
+			return Digest{}, bosherr.Errorf("Unable to determine digest algorithm of '%s'", value)
+		}
+	}
+
+	if _, err := hex.DecodeString(digest.Value); err != nil {
+		return Digest{}, bosherr.WrapErrorf(err, "Decoding %s digest", digest.Algorithm)
+	}
+
+	return digest, nil
+}
+
+// Calculate computes a digest of the reader using the given algorithm.
+func Calculate(algorithm string, reader io.Reader) (Digest, error) {
+	var h hash.Hash
+
+	switch algorithm {
+	case AlgorithmMD5:
+		h = md5.New()
+	case AlgorithmSHA1:
+		h = sha1.New()
+	case AlgorithmSHA256:
+		h = sha256.New()
+	case AlgorithmSHA512:
+		h = sha512.New()
+	default:
+		return Digest{}, bosherr.Errorf("Unsupported digest algorithm '%s'", algorithm)
+	}
+
+	_, err := io.Copy(h, reader)
+	if err != nil {
+		return Digest{}, bosherr.WrapErrorf(err, "Calculating %s digest", algorithm)
+	}
+
+	return Digest{Algorithm: algorithm, Value: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// Verify checks the reader against the expected fingerprint. In FIPS
+// mode digests using non-approved algorithms (MD5, SHA-1) are refused.
+func Verify(reader io.Reader, fingerprint string, fipsMode bool) error {
+	expected, err := ParseDigest(fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if fipsMode && !expected.Approved() {
+		return bosherr.Errorf("Refusing %s digest verification in FIPS mode", expected.Algorithm)
+	}
+
+	actual, err := Calculate(expected.Algorithm, reader)
+	if err != nil {
+		return err
+	}
+
+	if actual.Value != expected.Value {
+		return bosherr.Errorf("%s mismatch. Expected %s, got %s", strings.ToUpper(expected.Algorithm), expected.Value, actual.Value)
+	}
+
+	return nil
+}
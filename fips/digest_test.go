@@ -0,0 +1,90 @@
+package fips_test
+
+import (
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/fips"
+)
+
+const (
+	helloSHA1   = "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d"
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+)
+
+var _ = Describe("Digest", func() {
+	Describe("ParseDigest", func() {
+		It("treats unprefixed 40 character values as sha1", func() {
+			digest, err := ParseDigest(helloSHA1)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(Digest{Algorithm: "sha1", Value: helloSHA1}))
+			Expect(digest.Approved()).To(BeFalse())
+		})
+
+		It("treats unprefixed 32 character values as md5", func() {
+			digest, err := ParseDigest(helloMD5)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest.Algorithm).To(Equal("md5"))
+		})
+
+		It("parses prefixed sha256 values", func() {
+			digest, err := ParseDigest("sha256:" + helloSHA256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest).To(Equal(Digest{Algorithm: "sha256", Value: helloSHA256}))
+			Expect(digest.Approved()).To(BeTrue())
+			Expect(digest.String()).To(Equal("sha256:" + helloSHA256))
+		})
+
+		It("picks the strongest digest from multiple digests", func() {
+			digest, err := ParseDigest(helloSHA1 + ";sha256:" + helloSHA256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(digest.Algorithm).To(Equal("sha256"))
+		})
+
+		It("returns error for unknown algorithms", func() {
+			_, err := ParseDigest("crc32:abcd")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unsupported digest algorithm 'crc32'"))
+		})
+
+		It("returns error for non-hex values", func() {
+			_, err := ParseDigest("sha256:not-hex")
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns error for empty values", func() {
+			_, err := ParseDigest("")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Verify", func() {
+		It("verifies sha256 digests", func() {
+			err := Verify(strings.NewReader("hello"), "sha256:"+helloSHA256, true)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error on mismatch", func() {
+			err := Verify(strings.NewReader("bye"), "sha256:"+helloSHA256, true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("SHA256 mismatch"))
+		})
+
+		It("verifies sha1 digests outside of FIPS mode", func() {
+			err := Verify(strings.NewReader("hello"), helloSHA1, false)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("refuses sha1 and md5 digests in FIPS mode", func() {
+			err := Verify(strings.NewReader("hello"), helloSHA1, true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Refusing sha1 digest verification in FIPS mode"))
+
+			err = Verify(strings.NewReader("hello"), helloMD5, true)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package fips
+
+import (
+	"crypto/tls"
+)
+
+// Enabled returns true if FIPS mode is enforced either by the build
+// or by the deployment settings.
+func Enabled(enforced bool) bool {
+	return buildEnabled || enforced
+}
+
+// TLSConfig returns a TLS configuration restricted to TLS 1.2 and
+// FIPS 140-2 approved AEAD cipher suites.
+func TLSConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		CipherSuites: []uint16{
+			tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+			tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+			tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+		},
+		CurvePreferences: []tls.CurveID{
+			tls.CurveP256,
+			tls.CurveP384,
+			tls.CurveP521,
+		},
+		PreferServerCipherSuites: true,
+	}
+}
@@ -1,4 +1,4 @@
-package sigar_test
+package fips_test
 
 import (
 	. "github.com/onsi/ginkgo"
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestStats(t *testing.T) {
+func TestFips(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Sigar Suite")
+	RunSpecs(t, "FIPS Suite")
 }
@@ -0,0 +1,94 @@
+package fips
+
+import (
+	"os"
+
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type verifiableBlobstore struct {
+	blobstore boshblob.Blobstore
+	fs        boshsys.FileSystem
+}
+
+// NewVerifiableBlobstore wraps a blobstore so that blobs are verified
+// and fingerprinted with SHA-2 digests only. Fingerprints are never
+// passed to the inner blobstore so that its SHA-1 verification is skipped.
+func NewVerifiableBlobstore(blobstore boshblob.Blobstore, fs boshsys.FileSystem) boshblob.Blobstore {
+	return verifiableBlobstore{blobstore: blobstore, fs: fs}
+}
+
+func (b verifiableBlobstore) Get(blobID, fingerprint string) (string, error) {
+	if fingerprint != "" {
+		digest, err := ParseDigest(fingerprint)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Parsing fingerprint of blob %s", blobID)
+		}
+
+		if !digest.Approved() {
+			return "", bosherr.Errorf("Refusing %s digest verification of blob %s in FIPS mode", digest.Algorithm, blobID)
+		}
+	}
+
+	fileName, err := b.blobstore.Get(blobID, "")
+	if err != nil {
+		return "", bosherr.WrapError(err, "Getting blob from inner blobstore")
+	}
+
+	if fingerprint == "" {
+		return fileName, nil
+	}
+
+	err = b.verify(fileName, fingerprint)
+	if err != nil {
+		_ = b.blobstore.CleanUp(fileName)
+		return "", bosherr.WrapErrorf(err, "Verifying blob %s", blobID)
+	}
+
+	return fileName, nil
+}
+
+func (b verifiableBlobstore) verify(fileName, fingerprint string) error {
+	file, err := b.fs.OpenFile(fileName, os.O_RDONLY, 0)
+	if err != nil {
+		return bosherr.WrapError(err, "Opening blob for digest verification")
+	}
+
+	defer file.Close()
+
+	return Verify(file, fingerprint, true)
+}
+
+func (b verifiableBlobstore) Create(fileName string) (string, string, error) {
+	file, err := b.fs.OpenFile(fileName, os.O_RDONLY, 0)
+	if err != nil {
+		return "", "", bosherr.WrapError(err, "Opening file for sha256 calculation")
+	}
+
+	digest, err := Calculate(AlgorithmSHA256, file)
+	file.Close()
+	if err != nil {
+		return "", "", err
+	}
+
+	blobID, _, err := b.blobstore.Create(fileName)
+	if err != nil {
+		return "", "", err
+	}
+
+	return blobID, digest.String(), nil
+}
+
+func (b verifiableBlobstore) CleanUp(fileName string) error {
+	return b.blobstore.CleanUp(fileName)
+}
+
+func (b verifiableBlobstore) Delete(blobID string) error {
+	return b.blobstore.Delete(blobID)
+}
+
+func (b verifiableBlobstore) Validate() error {
+	return b.blobstore.Validate()
+}
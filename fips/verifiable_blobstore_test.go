@@ -0,0 +1,92 @@
+package fips_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/fips"
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var _ = Describe("verifiableBlobstore", func() {
+	var (
+		innerBlobstore *fakeblob.FakeBlobstore
+		blobstore      boshblob.Blobstore
+		blobFile       *os.File
+	)
+
+	BeforeEach(func() {
+		var err error
+		blobFile, err = ioutil.TempFile("", "fips-blob")
+		Expect(err).ToNot(HaveOccurred())
+		_, err = blobFile.Write([]byte("hello"))
+		Expect(err).ToNot(HaveOccurred())
+		blobFile.Close()
+
+		innerBlobstore = fakeblob.NewFakeBlobstore()
+		innerBlobstore.GetFileName = blobFile.Name()
+
+		fs := boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+		blobstore = NewVerifiableBlobstore(innerBlobstore, fs)
+	})
+
+	AfterEach(func() {
+		os.Remove(blobFile.Name())
+	})
+
+	Describe("Get", func() {
+		It("verifies sha256 fingerprints without passing them to the inner blobstore", func() {
+			fileName, err := blobstore.Get("fake-blob-id", "sha256:"+helloSHA256)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fileName).To(Equal(blobFile.Name()))
+			Expect(innerBlobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+			Expect(innerBlobstore.GetFingerprints).To(Equal([]string{""}))
+		})
+
+		It("returns error and cleans up blob on digest mismatch", func() {
+			_, err := blobstore.Get("fake-blob-id", "sha256:"+helloSHA256[1:]+"0")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("SHA256 mismatch"))
+			Expect(innerBlobstore.CleanUpFileName).To(Equal(blobFile.Name()))
+		})
+
+		It("refuses sha1 fingerprints without downloading the blob", func() {
+			_, err := blobstore.Get("fake-blob-id", helloSHA1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Refusing sha1 digest verification of blob fake-blob-id in FIPS mode"))
+			Expect(innerBlobstore.GetBlobIDs).To(BeEmpty())
+		})
+
+		It("returns error if inner blobstore fails", func() {
+			innerBlobstore.GetError = errors.New("fake-get-err")
+			_, err := blobstore.Get("fake-blob-id", "sha256:"+helloSHA256)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-get-err"))
+		})
+	})
+
+	Describe("Create", func() {
+		It("returns a sha256 fingerprint", func() {
+			innerBlobstore.CreateBlobID = "fake-blob-id"
+			innerBlobstore.CreateFingerprint = helloSHA1
+
+			blobID, fingerprint, err := blobstore.Create(blobFile.Name())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(blobID).To(Equal("fake-blob-id"))
+			Expect(fingerprint).To(Equal("sha256:" + helloSHA256))
+		})
+
+		It("returns error if inner blobstore fails", func() {
+			innerBlobstore.CreateErr = errors.New("fake-create-err")
+			_, _, err := blobstore.Create(blobFile.Name())
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
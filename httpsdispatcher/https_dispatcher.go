@@ -5,11 +5,20 @@ import (
 	"net"
 	"net/http"
 	"net/url"
+	"os"
+	"sync"
+	"time"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
+const (
+	// Agent certificate and its private key are relative to working directory
+	CertificatePath = "agent.cert"
+	PrivateKeyPath  = "agent.key"
+)
+
 type HTTPSDispatcher struct {
 	httpServer *http.Server
 	mux        *http.ServeMux
@@ -21,7 +30,11 @@ type HTTPSDispatcher struct {
 type HTTPHandlerFunc func(writer http.ResponseWriter, request *http.Request)
 
 func NewHTTPSDispatcher(baseURL *url.URL, logger boshlog.Logger) *HTTPSDispatcher {
-	tlsConfig := &tls.Config{
+	return NewHTTPSDispatcherWithConfig(DefaultTLSConfig(), baseURL, logger)
+}
+
+func DefaultTLSConfig() *tls.Config {
+	return &tls.Config{
 		// SSLv3 is insecure due to BEAST and POODLE attacks
 		MinVersion: tls.VersionTLS10,
 		// Both 3DES & RC4 ciphers can be exploited
@@ -38,7 +51,6 @@ func NewHTTPSDispatcher(baseURL *url.URL, logger boshlog.Logger) *HTTPSDispatche
 		},
 		PreferServerCipherSuites: true,
 	}
-	return NewHTTPSDispatcherWithConfig(tlsConfig, baseURL, logger)
 }
 
 func NewHTTPSDispatcherWithConfig(tlsConfig *tls.Config, baseURL *url.URL, logger boshlog.Logger) *HTTPSDispatcher {
@@ -63,15 +75,21 @@ func (h *HTTPSDispatcher) Start() error {
 	}
 	h.listener = tcpListener
 
-	cert, err := tls.LoadX509KeyPair("agent.cert", "agent.key")
-	if err != nil {
-		return bosherr.WrapError(err, "Loading agent SSL cert")
-	}
-
-	// update the server config with the cert
 	config := h.httpServer.TLSConfig
 	config.NextProtos = []string{"http/1.1"}
-	config.Certificates = []tls.Certificate{cert}
+
+	// Certificates with device-backed private keys are provided with the config
+	if len(config.Certificates) == 0 {
+		reloader := &certificateReloader{certPath: CertificatePath, keyPath: PrivateKeyPath}
+
+		_, err := reloader.GetCertificate(nil)
+		if err != nil {
+			return bosherr.WrapError(err, "Loading agent SSL cert")
+		}
+
+		// update the server config with the cert
+		config.GetCertificate = reloader.GetCertificate
+	}
 
 	tlsListener := tls.NewListener(tcpListener, config)
 
@@ -88,3 +106,43 @@ func (h *HTTPSDispatcher) Stop() {
 func (h *HTTPSDispatcher) AddRoute(route string, handler HTTPHandlerFunc) {
 	h.mux.HandleFunc(route, handler)
 }
+
+// certificateReloader serves agent certificate and picks up certificates
+// installed while the agent is running, e.g. by install_certificate action
+type certificateReloader struct {
+	certPath string
+	keyPath  string
+
+	lock    sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+func (r *certificateReloader) GetCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	certInfo, err := os.Stat(r.certPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.cert != nil && certInfo.ModTime().Equal(r.modTime) {
+		return r.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.certPath, r.keyPath)
+	if err != nil {
+		// Certificate and key are replaced one after another
+		if r.cert != nil {
+			return r.cert, nil
+		}
+
+		return nil, err
+	}
+
+	r.cert = &cert
+	r.modTime = certInfo.ModTime()
+
+	return r.cert, nil
+}
@@ -0,0 +1,119 @@
+// Package identitykey keeps private key of agent identity in TPM 2.0 chips or PKCS#11
+// tokens so that copies of disk images cannot impersonate agents; the agent only holds
+// the certificate and delegates signing of TLS handshakes to the device.
+package identitykey
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var hashNames = map[crypto.Hash]string{
+	crypto.SHA1:   "sha1",
+	crypto.SHA256: "sha256",
+	crypto.SHA384: "sha384",
+	crypto.SHA512: "sha512",
+}
+
+// LoadCertificate returns certificate from certPath whose private key stays in the device
+func LoadCertificate(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, certPath string, identityKey boshsettings.IdentityKey) (tls.Certificate, error) {
+	if identityKey.Type != boshsettings.IdentityKeyTypeTPM && identityKey.Type != boshsettings.IdentityKeyTypePKCS11 {
+		return tls.Certificate{}, bosherr.Errorf("Identity key type '%s' is not supported", identityKey.Type)
+	}
+
+	if identityKey.KeyID == "" {
+		return tls.Certificate{}, bosherr.Errorf("Identity key of type '%s' requires a key id", identityKey.Type)
+	}
+
+	if len(identityKey.SignCommand) == 0 {
+		return tls.Certificate{}, bosherr.Errorf("Identity key of type '%s' requires a sign command", identityKey.Type)
+	}
+
+	certPEM, err := fs.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapErrorf(err, "Reading agent certificate %s", certPath)
+	}
+
+	block, _ := pem.Decode(certPEM)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return tls.Certificate{}, bosherr.Errorf("Agent certificate %s is not a PEM encoded certificate", certPath)
+	}
+
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return tls.Certificate{}, bosherr.WrapErrorf(err, "Parsing agent certificate %s", certPath)
+	}
+
+	switch leaf.PublicKey.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return tls.Certificate{}, bosherr.Errorf("Public key of agent certificate %s must be RSA or ECDSA", certPath)
+	}
+
+	signer := commandSigner{
+		publicKey:   leaf.PublicKey,
+		identityKey: identityKey,
+		cmdRunner:   cmdRunner,
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{block.Bytes},
+		PrivateKey:  signer,
+		Leaf:        leaf,
+	}, nil
+}
+
+type commandSigner struct {
+	publicKey   crypto.PublicKey
+	identityKey boshsettings.IdentityKey
+	cmdRunner   boshsys.CmdRunner
+}
+
+func (s commandSigner) Public() crypto.PublicKey {
+	return s.publicKey
+}
+
+func (s commandSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	hashName, found := hashNames[opts.HashFunc()]
+	if !found {
+		return nil, bosherr.Errorf("Hash function '%d' is not supported", opts.HashFunc())
+	}
+
+	scheme := "pkcs1v15"
+	if _, isECDSA := s.publicKey.(*ecdsa.PublicKey); isECDSA {
+		scheme = "ecdsa"
+	} else if _, isPSS := opts.(*rsa.PSSOptions); isPSS {
+		scheme = "pss"
+	}
+
+	args := append([]string{}, s.identityKey.SignCommand[1:]...)
+	args = append(args, s.identityKey.KeyID, hashName, scheme)
+
+	stdout, _, _, err := s.cmdRunner.RunComplexCommand(boshsys.Command{
+		Name:  s.identityKey.SignCommand[0],
+		Args:  args,
+		Stdin: bytes.NewReader(digest),
+	})
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Signing with %s identity key", s.identityKey.Type)
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(strings.TrimSpace(stdout))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Decoding signature")
+	}
+
+	return signature, nil
+}
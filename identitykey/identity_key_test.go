@@ -0,0 +1,144 @@
+package identitykey_test
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/identitykey"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("LoadCertificate", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		cmdRunner   *fakesys.FakeCmdRunner
+		identityKey boshsettings.IdentityKey
+		privateKey  *ecdsa.PrivateKey
+		digest      []byte
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		identityKey = boshsettings.IdentityKey{
+			Type:        "tpm",
+			KeyID:       "0x81010001",
+			SignCommand: []string{"/fake-tpm-sign", "--fake-arg"},
+		}
+
+		var err error
+		privateKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "fake-agent"},
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		fs.WriteFile("/fake-agent.cert", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+		sum := sha256.Sum256([]byte("fake-handshake"))
+		digest = sum[:]
+	})
+
+	It("returns certificate that signs with the key in the device", func() {
+		signature, err := privateKey.Sign(rand.Reader, digest, crypto.SHA256)
+		Expect(err).ToNot(HaveOccurred())
+
+		cmdRunner.AddCmdResult("/fake-tpm-sign --fake-arg 0x81010001 sha256 ecdsa", fakesys.FakeCmdResult{
+			Stdout: base64.StdEncoding.EncodeToString(signature) + "\n",
+		})
+
+		cert, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cert.Leaf.Subject.CommonName).To(Equal("fake-agent"))
+
+		signer, ok := cert.PrivateKey.(crypto.Signer)
+		Expect(ok).To(BeTrue())
+		Expect(signer.Public()).To(Equal(privateKey.Public()))
+
+		deviceSignature, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(deviceSignature).To(Equal(signature))
+
+		Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+
+		stdin, err := ioutil.ReadAll(cmdRunner.RunComplexCommands[0].Stdin)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stdin).To(Equal(digest))
+	})
+
+	It("returns an error when signing fails", func() {
+		cmdRunner.AddCmdResult("/fake-tpm-sign --fake-arg 0x81010001 sha256 ecdsa", fakesys.FakeCmdResult{Error: errors.New("fake-sign-err")})
+
+		cert, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = cert.PrivateKey.(crypto.Signer).Sign(rand.Reader, digest, crypto.SHA256)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Signing with tpm identity key: fake-sign-err"))
+	})
+
+	It("passes pss signature scheme for RSA-PSS signatures", func() {
+		rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).ToNot(HaveOccurred())
+
+		template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+		der, err := x509.CreateCertificate(rand.Reader, template, template, rsaKey.Public(), rsaKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		fs.WriteFile("/fake-agent.cert", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+		cert, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = cert.PrivateKey.(crypto.Signer).Sign(rand.Reader, digest, &rsa.PSSOptions{Hash: crypto.SHA256})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmdRunner.RunComplexCommands[0].Args).To(Equal([]string{"--fake-arg", "0x81010001", "sha256", "pss"}))
+	})
+
+	It("returns an error when identity key type is not supported", func() {
+		identityKey.Type = "fake-type"
+
+		_, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Identity key type 'fake-type' is not supported"))
+	})
+
+	It("returns an error when sign command is missing", func() {
+		identityKey.SignCommand = nil
+
+		_, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Identity key of type 'tpm' requires a sign command"))
+	})
+
+	It("returns an error when certificate is not PEM encoded", func() {
+		fs.WriteFileString("/fake-agent.cert", "fake-cert")
+
+		_, err := LoadCertificate(fs, cmdRunner, "/fake-agent.cert", identityKey)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Agent certificate /fake-agent.cert is not a PEM encoded certificate"))
+	})
+})
@@ -0,0 +1,13 @@
+package identitykey_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestIdentityKey(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Identity Key Suite")
+}
@@ -0,0 +1,92 @@
+package devicepathresolver
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type azureLUNDevicePathResolver struct {
+	diskWaitTimeout  time.Duration
+	eventMonitor     boshudev.EventMonitor
+	fs               boshsys.FileSystem
+	fallbackResolver DevicePathResolver
+	logger           boshlog.Logger
+	logTag           string
+}
+
+// NewAzureLUNDevicePathResolver returns resolver that finds Azure data disks by
+// their LUN, which unlike sdX names does not change across reboots. Links created
+// by Azure udev rules are used; disks are looked up on the SCSI controller
+// with the host device id when the links are missing.
+// Disks without LUN (e.g. resource disk) are resolved with the fallback resolver
+func NewAzureLUNDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	fs boshsys.FileSystem,
+	fallbackResolver DevicePathResolver,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return azureLUNDevicePathResolver{
+		diskWaitTimeout:  diskWaitTimeout,
+		eventMonitor:     eventMonitor,
+		fs:               fs,
+		fallbackResolver: fallbackResolver,
+		logger:           logger,
+		logTag:           "azureLUNDevicePathResolver",
+	}
+}
+
+func (r azureLUNDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	if diskSettings.Lun == "" {
+		return r.fallbackResolver.GetRealDevicePath(diskSettings)
+	}
+
+	lunPath := path.Join("/dev/disk/azure/scsi1", "lun"+diskSettings.Lun)
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		realPath, err := r.fs.ReadLink(lunPath)
+		if err == nil && r.fs.FileExists(realPath) {
+			r.logger.Debug(r.logTag, "Resolved LUN %s to %s", diskSettings.Lun, realPath)
+			return realPath, false, nil
+		}
+
+		realPath, found := r.findDeviceOnHost(diskSettings.HostDeviceID, diskSettings.Lun)
+		if found {
+			r.logger.Debug(r.logTag, "Resolved LUN %s on host %s to %s", diskSettings.Lun, diskSettings.HostDeviceID, realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path for LUN '%s'", diskSettings.Lun)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
+
+// findDeviceOnHost looks up block device of the LUN on the SCSI controller attached
+// as VMBus device with the host device id, e.g. {f8b3781b-1e82-4818-a1c3-63d806ec15bb}
+func (r azureLUNDevicePathResolver) findDeviceOnHost(hostDeviceID, lun string) (string, bool) {
+	if hostDeviceID == "" {
+		return "", false
+	}
+
+	hostDeviceID = strings.Trim(hostDeviceID, "{}")
+	blockGlob := fmt.Sprintf("/sys/bus/vmbus/devices/%s/host*/target*/*:*:*:%s/block/*", hostDeviceID, lun)
+
+	blockPaths, err := r.fs.Glob(blockGlob)
+	if err != nil || len(blockPaths) == 0 {
+		return "", false
+	}
+
+	return path.Join("/dev", path.Base(blockPaths[0])), true
+}
@@ -0,0 +1,85 @@
+package devicepathresolver_test
+
+import (
+	"time"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("azureLUNDevicePathResolver", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		fallbackResolver *fakedpresolv.FakeDevicePathResolver
+		diskSettings     boshsettings.DiskSettings
+		pathResolver     DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fallbackResolver = fakedpresolv.NewFakeDevicePathResolver()
+		diskSettings = boshsettings.DiskSettings{
+			ID:           "fake-disk-id",
+			Lun:          "2",
+			HostDeviceID: "{f8b3781b-1e82-4818-a1c3-63d806ec15bb}",
+		}
+		pathResolver = NewAzureLUNDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), fs, fallbackResolver, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("GetRealDevicePath", func() {
+		It("returns device linked from LUN link created by Azure udev rules", func() {
+			fs.WriteFileString("/dev/sdd", "")
+			fs.Symlink("/dev/sdd", "/dev/disk/azure/scsi1/lun2")
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdd"))
+		})
+
+		It("returns device of the LUN on SCSI controller with host device id when LUN link is missing", func() {
+			fs.SetGlob("/sys/bus/vmbus/devices/f8b3781b-1e82-4818-a1c3-63d806ec15bb/host*/target*/*:*:*:2/block/*", []string{
+				"/sys/bus/vmbus/devices/f8b3781b-1e82-4818-a1c3-63d806ec15bb/host5/target5:0:0/5:0:0:2/block/sde",
+			})
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sde"))
+		})
+
+		It("times out when device of the LUN does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out getting real device path for LUN '2'"))
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("times out when LUN link points to missing device and host device id is not set", func() {
+			fs.Symlink("/dev/sdd", "/dev/disk/azure/scsi1/lun2")
+			diskSettings.HostDeviceID = ""
+
+			_, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("resolves disks without LUN with fallback resolver", func() {
+			fallbackResolver.RealDevicePath = "/dev/sdb"
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdb"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdb"))
+			Expect(fallbackResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{Path: "/dev/sdb"}))
+		})
+	})
+})
@@ -0,0 +1,89 @@
+package devicepathresolver
+
+import (
+	"fmt"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// ChainedStrategy is a named device path resolver tried by chained resolver
+type ChainedStrategy struct {
+	Name     string
+	Resolver DevicePathResolver
+}
+
+// ResolutionAttempt records outcome of a strategy that failed to resolve device path
+type ResolutionAttempt struct {
+	Strategy string
+	TimedOut bool
+	Err      error
+}
+
+// ChainedResolutionError is returned when none of the strategies resolved
+// device path; it lists every attempted strategy in order
+type ChainedResolutionError struct {
+	DiskID   string
+	Attempts []ResolutionAttempt
+}
+
+func (e ChainedResolutionError) Error() string {
+	attempts := make([]string, len(e.Attempts))
+
+	for i, attempt := range e.Attempts {
+		attempts[i] = fmt.Sprintf("%s (timed out: %t): %s", attempt.Strategy, attempt.TimedOut, attempt.Err.Error())
+	}
+
+	return fmt.Sprintf("Resolving device path of disk '%s' failed with all strategies: %s", e.DiskID, strings.Join(attempts, "; "))
+}
+
+type chainedDevicePathResolver struct {
+	strategies []ChainedStrategy
+	logger     boshlog.Logger
+	logTag     string
+}
+
+// NewChainedDevicePathResolver returns resolver that tries strategies in order
+// and returns device path found by the first one that succeeds.
+// Each strategy is expected to enforce its own timeout.
+func NewChainedDevicePathResolver(strategies []ChainedStrategy, logger boshlog.Logger) DevicePathResolver {
+	return chainedDevicePathResolver{
+		strategies: strategies,
+		logger:     logger,
+		logTag:     "chainedDevicePathResolver",
+	}
+}
+
+func (r chainedDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	resolutionErr := ChainedResolutionError{DiskID: diskSettings.ID}
+
+	for _, strategy := range r.strategies {
+		realPath, timedOut, err := strategy.Resolver.GetRealDevicePath(diskSettings)
+		if err == nil {
+			r.logger.Debug(r.logTag, "Resolved disk '%s' to %s with strategy %s", diskSettings.ID, realPath, strategy.Name)
+			return realPath, false, nil
+		}
+
+		r.logger.Warn(r.logTag, "Failed to resolve disk '%s' with strategy %s (timed out: %t): %s", diskSettings.ID, strategy.Name, timedOut, err.Error())
+
+		resolutionErr.Attempts = append(resolutionErr.Attempts, ResolutionAttempt{
+			Strategy: strategy.Name,
+			TimedOut: timedOut,
+			Err:      err,
+		})
+	}
+
+	return "", resolutionErr.allTimedOut(), resolutionErr
+}
+
+// allTimedOut reports whether device did not show up with any strategy,
+// i.e. disk is most likely not attached
+func (e ChainedResolutionError) allTimedOut() bool {
+	for _, attempt := range e.Attempts {
+		if !attempt.TimedOut {
+			return false
+		}
+	}
+	return len(e.Attempts) > 0
+}
@@ -0,0 +1,95 @@
+package devicepathresolver_test
+
+import (
+	"errors"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("chainedDevicePathResolver", func() {
+	var (
+		firstResolver  *fakedpresolv.FakeDevicePathResolver
+		secondResolver *fakedpresolv.FakeDevicePathResolver
+		diskSettings   boshsettings.DiskSettings
+		pathResolver   DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		firstResolver = fakedpresolv.NewFakeDevicePathResolver()
+		secondResolver = fakedpresolv.NewFakeDevicePathResolver()
+		diskSettings = boshsettings.DiskSettings{ID: "fake-disk-id", Path: "/dev/sdf"}
+
+		pathResolver = NewChainedDevicePathResolver([]ChainedStrategy{
+			{Name: "first", Resolver: firstResolver},
+			{Name: "second", Resolver: secondResolver},
+		}, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("GetRealDevicePath", func() {
+		It("returns device path resolved by first strategy", func() {
+			firstResolver.RealDevicePath = "/dev/sdb"
+			secondResolver.RealDevicePath = "/dev/sdc"
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdb"))
+			Expect(secondResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{}))
+		})
+
+		It("falls back to next strategy when previous one fails", func() {
+			firstResolver.GetRealDevicePathErr = errors.New("fake-first-err")
+			firstResolver.GetRealDevicePathTimedOut = true
+			secondResolver.RealDevicePath = "/dev/sdc"
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdc"))
+			Expect(secondResolver.GetRealDevicePathDiskSettings).To(Equal(diskSettings))
+		})
+
+		Context("when all strategies fail", func() {
+			BeforeEach(func() {
+				firstResolver.GetRealDevicePathErr = errors.New("fake-first-err")
+				firstResolver.GetRealDevicePathTimedOut = true
+				secondResolver.GetRealDevicePathErr = errors.New("fake-second-err")
+			})
+
+			It("returns error listing every attempted strategy", func() {
+				_, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal(
+					"Resolving device path of disk 'fake-disk-id' failed with all strategies: " +
+						"first (timed out: true): fake-first-err; second (timed out: false): fake-second-err",
+				))
+
+				resolutionErr, ok := err.(ChainedResolutionError)
+				Expect(ok).To(BeTrue())
+				Expect(resolutionErr.Attempts).To(Equal([]ResolutionAttempt{
+					{Strategy: "first", TimedOut: true, Err: errors.New("fake-first-err")},
+					{Strategy: "second", TimedOut: false, Err: errors.New("fake-second-err")},
+				}))
+			})
+
+			It("does not report time out if some strategy failed otherwise", func() {
+				_, timedOut, _ := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(timedOut).To(BeFalse())
+			})
+
+			It("reports time out if every strategy timed out", func() {
+				secondResolver.GetRealDevicePathTimedOut = true
+
+				_, timedOut, _ := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(timedOut).To(BeTrue())
+			})
+		})
+	})
+})
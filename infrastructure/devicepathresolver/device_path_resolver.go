@@ -7,3 +7,10 @@ import (
 type DevicePathResolver interface {
 	GetRealDevicePath(diskSettings boshsettings.DiskSettings) (realPath string, timedOut bool, err error)
 }
+
+// WrappingDevicePathResolver is implemented by resolvers that refine
+// paths resolved by another resolver
+type WrappingDevicePathResolver interface {
+	DevicePathResolver
+	Delegate() DevicePathResolver
+}
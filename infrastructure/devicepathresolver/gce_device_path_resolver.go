@@ -0,0 +1,72 @@
+package devicepathresolver
+
+import (
+	"path"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const gceDiskByIDPrefix = "google-"
+
+type gceDevicePathResolver struct {
+	diskWaitTimeout  time.Duration
+	eventMonitor     boshudev.EventMonitor
+	fs               boshsys.FileSystem
+	fallbackResolver DevicePathResolver
+	logger           boshlog.Logger
+	logTag           string
+}
+
+// NewGCEDevicePathResolver returns resolver that finds Google persistent disks
+// by the links that GCE udev rules create under /dev/disk/by-id from device names,
+// e.g. /dev/disk/by-id/google-disk-1234. Google CPI attaches disks using disk name as device name.
+// Disks without id (e.g. ephemeral disk given only as path) are resolved with the fallback resolver
+func NewGCEDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	fs boshsys.FileSystem,
+	fallbackResolver DevicePathResolver,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return gceDevicePathResolver{
+		diskWaitTimeout:  diskWaitTimeout,
+		eventMonitor:     eventMonitor,
+		fs:               fs,
+		fallbackResolver: fallbackResolver,
+		logger:           logger,
+		logTag:           "gceDevicePathResolver",
+	}
+}
+
+func (r gceDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	deviceName := diskSettings.DeviceID
+	if deviceName == "" {
+		deviceName = diskSettings.ID
+	}
+
+	if deviceName == "" {
+		return r.fallbackResolver.GetRealDevicePath(diskSettings)
+	}
+
+	byIDPath := path.Join("/dev/disk/by-id", gceDiskByIDPrefix+deviceName)
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		realPath, err := r.fs.ReadLink(byIDPath)
+		if err == nil && r.fs.FileExists(realPath) {
+			r.logger.Debug(r.logTag, "Resolved disk %s to %s", deviceName, realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", byIDPath)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
@@ -0,0 +1,79 @@
+package devicepathresolver_test
+
+import (
+	"time"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("gceDevicePathResolver", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		fallbackResolver *fakedpresolv.FakeDevicePathResolver
+		pathResolver     DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fallbackResolver = fakedpresolv.NewFakeDevicePathResolver()
+		pathResolver = NewGCEDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), fs, fallbackResolver, boshlog.NewLogger(boshlog.LevelNone))
+
+		fs.WriteFileString("/dev/sdc", "")
+		fs.Symlink("/dev/sdc", "/dev/disk/by-id/google-disk-1234")
+	})
+
+	Describe("GetRealDevicePath", func() {
+		It("returns device linked from by-id path of the disk name", func() {
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{
+				ID:   "disk-1234",
+				Path: "/dev/sdb",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdc"))
+		})
+
+		It("prefers device id over disk id", func() {
+			realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{
+				ID:       "fake-disk-cid",
+				DeviceID: "disk-1234",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/sdc"))
+		})
+
+		It("times out when by-id link does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{ID: "disk-5678"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out getting real device path for '/dev/disk/by-id/google-disk-5678'"))
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("times out when by-id link points to missing device", func() {
+			fs.Symlink("/dev/sdd", "/dev/disk/by-id/google-disk-5678")
+
+			_, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{ID: "disk-5678"})
+			Expect(err).To(HaveOccurred())
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("resolves disks without id with fallback resolver", func() {
+			fallbackResolver.RealDevicePath = "/dev/sdb"
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdb"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdb"))
+			Expect(fallbackResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{Path: "/dev/sdb"}))
+		})
+	})
+})
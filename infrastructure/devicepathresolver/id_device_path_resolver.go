@@ -15,6 +15,7 @@ const defaultDevicePrefix = "virtio"
 
 type idDevicePathResolver struct {
 	diskWaitTimeout time.Duration
+	eventMonitor    boshudev.EventMonitor
 	devicePrefix    string
 	udev            boshudev.UdevDevice
 	fs              boshsys.FileSystem
@@ -22,12 +23,14 @@ type idDevicePathResolver struct {
 
 func NewIDDevicePathResolver(
 	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
 	devicePrefix string,
 	udev boshudev.UdevDevice,
 	fs boshsys.FileSystem,
 ) DevicePathResolver {
 	return idDevicePathResolver{
 		diskWaitTimeout: diskWaitTimeout,
+		eventMonitor:    eventMonitor,
 		devicePrefix:    devicePrefix,
 		udev:            udev,
 		fs:              fs,
@@ -69,7 +72,7 @@ func (idpr idDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.Dis
 			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", diskID)
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		idpr.eventMonitor.WaitForEvent(time.Until(stopAfter))
 
 		deviceIDPath := path.Join("/", "dev", "disk", "by-id", deviceID)
 		realPath, err = idpr.fs.ReadLink(deviceIDPath)
@@ -34,7 +34,7 @@ var _ = Describe("IDDevicePathResolver", func() {
 	})
 
 	JustBeforeEach(func() {
-		pathResolver = NewIDDevicePathResolver(500*time.Millisecond, devicePrefix, udev, fs)
+		pathResolver = NewIDDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), devicePrefix, udev, fs)
 	})
 
 	Describe("GetRealDevicePath", func() {
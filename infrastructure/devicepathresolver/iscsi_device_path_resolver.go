@@ -0,0 +1,202 @@
+package devicepathresolver
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	iscsiInitiatorNamePath = "/etc/iscsi/initiatorname.iscsi"
+	iscsidConfPath         = "/etc/iscsi/iscsid.conf"
+	iscsiDefaultPort       = "3260"
+
+	// iscsiadm exits with 15 when logging into a target with an existing session
+	iscsiadmSessionExistsExitStatus = 15
+)
+
+type iscsiDevicePathResolver struct {
+	diskWaitTimeout  time.Duration
+	eventMonitor     boshudev.EventMonitor
+	runner           boshsys.CmdRunner
+	fs               boshsys.FileSystem
+	fallbackResolver DevicePathResolver
+	logger           boshlog.Logger
+	logTag           string
+}
+
+// NewISCSIDevicePathResolver returns resolver that logs into iSCSI targets of
+// disks with iSCSI settings; other disks (e.g. local ephemeral disks)
+// are resolved with the fallback resolver
+func NewISCSIDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	runner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	fallbackResolver DevicePathResolver,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return iscsiDevicePathResolver{
+		diskWaitTimeout:  diskWaitTimeout,
+		eventMonitor:     eventMonitor,
+		runner:           runner,
+		fs:               fs,
+		fallbackResolver: fallbackResolver,
+		logger:           logger,
+		logTag:           "iscsiDevicePathResolver",
+	}
+}
+
+func (r iscsiDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	iscsiSettings := diskSettings.ISCSISettings
+	if iscsiSettings == nil {
+		return r.fallbackResolver.GetRealDevicePath(diskSettings)
+	}
+
+	if iscsiSettings.Target == "" || iscsiSettings.IQN == "" {
+		return "", false, bosherr.Error("iSCSI target or IQN is not set")
+	}
+
+	err := r.configureInitiatorName(iscsiSettings.InitiatorName)
+	if err != nil {
+		return "", false, err
+	}
+
+	portal := iscsiSettings.Target
+	if !strings.Contains(portal, ":") {
+		portal = portal + ":" + iscsiDefaultPort
+	}
+
+	if iscsiSettings.Username != "" {
+		err = r.configureCHAP(iscsiSettings.Username, iscsiSettings.Password)
+		if err != nil {
+			return "", false, err
+		}
+	}
+
+	_, _, _, err = r.runner.RunCommand("iscsiadm", "-m", "discovery", "-t", "sendtargets", "-p", portal)
+	if err != nil {
+		return "", false, bosherr.WrapErrorf(err, "Discovering iSCSI targets on %s", portal)
+	}
+
+	_, _, exitStatus, err := r.runner.RunCommand("iscsiadm", "-m", "node", "-T", iscsiSettings.IQN, "-p", portal, "--login")
+	if err != nil && exitStatus != iscsiadmSessionExistsExitStatus {
+		return "", false, bosherr.WrapErrorf(err, "Logging into iSCSI target %s", iscsiSettings.IQN)
+	}
+
+	lun := iscsiSettings.LUN
+	if lun == "" {
+		lun = "0"
+	}
+
+	byPath := path.Join("/dev/disk/by-path", fmt.Sprintf("ip-%s-iscsi-%s-lun-%s", portal, iscsiSettings.IQN, lun))
+
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		realPath, err := r.fs.ReadLink(byPath)
+		if err == nil && r.fs.FileExists(realPath) {
+			r.logger.Debug(r.logTag, "Resolved iSCSI disk %s to %s", byPath, realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", byPath)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
+
+// configureCHAP keeps CHAP credentials in iscsid.conf readable only by root instead
+// of passing them on iscsiadm command line; discovery copies them into node records
+func (r iscsiDevicePathResolver) configureCHAP(username, password string) error {
+	contents, err := r.fs.ReadFileString(iscsidConfPath)
+	if err != nil && r.fs.FileExists(iscsidConfPath) {
+		return bosherr.WrapError(err, "Reading iscsid.conf")
+	}
+
+	var lines []string
+
+	for _, line := range strings.Split(strings.TrimSuffix(contents, "\n"), "\n") {
+		name := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		if name == "node.session.auth.authmethod" || name == "node.session.auth.username" || name == "node.session.auth.password" {
+			continue
+		}
+
+		if line != "" || len(lines) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	lines = append(lines,
+		"node.session.auth.authmethod = CHAP",
+		"node.session.auth.username = "+username,
+		"node.session.auth.password = "+password,
+	)
+
+	newContents := strings.Join(lines, "\n") + "\n"
+	if newContents == contents {
+		return nil
+	}
+
+	// Credentials are never readable by others, not even before the rename
+	tmpPath := iscsidConfPath + ".bosh-new"
+
+	file, err := r.fs.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, os.FileMode(0600))
+	if err != nil {
+		return bosherr.WrapError(err, "Opening iscsid.conf for writing")
+	}
+
+	_, err = file.Write([]byte(newContents))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = r.fs.RemoveAll(tmpPath)
+		return bosherr.WrapError(err, "Writing iscsid.conf")
+	}
+
+	err = r.fs.Rename(tmpPath, iscsidConfPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Replacing iscsid.conf")
+	}
+
+	return nil
+}
+
+// configureInitiatorName makes open-iscsi log in with the initiator name
+// the target was configured to accept; iscsid only reads it on start
+func (r iscsiDevicePathResolver) configureInitiatorName(initiatorName string) error {
+	if initiatorName == "" {
+		return nil
+	}
+
+	contents := fmt.Sprintf("InitiatorName=%s\n", initiatorName)
+
+	existingContents, err := r.fs.ReadFileString(iscsiInitiatorNamePath)
+	if err == nil && existingContents == contents {
+		return nil
+	}
+
+	err = r.fs.WriteFileString(iscsiInitiatorNamePath, contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing iSCSI initiator name")
+	}
+
+	_, _, _, err = r.runner.RunCommand("/etc/init.d/open-iscsi", "restart")
+	if err != nil {
+		return bosherr.WrapError(err, "Restarting open-iscsi")
+	}
+
+	return nil
+}
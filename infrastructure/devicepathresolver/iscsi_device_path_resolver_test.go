@@ -0,0 +1,151 @@
+package devicepathresolver_test
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("iscsiDevicePathResolver", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		runner           *fakesys.FakeCmdRunner
+		fallbackResolver *fakedpresolv.FakeDevicePathResolver
+		diskSettings     boshsettings.DiskSettings
+		pathResolver     DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		fallbackResolver = fakedpresolv.NewFakeDevicePathResolver()
+		diskSettings = boshsettings.DiskSettings{
+			ID: "fake-disk-id",
+			ISCSISettings: &boshsettings.ISCSISettings{
+				InitiatorName: "iqn.2007-05.com.example:fake-initiator",
+				Target:        "10.0.0.1",
+				IQN:           "iqn.2007-05.com.example:fake-target",
+				LUN:           "1",
+			},
+		}
+		pathResolver = NewISCSIDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), runner, fs, fallbackResolver, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("GetRealDevicePath", func() {
+		Context("when device shows up after logging into the target", func() {
+			BeforeEach(func() {
+				err := fs.MkdirAll("/dev/sdc", os.FileMode(0750))
+				Expect(err).ToNot(HaveOccurred())
+
+				err = fs.Symlink("/dev/sdc", "/dev/disk/by-path/ip-10.0.0.1:3260-iscsi-iqn.2007-05.com.example:fake-target-lun-1")
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("configures initiator name, logs into the target and returns the device", func() {
+				realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(timedOut).To(BeFalse())
+				Expect(realPath).To(Equal("/dev/sdc"))
+
+				contents, err := fs.ReadFileString("/etc/iscsi/initiatorname.iscsi")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(contents).To(Equal("InitiatorName=iqn.2007-05.com.example:fake-initiator\n"))
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"/etc/init.d/open-iscsi", "restart"},
+					{"iscsiadm", "-m", "discovery", "-t", "sendtargets", "-p", "10.0.0.1:3260"},
+					{"iscsiadm", "-m", "node", "-T", "iqn.2007-05.com.example:fake-target", "-p", "10.0.0.1:3260", "--login"},
+				}))
+			})
+
+			It("does not restart open-iscsi if initiator name is already configured", func() {
+				fs.WriteFileString("/etc/iscsi/initiatorname.iscsi", "InitiatorName=iqn.2007-05.com.example:fake-initiator\n")
+
+				_, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"/etc/init.d/open-iscsi", "restart"}))
+			})
+
+			It("configures CHAP authentication when credentials are given", func() {
+				diskSettings.ISCSISettings.Username = "fake-username"
+				diskSettings.ISCSISettings.Password = "fake-password"
+
+				fs.WriteFileString("/etc/iscsi/iscsid.conf", "node.startup = automatic\nnode.session.auth.username = old-username\n")
+
+				_, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+
+				contents, err := fs.ReadFileString("/etc/iscsi/iscsid.conf")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(contents).To(Equal("node.startup = automatic\n" +
+					"node.session.auth.authmethod = CHAP\n" +
+					"node.session.auth.username = fake-username\n" +
+					"node.session.auth.password = fake-password\n"))
+				Expect(fs.GetFileTestStat("/etc/iscsi/iscsid.conf").FileMode).To(Equal(os.FileMode(0600)))
+
+				for _, cmd := range runner.RunCommands {
+					Expect(cmd).ToNot(ContainElement("fake-password"))
+				}
+			})
+
+			It("does not rewrite iscsid.conf when CHAP credentials are already configured", func() {
+				diskSettings.ISCSISettings.Username = "fake-username"
+				diskSettings.ISCSISettings.Password = "fake-password"
+
+				fs.WriteFileString("/etc/iscsi/iscsid.conf", "node.session.auth.authmethod = CHAP\nnode.session.auth.username = fake-username\nnode.session.auth.password = fake-password\n")
+
+				_, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fs.RenameOldPaths).To(BeEmpty())
+			})
+
+			It("succeeds if session to the target already exists", func() {
+				runner.AddCmdResult(
+					"iscsiadm -m node -T iqn.2007-05.com.example:fake-target -p 10.0.0.1:3260 --login",
+					fakesys.FakeCmdResult{ExitStatus: 15, Error: errors.New("session exists")},
+				)
+
+				realPath, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/sdc"))
+			})
+		})
+
+		It("returns error if logging into the target fails", func() {
+			runner.AddCmdResult(
+				"iscsiadm -m node -T iqn.2007-05.com.example:fake-target -p 10.0.0.1:3260 --login",
+				fakesys.FakeCmdResult{ExitStatus: 24, Error: errors.New("fake-login-err")},
+			)
+
+			_, _, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-login-err"))
+		})
+
+		It("times out if device does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("uses fallback resolver for disks without iSCSI settings", func() {
+			fallbackResolver.RealDevicePath = "/dev/xvdb"
+
+			realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/xvdb"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/xvdb"))
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"strings"
 	"time"
 
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
@@ -11,14 +12,16 @@ import (
 
 type mappedDevicePathResolver struct {
 	diskWaitTimeout time.Duration
+	eventMonitor    boshudev.EventMonitor
 	fs              boshsys.FileSystem
 }
 
 func NewMappedDevicePathResolver(
 	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
 	fs boshsys.FileSystem,
 ) DevicePathResolver {
-	return mappedDevicePathResolver{fs: fs, diskWaitTimeout: diskWaitTimeout}
+	return mappedDevicePathResolver{fs: fs, diskWaitTimeout: diskWaitTimeout, eventMonitor: eventMonitor}
 }
 
 func (dpr mappedDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
@@ -36,7 +39,7 @@ func (dpr mappedDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.
 			return "", true, bosherr.Errorf("Timed out getting real device path for %s", devicePath)
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		dpr.eventMonitor.WaitForEvent(time.Until(stopAfter))
 
 		realPath, found = dpr.findPossibleDevice(devicePath)
 	}
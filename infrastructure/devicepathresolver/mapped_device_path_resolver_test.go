@@ -7,6 +7,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
@@ -16,12 +17,14 @@ var _ = Describe("mappedDevicePathResolver", func() {
 	var (
 		fs           boshsys.FileSystem
 		diskSettings boshsettings.DiskSettings
+		eventMonitor *fakeudev.FakeEventMonitor
 		resolver     DevicePathResolver
 	)
 
 	BeforeEach(func() {
 		fs = fakesys.NewFakeFileSystem()
-		resolver = NewMappedDevicePathResolver(time.Second, fs)
+		eventMonitor = fakeudev.NewFakeEventMonitor()
+		resolver = NewMappedDevicePathResolver(time.Second, eventMonitor, fs)
 		diskSettings = boshsettings.DiskSettings{
 			Path: "/dev/sda",
 		}
@@ -36,6 +39,24 @@ var _ = Describe("mappedDevicePathResolver", func() {
 		})
 	})
 
+	Context("when device shows up after waiting for device event", func() {
+		BeforeEach(func() {
+			eventMonitor.WaitForEventStub = func(maxWait time.Duration) {
+				Expect(maxWait).To(BeNumerically(">", 0))
+				Expect(maxWait).To(BeNumerically("<=", time.Second))
+				fs.WriteFile("/dev/sda", []byte{})
+			}
+		})
+
+		It("returns the device without further waiting", func() {
+			realPath, timedOut, err := resolver.GetRealDevicePath(diskSettings)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sda"))
+			Expect(eventMonitor.WaitForEventCallCount).To(Equal(1))
+		})
+	})
+
 	Context("when a matching /dev/xvdX device is found", func() {
 		BeforeEach(func() {
 			fs.WriteFile("/dev/xvda", []byte{})
@@ -81,7 +102,7 @@ var _ = Describe("mappedDevicePathResolver", func() {
 	Context("when no matching device is found the first time", func() {
 		Context("when the timeout has not expired", func() {
 			BeforeEach(func() {
-				time.AfterFunc(time.Second, func() {
+				time.AfterFunc(500*time.Millisecond, func() {
 					fs.WriteFile("/dev/xvda", []byte{})
 				})
 			})
@@ -0,0 +1,118 @@
+package devicepathresolver
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type multipathDevicePathResolver struct {
+	diskWaitTimeout time.Duration
+	eventMonitor    boshudev.EventMonitor
+	delegate        DevicePathResolver
+	runner          boshsys.CmdRunner
+	fs              boshsys.FileSystem
+	logger          boshlog.Logger
+	logTag          string
+}
+
+// NewMultipathDevicePathResolver returns resolver that resolves disks reachable
+// over several paths (e.g. fibre channel or iSCSI SAN volumes) to their dm-multipath
+// map when multipathd is active, so that the disk survives failure of a single path;
+// other disks are resolved by the delegate resolver
+func NewMultipathDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	delegate DevicePathResolver,
+	runner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return multipathDevicePathResolver{
+		diskWaitTimeout: diskWaitTimeout,
+		eventMonitor:    eventMonitor,
+		delegate:        delegate,
+		runner:          runner,
+		fs:              fs,
+		logger:          logger,
+		logTag:          "multipathDevicePathResolver",
+	}
+}
+
+func (r multipathDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	realPath, timedOut, err := r.delegate.GetRealDevicePath(diskSettings)
+	if err != nil || strings.HasPrefix(realPath, "/dev/mapper/") {
+		return realPath, timedOut, err
+	}
+
+	if !r.multipathdActive() {
+		return realPath, false, nil
+	}
+
+	// multipath -c exits with 0 only for devices that are paths of a multipath map
+	_, _, _, err = r.runner.RunCommand("multipath", "-c", realPath)
+	if err != nil {
+		return realPath, false, nil
+	}
+
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		mapPath, found := r.findMultipathMap(realPath)
+		if found {
+			r.logger.Debug(r.logTag, "Resolved multipath disk %s to %s", realPath, mapPath)
+			return mapPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting multipath device for '%s'", realPath)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
+
+func (r multipathDevicePathResolver) multipathdActive() bool {
+	if !r.runner.CommandExists("multipathd") {
+		return false
+	}
+
+	_, _, _, err := r.runner.RunCommand("multipathd", "show", "daemon")
+	return err == nil
+}
+
+// findMultipathMap returns device mapper path of the multipath map holding the device
+func (r multipathDevicePathResolver) findMultipathMap(devicePath string) (string, bool) {
+	holders, err := r.fs.Glob(path.Join("/sys/block", path.Base(devicePath), "holders", "*"))
+	if err != nil {
+		return "", false
+	}
+
+	for _, holder := range holders {
+		dmDir := path.Join("/sys/block", path.Base(holder), "dm")
+
+		uuid, err := r.fs.ReadFileString(path.Join(dmDir, "uuid"))
+		if err != nil || !strings.HasPrefix(uuid, "mpath-") {
+			continue
+		}
+
+		name, err := r.fs.ReadFileString(path.Join(dmDir, "name"))
+		if err != nil {
+			continue
+		}
+
+		return path.Join("/dev/mapper", strings.TrimSpace(name)), true
+	}
+
+	return "", false
+}
+
+func (r multipathDevicePathResolver) Delegate() DevicePathResolver {
+	return r.delegate
+}
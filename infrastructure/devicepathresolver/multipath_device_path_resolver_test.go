@@ -0,0 +1,122 @@
+package devicepathresolver_test
+
+import (
+	"errors"
+	"time"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("multipathDevicePathResolver", func() {
+	var (
+		fs           *fakesys.FakeFileSystem
+		runner       *fakesys.FakeCmdRunner
+		delegate     *fakedpresolv.FakeDevicePathResolver
+		diskSettings boshsettings.DiskSettings
+		pathResolver DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		runner.CommandExistsValue = true
+		delegate = fakedpresolv.NewFakeDevicePathResolver()
+		delegate.RealDevicePath = "/dev/sdc"
+		diskSettings = boshsettings.DiskSettings{ID: "fake-disk-id", Path: "/dev/sdc"}
+		pathResolver = NewMultipathDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), delegate, runner, fs, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("GetRealDevicePath", func() {
+		Context("when device is a path of a multipath map", func() {
+			BeforeEach(func() {
+				fs.SetGlob("/sys/block/sdc/holders/*", []string{"/sys/block/sdc/holders/dm-2"})
+				fs.WriteFileString("/sys/block/dm-2/dm/uuid", "mpath-3600a098038303053453f463045727a50\n")
+				fs.WriteFileString("/sys/block/dm-2/dm/name", "mpatha\n")
+			})
+
+			It("returns device mapper path of the multipath map", func() {
+				realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(timedOut).To(BeFalse())
+				Expect(realPath).To(Equal("/dev/mapper/mpatha"))
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"multipathd", "show", "daemon"},
+					{"multipath", "-c", "/dev/sdc"},
+				}))
+			})
+
+			It("ignores holders that are not multipath maps", func() {
+				fs.SetGlob("/sys/block/sdc/holders/*", []string{"/sys/block/sdc/holders/dm-1", "/sys/block/sdc/holders/dm-2"})
+				fs.WriteFileString("/sys/block/dm-1/dm/uuid", "CRYPT-LUKS1-fake\n")
+				fs.WriteFileString("/sys/block/dm-1/dm/name", "bosh_crypt_disk1\n")
+
+				realPath, _, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/mapper/mpatha"))
+			})
+		})
+
+		It("times out when multipath map does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out getting multipath device for '/dev/sdc'"))
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("returns device when it is not a path of a multipath map", func() {
+			runner.AddCmdResult("multipath -c /dev/sdc", fakesys.FakeCmdResult{Error: errors.New("not a multipath device"), ExitStatus: 1})
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/sdc"))
+		})
+
+		It("returns device when multipathd is not running", func() {
+			runner.AddCmdResult("multipathd show daemon", fakesys.FakeCmdResult{Error: errors.New("not running"), ExitStatus: 1})
+
+			realPath, _, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/sdc"))
+			Expect(runner.RunCommands).To(Equal([][]string{{"multipathd", "show", "daemon"}}))
+		})
+
+		It("returns device when multipathd is not installed", func() {
+			runner.CommandExistsValue = false
+
+			realPath, _, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/sdc"))
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns device mapper paths as is", func() {
+			delegate.RealDevicePath = "/dev/mapper/fake-device"
+
+			realPath, _, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/mapper/fake-device"))
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns error when delegate fails", func() {
+			delegate.GetRealDevicePathErr = errors.New("fake-delegate-err")
+			delegate.GetRealDevicePathTimedOut = true
+
+			_, timedOut, err := pathResolver.GetRealDevicePath(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-delegate-err"))
+			Expect(timedOut).To(BeTrue())
+		})
+	})
+})
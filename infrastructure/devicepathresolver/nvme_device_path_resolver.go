@@ -0,0 +1,105 @@
+package devicepathresolver
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const ebsVolumeIDPrefix = "vol-"
+
+type nvmeDevicePathResolver struct {
+	diskWaitTimeout  time.Duration
+	eventMonitor     boshudev.EventMonitor
+	fs               boshsys.FileSystem
+	fallbackResolver DevicePathResolver
+	logger           boshlog.Logger
+	logTag           string
+}
+
+// NewNVMeDevicePathResolver returns resolver that finds EBS volumes attached
+// as NVMe devices (e.g. on AWS Nitro instances), where device names requested
+// by the CPI are not honoured. EBS reports volume id without the dash as serial
+// number in the NVMe identify controller data (e.g. vol0123456789abcdef0).
+// Disks without EBS volume id (e.g. instance storage) are resolved with the fallback resolver
+func NewNVMeDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	fs boshsys.FileSystem,
+	fallbackResolver DevicePathResolver,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return nvmeDevicePathResolver{
+		diskWaitTimeout:  diskWaitTimeout,
+		eventMonitor:     eventMonitor,
+		fs:               fs,
+		fallbackResolver: fallbackResolver,
+		logger:           logger,
+		logTag:           "nvmeDevicePathResolver",
+	}
+}
+
+func (r nvmeDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	volumeID := r.ebsVolumeID(diskSettings)
+	if volumeID == "" {
+		return r.fallbackResolver.GetRealDevicePath(diskSettings)
+	}
+
+	serial := strings.Replace(volumeID, "-", "", 1)
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		realPath, found, err := r.findDeviceWithSerial(serial)
+		if err != nil {
+			return "", false, bosherr.WrapErrorf(err, "Finding NVMe device of volume '%s'", volumeID)
+		}
+
+		if found {
+			r.logger.Debug(r.logTag, "Resolved volume %s to %s", volumeID, realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting NVMe device for volume '%s'", volumeID)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
+
+// ebsVolumeID returns volume id from disk settings; AWS CPI uses volume id as disk cid
+func (r nvmeDevicePathResolver) ebsVolumeID(diskSettings boshsettings.DiskSettings) string {
+	for _, id := range []string{diskSettings.VolumeID, diskSettings.ID} {
+		if strings.HasPrefix(id, ebsVolumeIDPrefix) {
+			return id
+		}
+	}
+	return ""
+}
+
+func (r nvmeDevicePathResolver) findDeviceWithSerial(serial string) (string, bool, error) {
+	serialPaths, err := r.fs.Glob("/sys/block/nvme*n1/device/serial")
+	if err != nil {
+		return "", false, bosherr.WrapError(err, "Globbing NVMe devices")
+	}
+
+	for _, serialPath := range serialPaths {
+		deviceSerial, err := r.fs.ReadFileString(serialPath)
+		if err != nil {
+			continue
+		}
+
+		if strings.TrimSpace(deviceSerial) == serial {
+			// /sys/block/nvme1n1/device/serial -> /dev/nvme1n1
+			return path.Join("/dev", path.Base(path.Dir(path.Dir(serialPath)))), true, nil
+		}
+	}
+
+	return "", false, nil
+}
@@ -0,0 +1,87 @@
+package devicepathresolver_test
+
+import (
+	"errors"
+	"time"
+
+	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("nvmeDevicePathResolver", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		fallbackResolver *fakedpresolv.FakeDevicePathResolver
+		pathResolver     DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		fallbackResolver = fakedpresolv.NewFakeDevicePathResolver()
+		pathResolver = NewNVMeDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), fs, fallbackResolver, boshlog.NewLogger(boshlog.LevelNone))
+
+		fs.SetGlob("/sys/block/nvme*n1/device/serial", []string{
+			"/sys/block/nvme0n1/device/serial",
+			"/sys/block/nvme1n1/device/serial",
+			"/sys/block/nvme2n1/device/serial",
+		})
+		fs.WriteFileString("/sys/block/nvme0n1/device/serial", "vol0aaaaaaaaaaaaaaaa\n")
+		fs.WriteFileString("/sys/block/nvme1n1/device/serial", "AWS1234567890ABCDEF \n")
+		fs.WriteFileString("/sys/block/nvme2n1/device/serial", "vol0123456789abcdef0\n")
+	})
+
+	Describe("GetRealDevicePath", func() {
+		It("returns NVMe device with serial number of the volume id", func() {
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{
+				ID:       "vol-0123456789abcdef0",
+				VolumeID: "vol-0123456789abcdef0",
+				Path:     "/dev/sdf",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/nvme2n1"))
+		})
+
+		It("uses disk id when volume id is not set", func() {
+			realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{
+				ID:   "vol-0123456789abcdef0",
+				Path: "/dev/sdf",
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/nvme2n1"))
+		})
+
+		It("times out when device with serial number of the volume id does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{VolumeID: "vol-0fffffffffffffff0"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out getting NVMe device for volume 'vol-0fffffffffffffff0'"))
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("returns error when globbing devices fails", func() {
+			fs.GlobErr = errors.New("fake-glob-err")
+
+			_, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{VolumeID: "vol-0123456789abcdef0"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-glob-err"))
+		})
+
+		It("resolves disks without EBS volume id with fallback resolver", func() {
+			fallbackResolver.RealDevicePath = "/dev/xvdb"
+
+			realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdb"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(timedOut).To(BeFalse())
+			Expect(realPath).To(Equal("/dev/xvdb"))
+			Expect(fallbackResolver.GetRealDevicePathDiskSettings).To(Equal(boshsettings.DiskSettings{Path: "/dev/sdb"}))
+		})
+	})
+})
@@ -4,17 +4,29 @@ import (
 	"strings"
 	"time"
 
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+const (
+	// scsiRescanInterval is how often SCSI hosts are rescanned while waiting for disk
+	scsiRescanInterval = 10 * time.Second
+
+	// scsiFinalRescanWait is how long disk is waited for after the last rescan
+	// that is performed once disk wait timeout expires
+	scsiFinalRescanWait = 5 * time.Second
+)
+
 // SCSIIDDevicePathResolver resolves device path by performing a
 // SCSI rescan then looking under "/dev/disk/by-id/*uuid"
-// where "uuid" is the cloud ID of the disk
+// where "uuid" is the cloud ID of the disk. SCSI hosts are rescanned
+// periodically and once more before giving up on the disk.
 type SCSIIDDevicePathResolver struct {
 	diskWaitTimeout time.Duration
+	eventMonitor    boshudev.EventMonitor
 	fs              boshsys.FileSystem
 
 	logTag string
@@ -23,11 +35,13 @@ type SCSIIDDevicePathResolver struct {
 
 func NewSCSIIDDevicePathResolver(
 	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
 ) SCSIIDDevicePathResolver {
 	return SCSIIDDevicePathResolver{
 		diskWaitTimeout: diskWaitTimeout,
+		eventMonitor:    eventMonitor,
 		fs:              fs,
 
 		logTag: "scsiIDresolver",
@@ -40,20 +54,14 @@ func (idpr SCSIIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings
 		return "", false, bosherr.Errorf("Disk device ID is not set")
 	}
 
-	hostPaths, err := idpr.fs.Glob("/sys/class/scsi_host/host*/scan")
+	err := idpr.rescanSCSIHosts()
 	if err != nil {
-		return "", false, bosherr.WrapError(err, "Could not list SCSI hosts")
-	}
-
-	for _, hostPath := range hostPaths {
-		idpr.logger.Info(idpr.logTag, "Performing SCSI rescan of "+hostPath)
-		err = idpr.fs.WriteFileString(hostPath, "- - -")
-		if err != nil {
-			return "", false, bosherr.WrapError(err, "Starting SCSI rescan")
-		}
+		return "", false, err
 	}
 
 	stopAfter := time.Now().Add(idpr.diskWaitTimeout)
+	lastRescanAt := time.Now()
+	finalRescanDone := false
 	found := false
 
 	var realPath string
@@ -62,10 +70,20 @@ func (idpr SCSIIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings
 		idpr.logger.Debug(idpr.logTag, "Waiting for device to appear")
 
 		if time.Now().After(stopAfter) {
-			return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", diskSettings.DeviceID)
+			if finalRescanDone {
+				return "", true, bosherr.Errorf("Timed out getting real device path for '%s'", diskSettings.DeviceID)
+			}
+
+			// Hosts do not always notice hot-plugged disks (e.g. on vSphere)
+			idpr.rescanSCSIHostsIgnoringErrors()
+			finalRescanDone = true
+			stopAfter = time.Now().Add(idpr.finalRescanWait())
+		} else if time.Since(lastRescanAt) >= scsiRescanInterval {
+			idpr.rescanSCSIHostsIgnoringErrors()
+			lastRescanAt = time.Now()
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		idpr.eventMonitor.WaitForEvent(time.Until(stopAfter))
 
 		uuid := strings.Replace(diskSettings.DeviceID, "-", "", -1)
 		disks, err := idpr.fs.Glob("/dev/disk/by-id/*" + uuid)
@@ -89,3 +107,34 @@ func (idpr SCSIIDDevicePathResolver) GetRealDevicePath(diskSettings boshsettings
 
 	return realPath, false, nil
 }
+
+func (idpr SCSIIDDevicePathResolver) rescanSCSIHosts() error {
+	hostPaths, err := idpr.fs.Glob("/sys/class/scsi_host/host*/scan")
+	if err != nil {
+		return bosherr.WrapError(err, "Could not list SCSI hosts")
+	}
+
+	for _, hostPath := range hostPaths {
+		idpr.logger.Info(idpr.logTag, "Performing SCSI rescan of "+hostPath)
+		err = idpr.fs.WriteFileString(hostPath, "- - -")
+		if err != nil {
+			return bosherr.WrapError(err, "Starting SCSI rescan")
+		}
+	}
+
+	return nil
+}
+
+func (idpr SCSIIDDevicePathResolver) rescanSCSIHostsIgnoringErrors() {
+	err := idpr.rescanSCSIHosts()
+	if err != nil {
+		idpr.logger.Warn(idpr.logTag, "Failed to rescan SCSI hosts: %s", err.Error())
+	}
+}
+
+func (idpr SCSIIDDevicePathResolver) finalRescanWait() time.Duration {
+	if idpr.diskWaitTimeout < scsiFinalRescanWait {
+		return idpr.diskWaitTimeout
+	}
+	return scsiFinalRescanWait
+}
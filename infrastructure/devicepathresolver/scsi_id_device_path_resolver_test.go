@@ -5,6 +5,7 @@ import (
 	"strings"
 	"time"
 
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
@@ -19,6 +20,7 @@ var _ = Describe("ScsiIDDevicePathResolver", func() {
 	var (
 		fs           *fakesys.FakeFileSystem
 		diskSettings boshsettings.DiskSettings
+		eventMonitor *fakeudev.FakeEventMonitor
 		pathResolver DevicePathResolver
 		id           string
 		hosts        []string
@@ -28,7 +30,8 @@ var _ = Describe("ScsiIDDevicePathResolver", func() {
 		deviceID := "ab1b46b5-bf22-4332-bddd-12a05ea1a5fc"
 		id = strings.Replace(deviceID, "-", "", -1)
 		fs = fakesys.NewFakeFileSystem()
-		pathResolver = NewSCSIIDDevicePathResolver(500*time.Millisecond, fs, boshlog.NewLogger(boshlog.LevelNone))
+		eventMonitor = fakeudev.NewFakeEventMonitor()
+		pathResolver = NewSCSIIDDevicePathResolver(500*time.Millisecond, eventMonitor, fs, boshlog.NewLogger(boshlog.LevelNone))
 		diskSettings = boshsettings.DiskSettings{
 			DeviceID: deviceID,
 		}
@@ -85,6 +88,49 @@ var _ = Describe("ScsiIDDevicePathResolver", func() {
 				_, _, err := pathResolver.GetRealDevicePath(diskSettings)
 				Expect(err).To(HaveOccurred())
 			})
+
+			It("rescans SCSI hosts again before giving up", func() {
+				eventMonitor.WaitForEventStub = func(time.Duration) {
+					if eventMonitor.WaitForEventCallCount == 1 {
+						for _, host := range hosts {
+							fs.WriteFileString(host, "")
+						}
+					}
+				}
+
+				_, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(timeout).To(BeTrue())
+
+				for _, host := range hosts {
+					str, _ := fs.ReadFileString(host)
+					Expect(str).To(Equal("- - -"))
+				}
+			})
+		})
+
+		Context("when device shows up only after rescanning SCSI hosts again", func() {
+			BeforeEach(func() {
+				eventMonitor.WaitForEventStub = func(time.Duration) {
+					if eventMonitor.WaitForEventCallCount == 1 {
+						fs.WriteFileString(hosts[0], "")
+						return
+					}
+
+					str, _ := fs.ReadFileString(hosts[0])
+					if str == "- - -" {
+						fs.MkdirAll("fake-device-path", os.FileMode(0750))
+						fs.Symlink("fake-device-path", "/dev/disk/by-id/scsi-3"+id)
+					}
+				}
+			})
+
+			It("returns the path", func() {
+				path, timeout, err := pathResolver.GetRealDevicePath(diskSettings)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(path).To(Equal("fake-device-path"))
+				Expect(timeout).To(BeFalse())
+			})
 		})
 
 		Context("when no matching device is found the first time", func() {
@@ -0,0 +1,104 @@
+package devicepathresolver
+
+import (
+	"regexp"
+	"time"
+
+	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// xenLetterOffset is the number of letters by which some Xen kernels
+// (e.g. RHEL/CentOS 6) shift device names: /dev/sda becomes /dev/xvde
+const xenLetterOffset = 4
+
+// xenDeviceHintRegexp matches device name hints, e.g. /dev/sdf, /dev/hdb1 or /dev/xvdc
+var xenDeviceHintRegexp = regexp.MustCompile(`^/dev/(?:sd|hd|xvd)([a-z])(\d*)$`)
+
+type xenDevicePathResolver struct {
+	diskWaitTimeout time.Duration
+	eventMonitor    boshudev.EventMonitor
+	fs              boshsys.FileSystem
+	logger          boshlog.Logger
+	logTag          string
+}
+
+// NewXenDevicePathResolver returns resolver that maps /dev/sdX and /dev/hdX device
+// names requested by the CPI to /dev/xvdX names assigned by Xen blkfront driver.
+// When the root disk shows up as /dev/xvde instead of /dev/xvda the kernel
+// shifts all names by 4 letters and the hint is shifted the same way.
+func NewXenDevicePathResolver(
+	diskWaitTimeout time.Duration,
+	eventMonitor boshudev.EventMonitor,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) DevicePathResolver {
+	return xenDevicePathResolver{
+		diskWaitTimeout: diskWaitTimeout,
+		eventMonitor:    eventMonitor,
+		fs:              fs,
+		logger:          logger,
+		logTag:          "xenDevicePathResolver",
+	}
+}
+
+func (r xenDevicePathResolver) GetRealDevicePath(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+	devicePath := diskSettings.Path
+	if len(devicePath) == 0 {
+		return "", false, bosherr.Error("Getting real device path: path is missing")
+	}
+
+	stopAfter := time.Now().Add(r.diskWaitTimeout)
+
+	for {
+		realPath, found := r.findDevice(devicePath)
+		if found {
+			r.logger.Debug(r.logTag, "Resolved %s to %s", devicePath, realPath)
+			return realPath, false, nil
+		}
+
+		if time.Now().After(stopAfter) {
+			return "", true, bosherr.Errorf("Timed out getting real device path for %s", devicePath)
+		}
+
+		r.eventMonitor.WaitForEvent(time.Until(stopAfter))
+	}
+}
+
+func (r xenDevicePathResolver) findDevice(devicePath string) (string, bool) {
+	matches := xenDeviceHintRegexp.FindStringSubmatch(devicePath)
+	if matches == nil {
+		return devicePath, r.fs.FileExists(devicePath)
+	}
+
+	letter, partition := matches[1][0], matches[2]
+
+	if r.lettersShifted() {
+		if letter+xenLetterOffset > 'z' {
+			return "", false
+		}
+		letter += xenLetterOffset
+	}
+
+	for _, prefix := range []string{"/dev/xvd", "/dev/sd"} {
+		path := prefix + string(letter) + partition
+		if r.fs.FileExists(path) {
+			return path, true
+		}
+	}
+
+	return "", false
+}
+
+// lettersShifted reports whether root disk was named /dev/xvde instead of /dev/xvda;
+// the disk may only show up as its partition when Xen exposes partitions as disks
+func (r xenDevicePathResolver) lettersShifted() bool {
+	if r.fs.FileExists("/dev/xvda") || r.fs.FileExists("/dev/xvda1") {
+		return false
+	}
+
+	return r.fs.FileExists("/dev/xvde") || r.fs.FileExists("/dev/xvde1")
+}
@@ -0,0 +1,111 @@
+package devicepathresolver_test
+
+import (
+	"time"
+
+	fakeudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+)
+
+var _ = Describe("xenDevicePathResolver", func() {
+	var (
+		fs           *fakesys.FakeFileSystem
+		pathResolver DevicePathResolver
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		pathResolver = NewXenDevicePathResolver(500*time.Millisecond, fakeudev.NewFakeEventMonitor(), fs, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("GetRealDevicePath", func() {
+		Context("when root disk is /dev/xvda", func() {
+			BeforeEach(func() {
+				fs.WriteFileString("/dev/xvda", "")
+				fs.WriteFileString("/dev/xvdb", "")
+				fs.WriteFileString("/dev/xvdf", "")
+			})
+
+			It("maps /dev/sdX to /dev/xvdX", func() {
+				realPath, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdf"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(timedOut).To(BeFalse())
+				Expect(realPath).To(Equal("/dev/xvdf"))
+			})
+
+			It("maps /dev/hdX to /dev/xvdX", func() {
+				realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/hdb"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/xvdb"))
+			})
+
+			It("keeps /dev/xvdX", func() {
+				realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/xvdf"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/xvdf"))
+			})
+
+			It("uses /dev/sdX when device was not renamed", func() {
+				fs.WriteFileString("/dev/sdg", "")
+
+				realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdg"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/sdg"))
+			})
+		})
+
+		Context("when root disk is /dev/xvde", func() {
+			BeforeEach(func() {
+				fs.WriteFileString("/dev/xvde1", "")
+				fs.WriteFileString("/dev/xvdf", "")
+				fs.WriteFileString("/dev/xvdj", "")
+			})
+
+			It("shifts device letter by 4", func() {
+				realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdf"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/xvdj"))
+			})
+
+			It("shifts device letter of partitions", func() {
+				realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sda1"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(realPath).To(Equal("/dev/xvde1"))
+			})
+
+			It("times out when shifted device letter is past z", func() {
+				_, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdy"})
+				Expect(err).To(HaveOccurred())
+				Expect(timedOut).To(BeTrue())
+			})
+		})
+
+		It("times out when device does not show up", func() {
+			_, timedOut, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/sdf"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Timed out getting real device path for /dev/sdf"))
+			Expect(timedOut).To(BeTrue())
+		})
+
+		It("resolves paths that are not device name hints as is", func() {
+			fs.WriteFileString("/dev/mapper/fake-device", "")
+
+			realPath, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{Path: "/dev/mapper/fake-device"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(realPath).To(Equal("/dev/mapper/fake-device"))
+		})
+
+		It("returns error when path is missing", func() {
+			_, _, err := pathResolver.GetRealDevicePath(boshsettings.DiskSettings{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("path is missing"))
+		})
+	})
+})
@@ -15,17 +15,20 @@ type httpRegistry struct {
 	metadataService   MetadataService
 	platform          boshplat.Platform
 	useServerNameAsID bool
+	httpClient        *http.Client
 }
 
 func NewHTTPRegistry(
 	metadataService MetadataService,
 	platform boshplat.Platform,
 	useServerNameAsID bool,
+	httpClient *http.Client,
 ) Registry {
 	return httpRegistry{
 		metadataService:   metadataService,
 		platform:          platform,
 		useServerNameAsID: useServerNameAsID,
+		httpClient:        httpClient,
 	}
 }
 
@@ -69,7 +72,7 @@ func (r httpRegistry) GetSettings() (boshsettings.Settings, error) {
 	}
 
 	settingsURL := fmt.Sprintf("%s/instances/%s/settings", registryEndpoint, identifier)
-	wrapperResponse, err := http.Get(settingsURL)
+	wrapperResponse, err := r.httpClient.Get(settingsURL)
 	if err != nil {
 		return settings, bosherr.WrapError(err, "Getting settings from url")
 	}
@@ -28,7 +28,7 @@ func describeHTTPRegistry() {
 	BeforeEach(func() {
 		metadataService = &fakeinf.FakeMetadataService{}
 		platform = &fakeplat.FakePlatform{}
-		registry = NewHTTPRegistry(metadataService, platform, false)
+		registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient)
 	})
 
 	Describe("GetSettings", func() {
@@ -59,7 +59,7 @@ func describeHTTPRegistry() {
 				settingsJSON = `{"settings": "{\"agent_id\":\"my-agent-id\"}"}`
 				metadataService.InstanceID = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
-				registry = NewHTTPRegistry(metadataService, platform, false)
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient)
 			})
 
 			Context("when the metadata has Networks information", func() {
@@ -120,7 +120,7 @@ func describeHTTPRegistry() {
 
 		Context("when registry is configured to not use server name as id", func() {
 			BeforeEach(func() {
-				registry = NewHTTPRegistry(metadataService, platform, false)
+				registry = NewHTTPRegistry(metadataService, platform, false, http.DefaultClient)
 				metadataService.InstanceID = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
 			})
@@ -284,7 +284,7 @@ func describeHTTPRegistry() {
 
 		Context("when registry is configured to use server name as id", func() {
 			BeforeEach(func() {
-				registry = NewHTTPRegistry(metadataService, platform, true)
+				registry = NewHTTPRegistry(metadataService, platform, true, http.DefaultClient)
 				metadataService.ServerName = "fake-identifier"
 				metadataService.RegistryEndpoint = ts.URL
 			})
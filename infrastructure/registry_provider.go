@@ -1,6 +1,7 @@
 package infrastructure
 
 import (
+	"net/http"
 	"strings"
 
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
@@ -16,6 +17,7 @@ type RegistryProvider interface {
 type registryProvider struct {
 	metadataService MetadataService
 	useServerName   bool
+	httpClient      *http.Client
 	platform        boshplat.Platform
 	fs              boshsys.FileSystem
 	logTag          string
@@ -26,6 +28,7 @@ func NewRegistryProvider(
 	metadataService MetadataService,
 	platform boshplat.Platform,
 	useServerName bool,
+	httpClient *http.Client,
 	fs boshsys.FileSystem,
 	logger boshlog.Logger,
 ) RegistryProvider {
@@ -33,6 +36,7 @@ func NewRegistryProvider(
 		metadataService: metadataService,
 		platform:        platform,
 		useServerName:   useServerName,
+		httpClient:      httpClient,
 		fs:              fs,
 		logTag:          "registryProvider",
 		logger:          logger,
@@ -47,7 +51,7 @@ func (p *registryProvider) GetRegistry() (Registry, error) {
 
 	if strings.HasPrefix(registryEndpoint, "http") {
 		p.logger.Debug(p.logTag, "Using http registry at %s", registryEndpoint)
-		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName), nil
+		return NewHTTPRegistry(p.metadataService, p.platform, p.useServerName, p.httpClient), nil
 	}
 
 	p.logger.Debug(p.logTag, "Using file registry at %s", registryEndpoint)
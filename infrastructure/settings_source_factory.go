@@ -1,11 +1,15 @@
 package infrastructure
 
 import (
+	"crypto/tls"
 	"encoding/json"
 
 	mapstruc "github.com/mitchellh/mapstructure"
+	"github.com/pivotal-golang/clock"
 
+	boshfips "github.com/cloudfoundry/bosh-agent/fips"
 	boshplat "github.com/cloudfoundry/bosh-agent/platform"
+	boshrevocation "github.com/cloudfoundry/bosh-agent/revocation"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -19,6 +23,9 @@ type SettingsOptions struct {
 	Sources       SourceOptionsSlice
 	UseServerName bool
 	UseRegistry   bool
+
+	// CRL checking of registry server certificates; OCSP is not supported
+	RegistryRevocationCheck boshrevocation.Options
 }
 
 // SourceOptionsSlice is used for unmarshalling different source types
@@ -139,7 +146,14 @@ func (f SettingsSourceFactory) buildWithRegistry() (boshsettings.Source, error)
 	}
 
 	metadataService := NewMultiSourceMetadataService(metadataServices...)
-	registryProvider := NewRegistryProvider(metadataService, f.platform, f.options.UseServerName, f.platform.GetFs(), f.logger)
+	// FIPS mode of settings cannot apply before settings are fetched from registry
+	var registryTLSConfig *tls.Config
+	if boshfips.Enabled(false) {
+		registryTLSConfig = boshfips.TLSConfig()
+	}
+
+	httpClient := boshrevocation.NewHTTPClient(f.options.RegistryRevocationCheck, registryTLSConfig, clock.NewClock(), f.logger)
+	registryProvider := NewRegistryProvider(metadataService, f.platform, f.options.UseServerName, httpClient, f.platform.GetFs(), f.logger)
 	settingsSource := NewComplexSettingsSource(metadataService, registryProvider, f.logger)
 
 	return settingsSource, nil
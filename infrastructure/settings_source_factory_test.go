@@ -1,6 +1,8 @@
 package infrastructure_test
 
 import (
+	"net/http"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -45,7 +47,7 @@ var _ = Describe("SettingsSourceFactory", func() {
 						resolver := NewRegistryEndpointResolver(NewDigDNSResolver(platform.GetRunner(), logger))
 						httpMetadataService := NewHTTPMetadataService("http://fake-url", nil, "", "", "", resolver, platform, logger)
 						multiSourceMetadataService := NewMultiSourceMetadataService(httpMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, platform.GetFs(), logger)
 						httpSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
@@ -79,7 +81,7 @@ var _ = Describe("SettingsSourceFactory", func() {
 							logger,
 						)
 						multiSourceMetadataService := NewMultiSourceMetadataService(configDriveMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, platform.GetFs(), logger)
 						configDriveSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
@@ -109,7 +111,7 @@ var _ = Describe("SettingsSourceFactory", func() {
 							logger,
 						)
 						multiSourceMetadataService := NewMultiSourceMetadataService(fileMetadataService)
-						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, platform.GetFs(), logger)
+						registryProvider := NewRegistryProvider(multiSourceMetadataService, platform, useServerName, http.DefaultClient, platform.GetFs(), logger)
 						fileSettingsSource := NewComplexSettingsSource(multiSourceMetadataService, registryProvider, logger)
 
 						settingsSource, err := factory.New()
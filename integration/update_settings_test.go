@@ -96,7 +96,7 @@ QTETMBEGA1U=
 
 			individualCerts, err := testEnvironment.RunCommand("ls /usr/local/share/ca-certificates/")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(individualCerts).To(Equal("bosh-trusted-cert-1.crt\nbosh-trusted-cert-2.crt\n"))
+			Expect(individualCerts).To(MatchRegexp(`\Abosh-trusted-cert-[0-9a-f]{64}\.crt\nbosh-trusted-cert-[0-9a-f]{64}\.crt\n\z`))
 
 			processedCerts, err := testEnvironment.RunCommand("grep MIIEJDCCAwygAwIBAgIJAO\\+CqgiJnCgpMA0GCSqGSIb3DQEBBQUAMGkxCzAJBgNV /etc/ssl/certs/ca-certificates.crt")
 			Expect(processedCerts).To(Equal("MIIEJDCCAwygAwIBAgIJAO+CqgiJnCgpMA0GCSqGSIb3DQEBBQUAMGkxCzAJBgNV\n"))
@@ -0,0 +1,58 @@
+package jobsupervisor
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+// alertDeduplicator drops alerts about the same event of the same service
+// handled within the window; monit failures may be both mailed by monit
+// and noticed while polling monit status
+type alertDeduplicator struct {
+	handler JobFailureHandler
+	clock   clock.Clock
+	window  time.Duration
+
+	lock     sync.Mutex
+	lastSent map[string]time.Time
+}
+
+func newAlertDeduplicator(handler JobFailureHandler, clock clock.Clock, window time.Duration) *alertDeduplicator {
+	return &alertDeduplicator{
+		handler: handler,
+		clock:   clock,
+		window:  window,
+
+		lastSent: map[string]time.Time{},
+	}
+}
+
+func (d *alertDeduplicator) Handle(alert boshalert.MonitAlert) error {
+	if d.isDuplicate(alert) {
+		return nil
+	}
+
+	return d.handler(alert)
+}
+
+func (d *alertDeduplicator) isDuplicate(alert boshalert.MonitAlert) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	// Monit capitalizes events in mails
+	key := alert.Service + "/" + strings.ToLower(alert.Event)
+	now := d.clock.Now()
+
+	if lastSent, found := d.lastSent[key]; found && now.Sub(lastSent) < d.window {
+		return true
+	}
+
+	d.lastSent[key] = now
+
+	return false
+}
@@ -44,6 +44,18 @@ func (s *dummyJobSupervisor) RemoveAllJobs() error {
 	return nil
 }
 
+func (s *dummyJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	return nil
+}
+
+func (s *dummyJobSupervisor) SetJobConcurrency(limit int) error {
+	return nil
+}
+
+func (s *dummyJobSupervisor) SetJobCgroups(jobNames []string) error {
+	return nil
+}
+
 func (s *dummyJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
 	return nil
 }
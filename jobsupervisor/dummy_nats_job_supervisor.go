@@ -99,6 +99,18 @@ func (d *dummyNatsJobSupervisor) RemoveAllJobs() error {
 	return nil
 }
 
+func (d *dummyNatsJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	return nil
+}
+
+func (d *dummyNatsJobSupervisor) SetJobConcurrency(limit int) error {
+	return nil
+}
+
+func (d *dummyNatsJobSupervisor) SetJobCgroups(jobNames []string) error {
+	return nil
+}
+
 func (d *dummyNatsJobSupervisor) Status() string {
 	return d.status
 }
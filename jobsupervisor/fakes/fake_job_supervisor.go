@@ -14,6 +14,15 @@ type FakeJobSupervisor struct {
 	RemovedAllJobs    bool
 	RemovedAllJobsErr error
 
+	SetJobDependenciesDependencies map[string][]string
+	SetJobDependenciesErr          error
+
+	SetJobConcurrencyLimit int
+	SetJobConcurrencyErr   error
+
+	SetJobCgroupsJobNames []string
+	SetJobCgroupsErr      error
+
 	Started  bool
 	StartErr error
 
@@ -60,6 +69,21 @@ func (m *FakeJobSupervisor) RemoveAllJobs() error {
 	return m.RemovedAllJobsErr
 }
 
+func (m *FakeJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	m.SetJobDependenciesDependencies = dependencies
+	return m.SetJobDependenciesErr
+}
+
+func (m *FakeJobSupervisor) SetJobConcurrency(limit int) error {
+	m.SetJobConcurrencyLimit = limit
+	return m.SetJobConcurrencyErr
+}
+
+func (m *FakeJobSupervisor) SetJobCgroups(jobNames []string) error {
+	m.SetJobCgroupsJobNames = jobNames
+	return m.SetJobCgroupsErr
+}
+
 func (m *FakeJobSupervisor) Start() error {
 	m.Started = true
 	return m.StartErr
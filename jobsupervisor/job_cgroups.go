@@ -0,0 +1,16 @@
+package jobsupervisor
+
+import (
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+)
+
+// jobCgroupProcsPaths returns cgroup.procs files processes of the job write their pids to
+// when started so that limits apply from the start; jobs without cgroups get none
+func jobCgroupProcsPaths(dependencies jobDependencyStore, cgroupManager boshcgroup.Manager, jobName string) ([]string, error) {
+	hasCgroup, err := dependencies.HasCgroup(jobName)
+	if err != nil || !hasCgroup {
+		return nil, err
+	}
+
+	return cgroupManager.ProcsPaths(jobName), nil
+}
@@ -0,0 +1,47 @@
+package jobsupervisor
+
+import (
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// inParallel runs action for each group with at most limit groups at once;
+// limit of 0 runs all groups at once. Errors of all failed groups are returned.
+func inParallel(groups [][]string, limit int, action func(group []string) error) error {
+	if limit <= 0 || limit > len(groups) {
+		limit = len(groups)
+	}
+
+	slots := make(chan struct{}, limit)
+	errs := make([]error, len(groups))
+
+	var wg sync.WaitGroup
+
+	for i, group := range groups {
+		slots <- struct{}{}
+		wg.Add(1)
+
+		go func(i int, group []string) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			errs[i] = action(group)
+		}(i, group)
+	}
+
+	wg.Wait()
+
+	var failed []error
+	for _, err := range errs {
+		if err != nil {
+			failed = append(failed, err)
+		}
+	}
+
+	if len(failed) > 0 {
+		return bosherr.NewMultiError(failed...)
+	}
+
+	return nil
+}
@@ -0,0 +1,370 @@
+package jobsupervisor
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	jobDependenciesLogTag = "jobDependencies"
+
+	// Dependencies are kept under bosh dir since jobs are started again after agent restarts
+	jobDependenciesFileName = "job_dependencies.json"
+
+	// Processes of each start stage have this long to become healthy before dependents are started
+	jobDependenciesWaitTimeout   = 10 * time.Minute
+	jobDependenciesCheckInterval = 2 * time.Second
+)
+
+type jobDependencies struct {
+	// Jobs that have to be running and healthy before the job is started
+	DependsOn map[string][]string `json:"depends_on"`
+
+	// Monit files of added jobs to find processes of each job
+	ConfigPaths map[string]string `json:"config_paths"`
+
+	// Maximum number of jobs started or stopped at once when jobs
+	// do not depend on each other; 0 handles all jobs at once
+	Concurrency int `json:"concurrency"`
+
+	// Jobs with cgroups that processes join when supervisor starts them
+	CgroupJobs []string `json:"cgroup_jobs,omitempty"`
+}
+
+type jobDependencyStore struct {
+	fs   boshsys.FileSystem
+	path string
+}
+
+func newJobDependencyStore(fs boshsys.FileSystem, boshDir string) jobDependencyStore {
+	return jobDependencyStore{fs: fs, path: path.Join(boshDir, jobDependenciesFileName)}
+}
+
+func (s jobDependencyStore) Load() (jobDependencies, error) {
+	dependencies := jobDependencies{
+		DependsOn:   map[string][]string{},
+		ConfigPaths: map[string]string{},
+	}
+
+	if !s.fs.FileExists(s.path) {
+		return dependencies, nil
+	}
+
+	contents, err := s.fs.ReadFile(s.path)
+	if err != nil {
+		return dependencies, bosherr.WrapErrorf(err, "Reading %s", s.path)
+	}
+
+	err = json.Unmarshal(contents, &dependencies)
+	if err != nil {
+		return dependencies, bosherr.WrapErrorf(err, "Unmarshalling %s", s.path)
+	}
+
+	if dependencies.DependsOn == nil {
+		dependencies.DependsOn = map[string][]string{}
+	}
+
+	if dependencies.ConfigPaths == nil {
+		dependencies.ConfigPaths = map[string]string{}
+	}
+
+	return dependencies, nil
+}
+
+func (s jobDependencyStore) save(dependencies jobDependencies) error {
+	contents, err := json.Marshal(dependencies)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling job dependencies")
+	}
+
+	return s.fs.WriteFile(s.path, contents)
+}
+
+func (s jobDependencyStore) AddJob(jobName string, configPath string) error {
+	dependencies, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	dependencies.ConfigPaths[jobName] = configPath
+
+	return s.save(dependencies)
+}
+
+// SetDependsOn replaces dependencies of added jobs; dependencies on jobs
+// that were not added and dependency cycles are rejected
+func (s jobDependencyStore) SetDependsOn(dependsOn map[string][]string) error {
+	dependencies, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	dependencies.DependsOn = dependsOn
+	if dependencies.DependsOn == nil {
+		dependencies.DependsOn = map[string][]string{}
+	}
+
+	_, err = dependencies.jobStages()
+	if err != nil {
+		return err
+	}
+
+	return s.save(dependencies)
+}
+
+func (s jobDependencyStore) SetConcurrency(limit int) error {
+	if limit < 0 {
+		return bosherr.Errorf("Job concurrency %d must not be negative", limit)
+	}
+
+	dependencies, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	dependencies.Concurrency = limit
+
+	return s.save(dependencies)
+}
+
+func (s jobDependencyStore) SetCgroupJobs(jobNames []string) error {
+	dependencies, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	dependencies.CgroupJobs = jobNames
+
+	return s.save(dependencies)
+}
+
+// HasCgroup tells whether processes of the job have to join its cgroup
+func (s jobDependencyStore) HasCgroup(jobName string) (bool, error) {
+	dependencies, err := s.Load()
+	if err != nil {
+		return false, err
+	}
+
+	for _, cgroupJob := range dependencies.CgroupJobs {
+		if cgroupJob == jobName {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func (s jobDependencyStore) RemoveAll() error {
+	return s.fs.RemoveAll(s.path)
+}
+
+// ProcessStages returns names of processes grouped by stages in which they have to be started;
+// no stages are returned when jobs do not depend on each other
+func (s jobDependencyStore) ProcessStages() ([][]string, error) {
+	dependencies, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(dependencies.DependsOn) == 0 {
+		return nil, nil
+	}
+
+	jobStages, err := dependencies.jobStages()
+	if err != nil {
+		return nil, err
+	}
+
+	var stages [][]string
+
+	for _, jobStage := range jobStages {
+		var stage []string
+
+		for _, jobName := range jobStage {
+			processNames, err := s.jobProcessNames(dependencies, jobName)
+			if err != nil {
+				return nil, err
+			}
+
+			stage = append(stage, processNames...)
+		}
+
+		if len(stage) > 0 {
+			stages = append(stages, stage)
+		}
+	}
+
+	return stages, nil
+}
+
+// ProcessGroups returns names of processes grouped by job and the maximum number of groups
+// to start or stop at once; no groups are returned when jobs depend on each other
+// or concurrency is not limited so that all processes are handled at once
+func (s jobDependencyStore) ProcessGroups() ([][]string, int, error) {
+	dependencies, err := s.Load()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(dependencies.DependsOn) > 0 || dependencies.Concurrency == 0 {
+		return nil, 0, nil
+	}
+
+	var jobNames []string
+	for jobName := range dependencies.ConfigPaths {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	var groups [][]string
+
+	for _, jobName := range jobNames {
+		processNames, err := s.jobProcessNames(dependencies, jobName)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if len(processNames) > 0 {
+			groups = append(groups, processNames)
+		}
+	}
+
+	return groups, dependencies.Concurrency, nil
+}
+
+func (s jobDependencyStore) jobProcessNames(dependencies jobDependencies, jobName string) ([]string, error) {
+	configContent, err := s.fs.ReadFileString(dependencies.ConfigPaths[jobName])
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading job config of job '%s'", jobName)
+	}
+
+	processes, err := parseMonitProcesses(configContent)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing job config of job '%s'", jobName)
+	}
+
+	var processNames []string
+	for _, process := range processes {
+		processNames = append(processNames, process.Name)
+	}
+
+	return processNames, nil
+}
+
+// jobStages orders added jobs topologically; jobs within a stage
+// only depend on jobs of previous stages
+func (d jobDependencies) jobStages() ([][]string, error) {
+	for jobName, dependsOn := range d.DependsOn {
+		if _, found := d.ConfigPaths[jobName]; !found {
+			return nil, bosherr.Errorf("Job '%s' with dependencies is not deployed", jobName)
+		}
+
+		for _, dependency := range dependsOn {
+			if _, found := d.ConfigPaths[dependency]; !found {
+				return nil, bosherr.Errorf("Job '%s' depends on unknown job '%s'", jobName, dependency)
+			}
+		}
+	}
+
+	remaining := map[string]bool{}
+	for jobName := range d.ConfigPaths {
+		remaining[jobName] = true
+	}
+
+	var stages [][]string
+
+	for len(remaining) > 0 {
+		var stage []string
+
+		for jobName := range remaining {
+			ready := true
+
+			for _, dependency := range d.DependsOn[jobName] {
+				if remaining[dependency] {
+					ready = false
+					break
+				}
+			}
+
+			if ready {
+				stage = append(stage, jobName)
+			}
+		}
+
+		if len(stage) == 0 {
+			var cycle []string
+			for jobName := range remaining {
+				cycle = append(cycle, jobName)
+			}
+			sort.Strings(cycle)
+
+			return nil, bosherr.Errorf("Dependencies of jobs %v form a cycle", cycle)
+		}
+
+		sort.Strings(stage)
+
+		for _, jobName := range stage {
+			delete(remaining, jobName)
+		}
+
+		stages = append(stages, stage)
+	}
+
+	return stages, nil
+}
+
+// startInStages starts processes stage by stage waiting for processes of each stage
+// to become healthy before starting processes of the next stage
+func startInStages(
+	clock clock.Clock,
+	logger boshlog.Logger,
+	stages [][]string,
+	start func(processes []string) error,
+	unhealthy func(processes []string) ([]string, error),
+) error {
+	maxCheckTries := int(jobDependenciesWaitTimeout / jobDependenciesCheckInterval)
+
+	for i, stage := range stages {
+		logger.Debug(jobDependenciesLogTag, "Starting processes %v of stage %d", stage, i+1)
+
+		err := start(stage)
+		if err != nil {
+			return err
+		}
+
+		if i == len(stages)-1 {
+			break
+		}
+
+		for checkI := 0; ; checkI++ {
+			pending, err := unhealthy(stage)
+			if err != nil {
+				logger.Debug(jobDependenciesLogTag, "Checking health of processes %v: %s", stage, err.Error())
+				pending = stage
+			}
+
+			if len(pending) == 0 {
+				break
+			}
+
+			if checkI >= maxCheckTries {
+				return bosherr.Errorf("Processes %v did not become healthy within %s, dependent jobs were not started",
+					pending, jobDependenciesWaitTimeout)
+			}
+
+			logger.Debug(jobDependenciesLogTag, "Waiting for processes %v to become healthy", pending)
+
+			clock.Sleep(jobDependenciesCheckInterval)
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package jobsupervisor
 
 import (
+	"time"
+
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 )
 
@@ -10,6 +12,12 @@ type Process struct {
 	Uptime UptimeVitals `json:"uptime,omitempty"`
 	Memory MemoryVitals `json:"mem,omitempty"`
 	CPU    CPUVitals    `json:"cpu,omitempty"`
+	FD     FDVitals     `json:"fd,omitempty"`
+
+	// Left out by job supervisors that cannot tell
+	Restarts *int          `json:"restarts,omitempty"`
+	LastExit *ExitVitals   `json:"last_exit,omitempty"`
+	Health   *HealthVitals `json:"health,omitempty"`
 }
 
 type UptimeVitals struct {
@@ -25,6 +33,30 @@ type CPUVitals struct {
 	Total float64 `json:"total"`
 }
 
+type FDVitals struct {
+	Open int `json:"open"`
+}
+
+// ExitVitals describes how the last run of the process ended
+type ExitVitals struct {
+	// Set when process exited on its own
+	Status int `json:"status"`
+
+	// Set when process was terminated by a signal, e.g. TERM
+	Signal     string `json:"signal,omitempty"`
+	CoreDumped bool   `json:"core_dumped,omitempty"`
+}
+
+// HealthVitals holds the last result of process' liveness probe
+type HealthVitals struct {
+	Healthy bool `json:"healthy"`
+
+	// Consecutive failures of the probe
+	Failures  int       `json:"failures"`
+	Reason    string    `json:"reason,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
 type JobFailureHandler func(boshalert.MonitAlert) error
 
 type JobSupervisor interface {
@@ -46,5 +78,17 @@ type JobSupervisor interface {
 	AddJob(jobName string, jobIndex int, configPath string) error
 	RemoveAllJobs() error
 
+	// SetJobDependencies is called after all jobs were added with
+	// names of jobs that have to be healthy before each job is started
+	SetJobDependencies(dependencies map[string][]string) error
+
+	// SetJobConcurrency limits number of jobs started or stopped at once
+	// when jobs do not depend on each other; 0 removes the limit
+	SetJobConcurrency(limit int) error
+
+	// SetJobCgroups is called before jobs are added with names of jobs
+	// whose cgroups their processes should join once started
+	SetJobCgroups(jobNames []string) error
+
 	MonitorJobFailures(handler JobFailureHandler) error
 }
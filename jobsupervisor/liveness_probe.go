@@ -0,0 +1,396 @@
+package jobsupervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	livenessProbeLogTag = "livenessProbe"
+
+	// See loadProcessPolicies; monit ignores liveness probes and only checks that pid exists
+	livenessProbesFileName = "liveness_probes.json"
+
+	// Probes of added jobs are kept under bosh dir so that supervisors can run them
+	livenessProbesDirName = "liveness_probes"
+)
+
+const (
+	LivenessFailureActionRestart = "restart"
+	LivenessFailureActionAlert   = "alert"
+)
+
+// LivenessProbe checks that a running process is still able to do its work;
+// exactly one of HTTP, TCP or Exec has to be set
+type LivenessProbe struct {
+	HTTP *HTTPProbe `json:"http,omitempty"`
+	TCP  *TCPProbe  `json:"tcp,omitempty"`
+	Exec *ExecProbe `json:"exec,omitempty"`
+
+	IntervalSecs     int `json:"interval_secs"`
+	TimeoutSecs      int `json:"timeout_secs"`
+	FailureThreshold int `json:"failure_threshold"`
+
+	// Possible values: restart (default), alert (i.e. process keeps running and job is failing)
+	FailureAction string `json:"failure_action"`
+}
+
+// HTTPProbe succeeds when GET responds with 2xx or 3xx status
+type HTTPProbe struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+	Path string `json:"path"`
+}
+
+// TCPProbe succeeds when connection can be established
+type TCPProbe struct {
+	Host string `json:"host"`
+	Port int    `json:"port"`
+}
+
+// ExecProbe succeeds when command exits with 0
+type ExecProbe struct {
+	Command string `json:"command"`
+}
+
+// loadLivenessProbes returns probes keyed by process name;
+// jobs without liveness probes file get no probes
+func loadLivenessProbes(fs boshsys.FileSystem, configPath string) (map[string]LivenessProbe, error) {
+	return loadProcessPolicies[LivenessProbe](fs, configPath, livenessProbesFileName, "liveness probe")
+}
+
+func (p LivenessProbe) withDefaults() LivenessProbe {
+	if p.IntervalSecs == 0 {
+		p.IntervalSecs = 10
+	}
+
+	if p.TimeoutSecs == 0 {
+		p.TimeoutSecs = 5
+	}
+
+	if p.FailureThreshold == 0 {
+		p.FailureThreshold = 3
+	}
+
+	if p.FailureAction == "" {
+		p.FailureAction = LivenessFailureActionRestart
+	}
+
+	if p.HTTP != nil {
+		httpProbe := *p.HTTP
+		if httpProbe.Host == "" {
+			httpProbe.Host = "127.0.0.1"
+		}
+		if httpProbe.Path == "" {
+			httpProbe.Path = "/"
+		}
+		p.HTTP = &httpProbe
+	}
+
+	if p.TCP != nil {
+		tcpProbe := *p.TCP
+		if tcpProbe.Host == "" {
+			tcpProbe.Host = "127.0.0.1"
+		}
+		p.TCP = &tcpProbe
+	}
+
+	return p
+}
+
+func (p LivenessProbe) Validate() error {
+	var kinds int
+
+	if p.HTTP != nil {
+		kinds++
+
+		if p.HTTP.Port <= 0 || p.HTTP.Port > 65535 {
+			return bosherr.Errorf("HTTP port %d is invalid", p.HTTP.Port)
+		}
+
+		if !strings.HasPrefix(p.HTTP.Path, "/") {
+			return bosherr.Errorf("HTTP path '%s' must start with /", p.HTTP.Path)
+		}
+	}
+
+	if p.TCP != nil {
+		kinds++
+
+		if p.TCP.Port <= 0 || p.TCP.Port > 65535 {
+			return bosherr.Errorf("TCP port %d is invalid", p.TCP.Port)
+		}
+	}
+
+	if p.Exec != nil {
+		kinds++
+
+		if strings.TrimSpace(p.Exec.Command) == "" {
+			return bosherr.Error("Exec command must not be empty")
+		}
+	}
+
+	if kinds != 1 {
+		return bosherr.Error("Exactly one of http, tcp or exec must be specified")
+	}
+
+	if p.IntervalSecs <= 0 || p.TimeoutSecs <= 0 || p.FailureThreshold <= 0 {
+		return bosherr.Error("Interval, timeout and failure threshold must be positive")
+	}
+
+	switch p.FailureAction {
+	case LivenessFailureActionRestart, LivenessFailureActionAlert:
+	default:
+		return bosherr.Errorf("Unknown failure action '%s'", p.FailureAction)
+	}
+
+	return nil
+}
+
+// livenessProbeStore keeps probes of added processes, their last results
+// and marks processes whose probe is failing with alert failure action
+type livenessProbeStore struct {
+	processPolicyStore[LivenessProbe]
+}
+
+func newLivenessProbeStore(fs boshsys.FileSystem, boshDir string) livenessProbeStore {
+	return livenessProbeStore{newProcessPolicyStore[LivenessProbe](fs, boshDir, livenessProbesDirName, "liveness probe")}
+}
+
+func (s livenessProbeStore) SetUnhealthy(processName string, unhealthy bool) error {
+	unhealthyPath := path.Join(s.dir, processName+".unhealthy")
+
+	if unhealthy {
+		return s.fs.WriteFileString(unhealthyPath, "")
+	}
+
+	return s.fs.RemoveAll(unhealthyPath)
+}
+
+func (s livenessProbeStore) Unhealthy(processName string) bool {
+	return s.fs.FileExists(path.Join(s.dir, processName+".unhealthy"))
+}
+
+// SetHealth records the last result of the probe; nil forgets it
+func (s livenessProbeStore) SetHealth(processName string, health *HealthVitals) error {
+	healthPath := path.Join(s.dir, processName+".health")
+
+	if health == nil {
+		return s.fs.RemoveAll(healthPath)
+	}
+
+	contents, err := json.Marshal(health)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling liveness probe result")
+	}
+
+	return s.fs.WriteFile(healthPath, contents)
+}
+
+// Health returns nil when probe of the process did not run since process started
+func (s livenessProbeStore) Health(processName string) *HealthVitals {
+	contents, err := s.fs.ReadFile(path.Join(s.dir, processName+".health"))
+	if err != nil {
+		return nil
+	}
+
+	var health HealthVitals
+
+	err = json.Unmarshal(contents, &health)
+	if err != nil {
+		return nil
+	}
+
+	return &health
+}
+
+// livenessProber runs probes of running processes at their intervals
+// and counts consecutive failures
+type livenessProber struct {
+	store  livenessProbeStore
+	runner boshsys.CmdRunner
+	clock  clock.Clock
+	logger boshlog.Logger
+
+	probes   map[string]LivenessProbe
+	lastRuns map[string]time.Time
+	failures map[string]int
+}
+
+func newLivenessProber(store livenessProbeStore, runner boshsys.CmdRunner, clock clock.Clock, logger boshlog.Logger) *livenessProber {
+	return &livenessProber{
+		store:  store,
+		runner: runner,
+		clock:  clock,
+		logger: logger,
+
+		probes:   map[string]LivenessProbe{},
+		lastRuns: map[string]time.Time{},
+		failures: map[string]int{},
+	}
+}
+
+// Reload picks up probes of jobs added since the last reload
+func (p *livenessProber) Reload() {
+	probes, err := p.store.Policies()
+	if err != nil {
+		p.logger.Debug(livenessProbeLogTag, "Loading liveness probes: %s", err.Error())
+		return
+	}
+
+	p.probes = probes
+}
+
+// Check returns the probe and the reason of its last failure when probe of the process
+// reached its failure threshold; failures of processes with alert failure action
+// are only reported once until the probe succeeds again
+func (p *livenessProber) Check(processName string, running bool) (LivenessProbe, string, bool) {
+	probe, found := p.probes[processName]
+	if !found || !running {
+		if _, found := p.lastRuns[processName]; found {
+			p.setHealth(processName, nil)
+		}
+
+		delete(p.lastRuns, processName)
+		delete(p.failures, processName)
+		return probe, "", false
+	}
+
+	now := p.clock.Now()
+
+	if lastRun, found := p.lastRuns[processName]; found && now.Sub(lastRun) < time.Duration(probe.IntervalSecs)*time.Second {
+		return probe, "", false
+	}
+
+	p.lastRuns[processName] = now
+
+	err := p.run(probe)
+	if err == nil {
+		p.failures[processName] = 0
+		p.setHealth(processName, &HealthVitals{Healthy: true, CheckedAt: now})
+
+		if p.store.Unhealthy(processName) {
+			p.logger.Info(livenessProbeLogTag, "Liveness probe of %s succeeded again", processName)
+			p.setUnhealthy(processName, false)
+		}
+
+		return probe, "", false
+	}
+
+	p.failures[processName]++
+	p.setHealth(processName, &HealthVitals{Failures: p.failures[processName], Reason: err.Error(), CheckedAt: now})
+
+	p.logger.Debug(livenessProbeLogTag, "Liveness probe of %s failed %d time(s): %s",
+		processName, p.failures[processName], err.Error())
+
+	if probe.FailureAction == LivenessFailureActionRestart {
+		if p.failures[processName] < probe.FailureThreshold {
+			return probe, "", false
+		}
+
+		// Restarted process gets a fresh start
+		p.failures[processName] = 0
+		return probe, err.Error(), true
+	}
+
+	if p.failures[processName] != probe.FailureThreshold {
+		return probe, "", false
+	}
+
+	p.setUnhealthy(processName, true)
+
+	return probe, err.Error(), true
+}
+
+// Passes runs probe of the process right away; processes without probe pass
+func (p *livenessProber) Passes(processName string) bool {
+	probe, found := p.probes[processName]
+	if !found {
+		return true
+	}
+
+	err := p.run(probe)
+	if err != nil {
+		p.logger.Debug(livenessProbeLogTag, "Liveness probe of %s failed: %s", processName, err.Error())
+		return false
+	}
+
+	return true
+}
+
+func (p *livenessProber) setUnhealthy(processName string, unhealthy bool) {
+	err := p.store.SetUnhealthy(processName, unhealthy)
+	if err != nil {
+		p.logger.Error(livenessProbeLogTag, "Marking health of %s: %s", processName, err.Error())
+	}
+}
+
+func (p *livenessProber) setHealth(processName string, health *HealthVitals) {
+	err := p.store.SetHealth(processName, health)
+	if err != nil {
+		p.logger.Error(livenessProbeLogTag, "Recording health of %s: %s", processName, err.Error())
+	}
+}
+
+func (p *livenessProber) run(probe LivenessProbe) error {
+	timeout := time.Duration(probe.TimeoutSecs) * time.Second
+
+	switch {
+	case probe.HTTP != nil:
+		url := fmt.Sprintf("http://%s%s", net.JoinHostPort(probe.HTTP.Host, strconv.Itoa(probe.HTTP.Port)), probe.HTTP.Path)
+
+		client := http.Client{Timeout: timeout}
+
+		resp, err := client.Get(url)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Requesting %s", url)
+		}
+
+		resp.Body.Close()
+
+		if resp.StatusCode < 200 || resp.StatusCode >= 400 {
+			return bosherr.Errorf("Requesting %s: responded with status %d", url, resp.StatusCode)
+		}
+
+	case probe.TCP != nil:
+		address := net.JoinHostPort(probe.TCP.Host, strconv.Itoa(probe.TCP.Port))
+
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Connecting to %s", address)
+		}
+
+		conn.Close()
+
+	case probe.Exec != nil:
+		_, _, _, err := p.runner.RunCommand("timeout", strconv.Itoa(probe.TimeoutSecs), "sh", "-c", probe.Exec.Command)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Running '%s'", probe.Exec.Command)
+		}
+	}
+
+	return nil
+}
+
+// description returns what the probe checks for alerts
+func (p LivenessProbe) description() string {
+	switch {
+	case p.HTTP != nil:
+		return fmt.Sprintf("HTTP liveness probe of port %d path %s", p.HTTP.Port, p.HTTP.Path)
+	case p.TCP != nil:
+		return fmt.Sprintf("TCP liveness probe of port %d", p.TCP.Port)
+	default:
+		return "exec liveness probe"
+	}
+}
@@ -0,0 +1,178 @@
+package jobsupervisor
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	memoryWatchdogLogTag = "memoryWatchdog"
+
+	// See loadProcessPolicies; monit ignores memory policies, monit jobs can use 'if totalmem' statements instead
+	memoryPoliciesFileName = "memory_policies.json"
+
+	// Policies of added jobs are kept under bosh dir so that supervisors can enforce them
+	memoryPoliciesDirName = "memory_policies"
+)
+
+// MemoryPolicy restarts a leaky process before it exhausts memory of the whole VM;
+// at least one of MaxRSSMB or GrowthWindowSecs has to be set
+type MemoryPolicy struct {
+	// Process is restarted once its resident set size exceeds this many MB
+	MaxRSSMB uint64 `json:"max_rss_mb"`
+
+	// Process is restarted once its resident set size did not decrease
+	// between samples over this window and grew by more than MinGrowthMB
+	GrowthWindowSecs int    `json:"growth_window_secs"`
+	MinGrowthMB      uint64 `json:"min_growth_mb"`
+
+	// Defaults to 30
+	SampleIntervalSecs int `json:"sample_interval_secs"`
+}
+
+// loadMemoryPolicies returns policies keyed by process name;
+// jobs without memory policies file get no policies
+func loadMemoryPolicies(fs boshsys.FileSystem, configPath string) (map[string]MemoryPolicy, error) {
+	return loadProcessPolicies[MemoryPolicy](fs, configPath, memoryPoliciesFileName, "memory policy")
+}
+
+func (p MemoryPolicy) withDefaults() MemoryPolicy {
+	if p.SampleIntervalSecs == 0 {
+		p.SampleIntervalSecs = 30
+	}
+
+	return p
+}
+
+func (p MemoryPolicy) Validate() error {
+	if p.MaxRSSMB == 0 && p.GrowthWindowSecs == 0 {
+		return bosherr.Error("Either max RSS or growth window must be specified")
+	}
+
+	if p.SampleIntervalSecs <= 0 || p.GrowthWindowSecs < 0 {
+		return bosherr.Error("Sample interval and growth window must be positive")
+	}
+
+	if p.GrowthWindowSecs > 0 && p.GrowthWindowSecs < 2*p.SampleIntervalSecs {
+		return bosherr.Errorf("Growth window must span at least two sample intervals of %d seconds", p.SampleIntervalSecs)
+	}
+
+	return nil
+}
+
+type memoryPolicyStore = processPolicyStore[MemoryPolicy]
+
+func newMemoryPolicyStore(fs boshsys.FileSystem, boshDir string) memoryPolicyStore {
+	return newProcessPolicyStore[MemoryPolicy](fs, boshDir, memoryPoliciesDirName, "memory policy")
+}
+
+type memorySample struct {
+	At    time.Time
+	Pid   int
+	RSSKb uint64
+}
+
+// memoryWatchdog samples resident set size of running processes
+// with memory policies at their sample intervals
+type memoryWatchdog struct {
+	store  memoryPolicyStore
+	fs     boshsys.FileSystem
+	clock  clock.Clock
+	logger boshlog.Logger
+
+	policies map[string]MemoryPolicy
+	samples  map[string][]memorySample
+}
+
+func newMemoryWatchdog(store memoryPolicyStore, fs boshsys.FileSystem, clock clock.Clock, logger boshlog.Logger) *memoryWatchdog {
+	return &memoryWatchdog{
+		store:  store,
+		fs:     fs,
+		clock:  clock,
+		logger: logger,
+
+		policies: map[string]MemoryPolicy{},
+		samples:  map[string][]memorySample{},
+	}
+}
+
+// Reload picks up policies of jobs added since the last reload
+func (w *memoryWatchdog) Reload() {
+	policies, err := w.store.Policies()
+	if err != nil {
+		w.logger.Debug(memoryWatchdogLogTag, "Loading memory policies: %s", err.Error())
+		return
+	}
+
+	w.policies = policies
+}
+
+// Check returns why the process has to be restarted once its memory usage
+// breaks its policy; samples start over after the process is restarted
+func (w *memoryWatchdog) Check(processName string, pid int, running bool) (string, bool) {
+	policy, found := w.policies[processName]
+	if !found || !running || pid <= 0 {
+		delete(w.samples, processName)
+		return "", false
+	}
+
+	now := w.clock.Now()
+	samples := w.samples[processName]
+
+	if len(samples) > 0 {
+		last := samples[len(samples)-1]
+
+		if last.Pid != pid {
+			samples = nil
+		} else if now.Sub(last.At) < time.Duration(policy.SampleIntervalSecs)*time.Second {
+			return "", false
+		}
+	}
+
+	rssKb, err := readResidentMemoryKb(w.fs, pid)
+	if err != nil {
+		w.logger.Debug(memoryWatchdogLogTag, "Reading memory usage of %s: %s", processName, err.Error())
+		return "", false
+	}
+
+	samples = append(samples, memorySample{At: now, Pid: pid, RSSKb: rssKb})
+
+	// Oldest sample kept is the newest one taken at least a window ago
+	window := time.Duration(policy.GrowthWindowSecs) * time.Second
+	for len(samples) > 1 && now.Sub(samples[1].At) >= window {
+		samples = samples[1:]
+	}
+
+	w.samples[processName] = samples
+
+	if policy.MaxRSSMB > 0 && rssKb > policy.MaxRSSMB*1024 {
+		delete(w.samples, processName)
+		return fmt.Sprintf("RSS of %d MB exceeds %d MB", rssKb/1024, policy.MaxRSSMB), true
+	}
+
+	if policy.GrowthWindowSecs > 0 && now.Sub(samples[0].At) >= window && grewMonotonically(samples, policy.MinGrowthMB*1024) {
+		delete(w.samples, processName)
+		return fmt.Sprintf("RSS grew from %d MB to %d MB over %d seconds",
+			samples[0].RSSKb/1024, rssKb/1024, int(now.Sub(samples[0].At).Seconds())), true
+	}
+
+	return "", false
+}
+
+func grewMonotonically(samples []memorySample, minGrowthKb uint64) bool {
+	for i := 1; i < len(samples); i++ {
+		if samples[i].RSSKb < samples[i-1].RSSKb {
+			return false
+		}
+	}
+
+	first, last := samples[0].RSSKb, samples[len(samples)-1].RSSKb
+
+	return last > first && last-first > minGrowthKb
+}
@@ -1,10 +1,15 @@
 package fakes
 
 import (
+	"sync"
+
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 )
 
 type FakeMonitClient struct {
+	// Services are started and stopped concurrently
+	servicesLock sync.Mutex
+
 	ServicesInGroupName     string
 	ServicesInGroupServices []string
 	ServicesInGroupErr      error
@@ -35,11 +40,17 @@ func (c *FakeMonitClient) ServicesInGroup(name string) ([]string, error) {
 }
 
 func (c *FakeMonitClient) StartService(name string) error {
+	c.servicesLock.Lock()
+	defer c.servicesLock.Unlock()
+
 	c.StartServiceNames = append(c.StartServiceNames, name)
 	return c.StartServiceErr
 }
 
 func (c *FakeMonitClient) StopService(name string) error {
+	c.servicesLock.Lock()
+	defer c.servicesLock.Unlock()
+
 	c.StopServiceNames = append(c.StopServiceNames, name)
 	return c.StopServiceErr
 }
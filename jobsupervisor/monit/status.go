@@ -26,6 +26,7 @@ type serviceTag struct {
 	Status   int       `xml:"status"`
 	Monitor  int       `xml:"monitor"`
 	Uptime   int       `xml:"uptime"`
+	Pid      int       `xml:"pid"`
 	Children int       `xml:"children"`
 	Memory   memoryTag `xml:"memory"`
 	CPU      cpuTag    `xml:"cpu"`
@@ -57,6 +58,42 @@ type serviceGroupTag struct {
 	Services []string `xml:"service"`
 }
 
+// Bits of service status set by monit for failed checks named
+// after events of alerts monit sends for them
+var statusEvents = []struct {
+	bit   int
+	event string
+}{
+	{0x1, "checksum failed"},
+	{0x2, "resource limit matched"},
+	{0x4, "timeout"},
+	{0x8, "timestamp failed"},
+	{0x10, "size failed"},
+	{0x20, "connection failed"},
+	{0x40, "permission failed"},
+	{0x80, "uid failed"},
+	{0x100, "gid failed"},
+	{0x200, "does not exist"},
+	{0x400, "invalid type"},
+	{0x800, "data access error"},
+	{0x1000, "execution failed"},
+	{0x2000, "filesystem flags failed"},
+	{0x4000, "icmp failed"},
+	{0x8000, "content failed"},
+	{0x40000, "pid failed"},
+	{0x80000, "ppid failed"},
+	{0x100000, "heartbeat failed"},
+}
+
+func (s serviceTag) FailedEvents() (events []string) {
+	for _, statusEvent := range statusEvents {
+		if s.Status&statusEvent.bit != 0 {
+			events = append(events, statusEvent.event)
+		}
+	}
+	return
+}
+
 func (s serviceTag) StatusString() (status string) {
 	switch {
 	case s.Monitor == 0:
@@ -104,8 +141,10 @@ func (status status) ServicesInGroup(name string) (services []Service) {
 			service := Service{
 				Name:                 serviceTag.Name,
 				Status:               serviceTag.StatusString(),
+				FailedEvents:         serviceTag.FailedEvents(),
 				Monitored:            serviceTag.Monitor > 0,
 				Uptime:               serviceTag.Uptime,
+				Pid:                  serviceTag.Pid,
 				MemoryPercentTotal:   serviceTag.Memory.PercentTotal,
 				MemoryKilobytesTotal: serviceTag.Memory.KilobyteTotal,
 				CPUPercentTotal:      serviceTag.CPU.PercentTotal,
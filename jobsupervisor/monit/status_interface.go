@@ -9,7 +9,9 @@ type Service struct {
 	Name                 string
 	Monitored            bool
 	Status               string
+	FailedEvents         []string
 	Uptime               int
+	Pid                  int
 	MemoryPercentTotal   float64
 	MemoryKilobytesTotal int
 	CPUPercentTotal      float64
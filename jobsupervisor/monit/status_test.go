@@ -47,7 +47,7 @@ var _ = Describe("status", func() {
 				Service{Name: "running-service", Monitored: true, Status: "running"},
 				Service{Name: "unmonitored-service", Monitored: false, Status: "unknown"},
 				Service{Name: "starting-service", Monitored: true, Status: "starting"},
-				Service{Name: "failing-service", Monitored: true, Status: "failing"},
+				Service{Name: "failing-service", Monitored: true, Status: "failing", FailedEvents: []string{"does not exist"}},
 			}
 
 			services := status.ServicesInGroup("vcap")
@@ -92,6 +92,7 @@ var _ = Describe("status", func() {
 					Monitored:            true,
 					Status:               "running",
 					Uptime:               880183,
+					Pid:                  1,
 					MemoryPercentTotal:   0,
 					MemoryKilobytesTotal: 4004,
 					CPUPercentTotal:      0,
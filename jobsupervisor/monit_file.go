@@ -0,0 +1,198 @@
+package jobsupervisor
+
+import (
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const monitDefaultProgramTimeout = 30
+
+// monitProcess is a 'check process' entry of a job's monit file
+type monitProcess struct {
+	Name    string
+	PidFile string
+
+	StartProgram monitProgram
+	StopProgram  monitProgram
+}
+
+type monitProgram struct {
+	Command string
+	User    string
+	Group   string
+
+	// in seconds
+	Timeout int
+}
+
+// parseMonitProcesses reads process checks of a monit file;
+// other checks and tests, e.g. 'if failed port', are ignored
+func parseMonitProcesses(contents string) ([]monitProcess, error) {
+	tokens, err := monitTokens(contents)
+	if err != nil {
+		return nil, err
+	}
+
+	var processes []monitProcess
+	var process *monitProcess
+
+	for i := 0; i < len(tokens); i++ {
+		switch tokens[i] {
+		case "check":
+			if i+2 >= len(tokens) {
+				return nil, bosherr.Error("Parsing 'check' statement")
+			}
+
+			if process != nil {
+				processes = append(processes, *process)
+				process = nil
+			}
+
+			if tokens[i+1] == "process" {
+				process = &monitProcess{Name: tokens[i+2]}
+			}
+
+			i += 2
+
+		case "pidfile":
+			if process == nil || i+1 >= len(tokens) {
+				continue
+			}
+
+			process.PidFile = tokens[i+1]
+			i++
+
+		case "start", "stop":
+			if process == nil {
+				continue
+			}
+
+			keyword := tokens[i]
+
+			program, lastIndex, err := parseMonitProgram(tokens, i+1)
+			if err != nil {
+				return nil, bosherr.WrapErrorf(err, "Parsing %s program of process '%s'", keyword, process.Name)
+			}
+
+			if keyword == "start" {
+				process.StartProgram = program
+			} else {
+				process.StopProgram = program
+			}
+
+			i = lastIndex
+		}
+	}
+
+	if process != nil {
+		processes = append(processes, *process)
+	}
+
+	for _, process := range processes {
+		if process.StartProgram.Command == "" {
+			return nil, bosherr.Errorf("Process '%s' has no start program", process.Name)
+		}
+	}
+
+	return processes, nil
+}
+
+// parseMonitProgram parses e.g. 'program = "/bin/ctl start" as uid vcap and gid vcap with timeout 60 seconds'
+// starting at token after 'start' or 'stop'; it returns index of the last consumed token
+func parseMonitProgram(tokens []string, i int) (monitProgram, int, error) {
+	program := monitProgram{Timeout: monitDefaultProgramTimeout}
+
+	next := func(expected string) bool {
+		if i < len(tokens) && tokens[i] == expected {
+			i++
+			return true
+		}
+		return false
+	}
+
+	next("program")
+	next("=")
+
+	if i >= len(tokens) {
+		return program, i, bosherr.Error("Missing command")
+	}
+
+	program.Command = tokens[i]
+	i++
+
+	if next("as") {
+		if next("uid") && i < len(tokens) {
+			program.User = tokens[i]
+			i++
+		}
+
+		if next("and") && next("gid") && i < len(tokens) {
+			program.Group = tokens[i]
+			i++
+		}
+	}
+
+	if next("with") && next("timeout") && i < len(tokens) {
+		timeout, err := strconv.Atoi(tokens[i])
+		if err != nil {
+			return program, i, bosherr.WrapErrorf(err, "Parsing timeout '%s'", tokens[i])
+		}
+		program.Timeout = timeout
+		i++
+	}
+
+	return program, i - 1, nil
+}
+
+// monitTokens splits monit control file into words and quoted strings without comments
+func monitTokens(contents string) ([]string, error) {
+	var tokens []string
+	var token strings.Builder
+	var inToken bool
+	var quote rune
+
+	endToken := func() {
+		if inToken {
+			tokens = append(tokens, token.String())
+			token.Reset()
+			inToken = false
+		}
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		for _, char := range line {
+			if quote == 0 && char == '#' {
+				break
+			}
+
+			switch {
+			case quote != 0:
+				if char == quote {
+					quote = 0
+					endToken()
+				} else {
+					token.WriteRune(char)
+				}
+			case char == '"' || char == '\'':
+				endToken()
+				quote = char
+				inToken = true
+			case char == ' ' || char == '\t' || char == '\r':
+				endToken()
+			default:
+				token.WriteRune(char)
+				inToken = true
+			}
+		}
+
+		if quote != 0 {
+			return nil, bosherr.Errorf("Unterminated quoted string in line '%s'", line)
+		}
+
+		endToken()
+	}
+
+	return tokens, nil
+}
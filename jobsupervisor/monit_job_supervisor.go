@@ -5,6 +5,7 @@ import (
 	"path"
 	"time"
 
+	"github.com/pivotal-golang/clock"
 	"github.com/pivotal/go-smtpd/smtpd"
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
@@ -27,6 +28,12 @@ type monitJobSupervisor struct {
 	jobFailuresServerPort int
 
 	reloadOptions MonitReloadOptions
+
+	clock clock.Clock
+
+	// Monit status is polled for failures in addition to alerts mailed by monit
+	// since mails are only sent after monit cycle and may be lost
+	jobFailuresPollInterval time.Duration
 }
 
 type MonitReloadOptions struct {
@@ -49,6 +56,8 @@ func NewMonitJobSupervisor(
 	dirProvider boshdir.Provider,
 	jobFailuresServerPort int,
 	reloadOptions MonitReloadOptions,
+	clock clock.Clock,
+	jobFailuresPollInterval time.Duration,
 ) JobSupervisor {
 	return monitJobSupervisor{
 		fs:          fs,
@@ -60,6 +69,10 @@ func NewMonitJobSupervisor(
 		jobFailuresServerPort: jobFailuresServerPort,
 
 		reloadOptions: reloadOptions,
+
+		clock: clock,
+
+		jobFailuresPollInterval: jobFailuresPollInterval,
 	}
 }
 
@@ -115,12 +128,17 @@ func (m monitJobSupervisor) Start() error {
 		return bosherr.WrapError(err, "Getting vcap services")
 	}
 
-	for _, service := range services {
+	err = m.inParallel(services, func(service string) error {
 		m.logger.Debug(monitJobSupervisorLogTag, "Starting service %s", service)
-		err = m.client.StartService(service)
+		err := m.client.StartService(service)
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Starting service %s", service)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	err = m.fs.RemoveAll(m.stoppedFilePath())
@@ -137,12 +155,17 @@ func (m monitJobSupervisor) Stop() error {
 		return bosherr.WrapError(err, "Getting vcap services")
 	}
 
-	for _, service := range services {
+	err = m.inParallel(services, func(service string) error {
 		m.logger.Debug(monitJobSupervisorLogTag, "Stopping service %s", service)
-		err = m.client.StopService(service)
+		err := m.client.StopService(service)
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Stopping service %s", service)
 		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
 	err = m.fs.WriteFileString(m.stoppedFilePath(), "")
@@ -153,6 +176,24 @@ func (m monitJobSupervisor) Stop() error {
 	return nil
 }
 
+// inParallel runs action for at most job concurrency services at once since monit
+// does not know which job a service belongs to; monit still honors 'depends on' statements
+func (m monitJobSupervisor) inParallel(services []string, action func(service string) error) error {
+	dependencies, err := newJobDependencyStore(m.fs, m.dirProvider.BoshDir()).Load()
+	if err != nil {
+		return bosherr.WrapError(err, "Loading job concurrency")
+	}
+
+	var groups [][]string
+	for _, service := range services {
+		groups = append(groups, []string{service})
+	}
+
+	return inParallel(groups, dependencies.Concurrency, func(group []string) error {
+		return action(group[0])
+	})
+}
+
 func (m monitJobSupervisor) Unmonitor() error {
 	services, err := m.client.ServicesInGroup("vcap")
 	if err != nil {
@@ -220,6 +261,9 @@ func (m monitJobSupervisor) Processes() (processes []Process, err error) {
 			CPU: CPUVitals{
 				Total: service.CPUPercentTotal,
 			},
+			FD: FDVitals{
+				Open: m.openFDs(service.Pid),
+			},
 		}
 		processes = append(processes, process)
 	}
@@ -227,6 +271,21 @@ func (m monitJobSupervisor) Processes() (processes []Process, err error) {
 	return
 }
 
+// openFDs returns 0 when process is not running since monit reports no pid
+func (m monitJobSupervisor) openFDs(pid int) int {
+	if pid <= 0 {
+		return 0
+	}
+
+	fds, err := countOpenFDs(m.fs, pid)
+	if err != nil {
+		m.logger.Debug(monitJobSupervisorLogTag, "Counting open fds of pid %d: %s", pid, err.Error())
+		return 0
+	}
+
+	return fds
+}
+
 func (m monitJobSupervisor) getIncarnation() (int, error) {
 	monitStatus, err := m.client.Status()
 	if err != nil {
@@ -257,11 +316,45 @@ func (m monitJobSupervisor) RemoveAllJobs() error {
 	return m.fs.RemoveAll(m.dirProvider.MonitJobsDir())
 }
 
+// SetJobConcurrency makes Start and Stop control at most limit services at once
+func (m monitJobSupervisor) SetJobConcurrency(limit int) error {
+	err := newJobDependencyStore(m.fs, m.dirProvider.BoshDir()).SetConcurrency(limit)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job concurrency")
+	}
+
+	return nil
+}
+
+// SetJobCgroups leaves processes started by monit to be moved
+// into cgroups by the agent since monit cannot place them itself
+func (m monitJobSupervisor) SetJobCgroups(jobNames []string) error {
+	return nil
+}
+
+// SetJobDependencies leaves start order to monit which honors
+// 'depends on' statements of jobs' monit files
+func (m monitJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	if len(dependencies) > 0 {
+		m.logger.Warn(monitJobSupervisorLogTag, "Job dependencies %v are ignored, monit starts processes according to 'depends on' statements of monit files", dependencies)
+	}
+
+	return nil
+}
+
 func (m monitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) (err error) {
+	// Same failure is usually both mailed and polled within a few poll intervals
+	deduplicator := newAlertDeduplicator(handler, m.clock, 3*m.jobFailuresPollInterval)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	go m.pollJobFailures(deduplicator.Handle, done)
+
 	alertHandler := func(smtpd.Connection, smtpd.MailAddress) (env smtpd.Envelope, err error) {
 		env = &alertEnvelope{
 			new(smtpd.BasicEnvelope),
-			handler,
+			deduplicator.Handle,
 			new(boshalert.MonitAlert),
 		}
 		return
@@ -279,6 +372,98 @@ func (m monitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) (err e
 	return
 }
 
+// pollJobFailures alerts about checks failed by monit services and about services
+// restarted by monit between polls until done is closed
+func (m monitJobSupervisor) pollJobFailures(handler JobFailureHandler, done <-chan struct{}) {
+	previous := map[string]boshmonit.Service{}
+
+	for {
+		previous = m.checkJobFailures(handler, previous)
+
+		m.clock.Sleep(m.jobFailuresPollInterval)
+
+		select {
+		case <-done:
+			return
+		default:
+		}
+	}
+}
+
+func (m monitJobSupervisor) checkJobFailures(handler JobFailureHandler, previous map[string]boshmonit.Service) map[string]boshmonit.Service {
+	current := map[string]boshmonit.Service{}
+
+	// Services are expected to go away while jobs are stopped
+	if m.fs.FileExists(m.stoppedFilePath()) {
+		return current
+	}
+
+	monitStatus, err := m.client.Status()
+	if err != nil {
+		m.logger.Debug(monitJobSupervisorLogTag, "Getting monit status: %s", err.Error())
+		return previous
+	}
+
+	for _, service := range monitStatus.ServicesInGroup("vcap") {
+		if !service.Monitored {
+			continue
+		}
+
+		current[service.Name] = service
+
+		previousService, found := previous[service.Name]
+		if !found {
+			continue
+		}
+
+		previousEvents := map[string]bool{}
+		for _, event := range previousService.FailedEvents {
+			previousEvents[event] = true
+		}
+
+		for _, event := range service.FailedEvents {
+			if previousEvents[event] {
+				continue
+			}
+
+			action := "alert"
+			if event == "does not exist" {
+				action = "restart"
+			}
+
+			m.handleJobFailure(handler, service.Name, event, action,
+				fmt.Sprintf("monit service %s: %s", service.Name, event))
+		}
+
+		// Monit may restart process between polls without failed checks showing up in status
+		restarted := previousService.Pid > 0 && service.Pid > 0 && previousService.Pid != service.Pid
+		if restarted && len(previousService.FailedEvents) == 0 && len(service.FailedEvents) == 0 {
+			m.handleJobFailure(handler, service.Name, "does not exist", "restart",
+				fmt.Sprintf("monit service %s was restarted with pid %d, previous pid %d", service.Name, service.Pid, previousService.Pid))
+		}
+	}
+
+	return current
+}
+
+func (m monitJobSupervisor) handleJobFailure(handler JobFailureHandler, serviceName, event, action, description string) {
+	now := m.clock.Now()
+
+	alert := boshalert.MonitAlert{
+		ID:          fmt.Sprintf("%d.%s@localhost", now.UnixNano(), serviceName),
+		Service:     serviceName,
+		Event:       event,
+		Action:      action,
+		Date:        now.Format(time.RFC1123Z),
+		Description: description,
+	}
+
+	err := handler(alert)
+	if err != nil {
+		m.logger.Error(monitJobSupervisorLogTag, "Handling failure of %s: %s", serviceName, err.Error())
+	}
+}
+
 func (m monitJobSupervisor) stoppedFilePath() string {
 	return path.Join(m.dirProvider.MonitDir(), "stopped")
 }
@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"net/smtp"
 	"os"
+	"sync"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
 
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
@@ -28,6 +30,7 @@ var _ = Describe("monitJobSupervisor", func() {
 		logger                boshlog.Logger
 		dirProvider           boshdir.Provider
 		jobFailuresServerPort int
+		clock                 *fakeclock.FakeClock
 		monit                 JobSupervisor
 	)
 
@@ -45,6 +48,7 @@ var _ = Describe("monitJobSupervisor", func() {
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 		dirProvider = boshdir.NewProvider("/var/vcap")
 		jobFailuresServerPort = getJobFailureServerPort()
+		clock = fakeclock.NewFakeClock(time.Date(2026, time.October, 14, 10, 5, 0, 0, time.UTC))
 
 		monit = NewMonitJobSupervisor(
 			fs,
@@ -58,6 +62,8 @@ var _ = Describe("monitJobSupervisor", func() {
 				MaxCheckTries:          10,
 				DelayBetweenCheckTries: 0 * time.Millisecond,
 			},
+			clock,
+			10*time.Second,
 		)
 	})
 
@@ -158,6 +164,27 @@ var _ = Describe("monitJobSupervisor", func() {
 			Expect(client.StartServiceNames[0]).To(Equal("fake-service"))
 		})
 
+		It("starts at most job concurrency services at once", func() {
+			client.ServicesInGroupServices = []string{"fake-service-1", "fake-service-2", "fake-service-3"}
+
+			err := monit.SetJobConcurrency(2)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = monit.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(client.StartServiceNames).To(ConsistOf("fake-service-1", "fake-service-2", "fake-service-3"))
+		})
+
+		It("returns error when services cannot be started", func() {
+			client.ServicesInGroupServices = []string{"fake-service"}
+			client.StartServiceErr = errors.New("fake-start-err")
+
+			err := monit.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Starting service fake-service: fake-start-err"))
+		})
+
 		It("deletes stopped file", func() {
 			fs.MkdirAll("/var/vcap/monit/stopped", os.FileMode(0755))
 			fs.WriteFileString("/var/vcap/monit/stopped", "")
@@ -273,6 +300,8 @@ var _ = Describe("monitJobSupervisor", func() {
 
 	Describe("Processes", func() {
 		It("returns all processes", func() {
+			fs.SetGlob("/proc/1111/fd/*", []string{"/proc/1111/fd/0", "/proc/1111/fd/1", "/proc/1111/fd/2"})
+
 			client.StatusStatus = fakemonit.FakeMonitStatus{
 				Services: []boshmonit.Service{
 					boshmonit.Service{
@@ -280,6 +309,7 @@ var _ = Describe("monitJobSupervisor", func() {
 						Monitored:            true,
 						Status:               "running",
 						Uptime:               1234,
+						Pid:                  1111,
 						MemoryPercentTotal:   0.4,
 						MemoryKilobytesTotal: 100,
 						CPUPercentTotal:      0.5,
@@ -312,6 +342,9 @@ var _ = Describe("monitJobSupervisor", func() {
 					CPU: CPUVitals{
 						Total: 0.5,
 					},
+					FD: FDVitals{
+						Open: 3,
+					},
 				},
 				Process{
 					Name:  "fake-service-2",
@@ -384,6 +417,109 @@ var _ = Describe("monitJobSupervisor", func() {
 			Expect(err).ToNot(HaveOccurred())
 			Expect(didHandleAlert).To(BeFalse())
 		})
+
+		Context("when polling monit status", func() {
+			var (
+				alertsLock sync.Mutex
+				alerts     []boshalert.MonitAlert
+			)
+
+			BeforeEach(func() {
+				alerts = nil
+
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Name: "nats", Monitored: true, Status: "running", Pid: 100},
+						{Name: "redis", Monitored: true, Status: "running", Pid: 200},
+					},
+				}
+
+				go monit.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+					alertsLock.Lock()
+					defer alertsLock.Unlock()
+					alerts = append(alerts, alert)
+					return nil
+				})
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+			})
+
+			alertCount := func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}
+
+			It("alerts about newly failed checks and processes restarted by monit", func() {
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Name: "nats", Monitored: true, Status: "failing", FailedEvents: []string{"does not exist"}},
+						{Name: "redis", Monitored: true, Status: "running", Pid: 201},
+					},
+				}
+
+				clock.Increment(10 * time.Second)
+
+				Eventually(alertCount).Should(Equal(2))
+
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				Expect(alerts[0]).To(Equal(boshalert.MonitAlert{
+					ID:          "1791972310000000000.nats@localhost",
+					Service:     "nats",
+					Event:       "does not exist",
+					Action:      "restart",
+					Date:        "Wed, 14 Oct 2026 10:05:10 +0000",
+					Description: "monit service nats: does not exist",
+				}))
+
+				Expect(alerts[1].Service).To(Equal("redis"))
+				Expect(alerts[1].Event).To(Equal("does not exist"))
+				Expect(alerts[1].Action).To(Equal("restart"))
+				Expect(alerts[1].Description).To(ContainSubstring("pid 201"))
+			})
+
+			It("does not alert again when monit mails the same failure", func() {
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Name: "nats", Monitored: true, Status: "failing", FailedEvents: []string{"does not exist"}},
+						{Name: "redis", Monitored: true, Status: "running", Pid: 200},
+					},
+				}
+
+				clock.Increment(10 * time.Second)
+
+				Eventually(alertCount).Should(Equal(1))
+
+				msg := `Message-id: <1304319946.0@localhost>
+ Service: nats
+ Event: Does not exist
+ Action: restart
+ Date: Sun, 22 May 2011 20:07:41 +0500
+ Description: process is not running`
+
+				err := doJobFailureEmail(msg, jobFailuresServerPort)
+				Expect(err).ToNot(HaveOccurred())
+
+				Consistently(alertCount, 100*time.Millisecond).Should(Equal(1))
+			})
+
+			It("does not alert while jobs are stopped", func() {
+				fs.WriteFileString("/var/vcap/monit/stopped", "")
+
+				client.StatusStatus = fakemonit.FakeMonitStatus{
+					Services: []boshmonit.Service{
+						{Name: "nats", Monitored: true, Status: "failing", FailedEvents: []string{"does not exist"}},
+						{Name: "redis", Monitored: true, Status: "running", Pid: 201},
+					},
+				}
+
+				clock.Increment(10 * time.Second)
+
+				Consistently(alertCount, 100*time.Millisecond).Should(Equal(0))
+			})
+		})
 	})
 
 	Describe("AddJob", func() {
@@ -0,0 +1,77 @@
+package jobsupervisor
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Jobs list processes whose stdout and stderr are captured by the supervisor in a file
+// rendered next to their monit file; systemd and runit job supervisors honor it
+const captureOutputFileName = "capture_output.json"
+
+// loadCapturedProcesses returns names of processes whose output is captured
+// and creates job's log dir for them; jobs without capture output file get none
+func loadCapturedProcesses(
+	fs boshsys.FileSystem,
+	dirProvider boshdir.Provider,
+	jobName string,
+	configPath string,
+	processes []monitProcess,
+) (map[string]bool, error) {
+	capturePath := path.Join(path.Dir(configPath), captureOutputFileName)
+
+	captured := map[string]bool{}
+
+	if !fs.FileExists(capturePath) {
+		return captured, nil
+	}
+
+	contents, err := fs.ReadFile(capturePath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", capturePath)
+	}
+
+	var processNames []string
+
+	err = json.Unmarshal(contents, &processNames)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", capturePath)
+	}
+
+	known := map[string]bool{}
+	for _, process := range processes {
+		known[process.Name] = true
+	}
+
+	for _, processName := range processNames {
+		if !known[processName] {
+			return nil, bosherr.Errorf("Process '%s' listed in %s is not in job config", processName, capturePath)
+		}
+
+		captured[processName] = true
+	}
+
+	if len(captured) > 0 {
+		err = fs.MkdirAll(path.Join(dirProvider.LogsDir(), jobName), os.FileMode(0750))
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Creating log dir of job %s", jobName)
+		}
+	}
+
+	return captured, nil
+}
+
+// outputLogPaths returns paths of files capturing stdout and stderr of the process;
+// files are appended to so that logrotate configured by the agent can copytruncate them
+func outputLogPaths(dirProvider boshdir.Provider, jobName, processName string) (string, string) {
+	logDir := path.Join(dirProvider.LogsDir(), jobName)
+
+	return path.Join(logDir, fmt.Sprintf("%s.stdout.log", processName)),
+		path.Join(logDir, fmt.Sprintf("%s.stderr.log", processName))
+}
@@ -0,0 +1,45 @@
+package jobsupervisor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+func countOpenFDs(fs boshsys.FileSystem, pid int) (int, error) {
+	fds, err := fs.Glob(fmt.Sprintf("/proc/%d/fd/*", pid))
+	if err != nil {
+		return 0, err
+	}
+
+	return len(fds), nil
+}
+
+func readMemTotalKb(fs boshsys.FileSystem) (uint64, error) {
+	return readProcKbField(fs, "/proc/meminfo", "MemTotal:")
+}
+
+// readResidentMemoryKb returns resident set size of the process
+func readResidentMemoryKb(fs boshsys.FileSystem, pid int) (uint64, error) {
+	return readProcKbField(fs, fmt.Sprintf("/proc/%d/status", pid), "VmRSS:")
+}
+
+// readProcKbField reads lines such as "MemTotal:        8167848 kB"
+func readProcKbField(fs boshsys.FileSystem, path, name string) (uint64, error) {
+	contents, err := fs.ReadFileString(path)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Reading %s", path)
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == name {
+			return strconv.ParseUint(fields[1], 10, 64)
+		}
+	}
+
+	return 0, bosherr.Errorf("Finding %s in %s", name, path)
+}
@@ -0,0 +1,69 @@
+package jobsupervisor
+
+import (
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Exits of processes are recorded by hooks of supervisors under bosh dir
+// since supervisors forget how processes exited once they are restarted
+const processExitsDirName = "process_exits"
+
+// processExitStore keeps how processes last exited, recorded the same way
+// as systemd passes it to ExecStopPost, e.g. "exited 1", "killed TERM" or "dumped SEGV"
+type processExitStore struct {
+	fs  boshsys.FileSystem
+	dir string
+}
+
+func newProcessExitStore(fs boshsys.FileSystem, boshDir string) processExitStore {
+	return processExitStore{fs: fs, dir: path.Join(boshDir, processExitsDirName)}
+}
+
+// Prepare creates the dir hooks write to
+func (s processExitStore) Prepare() error {
+	return s.fs.MkdirAll(s.dir, os.FileMode(0750))
+}
+
+// Path returns file that hooks of the process record its exit in
+func (s processExitStore) Path(processName string) string {
+	return path.Join(s.dir, processName)
+}
+
+// LastExit returns nil when process did not exit yet or its exit cannot be parsed
+func (s processExitStore) LastExit(processName string) *ExitVitals {
+	contents, err := s.fs.ReadFileString(s.Path(processName))
+	if err != nil {
+		return nil
+	}
+
+	fields := strings.Fields(contents)
+	if len(fields) != 2 {
+		return nil
+	}
+
+	switch fields[0] {
+	case "exited":
+		status, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil
+		}
+
+		return &ExitVitals{Status: status}
+	case "killed", "dumped":
+		return &ExitVitals{
+			Signal:     strings.TrimPrefix(fields[1], "SIG"),
+			CoreDumped: fields[0] == "dumped",
+		}
+	}
+
+	return nil
+}
+
+func (s processExitStore) RemoveAll() error {
+	return s.fs.RemoveAll(s.dir)
+}
@@ -0,0 +1,106 @@
+package jobsupervisor
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Jobs declare restart, stop and memory policies and liveness probes of their processes
+// in json files keyed by process name and rendered next to their monit file;
+// only systemd and runit job supervisors honor them, monit ignores them all
+type processPolicy[T any] interface {
+	withDefaults() T
+	Validate() error
+}
+
+// loadProcessPolicies returns policies declared in given file keyed by process name;
+// jobs without the file get no policies
+func loadProcessPolicies[T processPolicy[T]](fs boshsys.FileSystem, configPath, fileName, kind string) (map[string]T, error) {
+	policiesPath := path.Join(path.Dir(configPath), fileName)
+
+	if !fs.FileExists(policiesPath) {
+		return map[string]T{}, nil
+	}
+
+	contents, err := fs.ReadFile(policiesPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", policiesPath)
+	}
+
+	var policies map[string]T
+
+	err = json.Unmarshal(contents, &policies)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", policiesPath)
+	}
+
+	for name, policy := range policies {
+		policy = policy.withDefaults()
+
+		err = policy.Validate()
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Validating %s of process '%s'", kind, name)
+		}
+
+		policies[name] = policy
+	}
+
+	return policies, nil
+}
+
+// processPolicyStore keeps policies of added processes under bosh dir
+// so that supervisors can enforce them after jobs are added
+type processPolicyStore[T any] struct {
+	fs   boshsys.FileSystem
+	dir  string
+	kind string
+}
+
+func newProcessPolicyStore[T any](fs boshsys.FileSystem, boshDir, dirName, kind string) processPolicyStore[T] {
+	return processPolicyStore[T]{fs: fs, dir: path.Join(boshDir, dirName), kind: kind}
+}
+
+func (s processPolicyStore[T]) Write(processName string, policy T) error {
+	contents, err := json.Marshal(policy)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Marshalling %s", s.kind)
+	}
+
+	return s.fs.WriteFile(path.Join(s.dir, processName+".json"), contents)
+}
+
+// Policies returns stored policies keyed by process name
+func (s processPolicyStore[T]) Policies() (map[string]T, error) {
+	policies := map[string]T{}
+
+	policyPaths, err := s.fs.Glob(path.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Listing %s files", s.kind)
+	}
+
+	for _, policyPath := range policyPaths {
+		contents, err := s.fs.ReadFile(policyPath)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Reading %s", policyPath)
+		}
+
+		var policy T
+
+		err = json.Unmarshal(contents, &policy)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", policyPath)
+		}
+
+		policies[strings.TrimSuffix(path.Base(policyPath), ".json")] = policy
+	}
+
+	return policies, nil
+}
+
+func (s processPolicyStore[T]) RemoveAll() error {
+	return s.fs.RemoveAll(s.dir)
+}
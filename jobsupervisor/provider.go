@@ -3,6 +3,8 @@ package jobsupervisor
 import (
 	"time"
 
+	"github.com/pivotal-golang/clock"
+
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
@@ -34,10 +36,39 @@ func NewProvider(
 			MaxCheckTries:          6,
 			DelayBetweenCheckTries: 5 * time.Second,
 		},
+		clock.NewClock(),
+		10*time.Second,
+	)
+
+	systemdJobSupervisor := NewSystemdJobSupervisor(
+		platform.GetFs(),
+		platform.GetRunner(),
+		logger,
+		dirProvider,
+		platform.GetCgroupManager(),
+		clock.NewClock(),
+		10*time.Second,
+	)
+
+	runitJobSupervisor := NewRunitJobSupervisor(
+		platform.GetFs(),
+		platform.GetRunner(),
+		logger,
+		dirProvider,
+		platform.GetCgroupManager(),
+		clock.NewClock(),
+		"/etc/service",
+		RunitReloadOptions{
+			MaxCheckTries:          10,
+			DelayBetweenCheckTries: 1 * time.Second,
+		},
+		10*time.Second,
 	)
 
 	p.supervisors = map[string]JobSupervisor{
 		"monit":      monitJobSupervisor,
+		"systemd":    systemdJobSupervisor,
+		"runit":      runitJobSupervisor,
 		"dummy":      NewDummyJobSupervisor(),
 		"dummy-nats": NewDummyNatsJobSupervisor(handler),
 	}
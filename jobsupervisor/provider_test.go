@@ -5,6 +5,7 @@ import (
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock"
 
 	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakemonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit/fakes"
@@ -59,6 +60,45 @@ func init() {
 					MaxCheckTries:          6,
 					DelayBetweenCheckTries: 5 * time.Second,
 				},
+				clock.NewClock(),
+				10*time.Second,
+			)
+			Expect(actualSupervisor).To(Equal(expectedSupervisor))
+		})
+
+		It("provides a systemd job supervisor", func() {
+			actualSupervisor, err := provider.Get("systemd")
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedSupervisor := NewSystemdJobSupervisor(
+				platform.Fs,
+				platform.Runner,
+				logger,
+				dirProvider,
+				platform.GetCgroupManager(),
+				clock.NewClock(),
+				10*time.Second,
+			)
+			Expect(actualSupervisor).To(Equal(expectedSupervisor))
+		})
+
+		It("provides a runit job supervisor", func() {
+			actualSupervisor, err := provider.Get("runit")
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedSupervisor := NewRunitJobSupervisor(
+				platform.Fs,
+				platform.Runner,
+				logger,
+				dirProvider,
+				platform.GetCgroupManager(),
+				clock.NewClock(),
+				"/etc/service",
+				RunitReloadOptions{
+					MaxCheckTries:          10,
+					DelayBetweenCheckTries: 1 * time.Second,
+				},
+				10*time.Second,
 			)
 			Expect(actualSupervisor).To(Equal(expectedSupervisor))
 		})
@@ -0,0 +1,94 @@
+package jobsupervisor
+
+import (
+	"math"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// See loadProcessPolicies; monit ignores restart policies and keeps restarting processes every cycle
+const restartPoliciesFileName = "restart_policies.json"
+
+const (
+	RestartGiveUpActionAlert  = "alert"
+	RestartGiveUpActionReboot = "reboot"
+)
+
+// RestartPolicy limits how often a crashed process is restarted
+type RestartPolicy struct {
+	MaxRestarts int `json:"max_restarts"`
+	WindowSecs  int `json:"window_secs"`
+
+	Backoff RestartBackoff `json:"backoff"`
+
+	// Possible values: alert (defaults to alert, i.e. process stays down and job is failing), reboot
+	GiveUpAction string `json:"give_up_action"`
+}
+
+// RestartBackoff grows delay before each restart within the window by multiplier up to max delay
+type RestartBackoff struct {
+	InitialDelaySecs int     `json:"initial_delay_secs"`
+	MaxDelaySecs     int     `json:"max_delay_secs"`
+	Multiplier       float64 `json:"multiplier"`
+}
+
+// loadRestartPolicies returns policies keyed by process name;
+// jobs without restart policies file get no policies
+func loadRestartPolicies(fs boshsys.FileSystem, configPath string) (map[string]RestartPolicy, error) {
+	return loadProcessPolicies[RestartPolicy](fs, configPath, restartPoliciesFileName, "restart policy")
+}
+
+func (p RestartPolicy) withDefaults() RestartPolicy {
+	if p.GiveUpAction == "" {
+		p.GiveUpAction = RestartGiveUpActionAlert
+	}
+
+	if p.Backoff.Multiplier == 0 {
+		p.Backoff.Multiplier = 1
+	}
+
+	if p.Backoff.MaxDelaySecs == 0 {
+		p.Backoff.MaxDelaySecs = p.Backoff.InitialDelaySecs
+	}
+
+	return p
+}
+
+func (p RestartPolicy) Validate() error {
+	if p.MaxRestarts <= 0 {
+		return bosherr.Error("Max restarts must be positive")
+	}
+
+	if p.WindowSecs <= 0 {
+		return bosherr.Error("Window must be positive")
+	}
+
+	if p.Backoff.InitialDelaySecs < 0 || p.Backoff.MaxDelaySecs < p.Backoff.InitialDelaySecs {
+		return bosherr.Error("Backoff delays must not be negative and max delay must not be less than initial delay")
+	}
+
+	if p.Backoff.Multiplier < 1 {
+		return bosherr.Error("Backoff multiplier must be at least 1")
+	}
+
+	switch p.GiveUpAction {
+	case RestartGiveUpActionAlert, RestartGiveUpActionReboot:
+	default:
+		return bosherr.Errorf("Unknown give up action '%s'", p.GiveUpAction)
+	}
+
+	return nil
+}
+
+// backoffSteps returns number of restarts it takes for delay to grow from initial to max delay
+func (p RestartPolicy) backoffSteps() int {
+	initial := math.Max(float64(p.Backoff.InitialDelaySecs), 1)
+	max := float64(p.Backoff.MaxDelaySecs)
+
+	if p.Backoff.Multiplier <= 1 || max <= initial {
+		return 0
+	}
+
+	return int(math.Ceil(math.Log(max/initial) / math.Log(p.Backoff.Multiplier)))
+}
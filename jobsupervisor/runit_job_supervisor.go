@@ -0,0 +1,1008 @@
+package jobsupervisor
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	runitJobSupervisorLogTag = "runitJobSupervisor"
+
+	runitServicePrefix = "bosh-job-"
+
+	// Marks services started with 'sv once' which runsv does not restart
+	runitUnmonitoredFile = "bosh-unmonitored"
+
+	// Path of pid file of process daemonized by job's ctl script
+	runitPidFileFile = "bosh-pidfile"
+
+	// Times of restarts within restart policy window
+	runitRestartsFile = "bosh-restarts"
+
+	// Number of restarts since service was added
+	runitRestartCountFile = "bosh-restart-count"
+
+	// Longest time stopping service may take according to its stop policy
+	runitStopTimeoutFile = "bosh-stop-timeout"
+
+	runitStopWaitSecs = 60
+)
+
+// e.g. "run: /etc/service/bosh-job-redis: (pid 1234) 300s; run: log: (pid 1233) 300s"
+var runitStatusRegexp = regexp.MustCompile(`\A(\w+): ([^:]+):(?: \(pid (\d+)\))?(?: (\d+)s)?`)
+
+type RunitReloadOptions struct {
+	// Number of times runsv of each service will be checked
+	MaxCheckTries int
+
+	// Length of time between checks, runsvdir scans for new services every 5 seconds
+	DelayBetweenCheckTries time.Duration
+}
+
+type runitJobSupervisor struct {
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	logger      boshlog.Logger
+	dirProvider boshdir.Provider
+	clock       clock.Clock
+
+	cgroupManager boshcgroup.Manager
+
+	// Directory scanned by runsvdir, e.g. /etc/service
+	serviceDir string
+
+	reloadOptions           RunitReloadOptions
+	jobFailuresPollInterval time.Duration
+}
+
+type runitServiceStatus struct {
+	Service     string
+	State       string
+	Pid         int
+	UptimeSecs  int
+	Unmonitored bool
+	Unhealthy   bool
+}
+
+// NewRunitJobSupervisor renders processes of jobs' monit files as runit services
+// for stemcells that come without monit
+func NewRunitJobSupervisor(
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	logger boshlog.Logger,
+	dirProvider boshdir.Provider,
+	cgroupManager boshcgroup.Manager,
+	clock clock.Clock,
+	serviceDir string,
+	reloadOptions RunitReloadOptions,
+	jobFailuresPollInterval time.Duration,
+) JobSupervisor {
+	return runitJobSupervisor{
+		fs:          fs,
+		runner:      runner,
+		logger:      logger,
+		dirProvider: dirProvider,
+		clock:       clock,
+
+		cgroupManager: cgroupManager,
+
+		serviceDir: serviceDir,
+
+		reloadOptions:           reloadOptions,
+		jobFailuresPollInterval: jobFailuresPollInterval,
+	}
+}
+
+// Reload waits for runsvdir to pick up added services since
+// sv cannot control services before their runsv is running
+func (r runitJobSupervisor) Reload() error {
+	services, err := r.services()
+	if err != nil {
+		return err
+	}
+
+	for checkI := 0; checkI < r.reloadOptions.MaxCheckTries; checkI++ {
+		var pending []string
+
+		for _, service := range services {
+			if !r.fs.FileExists(path.Join(service, "supervise", "ok")) {
+				pending = append(pending, service)
+			}
+		}
+
+		if len(pending) == 0 {
+			return nil
+		}
+
+		r.logger.Debug(runitJobSupervisorLogTag, "Waiting for runsvdir to pick up services %v", pending)
+
+		r.clock.Sleep(r.reloadOptions.DelayBetweenCheckTries)
+	}
+
+	return bosherr.Error("Failed to reload runit services: runsv is not running for all services")
+}
+
+// Start removes down files so that jobs are started again after reboot
+// and re-monitors services started with 'sv once'; services of jobs
+// with dependencies are started once their dependencies are healthy
+func (r runitJobSupervisor) Start() error {
+	services, err := r.services()
+	if err != nil {
+		return err
+	}
+
+	if len(services) > 0 {
+		stages, err := r.jobDependencies().ProcessStages()
+		if err != nil {
+			return bosherr.WrapError(err, "Ordering jobs by dependencies")
+		}
+
+		if len(stages) > 0 {
+			err = startInStages(r.clock, r.logger, stages, r.startProcesses, r.unhealthyProcesses)
+		} else {
+			err = r.startAllServices(services)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.fs.RemoveAll(r.stoppedFilePath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing stopped File")
+	}
+
+	return nil
+}
+
+func (r runitJobSupervisor) startServices(services []string) error {
+	err := r.removeDownFiles(services)
+	if err != nil {
+		return err
+	}
+
+	return r.upServices(services)
+}
+
+// startAllServices starts services of at most job concurrency jobs at once
+func (r runitJobSupervisor) startAllServices(services []string) error {
+	err := r.removeDownFiles(services)
+	if err != nil {
+		return err
+	}
+
+	upProcesses := func(processes []string) error {
+		return r.upServices(r.processServices(processes))
+	}
+
+	return r.inJobGroups(services, upProcesses, r.upServices)
+}
+
+func (r runitJobSupervisor) removeDownFiles(services []string) error {
+	for _, service := range services {
+		err := r.fs.RemoveAll(path.Join(service, "down"))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing down file of %s", service)
+		}
+
+		err = r.fs.RemoveAll(path.Join(service, runitUnmonitoredFile))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing unmonitored file of %s", service)
+		}
+	}
+
+	return nil
+}
+
+func (r runitJobSupervisor) upServices(services []string) error {
+	r.logger.Debug(runitJobSupervisorLogTag, "Starting services %v", services)
+
+	_, _, _, err := r.runner.RunCommand("sv", append([]string{"up"}, services...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Starting services")
+	}
+
+	return nil
+}
+
+func (r runitJobSupervisor) startProcesses(processes []string) error {
+	return r.startServices(r.processServices(processes))
+}
+
+func (r runitJobSupervisor) processServices(processes []string) []string {
+	var services []string
+	for _, process := range processes {
+		services = append(services, path.Join(r.serviceDir, runitServicePrefix+process))
+	}
+
+	return services
+}
+
+// inJobGroups runs groupAction with processes of each job when job concurrency is limited
+// and jobs do not depend on each other, otherwise allAction is run with all services
+func (r runitJobSupervisor) inJobGroups(services []string, groupAction, allAction func([]string) error) error {
+	groups, limit, err := r.jobDependencies().ProcessGroups()
+	if err != nil {
+		return bosherr.WrapError(err, "Grouping processes by job")
+	}
+
+	if len(groups) == 0 {
+		return allAction(services)
+	}
+
+	return inParallel(groups, limit, groupAction)
+}
+
+// unhealthyProcesses returns processes whose services do not run yet or fail their liveness probe
+func (r runitJobSupervisor) unhealthyProcesses(processes []string) ([]string, error) {
+	statuses, err := r.serviceStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	running := map[string]bool{}
+	for _, serviceStatus := range statuses {
+		running[serviceStatus.processName()] = serviceStatus.State == "run"
+	}
+
+	prober := newLivenessProber(r.livenessProbes(), r.runner, r.clock, r.logger)
+	prober.Reload()
+
+	var unhealthy []string
+
+	for _, process := range processes {
+		if !running[process] || !prober.Passes(process) {
+			unhealthy = append(unhealthy, process)
+		}
+	}
+
+	return unhealthy, nil
+}
+
+func (r runitJobSupervisor) Stop() error {
+	services, err := r.services()
+	if err != nil {
+		return err
+	}
+
+	if len(services) > 0 {
+		for _, service := range services {
+			err = r.fs.WriteFileString(path.Join(service, "down"), "")
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing down file of %s", service)
+			}
+		}
+
+		// Each sv call waits as long as the slowest stop policy of all services needs
+		waitSecs := r.stopWaitSecs(services)
+
+		downServices := func(services []string) error {
+			r.logger.Debug(runitJobSupervisorLogTag, "Stopping services %v", services)
+
+			args := []string{"-w", strconv.Itoa(waitSecs), "down"}
+
+			_, _, _, err := r.runner.RunCommand("sv", append(args, services...)...)
+			if err != nil {
+				return bosherr.WrapError(err, "Stopping services")
+			}
+
+			return nil
+		}
+
+		downProcesses := func(processes []string) error {
+			return downServices(r.processServices(processes))
+		}
+
+		err = r.inJobGroups(services, downProcesses, downServices)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = r.fs.WriteFileString(r.stoppedFilePath(), "")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	return nil
+}
+
+// Unmonitor keeps processes running but stops runsv from restarting them
+func (r runitJobSupervisor) Unmonitor() error {
+	services, err := r.services()
+	if err != nil {
+		return err
+	}
+
+	if len(services) == 0 {
+		return nil
+	}
+
+	for _, service := range services {
+		r.logger.Debug(runitJobSupervisorLogTag, "Unmonitoring service %s", service)
+
+		err = r.fs.WriteFileString(path.Join(service, runitUnmonitoredFile), "")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing unmonitored file of %s", service)
+		}
+	}
+
+	_, _, _, err = r.runner.RunCommand("sv", append([]string{"once"}, services...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmonitoring services")
+	}
+
+	return nil
+}
+
+func (r runitJobSupervisor) Status() string {
+	statuses, err := r.serviceStatuses()
+	if err != nil {
+		r.logger.Debug(runitJobSupervisorLogTag, "Getting services status: %s", err.Error())
+		return "unknown"
+	}
+
+	if r.fs.FileExists(r.stoppedFilePath()) {
+		return "stopped"
+	}
+
+	for _, serviceStatus := range statuses {
+		if serviceStatus.state() != "running" {
+			return "failing"
+		}
+	}
+
+	return "running"
+}
+
+// Processes reports memory and open fds of process daemonized by job's ctl script
+// when its pid file is known; CPU usage is left out since runit does not account it.
+// Restarts are counted by finish scripts since services were added.
+func (r runitJobSupervisor) Processes() ([]Process, error) {
+	processes := []Process{}
+
+	statuses, err := r.serviceStatuses()
+	if err != nil {
+		return processes, err
+	}
+
+	memTotalKb, err := readMemTotalKb(r.fs)
+	if err != nil {
+		r.logger.Debug(runitJobSupervisorLogTag, "Getting total memory: %s", err.Error())
+	}
+
+	for _, serviceStatus := range statuses {
+		process := Process{
+			Name:   serviceStatus.processName(),
+			State:  serviceStatus.state(),
+			Uptime: UptimeVitals{Secs: serviceStatus.UptimeSecs},
+		}
+
+		if serviceStatus.State == "run" {
+			pid := r.daemonPid(serviceStatus)
+
+			memoryKb, err := readResidentMemoryKb(r.fs, pid)
+			if err != nil {
+				r.logger.Debug(runitJobSupervisorLogTag, "Getting memory of pid %d: %s", pid, err.Error())
+			}
+
+			process.Memory.Kb = int(memoryKb)
+			if memTotalKb > 0 {
+				process.Memory.Percent = float64(memoryKb) / float64(memTotalKb) * 100
+			}
+
+			process.FD.Open, err = countOpenFDs(r.fs, pid)
+			if err != nil {
+				r.logger.Debug(runitJobSupervisorLogTag, "Counting open fds of pid %d: %s", pid, err.Error())
+			}
+		}
+
+		restartCount, _ := r.fs.ReadFileString(path.Join(serviceStatus.Service, runitRestartCountFile))
+		restarts, _ := strconv.Atoi(strings.TrimSpace(restartCount))
+		process.Restarts = &restarts
+		process.LastExit = r.processExits().LastExit(process.Name)
+		process.Health = r.livenessProbes().Health(process.Name)
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+// AddJob renders a service for each 'check process' of job's monit file;
+// services are rendered down so that runsv does not start them before Start
+func (r runitJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+	configContent, err := r.fs.ReadFileString(configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading job config from file")
+	}
+
+	processes, err := parseMonitProcesses(configContent)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing job config %s", configPath)
+	}
+
+	policies, err := loadRestartPolicies(r.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading restart policies")
+	}
+
+	probes, err := loadLivenessProbes(r.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading liveness probes")
+	}
+
+	memoryPolicies, err := loadMemoryPolicies(r.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading memory policies")
+	}
+
+	stopPolicies, err := loadStopPolicies(r.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading stop policies")
+	}
+
+	captured, err := loadCapturedProcesses(r.fs, r.dirProvider, jobName, configPath, processes)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading captured processes")
+	}
+
+	cgroupProcsPaths, err := jobCgroupProcsPaths(r.jobDependencies(), r.cgroupManager, jobName)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading job cgroups")
+	}
+
+	err = r.processExits().Prepare()
+	if err != nil {
+		return bosherr.WrapError(err, "Creating process exits dir")
+	}
+
+	for _, process := range processes {
+		if strings.ContainsAny(process.Name, "/ ") || strings.HasPrefix(process.Name, ".") {
+			return bosherr.Errorf("Process name '%s' cannot be used as runit service name", process.Name)
+		}
+
+		var policy *RestartPolicy
+		if processPolicy, found := policies[process.Name]; found {
+			policy = &processPolicy
+		}
+
+		var stopPolicy *StopPolicy
+		if processStopPolicy, found := stopPolicies[process.Name]; found {
+			stopPolicy = &processStopPolicy
+		}
+
+		err = r.writeService(jobName, process, policy, stopPolicy, captured[process.Name], cgroupProcsPaths)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing service of process %s", process.Name)
+		}
+
+		if probe, found := probes[process.Name]; found {
+			err = r.livenessProbes().Write(process.Name, probe)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing liveness probe of process %s", process.Name)
+			}
+		}
+
+		if memoryPolicy, found := memoryPolicies[process.Name]; found {
+			err = r.memoryPolicies().Write(process.Name, memoryPolicy)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing memory policy of process %s", process.Name)
+			}
+		}
+	}
+
+	err = r.jobDependencies().AddJob(jobName, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording job for dependencies")
+	}
+
+	return nil
+}
+
+func (r runitJobSupervisor) RemoveAllJobs() error {
+	services, err := r.services()
+	if err != nil {
+		return err
+	}
+
+	for _, service := range services {
+		err = r.fs.RemoveAll(service)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing service %s", service)
+		}
+	}
+
+	err = r.livenessProbes().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing liveness probes")
+	}
+
+	err = r.memoryPolicies().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing memory policies")
+	}
+
+	err = r.processExits().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing process exits")
+	}
+
+	err = r.jobDependencies().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing job dependencies")
+	}
+
+	return nil
+}
+
+// SetJobConcurrency makes Start and Stop handle services of at most limit jobs at once
+func (r runitJobSupervisor) SetJobConcurrency(limit int) error {
+	err := r.jobDependencies().SetConcurrency(limit)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job concurrency")
+	}
+
+	return nil
+}
+
+// SetJobCgroups makes services of added jobs with cgroups place their processes into them
+func (r runitJobSupervisor) SetJobCgroups(jobNames []string) error {
+	err := r.jobDependencies().SetCgroupJobs(jobNames)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job cgroups")
+	}
+
+	return nil
+}
+
+// SetJobDependencies makes Start start services of jobs in order of their dependencies
+func (r runitJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	err := r.jobDependencies().SetDependsOn(dependencies)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job dependencies")
+	}
+
+	return nil
+}
+
+// MonitorJobFailures polls services and alerts when runsv restarted a process
+// or restart policy gave up on it; unmonitored services and services stopped
+// by the agent are not reported. Liveness probes and memory policies of running services
+// are checked on each poll.
+func (r runitJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	runPids := map[string]int{}
+	states := map[string]string{}
+
+	prober := newLivenessProber(r.livenessProbes(), r.runner, r.clock, r.logger)
+	watchdog := newMemoryWatchdog(r.memoryPolicies(), r.fs, r.clock, r.logger)
+
+	for {
+		statuses, err := r.serviceStatuses()
+		if err != nil {
+			r.logger.Debug(runitJobSupervisorLogTag, "Getting services status: %s", err.Error())
+		}
+
+		stopped := r.fs.FileExists(r.stoppedFilePath())
+
+		prober.Reload()
+		watchdog.Reload()
+
+		for _, serviceStatus := range statuses {
+			previousRunPid := runPids[serviceStatus.Service]
+			previousState, found := states[serviceStatus.Service]
+
+			// Finish script runs with its own pid between restarts
+			if serviceStatus.State == "run" {
+				runPids[serviceStatus.Service] = serviceStatus.Pid
+			}
+			states[serviceStatus.Service] = serviceStatus.State
+
+			running := serviceStatus.State == "run" && !stopped && !serviceStatus.Unmonitored
+
+			if probe, reason, failed := prober.Check(serviceStatus.processName(), running); failed {
+				r.handleLivenessFailure(handler, serviceStatus, probe, reason)
+
+				// Restart is already alerted
+				if probe.FailureAction == LivenessFailureActionRestart {
+					delete(runPids, serviceStatus.Service)
+				}
+			}
+
+			if reason, exceeded := watchdog.Check(serviceStatus.processName(), serviceStatus.Pid, running); exceeded {
+				r.handleMemoryFailure(handler, serviceStatus, reason)
+
+				// Restart is already alerted
+				delete(runPids, serviceStatus.Service)
+			}
+
+			if !found || stopped || serviceStatus.Unmonitored {
+				continue
+			}
+
+			switch {
+			case serviceStatus.State == "run" && previousRunPid != 0 && serviceStatus.Pid != previousRunPid:
+				r.handleJobFailure(handler, serviceStatus, "does not exist", "restart", "")
+			case serviceStatus.State == "down" && previousState != "down":
+				r.handleJobFailure(handler, serviceStatus, "execution failed", "alert", "")
+			}
+		}
+
+		r.clock.Sleep(r.jobFailuresPollInterval)
+	}
+}
+
+func (r runitJobSupervisor) handleLivenessFailure(handler JobFailureHandler, serviceStatus runitServiceStatus, probe LivenessProbe, reason string) {
+	description := fmt.Sprintf("%s of runit service %s failed %d time(s): %s",
+		probe.description(), serviceStatus.Service, probe.FailureThreshold, reason)
+
+	if probe.FailureAction == LivenessFailureActionRestart {
+		r.logger.Info(runitJobSupervisorLogTag, "Restarting service %s after liveness probe failures", serviceStatus.Service)
+
+		_, _, _, err := r.runner.RunCommand("sv", "restart", serviceStatus.Service)
+		if err != nil {
+			r.logger.Error(runitJobSupervisorLogTag, "Restarting service %s: %s", serviceStatus.Service, err.Error())
+		}
+	}
+
+	r.handleJobFailure(handler, serviceStatus, "connection failed", probe.FailureAction, description)
+}
+
+func (r runitJobSupervisor) handleMemoryFailure(handler JobFailureHandler, serviceStatus runitServiceStatus, reason string) {
+	description := fmt.Sprintf("memory policy of runit service %s exceeded: %s", serviceStatus.Service, reason)
+
+	r.logger.Info(runitJobSupervisorLogTag, "Restarting service %s after exceeding memory policy: %s", serviceStatus.Service, reason)
+
+	_, _, _, err := r.runner.RunCommand("sv", "restart", serviceStatus.Service)
+	if err != nil {
+		r.logger.Error(runitJobSupervisorLogTag, "Restarting service %s: %s", serviceStatus.Service, err.Error())
+	}
+
+	r.handleJobFailure(handler, serviceStatus, "resource limit matched", "restart", description)
+}
+
+func (r runitJobSupervisor) handleJobFailure(handler JobFailureHandler, serviceStatus runitServiceStatus, event, action, description string) {
+	now := r.clock.Now()
+
+	if description == "" {
+		description = fmt.Sprintf("runit service %s is %s", serviceStatus.Service, serviceStatus.State)
+	}
+
+	alert := boshalert.MonitAlert{
+		ID:          fmt.Sprintf("%d.%s@localhost", now.UnixNano(), serviceStatus.processName()),
+		Service:     serviceStatus.processName(),
+		Event:       event,
+		Action:      action,
+		Date:        now.Format(time.RFC1123Z),
+		Description: description,
+	}
+
+	err := handler(alert)
+	if err != nil {
+		r.logger.Error(runitJobSupervisorLogTag, "Handling failure of %s: %s", serviceStatus.Service, err.Error())
+	}
+}
+
+func (r runitJobSupervisor) writeService(
+	jobName string,
+	process monitProcess,
+	policy *RestartPolicy,
+	stopPolicy *StopPolicy,
+	captureOutput bool,
+	cgroupProcsPaths []string,
+) error {
+	service := path.Join(r.serviceDir, runitServicePrefix+process.Name)
+
+	err := r.fs.WriteFileString(path.Join(service, "down"), "")
+	if err != nil {
+		return err
+	}
+
+	scripts := map[string]string{
+		path.Join(service, "run"): r.renderRunScript(jobName, process, captureOutput, cgroupProcsPaths),
+	}
+
+	if stopPolicy != nil {
+		// runsv runs control scripts in service dir
+		pidCommand := "cat supervise/pid 2>/dev/null"
+		if process.PidFile != "" {
+			pidCommand = fmt.Sprintf("cat %s 2>/dev/null", process.PidFile)
+		}
+
+		var stopCommand string
+		if process.StopProgram.Command != "" {
+			stopCommand = r.renderCommand(process.StopProgram)
+		}
+
+		scripts[path.Join(service, "control", "t")] = renderStopScript(process.Name, pidCommand, stopCommand, *stopPolicy)
+	} else if process.StopProgram.Command != "" {
+		// runsv runs control/t instead of sending TERM when it exits successfully
+		scripts[path.Join(service, "control", "t")] = fmt.Sprintf(
+			"#!/bin/sh\nexec %s\n", r.renderCommand(process.StopProgram),
+		)
+	} else if process.PidFile != "" {
+		scripts[path.Join(service, "control", "t")] = fmt.Sprintf(
+			"#!/bin/sh\nexec kill \"$(cat %s)\"\n", process.PidFile,
+		)
+	}
+
+	// runsv waits for finish script before restarting run script
+	scripts[path.Join(service, "finish")] = r.renderFinishScript(process, policy)
+
+	for scriptPath, script := range scripts {
+		err = r.fs.WriteFileString(scriptPath, script)
+		if err != nil {
+			return err
+		}
+
+		err = r.fs.Chmod(scriptPath, 0755)
+		if err != nil {
+			return err
+		}
+	}
+
+	if process.PidFile != "" {
+		err = r.fs.WriteFileString(path.Join(service, runitPidFileFile), process.PidFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if stopPolicy != nil {
+		stopSecs := stopPolicy.stopSecs(process.StopProgram.Command != "")
+
+		err = r.fs.WriteFileString(path.Join(service, runitStopTimeoutFile), strconv.Itoa(stopSecs))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// renderRunScript keeps run script in foreground, as runsv expects,
+// while daemonized process in job's pid file is alive
+func (r runitJobSupervisor) renderRunScript(jobName string, process monitProcess, captureOutput bool, cgroupProcsPaths []string) string {
+	var script bytes.Buffer
+
+	fmt.Fprintf(&script, "#!/bin/sh\n# %s process of BOSH job %s\n", process.Name, jobName)
+
+	if captureOutput {
+		stdoutPath, stderrPath := outputLogPaths(r.dirProvider, jobName, process.Name)
+		fmt.Fprintf(&script, "exec >>%s 2>>%s\n\n", stdoutPath, stderrPath)
+	} else {
+		script.WriteString("exec 2>&1\n\n")
+	}
+
+	// Run script joins job's cgroup while still running as root so that
+	// the process and everything it forks start with job's limits
+	if len(cgroupProcsPaths) > 0 {
+		for _, procsPath := range cgroupProcsPaths {
+			fmt.Fprintf(&script, "echo $$ > %s\n", procsPath)
+		}
+		script.WriteString("\n")
+	}
+
+	if process.PidFile == "" {
+		fmt.Fprintf(&script, "exec %s\n", r.renderCommand(process.StartProgram))
+		return script.String()
+	}
+
+	fmt.Fprintf(&script, "%s || exit 1\n\n", r.renderCommand(process.StartProgram))
+
+	fmt.Fprintf(&script, "for i in $(seq %d); do\n  [ -s %s ] && break\n  sleep 1\ndone\n\n",
+		process.StartProgram.Timeout, process.PidFile)
+
+	fmt.Fprintf(&script, "while kill -0 \"$(cat %s 2>/dev/null)\" 2>/dev/null; do\n  sleep 1\ndone\n",
+		process.PidFile)
+
+	return script.String()
+}
+
+// renderFinishScript records how process exited and counts restarts; with restart policy
+// it delays restarts according to the policy and gives up once process was restarted
+// too many times within the window
+func (r runitJobSupervisor) renderFinishScript(process monitProcess, policy *RestartPolicy) string {
+	var script bytes.Buffer
+
+	script.WriteString("#!/bin/sh\n")
+	if policy != nil {
+		fmt.Fprintf(&script, "# Restart policy of %s: at most %d restarts within %d seconds\n",
+			process.Name, policy.MaxRestarts, policy.WindowSecs)
+	}
+	script.WriteString("cd \"$(dirname \"$0\")\"\n\n")
+
+	// runsv passes exit status, or -1 and the signal when process was terminated by a signal
+	exitPath := r.processExits().Path(process.Name)
+	fmt.Fprintf(&script, "if [ \"$1\" = -1 ]; then\n  echo \"killed $(kill -l \"$2\")\" > %s\nelse\n  echo \"exited $1\" > %s\nfi\n\n",
+		exitPath, exitPath)
+
+	script.WriteString("# Services brought down by sv down or sv once are not restarted\n")
+	script.WriteString("grep -q \"want down\" supervise/stat 2>/dev/null && exit 0\n\n")
+
+	if policy == nil {
+		fmt.Fprintf(&script, "echo $(($(cat %s 2>/dev/null) + 1)) > %s\n", runitRestartCountFile, runitRestartCountFile)
+		return script.String()
+	}
+
+	script.WriteString("now=$(date +%s)\n")
+	fmt.Fprintf(&script, "awk -v since=$((now - %d)) '$1 > since' %s > %s.new 2>/dev/null\n",
+		policy.WindowSecs, runitRestartsFile, runitRestartsFile)
+	fmt.Fprintf(&script, "mv %s.new %s\n", runitRestartsFile, runitRestartsFile)
+	fmt.Fprintf(&script, "restarts=$(wc -l < %s)\n\n", runitRestartsFile)
+
+	giveUp := "sv down ."
+	if policy.GiveUpAction == RestartGiveUpActionReboot {
+		giveUp = "reboot"
+	}
+
+	fmt.Fprintf(&script, "if [ \"$restarts\" -ge %d ]; then\n  rm -f %s\n  %s\n  exit 0\nfi\n\n",
+		policy.MaxRestarts, runitRestartsFile, giveUp)
+
+	fmt.Fprintf(&script, "echo $(($(cat %s 2>/dev/null) + 1)) > %s\n", runitRestartCountFile, runitRestartCountFile)
+	fmt.Fprintf(&script, "echo \"$now\" >> %s\n", runitRestartsFile)
+	fmt.Fprintf(&script, "sleep $(awk -v n=\"$restarts\" 'BEGIN { d = %d * %g ^ n; if (d > %d) d = %d; printf \"%%d\", d }')\n",
+		policy.Backoff.InitialDelaySecs, policy.Backoff.Multiplier, policy.Backoff.MaxDelaySecs, policy.Backoff.MaxDelaySecs)
+
+	return script.String()
+}
+
+// stopWaitSecs returns time sv waits for services to go down;
+// services with stop policies may need longer than the default
+func (r runitJobSupervisor) stopWaitSecs(services []string) int {
+	waitSecs := runitStopWaitSecs
+
+	for _, service := range services {
+		stopTimeout, err := r.fs.ReadFileString(path.Join(service, runitStopTimeoutFile))
+		if err != nil {
+			continue
+		}
+
+		stopSecs, err := strconv.Atoi(strings.TrimSpace(stopTimeout))
+		if err == nil && stopSecs > waitSecs {
+			waitSecs = stopSecs
+		}
+	}
+
+	return waitSecs
+}
+
+func (r runitJobSupervisor) renderCommand(program monitProgram) string {
+	if program.User == "" {
+		return program.Command
+	}
+
+	user := program.User
+	if program.Group != "" {
+		user += ":" + program.Group
+	}
+
+	return fmt.Sprintf("chpst -u %s %s", user, program.Command)
+}
+
+// services returns paths of rendered services, e.g. /etc/service/bosh-job-redis
+func (r runitJobSupervisor) services() ([]string, error) {
+	services, err := r.fs.Glob(path.Join(r.serviceDir, runitServicePrefix+"*"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing services")
+	}
+
+	return services, nil
+}
+
+func (r runitJobSupervisor) serviceStatuses() ([]runitServiceStatus, error) {
+	services, err := r.services()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(services) == 0 {
+		return nil, nil
+	}
+
+	// sv exits with number of services it could not report
+	stdout, _, _, err := r.runner.RunCommand("sv", append([]string{"status"}, services...)...)
+	if err != nil && strings.TrimSpace(stdout) == "" {
+		return nil, bosherr.WrapError(err, "Getting services status")
+	}
+
+	var statuses []runitServiceStatus
+
+	for _, line := range strings.Split(strings.TrimSpace(stdout), "\n") {
+		matches := runitStatusRegexp.FindStringSubmatch(line)
+		if matches == nil {
+			continue
+		}
+
+		serviceStatus := runitServiceStatus{
+			Service: matches[2],
+			State:   matches[1],
+		}
+
+		serviceStatus.Pid, _ = strconv.Atoi(matches[3])
+		if serviceStatus.State == "run" {
+			serviceStatus.UptimeSecs, _ = strconv.Atoi(matches[4])
+		}
+
+		serviceStatus.Unmonitored = r.fs.FileExists(path.Join(serviceStatus.Service, runitUnmonitoredFile))
+		serviceStatus.Unhealthy = r.livenessProbes().Unhealthy(serviceStatus.processName())
+
+		statuses = append(statuses, serviceStatus)
+	}
+
+	return statuses, nil
+}
+
+// daemonPid falls back to pid of the run script when process has no pid file
+func (r runitJobSupervisor) daemonPid(serviceStatus runitServiceStatus) int {
+	pidFile, err := r.fs.ReadFileString(path.Join(serviceStatus.Service, runitPidFileFile))
+	if err != nil {
+		return serviceStatus.Pid
+	}
+
+	pidContent, err := r.fs.ReadFileString(strings.TrimSpace(pidFile))
+	if err != nil {
+		return serviceStatus.Pid
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(pidContent))
+	if err != nil {
+		return serviceStatus.Pid
+	}
+
+	return pid
+}
+
+func (r runitJobSupervisor) livenessProbes() livenessProbeStore {
+	return newLivenessProbeStore(r.fs, r.dirProvider.BoshDir())
+}
+
+func (r runitJobSupervisor) memoryPolicies() memoryPolicyStore {
+	return newMemoryPolicyStore(r.fs, r.dirProvider.BoshDir())
+}
+
+func (r runitJobSupervisor) processExits() processExitStore {
+	return newProcessExitStore(r.fs, r.dirProvider.BoshDir())
+}
+
+func (r runitJobSupervisor) jobDependencies() jobDependencyStore {
+	return newJobDependencyStore(r.fs, r.dirProvider.BoshDir())
+}
+
+func (r runitJobSupervisor) stoppedFilePath() string {
+	return path.Join(r.dirProvider.BoshDir(), "jobs_stopped")
+}
+
+// processName returns monit process name the service was rendered from
+func (s runitServiceStatus) processName() string {
+	return strings.TrimPrefix(path.Base(s.Service), runitServicePrefix)
+}
+
+// state maps service state onto process states reported by monit;
+// runit does not distinguish starting processes and processes failing
+// their liveness probe are reported failing
+func (s runitServiceStatus) state() string {
+	if s.Unmonitored {
+		return "unknown"
+	}
+
+	if s.State == "run" && !s.Unhealthy {
+		return "running"
+	}
+
+	return "failing"
+}
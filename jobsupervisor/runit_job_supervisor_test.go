@@ -0,0 +1,690 @@
+package jobsupervisor_test
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup/fakes"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("runitJobSupervisor", func() {
+	const statusCmd = "sv status /etc/service/bosh-job-redis /etc/service/bosh-job-redis-sentinel"
+
+	var (
+		fs          *fakesys.FakeFileSystem
+		runner      *fakesys.FakeCmdRunner
+		clock       *fakeclock.FakeClock
+		dirProvider boshdir.Provider
+		cgroups     *fakecgroup.FakeManager
+		supervisor  JobSupervisor
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		clock = fakeclock.NewFakeClock(time.Date(2026, time.October, 14, 10, 5, 0, 0, time.UTC))
+		dirProvider = boshdir.NewProvider("/var/vcap")
+		cgroups = fakecgroup.NewFakeManager()
+
+		supervisor = NewRunitJobSupervisor(
+			fs,
+			runner,
+			boshlog.NewLogger(boshlog.LevelNone),
+			dirProvider,
+			cgroups,
+			clock,
+			"/etc/service",
+			RunitReloadOptions{
+				MaxCheckTries:          3,
+				DelayBetweenCheckTries: 1 * time.Second,
+			},
+			10*time.Second,
+		)
+	})
+
+	setServices := func() {
+		fs.SetGlob("/etc/service/bosh-job-*", []string{
+			"/etc/service/bosh-job-redis",
+			"/etc/service/bosh-job-redis-sentinel",
+		})
+	}
+
+	setStatusResult := func(stdout string) {
+		runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{Stdout: stdout, Sticky: true})
+	}
+
+	Describe("AddJob", func() {
+		It("renders a down service for each process of the monit file", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `
+check process redis
+  with pidfile /var/vcap/sys/run/redis/redis.pid
+  start program "/var/vcap/jobs/redis/bin/redis_ctl start" with timeout 60 seconds
+  stop program "/var/vcap/jobs/redis/bin/redis_ctl stop"
+  group vcap
+
+check process redis-sentinel
+  start program "/var/vcap/jobs/redis/bin/sentinel" as uid vcap and gid vcap
+  group vcap
+`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/down")).To(BeTrue())
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/run")).To(Equal(`#!/bin/sh
+# redis process of BOSH job redis
+exec 2>&1
+
+/var/vcap/jobs/redis/bin/redis_ctl start || exit 1
+
+for i in $(seq 60); do
+  [ -s /var/vcap/sys/run/redis/redis.pid ] && break
+  sleep 1
+done
+
+while kill -0 "$(cat /var/vcap/sys/run/redis/redis.pid 2>/dev/null)" 2>/dev/null; do
+  sleep 1
+done
+`))
+			Expect(fs.GetFileTestStat("/etc/service/bosh-job-redis/run").FileMode).To(Equal(os.FileMode(0755)))
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/control/t")).To(Equal(
+				"#!/bin/sh\nexec /var/vcap/jobs/redis/bin/redis_ctl stop\n",
+			))
+			Expect(fs.GetFileTestStat("/etc/service/bosh-job-redis/control/t").FileMode).To(Equal(os.FileMode(0755)))
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/bosh-pidfile")).To(Equal("/var/vcap/sys/run/redis/redis.pid"))
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis-sentinel/down")).To(BeTrue())
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis-sentinel/run")).To(Equal(
+				"#!/bin/sh\n# redis-sentinel process of BOSH job redis\nexec 2>&1\n\nexec chpst -u vcap:vcap /var/vcap/jobs/redis/bin/sentinel\n",
+			))
+			Expect(fs.FileExists("/etc/service/bosh-job-redis-sentinel/control/t")).To(BeFalse())
+		})
+
+		It("makes run script join cgroup of job with cgroup before starting the process", func() {
+			cgroups.ProcsPathsPaths["redis"] = []string{
+				"/sys/fs/cgroup/memory/bosh/redis/cgroup.procs",
+				"/sys/fs/cgroup/pids/bosh/redis/cgroup.procs",
+			}
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis" as uid vcap`)
+
+			err := supervisor.SetJobCgroups([]string{"redis"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/run")).To(Equal(`#!/bin/sh
+# redis process of BOSH job redis
+exec 2>&1
+
+echo $$ > /sys/fs/cgroup/memory/bosh/redis/cgroup.procs
+echo $$ > /sys/fs/cgroup/pids/bosh/redis/cgroup.procs
+
+exec chpst -u vcap /var/vcap/jobs/redis/bin/redis
+`))
+		})
+
+		It("does not join cgroups of jobs without cgroup", func() {
+			cgroups.ProcsPathsPaths["redis"] = []string{"/sys/fs/cgroup/bosh/redis/cgroup.procs"}
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis"`)
+
+			err := supervisor.SetJobCgroups([]string{"sentinel"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/run")).ToNot(ContainSubstring("cgroup.procs"))
+		})
+
+		It("renders finish script for processes with restart policy", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/restart_policies.json", `{
+				"redis": {
+					"max_restarts": 3,
+					"window_secs": 60,
+					"backoff": {"initial_delay_secs": 1, "max_delay_secs": 30, "multiplier": 1.5}
+				}
+			}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/finish")).To(Equal(`#!/bin/sh
+# Restart policy of redis: at most 3 restarts within 60 seconds
+cd "$(dirname "$0")"
+
+if [ "$1" = -1 ]; then
+  echo "killed $(kill -l "$2")" > /var/vcap/bosh/process_exits/redis
+else
+  echo "exited $1" > /var/vcap/bosh/process_exits/redis
+fi
+
+# Services brought down by sv down or sv once are not restarted
+grep -q "want down" supervise/stat 2>/dev/null && exit 0
+
+now=$(date +%s)
+awk -v since=$((now - 60)) '$1 > since' bosh-restarts > bosh-restarts.new 2>/dev/null
+mv bosh-restarts.new bosh-restarts
+restarts=$(wc -l < bosh-restarts)
+
+if [ "$restarts" -ge 3 ]; then
+  rm -f bosh-restarts
+  sv down .
+  exit 0
+fi
+
+echo $(($(cat bosh-restart-count 2>/dev/null) + 1)) > bosh-restart-count
+echo "$now" >> bosh-restarts
+sleep $(awk -v n="$restarts" 'BEGIN { d = 1 * 1.5 ^ n; if (d > 30) d = 30; printf "%d", d }')
+`))
+			Expect(fs.GetFileTestStat("/etc/service/bosh-job-redis/finish").FileMode).To(Equal(os.FileMode(0755)))
+		})
+
+		It("renders finish script recording exits and restarts for processes without restart policy", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis"`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/finish")).To(Equal(`#!/bin/sh
+cd "$(dirname "$0")"
+
+if [ "$1" = -1 ]; then
+  echo "killed $(kill -l "$2")" > /var/vcap/bosh/process_exits/redis
+else
+  echo "exited $1" > /var/vcap/bosh/process_exits/redis
+fi
+
+# Services brought down by sv down or sv once are not restarted
+grep -q "want down" supervise/stat 2>/dev/null && exit 0
+
+echo $(($(cat bosh-restart-count 2>/dev/null) + 1)) > bosh-restart-count
+`))
+			Expect(fs.FileExists("/var/vcap/bosh/process_exits")).To(BeTrue())
+		})
+
+		It("renders control script escalating stop signals of processes with stop policy", func() {
+			fs.WriteFileString("/var/vcap/jobs/postgres/monit", `check process postgres start program "/var/vcap/jobs/postgres/bin/postgres"`)
+			fs.WriteFileString("/var/vcap/jobs/postgres/stop_policies.json", `{"postgres": {"timeout_secs": 600}}`)
+
+			err := supervisor.AddJob("postgres", 0, "/var/vcap/jobs/postgres/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-postgres/control/t")).To(Equal(`#!/bin/sh
+# Stops postgres escalating signals TERM, KILL
+pid=$(cat supervise/pid 2>/dev/null)
+[ -n "$pid" ] || exit 0
+
+wait_exit() {
+  for i in $(seq $1); do
+    kill -0 "$pid" 2>/dev/null || exit 0
+    sleep 1
+  done
+}
+
+kill -TERM "$pid" 2>/dev/null
+wait_exit 600
+
+kill -KILL "$pid" 2>/dev/null
+wait_exit 10
+
+! kill -0 "$pid" 2>/dev/null
+`))
+			Expect(fs.ReadFileString("/etc/service/bosh-job-postgres/bosh-stop-timeout")).To(Equal("610"))
+		})
+
+		It("appends output of processes listed in capture output file to their log files", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/packages/redis/bin/redis-server"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/capture_output.json", `["redis"]`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/service/bosh-job-redis/run")).To(Equal(`#!/bin/sh
+# redis process of BOSH job redis
+exec >>/var/vcap/data/sys/log/redis/redis.stdout.log 2>>/var/vcap/data/sys/log/redis/redis.stderr.log
+
+exec /var/vcap/packages/redis/bin/redis-server
+`))
+		})
+
+		It("keeps liveness probes of processes for monitoring", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/liveness_probes.json", `{"redis": {"http": {"port": 8080}}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/var/vcap/bosh/liveness_probes/redis.json")).To(ContainSubstring(`"http":{"host":"127.0.0.1","port":8080,"path":"/"}`))
+		})
+
+		It("keeps memory policies of processes for monitoring", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/memory_policies.json", `{"redis": {"growth_window_secs": 600, "min_growth_mb": 256}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/var/vcap/bosh/memory_policies/redis.json")).To(Equal(
+				`{"max_rss_mb":0,"growth_window_secs":600,"min_growth_mb":256,"sample_interval_secs":30}`))
+		})
+
+		It("returns error when process name cannot be used as service name", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis/0 start program "/bin/redis_ctl start"`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be used as runit service name"))
+		})
+	})
+
+	Describe("RemoveAllJobs", func() {
+		It("removes rendered services", func() {
+			setServices()
+			fs.WriteFileString("/etc/service/bosh-job-redis/run", "")
+			fs.WriteFileString("/etc/service/ssh/run", "")
+
+			err := supervisor.RemoveAllJobs()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis")).To(BeFalse())
+			Expect(fs.FileExists("/etc/service/ssh/run")).To(BeTrue())
+		})
+	})
+
+	Describe("Reload", func() {
+		BeforeEach(setServices)
+
+		It("succeeds once runsv is running for all services", func() {
+			fs.WriteFileString("/etc/service/bosh-job-redis/supervise/ok", "")
+			fs.WriteFileString("/etc/service/bosh-job-redis-sentinel/supervise/ok", "")
+
+			err := supervisor.Reload()
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error when runsv does not pick up services", func() {
+			fs.WriteFileString("/etc/service/bosh-job-redis/supervise/ok", "")
+
+			errCh := make(chan error)
+			go func() { errCh <- supervisor.Reload() }()
+
+			for i := 0; i < 3; i++ {
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				clock.Increment(1 * time.Second)
+			}
+
+			var err error
+			Eventually(errCh).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("runsv is not running for all services"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("removes down and unmonitored files, starts services and removes stopped file", func() {
+			setServices()
+			fs.WriteFileString("/etc/service/bosh-job-redis/down", "")
+			fs.WriteFileString("/etc/service/bosh-job-redis/bosh-unmonitored", "")
+			fs.WriteFileString("/var/vcap/bosh/jobs_stopped", "")
+
+			err := supervisor.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/down")).To(BeFalse())
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/bosh-unmonitored")).To(BeFalse())
+			Expect(fs.FileExists("/var/vcap/bosh/jobs_stopped")).To(BeFalse())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "up", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+
+		It("starts services of dependent jobs once services of their dependencies run", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+			Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+			Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+			Expect(supervisor.SetJobDependencies(map[string][]string{"sentinel": {"redis"}})).To(Succeed())
+
+			setServices()
+
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{
+				Stdout: "down: /etc/service/bosh-job-redis: 1s, normally up, want up\ndown: /etc/service/bosh-job-redis-sentinel: 1s\n",
+			})
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 2s\ndown: /etc/service/bosh-job-redis-sentinel: 3s\n")
+
+			errCh := make(chan error)
+			go func() { errCh <- supervisor.Start() }()
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(2 * time.Second)
+
+			Eventually(errCh).Should(Receive(BeNil()))
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "up", "/etc/service/bosh-job-redis"},
+				{"sv", "status", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+				{"sv", "status", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+				{"sv", "up", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+
+		It("starts services of at most job concurrency jobs at once", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+			Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+			Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+			Expect(supervisor.SetJobConcurrency(1)).To(Succeed())
+
+			setServices()
+
+			err := supervisor.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/down")).To(BeFalse())
+			Expect(fs.FileExists("/etc/service/bosh-job-redis-sentinel/down")).To(BeFalse())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "up", "/etc/service/bosh-job-redis"},
+				{"sv", "up", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+	})
+
+	Describe("Stop", func() {
+		It("writes down files, stops services and writes stopped file", func() {
+			setServices()
+
+			err := supervisor.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/down")).To(BeTrue())
+			Expect(fs.FileExists("/etc/service/bosh-job-redis-sentinel/down")).To(BeTrue())
+			Expect(fs.FileExists("/var/vcap/bosh/jobs_stopped")).To(BeTrue())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "-w", "60", "down", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+
+		It("waits for services as long as their stop policies require", func() {
+			setServices()
+			fs.WriteFileString("/etc/service/bosh-job-redis-sentinel/bosh-stop-timeout", "610")
+
+			err := supervisor.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "-w", "610", "down", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+
+		It("stops services of at most job concurrency jobs at once", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+			Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+			Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+			Expect(supervisor.SetJobConcurrency(1)).To(Succeed())
+
+			setServices()
+
+			err := supervisor.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "-w", "60", "down", "/etc/service/bosh-job-redis"},
+				{"sv", "-w", "60", "down", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+
+		It("returns error when services do not stop", func() {
+			setServices()
+			runner.AddCmdResult(
+				"sv -w 60 down /etc/service/bosh-job-redis /etc/service/bosh-job-redis-sentinel",
+				fakesys.FakeCmdResult{Error: errors.New("fake-stop-err")},
+			)
+
+			err := supervisor.Stop()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-stop-err"))
+			Expect(fs.FileExists("/var/vcap/bosh/jobs_stopped")).To(BeFalse())
+		})
+	})
+
+	Describe("Unmonitor", func() {
+		It("runs services once so that they are not restarted", func() {
+			setServices()
+
+			err := supervisor.Unmonitor()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/service/bosh-job-redis/bosh-unmonitored")).To(BeTrue())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"sv", "once", "/etc/service/bosh-job-redis", "/etc/service/bosh-job-redis-sentinel"},
+			}))
+		})
+	})
+
+	Describe("Status", func() {
+		BeforeEach(setServices)
+
+		It("returns running when all services run", func() {
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 300s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 300s, normally down\n")
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+
+		It("returns failing when any service is down", func() {
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 300s\ndown: /etc/service/bosh-job-redis-sentinel: 1s, normally up, want up\n")
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns failing when any service is unmonitored", func() {
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 300s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 300s\n")
+			fs.WriteFileString("/etc/service/bosh-job-redis/bosh-unmonitored", "")
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns stopped when jobs were stopped", func() {
+			setStatusResult("down: /etc/service/bosh-job-redis: 10s\ndown: /etc/service/bosh-job-redis-sentinel: 10s\n")
+			fs.WriteFileString("/var/vcap/bosh/jobs_stopped", "")
+			Expect(supervisor.Status()).To(Equal("stopped"))
+		})
+
+		It("returns unknown when status of services cannot be read", func() {
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{Error: errors.New("fake-status-err")})
+			Expect(supervisor.Status()).To(Equal("unknown"))
+		})
+	})
+
+	Describe("Processes", func() {
+		It("returns state, uptime, memory, open fds, restarts, last exit and health of processes", func() {
+			setServices()
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 300s; run: log: (pid 1233) 300s\nfail: /etc/service/bosh-job-redis-sentinel: runsv not running\n")
+			fs.WriteFileString("/etc/service/bosh-job-redis/bosh-pidfile", "/var/vcap/sys/run/redis/redis.pid")
+			fs.WriteFileString("/var/vcap/sys/run/redis/redis.pid", "1300\n")
+			fs.WriteFileString("/proc/meminfo", "MemTotal:        1024000 kB\n")
+			fs.WriteFileString("/proc/1300/status", "Name:\tredis-server\nVmRSS:\t  102400 kB\n")
+			fs.SetGlob("/proc/1300/fd/*", []string{"/proc/1300/fd/0"})
+			fs.WriteFileString("/etc/service/bosh-job-redis/bosh-restart-count", "2\n")
+			fs.WriteFileString("/var/vcap/bosh/process_exits/redis", "killed KILL\n")
+			fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.health",
+				`{"healthy": true, "failures": 0, "checked_at": "2026-10-14T10:04:50Z"}`)
+
+			twoRestarts := 2
+			noRestarts := 0
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(Equal([]Process{
+				{
+					Name:     "redis",
+					State:    "running",
+					Uptime:   UptimeVitals{Secs: 300},
+					Memory:   MemoryVitals{Kb: 102400, Percent: 10},
+					FD:       FDVitals{Open: 1},
+					Restarts: &twoRestarts,
+					LastExit: &ExitVitals{Signal: "KILL"},
+					Health: &HealthVitals{
+						Healthy:   true,
+						CheckedAt: time.Date(2026, time.October, 14, 10, 4, 50, 0, time.UTC),
+					},
+				},
+				{
+					Name:     "redis-sentinel",
+					State:    "failing",
+					Restarts: &noRestarts,
+				},
+			}))
+		})
+	})
+
+	Describe("MonitorJobFailures", func() {
+		It("alerts when runsv restarted a process or restart policy gave up on it", func() {
+			setServices()
+
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{
+				Stdout: "run: /etc/service/bosh-job-redis: (pid 1234) 300s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 300s\n",
+			})
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{
+				Stdout: "run: /etc/service/bosh-job-redis: (pid 1500) 1s\ndown: /etc/service/bosh-job-redis-sentinel: 2s, normally up\n",
+				Sticky: true,
+			})
+
+			var alertsLock sync.Mutex
+			var alerts []boshalert.MonitAlert
+
+			go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(10 * time.Second)
+
+			Eventually(func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}).Should(Equal(2))
+
+			Consistently(func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}, 100*time.Millisecond).Should(Equal(2))
+
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			Expect(alerts[0].Service).To(Equal("redis"))
+			Expect(alerts[0].Event).To(Equal("does not exist"))
+			Expect(alerts[0].Action).To(Equal("restart"))
+
+			Expect(alerts[1].Service).To(Equal("redis-sentinel"))
+			Expect(alerts[1].Event).To(Equal("execution failed"))
+			Expect(alerts[1].Action).To(Equal("alert"))
+		})
+
+		It("restarts services failing their liveness probe", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+
+			port := listener.Addr().(*net.TCPAddr).Port
+			listener.Close()
+
+			setServices()
+			setStatusResult("run: /etc/service/bosh-job-redis: (pid 1234) 300s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 300s\n")
+
+			fs.SetGlob("/var/vcap/bosh/liveness_probes/*.json", []string{"/var/vcap/bosh/liveness_probes/redis.json"})
+			fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.json", fmt.Sprintf(
+				`{"tcp": {"host": "127.0.0.1", "port": %d}, "interval_secs": 10, "timeout_secs": 1, "failure_threshold": 1, "failure_action": "restart"}`, port))
+
+			var alertsLock sync.Mutex
+			var alerts []boshalert.MonitAlert
+
+			go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+
+			Eventually(func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}).Should(Equal(1))
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			Expect(alerts[0].Service).To(Equal("redis"))
+			Expect(alerts[0].Event).To(Equal("connection failed"))
+			Expect(alerts[0].Action).To(Equal("restart"))
+			Expect(alerts[0].Description).To(ContainSubstring(fmt.Sprintf("TCP liveness probe of port %d", port)))
+
+			Expect(runner.RunCommands).To(ContainElement([]string{"sv", "restart", "/etc/service/bosh-job-redis"}))
+		})
+
+		It("restarts services exceeding their memory policy without alerting the restart twice", func() {
+			setServices()
+
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{
+				Stdout: "run: /etc/service/bosh-job-redis: (pid 1234) 300s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 300s\n",
+			})
+			runner.AddCmdResult(statusCmd, fakesys.FakeCmdResult{
+				Stdout: "run: /etc/service/bosh-job-redis: (pid 1500) 1s\nrun: /etc/service/bosh-job-redis-sentinel: (pid 1240) 310s\n",
+				Sticky: true,
+			})
+
+			fs.SetGlob("/var/vcap/bosh/memory_policies/*.json", []string{"/var/vcap/bosh/memory_policies/redis.json"})
+			fs.WriteFileString("/var/vcap/bosh/memory_policies/redis.json", `{"max_rss_mb": 512, "sample_interval_secs": 10}`)
+			fs.WriteFileString("/proc/1234/status", "VmRSS:\t 786432 kB\n")
+			fs.WriteFileString("/proc/1500/status", "VmRSS:\t 10240 kB\n")
+
+			var alertsLock sync.Mutex
+			var alerts []boshalert.MonitAlert
+
+			go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(10 * time.Second)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].Service).To(Equal("redis"))
+			Expect(alerts[0].Event).To(Equal("resource limit matched"))
+			Expect(alerts[0].Action).To(Equal("restart"))
+			Expect(alerts[0].Description).To(Equal("memory policy of runit service /etc/service/bosh-job-redis exceeded: RSS of 768 MB exceeds 512 MB"))
+
+			Expect(runner.RunCommands).To(ContainElement([]string{"sv", "restart", "/etc/service/bosh-job-redis"}))
+		})
+	})
+})
@@ -0,0 +1,108 @@
+package jobsupervisor
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// See loadProcessPolicies; monit ignores stop policies and applies its own stop program timeout
+const stopPoliciesFileName = "stop_policies.json"
+
+var stopSignals = map[string]bool{
+	"TERM": true, "INT": true, "QUIT": true, "HUP": true,
+	"USR1": true, "USR2": true, "KILL": true,
+}
+
+// StopPolicy gives process time to stop gracefully after its stop program
+// or first signal and escalates through remaining signals afterwards
+type StopPolicy struct {
+	TimeoutSecs int `json:"timeout_secs"`
+
+	// e.g. TERM, QUIT, KILL (defaults to TERM, KILL)
+	Signals []string `json:"signals"`
+
+	// Time to wait after each escalated signal (defaults to 10)
+	EscalationIntervalSecs int `json:"escalation_interval_secs"`
+}
+
+// loadStopPolicies returns policies keyed by process name;
+// jobs without stop policies file get no policies
+func loadStopPolicies(fs boshsys.FileSystem, configPath string) (map[string]StopPolicy, error) {
+	return loadProcessPolicies[StopPolicy](fs, configPath, stopPoliciesFileName, "stop policy")
+}
+
+func (p StopPolicy) withDefaults() StopPolicy {
+	if len(p.Signals) == 0 {
+		p.Signals = []string{"TERM", "KILL"}
+	}
+
+	if p.EscalationIntervalSecs == 0 {
+		p.EscalationIntervalSecs = 10
+	}
+
+	for i, signal := range p.Signals {
+		p.Signals[i] = strings.TrimPrefix(strings.ToUpper(signal), "SIG")
+	}
+
+	return p
+}
+
+func (p StopPolicy) Validate() error {
+	if p.TimeoutSecs <= 0 {
+		return bosherr.Error("Timeout must be positive")
+	}
+
+	if p.EscalationIntervalSecs <= 0 {
+		return bosherr.Error("Escalation interval must be positive")
+	}
+
+	for _, signal := range p.Signals {
+		if !stopSignals[signal] {
+			return bosherr.Errorf("Unknown signal '%s'", signal)
+		}
+	}
+
+	return nil
+}
+
+// stopSecs returns the longest time stopping process may take
+func (p StopPolicy) stopSecs(hasStopProgram bool) int {
+	escalations := len(p.Signals)
+	if !hasStopProgram {
+		escalations--
+	}
+
+	return p.TimeoutSecs + escalations*p.EscalationIntervalSecs
+}
+
+// renderStopScript renders a script that stops process with pid printed by pidCommand
+// and exits as soon as process is gone; stopCommand may be empty
+func renderStopScript(processName, pidCommand, stopCommand string, policy StopPolicy) string {
+	var script bytes.Buffer
+
+	fmt.Fprintf(&script, "#!/bin/sh\n# Stops %s escalating signals %s\n", processName, strings.Join(policy.Signals, ", "))
+	fmt.Fprintf(&script, "pid=$(%s)\n[ -n \"$pid\" ] || exit 0\n\n", pidCommand)
+
+	script.WriteString("wait_exit() {\n  for i in $(seq $1); do\n    kill -0 \"$pid\" 2>/dev/null || exit 0\n    sleep 1\n  done\n}\n\n")
+
+	signals := policy.Signals
+
+	if stopCommand != "" {
+		fmt.Fprintf(&script, "%s\nwait_exit %d\n", stopCommand, policy.TimeoutSecs)
+	} else {
+		fmt.Fprintf(&script, "kill -%s \"$pid\" 2>/dev/null\nwait_exit %d\n", signals[0], policy.TimeoutSecs)
+		signals = signals[1:]
+	}
+
+	for _, signal := range signals {
+		fmt.Fprintf(&script, "\nkill -%s \"$pid\" 2>/dev/null\nwait_exit %d\n", signal, policy.EscalationIntervalSecs)
+	}
+
+	script.WriteString("\n! kill -0 \"$pid\" 2>/dev/null\n")
+
+	return script.String()
+}
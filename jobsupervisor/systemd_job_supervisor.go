@@ -0,0 +1,867 @@
+package jobsupervisor
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	systemdJobSupervisorLogTag = "systemdJobSupervisor"
+
+	systemdUnitPrefix = "bosh-job-"
+	systemdUnitsDir   = "/etc/systemd/system"
+
+	// Runtime drop-ins do not survive reboot, same as monit's unmonitored state
+	systemdRuntimeUnitsDir = "/run/systemd/system"
+	systemdUnmonitorDropIn = "bosh-unmonitor.conf"
+
+	systemdRestartSec = 5
+
+	// Stop scripts are kept in units' drop-in dirs where systemd ignores them
+	systemdStopScript = "bosh-stop"
+
+	// Extra time given to stop scripts on top of stop policy timeouts
+	systemdStopTimeoutMarginSecs = 5
+
+	// e.g. "Wed 2026-10-14 10:00:00 UTC"
+	systemdTimestampLayout = "Mon 2006-01-02 15:04:05 MST"
+)
+
+var systemdUnitNameRegexp = regexp.MustCompile(`\A[a-zA-Z0-9_.\-]+\z`)
+
+type systemdJobSupervisor struct {
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	logger      boshlog.Logger
+	dirProvider boshdir.Provider
+	clock       clock.Clock
+
+	cgroupManager boshcgroup.Manager
+
+	jobFailuresPollInterval time.Duration
+}
+
+type systemdUnitStatus struct {
+	Unit        string
+	ActiveState string
+	MainPID     int
+	Unmonitored bool
+	Unhealthy   bool
+
+	ActiveSince time.Time
+	MemoryBytes uint64
+	Restarts    uint64
+}
+
+// NewSystemdJobSupervisor renders processes of jobs' monit files
+// as systemd services instead of handing monit files to monit
+func NewSystemdJobSupervisor(
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	logger boshlog.Logger,
+	dirProvider boshdir.Provider,
+	cgroupManager boshcgroup.Manager,
+	clock clock.Clock,
+	jobFailuresPollInterval time.Duration,
+) JobSupervisor {
+	return systemdJobSupervisor{
+		fs:          fs,
+		runner:      runner,
+		logger:      logger,
+		dirProvider: dirProvider,
+		clock:       clock,
+
+		cgroupManager: cgroupManager,
+
+		jobFailuresPollInterval: jobFailuresPollInterval,
+	}
+}
+
+func (s systemdJobSupervisor) Reload() error {
+	_, _, _, err := s.runner.RunCommand("systemctl", "daemon-reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd units")
+	}
+
+	return nil
+}
+
+// Start enables units so that jobs are started again after reboot
+// and re-monitors them by removing unmonitor drop-ins; units of jobs
+// with dependencies are started once their dependencies are healthy
+func (s systemdJobSupervisor) Start() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) > 0 {
+		for _, unit := range units {
+			err = s.fs.RemoveAll(s.unmonitorDropInPath(unit))
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Removing unmonitor drop-in of %s", unit)
+			}
+		}
+
+		err = s.Reload()
+		if err != nil {
+			return err
+		}
+
+		stages, err := s.jobDependencies().ProcessStages()
+		if err != nil {
+			return bosherr.WrapError(err, "Ordering jobs by dependencies")
+		}
+
+		if len(stages) > 0 {
+			err = startInStages(s.clock, s.logger, stages, s.startProcesses, s.unhealthyProcesses)
+		} else {
+			err = s.inJobGroups(units, s.startProcesses, s.startUnits)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.fs.RemoveAll(s.stoppedFilePath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing stopped File")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) startUnits(units []string) error {
+	s.logger.Debug(systemdJobSupervisorLogTag, "Starting units %v", units)
+
+	_, _, _, err := s.runner.RunCommand("systemctl", append([]string{"enable", "--now"}, units...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Starting units")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) startProcesses(processes []string) error {
+	return s.startUnits(s.processUnits(processes))
+}
+
+func (s systemdJobSupervisor) processUnits(processes []string) []string {
+	var units []string
+	for _, process := range processes {
+		units = append(units, systemdUnitPrefix+process+".service")
+	}
+
+	return units
+}
+
+// inJobGroups runs groupAction with processes of each job when job concurrency is limited
+// and jobs do not depend on each other, otherwise allAction is run with all units
+func (s systemdJobSupervisor) inJobGroups(units []string, groupAction, allAction func([]string) error) error {
+	groups, limit, err := s.jobDependencies().ProcessGroups()
+	if err != nil {
+		return bosherr.WrapError(err, "Grouping processes by job")
+	}
+
+	if len(groups) == 0 {
+		return allAction(units)
+	}
+
+	return inParallel(groups, limit, groupAction)
+}
+
+// unhealthyProcesses returns processes whose units are not active yet or fail their liveness probe
+func (s systemdJobSupervisor) unhealthyProcesses(processes []string) ([]string, error) {
+	statuses, err := s.unitStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	active := map[string]bool{}
+	for _, unitStatus := range statuses {
+		active[unitStatus.processName()] = unitStatus.ActiveState == "active"
+	}
+
+	prober := newLivenessProber(s.livenessProbes(), s.runner, s.clock, s.logger)
+	prober.Reload()
+
+	var unhealthy []string
+
+	for _, process := range processes {
+		if !active[process] || !prober.Passes(process) {
+			unhealthy = append(unhealthy, process)
+		}
+	}
+
+	return unhealthy, nil
+}
+
+func (s systemdJobSupervisor) Stop() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) > 0 {
+		err = s.inJobGroups(units, s.stopProcesses, s.stopUnits)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.fs.WriteFileString(s.stoppedFilePath(), "")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) stopUnits(units []string) error {
+	s.logger.Debug(systemdJobSupervisorLogTag, "Stopping units %v", units)
+
+	_, _, _, err := s.runner.RunCommand("systemctl", append([]string{"disable", "--now"}, units...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Stopping units")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) stopProcesses(processes []string) error {
+	return s.stopUnits(s.processUnits(processes))
+}
+
+// Unmonitor keeps processes running but stops systemd from restarting them
+func (s systemdJobSupervisor) Unmonitor() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	for _, unit := range units {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Unmonitoring unit %s", unit)
+
+		err = s.fs.WriteFileString(s.unmonitorDropInPath(unit), "[Service]\nRestart=no\n")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing unmonitor drop-in of %s", unit)
+		}
+	}
+
+	return s.Reload()
+}
+
+func (s systemdJobSupervisor) Status() string {
+	statuses, err := s.unitStatuses()
+	if err != nil {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Getting units status: %s", err.Error())
+		return "unknown"
+	}
+
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return "stopped"
+	}
+
+	status := "running"
+
+	for _, unitStatus := range statuses {
+		switch unitStatus.state() {
+		case "starting":
+			return "starting"
+		case "running":
+		default:
+			status = "failing"
+		}
+	}
+
+	return status
+}
+
+// Processes leaves out CPU usage since systemd only accounts total CPU time;
+// restarts are counted by systemd since units were last started
+func (s systemdJobSupervisor) Processes() ([]Process, error) {
+	processes := []Process{}
+
+	statuses, err := s.unitStatuses()
+	if err != nil {
+		return processes, err
+	}
+
+	memTotalKb, err := readMemTotalKb(s.fs)
+	if err != nil {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Getting total memory: %s", err.Error())
+	}
+
+	for _, unitStatus := range statuses {
+		process := Process{
+			Name:  unitStatus.processName(),
+			State: unitStatus.state(),
+		}
+
+		if unitStatus.ActiveState == "active" && !unitStatus.ActiveSince.IsZero() {
+			process.Uptime.Secs = int(s.clock.Now().Sub(unitStatus.ActiveSince).Seconds())
+		}
+
+		process.Memory.Kb = int(unitStatus.MemoryBytes / 1024)
+		if memTotalKb > 0 {
+			process.Memory.Percent = float64(process.Memory.Kb) / float64(memTotalKb) * 100
+		}
+
+		if unitStatus.MainPID > 0 {
+			process.FD.Open, err = countOpenFDs(s.fs, unitStatus.MainPID)
+			if err != nil {
+				s.logger.Debug(systemdJobSupervisorLogTag, "Counting open fds of pid %d: %s", unitStatus.MainPID, err.Error())
+			}
+		}
+
+		restarts := int(unitStatus.Restarts)
+		process.Restarts = &restarts
+		process.LastExit = s.processExits().LastExit(process.Name)
+		process.Health = s.livenessProbes().Health(process.Name)
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+// AddJob renders a service for each 'check process' of job's monit file
+func (s systemdJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+	configContent, err := s.fs.ReadFileString(configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading job config from file")
+	}
+
+	processes, err := parseMonitProcesses(configContent)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing job config %s", configPath)
+	}
+
+	policies, err := loadRestartPolicies(s.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading restart policies")
+	}
+
+	probes, err := loadLivenessProbes(s.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading liveness probes")
+	}
+
+	memoryPolicies, err := loadMemoryPolicies(s.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading memory policies")
+	}
+
+	stopPolicies, err := loadStopPolicies(s.fs, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading stop policies")
+	}
+
+	captured, err := loadCapturedProcesses(s.fs, s.dirProvider, jobName, configPath, processes)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading captured processes")
+	}
+
+	cgroupProcsPaths, err := jobCgroupProcsPaths(s.jobDependencies(), s.cgroupManager, jobName)
+	if err != nil {
+		return bosherr.WrapError(err, "Loading job cgroups")
+	}
+
+	err = s.processExits().Prepare()
+	if err != nil {
+		return bosherr.WrapError(err, "Creating process exits dir")
+	}
+
+	for _, process := range processes {
+		if !systemdUnitNameRegexp.MatchString(process.Name) {
+			return bosherr.Errorf("Process name '%s' cannot be used as systemd unit name", process.Name)
+		}
+
+		unitPath := path.Join(systemdUnitsDir, systemdUnitPrefix+process.Name+".service")
+
+		var policy *RestartPolicy
+		if processPolicy, found := policies[process.Name]; found {
+			policy = &processPolicy
+		}
+
+		var stopPolicy *StopPolicy
+		if processStopPolicy, found := stopPolicies[process.Name]; found {
+			stopPolicy = &processStopPolicy
+
+			err = s.writeStopScript(process, processStopPolicy)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing stop script of process %s", process.Name)
+			}
+		}
+
+		err = s.fs.WriteFileString(unitPath, s.renderUnit(jobName, process, policy, stopPolicy, captured[process.Name], cgroupProcsPaths))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing unit of process %s", process.Name)
+		}
+
+		if probe, found := probes[process.Name]; found {
+			err = s.livenessProbes().Write(process.Name, probe)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing liveness probe of process %s", process.Name)
+			}
+		}
+
+		if memoryPolicy, found := memoryPolicies[process.Name]; found {
+			err = s.memoryPolicies().Write(process.Name, memoryPolicy)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing memory policy of process %s", process.Name)
+			}
+		}
+	}
+
+	err = s.jobDependencies().AddJob(jobName, configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording job for dependencies")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) RemoveAllJobs() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	for _, unit := range units {
+		err = s.fs.RemoveAll(path.Join(systemdUnitsDir, unit))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing unit %s", unit)
+		}
+
+		err = s.fs.RemoveAll(path.Dir(s.unmonitorDropInPath(unit)))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing drop-ins of %s", unit)
+		}
+
+		err = s.fs.RemoveAll(path.Dir(s.stopScriptPath(unit)))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing stop script of %s", unit)
+		}
+	}
+
+	err = s.livenessProbes().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing liveness probes")
+	}
+
+	err = s.memoryPolicies().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing memory policies")
+	}
+
+	err = s.processExits().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing process exits")
+	}
+
+	err = s.jobDependencies().RemoveAll()
+	if err != nil {
+		return bosherr.WrapError(err, "Removing job dependencies")
+	}
+
+	return nil
+}
+
+// SetJobConcurrency makes Start and Stop handle units of at most limit jobs at once
+func (s systemdJobSupervisor) SetJobConcurrency(limit int) error {
+	err := s.jobDependencies().SetConcurrency(limit)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job concurrency")
+	}
+
+	return nil
+}
+
+// SetJobCgroups makes units of added jobs with cgroups place their processes into them
+func (s systemdJobSupervisor) SetJobCgroups(jobNames []string) error {
+	err := s.jobDependencies().SetCgroupJobs(jobNames)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job cgroups")
+	}
+
+	return nil
+}
+
+// SetJobDependencies makes Start start units of jobs in order of their dependencies
+func (s systemdJobSupervisor) SetJobDependencies(dependencies map[string][]string) error {
+	err := s.jobDependencies().SetDependsOn(dependencies)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting job dependencies")
+	}
+
+	return nil
+}
+
+// MonitorJobFailures polls units and alerts when systemd restarted a process
+// or gave up on restarting it; unmonitored units are not reported. Liveness probes
+// and memory policies of active units are checked on each poll.
+func (s systemdJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	restarts := map[string]uint64{}
+	failed := map[string]bool{}
+
+	prober := newLivenessProber(s.livenessProbes(), s.runner, s.clock, s.logger)
+	watchdog := newMemoryWatchdog(s.memoryPolicies(), s.fs, s.clock, s.logger)
+
+	for {
+		statuses, err := s.unitStatuses()
+		if err != nil {
+			s.logger.Debug(systemdJobSupervisorLogTag, "Getting units status: %s", err.Error())
+		}
+
+		prober.Reload()
+		watchdog.Reload()
+
+		for _, unitStatus := range statuses {
+			previousRestarts, found := restarts[unitStatus.Unit]
+			restarts[unitStatus.Unit] = unitStatus.Restarts
+
+			isFailed := unitStatus.ActiveState == "failed"
+			wasFailed := failed[unitStatus.Unit]
+			failed[unitStatus.Unit] = isFailed
+
+			running := unitStatus.ActiveState == "active" && !unitStatus.Unmonitored
+
+			if probe, reason, failed := prober.Check(unitStatus.processName(), running); failed {
+				s.handleLivenessFailure(handler, unitStatus, probe, reason)
+			}
+
+			if reason, exceeded := watchdog.Check(unitStatus.processName(), unitStatus.MainPID, running); exceeded {
+				s.handleMemoryFailure(handler, unitStatus, reason)
+			}
+
+			if unitStatus.Unmonitored {
+				continue
+			}
+
+			if found && unitStatus.Restarts > previousRestarts {
+				s.handleJobFailure(handler, unitStatus, "does not exist", "restart", "")
+			}
+
+			if isFailed && !wasFailed {
+				s.handleJobFailure(handler, unitStatus, "execution failed", "alert", "")
+			}
+		}
+
+		s.clock.Sleep(s.jobFailuresPollInterval)
+	}
+}
+
+// handleLivenessFailure restarts unit when probe asks for it; restart through
+// systemctl does not count towards NRestarts so it is alerted here
+func (s systemdJobSupervisor) handleLivenessFailure(handler JobFailureHandler, unitStatus systemdUnitStatus, probe LivenessProbe, reason string) {
+	description := fmt.Sprintf("%s of systemd unit %s failed %d time(s): %s",
+		probe.description(), unitStatus.Unit, probe.FailureThreshold, reason)
+
+	if probe.FailureAction == LivenessFailureActionRestart {
+		s.logger.Info(systemdJobSupervisorLogTag, "Restarting unit %s after liveness probe failures", unitStatus.Unit)
+
+		_, _, _, err := s.runner.RunCommand("systemctl", "restart", unitStatus.Unit)
+		if err != nil {
+			s.logger.Error(systemdJobSupervisorLogTag, "Restarting unit %s: %s", unitStatus.Unit, err.Error())
+		}
+	}
+
+	s.handleJobFailure(handler, unitStatus, "connection failed", probe.FailureAction, description)
+}
+
+// handleMemoryFailure restarts unit whose process broke its memory policy
+// before it exhausts memory of the whole VM
+func (s systemdJobSupervisor) handleMemoryFailure(handler JobFailureHandler, unitStatus systemdUnitStatus, reason string) {
+	description := fmt.Sprintf("memory policy of systemd unit %s exceeded: %s", unitStatus.Unit, reason)
+
+	s.logger.Info(systemdJobSupervisorLogTag, "Restarting unit %s after exceeding memory policy: %s", unitStatus.Unit, reason)
+
+	_, _, _, err := s.runner.RunCommand("systemctl", "restart", unitStatus.Unit)
+	if err != nil {
+		s.logger.Error(systemdJobSupervisorLogTag, "Restarting unit %s: %s", unitStatus.Unit, err.Error())
+	}
+
+	s.handleJobFailure(handler, unitStatus, "resource limit matched", "restart", description)
+}
+
+func (s systemdJobSupervisor) handleJobFailure(handler JobFailureHandler, unitStatus systemdUnitStatus, event, action, description string) {
+	now := s.clock.Now()
+
+	if description == "" {
+		description = fmt.Sprintf("systemd unit %s is %s", unitStatus.Unit, unitStatus.ActiveState)
+	}
+
+	alert := boshalert.MonitAlert{
+		ID:          fmt.Sprintf("%d.%s@localhost", now.UnixNano(), unitStatus.Unit),
+		Service:     unitStatus.processName(),
+		Event:       event,
+		Action:      action,
+		Date:        now.Format(time.RFC1123Z),
+		Description: description,
+	}
+
+	err := handler(alert)
+	if err != nil {
+		s.logger.Error(systemdJobSupervisorLogTag, "Handling failure of %s: %s", unitStatus.Unit, err.Error())
+	}
+}
+
+// writeStopScript renders stop script run instead of stop program; systemd passes pid of the process as $MAINPID
+func (s systemdJobSupervisor) writeStopScript(process monitProcess, policy StopPolicy) error {
+	scriptPath := s.stopScriptPath(systemdUnitPrefix + process.Name + ".service")
+
+	script := renderStopScript(process.Name, `echo "$MAINPID"`, process.StopProgram.Command, policy)
+
+	err := s.fs.WriteFileString(scriptPath, script)
+	if err != nil {
+		return err
+	}
+
+	return s.fs.Chmod(scriptPath, 0755)
+}
+
+func (s systemdJobSupervisor) renderUnit(
+	jobName string,
+	process monitProcess,
+	policy *RestartPolicy,
+	stopPolicy *StopPolicy,
+	captureOutput bool,
+	cgroupProcsPaths []string,
+) string {
+	var unit bytes.Buffer
+
+	fmt.Fprintf(&unit, "[Unit]\nDescription=%s process of BOSH job %s\n", process.Name, jobName)
+
+	if policy != nil {
+		// Initial start counts towards the limit as well
+		fmt.Fprintf(&unit, "StartLimitIntervalSec=%d\nStartLimitBurst=%d\n", policy.WindowSecs, policy.MaxRestarts+1)
+
+		if policy.GiveUpAction == RestartGiveUpActionReboot {
+			unit.WriteString("StartLimitAction=reboot\n")
+		}
+	}
+
+	unit.WriteString("\n")
+
+	// Job ctl scripts daemonize processes and write pid files like monit expects
+	unit.WriteString("[Service]\nType=forking\n")
+
+	if process.PidFile != "" {
+		fmt.Fprintf(&unit, "PIDFile=%s\n", process.PidFile)
+	}
+
+	fmt.Fprintf(&unit, "ExecStart=%s\n", systemdEscape(process.StartProgram.Command))
+	fmt.Fprintf(&unit, "TimeoutStartSec=%d\n", process.StartProgram.Timeout)
+
+	// Moves started process into job's cgroup with full privileges ('+') as soon as
+	// systemd knows its pid, processes it forks afterwards inherit the cgroup
+	if len(cgroupProcsPaths) > 0 {
+		var joins []string
+		for _, procsPath := range cgroupProcsPaths {
+			joins = append(joins, fmt.Sprintf("echo \"$$MAINPID\" > %s", procsPath))
+		}
+
+		fmt.Fprintf(&unit, "ExecStartPost=-+/bin/sh -c '%s'\n", strings.Join(joins, "; "))
+	}
+
+	if stopPolicy != nil {
+		stopSecs := stopPolicy.stopSecs(process.StopProgram.Command != "")
+
+		fmt.Fprintf(&unit, "ExecStop=%s\n", s.stopScriptPath(systemdUnitPrefix+process.Name+".service"))
+		fmt.Fprintf(&unit, "TimeoutStopSec=%d\n", stopSecs+systemdStopTimeoutMarginSecs)
+	} else if process.StopProgram.Command != "" {
+		fmt.Fprintf(&unit, "ExecStop=%s\n", systemdEscape(process.StopProgram.Command))
+		fmt.Fprintf(&unit, "TimeoutStopSec=%d\n", process.StopProgram.Timeout)
+	}
+
+	// Records how process exited with full privileges ('+') and without failing the unit ('-');
+	// systemd forgets it once process is restarted
+	fmt.Fprintf(&unit, "ExecStopPost=-+/bin/sh -c 'echo \"$$EXIT_CODE $$EXIT_STATUS\" > %s'\n",
+		s.processExits().Path(process.Name))
+
+	if process.StartProgram.User != "" {
+		fmt.Fprintf(&unit, "User=%s\n", process.StartProgram.User)
+	}
+
+	if process.StartProgram.Group != "" {
+		fmt.Fprintf(&unit, "Group=%s\n", process.StartProgram.Group)
+	}
+
+	if captureOutput {
+		stdoutPath, stderrPath := outputLogPaths(s.dirProvider, jobName, process.Name)
+
+		// Supported since systemd 240
+		fmt.Fprintf(&unit, "StandardOutput=append:%s\nStandardError=append:%s\n", stdoutPath, stderrPath)
+	}
+
+	unit.WriteString("Restart=always\n")
+
+	if policy == nil {
+		fmt.Fprintf(&unit, "RestartSec=%d\n", systemdRestartSec)
+	} else {
+		fmt.Fprintf(&unit, "RestartSec=%d\n", policy.Backoff.InitialDelaySecs)
+
+		// Delay grows exponentially over the steps, supported since systemd 254
+		if steps := policy.backoffSteps(); steps > 0 {
+			fmt.Fprintf(&unit, "RestartSteps=%d\nRestartMaxDelaySec=%d\n", steps, policy.Backoff.MaxDelaySecs)
+		}
+	}
+
+	unit.WriteString("\n")
+	unit.WriteString("[Install]\nWantedBy=multi-user.target\n")
+
+	return unit.String()
+}
+
+// units returns names of rendered services, e.g. bosh-job-redis.service
+func (s systemdJobSupervisor) units() ([]string, error) {
+	unitPaths, err := s.fs.Glob(path.Join(systemdUnitsDir, systemdUnitPrefix+"*.service"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing units")
+	}
+
+	units := []string{}
+	for _, unitPath := range unitPaths {
+		units = append(units, path.Base(unitPath))
+	}
+
+	return units, nil
+}
+
+func (s systemdJobSupervisor) unitStatuses() ([]systemdUnitStatus, error) {
+	units, err := s.units()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(units) == 0 {
+		return nil, nil
+	}
+
+	args := []string{"show", "--property=Id,ActiveState,MainPID,ActiveEnterTimestamp,MemoryCurrent,NRestarts"}
+
+	stdout, _, _, err := s.runner.RunCommand("systemctl", append(args, units...)...)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Showing units")
+	}
+
+	var statuses []systemdUnitStatus
+
+	// Properties of each unit are separated by an empty line
+	for _, block := range strings.Split(strings.TrimSpace(stdout), "\n\n") {
+		properties := map[string]string{}
+
+		for _, line := range strings.Split(block, "\n") {
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				properties[parts[0]] = strings.TrimSpace(parts[1])
+			}
+		}
+
+		if properties["Id"] == "" {
+			continue
+		}
+
+		unitStatus := systemdUnitStatus{
+			Unit:        properties["Id"],
+			ActiveState: properties["ActiveState"],
+		}
+
+		unitStatus.Unmonitored = s.fs.FileExists(s.unmonitorDropInPath(unitStatus.Unit))
+		unitStatus.Unhealthy = s.livenessProbes().Unhealthy(unitStatus.processName())
+		unitStatus.MainPID, _ = strconv.Atoi(properties["MainPID"])
+		unitStatus.Restarts, _ = strconv.ParseUint(properties["NRestarts"], 10, 64)
+
+		// "[not set]" when memory accounting is disabled
+		unitStatus.MemoryBytes, _ = strconv.ParseUint(properties["MemoryCurrent"], 10, 64)
+		if unitStatus.MemoryBytes == ^uint64(0) {
+			unitStatus.MemoryBytes = 0
+		}
+
+		activeSince, err := time.Parse(systemdTimestampLayout, properties["ActiveEnterTimestamp"])
+		if err == nil {
+			unitStatus.ActiveSince = activeSince
+		}
+
+		statuses = append(statuses, unitStatus)
+	}
+
+	return statuses, nil
+}
+
+func (s systemdJobSupervisor) unmonitorDropInPath(unit string) string {
+	return path.Join(systemdRuntimeUnitsDir, unit+".d", systemdUnmonitorDropIn)
+}
+
+func (s systemdJobSupervisor) stopScriptPath(unit string) string {
+	return path.Join(systemdUnitsDir, unit+".d", systemdStopScript)
+}
+
+func (s systemdJobSupervisor) livenessProbes() livenessProbeStore {
+	return newLivenessProbeStore(s.fs, s.dirProvider.BoshDir())
+}
+
+func (s systemdJobSupervisor) memoryPolicies() memoryPolicyStore {
+	return newMemoryPolicyStore(s.fs, s.dirProvider.BoshDir())
+}
+
+func (s systemdJobSupervisor) processExits() processExitStore {
+	return newProcessExitStore(s.fs, s.dirProvider.BoshDir())
+}
+
+func (s systemdJobSupervisor) jobDependencies() jobDependencyStore {
+	return newJobDependencyStore(s.fs, s.dirProvider.BoshDir())
+}
+
+func (s systemdJobSupervisor) stoppedFilePath() string {
+	return path.Join(s.dirProvider.BoshDir(), "jobs_stopped")
+}
+
+// processName returns monit process name the unit was rendered from
+func (u systemdUnitStatus) processName() string {
+	return strings.TrimSuffix(strings.TrimPrefix(u.Unit, systemdUnitPrefix), ".service")
+}
+
+// state maps unit state onto process states reported by monit;
+// processes failing their liveness probe are reported failing
+func (u systemdUnitStatus) state() string {
+	if u.Unmonitored {
+		return "unknown"
+	}
+
+	switch u.ActiveState {
+	case "active":
+		if u.Unhealthy {
+			return "failing"
+		}
+		return "running"
+	case "activating", "reloading":
+		return "starting"
+	default:
+		return "failing"
+	}
+}
+
+// systemdEscape prevents systemd from expanding specifiers, e.g. %n, in commands
+func systemdEscape(command string) string {
+	return strings.Replace(command, "%", "%%", -1)
+}
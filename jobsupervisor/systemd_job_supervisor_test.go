@@ -0,0 +1,955 @@
+package jobsupervisor_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup/fakes"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("systemdJobSupervisor", func() {
+	const showCmd = "systemctl show --property=Id,ActiveState,MainPID,ActiveEnterTimestamp,MemoryCurrent,NRestarts bosh-job-redis.service bosh-job-redis-sentinel.service"
+
+	var (
+		fs          *fakesys.FakeFileSystem
+		runner      *fakesys.FakeCmdRunner
+		clock       *fakeclock.FakeClock
+		dirProvider boshdir.Provider
+		cgroups     *fakecgroup.FakeManager
+		supervisor  JobSupervisor
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		clock = fakeclock.NewFakeClock(time.Date(2026, time.October, 14, 10, 5, 0, 0, time.UTC))
+		dirProvider = boshdir.NewProvider("/var/vcap")
+		cgroups = fakecgroup.NewFakeManager()
+
+		supervisor = NewSystemdJobSupervisor(
+			fs,
+			runner,
+			boshlog.NewLogger(boshlog.LevelNone),
+			dirProvider,
+			cgroups,
+			clock,
+			10*time.Second,
+		)
+	})
+
+	setUnits := func() {
+		fs.SetGlob("/etc/systemd/system/bosh-job-*.service", []string{
+			"/etc/systemd/system/bosh-job-redis.service",
+			"/etc/systemd/system/bosh-job-redis-sentinel.service",
+		})
+	}
+
+	setShowResult := func(stdout string) {
+		runner.AddCmdResult(showCmd, fakesys.FakeCmdResult{Stdout: stdout, Sticky: true})
+	}
+
+	Describe("AddJob", func() {
+		It("renders a service for each process of the monit file", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `
+# redis and its sentinel
+check process redis
+  with pidfile /var/vcap/sys/run/redis/redis.pid
+  start program "/var/vcap/jobs/redis/bin/redis_ctl start"
+  stop program "/var/vcap/jobs/redis/bin/redis_ctl stop"
+  group vcap
+
+check process redis-sentinel with pidfile /var/vcap/sys/run/redis/sentinel.pid
+  start program = "/bin/sh -c '/var/vcap/jobs/redis/bin/sentinel_ctl start 100%'"
+    as uid vcap and gid vcap with timeout 60 seconds
+  stop program = "/var/vcap/jobs/redis/bin/sentinel_ctl stop" with timeout 10 seconds
+  if failed port 26379 then restart
+  group vcap
+
+check file redis-config with path /var/vcap/jobs/redis/config/redis.conf
+`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis.service")).To(Equal(`[Unit]
+Description=redis process of BOSH job redis
+
+[Service]
+Type=forking
+PIDFile=/var/vcap/sys/run/redis/redis.pid
+ExecStart=/var/vcap/jobs/redis/bin/redis_ctl start
+TimeoutStartSec=30
+ExecStop=/var/vcap/jobs/redis/bin/redis_ctl stop
+TimeoutStopSec=30
+ExecStopPost=-+/bin/sh -c 'echo "$$EXIT_CODE $$EXIT_STATUS" > /var/vcap/bosh/process_exits/redis'
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis-sentinel.service")).To(Equal(`[Unit]
+Description=redis-sentinel process of BOSH job redis
+
+[Service]
+Type=forking
+PIDFile=/var/vcap/sys/run/redis/sentinel.pid
+ExecStart=/bin/sh -c '/var/vcap/jobs/redis/bin/sentinel_ctl start 100%%'
+TimeoutStartSec=60
+ExecStop=/var/vcap/jobs/redis/bin/sentinel_ctl stop
+TimeoutStopSec=10
+ExecStopPost=-+/bin/sh -c 'echo "$$EXIT_CODE $$EXIT_STATUS" > /var/vcap/bosh/process_exits/redis-sentinel'
+User=vcap
+Group=vcap
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`))
+
+			Expect(fs.FileExists("/etc/systemd/system/bosh-job-redis-config.service")).To(BeFalse())
+		})
+
+		It("moves started process of job with cgroup into the cgroup", func() {
+			cgroups.ProcsPathsPaths["redis"] = []string{
+				"/sys/fs/cgroup/memory/bosh/redis/cgroup.procs",
+				"/sys/fs/cgroup/pids/bosh/redis/cgroup.procs",
+			}
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis_ctl start"`)
+
+			err := supervisor.SetJobCgroups([]string{"redis"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis.service")).To(ContainSubstring(
+				"TimeoutStartSec=30\n" +
+					"ExecStartPost=-+/bin/sh -c '" +
+					`echo "$$MAINPID" > /sys/fs/cgroup/memory/bosh/redis/cgroup.procs; ` +
+					`echo "$$MAINPID" > /sys/fs/cgroup/pids/bosh/redis/cgroup.procs'` + "\n",
+			))
+		})
+
+		It("does not move processes of jobs without cgroup", func() {
+			cgroups.ProcsPathsPaths["redis"] = []string{"/sys/fs/cgroup/bosh/redis/cgroup.procs"}
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/var/vcap/jobs/redis/bin/redis_ctl start"`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis.service")).ToNot(ContainSubstring("ExecStartPost"))
+		})
+
+		It("limits restarts of processes with restart policy", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `
+check process redis
+  with pidfile /var/vcap/sys/run/redis/redis.pid
+  start program "/var/vcap/jobs/redis/bin/redis_ctl start"
+  group vcap
+`)
+			fs.WriteFileString("/var/vcap/jobs/redis/restart_policies.json", `{
+				"redis": {
+					"max_restarts": 5,
+					"window_secs": 300,
+					"backoff": {"initial_delay_secs": 2, "max_delay_secs": 60, "multiplier": 2},
+					"give_up_action": "reboot"
+				}
+			}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis.service")).To(Equal(`[Unit]
+Description=redis process of BOSH job redis
+StartLimitIntervalSec=300
+StartLimitBurst=6
+StartLimitAction=reboot
+
+[Service]
+Type=forking
+PIDFile=/var/vcap/sys/run/redis/redis.pid
+ExecStart=/var/vcap/jobs/redis/bin/redis_ctl start
+TimeoutStartSec=30
+ExecStopPost=-+/bin/sh -c 'echo "$$EXIT_CODE $$EXIT_STATUS" > /var/vcap/bosh/process_exits/redis'
+Restart=always
+RestartSec=2
+RestartSteps=5
+RestartMaxDelaySec=60
+
+[Install]
+WantedBy=multi-user.target
+`))
+		})
+
+		It("returns error when restart policy is invalid", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/restart_policies.json", `{"redis": {"max_restarts": 5, "window_secs": 60, "give_up_action": "fake-action"}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown give up action 'fake-action'"))
+		})
+
+		It("escalates stop signals of processes with stop policy", func() {
+			fs.WriteFileString("/var/vcap/jobs/postgres/monit", `
+check process postgres
+  with pidfile /var/vcap/sys/run/postgres/postgres.pid
+  start program "/var/vcap/jobs/postgres/bin/postgres_ctl start"
+  stop program "/var/vcap/jobs/postgres/bin/postgres_ctl stop"
+  group vcap
+`)
+			fs.WriteFileString("/var/vcap/jobs/postgres/stop_policies.json", `{
+				"postgres": {"timeout_secs": 600, "signals": ["SIGTERM", "QUIT", "KILL"], "escalation_interval_secs": 30}
+			}`)
+
+			err := supervisor.AddJob("postgres", 0, "/var/vcap/jobs/postgres/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-postgres.service")).To(ContainSubstring(`
+ExecStop=/etc/systemd/system/bosh-job-postgres.service.d/bosh-stop
+TimeoutStopSec=695
+`))
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-postgres.service.d/bosh-stop")).To(Equal(`#!/bin/sh
+# Stops postgres escalating signals TERM, QUIT, KILL
+pid=$(echo "$MAINPID")
+[ -n "$pid" ] || exit 0
+
+wait_exit() {
+  for i in $(seq $1); do
+    kill -0 "$pid" 2>/dev/null || exit 0
+    sleep 1
+  done
+}
+
+/var/vcap/jobs/postgres/bin/postgres_ctl stop
+wait_exit 600
+
+kill -TERM "$pid" 2>/dev/null
+wait_exit 30
+
+kill -QUIT "$pid" 2>/dev/null
+wait_exit 30
+
+kill -KILL "$pid" 2>/dev/null
+wait_exit 30
+
+! kill -0 "$pid" 2>/dev/null
+`))
+			Expect(fs.GetFileTestStat("/etc/systemd/system/bosh-job-postgres.service.d/bosh-stop").FileMode).To(Equal(os.FileMode(0755)))
+		})
+
+		It("returns error when stop policy is invalid", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/stop_policies.json", `{"redis": {"timeout_secs": 60, "signals": ["TERM", "STOP"]}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown signal 'STOP'"))
+		})
+
+		It("captures output of processes listed in capture output file", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start" as uid vcap`)
+			fs.WriteFileString("/var/vcap/jobs/redis/capture_output.json", `["redis"]`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/etc/systemd/system/bosh-job-redis.service")).To(ContainSubstring(`
+User=vcap
+StandardOutput=append:/var/vcap/data/sys/log/redis/redis.stdout.log
+StandardError=append:/var/vcap/data/sys/log/redis/redis.stderr.log
+`))
+			Expect(fs.FileExists("/var/vcap/data/sys/log/redis")).To(BeTrue())
+		})
+
+		It("returns error when capture output file lists unknown process", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/capture_output.json", `["redis-sentinel"]`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Process 'redis-sentinel' listed in /var/vcap/jobs/redis/capture_output.json is not in job config"))
+		})
+
+		It("keeps liveness probes of processes for monitoring", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/liveness_probes.json", `{"redis": {"tcp": {"port": 6379}, "failure_threshold": 2}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFile("/var/vcap/bosh/liveness_probes/redis.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			var probe LivenessProbe
+			Expect(json.Unmarshal(contents, &probe)).To(Succeed())
+			Expect(probe).To(Equal(LivenessProbe{
+				TCP:              &TCPProbe{Host: "127.0.0.1", Port: 6379},
+				IntervalSecs:     10,
+				TimeoutSecs:      5,
+				FailureThreshold: 2,
+				FailureAction:    "restart",
+			}))
+		})
+
+		It("returns error when liveness probe is invalid", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/liveness_probes.json", `{"redis": {"tcp": {"port": 6379}, "exec": {"command": "/bin/true"}}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Exactly one of http, tcp or exec must be specified"))
+		})
+
+		It("keeps memory policies of processes for monitoring", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/memory_policies.json", `{"redis": {"max_rss_mb": 2048}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFile("/var/vcap/bosh/memory_policies/redis.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			var policy MemoryPolicy
+			Expect(json.Unmarshal(contents, &policy)).To(Succeed())
+			Expect(policy).To(Equal(MemoryPolicy{MaxRSSMB: 2048, SampleIntervalSecs: 30}))
+		})
+
+		It("returns error when memory policy is invalid", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/redis/memory_policies.json", `{"redis": {"growth_window_secs": 30}}`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Growth window must span at least two sample intervals of 30 seconds"))
+		})
+
+		It("returns error when process has no start program", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", "check process redis\n  with pidfile /var/vcap/sys/run/redis/redis.pid\n")
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Process 'redis' has no start program"))
+		})
+
+		It("returns error when process name cannot be used as unit name", func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis/0 start program "/bin/redis_ctl start"`)
+
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot be used as systemd unit name"))
+		})
+
+		It("returns error when monit file cannot be read", func() {
+			err := supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading job config from file"))
+		})
+	})
+
+	Describe("RemoveAllJobs", func() {
+		It("removes rendered units and their drop-ins", func() {
+			setUnits()
+			fs.WriteFileString("/etc/systemd/system/bosh-job-redis.service", "")
+			fs.WriteFileString("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf", "")
+			fs.WriteFileString("/etc/systemd/system/bosh-job-redis.service.d/bosh-stop", "")
+			fs.WriteFileString("/etc/systemd/system/ssh.service", "")
+
+			err := supervisor.RemoveAllJobs()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/system/bosh-job-redis.service.d/bosh-stop")).To(BeFalse())
+
+			Expect(fs.FileExists("/etc/systemd/system/bosh-job-redis.service")).To(BeFalse())
+			Expect(fs.FileExists("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf")).To(BeFalse())
+			Expect(fs.FileExists("/etc/systemd/system/ssh.service")).To(BeTrue())
+		})
+
+		It("removes liveness probes", func() {
+			fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.json", "{}")
+
+			err := supervisor.RemoveAllJobs()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/var/vcap/bosh/liveness_probes/redis.json")).To(BeFalse())
+		})
+
+		It("removes memory policies", func() {
+			fs.WriteFileString("/var/vcap/bosh/memory_policies/redis.json", "{}")
+
+			err := supervisor.RemoveAllJobs()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/var/vcap/bosh/memory_policies/redis.json")).To(BeFalse())
+		})
+	})
+
+	Describe("Reload", func() {
+		It("reloads systemd units", func() {
+			err := supervisor.Reload()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{{"systemctl", "daemon-reload"}}))
+		})
+	})
+
+	Describe("Start", func() {
+		It("re-monitors, enables and starts units and removes stopped file", func() {
+			setUnits()
+			fs.WriteFileString("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf", "")
+			fs.WriteFileString("/var/vcap/bosh/jobs_stopped", "")
+
+			err := supervisor.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf")).To(BeFalse())
+			Expect(fs.FileExists("/var/vcap/bosh/jobs_stopped")).To(BeFalse())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "daemon-reload"},
+				{"systemctl", "enable", "--now", "bosh-job-redis.service", "bosh-job-redis-sentinel.service"},
+			}))
+		})
+
+		It("returns error when units cannot be started", func() {
+			setUnits()
+			runner.AddCmdResult(
+				"systemctl enable --now bosh-job-redis.service bosh-job-redis-sentinel.service",
+				fakesys.FakeCmdResult{Error: errors.New("fake-start-err")},
+			)
+
+			err := supervisor.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-start-err"))
+		})
+
+		Context("when jobs depend on each other", func() {
+			BeforeEach(func() {
+				fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+				fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+				Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+				Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+				Expect(supervisor.SetJobDependencies(map[string][]string{"sentinel": {"redis"}})).To(Succeed())
+
+				setUnits()
+			})
+
+			It("starts units of dependent jobs once units of their dependencies are active", func() {
+				runner.AddCmdResult(showCmd, fakesys.FakeCmdResult{
+					Stdout: "Id=bosh-job-redis.service\nActiveState=activating\n\nId=bosh-job-redis-sentinel.service\nActiveState=inactive\n",
+				})
+				setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=inactive\n")
+
+				errCh := make(chan error)
+				go func() { errCh <- supervisor.Start() }()
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				clock.Increment(2 * time.Second)
+
+				Eventually(errCh).Should(Receive(BeNil()))
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"systemctl", "daemon-reload"},
+					{"systemctl", "enable", "--now", "bosh-job-redis.service"},
+					{"systemctl", "show", "--property=Id,ActiveState,MainPID,ActiveEnterTimestamp,MemoryCurrent,NRestarts", "bosh-job-redis.service", "bosh-job-redis-sentinel.service"},
+					{"systemctl", "show", "--property=Id,ActiveState,MainPID,ActiveEnterTimestamp,MemoryCurrent,NRestarts", "bosh-job-redis.service", "bosh-job-redis-sentinel.service"},
+					{"systemctl", "enable", "--now", "bosh-job-redis-sentinel.service"},
+				}))
+			})
+
+			It("does not start units of dependent jobs until liveness probes of their dependencies pass", func() {
+				setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=inactive\n")
+
+				fs.SetGlob("/var/vcap/bosh/liveness_probes/*.json", []string{"/var/vcap/bosh/liveness_probes/redis.json"})
+				fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.json", `{"exec": {"command": "/bin/check"}, "timeout_secs": 1}`)
+				runner.AddCmdResult("timeout 1 sh -c /bin/check", fakesys.FakeCmdResult{Error: errors.New("fake-check-err")})
+				runner.AddCmdResult("timeout 1 sh -c /bin/check", fakesys.FakeCmdResult{})
+
+				errCh := make(chan error)
+				go func() { errCh <- supervisor.Start() }()
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"systemctl", "enable", "--now", "bosh-job-redis-sentinel.service"}))
+
+				clock.Increment(2 * time.Second)
+
+				Eventually(errCh).Should(Receive(BeNil()))
+				Expect(runner.RunCommands).To(ContainElement([]string{"systemctl", "enable", "--now", "bosh-job-redis-sentinel.service"}))
+			})
+		})
+
+		Context("when job concurrency is limited", func() {
+			BeforeEach(func() {
+				fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+				fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+				Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+				Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+				Expect(supervisor.SetJobConcurrency(1)).To(Succeed())
+
+				setUnits()
+			})
+
+			It("starts units of at most that many jobs at once", func() {
+				err := supervisor.Start()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"systemctl", "daemon-reload"},
+					{"systemctl", "enable", "--now", "bosh-job-redis.service"},
+					{"systemctl", "enable", "--now", "bosh-job-redis-sentinel.service"},
+				}))
+			})
+
+			It("returns errors of all jobs whose units cannot be started", func() {
+				runner.AddCmdResult("systemctl enable --now bosh-job-redis.service", fakesys.FakeCmdResult{Error: errors.New("fake-redis-err")})
+				runner.AddCmdResult("systemctl enable --now bosh-job-redis-sentinel.service", fakesys.FakeCmdResult{Error: errors.New("fake-sentinel-err")})
+
+				err := supervisor.Start()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-redis-err"))
+				Expect(err.Error()).To(ContainSubstring("fake-sentinel-err"))
+			})
+
+			It("stops units of all jobs at once when jobs depend on each other", func() {
+				Expect(supervisor.SetJobDependencies(map[string][]string{"sentinel": {"redis"}})).To(Succeed())
+
+				err := supervisor.Stop()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"systemctl", "disable", "--now", "bosh-job-redis.service", "bosh-job-redis-sentinel.service"},
+				}))
+			})
+
+			It("stops units of at most that many jobs at once", func() {
+				err := supervisor.Stop()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"systemctl", "disable", "--now", "bosh-job-redis.service"},
+					{"systemctl", "disable", "--now", "bosh-job-redis-sentinel.service"},
+				}))
+			})
+		})
+	})
+
+	Describe("SetJobConcurrency", func() {
+		It("returns error when limit is negative", func() {
+			err := supervisor.SetJobConcurrency(-1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job concurrency -1 must not be negative"))
+		})
+	})
+
+	Describe("SetJobDependencies", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/var/vcap/jobs/redis/monit", `check process redis start program "/bin/redis_ctl start"`)
+			fs.WriteFileString("/var/vcap/jobs/sentinel/monit", `check process redis-sentinel start program "/bin/sentinel_ctl start"`)
+
+			Expect(supervisor.AddJob("redis", 0, "/var/vcap/jobs/redis/monit")).To(Succeed())
+			Expect(supervisor.AddJob("sentinel", 1, "/var/vcap/jobs/sentinel/monit")).To(Succeed())
+		})
+
+		It("returns error when dependencies form a cycle", func() {
+			err := supervisor.SetJobDependencies(map[string][]string{"sentinel": {"redis"}, "redis": {"sentinel"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Dependencies of jobs [redis sentinel] form a cycle"))
+		})
+
+		It("returns error when job depends on unknown job", func() {
+			err := supervisor.SetJobDependencies(map[string][]string{"sentinel": {"postgres"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Job 'sentinel' depends on unknown job 'postgres'"))
+		})
+	})
+
+	Describe("Stop", func() {
+		It("disables and stops units and writes stopped file", func() {
+			setUnits()
+
+			err := supervisor.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/var/vcap/bosh/jobs_stopped")).To(BeTrue())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "disable", "--now", "bosh-job-redis.service", "bosh-job-redis-sentinel.service"},
+			}))
+		})
+	})
+
+	Describe("Unmonitor", func() {
+		It("disables restarts of units", func() {
+			setUnits()
+
+			err := supervisor.Unmonitor()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf")).To(Equal("[Service]\nRestart=no\n"))
+			Expect(fs.ReadFileString("/run/systemd/system/bosh-job-redis-sentinel.service.d/bosh-unmonitor.conf")).To(Equal("[Service]\nRestart=no\n"))
+			Expect(runner.RunCommands).To(Equal([][]string{{"systemctl", "daemon-reload"}}))
+		})
+	})
+
+	Describe("Status", func() {
+		BeforeEach(setUnits)
+
+		It("returns running when all units are active", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\n")
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+
+		It("returns starting when any unit is activating", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=failed\n\nId=bosh-job-redis-sentinel.service\nActiveState=activating\n")
+			Expect(supervisor.Status()).To(Equal("starting"))
+		})
+
+		It("returns failing when any unit is not active", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=failed\n")
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns failing when any unit is unmonitored", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\n")
+			fs.WriteFileString("/run/systemd/system/bosh-job-redis.service.d/bosh-unmonitor.conf", "")
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns failing when any unit fails its liveness probe", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=active\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\n")
+			fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.unhealthy", "")
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns stopped when jobs were stopped", func() {
+			setShowResult("Id=bosh-job-redis.service\nActiveState=inactive\n\nId=bosh-job-redis-sentinel.service\nActiveState=inactive\n")
+			fs.WriteFileString("/var/vcap/bosh/jobs_stopped", "")
+			Expect(supervisor.Status()).To(Equal("stopped"))
+		})
+
+		It("returns unknown when units cannot be shown", func() {
+			runner.AddCmdResult(showCmd, fakesys.FakeCmdResult{Error: errors.New("fake-show-err")})
+			Expect(supervisor.Status()).To(Equal("unknown"))
+		})
+	})
+
+	Describe("Processes", func() {
+		It("returns state, uptime, memory, open fds, restarts, last exit and health of units", func() {
+			setUnits()
+			setShowResult(`Id=bosh-job-redis.service
+ActiveState=active
+MainPID=1234
+ActiveEnterTimestamp=Wed 2026-10-14 10:00:00 UTC
+MemoryCurrent=104857600
+NRestarts=0
+
+Id=bosh-job-redis-sentinel.service
+ActiveState=failed
+MainPID=0
+ActiveEnterTimestamp=
+MemoryCurrent=[not set]
+NRestarts=3
+`)
+			fs.WriteFileString("/proc/meminfo", "MemTotal:        1024000 kB\nMemFree:          512000 kB\n")
+			fs.SetGlob("/proc/1234/fd/*", []string{"/proc/1234/fd/0", "/proc/1234/fd/1"})
+			fs.WriteFileString("/var/vcap/bosh/process_exits/redis", "exited 1\n")
+			fs.WriteFileString("/var/vcap/bosh/process_exits/redis-sentinel", "dumped SEGV\n")
+			fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.health",
+				`{"healthy": false, "failures": 2, "reason": "fake-probe-err", "checked_at": "2026-10-14T10:04:50Z"}`)
+
+			noRestarts := 0
+			threeRestarts := 3
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(Equal([]Process{
+				{
+					Name:     "redis",
+					State:    "running",
+					Uptime:   UptimeVitals{Secs: 300},
+					Memory:   MemoryVitals{Kb: 102400, Percent: 10},
+					FD:       FDVitals{Open: 2},
+					Restarts: &noRestarts,
+					LastExit: &ExitVitals{Status: 1},
+					Health: &HealthVitals{
+						Failures:  2,
+						Reason:    "fake-probe-err",
+						CheckedAt: time.Date(2026, time.October, 14, 10, 4, 50, 0, time.UTC),
+					},
+				},
+				{
+					Name:     "redis-sentinel",
+					State:    "failing",
+					Restarts: &threeRestarts,
+					LastExit: &ExitVitals{Signal: "SEGV", CoreDumped: true},
+				},
+			}))
+		})
+
+		It("returns no processes when there are no units", func() {
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(processes).To(BeEmpty())
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+
+	Describe("MonitorJobFailures", func() {
+		It("alerts when systemd restarts a process or gives up on it", func() {
+			setUnits()
+
+			runner.AddCmdResult(showCmd, fakesys.FakeCmdResult{
+				Stdout: "Id=bosh-job-redis.service\nActiveState=active\nNRestarts=0\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\nNRestarts=0\n",
+			})
+			runner.AddCmdResult(showCmd, fakesys.FakeCmdResult{
+				Stdout: "Id=bosh-job-redis.service\nActiveState=active\nNRestarts=1\n\nId=bosh-job-redis-sentinel.service\nActiveState=failed\nNRestarts=0\n",
+				Sticky: true,
+			})
+
+			var alertsLock sync.Mutex
+			var alerts []boshalert.MonitAlert
+
+			go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				alerts = append(alerts, alert)
+				return nil
+			})
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(10 * time.Second)
+
+			Eventually(func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}).Should(Equal(2))
+
+			alertsLock.Lock()
+			defer alertsLock.Unlock()
+
+			Expect(alerts[0].Service).To(Equal("redis"))
+			Expect(alerts[0].Event).To(Equal("does not exist"))
+			Expect(alerts[0].Action).To(Equal("restart"))
+			Expect(alerts[0].Date).To(Equal("Wed, 14 Oct 2026 10:05:10 +0000"))
+
+			Expect(alerts[1].Service).To(Equal("redis-sentinel"))
+			Expect(alerts[1].Event).To(Equal("execution failed"))
+			Expect(alerts[1].Action).To(Equal("alert"))
+		})
+
+		Context("when processes have liveness probes", func() {
+			var (
+				alertsLock sync.Mutex
+				alerts     []boshalert.MonitAlert
+			)
+
+			BeforeEach(func() {
+				setUnits()
+				setShowResult("Id=bosh-job-redis.service\nActiveState=active\nNRestarts=0\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\nNRestarts=0\n")
+				fs.SetGlob("/var/vcap/bosh/liveness_probes/*.json", []string{"/var/vcap/bosh/liveness_probes/redis.json"})
+
+				alerts = nil
+			})
+
+			monitor := func() {
+				go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+					alertsLock.Lock()
+					defer alertsLock.Unlock()
+					alerts = append(alerts, alert)
+					return nil
+				})
+			}
+
+			alertCount := func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}
+
+			It("restarts units once probe fails failure threshold times in a row", func() {
+				fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.json",
+					`{"exec": {"command": "/var/vcap/jobs/redis/bin/check"}, "interval_secs": 10, "timeout_secs": 3, "failure_threshold": 2, "failure_action": "restart"}`)
+
+				runner.AddCmdResult("timeout 3 sh -c /var/vcap/jobs/redis/bin/check", fakesys.FakeCmdResult{
+					Error:  errors.New("fake-check-err"),
+					Sticky: true,
+				})
+
+				monitor()
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				Consistently(alertCount, 100*time.Millisecond).Should(Equal(0))
+
+				clock.Increment(10 * time.Second)
+
+				Eventually(alertCount).Should(Equal(1))
+
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				Expect(alerts[0].Service).To(Equal("redis"))
+				Expect(alerts[0].Event).To(Equal("connection failed"))
+				Expect(alerts[0].Action).To(Equal("restart"))
+				Expect(alerts[0].Description).To(ContainSubstring("fake-check-err"))
+
+				Expect(runner.RunCommands).To(ContainElement([]string{"systemctl", "restart", "bosh-job-redis.service"}))
+			})
+
+			It("reports process failing without restarting it when failure action is alert", func() {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if r.URL.Path == "/healthz" {
+						w.WriteHeader(http.StatusServiceUnavailable)
+					}
+				}))
+				defer server.Close()
+
+				port := server.Listener.Addr().(*net.TCPAddr).Port
+
+				fs.WriteFileString("/var/vcap/bosh/liveness_probes/redis.json", fmt.Sprintf(
+					`{"http": {"host": "127.0.0.1", "port": %d, "path": "/healthz"}, "interval_secs": 10, "timeout_secs": 1, "failure_threshold": 1, "failure_action": "alert"}`, port))
+
+				monitor()
+
+				Eventually(alertCount).Should(Equal(1))
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				clock.Increment(10 * time.Second)
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				Consistently(alertCount, 100*time.Millisecond).Should(Equal(1))
+
+				alertsLock.Lock()
+				Expect(alerts[0].Service).To(Equal("redis"))
+				Expect(alerts[0].Event).To(Equal("connection failed"))
+				Expect(alerts[0].Action).To(Equal("alert"))
+				Expect(alerts[0].Description).To(ContainSubstring("status 503"))
+				alertsLock.Unlock()
+
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"systemctl", "restart", "bosh-job-redis.service"}))
+				Expect(supervisor.Status()).To(Equal("failing"))
+
+				health, err := fs.ReadFileString("/var/vcap/bosh/liveness_probes/redis.health")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(health).To(ContainSubstring(`"healthy":false,"failures":2`))
+				Expect(health).To(ContainSubstring("status 503"))
+			})
+		})
+
+		Context("when processes have memory policies", func() {
+			var (
+				alertsLock sync.Mutex
+				alerts     []boshalert.MonitAlert
+			)
+
+			BeforeEach(func() {
+				setUnits()
+				setShowResult("Id=bosh-job-redis.service\nActiveState=active\nMainPID=1300\nNRestarts=0\n\nId=bosh-job-redis-sentinel.service\nActiveState=active\nMainPID=1301\nNRestarts=0\n")
+				fs.SetGlob("/var/vcap/bosh/memory_policies/*.json", []string{"/var/vcap/bosh/memory_policies/redis.json"})
+				fs.WriteFileString("/proc/1301/status", "VmRSS:\t 4194304 kB\n")
+
+				alerts = nil
+			})
+
+			monitor := func() {
+				go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+					alertsLock.Lock()
+					defer alertsLock.Unlock()
+					alerts = append(alerts, alert)
+					return nil
+				})
+			}
+
+			alertCount := func() int {
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+				return len(alerts)
+			}
+
+			It("restarts units whose RSS exceeds max RSS", func() {
+				fs.WriteFileString("/var/vcap/bosh/memory_policies/redis.json", `{"max_rss_mb": 1024, "sample_interval_secs": 10}`)
+				fs.WriteFileString("/proc/1300/status", "Name:\tredis-server\nVmRSS:\t 1572864 kB\n")
+
+				monitor()
+
+				Eventually(alertCount).Should(Equal(1))
+
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				Expect(alerts[0].Service).To(Equal("redis"))
+				Expect(alerts[0].Event).To(Equal("resource limit matched"))
+				Expect(alerts[0].Action).To(Equal("restart"))
+				Expect(alerts[0].Description).To(Equal("memory policy of systemd unit bosh-job-redis.service exceeded: RSS of 1536 MB exceeds 1024 MB"))
+
+				Expect(runner.RunCommands).To(ContainElement([]string{"systemctl", "restart", "bosh-job-redis.service"}))
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"systemctl", "restart", "bosh-job-redis-sentinel.service"}))
+			})
+
+			It("restarts units whose RSS keeps growing over growth window", func() {
+				fs.WriteFileString("/var/vcap/bosh/memory_policies/redis.json", `{"growth_window_secs": 20, "min_growth_mb": 64, "sample_interval_secs": 10}`)
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 102400 kB\n")
+
+				monitor()
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 153600 kB\n")
+				clock.Increment(10 * time.Second)
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				Expect(alertCount()).To(Equal(0))
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 204800 kB\n")
+				clock.Increment(10 * time.Second)
+
+				Eventually(alertCount).Should(Equal(1))
+
+				alertsLock.Lock()
+				defer alertsLock.Unlock()
+
+				Expect(alerts[0].Service).To(Equal("redis"))
+				Expect(alerts[0].Event).To(Equal("resource limit matched"))
+				Expect(alerts[0].Description).To(ContainSubstring("RSS grew from 100 MB to 200 MB over 20 seconds"))
+
+				Expect(runner.RunCommands).To(ContainElement([]string{"systemctl", "restart", "bosh-job-redis.service"}))
+			})
+
+			It("does not restart units whose RSS decreased within growth window", func() {
+				fs.WriteFileString("/var/vcap/bosh/memory_policies/redis.json", `{"growth_window_secs": 20, "sample_interval_secs": 10}`)
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 102400 kB\n")
+
+				monitor()
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 51200 kB\n")
+				clock.Increment(10 * time.Second)
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				fs.WriteFileString("/proc/1300/status", "VmRSS:\t 204800 kB\n")
+				clock.Increment(10 * time.Second)
+
+				Eventually(clock.WatcherCount).Should(Equal(1))
+				Expect(alertCount()).To(Equal(0))
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"systemctl", "restart", "bosh-job-redis.service"}))
+			})
+		})
+	})
+})
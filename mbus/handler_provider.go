@@ -1,11 +1,15 @@
 package mbus
 
 import (
+	"crypto/tls"
 	"net/url"
 
 	"github.com/cloudfoundry/yagnats"
 
+	boshfips "github.com/cloudfoundry/bosh-agent/fips"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshdispatcher "github.com/cloudfoundry/bosh-agent/httpsdispatcher"
+	boshidentitykey "github.com/cloudfoundry/bosh-agent/identitykey"
 	boshmicro "github.com/cloudfoundry/bosh-agent/micro"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -46,9 +50,37 @@ func (p HandlerProvider) Get(
 
 	switch mbusURL.Scheme {
 	case "nats":
+		if boshfips.Enabled(p.settingsService.GetSettings().Env.GetFIPS()) {
+			err = bosherr.Error("NATS message bus does not use TLS and is not supported in FIPS mode")
+			return
+		}
+
 		handler = NewNatsHandler(p.settingsService, yagnats.NewClient(), p.logger, platform)
 	case "https":
-		handler = boshmicro.NewHTTPSHandler(mbusURL, p.logger, platform.GetFs(), dirProvider)
+		env := p.settingsService.GetSettings().Env
+
+		switch {
+		case env.GetIdentityKey().DeviceBacked():
+			tlsConfig := boshdispatcher.DefaultTLSConfig()
+			if boshfips.Enabled(env.GetFIPS()) {
+				tlsConfig = boshfips.TLSConfig()
+			}
+
+			var cert tls.Certificate
+
+			cert, err = boshidentitykey.LoadCertificate(platform.GetFs(), platform.GetRunner(), boshdispatcher.CertificatePath, env.GetIdentityKey())
+			if err != nil {
+				err = bosherr.WrapError(err, "Loading device-backed agent identity")
+				return
+			}
+
+			tlsConfig.Certificates = []tls.Certificate{cert}
+			handler = boshmicro.NewHTTPSHandlerWithTLSConfig(tlsConfig, mbusURL, p.logger, platform.GetFs(), dirProvider)
+		case boshfips.Enabled(env.GetFIPS()):
+			handler = boshmicro.NewHTTPSHandlerWithTLSConfig(boshfips.TLSConfig(), mbusURL, p.logger, platform.GetFs(), dirProvider)
+		default:
+			handler = boshmicro.NewHTTPSHandler(mbusURL, p.logger, platform.GetFs(), dirProvider)
+		}
 	default:
 		err = bosherr.Errorf("Message Bus Handler with scheme %s could not be found", mbusURL.Scheme)
 	}
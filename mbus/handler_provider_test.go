@@ -1,6 +1,7 @@
 package mbus_test
 
 import (
+	"io/ioutil"
 	gourl "net/url"
 	"reflect"
 
@@ -8,9 +9,11 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshfips "github.com/cloudfoundry/bosh-agent/fips"
 	. "github.com/cloudfoundry/bosh-agent/mbus"
 	"github.com/cloudfoundry/bosh-agent/micro"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -44,6 +47,14 @@ var _ = Describe("HandlerProvider", func() {
 			Expect(reflect.TypeOf(handler)).To(Equal(reflect.TypeOf(expectedHandler)))
 		})
 
+		It("refuses nats handler when FIPS is enforced", func() {
+			settingsService.Settings.Mbus = "nats://lol"
+			settingsService.Settings.Env.Bosh.FIPS = true
+			_, err := provider.Get(platform, dirProvider)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not supported in FIPS mode"))
+		})
+
 		It("returns https handler", func() {
 			url, err := gourl.Parse("https://lol")
 			Expect(err).ToNot(HaveOccurred())
@@ -54,6 +65,45 @@ var _ = Describe("HandlerProvider", func() {
 			Expect(handler).To(Equal(micro.NewHTTPSHandler(url, logger, platform.GetFs(), dirProvider)))
 		})
 
+		It("returns https handler restricted to FIPS TLS settings when FIPS is enforced", func() {
+			url, err := gourl.Parse("https://lol")
+			Expect(err).ToNot(HaveOccurred())
+
+			settingsService.Settings.Mbus = "https://lol"
+			settingsService.Settings.Env.Bosh.FIPS = true
+			handler, err := provider.Get(platform, dirProvider)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(handler).To(Equal(micro.NewHTTPSHandlerWithTLSConfig(boshfips.TLSConfig(), url, logger, platform.GetFs(), dirProvider)))
+		})
+
+		Context("when identity key is device-backed", func() {
+			BeforeEach(func() {
+				settingsService.Settings.Mbus = "https://lol"
+				settingsService.Settings.Env.Bosh.IdentityKey = boshsettings.IdentityKey{
+					Type:        "tpm",
+					KeyID:       "0x81010001",
+					SignCommand: []string{"/fake-tpm-sign"},
+				}
+			})
+
+			It("returns https handler with certificate whose key stays in the device", func() {
+				cert, err := ioutil.ReadFile("agent.cert")
+				Expect(err).ToNot(HaveOccurred())
+
+				platform.GetFs().WriteFile("agent.cert", cert)
+
+				handler, err := provider.Get(platform, dirProvider)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(handler).To(BeAssignableToTypeOf(micro.HTTPSHandler{}))
+			})
+
+			It("returns an error when agent certificate cannot be loaded", func() {
+				_, err := provider.Get(platform, dirProvider)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Loading device-backed agent identity"))
+			})
+		})
+
 		It("returns an error if not supported", func() {
 			settingsService.Settings.Mbus = "unknown-scheme://lol"
 			_, err := provider.Get(platform, dirProvider)
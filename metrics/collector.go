@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"strconv"
+	"strings"
+
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	collectorLogTag = "metricsCollector"
+
+	diskStatsPath  = "/proc/diskstats"
+	netDevPath     = "/proc/net/dev"
+	diskSectorSize = 512
+)
+
+type Collector interface {
+	Collect() ([]Metric, error)
+}
+
+type concreteCollector struct {
+	statsCollector boshstats.Collector
+	dirProvider    boshdirs.Provider
+	fs             boshsys.FileSystem
+	logger         boshlog.Logger
+}
+
+func NewCollector(
+	statsCollector boshstats.Collector,
+	dirProvider boshdirs.Provider,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) Collector {
+	return concreteCollector{
+		statsCollector: statsCollector,
+		dirProvider:    dirProvider,
+		fs:             fs,
+		logger:         logger,
+	}
+}
+
+func (c concreteCollector) Collect() ([]Metric, error) {
+	load, err := c.statsCollector.GetCPULoad()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting CPU Load")
+	}
+
+	cpuStats, err := c.statsCollector.GetCPUStats()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting CPU Stats")
+	}
+
+	memStats, err := c.statsCollector.GetMemStats()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting Memory Stats")
+	}
+
+	swapStats, err := c.statsCollector.GetSwapStats()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting Swap Stats")
+	}
+
+	diskMetrics, err := c.diskUsageMetrics()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting Disk Stats")
+	}
+
+	metrics := []Metric{
+		gauge("bosh_node_load1", "1m load average.", load.One),
+		gauge("bosh_node_load5", "5m load average.", load.Five),
+		gauge("bosh_node_load15", "15m load average.", load.Fifteen),
+		{
+			Name: "bosh_node_cpu_percent",
+			Help: "Percentage of CPU time spent in each mode during last collection interval.",
+			Type: TypeGauge,
+			Samples: []Sample{
+				{Labels: map[string]string{"mode": "user"}, Value: cpuStats.UserPercent().FractionOf100()},
+				{Labels: map[string]string{"mode": "sys"}, Value: cpuStats.SysPercent().FractionOf100()},
+				{Labels: map[string]string{"mode": "wait"}, Value: cpuStats.WaitPercent().FractionOf100()},
+			},
+		},
+		gauge("bosh_node_memory_used_bytes", "Memory used excluding buffers and cache.", float64(memStats.Used)),
+		gauge("bosh_node_memory_total_bytes", "Total memory.", float64(memStats.Total)),
+		gauge("bosh_node_swap_used_bytes", "Swap used.", float64(swapStats.Used)),
+		gauge("bosh_node_swap_total_bytes", "Total swap.", float64(swapStats.Total)),
+	}
+
+	metrics = append(metrics, diskMetrics...)
+	metrics = append(metrics, c.diskIOMetrics()...)
+	metrics = append(metrics, c.networkMetrics()...)
+
+	return metrics, nil
+}
+
+func (c concreteCollector) diskUsageMetrics() ([]Metric, error) {
+	usedBytes := Metric{Name: "bosh_node_disk_used_bytes", Help: "Disk space used.", Type: TypeGauge}
+	totalBytes := Metric{Name: "bosh_node_disk_total_bytes", Help: "Total disk space.", Type: TypeGauge}
+	usedInodes := Metric{Name: "bosh_node_disk_inodes_used", Help: "Inodes used.", Type: TypeGauge}
+	totalInodes := Metric{Name: "bosh_node_disk_inodes_total", Help: "Total inodes.", Type: TypeGauge}
+
+	disks := []struct{ path, name string }{
+		{"/", "system"},
+		{c.dirProvider.DataDir(), "ephemeral"},
+		{c.dirProvider.StoreDir(), "persistent"},
+	}
+
+	for _, disk := range disks {
+		stats, err := c.statsCollector.GetDiskStats(disk.path)
+		if err != nil {
+			// Ephemeral and persistent disks are optional
+			if disk.path == "/" {
+				return nil, bosherr.WrapError(err, "Getting Disk Stats for /")
+			}
+			continue
+		}
+
+		labels := map[string]string{"disk": disk.name}
+
+		// Stats collector reports disk usage in kilobytes
+		usedBytes.Samples = append(usedBytes.Samples, Sample{Labels: labels, Value: float64(stats.DiskUsage.Used * 1024)})
+		totalBytes.Samples = append(totalBytes.Samples, Sample{Labels: labels, Value: float64(stats.DiskUsage.Total * 1024)})
+		usedInodes.Samples = append(usedInodes.Samples, Sample{Labels: labels, Value: float64(stats.InodeUsage.Used)})
+		totalInodes.Samples = append(totalInodes.Samples, Sample{Labels: labels, Value: float64(stats.InodeUsage.Total)})
+	}
+
+	return []Metric{usedBytes, totalBytes, usedInodes, totalInodes}, nil
+}
+
+// diskIOMetrics skips counters when /proc/diskstats is not available
+func (c concreteCollector) diskIOMetrics() []Metric {
+	contents, err := c.fs.ReadFileString(diskStatsPath)
+	if err != nil {
+		c.logger.Debug(collectorLogTag, "Skipping disk counters: %s", err.Error())
+		return nil
+	}
+
+	reads := Metric{Name: "bosh_node_disk_reads_completed_total", Help: "Reads completed.", Type: TypeCounter}
+	readBytes := Metric{Name: "bosh_node_disk_read_bytes_total", Help: "Bytes read.", Type: TypeCounter}
+	writes := Metric{Name: "bosh_node_disk_writes_completed_total", Help: "Writes completed.", Type: TypeCounter}
+	writtenBytes := Metric{Name: "bosh_node_disk_written_bytes_total", Help: "Bytes written.", Type: TypeCounter}
+
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g. "   8       0 sda 4751 2 374210 2012 8329 3701 227656 6956 0 5140 8968"
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+
+		device := fields[2]
+		if strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram") {
+			continue
+		}
+
+		counters, err := parseCounters(fields[3:10])
+		if err != nil {
+			c.logger.Debug(collectorLogTag, "Skipping disk counters of %s: %s", device, err.Error())
+			continue
+		}
+
+		labels := map[string]string{"device": device}
+
+		reads.Samples = append(reads.Samples, Sample{Labels: labels, Value: counters[0]})
+		readBytes.Samples = append(readBytes.Samples, Sample{Labels: labels, Value: counters[2] * diskSectorSize})
+		writes.Samples = append(writes.Samples, Sample{Labels: labels, Value: counters[4]})
+		writtenBytes.Samples = append(writtenBytes.Samples, Sample{Labels: labels, Value: counters[6] * diskSectorSize})
+	}
+
+	return []Metric{reads, readBytes, writes, writtenBytes}
+}
+
+// networkMetrics skips counters when /proc/net/dev is not available
+func (c concreteCollector) networkMetrics() []Metric {
+	contents, err := c.fs.ReadFileString(netDevPath)
+	if err != nil {
+		c.logger.Debug(collectorLogTag, "Skipping network counters: %s", err.Error())
+		return nil
+	}
+
+	receiveBytes := Metric{Name: "bosh_node_network_receive_bytes_total", Help: "Bytes received.", Type: TypeCounter}
+	receivePackets := Metric{Name: "bosh_node_network_receive_packets_total", Help: "Packets received.", Type: TypeCounter}
+	receiveErrs := Metric{Name: "bosh_node_network_receive_errs_total", Help: "Receive errors.", Type: TypeCounter}
+	transmitBytes := Metric{Name: "bosh_node_network_transmit_bytes_total", Help: "Bytes transmitted.", Type: TypeCounter}
+	transmitPackets := Metric{Name: "bosh_node_network_transmit_packets_total", Help: "Packets transmitted.", Type: TypeCounter}
+	transmitErrs := Metric{Name: "bosh_node_network_transmit_errs_total", Help: "Transmit errors.", Type: TypeCounter}
+
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g. "  eth0: 1234 10 0 0 0 0 0 0 5678 20 0 0 0 0 0 0"; header lines have no counters
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		iface := strings.TrimSpace(parts[0])
+
+		fields := strings.Fields(parts[1])
+		if len(fields) < 11 {
+			continue
+		}
+
+		counters, err := parseCounters(fields[:11])
+		if err != nil {
+			c.logger.Debug(collectorLogTag, "Skipping network counters of %s: %s", iface, err.Error())
+			continue
+		}
+
+		labels := map[string]string{"interface": iface}
+
+		receiveBytes.Samples = append(receiveBytes.Samples, Sample{Labels: labels, Value: counters[0]})
+		receivePackets.Samples = append(receivePackets.Samples, Sample{Labels: labels, Value: counters[1]})
+		receiveErrs.Samples = append(receiveErrs.Samples, Sample{Labels: labels, Value: counters[2]})
+		transmitBytes.Samples = append(transmitBytes.Samples, Sample{Labels: labels, Value: counters[8]})
+		transmitPackets.Samples = append(transmitPackets.Samples, Sample{Labels: labels, Value: counters[9]})
+		transmitErrs.Samples = append(transmitErrs.Samples, Sample{Labels: labels, Value: counters[10]})
+	}
+
+	return []Metric{receiveBytes, receivePackets, receiveErrs, transmitBytes, transmitPackets, transmitErrs}
+}
+
+func parseCounters(fields []string) ([]float64, error) {
+	counters := make([]float64, len(fields))
+
+	for i, field := range fields {
+		counter, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing counter '%s'", field)
+		}
+		counters[i] = float64(counter)
+	}
+
+	return counters, nil
+}
+
+func gauge(name, help string, value float64) Metric {
+	return Metric{Name: name, Help: help, Type: TypeGauge, Samples: []Sample{{Value: value}}}
+}
@@ -0,0 +1,106 @@
+package metrics_test
+
+import (
+	"bytes"
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/metrics"
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Collector", func() {
+	var (
+		statsCollector *fakestats.FakeCollector
+		fs             *fakesys.FakeFileSystem
+		collector      Collector
+	)
+
+	collectText := func() string {
+		metrics, err := collector.Collect()
+		Expect(err).ToNot(HaveOccurred())
+
+		var buf bytes.Buffer
+		Expect(WriteText(&buf, metrics)).To(Succeed())
+
+		return buf.String()
+	}
+
+	BeforeEach(func() {
+		dirProvider := boshdirs.NewProvider("/fake/base/dir")
+
+		statsCollector = &fakestats.FakeCollector{
+			CPULoad:                 boshstats.CPULoad{One: 0.2, Five: 4.55, Fifteen: 1.123},
+			StartCollectingCPUStats: boshstats.CPUStats{User: 50, Sys: 25, Wait: 1, Total: 100},
+			MemStats:                boshstats.Usage{Used: 700 * 1024, Total: 1000 * 1024},
+			SwapStats:               boshstats.Usage{Used: 600 * 1024, Total: 1000 * 1024},
+			DiskStats: map[string]boshstats.DiskStats{
+				"/": {
+					DiskUsage:  boshstats.Usage{Used: 100, Total: 200},
+					InodeUsage: boshstats.Usage{Used: 50, Total: 500},
+				},
+				dirProvider.DataDir(): {
+					DiskUsage:  boshstats.Usage{Used: 15, Total: 20},
+					InodeUsage: boshstats.Usage{Used: 10, Total: 50},
+				},
+			},
+		}
+		statsCollector.StartCollecting(0, nil)
+
+		fs = fakesys.NewFakeFileSystem()
+		collector = NewCollector(statsCollector, dirProvider, fs, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("returns stats collected by stats collector", func() {
+		text := collectText()
+
+		Expect(text).To(ContainSubstring("# HELP bosh_node_load5 5m load average.\n# TYPE bosh_node_load5 gauge\nbosh_node_load5 4.55\n"))
+		Expect(text).To(ContainSubstring("bosh_node_cpu_percent{mode=\"user\"} 50\n"))
+		Expect(text).To(ContainSubstring("bosh_node_cpu_percent{mode=\"wait\"} 1\n"))
+		Expect(text).To(ContainSubstring("bosh_node_memory_used_bytes 716800\n"))
+		Expect(text).To(ContainSubstring("bosh_node_swap_total_bytes 1.024e+06\n"))
+		Expect(text).To(ContainSubstring("bosh_node_disk_used_bytes{disk=\"system\"} 102400\n"))
+		Expect(text).To(ContainSubstring("bosh_node_disk_inodes_total{disk=\"ephemeral\"} 50\n"))
+		Expect(text).ToNot(ContainSubstring("disk=\"persistent\""))
+	})
+
+	It("returns disk and network counters", func() {
+		fs.WriteFileString("/proc/diskstats", `   7       0 loop0 10 0 20 0 0 0 0 0 0 0 0
+   8       0 sda 4751 2 374210 2012 8329 3701 227656 6956 0 5140 8968
+`)
+		fs.WriteFileString("/proc/net/dev", `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+  eth0: 1234 10 1 0 0 0 0 0 5678 20 2 0 0 0 0 0
+`)
+
+		text := collectText()
+
+		Expect(text).To(ContainSubstring("# TYPE bosh_node_disk_reads_completed_total counter\nbosh_node_disk_reads_completed_total{device=\"sda\"} 4751\n"))
+		Expect(text).To(ContainSubstring("bosh_node_disk_written_bytes_total{device=\"sda\"} 1.16559872e+08\n"))
+		Expect(text).ToNot(ContainSubstring("loop0"))
+		Expect(text).To(ContainSubstring("bosh_node_network_receive_bytes_total{interface=\"eth0\"} 1234\n"))
+		Expect(text).To(ContainSubstring("bosh_node_network_transmit_errs_total{interface=\"eth0\"} 2\n"))
+	})
+
+	It("returns an error when system disk stats cannot be collected", func() {
+		delete(statsCollector.DiskStats, "/")
+
+		_, err := collector.Collect()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Getting Disk Stats for /"))
+	})
+
+	It("returns an error when memory stats cannot be collected", func() {
+		statsCollector.MemStatsErr = errors.New("fake-mem-err")
+
+		_, err := collector.Collect()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-mem-err"))
+	})
+})
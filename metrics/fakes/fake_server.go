@@ -0,0 +1,15 @@
+package fakes
+
+type FakeServer struct {
+	StartErr error
+
+	StopErr error
+}
+
+func (s *FakeServer) Start() error {
+	return s.StartErr
+}
+
+func (s *FakeServer) Stop() error {
+	return s.StopErr
+}
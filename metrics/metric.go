@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	TypeGauge   = "gauge"
+	TypeCounter = "counter"
+)
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type Metric struct {
+	Name    string
+	Help    string
+	Type    string
+	Samples []Sample
+}
+
+type Sample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// WriteText writes metrics in Prometheus text exposition format 0.0.4
+func WriteText(w io.Writer, metrics []Metric) error {
+	for _, metric := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.Name, metric.Help, metric.Name, metric.Type)
+		if err != nil {
+			return err
+		}
+
+		for _, sample := range metric.Samples {
+			_, err = fmt.Fprintf(w, "%s%s %s\n", metric.Name, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func formatLabels(labels map[string]string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf("%s=\"%s\"", name, labelValueEscaper.Replace(labels[name]))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	serverLogTag = "metricsServer"
+
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+type Server interface {
+	Start() error
+	Stop() error
+}
+
+type concreteServer struct {
+	options          boshsettings.Metrics
+	tlsConfig        *tls.Config
+	collector        Collector
+	listenerProvider func(protocol, address string) (net.Listener, error)
+	logger           boshlog.Logger
+
+	listener net.Listener
+	lock     sync.Mutex
+}
+
+// NewServer serves over https with given TLS config when metrics certificate is configured
+func NewServer(
+	options boshsettings.Metrics,
+	tlsConfig *tls.Config,
+	collector Collector,
+	listenerProvider func(protocol, address string) (net.Listener, error),
+	logger boshlog.Logger,
+) Server {
+	return &concreteServer{
+		options:          options,
+		tlsConfig:        tlsConfig,
+		collector:        collector,
+		listenerProvider: listenerProvider,
+		logger:           logger,
+	}
+}
+
+// Start serves metrics until server is stopped; returns right away when metrics are disabled
+func (s *concreteServer) Start() error {
+	if !s.options.Enabled {
+		return nil
+	}
+
+	port := s.options.ListenPort()
+
+	// Credentials would be sent in clear text without TLS
+	if s.credentialsConfigured() && len(s.options.Certificate) == 0 {
+		return bosherr.Error("Refusing to serve metrics with basic auth without TLS certificate")
+	}
+
+	s.lock.Lock()
+
+	listener, err := s.listenerProvider("tcp", ":"+strconv.Itoa(port))
+	if err != nil {
+		s.lock.Unlock()
+		return bosherr.WrapErrorf(err, "Listening on port %d", port)
+	}
+
+	if len(s.options.Certificate) > 0 {
+		cert, err := tls.X509KeyPair([]byte(s.options.Certificate), []byte(s.options.PrivateKey))
+		if err != nil {
+			listener.Close()
+			s.lock.Unlock()
+			return bosherr.WrapError(err, "Loading metrics certificate")
+		}
+
+		config := s.tlsConfig.Clone()
+		config.Certificates = []tls.Certificate{cert}
+
+		listener = tls.NewListener(listener, config)
+	}
+
+	s.listener = listener
+
+	// Should not defer unlock since serving blocks
+	s.lock.Unlock()
+
+	s.logger.Info(serverLogTag, "Serving metrics on port %d", port)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.handleMetrics)
+
+	return http.Serve(listener, mux)
+}
+
+func (s *concreteServer) Stop() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+
+	return nil
+}
+
+func (s *concreteServer) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if !s.authorized(r) {
+		w.Header().Set("WWW-Authenticate", `Basic realm="metrics"`)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	metrics, err := s.collector.Collect()
+	if err != nil {
+		s.logger.Error(serverLogTag, "Collecting metrics: %s", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var body bytes.Buffer
+
+	err = WriteText(&body, metrics)
+	if err != nil {
+		s.logger.Error(serverLogTag, "Formatting metrics: %s", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Write(body.Bytes())
+}
+
+func (s *concreteServer) credentialsConfigured() bool {
+	return len(s.options.Username) > 0 || len(s.options.Password) > 0
+}
+
+func (s *concreteServer) authorized(r *http.Request) bool {
+	if !s.credentialsConfigured() {
+		return true
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return false
+	}
+
+	usernameMatches := subtle.ConstantTimeCompare([]byte(username), []byte(s.options.Username)) == 1
+	passwordMatches := subtle.ConstantTimeCompare([]byte(password), []byte(s.options.Password)) == 1
+
+	return usernameMatches && passwordMatches
+}
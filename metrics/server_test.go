@@ -0,0 +1,215 @@
+package metrics_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/metrics"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type fakeCollector struct {
+	metrics []Metric
+	err     error
+}
+
+func (c fakeCollector) Collect() ([]Metric, error) {
+	return c.metrics, c.err
+}
+
+var _ = Describe("Server", func() {
+	var (
+		options   boshsettings.Metrics
+		tlsConfig *tls.Config
+		collector fakeCollector
+		server    Server
+		startErr  chan error
+	)
+
+	grabEphemeralPort := func() int {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		Expect(err).ToNot(HaveOccurred())
+
+		defer l.Close()
+
+		return l.Addr().(*net.TCPAddr).Port
+	}
+
+	start := func() {
+		server = NewServer(options, tlsConfig, collector, net.Listen, boshlog.NewLogger(boshlog.LevelNone))
+		startErr = make(chan error, 1)
+
+		go func() {
+			startErr <- server.Start()
+		}()
+	}
+
+	get := func(scheme string, configure func(*http.Request)) *http.Response {
+		client := &http.Client{
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+		}
+
+		req, err := http.NewRequest("GET", scheme+"://127.0.0.1:"+strconv.Itoa(options.Port)+"/metrics", nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		if configure != nil {
+			configure(req)
+		}
+
+		var resp *http.Response
+		Eventually(func() error {
+			resp, err = client.Do(req)
+			return err
+		}).Should(Succeed())
+
+		return resp
+	}
+
+	BeforeEach(func() {
+		options = boshsettings.Metrics{Enabled: true, Port: grabEphemeralPort()}
+		tlsConfig = &tls.Config{}
+		collector = fakeCollector{
+			metrics: []Metric{{Name: "fake_metric", Help: "Fake.", Type: TypeGauge, Samples: []Sample{{Value: 1}}}},
+		}
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Stop()
+		}
+	})
+
+	It("returns right away when metrics are disabled", func() {
+		options.Enabled = false
+		start()
+
+		Eventually(startErr).Should(Receive(BeNil()))
+	})
+
+	It("serves metrics in Prometheus text format", func() {
+		start()
+
+		resp := get("http", nil)
+		defer resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("text/plain; version=0.0.4; charset=utf-8"))
+
+		body, err := ioutil.ReadAll(resp.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(body)).To(Equal("# HELP fake_metric Fake.\n# TYPE fake_metric gauge\nfake_metric 1\n"))
+	})
+
+	It("responds with an error when metrics cannot be collected", func() {
+		collector.err = errors.New("fake-collect-err")
+		start()
+
+		resp := get("http", nil)
+		resp.Body.Close()
+
+		Expect(resp.StatusCode).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("returns an error when credentials are configured without certificate", func() {
+		options.Username = "fake-user"
+		options.Password = "fake-password"
+		start()
+
+		var err error
+		Eventually(startErr).Should(Receive(&err))
+		Expect(err.Error()).To(ContainSubstring("Refusing to serve metrics with basic auth without TLS certificate"))
+	})
+
+	Context("when certificate is configured", func() {
+		BeforeEach(func() {
+			key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+			Expect(err).ToNot(HaveOccurred())
+
+			template := &x509.Certificate{
+				SerialNumber: big.NewInt(1),
+				Subject:      pkix.Name{CommonName: "127.0.0.1"},
+				IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+				NotBefore:    time.Now().Add(-time.Hour),
+				NotAfter:     time.Now().Add(time.Hour),
+			}
+
+			der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+			Expect(err).ToNot(HaveOccurred())
+
+			keyDER, err := x509.MarshalECPrivateKey(key)
+			Expect(err).ToNot(HaveOccurred())
+
+			options.Certificate = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+			options.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}))
+		})
+
+		It("serves metrics over https", func() {
+			start()
+
+			resp := get("https", nil)
+			resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.TLS).ToNot(BeNil())
+		})
+
+		It("uses given TLS config", func() {
+			tlsConfig = &tls.Config{MinVersion: tls.VersionTLS13}
+			start()
+
+			resp := get("https", nil)
+			resp.Body.Close()
+
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.TLS.Version).To(Equal(uint16(tls.VersionTLS13)))
+		})
+
+		Context("when credentials are configured", func() {
+			BeforeEach(func() {
+				options.Username = "fake-user"
+				options.Password = "fake-password"
+			})
+
+			It("requires basic auth", func() {
+				start()
+
+				resp := get("https", nil)
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+				resp = get("https", func(req *http.Request) { req.SetBasicAuth("fake-user", "wrong-password") })
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+				resp = get("https", func(req *http.Request) { req.SetBasicAuth("fake-user", "fake-password") })
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+		})
+
+		It("returns an error when certificate is invalid", func() {
+			options.PrivateKey = "fake-key"
+			start()
+
+			var err error
+			Eventually(startErr).Should(Receive(&err))
+			Expect(err.Error()).To(ContainSubstring("Loading metrics certificate"))
+		})
+	})
+})
@@ -2,6 +2,7 @@ package micro
 
 import (
 	"bufio"
+	"crypto/tls"
 	"encoding/base64"
 	"io"
 	"io/ioutil"
@@ -40,6 +41,21 @@ func NewHTTPSHandler(
 	return
 }
 
+func NewHTTPSHandlerWithTLSConfig(
+	tlsConfig *tls.Config,
+	parsedURL *url.URL,
+	logger boshlog.Logger,
+	fs boshsys.FileSystem,
+	dirProvider boshdir.Provider,
+) (handler HTTPSHandler) {
+	handler.parsedURL = parsedURL
+	handler.logger = logger
+	handler.fs = fs
+	handler.dirProvider = dirProvider
+	handler.dispatcher = boshdispatcher.NewHTTPSDispatcherWithConfig(tlsConfig, parsedURL, logger)
+	return
+}
+
 func (h HTTPSHandler) Run(handlerFunc boshhandler.Func) error {
 	err := h.Start(handlerFunc)
 	if err != nil {
@@ -1,6 +1,9 @@
 package cert
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/pem"
 	"fmt"
 	"strings"
 	"time"
@@ -30,9 +33,25 @@ type Manager interface {
 	// The certs argument should contain zero or more X.509 certificates in PEM format
 	// concatenated together. Any text that is not between `-----BEGIN CERTIFICATE-----`
 	// and `-----END CERTIFICATE-----` lines is ignored.
-	UpdateCertificates(certs string) error
+	//
+	// Only certificates that were added or removed since the previous call are written
+	// or deleted; their fingerprints are returned so that rotations can be audited.
+	UpdateCertificates(certs string) (Changes, error)
+}
+
+// Changes are SHA-256 fingerprints (lower case hex of DER encoding) of certificates
+// that were added to or removed from the set of trusted certificates
+type Changes struct {
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+}
+
+func (c Changes) IsEmpty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0
 }
 
+const certFilePrefix = "bosh-trusted-cert-"
+
 type certManager struct {
 	fs            boshsys.FileSystem
 	runner        boshsys.CmdRunner
@@ -46,29 +65,32 @@ type certManager struct {
 	updateTimeout time.Duration
 }
 
-func NewUbuntuCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeout time.Duration, logger logger.Logger) Manager {
+// NewCertManager manages trusted certificates in the given trust store
+// so that distros only differ in their trust store
+func NewCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, trustStore TrustStore, timeout time.Duration, logger logger.Logger) Manager {
+	path := trustStore.Path
+	if !strings.HasSuffix(path, "/") {
+		path += "/"
+	}
+
 	return &certManager{
 		fs:            fs,
 		runner:        runner,
-		path:          "/usr/local/share/ca-certificates/",
-		updateCmdPath: "/usr/sbin/update-ca-certificates",
-		updateCmdArgs: []string{"-f"},
+		path:          path,
+		updateCmdPath: trustStore.UpdateCommand[0],
+		updateCmdArgs: trustStore.UpdateCommand[1:],
 		logger:        logger,
-		logTag:        "UbuntuCertManager",
+		logTag:        trustStore.Name + "CertManager",
 		updateTimeout: timeout,
 	}
 }
 
+func NewUbuntuCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeout time.Duration, logger logger.Logger) Manager {
+	return NewCertManager(fs, runner, UbuntuTrustStore, timeout, logger)
+}
+
 func NewCentOSCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeout time.Duration, logger logger.Logger) Manager {
-	return &certManager{
-		fs:            fs,
-		runner:        runner,
-		path:          "/etc/pki/ca-trust/source/anchors/",
-		updateCmdPath: "/usr/bin/update-ca-trust",
-		logger:        logger,
-		logTag:        "CentOSCertManager",
-		updateTimeout: timeout,
-	}
+	return NewCertManager(fs, runner, CentOSTrustStore, timeout, logger)
 }
 
 func NewDummyCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeout time.Duration, logger logger.Logger) Manager {
@@ -83,27 +105,26 @@ func NewDummyCertManager(fs boshsys.FileSystem, runner boshsys.CmdRunner, timeou
 	}
 }
 
-func (c *certManager) UpdateCertificates(certs string) error {
+func (c *certManager) UpdateCertificates(certs string) (Changes, error) {
 	c.logger.Info(c.logTag, "Running Update Certificate command")
 
+	changes := Changes{Added: []string{}, Removed: []string{}}
+
 	if c.updateCmdPath == "dummy" {
-		return nil
+		return changes, nil
 	}
 
-	deletedFilesCount, err := deleteFiles(c.fs, c.path, "bosh-trusted-cert-")
-	c.logger.Debug(c.logTag, "Deleted %d existing certificate files", deletedFilesCount)
+	filesChanged, err := c.convergeCertFiles(certs, &changes)
 	if err != nil {
-		return err
+		return changes, err
 	}
 
-	slicedCerts := splitCerts(certs)
-	for i, cert := range slicedCerts {
-		err := c.fs.WriteFileString(fmt.Sprintf("%sbosh-trusted-cert-%d.crt", c.path, i+1), cert)
-		if err != nil {
-			return err
-		}
+	c.logger.Debug(c.logTag, "Added %d and removed %d certificate files", len(changes.Added), len(changes.Removed))
+
+	if !filesChanged {
+		c.logger.Debug(c.logTag, "Trusted certificates did not change, skipping update")
+		return changes, nil
 	}
-	c.logger.Debug(c.logTag, "Wrote %d new certificate files", len(slicedCerts))
 
 	// For Ubuntu OS, update-ca-certificates occasionally hangs, which results
 	// in bosh-agent failure. A retry normally solves this issue. We kill the process
@@ -119,7 +140,7 @@ func (c *certManager) UpdateCertificates(certs string) error {
 
 			process, err := c.runner.RunComplexCommandAsync(command)
 			if err != nil {
-				return bosherr.WrapError(err, "Running command to update certificates with retries")
+				return changes, bosherr.WrapError(err, "Running command to update certificates with retries")
 			}
 
 			resultChannel := process.Wait()
@@ -133,23 +154,114 @@ func (c *certManager) UpdateCertificates(certs string) error {
 			case result := <-resultChannel:
 				if result.Error == nil {
 					c.logger.Debug(c.logTag, "Successfully updated new certificate files")
-					return nil
+					return changes, nil
 				}
 			}
 		}
 
-		return bosherr.Error("Updating certificates with retries")
+		return changes, bosherr.Error("Updating certificates with retries")
 	}
 
 	c.logger.Debug(c.logTag, "Try to update new certificate files without retry")
 
 	_, _, _, err = c.runner.RunCommand(c.updateCmdPath, c.updateCmdArgs...)
 	if err != nil {
-		return bosherr.WrapError(err, "Running command to update certificates without retries")
+		return changes, bosherr.WrapError(err, "Running command to update certificates without retries")
 	}
 
 	c.logger.Debug(c.logTag, "Successfully updated new certificate files.")
-	return nil
+	return changes, nil
+}
+
+// convergeCertFiles writes files of added certificates and deletes files of removed ones;
+// files named by index by previous agent versions are renamed after fingerprints of their certificates
+func (c *certManager) convergeCertFiles(certs string, changes *Changes) (bool, error) {
+	desiredCerts := map[string]string{}
+	desiredFingerprints := []string{}
+
+	for _, cert := range splitCerts(certs) {
+		fingerprint := certFingerprint(cert)
+		if _, found := desiredCerts[fingerprint]; !found {
+			desiredFingerprints = append(desiredFingerprints, fingerprint)
+		}
+		desiredCerts[fingerprint] = cert
+	}
+
+	existingFiles, err := c.fs.Glob(fmt.Sprintf("%s%s*", c.path, certFilePrefix))
+	if err != nil {
+		return false, bosherr.WrapError(err, "Glob command failed")
+	}
+
+	filesChanged := false
+	trustedCerts := map[string]bool{}
+	renamedCerts := map[string]bool{}
+	removedCerts := map[string]bool{}
+
+	for _, file := range existingFiles {
+		contents, err := c.fs.ReadFileString(file)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Reading %s", file)
+		}
+
+		fingerprint := certFingerprint(contents)
+		_, desired := desiredCerts[fingerprint]
+
+		if desired && file == c.certFilePath(fingerprint) {
+			trustedCerts[fingerprint] = true
+			continue
+		}
+
+		err = c.fs.RemoveAll(file)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "deleting %s failed", file)
+		}
+
+		filesChanged = true
+
+		if desired {
+			renamedCerts[fingerprint] = true
+		} else if !removedCerts[fingerprint] {
+			removedCerts[fingerprint] = true
+			changes.Removed = append(changes.Removed, fingerprint)
+		}
+	}
+
+	for _, fingerprint := range desiredFingerprints {
+		if trustedCerts[fingerprint] {
+			continue
+		}
+
+		err := c.fs.WriteFileString(c.certFilePath(fingerprint), desiredCerts[fingerprint])
+		if err != nil {
+			return false, err
+		}
+
+		filesChanged = true
+
+		if !renamedCerts[fingerprint] {
+			changes.Added = append(changes.Added, fingerprint)
+		}
+	}
+
+	return filesChanged, nil
+}
+
+func (c *certManager) certFilePath(fingerprint string) string {
+	return fmt.Sprintf("%s%s%s.crt", c.path, certFilePrefix, fingerprint)
+}
+
+// certFingerprint is a digest of DER encoding of the certificate
+// or of its text when it cannot be decoded
+func certFingerprint(cert string) string {
+	content := []byte(strings.TrimSpace(cert))
+
+	if block, _ := pem.Decode(content); block != nil {
+		content = block.Bytes
+	}
+
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
 }
 
 // SplitCerts returns a slice containing each PEM certificate in the given string.
@@ -23,6 +23,13 @@ qokoSBXzJCJTt2P681gyqBDr/hUYzqpoXUsOTRisScbEbaSv8hTiTeFJUMyNQAqn
 DtmvI8bXKxU=
 -----END CERTIFICATE-----`
 
+const cert2 string = `-----BEGIN CERTIFICATE-----
+MIIEJDCCAwygAwIBAgIJAPYjHNqGLtcdMA0GCSqGSIb3DQEBBQUAMGkxCzAJBgNV
+BAYTAkNBMRMwEQYDVQQIEwpTb21lLVN0YXRlMSEwHwYDVQQKExhJbnRlcm5ldCBX
+xU2nOvP9ZfLJpbx8yZDa3qE7rrWKhZvT0uEdMj9lkBJhTSaFQNBX5oB9jR5M2Lqk
+JlCsDXN5yHk=
+-----END CERTIFICATE-----`
+
 var _ = Describe("Certificate Management", func() {
 	var log logger.Logger
 	BeforeEach(func() {
@@ -100,6 +107,14 @@ var _ = Describe("Certificate Management", func() {
 		})
 	})
 
+	Describe("CertFingerprint", func() {
+		It("is a SHA-256 digest of DER encoding of the certificate", func() {
+			Expect(cert.CertFingerprint(cert1)).To(HaveLen(64))
+			Expect(cert.CertFingerprint(cert1)).To(Equal(cert.CertFingerprint("\n" + cert1 + "\n")))
+			Expect(cert.CertFingerprint(cert1)).ToNot(Equal(cert.CertFingerprint(cert2)))
+		})
+	})
+
 	Describe("DeleteFile()", func() {
 		var (
 			fakeFs *fakesys.FakeFileSystem
@@ -169,63 +184,109 @@ var _ = Describe("Certificate Management", func() {
 		)
 
 		SharedLinuxCertManagerExamples := func(certBasePath, certUpdateProgram string) {
-			It("writes 1 cert to a file", func() {
-				err := certManager.UpdateCertificates(cert1)
+			certPath := func(c string) string {
+				return fmt.Sprintf("%s/bosh-trusted-cert-%s.crt", certBasePath, cert.CertFingerprint(c))
+			}
+
+			setCertFiles := func(paths ...string) {
+				fakeFs.SetGlob(fmt.Sprintf("%s/bosh-trusted-cert-*", certBasePath), paths)
+			}
+
+			It("writes 1 cert to a file named by its fingerprint", func() {
+				changes, err := certManager.UpdateCertificates(cert1)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath))).To(BeTrue())
+				Expect(fakeFs.FileExists(certPath(cert1))).To(BeTrue())
+				Expect(changes).To(Equal(cert.Changes{Added: []string{cert.CertFingerprint(cert1)}, Removed: []string{}}))
 			})
 
 			It("writes each cert to its own file", func() {
-				certs := fmt.Sprintf("%s\n%s\n", cert1, cert1)
+				certs := fmt.Sprintf("%s\n%s\n", cert1, cert2)
 
-				err := certManager.UpdateCertificates(certs)
+				_, err := certManager.UpdateCertificates(certs)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath))).To(BeTrue())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-2.crt", certBasePath))).To(BeTrue())
+				Expect(fakeFs.FileExists(certPath(cert1))).To(BeTrue())
+				Expect(fakeFs.FileExists(certPath(cert2))).To(BeTrue())
 				Expect(countFiles(fakeFs, certBasePath)).To(Equal(2))
 			})
 
-			It("deletes all certs when passed an empty string", func() {
-				fakeFs.WriteFileString(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath), "goodbye")
-				fakeFs.SetGlob(fmt.Sprintf("%s/bosh-trusted-cert-*", certBasePath), []string{
-					fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath),
-				})
-				err := certManager.UpdateCertificates("")
+			It("writes duplicate certs once", func() {
+				certs := fmt.Sprintf("%s\n%s\n", cert1, cert1)
+
+				changes, err := certManager.UpdateCertificates(certs)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath))).To(BeFalse())
+				Expect(countFiles(fakeFs, certBasePath)).To(Equal(1))
+				Expect(changes.Added).To(Equal([]string{cert.CertFingerprint(cert1)}))
 			})
 
-			It("deletes exisitng cert files before writing new ones", func() {
-				certs := fmt.Sprintf("%s\n%s\n", cert1, cert1)
-				err := certManager.UpdateCertificates(certs)
+			It("deletes all certs when passed an empty string", func() {
+				fakeFs.WriteFileString(certPath(cert1), cert1)
+				setCertFiles(certPath(cert1))
+
+				changes, err := certManager.UpdateCertificates("")
 				Expect(err).NotTo(HaveOccurred())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath))).To(BeTrue())
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-2.crt", certBasePath))).To(BeTrue())
-				Expect(countFiles(fakeFs, certBasePath)).To(Equal(2))
+				Expect(fakeFs.FileExists(certPath(cert1))).To(BeFalse())
+				Expect(changes).To(Equal(cert.Changes{Added: []string{}, Removed: []string{cert.CertFingerprint(cert1)}}))
+			})
 
-				fakeFs.SetGlob(fmt.Sprintf("%s/bosh-trusted-cert-*", certBasePath), []string{
-					fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath),
-					fmt.Sprintf("%s/bosh-trusted-cert-2.crt", certBasePath),
-				})
-				certManager.UpdateCertificates(cert1)
-				Expect(fakeFs.FileExists(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath))).To(BeTrue())
+			It("only adds new certs and removes certs that are no longer trusted", func() {
+				fakeFs.WriteFileString(certPath(cert1), cert1)
+				setCertFiles(certPath(cert1))
+
+				changes, err := certManager.UpdateCertificates(cert2)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeFs.FileExists(certPath(cert1))).To(BeFalse())
+				Expect(fakeFs.FileExists(certPath(cert2))).To(BeTrue())
 				Expect(countFiles(fakeFs, certBasePath)).To(Equal(1))
+				Expect(changes).To(Equal(cert.Changes{
+					Added:   []string{cert.CertFingerprint(cert2)},
+					Removed: []string{cert.CertFingerprint(cert1)},
+				}))
+			})
+
+			It("keeps files of certs that are still trusted", func() {
+				fakeFs.WriteFileString(certPath(cert1), cert1)
+				setCertFiles(certPath(cert1))
+				fakeFs.WriteFileError = errors.New("NOT ALLOW")
+
+				changes, err := certManager.UpdateCertificates(cert1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(changes.IsEmpty()).To(BeTrue())
+			})
+
+			It("does not run update command when certs did not change", func() {
+				fakeFs.WriteFileString(certPath(cert1), cert1)
+				setCertFiles(certPath(cert1))
+
+				_, err := certManager.UpdateCertificates(cert1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCmdRunner.RunComplexCommands).To(BeEmpty())
+				Expect(fakeCmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("renames cert files named by index without reporting them as changed", func() {
+				legacyPath := fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath)
+				fakeFs.WriteFileString(legacyPath, cert1)
+				setCertFiles(legacyPath)
+
+				changes, err := certManager.UpdateCertificates(cert1)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeFs.FileExists(legacyPath)).To(BeFalse())
+				Expect(fakeFs.FileExists(certPath(cert1))).To(BeTrue())
+				Expect(changes.IsEmpty()).To(BeTrue())
 			})
 
 			It("returns an error when writing new cert files fails", func() {
 				fakeFs.WriteFileError = errors.New("NOT ALLOW")
-				err := certManager.UpdateCertificates(cert1)
+				_, err := certManager.UpdateCertificates(cert1)
 				Expect(err).To(HaveOccurred())
 			})
 
 			It("returns an error when deleting old certs fails", func() {
 				fakeFs.RemoveAllError = errors.New("NOT ALLOW")
-				fakeFs.WriteFileString(fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath), "goodbye")
-				fakeFs.SetGlob(fmt.Sprintf("%s/bosh-trusted-cert-*", certBasePath), []string{
-					fmt.Sprintf("%s/bosh-trusted-cert-1.crt", certBasePath),
-				})
+				fakeFs.WriteFileString(certPath(cert1), cert1)
+				setCertFiles(certPath(cert1))
 
-				err := certManager.UpdateCertificates("")
+				_, err := certManager.UpdateCertificates("")
 				Expect(err).To(HaveOccurred())
 			})
 		}
@@ -258,15 +319,15 @@ var _ = Describe("Certificate Management", func() {
 				fakeProcess2 = &fakesys.FakeProcess{WaitResult: fakeResult}
 				fakeProcess3 = &fakesys.FakeProcess{WaitResult: fakeResult}
 
-				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates -f", fakeProcess1)
-				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates -f", fakeProcess2)
-				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates -f", fakeProcess3)
+				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates", fakeProcess1)
+				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates", fakeProcess2)
+				fakeCmdRunner.AddProcess("/usr/sbin/update-ca-certificates", fakeProcess3)
 			})
 
 			SharedLinuxCertManagerExamples("/usr/local/share/ca-certificates", "/usr/sbin/update-ca-certificates")
 
 			It("updates certs", func() {
-				err := certManager.UpdateCertificates(cert1)
+				_, err := certManager.UpdateCertificates(cert1)
 
 				Expect(fakeProcess1.Waited).To(BeTrue())
 				Expect(fakeProcess1.TerminatedNicely).To(BeFalse())
@@ -280,7 +341,7 @@ var _ = Describe("Certificate Management", func() {
 
 				fakeProcess1.TerminatedNicelyCallBack = func(p *fakesys.FakeProcess) {}
 
-				err := certManager.UpdateCertificates(cert1)
+				_, err := certManager.UpdateCertificates(cert1)
 
 				Expect(fakeProcess1.Waited).To(BeTrue())
 				Expect(fakeProcess1.TerminatedNicely).To(BeTrue())
@@ -300,7 +361,7 @@ var _ = Describe("Certificate Management", func() {
 				fakeProcess2.TerminatedNicelyCallBack = func(p *fakesys.FakeProcess) {}
 				fakeProcess3.TerminatedNicelyCallBack = func(p *fakesys.FakeProcess) {}
 
-				err := certManager.UpdateCertificates(cert1)
+				_, err := certManager.UpdateCertificates(cert1)
 
 				Expect(fakeProcess1.Waited).To(BeTrue())
 				Expect(fakeProcess1.TerminatedNicely).To(BeTrue())
@@ -341,10 +402,34 @@ var _ = Describe("Certificate Management", func() {
 				})
 				certManager = cert.NewCentOSCertManager(fakeFs, fakeCmdRunner, 0, log)
 
-				err := certManager.UpdateCertificates(cert1)
+				_, err := certManager.UpdateCertificates(cert1)
 				Expect(err).To(HaveOccurred())
 			})
 		})
+
+		Context("configurable trust store", func() {
+			BeforeEach(func() {
+				fakeFs = fakesys.NewFakeFileSystem()
+				fakeCmdRunner = fakesys.NewFakeCmdRunner()
+				certManager = cert.NewCertManager(fakeFs, fakeCmdRunner, cert.SUSETrustStore, 0, log)
+			})
+
+			SharedLinuxCertManagerExamples("/etc/pki/trust/anchors", "/usr/sbin/update-ca-certificates")
+
+			It("runs update command with its arguments", func() {
+				trustStore := cert.TrustStore{
+					Name:          "Fake",
+					Path:          "/fake-anchors",
+					UpdateCommand: []string{"/fake-update", "--fake-arg"},
+				}
+				certManager = cert.NewCertManager(fakeFs, fakeCmdRunner, trustStore, 0, log)
+
+				_, err := certManager.UpdateCertificates(cert1)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fakeFs.FileExists(fmt.Sprintf("/fake-anchors/bosh-trusted-cert-%s.crt", cert.CertFingerprint(cert1)))).To(BeTrue())
+				Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{{"/fake-update", "--fake-arg"}}))
+			})
+		})
 	})
 })
 
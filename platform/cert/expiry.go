@@ -0,0 +1,40 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"time"
+)
+
+// Expiry is when a certificate that the agent relies on stops being valid
+type Expiry struct {
+	Fingerprint string
+	Subject     string
+	NotAfter    time.Time
+}
+
+// Expiries returns expiry of each X.509 certificate in PEM encoded certs;
+// text that cannot be parsed as a certificate is skipped
+func Expiries(certs string) []Expiry {
+	expiries := []Expiry{}
+
+	for _, cert := range splitCerts(certs) {
+		block, _ := pem.Decode([]byte(cert))
+		if block == nil {
+			continue
+		}
+
+		parsedCert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			continue
+		}
+
+		expiries = append(expiries, Expiry{
+			Fingerprint: certFingerprint(cert),
+			Subject:     parsedCert.Subject.CommonName,
+			NotAfter:    parsedCert.NotAfter,
+		})
+	}
+
+	return expiries
+}
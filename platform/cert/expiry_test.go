@@ -0,0 +1,46 @@
+package cert_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloudfoundry/bosh-agent/platform/cert"
+)
+
+var _ = Describe("Expiries", func() {
+	It("returns expiry of each certificate skipping text that is not a certificate", func() {
+		privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			Subject:      pkix.Name{CommonName: "fake-ca"},
+			NotBefore:    notAfter.Add(-time.Hour),
+			NotAfter:     notAfter,
+		}
+
+		der, err := x509.CreateCertificate(rand.Reader, template, template, privateKey.Public(), privateKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		validCert := string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+
+		expiries := cert.Expiries("junk\n" + validCert + cert1)
+		Expect(expiries).To(Equal([]cert.Expiry{
+			{
+				Fingerprint: cert.CertFingerprint(validCert),
+				Subject:     "fake-ca",
+				NotAfter:    notAfter,
+			},
+		}))
+	})
+})
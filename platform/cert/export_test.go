@@ -22,6 +22,10 @@ func SplitCerts(certs string) []string {
 	return splitCerts(certs)
 }
 
+func CertFingerprint(cert string) string {
+	return certFingerprint(cert)
+}
+
 func DeleteFiles(fs boshsys.FileSystem, path string, filenamePrefix string) (int, error) {
 	return deleteFiles(fs, path, filenamePrefix)
 }
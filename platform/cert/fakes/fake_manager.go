@@ -8,17 +8,18 @@ import (
 )
 
 type FakeManager struct {
-	UpdateCertificatesStub        func(certs string) error
+	UpdateCertificatesStub        func(certs string) (cert.Changes, error)
 	updateCertificatesMutex       sync.RWMutex
 	updateCertificatesArgsForCall []struct {
 		certs string
 	}
 	updateCertificatesReturns struct {
-		result1 error
+		result1 cert.Changes
+		result2 error
 	}
 }
 
-func (fake *FakeManager) UpdateCertificates(certs string) error {
+func (fake *FakeManager) UpdateCertificates(certs string) (cert.Changes, error) {
 	fake.updateCertificatesMutex.Lock()
 	fake.updateCertificatesArgsForCall = append(fake.updateCertificatesArgsForCall, struct {
 		certs string
@@ -27,7 +28,7 @@ func (fake *FakeManager) UpdateCertificates(certs string) error {
 	if fake.UpdateCertificatesStub != nil {
 		return fake.UpdateCertificatesStub(certs)
 	} else {
-		return fake.updateCertificatesReturns.result1
+		return fake.updateCertificatesReturns.result1, fake.updateCertificatesReturns.result2
 	}
 }
 
@@ -43,11 +44,12 @@ func (fake *FakeManager) UpdateCertificatesArgsForCall(i int) string {
 	return fake.updateCertificatesArgsForCall[i].certs
 }
 
-func (fake *FakeManager) UpdateCertificatesReturns(result1 error) {
+func (fake *FakeManager) UpdateCertificatesReturns(result1 cert.Changes, result2 error) {
 	fake.UpdateCertificatesStub = nil
 	fake.updateCertificatesReturns = struct {
-		result1 error
-	}{result1}
+		result1 cert.Changes
+		result2 error
+	}{result1, result2}
 }
 
 var _ cert.Manager = new(FakeManager)
@@ -0,0 +1,62 @@
+package cert
+
+// TrustStore describes where an OS keeps CA certificates trusted in addition
+// to pre-installed ones and how it regenerates its certificate bundle from them
+type TrustStore struct {
+	// Name is used in log tags, e.g. 'Ubuntu'
+	Name string
+
+	// Path is a directory that is scanned by UpdateCommand
+	Path string
+
+	// UpdateCommand is the program followed by its arguments
+	UpdateCommand []string
+}
+
+var (
+	UbuntuTrustStore = TrustStore{
+		Name:          "Ubuntu",
+		Path:          "/usr/local/share/ca-certificates/",
+		UpdateCommand: []string{"/usr/sbin/update-ca-certificates"},
+	}
+
+	CentOSTrustStore = TrustStore{
+		Name:          "CentOS",
+		Path:          "/etc/pki/ca-trust/source/anchors/",
+		UpdateCommand: []string{"/usr/bin/update-ca-trust"},
+	}
+
+	SUSETrustStore = TrustStore{
+		Name:          "SUSE",
+		Path:          "/etc/pki/trust/anchors/",
+		UpdateCommand: []string{"/usr/sbin/update-ca-certificates"},
+	}
+
+	AlpineTrustStore = TrustStore{
+		Name:          "Alpine",
+		Path:          "/usr/local/share/ca-certificates/",
+		UpdateCommand: []string{"/usr/sbin/update-ca-certificates"},
+	}
+
+	PhotonTrustStore = TrustStore{
+		Name:          "Photon",
+		Path:          "/etc/ssl/certs/",
+		UpdateCommand: []string{"/usr/bin/rehash_ca_certificates.sh"},
+	}
+
+	AmazonLinuxTrustStore = TrustStore{
+		Name:          "AmazonLinux",
+		Path:          "/etc/pki/ca-trust/source/anchors/",
+		UpdateCommand: []string{"/usr/bin/update-ca-trust", "extract"},
+	}
+)
+
+// TrustStores are known trust stores keyed by names used in agent configuration
+var TrustStores = map[string]TrustStore{
+	"ubuntu":       UbuntuTrustStore,
+	"centos":       CentOSTrustStore,
+	"suse":         SUSETrustStore,
+	"alpine":       AlpineTrustStore,
+	"photon":       PhotonTrustStore,
+	"amazon-linux": AmazonLinuxTrustStore,
+}
@@ -1,4 +1,4 @@
-package sigar_test
+package cgroup_test
 
 import (
 	. "github.com/onsi/ginkgo"
@@ -7,7 +7,7 @@ import (
 	"testing"
 )
 
-func TestGosigar(t *testing.T) {
+func TestCgroup(t *testing.T) {
 	RegisterFailHandler(Fail)
-	RunSpecs(t, "Gosigar Suite")
+	RunSpecs(t, "Cgroup Suite")
 }
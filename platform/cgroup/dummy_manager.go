@@ -0,0 +1,31 @@
+package cgroup
+
+type dummyManager struct{}
+
+func NewDummyManager() Manager {
+	return dummyManager{}
+}
+
+func (m dummyManager) Version() Version {
+	return Version2
+}
+
+func (m dummyManager) CreateGroup(name string) error {
+	return nil
+}
+
+func (m dummyManager) DeleteGroup(name string) error {
+	return nil
+}
+
+func (m dummyManager) ApplyLimits(name string, limits Limits) error {
+	return nil
+}
+
+func (m dummyManager) AddProcess(name string, pid int) error {
+	return nil
+}
+
+func (m dummyManager) ProcsPaths(name string) []string {
+	return nil
+}
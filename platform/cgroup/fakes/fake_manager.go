@@ -0,0 +1,60 @@
+package fakes
+
+import (
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+)
+
+type FakeManager struct {
+	VersionVersion boshcgroup.Version
+
+	CreateGroupNames []string
+	CreateGroupErr   error
+
+	DeleteGroupNames []string
+	DeleteGroupErr   error
+
+	ApplyLimitsLimits map[string]boshcgroup.Limits
+	ApplyLimitsErr    error
+
+	AddProcessPids map[string][]int
+	AddProcessErr  error
+
+	ProcsPathsPaths map[string][]string
+}
+
+func NewFakeManager() *FakeManager {
+	return &FakeManager{
+		VersionVersion:    boshcgroup.Version2,
+		ApplyLimitsLimits: map[string]boshcgroup.Limits{},
+		AddProcessPids:    map[string][]int{},
+		ProcsPathsPaths:   map[string][]string{},
+	}
+}
+
+func (m *FakeManager) Version() boshcgroup.Version {
+	return m.VersionVersion
+}
+
+func (m *FakeManager) CreateGroup(name string) error {
+	m.CreateGroupNames = append(m.CreateGroupNames, name)
+	return m.CreateGroupErr
+}
+
+func (m *FakeManager) DeleteGroup(name string) error {
+	m.DeleteGroupNames = append(m.DeleteGroupNames, name)
+	return m.DeleteGroupErr
+}
+
+func (m *FakeManager) ApplyLimits(name string, limits boshcgroup.Limits) error {
+	m.ApplyLimitsLimits[name] = limits
+	return m.ApplyLimitsErr
+}
+
+func (m *FakeManager) AddProcess(name string, pid int) error {
+	m.AddProcessPids[name] = append(m.AddProcessPids[name], pid)
+	return m.AddProcessErr
+}
+
+func (m *FakeManager) ProcsPaths(name string) []string {
+	return m.ProcsPathsPaths[name]
+}
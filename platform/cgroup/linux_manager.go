@@ -0,0 +1,253 @@
+package cgroup
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	groupPermissions = os.FileMode(0755)
+	boshGroupName    = "bosh"
+
+	maxCPUShares = 262144
+	minCPUShares = 2
+	maxCPUWeight = 10000
+
+	cpuPeriodMicros = 100000
+)
+
+var v1Controllers = []string{"memory", "cpu", "cpuacct", "pids"}
+
+type linuxManager struct {
+	fs     boshsys.FileSystem
+	root   string
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxManager(fs boshsys.FileSystem, root string, logger boshlog.Logger) Manager {
+	return linuxManager{
+		fs:     fs,
+		root:   root,
+		logger: logger,
+		logTag: "cgroupManager",
+	}
+}
+
+func (m linuxManager) Version() Version {
+	if m.fs.FileExists(path.Join(m.root, "cgroup.controllers")) {
+		return Version2
+	}
+	return Version1
+}
+
+func (m linuxManager) CreateGroup(name string) error {
+	if m.Version() == Version2 {
+		return m.createUnifiedGroup(name)
+	}
+
+	for _, controller := range v1Controllers {
+		groupPath := m.v1GroupPath(controller, name)
+
+		err := m.fs.MkdirAll(groupPath, groupPermissions)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating cgroup %s", groupPath)
+		}
+	}
+
+	return nil
+}
+
+func (m linuxManager) createUnifiedGroup(name string) error {
+	boshGroupPath := path.Join(m.root, boshGroupName)
+
+	err := m.fs.MkdirAll(path.Join(boshGroupPath, name), groupPermissions)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating cgroup %s", path.Join(boshGroupPath, name))
+	}
+
+	// Controllers must be delegated by every ancestor before
+	// their interface files show up in the child group
+	for _, parent := range []string{m.root, boshGroupPath} {
+		subtreeControlPath := path.Join(parent, "cgroup.subtree_control")
+
+		err = m.fs.WriteFileString(subtreeControlPath, "+cpu +memory +pids")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Enabling controllers in %s", subtreeControlPath)
+		}
+	}
+
+	return nil
+}
+
+func (m linuxManager) DeleteGroup(name string) error {
+	var groupPaths []string
+
+	if m.Version() == Version2 {
+		groupPaths = []string{m.v2GroupPath(name)}
+	} else {
+		for _, controller := range v1Controllers {
+			groupPaths = append(groupPaths, m.v1GroupPath(controller, name))
+		}
+	}
+
+	for _, groupPath := range groupPaths {
+		err := m.fs.RemoveAll(groupPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing cgroup %s", groupPath)
+		}
+	}
+
+	return nil
+}
+
+func (m linuxManager) ApplyLimits(name string, limits Limits) error {
+	m.logger.Debug(m.logTag, "Applying limits %+v to cgroup %s", limits, name)
+
+	if m.Version() == Version2 {
+		groupPath := m.v2GroupPath(name)
+
+		memoryMax := "max"
+		if limits.MemoryBytes > 0 {
+			memoryMax = strconv.FormatUint(limits.MemoryBytes, 10)
+		}
+
+		pidsMax := "max"
+		if limits.MaxPIDs > 0 {
+			pidsMax = strconv.FormatUint(limits.MaxPIDs, 10)
+		}
+
+		cpuMax := fmt.Sprintf("max %d", cpuPeriodMicros)
+		if limits.CPUQuotaPercent > 0 {
+			cpuMax = fmt.Sprintf("%d %d", cpuQuotaMicros(limits.CPUQuotaPercent), cpuPeriodMicros)
+		}
+
+		files := map[string]string{
+			"memory.max": memoryMax,
+			"pids.max":   pidsMax,
+			"cpu.max":    cpuMax,
+		}
+
+		if limits.CPUShares > 0 {
+			files["cpu.weight"] = strconv.FormatUint(cpuSharesToWeight(limits.CPUShares), 10)
+		}
+
+		return m.writeFiles(groupPath, files)
+	}
+
+	memoryLimit := "-1"
+	if limits.MemoryBytes > 0 {
+		memoryLimit = strconv.FormatUint(limits.MemoryBytes, 10)
+	}
+
+	err := m.writeFiles(m.v1GroupPath("memory", name), map[string]string{"memory.limit_in_bytes": memoryLimit})
+	if err != nil {
+		return err
+	}
+
+	pidsMax := "max"
+	if limits.MaxPIDs > 0 {
+		pidsMax = strconv.FormatUint(limits.MaxPIDs, 10)
+	}
+
+	err = m.writeFiles(m.v1GroupPath("pids", name), map[string]string{"pids.max": pidsMax})
+	if err != nil {
+		return err
+	}
+
+	cpuQuota := "-1"
+	if limits.CPUQuotaPercent > 0 {
+		cpuQuota = strconv.FormatUint(cpuQuotaMicros(limits.CPUQuotaPercent), 10)
+	}
+
+	cpuFiles := map[string]string{
+		"cpu.cfs_period_us": strconv.Itoa(cpuPeriodMicros),
+		"cpu.cfs_quota_us":  cpuQuota,
+	}
+
+	if limits.CPUShares > 0 {
+		cpuFiles["cpu.shares"] = strconv.FormatUint(limits.CPUShares, 10)
+	}
+
+	// Period has to be written before quota that is validated against it
+	for _, fileName := range []string{"cpu.cfs_period_us", "cpu.cfs_quota_us", "cpu.shares"} {
+		content, found := cpuFiles[fileName]
+		if !found {
+			continue
+		}
+
+		err = m.writeFiles(m.v1GroupPath("cpu", name), map[string]string{fileName: content})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m linuxManager) AddProcess(name string, pid int) error {
+	for _, procsPath := range m.ProcsPaths(name) {
+		err := m.fs.WriteFileString(procsPath, strconv.Itoa(pid))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing %s", procsPath)
+		}
+	}
+
+	return nil
+}
+
+func (m linuxManager) ProcsPaths(name string) []string {
+	if m.Version() == Version2 {
+		return []string{path.Join(m.v2GroupPath(name), "cgroup.procs")}
+	}
+
+	var procsPaths []string
+	for _, controller := range v1Controllers {
+		procsPaths = append(procsPaths, path.Join(m.v1GroupPath(controller, name), "cgroup.procs"))
+	}
+
+	return procsPaths
+}
+
+func (m linuxManager) v1GroupPath(controller, name string) string {
+	return path.Join(m.root, controller, boshGroupName, name)
+}
+
+func (m linuxManager) v2GroupPath(name string) string {
+	return path.Join(m.root, boshGroupName, name)
+}
+
+func (m linuxManager) writeFiles(groupPath string, files map[string]string) error {
+	for fileName, content := range files {
+		filePath := path.Join(groupPath, fileName)
+
+		err := m.fs.WriteFileString(filePath, content)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing %s", filePath)
+		}
+	}
+
+	return nil
+}
+
+func cpuQuotaMicros(percent uint64) uint64 {
+	return percent * cpuPeriodMicros / 100
+}
+
+// cpuSharesToWeight maps cpu.shares [2-262144] onto cpu.weight [1-10000]
+// the same way runc and systemd do
+func cpuSharesToWeight(shares uint64) uint64 {
+	if shares < minCPUShares {
+		shares = minCPUShares
+	}
+	if shares > maxCPUShares {
+		shares = maxCPUShares
+	}
+	return 1 + ((shares-minCPUShares)*(maxCPUWeight-1))/(maxCPUShares-minCPUShares)
+}
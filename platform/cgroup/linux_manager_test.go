@@ -0,0 +1,170 @@
+package cgroup_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxManager", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		manager Manager
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		manager = NewLinuxManager(fs, "/fake-cgroup", boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	readFile := func(path string) string {
+		contents, err := fs.ReadFileString(path)
+		Expect(err).NotTo(HaveOccurred())
+		return contents
+	}
+
+	Context("when host uses the unified (v2) hierarchy", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/fake-cgroup/cgroup.controllers", "cpuset cpu io memory pids")
+		})
+
+		It("reports version 2", func() {
+			Expect(manager.Version()).To(Equal(Version2))
+		})
+
+		Describe("CreateGroup", func() {
+			It("creates group under bosh cgroup and delegates controllers", func() {
+				err := manager.CreateGroup("fake-job")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fs.GetFileTestStat("/fake-cgroup/bosh/fake-job").FileType).To(Equal(fakesys.FakeFileTypeDir))
+				Expect(readFile("/fake-cgroup/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+				Expect(readFile("/fake-cgroup/bosh/cgroup.subtree_control")).To(Equal("+cpu +memory +pids"))
+			})
+
+			It("returns error if delegating controllers fails", func() {
+				fs.WriteFileError = errors.New("fake-write-err")
+
+				err := manager.CreateGroup("fake-job")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+			})
+		})
+
+		Describe("ApplyLimits", func() {
+			It("writes memory.max, pids.max and cpu.weight", func() {
+				err := manager.ApplyLimits("fake-job", Limits{MemoryBytes: 1024, CPUShares: 1024, MaxPIDs: 100})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("/fake-cgroup/bosh/fake-job/memory.max")).To(Equal("1024"))
+				Expect(readFile("/fake-cgroup/bosh/fake-job/pids.max")).To(Equal("100"))
+				Expect(readFile("/fake-cgroup/bosh/fake-job/cpu.weight")).To(Equal("39"))
+			})
+
+			It("writes cpu.max with quota per period", func() {
+				err := manager.ApplyLimits("fake-job", Limits{CPUQuotaPercent: 150})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("/fake-cgroup/bosh/fake-job/cpu.max")).To(Equal("150000 100000"))
+			})
+
+			It("removes limits when they are not set", func() {
+				err := manager.ApplyLimits("fake-job", Limits{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("/fake-cgroup/bosh/fake-job/memory.max")).To(Equal("max"))
+				Expect(readFile("/fake-cgroup/bosh/fake-job/pids.max")).To(Equal("max"))
+				Expect(readFile("/fake-cgroup/bosh/fake-job/cpu.max")).To(Equal("max 100000"))
+				Expect(fs.FileExists("/fake-cgroup/bosh/fake-job/cpu.weight")).To(BeFalse())
+			})
+		})
+
+		Describe("AddProcess", func() {
+			It("writes pid to cgroup.procs", func() {
+				err := manager.AddProcess("fake-job", 123)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(readFile("/fake-cgroup/bosh/fake-job/cgroup.procs")).To(Equal("123"))
+			})
+		})
+
+		Describe("ProcsPaths", func() {
+			It("returns cgroup.procs of the group", func() {
+				Expect(manager.ProcsPaths("fake-job")).To(Equal([]string{"/fake-cgroup/bosh/fake-job/cgroup.procs"}))
+			})
+		})
+
+		Describe("DeleteGroup", func() {
+			It("removes the group", func() {
+				fs.MkdirAll("/fake-cgroup/bosh/fake-job", 0755)
+
+				err := manager.DeleteGroup("fake-job")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fs.FileExists("/fake-cgroup/bosh/fake-job")).To(BeFalse())
+			})
+		})
+	})
+
+	Context("when host uses the legacy (v1) hierarchy", func() {
+		It("reports version 1", func() {
+			Expect(manager.Version()).To(Equal(Version1))
+		})
+
+		Describe("CreateGroup", func() {
+			It("creates group under each controller", func() {
+				err := manager.CreateGroup("fake-job")
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, controller := range []string{"memory", "cpu", "cpuacct", "pids"} {
+					Expect(fs.GetFileTestStat("/fake-cgroup/" + controller + "/bosh/fake-job").FileType).To(Equal(fakesys.FakeFileTypeDir))
+				}
+			})
+		})
+
+		Describe("ApplyLimits", func() {
+			It("writes per-controller limit files", func() {
+				err := manager.ApplyLimits("fake-job", Limits{MemoryBytes: 1024, CPUShares: 512, CPUQuotaPercent: 50, MaxPIDs: 100})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("/fake-cgroup/memory/bosh/fake-job/memory.limit_in_bytes")).To(Equal("1024"))
+				Expect(readFile("/fake-cgroup/pids/bosh/fake-job/pids.max")).To(Equal("100"))
+				Expect(readFile("/fake-cgroup/cpu/bosh/fake-job/cpu.shares")).To(Equal("512"))
+				Expect(readFile("/fake-cgroup/cpu/bosh/fake-job/cpu.cfs_period_us")).To(Equal("100000"))
+				Expect(readFile("/fake-cgroup/cpu/bosh/fake-job/cpu.cfs_quota_us")).To(Equal("50000"))
+			})
+
+			It("removes cpu quota when it is not set", func() {
+				err := manager.ApplyLimits("fake-job", Limits{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(readFile("/fake-cgroup/cpu/bosh/fake-job/cpu.cfs_quota_us")).To(Equal("-1"))
+			})
+		})
+
+		Describe("AddProcess", func() {
+			It("writes pid to cgroup.procs of each controller", func() {
+				err := manager.AddProcess("fake-job", 123)
+				Expect(err).NotTo(HaveOccurred())
+
+				for _, controller := range []string{"memory", "cpu", "cpuacct", "pids"} {
+					Expect(readFile("/fake-cgroup/" + controller + "/bosh/fake-job/cgroup.procs")).To(Equal("123"))
+				}
+			})
+		})
+
+		Describe("ProcsPaths", func() {
+			It("returns cgroup.procs of each controller", func() {
+				Expect(manager.ProcsPaths("fake-job")).To(Equal([]string{
+					"/fake-cgroup/memory/bosh/fake-job/cgroup.procs",
+					"/fake-cgroup/cpu/bosh/fake-job/cgroup.procs",
+					"/fake-cgroup/cpuacct/bosh/fake-job/cgroup.procs",
+					"/fake-cgroup/pids/bosh/fake-job/cgroup.procs",
+				}))
+			})
+		})
+	})
+})
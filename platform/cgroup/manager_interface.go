@@ -0,0 +1,42 @@
+package cgroup
+
+type Version int
+
+const (
+	// Version1 is the legacy per-controller hierarchy (e.g. /sys/fs/cgroup/memory)
+	Version1 Version = 1
+
+	// Version2 is the unified hierarchy used by cgroup v2-only hosts (e.g. Ubuntu 22.04+)
+	Version2 Version = 2
+)
+
+type Limits struct {
+	// Zero means no limit
+	MemoryBytes uint64
+
+	// Relative CPU weight using cgroup v1 cpu.shares semantics (2-262144);
+	// converted to cpu.weight on the unified hierarchy. Zero keeps the default.
+	CPUShares uint64
+
+	// Hard cap in percent of a single CPU, e.g. 150 allows 1.5 CPUs. Zero means no limit
+	CPUQuotaPercent uint64
+
+	// Zero means no limit
+	MaxPIDs uint64
+}
+
+// Manager hides the differences between cgroup v1 and v2 hierarchies
+// from the rest of the agent. Group names are relative to the bosh cgroup.
+type Manager interface {
+	Version() Version
+
+	CreateGroup(name string) error
+	DeleteGroup(name string) error
+
+	ApplyLimits(name string, limits Limits) error
+	AddProcess(name string, pid int) error
+
+	// ProcsPaths returns cgroup.procs files a process writes its own pid to
+	// so that it and processes it forks afterwards join the group
+	ProcsPaths(name string) []string
+}
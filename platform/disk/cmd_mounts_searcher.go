@@ -31,10 +31,16 @@ func (s cmdMountsSearcher) SearchMounts() ([]Mount, error) {
 
 		mountFields := strings.Fields(mountEntry)
 
-		mounts = append(mounts, Mount{
+		mount := Mount{
 			PartitionPath: mountFields[0],
 			MountPoint:    mountFields[2],
-		})
+		}
+
+		if len(mountFields) > 5 {
+			mount.Options = strings.Split(strings.Trim(mountFields[5], "()"), ",")
+		}
+
+		mounts = append(mounts, mount)
 	}
 
 	return mounts, nil
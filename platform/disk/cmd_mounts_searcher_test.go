@@ -34,10 +34,10 @@ none on /tmp/warden/cgroup type tmpfs (rw)`,
 				mounts, err := searcher.SearchMounts()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(mounts).To(Equal([]Mount{
-					Mount{PartitionPath: "devpts", MountPoint: "/dev/pts"},
-					Mount{PartitionPath: "tmpfs", MountPoint: "/run"},
-					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot"},
-					Mount{PartitionPath: "none", MountPoint: "/tmp/warden/cgroup"},
+					Mount{PartitionPath: "devpts", MountPoint: "/dev/pts", Options: []string{"rw", "noexec", "nosuid", "gid=5", "mode=0620"}},
+					Mount{PartitionPath: "tmpfs", MountPoint: "/run", Options: []string{"rw", "noexec", "nosuid", "size=10%", "mode=0755"}},
+					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot", Options: []string{"rw"}},
+					Mount{PartitionPath: "none", MountPoint: "/tmp/warden/cgroup", Options: []string{"rw"}},
 				}))
 			})
 
@@ -54,8 +54,8 @@ tmpfs on /run type tmpfs (rw,noexec,nosuid,size=10%,mode=0755)
 				mounts, err := searcher.SearchMounts()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(mounts).To(Equal([]Mount{
-					Mount{PartitionPath: "tmpfs", MountPoint: "/run"},
-					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot"},
+					Mount{PartitionPath: "tmpfs", MountPoint: "/run", Options: []string{"rw", "noexec", "nosuid", "size=10%", "mode=0755"}},
+					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot", Options: []string{"rw"}},
 				}))
 			})
 		})
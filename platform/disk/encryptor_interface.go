@@ -0,0 +1,18 @@
+package disk
+
+type Encryptor interface {
+	// Open makes sure that the device is a LUKS volume, formatting it with
+	// the key when it is not, and maps it to a plain device with given name.
+	// Returns path of the mapped device.
+	Open(devicePath, name, key string) (string, error)
+
+	// Resize grows the mapped device to the size of the underlying device.
+	Resize(name, key string) error
+
+	// Close removes the mapping; closing a mapping that is not open is a no-op.
+	Close(name string) error
+
+	IsOpen(name string) (bool, error)
+
+	Path(name string) string
+}
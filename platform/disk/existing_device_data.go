@@ -0,0 +1,54 @@
+package disk
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+var (
+	blkidTypeRegexp           = regexp.MustCompile(` TYPE="([^"]+)"`)
+	blkidPartitionTableRegexp = regexp.MustCompile(` PTTYPE="([^"]+)"`)
+)
+
+// existingDeviceData describes data found on a device, e.g. "ext4 filesystem",
+// "dos partition table" or "partitions"; returns empty string when device holds no data.
+// Devices with data must never be wiped, e.g. persistent disks formatted before
+// LVM, ZFS or encryption was enabled
+func existingDeviceData(runner boshsys.CmdRunner, devicePath string) (string, error) {
+	stdout, stderr, exitStatus, err := runner.RunCommand("blkid", "-p", devicePath)
+	if err != nil {
+		// blkid exits with 2 when it finds nothing on device
+		if exitStatus != 2 || stderr != "" {
+			return "", bosherr.WrapErrorf(err, "Probing %s", devicePath)
+		}
+
+		stdout = ""
+	}
+
+	if match := blkidTypeRegexp.FindStringSubmatch(stdout); match != nil {
+		return fmt.Sprintf("%s filesystem", match[1]), nil
+	}
+
+	if match := blkidPartitionTableRegexp.FindStringSubmatch(stdout); match != nil {
+		return fmt.Sprintf("%s partition table", match[1]), nil
+	}
+
+	stdout, _, _, err = runner.RunCommand("lsblk", "-n", "-r", "-o", "TYPE", devicePath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Listing partitions of %s", devicePath)
+	}
+
+	// First line describes the device itself which may be a partition
+	blockTypes := strings.Fields(stdout)
+	for i, blockType := range blockTypes {
+		if i > 0 && blockType == "part" {
+			return "partitions", nil
+		}
+	}
+
+	return "", nil
+}
@@ -12,6 +12,11 @@ type FakeDiskManager struct {
 	FakeMounter               *FakeMounter
 	FakeMountsSearcher        *FakeMountsSearcher
 	FakeRootDevicePartitioner *FakePartitioner
+	FakeLogicalVolumeManager  *FakeLogicalVolumeManager
+	FakeZFSPoolManager        *FakeZFSPoolManager
+	FakeEncryptor             *FakeEncryptor
+	FakeRAIDManager           *FakeRAIDManager
+	FakeFilesystemChecker     *FakeFilesystemChecker
 	FakeDiskUtil              *fakedevutil.FakeDeviceUtil
 	DiskUtilDiskPath          string
 	PartedPartitionerCalled   bool
@@ -25,6 +30,11 @@ func NewFakeDiskManager() *FakeDiskManager {
 		FakeMounter:               &FakeMounter{},
 		FakeMountsSearcher:        &FakeMountsSearcher{},
 		FakeRootDevicePartitioner: NewFakePartitioner(),
+		FakeLogicalVolumeManager:  NewFakeLogicalVolumeManager(),
+		FakeZFSPoolManager:        NewFakeZFSPoolManager(),
+		FakeEncryptor:             NewFakeEncryptor(),
+		FakeRAIDManager:           NewFakeRAIDManager(),
+		FakeFilesystemChecker:     NewFakeFilesystemChecker(),
 		FakeDiskUtil:              fakedevutil.NewFakeDeviceUtil(),
 		PartedPartitionerCalled:   false,
 		PartitionerCalled:         false,
@@ -57,6 +67,26 @@ func (m *FakeDiskManager) GetMountsSearcher() boshdisk.MountsSearcher {
 	return m.FakeMountsSearcher
 }
 
+func (m *FakeDiskManager) GetLogicalVolumeManager() boshdisk.LogicalVolumeManager {
+	return m.FakeLogicalVolumeManager
+}
+
+func (m *FakeDiskManager) GetZFSPoolManager() boshdisk.ZFSPoolManager {
+	return m.FakeZFSPoolManager
+}
+
+func (m *FakeDiskManager) GetEncryptor() boshdisk.Encryptor {
+	return m.FakeEncryptor
+}
+
+func (m *FakeDiskManager) GetRAIDManager() boshdisk.RAIDManager {
+	return m.FakeRAIDManager
+}
+
+func (m *FakeDiskManager) GetFilesystemChecker() boshdisk.FilesystemChecker {
+	return m.FakeFilesystemChecker
+}
+
 func (m *FakeDiskManager) GetDiskUtil(diskPath string) boshdevutil.DeviceUtil {
 	m.DiskUtilDiskPath = diskPath
 	return m.FakeDiskUtil
@@ -0,0 +1,55 @@
+package fakes
+
+import (
+	"path"
+)
+
+type FakeEncryptor struct {
+	OpenDevicePaths []string
+	OpenName        string
+	OpenKey         string
+	OpenErr         error
+
+	ResizeName string
+	ResizeKey  string
+	ResizeErr  error
+
+	CloseNames []string
+	CloseErr   error
+
+	IsOpenResult bool
+	IsOpenErr    error
+}
+
+func NewFakeEncryptor() *FakeEncryptor {
+	return &FakeEncryptor{}
+}
+
+func (e *FakeEncryptor) Open(devicePath, name, key string) (string, error) {
+	e.OpenDevicePaths = append(e.OpenDevicePaths, devicePath)
+	e.OpenName = name
+	e.OpenKey = key
+	if e.OpenErr != nil {
+		return "", e.OpenErr
+	}
+	return e.Path(name), nil
+}
+
+func (e *FakeEncryptor) Resize(name, key string) error {
+	e.ResizeName = name
+	e.ResizeKey = key
+	return e.ResizeErr
+}
+
+func (e *FakeEncryptor) Close(name string) error {
+	e.CloseNames = append(e.CloseNames, name)
+	return e.CloseErr
+}
+
+func (e *FakeEncryptor) IsOpen(name string) (bool, error) {
+	return e.IsOpenResult, e.IsOpenErr
+}
+
+func (e *FakeEncryptor) Path(name string) string {
+	return path.Join("/dev/mapper", name)
+}
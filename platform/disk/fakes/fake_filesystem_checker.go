@@ -0,0 +1,22 @@
+package fakes
+
+import (
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+type FakeFilesystemChecker struct {
+	CheckPartitionPaths []string
+	CheckRepairs        []bool
+	CheckResult         boshdisk.FilesystemCheckResult
+	CheckErr            error
+}
+
+func NewFakeFilesystemChecker() *FakeFilesystemChecker {
+	return &FakeFilesystemChecker{}
+}
+
+func (c *FakeFilesystemChecker) Check(partitionPath string, repair bool) (boshdisk.FilesystemCheckResult, error) {
+	c.CheckPartitionPaths = append(c.CheckPartitionPaths, partitionPath)
+	c.CheckRepairs = append(c.CheckRepairs, repair)
+	return c.CheckResult, c.CheckErr
+}
@@ -8,15 +8,19 @@ type FakeFormatter struct {
 	FormatCalled         bool
 	FormatPartitionPaths []string
 	FormatFsTypes        []boshdisk.FileSystemType
+	FormatMkfsTunings    []boshdisk.MkfsTuning
+	FormatMkfsOptions    [][]string
 	FormatError          error
 }
 
-func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType) (err error) {
+func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType, tuning boshdisk.MkfsTuning, mkfsOptions ...string) (err error) {
 	if p.FormatError != nil {
 		return p.FormatError
 	}
 	p.FormatCalled = true
 	p.FormatPartitionPaths = append(p.FormatPartitionPaths, partitionPath)
 	p.FormatFsTypes = append(p.FormatFsTypes, fsType)
+	p.FormatMkfsTunings = append(p.FormatMkfsTunings, tuning)
+	p.FormatMkfsOptions = append(p.FormatMkfsOptions, mkfsOptions)
 	return
 }
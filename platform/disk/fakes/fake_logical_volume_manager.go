@@ -0,0 +1,53 @@
+package fakes
+
+type FakeLogicalVolumeManager struct {
+	CreateVolumeGroup   string
+	CreateLogicalVolume string
+	CreateDevicePaths   []string
+	CreateErr           error
+
+	ExistsResult bool
+	ExistsErr    error
+
+	GrowVolumeGroup   string
+	GrowLogicalVolume string
+	GrowDevicePaths   []string
+	GrowErr           error
+
+	DeactivateVolumeGroups []string
+	DeactivateErr          error
+}
+
+func NewFakeLogicalVolumeManager() *FakeLogicalVolumeManager {
+	return &FakeLogicalVolumeManager{}
+}
+
+func (m *FakeLogicalVolumeManager) Create(volumeGroup, logicalVolume string, devicePaths []string) (string, error) {
+	m.CreateVolumeGroup = volumeGroup
+	m.CreateLogicalVolume = logicalVolume
+	m.CreateDevicePaths = devicePaths
+	if m.CreateErr != nil {
+		return "", m.CreateErr
+	}
+	return m.Path(volumeGroup, logicalVolume), nil
+}
+
+func (m *FakeLogicalVolumeManager) Exists(volumeGroup, logicalVolume string) (bool, error) {
+	return m.ExistsResult, m.ExistsErr
+}
+
+func (m *FakeLogicalVolumeManager) Grow(volumeGroup, logicalVolume string, devicePaths []string) error {
+	m.GrowVolumeGroup = volumeGroup
+	m.GrowLogicalVolume = logicalVolume
+	m.GrowDevicePaths = devicePaths
+	return m.GrowErr
+}
+
+func (m *FakeLogicalVolumeManager) Deactivate(volumeGroup string) error {
+	m.DeactivateVolumeGroups = append(m.DeactivateVolumeGroups, volumeGroup)
+	return m.DeactivateErr
+}
+
+func (m *FakeLogicalVolumeManager) Path(volumeGroup, logicalVolume string) string {
+	return "/dev/mapper/" + volumeGroup + "-" + logicalVolume
+}
@@ -7,6 +7,9 @@ type FakeMounter struct {
 	MountMountOptions   [][]string
 	MountErr            error
 
+	// Errors returned by subsequent Mount calls before falling back to MountErr
+	MountErrs []error
+
 	RemountAsReadonlyCalled bool
 	RemountAsReadonlyPath   string
 	RemountAsReadonlyErr    error
@@ -19,9 +22,10 @@ type FakeMounter struct {
 	SwapOnPartitionPaths []string
 	SwapOnErr            error
 
-	UnmountPartitionPathOrMountPoint string
-	UnmountDidUnmount                bool
-	UnmountErr                       error
+	UnmountPartitionPathOrMountPoint   string
+	UnmountPartitionPathsOrMountPoints []string
+	UnmountDidUnmount                  bool
+	UnmountErr                         error
 
 	IsMountPointPath          string
 	IsMountPointPartitionPath string
@@ -38,6 +42,13 @@ func (m *FakeMounter) Mount(partitionPath, mountPoint string, mountOptions ...st
 	m.MountPartitionPaths = append(m.MountPartitionPaths, partitionPath)
 	m.MountMountPoints = append(m.MountMountPoints, mountPoint)
 	m.MountMountOptions = append(m.MountMountOptions, mountOptions)
+
+	if len(m.MountErrs) > 0 {
+		err := m.MountErrs[0]
+		m.MountErrs = m.MountErrs[1:]
+		return err
+	}
+
 	return m.MountErr
 }
 
@@ -61,6 +72,7 @@ func (m *FakeMounter) SwapOn(partitionPath string) (err error) {
 
 func (m *FakeMounter) Unmount(partitionPathOrMountPoint string) (didUnmount bool, err error) {
 	m.UnmountPartitionPathOrMountPoint = partitionPathOrMountPoint
+	m.UnmountPartitionPathsOrMountPoints = append(m.UnmountPartitionPathsOrMountPoints, partitionPathOrMountPoint)
 	return m.UnmountDidUnmount, m.UnmountErr
 }
 
@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	"path"
+)
+
+type FakeRAIDManager struct {
+	AssembleName        string
+	AssembleDevicePaths []string
+	AssembleErr         error
+
+	IsActiveResult bool
+}
+
+func NewFakeRAIDManager() *FakeRAIDManager {
+	return &FakeRAIDManager{}
+}
+
+func (m *FakeRAIDManager) Assemble(name string, devicePaths []string) (string, error) {
+	m.AssembleName = name
+	m.AssembleDevicePaths = devicePaths
+	if m.AssembleErr != nil {
+		return "", m.AssembleErr
+	}
+	return m.Path(name), nil
+}
+
+func (m *FakeRAIDManager) IsActive(name string) bool {
+	return m.IsActiveResult
+}
+
+func (m *FakeRAIDManager) Path(name string) string {
+	return path.Join("/dev/md", name)
+}
@@ -0,0 +1,61 @@
+package fakes
+
+type FakeZFSPoolManager struct {
+	CreatePool        string
+	CreateDataset     string
+	CreateDevicePaths []string
+	CreateErr         error
+
+	ExistsResult bool
+	ExistsErr    error
+
+	GrowPool        string
+	GrowDevicePaths []string
+	GrowErr         error
+
+	SnapshotDatasets  []string
+	SnapshotSnapshots []string
+	SnapshotErr       error
+
+	ExportPools []string
+	ExportErr   error
+}
+
+func NewFakeZFSPoolManager() *FakeZFSPoolManager {
+	return &FakeZFSPoolManager{}
+}
+
+func (m *FakeZFSPoolManager) Create(pool, dataset string, devicePaths []string) (string, error) {
+	m.CreatePool = pool
+	m.CreateDataset = dataset
+	m.CreateDevicePaths = devicePaths
+	if m.CreateErr != nil {
+		return "", m.CreateErr
+	}
+	return m.Dataset(pool, dataset), nil
+}
+
+func (m *FakeZFSPoolManager) Exists(pool, dataset string) (bool, error) {
+	return m.ExistsResult, m.ExistsErr
+}
+
+func (m *FakeZFSPoolManager) Grow(pool string, devicePaths []string) error {
+	m.GrowPool = pool
+	m.GrowDevicePaths = devicePaths
+	return m.GrowErr
+}
+
+func (m *FakeZFSPoolManager) Snapshot(dataset, snapshot string) error {
+	m.SnapshotDatasets = append(m.SnapshotDatasets, dataset)
+	m.SnapshotSnapshots = append(m.SnapshotSnapshots, snapshot)
+	return m.SnapshotErr
+}
+
+func (m *FakeZFSPoolManager) Export(pool string) error {
+	m.ExportPools = append(m.ExportPools, pool)
+	return m.ExportErr
+}
+
+func (m *FakeZFSPoolManager) Dataset(pool, dataset string) string {
+	return pool + "/" + dataset
+}
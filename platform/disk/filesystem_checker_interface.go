@@ -0,0 +1,23 @@
+package disk
+
+// FilesystemCheckResult describes outcome of a filesystem check
+// so that it can be reported without inspecting fsck output
+type FilesystemCheckResult struct {
+	PartitionPath  string         `json:"partition_path"`
+	FileSystemType FileSystemType `json:"filesystem_type"`
+
+	// Whether errors were found and repaired
+	Repaired bool `json:"repaired"`
+
+	// Whether filesystem has no errors left
+	Clean bool `json:"clean"`
+
+	ExitStatus int    `json:"exit_status"`
+	Output     string `json:"output"`
+}
+
+type FilesystemChecker interface {
+	// Check checks filesystem on an unmounted partition; when repair is set
+	// errors that can be fixed without operator input are repaired
+	Check(partitionPath string, repair bool) (FilesystemCheckResult, error)
+}
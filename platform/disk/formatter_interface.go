@@ -9,6 +9,22 @@ const (
 	FileSystemDefault FileSystemType = ""
 )
 
+// MkfsTuning is translated by the formatter into mkfs arguments
+// of the filesystem type; it currently only applies to ext4
+type MkfsTuning struct {
+	// Percentage of blocks reserved for root, e.g. 0 on large persistent disks (mke2fs defaults to 5)
+	ReservedBlocksPercent *int `json:"reserved_blocks_percent"`
+
+	// Bytes per inode; lower values create more inodes for workloads with many small files
+	BytesPerInode int `json:"bytes_per_inode"`
+
+	// Whether inode tables and journal are initialized lazily after mounting
+	// (defaults to lazy inode table initialization when the kernel supports it)
+	LazyInit *bool `json:"lazy_init"`
+}
+
 type Formatter interface {
-	Format(partitionPath string, fsType FileSystemType) (err error)
+	// Format creates filesystem on the partition unless it is already formatted;
+	// mkfsOptions are passed through to the mkfs command after arguments derived from tuning
+	Format(partitionPath string, fsType FileSystemType, tuning MkfsTuning, mkfsOptions ...string) (err error)
 }
@@ -16,6 +16,11 @@ type linuxDiskManager struct {
 	formatter             Formatter
 	mounter               Mounter
 	mountsSearcher        MountsSearcher
+	logicalVolumeManager  LogicalVolumeManager
+	zfsPoolManager        ZFSPoolManager
+	encryptor             Encryptor
+	raidManager           RAIDManager
+	filesystemChecker     FilesystemChecker
 	fs                    boshsys.FileSystem
 	logger                boshlog.Logger
 	runner                boshsys.CmdRunner
@@ -55,6 +60,11 @@ func NewLinuxDiskManager(
 		formatter:             NewLinuxFormatter(runner, fs),
 		mounter:               mounter,
 		mountsSearcher:        mountsSearcher,
+		logicalVolumeManager:  NewLinuxLogicalVolumeManager(runner, logger),
+		zfsPoolManager:        NewLinuxZFSPoolManager(runner, logger),
+		encryptor:             NewLinuxLUKSEncryptor(runner, logger),
+		raidManager:           NewLinuxMdadmRAIDManager(runner, logger),
+		filesystemChecker:     NewLinuxFilesystemChecker(runner, logger),
 		fs:                    fs,
 		logger:                logger,
 		runner:                runner,
@@ -73,6 +83,17 @@ func (m linuxDiskManager) GetFormatter() Formatter           { return m.formatte
 func (m linuxDiskManager) GetMounter() Mounter               { return m.mounter }
 func (m linuxDiskManager) GetMountsSearcher() MountsSearcher { return m.mountsSearcher }
 
+func (m linuxDiskManager) GetLogicalVolumeManager() LogicalVolumeManager {
+	return m.logicalVolumeManager
+}
+
+func (m linuxDiskManager) GetZFSPoolManager() ZFSPoolManager { return m.zfsPoolManager }
+
+func (m linuxDiskManager) GetEncryptor() Encryptor     { return m.encryptor }
+func (m linuxDiskManager) GetRAIDManager() RAIDManager { return m.raidManager }
+
+func (m linuxDiskManager) GetFilesystemChecker() FilesystemChecker { return m.filesystemChecker }
+
 func (m linuxDiskManager) GetDiskUtil(diskPath string) boshdevutil.DeviceUtil {
 	return NewDiskUtil(diskPath, m.runner, m.mounter, m.fs, m.logger)
 }
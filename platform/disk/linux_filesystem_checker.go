@@ -0,0 +1,119 @@
+package disk
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// e2fsck exit status bits
+	e2fsckErrorsCorrected   = 1
+	e2fsckErrorsCorrectedRR = 2
+	e2fsckErrorsUncorrected = 4
+	e2fsckOperationalError  = 8
+
+	// xfs_repair -n exits with 1 when corruption is detected
+	xfsRepairCorruptionDetected = 1
+
+	// Only the tail of fsck output is kept since it can be very long on badly damaged filesystems
+	maxFilesystemCheckOutputLength = 4096
+)
+
+type linuxFilesystemChecker struct {
+	runner boshsys.CmdRunner
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxFilesystemChecker(runner boshsys.CmdRunner, logger boshlog.Logger) FilesystemChecker {
+	return linuxFilesystemChecker{
+		runner: runner,
+		logger: logger,
+		logTag: "LinuxFilesystemChecker",
+	}
+}
+
+func (c linuxFilesystemChecker) Check(partitionPath string, repair bool) (FilesystemCheckResult, error) {
+	result := FilesystemCheckResult{PartitionPath: partitionPath}
+
+	fsType, err := partitionFileSystemType(c.runner, partitionPath)
+	if err != nil {
+		return result, bosherr.WrapError(err, "Checking filesystem type of partition")
+	}
+
+	result.FileSystemType = fsType
+
+	switch fsType {
+	case FileSystemExt4:
+		return c.checkExt4(result, repair)
+	case FileSystemXFS:
+		return c.checkXFS(result, repair)
+	default:
+		return result, bosherr.Errorf("Checking filesystem type '%s' is not supported", fsType)
+	}
+}
+
+func (c linuxFilesystemChecker) checkExt4(result FilesystemCheckResult, repair bool) (FilesystemCheckResult, error) {
+	// -p only makes repairs that are safe without operator input
+	mode := "-n"
+	if repair {
+		mode = "-p"
+	}
+
+	stdout, stderr, exitStatus, err := c.runner.RunCommand("e2fsck", "-f", mode, result.PartitionPath)
+	result.ExitStatus = exitStatus
+	result.Output = filesystemCheckOutput(stdout, stderr)
+
+	if err != nil && (exitStatus < 0 || exitStatus >= e2fsckOperationalError) {
+		return result, bosherr.WrapError(err, "Shelling out to e2fsck")
+	}
+
+	result.Repaired = exitStatus&(e2fsckErrorsCorrected|e2fsckErrorsCorrectedRR) != 0
+	result.Clean = exitStatus&e2fsckErrorsUncorrected == 0
+
+	c.logger.Info(c.logTag, "Checked %s: repaired=%t clean=%t exit status=%d", result.PartitionPath, result.Repaired, result.Clean, exitStatus)
+
+	return result, nil
+}
+
+func (c linuxFilesystemChecker) checkXFS(result FilesystemCheckResult, repair bool) (FilesystemCheckResult, error) {
+	stdout, stderr, exitStatus, err := c.runner.RunCommand("xfs_repair", "-n", result.PartitionPath)
+	result.ExitStatus = exitStatus
+	result.Output = filesystemCheckOutput(stdout, stderr)
+
+	if err != nil && exitStatus != xfsRepairCorruptionDetected {
+		return result, bosherr.WrapError(err, "Shelling out to xfs_repair")
+	}
+
+	result.Clean = err == nil
+
+	if result.Clean || !repair {
+		c.logger.Info(c.logTag, "Checked %s: clean=%t exit status=%d", result.PartitionPath, result.Clean, exitStatus)
+		return result, nil
+	}
+
+	// Zeroing the log (-L) loses metadata changes so it is left to operators
+	stdout, stderr, exitStatus, err = c.runner.RunCommand("xfs_repair", result.PartitionPath)
+	result.ExitStatus = exitStatus
+	result.Output = filesystemCheckOutput(stdout, stderr)
+
+	if err != nil {
+		return result, bosherr.WrapError(err, "Shelling out to xfs_repair")
+	}
+
+	result.Repaired = true
+	result.Clean = true
+
+	c.logger.Info(c.logTag, "Repaired %s", result.PartitionPath)
+
+	return result, nil
+}
+
+func filesystemCheckOutput(stdout, stderr string) string {
+	output := stdout + stderr
+	if len(output) > maxFilesystemCheckOutputLength {
+		output = output[len(output)-maxFilesystemCheckOutputLength:]
+	}
+	return output
+}
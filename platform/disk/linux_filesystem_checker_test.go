@@ -0,0 +1,150 @@
+package disk_test
+
+import (
+	"errors"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxFilesystemChecker", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		checker FilesystemChecker
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		checker = NewLinuxFilesystemChecker(runner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Check", func() {
+		Context("when partition is formatted with ext4", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `/dev/sdf1: UUID="fake-uuid" TYPE="ext4"`})
+			})
+
+			It("checks filesystem without making changes", func() {
+				runner.AddCmdResult("e2fsck -f -n /dev/sdf1", fakesys.FakeCmdResult{Stdout: "fake-output", ExitStatus: 4, Error: errors.New("fake-exit-4")})
+
+				result, err := checker.Check("/dev/sdf1", false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(FilesystemCheckResult{
+					PartitionPath:  "/dev/sdf1",
+					FileSystemType: FileSystemExt4,
+					Repaired:       false,
+					Clean:          false,
+					ExitStatus:     4,
+					Output:         "fake-output",
+				}))
+			})
+
+			It("repairs errors that can be fixed safely", func() {
+				runner.AddCmdResult("e2fsck -f -p /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Repaired).To(BeTrue())
+				Expect(result.Clean).To(BeTrue())
+				Expect(runner.RunCommands).To(ContainElement([]string{"e2fsck", "-f", "-p", "/dev/sdf1"}))
+			})
+
+			It("reports errors that could not be repaired", func() {
+				runner.AddCmdResult("e2fsck -f -p /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 5, Error: errors.New("fake-exit-5")})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Repaired).To(BeTrue())
+				Expect(result.Clean).To(BeFalse())
+			})
+
+			It("reports clean filesystem", func() {
+				runner.AddCmdResult("e2fsck -f -p /dev/sdf1", fakesys.FakeCmdResult{})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Repaired).To(BeFalse())
+				Expect(result.Clean).To(BeTrue())
+			})
+
+			It("returns error when e2fsck fails to run", func() {
+				runner.AddCmdResult("e2fsck -f -p /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 8, Error: errors.New("fake-e2fsck-err")})
+
+				_, err := checker.Check("/dev/sdf1", true)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-e2fsck-err"))
+			})
+
+			It("keeps only the tail of long output", func() {
+				runner.AddCmdResult("e2fsck -f -n /dev/sdf1", fakesys.FakeCmdResult{Stdout: strings.Repeat("a", 5000) + "fake-tail"})
+
+				result, err := checker.Check("/dev/sdf1", false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Output).To(HaveLen(4096))
+				Expect(result.Output).To(HaveSuffix("fake-tail"))
+			})
+		})
+
+		Context("when partition is formatted with xfs", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `/dev/sdf1: UUID="fake-uuid" TYPE="xfs"`})
+			})
+
+			It("does not repair clean filesystem", func() {
+				runner.AddCmdResult("xfs_repair -n /dev/sdf1", fakesys.FakeCmdResult{})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Clean).To(BeTrue())
+				Expect(result.Repaired).To(BeFalse())
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"blkid", "-p", "/dev/sdf1"},
+					{"xfs_repair", "-n", "/dev/sdf1"},
+				}))
+			})
+
+			It("only reports corruption when not repairing", func() {
+				runner.AddCmdResult("xfs_repair -n /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+				result, err := checker.Check("/dev/sdf1", false)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Clean).To(BeFalse())
+				Expect(result.FileSystemType).To(Equal(FileSystemXFS))
+				Expect(runner.RunCommands).To(HaveLen(2))
+			})
+
+			It("repairs corrupted filesystem without zeroing the log", func() {
+				runner.AddCmdResult("xfs_repair -n /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Clean).To(BeTrue())
+				Expect(result.Repaired).To(BeTrue())
+				Expect(runner.RunCommands).To(ContainElement([]string{"xfs_repair", "/dev/sdf1"}))
+			})
+
+			It("returns error when repair fails", func() {
+				runner.AddCmdResult("xfs_repair -n /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+				runner.AddCmdResult("xfs_repair /dev/sdf1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("fake-dirty-log")})
+
+				result, err := checker.Check("/dev/sdf1", true)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-dirty-log"))
+				Expect(result.ExitStatus).To(Equal(2))
+			})
+		})
+
+		It("returns error when filesystem type is not supported", func() {
+			runner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `/dev/sdf1: TYPE="btrfs"`})
+
+			_, err := checker.Check("/dev/sdf1", true)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Checking filesystem type 'btrfs' is not supported"))
+		})
+	})
+})
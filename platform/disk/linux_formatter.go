@@ -1,9 +1,11 @@
 package disk
 
 import (
+	"regexp"
+	"strconv"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
-	"regexp"
 )
 
 type linuxFormatter struct {
@@ -18,8 +20,8 @@ func NewLinuxFormatter(runner boshsys.CmdRunner, fs boshsys.FileSystem) Formatte
 	}
 }
 
-func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err error) {
-	existingFsType, err := f.getPartitionFormatType(partitionPath)
+func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType, tuning MkfsTuning, mkfsOptions ...string) (err error) {
+	existingFsType, err := partitionFileSystemType(f.runner, partitionPath)
 	if err != nil {
 		return bosherr.WrapError(err, "Checking filesystem format of partition")
 	}
@@ -42,17 +44,17 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 		}
 
 	case FileSystemExt4:
-		if f.fs.FileExists("/sys/fs/ext4/features/lazy_itable_init") {
-			_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(fsType), "-j", "-E", "lazy_itable_init=1", partitionPath)
-		} else {
-			_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(fsType), "-j", partitionPath)
-		}
+		args := []string{"-t", string(fsType), "-j"}
+		args = append(args, f.ext4TuningArgs(tuning)...)
+		args = append(args, mkfsOptions...)
+		_, _, _, err = f.runner.RunCommand("mke2fs", append(args, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mke2fs")
 		}
 
 	case FileSystemXFS:
-		_, _, _, err = f.runner.RunCommand("mkfs.xfs", partitionPath)
+		args := append(append([]string{}, mkfsOptions...), partitionPath)
+		_, _, _, err = f.runner.RunCommand("mkfs.xfs", args...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mkfs.xfs")
 		}
@@ -60,8 +62,33 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 	return
 }
 
-func (f linuxFormatter) getPartitionFormatType(partitionPath string) (FileSystemType, error) {
-	stdout, stderr, exitStatus, err := f.runner.RunCommand("blkid", "-p", partitionPath)
+func (f linuxFormatter) ext4TuningArgs(tuning MkfsTuning) []string {
+	var args []string
+
+	if tuning.LazyInit == nil {
+		if f.fs.FileExists("/sys/fs/ext4/features/lazy_itable_init") {
+			args = append(args, "-E", "lazy_itable_init=1")
+		}
+	} else if *tuning.LazyInit {
+		args = append(args, "-E", "lazy_itable_init=1,lazy_journal_init=1")
+	} else {
+		args = append(args, "-E", "lazy_itable_init=0,lazy_journal_init=0")
+	}
+
+	if tuning.ReservedBlocksPercent != nil {
+		args = append(args, "-m", strconv.Itoa(*tuning.ReservedBlocksPercent))
+	}
+
+	if tuning.BytesPerInode > 0 {
+		args = append(args, "-i", strconv.Itoa(tuning.BytesPerInode))
+	}
+
+	return args
+}
+
+// partitionFileSystemType returns empty type when partition is not formatted
+func partitionFileSystemType(runner boshsys.CmdRunner, partitionPath string) (FileSystemType, error) {
+	stdout, stderr, exitStatus, err := runner.RunCommand("blkid", "-p", partitionPath)
 
 	if err != nil {
 		if exitStatus == 2 && stderr == "" {
@@ -17,7 +17,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemSwap)
+			formatter.Format("/dev/xvda2", FileSystemSwap, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkswap", "/dev/xvda2"}))
@@ -29,7 +29,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemSwap)
+			formatter.Format("/dev/xvda1", FileSystemSwap, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkswap", "/dev/xvda1"}))
@@ -41,7 +41,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="swap" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemSwap)
+			formatter.Format("/dev/xvda1", FileSystemSwap, MkfsTuning{})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -56,7 +56,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext2" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "/dev/xvda2"}))
@@ -69,7 +69,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext2" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "/dev/xvda2"}))
@@ -81,7 +81,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemExt4)
+			formatter.Format("/dev/xvda1", FileSystemExt4, MkfsTuning{})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -93,7 +93,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda2"}))
@@ -105,13 +105,80 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="somethingelse" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda2"}))
 		})
 	})
 
+	Describe("when using ext4 with mkfs options", func() {
+		It("passes mkfs options before the partition path", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeFs.WriteFile("/sys/fs/ext4/features/lazy_itable_init", []byte{})
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{}, "-m", "1")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "-m", "1", "/dev/xvda2"}))
+		})
+	})
+
+	Describe("when using ext4 with mkfs tuning", func() {
+		var (
+			fakeRunner *fakesys.FakeCmdRunner
+			fakeFs     *fakesys.FakeFileSystem
+			formatter  Formatter
+		)
+
+		BeforeEach(func() {
+			fakeRunner = fakesys.NewFakeCmdRunner()
+			fakeFs = fakesys.NewFakeFileSystem()
+			fakeFs.WriteFile("/sys/fs/ext4/features/lazy_itable_init", []byte{})
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+			formatter = NewLinuxFormatter(fakeRunner, fakeFs)
+		})
+
+		It("passes reserved blocks percentage and bytes per inode", func() {
+			reservedBlocksPercent := 0
+
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{ReservedBlocksPercent: &reservedBlocksPercent, BytesPerInode: 8192})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "-m", "0", "-i", "8192", "/dev/xvda2"}))
+		})
+
+		It("disables lazy initialization of inode tables and journal", func() {
+			lazyInit := false
+
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{LazyInit: &lazyInit})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=0,lazy_journal_init=0", "/dev/xvda2"}))
+		})
+
+		It("enables lazy initialization of inode tables and journal", func() {
+			lazyInit := true
+
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{LazyInit: &lazyInit})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1,lazy_journal_init=1", "/dev/xvda2"}))
+		})
+
+		It("passes mkfs options after tuning so that they take precedence", func() {
+			reservedBlocksPercent := 0
+
+			err := formatter.Format("/dev/xvda2", FileSystemExt4, MkfsTuning{ReservedBlocksPercent: &reservedBlocksPercent}, "-m", "2")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "-m", "0", "-m", "2", "/dev/xvda2"}))
+		})
+	})
+
 	Describe("when using xfs", func() {
 		It("formats a blank disk with type xfs", func() {
 			fakeRunner := fakesys.NewFakeCmdRunner()
@@ -119,7 +186,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemXFS)
+			formatter.Format("/dev/xvda2", FileSystemXFS, MkfsTuning{})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.xfs", "/dev/xvda2"}))
@@ -131,7 +198,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemXFS)
+			formatter.Format("/dev/xvda1", FileSystemXFS, MkfsTuning{})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -143,7 +210,7 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemXFS)
+			formatter.Format("/dev/xvda1", FileSystemXFS, MkfsTuning{})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -156,10 +223,22 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stderr: "", ExitStatus: 2})
 
 			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			err := formatter.Format("/dev/xvda2", FileSystemXFS)
+			err := formatter.Format("/dev/xvda2", FileSystemXFS, MkfsTuning{})
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Shelling out to mkfs.xfs: Sadness"))
 		})
+
+		It("passes mkfs options before the partition path", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
+			err := formatter.Format("/dev/xvda2", FileSystemXFS, MkfsTuning{}, "-i", "size=512")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.xfs", "-i", "size=512", "/dev/xvda2"}))
+		})
 	})
 })
@@ -0,0 +1,157 @@
+package disk
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type linuxLogicalVolumeManager struct {
+	runner boshsys.CmdRunner
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxLogicalVolumeManager(runner boshsys.CmdRunner, logger boshlog.Logger) LogicalVolumeManager {
+	return linuxLogicalVolumeManager{
+		runner: runner,
+		logger: logger,
+		logTag: "LinuxLogicalVolumeManager",
+	}
+}
+
+func (m linuxLogicalVolumeManager) Create(volumeGroup, logicalVolume string, devicePaths []string) (string, error) {
+	var unassignedDevicePaths []string
+
+	for _, devicePath := range devicePaths {
+		assignedVolumeGroup, isPhysicalVolume := m.physicalVolumeGroup(devicePath)
+
+		if !isPhysicalVolume {
+			// Never wipe data that is not managed with LVM yet, e.g. disks formatted before
+			existingData, err := existingDeviceData(m.runner, devicePath)
+			if err != nil {
+				return "", bosherr.WrapErrorf(err, "Checking existing data on %s", devicePath)
+			}
+
+			if existingData != "" {
+				return "", bosherr.Errorf("Refusing to create physical volume on %s with existing %s", devicePath, existingData)
+			}
+
+			m.logger.Info(m.logTag, "Creating physical volume on %s", devicePath)
+
+			_, _, _, err = m.runner.RunCommand("pvcreate", "-f", "-y", devicePath)
+			if err != nil {
+				return "", bosherr.WrapErrorf(err, "Creating physical volume on %s", devicePath)
+			}
+		}
+
+		switch assignedVolumeGroup {
+		case volumeGroup:
+		case "":
+			unassignedDevicePaths = append(unassignedDevicePaths, devicePath)
+		default:
+			return "", bosherr.Errorf("Physical volume %s belongs to volume group %s", devicePath, assignedVolumeGroup)
+		}
+	}
+
+	_, _, _, err := m.runner.RunCommand("vgs", volumeGroup)
+	if err != nil {
+		m.logger.Info(m.logTag, "Creating volume group %s on %v", volumeGroup, unassignedDevicePaths)
+
+		args := append([]string{volumeGroup}, unassignedDevicePaths...)
+		_, _, _, err = m.runner.RunCommand("vgcreate", args...)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating volume group %s", volumeGroup)
+		}
+	} else if len(unassignedDevicePaths) > 0 {
+		m.logger.Info(m.logTag, "Extending volume group %s with %v", volumeGroup, unassignedDevicePaths)
+
+		args := append([]string{volumeGroup}, unassignedDevicePaths...)
+		_, _, _, err = m.runner.RunCommand("vgextend", args...)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Extending volume group %s", volumeGroup)
+		}
+	}
+
+	exists, err := m.Exists(volumeGroup, logicalVolume)
+	if err != nil {
+		return "", err
+	}
+
+	if !exists {
+		m.logger.Info(m.logTag, "Creating logical volume %s in %s", logicalVolume, volumeGroup)
+
+		_, _, _, err = m.runner.RunCommand("lvcreate", "-y", "-n", logicalVolume, "-l", "100%FREE", volumeGroup)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating logical volume %s", logicalVolume)
+		}
+	}
+
+	_, _, _, err = m.runner.RunCommand("vgchange", "-ay", volumeGroup)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Activating volume group %s", volumeGroup)
+	}
+
+	return m.Path(volumeGroup, logicalVolume), nil
+}
+
+func (m linuxLogicalVolumeManager) Exists(volumeGroup, logicalVolume string) (bool, error) {
+	_, _, exitStatus, err := m.runner.RunCommand("lvs", volumeGroup+"/"+logicalVolume)
+	if err != nil {
+		// lvs exits with 5 when volume group or logical volume is not found
+		if exitStatus == 5 {
+			return false, nil
+		}
+		return false, bosherr.WrapErrorf(err, "Checking logical volume %s/%s", volumeGroup, logicalVolume)
+	}
+
+	return true, nil
+}
+
+func (m linuxLogicalVolumeManager) Grow(volumeGroup, logicalVolume string, devicePaths []string) error {
+	for _, devicePath := range devicePaths {
+		_, _, _, err := m.runner.RunCommand("pvresize", devicePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Resizing physical volume %s", devicePath)
+		}
+	}
+
+	_, stderr, _, err := m.runner.RunCommand("lvextend", "-l", "+100%FREE", volumeGroup+"/"+logicalVolume)
+	if err != nil {
+		// lvextend fails when there are no free extents to grow into
+		if strings.Contains(stderr, "matches existing size") {
+			return nil
+		}
+		return bosherr.WrapErrorf(err, "Extending logical volume %s/%s", volumeGroup, logicalVolume)
+	}
+
+	return nil
+}
+
+func (m linuxLogicalVolumeManager) Deactivate(volumeGroup string) error {
+	_, _, _, err := m.runner.RunCommand("vgchange", "-an", volumeGroup)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Deactivating volume group %s", volumeGroup)
+	}
+
+	return nil
+}
+
+// Path returns device mapper path since that is what shows up in /proc/mounts
+func (m linuxLogicalVolumeManager) Path(volumeGroup, logicalVolume string) string {
+	escape := func(name string) string { return strings.Replace(name, "-", "--", -1) }
+	return "/dev/mapper/" + escape(volumeGroup) + "-" + escape(logicalVolume)
+}
+
+// physicalVolumeGroup returns volume group name the device is assigned to
+// and whether the device is initialized as a physical volume at all.
+func (m linuxLogicalVolumeManager) physicalVolumeGroup(devicePath string) (string, bool) {
+	stdout, _, _, err := m.runner.RunCommand("pvs", "--noheadings", "-o", "vg_name", devicePath)
+	if err != nil {
+		return "", false
+	}
+
+	return strings.TrimSpace(stdout), true
+}
@@ -0,0 +1,198 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxLogicalVolumeManager", func() {
+	var (
+		runner *fakesys.FakeCmdRunner
+		lvm    LogicalVolumeManager
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		lvm = NewLinuxLogicalVolumeManager(runner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Create", func() {
+		Context("when devices are not physical volumes yet", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("pvs --noheadings -o vg_name /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("not a pv")})
+				runner.AddCmdResult("pvs --noheadings -o vg_name /dev/sdc", fakesys.FakeCmdResult{Error: errors.New("not a pv")})
+				runner.AddCmdResult("vgs fake-vg", fakesys.FakeCmdResult{Error: errors.New("not found")})
+				runner.AddCmdResult("lvs fake-vg/fake-lv", fakesys.FakeCmdResult{ExitStatus: 5, Error: errors.New("not found")})
+			})
+
+			It("creates physical volumes, volume group and logical volume", func() {
+				path, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb", "/dev/sdc"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(path).To(Equal("/dev/mapper/fake--vg-fake--lv"))
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"pvs", "--noheadings", "-o", "vg_name", "/dev/sdb"},
+					{"blkid", "-p", "/dev/sdb"},
+					{"lsblk", "-n", "-r", "-o", "TYPE", "/dev/sdb"},
+					{"pvcreate", "-f", "-y", "/dev/sdb"},
+					{"pvs", "--noheadings", "-o", "vg_name", "/dev/sdc"},
+					{"blkid", "-p", "/dev/sdc"},
+					{"lsblk", "-n", "-r", "-o", "TYPE", "/dev/sdc"},
+					{"pvcreate", "-f", "-y", "/dev/sdc"},
+					{"vgs", "fake-vg"},
+					{"vgcreate", "fake-vg", "/dev/sdb", "/dev/sdc"},
+					{"lvs", "fake-vg/fake-lv"},
+					{"lvcreate", "-y", "-n", "fake-lv", "-l", "100%FREE", "fake-vg"},
+					{"vgchange", "-ay", "fake-vg"},
+				}))
+			})
+
+			It("returns error if creating physical volume fails", func() {
+				runner.AddCmdResult("pvcreate -f -y /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("fake-pvcreate-err")})
+
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-pvcreate-err"))
+			})
+
+			It("refuses to create physical volume on device with existing filesystem", func() {
+				runner.AddCmdResult("blkid -p /dev/sdc", fakesys.FakeCmdResult{Stdout: `/dev/sdc: UUID="fake-uuid" TYPE="ext4" USAGE="filesystem"`})
+
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb", "/dev/sdc"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to create physical volume on /dev/sdc with existing ext4 filesystem"))
+
+				Expect(runner.RunCommands).ToNot(ContainElement([]string{"pvcreate", "-f", "-y", "/dev/sdc"}))
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("vgcreate")))
+			})
+
+			It("refuses to create physical volume on device with partition table", func() {
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{Stdout: `/dev/sdb: PTUUID="fake-uuid" PTTYPE="dos"`})
+
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to create physical volume on /dev/sdb with existing dos partition table"))
+
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("pvcreate")))
+			})
+
+			It("refuses to create physical volume on device with partitions", func() {
+				runner.AddCmdResult("lsblk -n -r -o TYPE /dev/sdb", fakesys.FakeCmdResult{Stdout: "disk\npart\n"})
+
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to create physical volume on /dev/sdb with existing partitions"))
+
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("pvcreate")))
+			})
+
+			It("returns error if checking existing filesystem fails", func() {
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{ExitStatus: 4, Error: errors.New("fake-blkid-err")})
+
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-blkid-err"))
+			})
+		})
+
+		Context("when volume group and logical volume already exist", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("pvs --noheadings -o vg_name /dev/sdb", fakesys.FakeCmdResult{Stdout: "  fake-vg\n"})
+				runner.AddCmdResult("pvs --noheadings -o vg_name /dev/sdc", fakesys.FakeCmdResult{Stdout: "\n"})
+			})
+
+			It("only activates volume group", func() {
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"pvs", "--noheadings", "-o", "vg_name", "/dev/sdb"},
+					{"vgs", "fake-vg"},
+					{"lvs", "fake-vg/fake-lv"},
+					{"vgchange", "-ay", "fake-vg"},
+				}))
+			})
+
+			It("extends volume group with physical volumes that are not in any volume group", func() {
+				_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb", "/dev/sdc"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runner.RunCommands).To(ContainElement([]string{"vgextend", "fake-vg", "/dev/sdc"}))
+			})
+		})
+
+		It("returns error if device belongs to another volume group", func() {
+			runner.AddCmdResult("pvs --noheadings -o vg_name /dev/sdb", fakesys.FakeCmdResult{Stdout: "other-vg\n"})
+
+			_, err := lvm.Create("fake-vg", "fake-lv", []string{"/dev/sdb"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Physical volume /dev/sdb belongs to volume group other-vg"))
+		})
+	})
+
+	Describe("Exists", func() {
+		It("returns true if logical volume is found", func() {
+			exists, err := lvm.Exists("fake-vg", "fake-lv")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("returns false if logical volume is not found", func() {
+			runner.AddCmdResult("lvs fake-vg/fake-lv", fakesys.FakeCmdResult{ExitStatus: 5, Error: errors.New("not found")})
+
+			exists, err := lvm.Exists("fake-vg", "fake-lv")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("returns error if lvs fails otherwise", func() {
+			runner.AddCmdResult("lvs fake-vg/fake-lv", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-lvs-err")})
+
+			_, err := lvm.Exists("fake-vg", "fake-lv")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Grow", func() {
+		It("resizes physical volumes and extends logical volume", func() {
+			err := lvm.Grow("fake-vg", "fake-lv", []string{"/dev/sdb", "/dev/sdc"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"pvresize", "/dev/sdb"},
+				{"pvresize", "/dev/sdc"},
+				{"lvextend", "-l", "+100%FREE", "fake-vg/fake-lv"},
+			}))
+		})
+
+		It("succeeds if there is no free space to extend into", func() {
+			runner.AddCmdResult("lvextend -l +100%FREE fake-vg/fake-lv", fakesys.FakeCmdResult{
+				Stderr: "New size (25 extents) matches existing size (25 extents).",
+				Error:  errors.New("fake-lvextend-err"),
+			})
+
+			err := lvm.Grow("fake-vg", "fake-lv", []string{"/dev/sdb"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error if resizing physical volume fails", func() {
+			runner.AddCmdResult("pvresize /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("fake-pvresize-err")})
+
+			err := lvm.Grow("fake-vg", "fake-lv", []string{"/dev/sdb"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-pvresize-err"))
+		})
+	})
+
+	Describe("Deactivate", func() {
+		It("deactivates volume group", func() {
+			err := lvm.Deactivate("fake-vg")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{{"vgchange", "-an", "fake-vg"}}))
+		})
+	})
+})
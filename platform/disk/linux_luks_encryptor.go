@@ -0,0 +1,116 @@
+package disk
+
+import (
+	"path"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// cryptsetup isLuks exits with 1 when device is not a LUKS volume
+	cryptsetupNotLUKSExitStatus = 1
+
+	// cryptsetup status exits with 4 when mapping does not exist
+	cryptsetupInactiveExitStatus = 4
+)
+
+type linuxLUKSEncryptor struct {
+	runner boshsys.CmdRunner
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxLUKSEncryptor(runner boshsys.CmdRunner, logger boshlog.Logger) Encryptor {
+	return linuxLUKSEncryptor{
+		runner: runner,
+		logger: logger,
+		logTag: "LinuxLUKSEncryptor",
+	}
+}
+
+func (e linuxLUKSEncryptor) Open(devicePath, name, key string) (string, error) {
+	isOpen, err := e.IsOpen(name)
+	if err != nil {
+		return "", err
+	}
+
+	if isOpen {
+		return e.Path(name), nil
+	}
+
+	_, _, exitStatus, err := e.runner.RunCommand("cryptsetup", "isLuks", devicePath)
+	if err != nil {
+		if exitStatus != cryptsetupNotLUKSExitStatus {
+			return "", bosherr.WrapErrorf(err, "Checking whether %s is a LUKS volume", devicePath)
+		}
+
+		existingData, err := existingDeviceData(e.runner, devicePath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Checking existing data on %s", devicePath)
+		}
+
+		if existingData != "" {
+			return "", bosherr.Errorf("Refusing to format %s with existing %s as LUKS volume", devicePath, existingData)
+		}
+
+		e.logger.Info(e.logTag, "Formatting %s as LUKS volume", devicePath)
+
+		// Keys are passed on stdin so that they never show up in process listings
+		_, _, _, err = e.runner.RunCommandWithInput(key, "cryptsetup", "luksFormat", "--batch-mode", "--key-file", "-", devicePath)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Formatting %s as LUKS volume", devicePath)
+		}
+	}
+
+	_, _, _, err = e.runner.RunCommandWithInput(key, "cryptsetup", "luksOpen", "--key-file", "-", devicePath, name)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Opening LUKS volume %s", devicePath)
+	}
+
+	return e.Path(name), nil
+}
+
+func (e linuxLUKSEncryptor) Resize(name, key string) error {
+	_, _, _, err := e.runner.RunCommandWithInput(key, "cryptsetup", "resize", "--key-file", "-", name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Resizing LUKS mapping %s", name)
+	}
+
+	return nil
+}
+
+func (e linuxLUKSEncryptor) Close(name string) error {
+	isOpen, err := e.IsOpen(name)
+	if err != nil {
+		return err
+	}
+
+	if !isOpen {
+		return nil
+	}
+
+	_, _, _, err = e.runner.RunCommand("cryptsetup", "luksClose", name)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Closing LUKS mapping %s", name)
+	}
+
+	return nil
+}
+
+func (e linuxLUKSEncryptor) IsOpen(name string) (bool, error) {
+	_, _, exitStatus, err := e.runner.RunCommand("cryptsetup", "status", name)
+	if err != nil {
+		if exitStatus == cryptsetupInactiveExitStatus {
+			return false, nil
+		}
+		return false, bosherr.WrapErrorf(err, "Checking status of LUKS mapping %s", name)
+	}
+
+	return true, nil
+}
+
+func (e linuxLUKSEncryptor) Path(name string) string {
+	return path.Join("/dev/mapper", name)
+}
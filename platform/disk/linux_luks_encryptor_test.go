@@ -0,0 +1,145 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxLUKSEncryptor", func() {
+	var (
+		runner    *fakesys.FakeCmdRunner
+		encryptor Encryptor
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		encryptor = NewLinuxLUKSEncryptor(runner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Open", func() {
+		Context("when mapping is not open", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("cryptsetup status fake-name", fakesys.FakeCmdResult{ExitStatus: 4, Error: errors.New("inactive")})
+			})
+
+			It("formats device that is not a LUKS volume yet and opens it", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("not luks")})
+
+				path, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(path).To(Equal("/dev/mapper/fake-name"))
+				Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+					{"fake-key", "cryptsetup", "luksFormat", "--batch-mode", "--key-file", "-", "/dev/sdb1"},
+					{"fake-key", "cryptsetup", "luksOpen", "--key-file", "-", "/dev/sdb1", "fake-name"},
+				}))
+			})
+
+			It("formats partition without partitions of its own", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("not luks")})
+				runner.AddCmdResult("lsblk -n -r -o TYPE /dev/sdb1", fakesys.FakeCmdResult{Stdout: "part\n"})
+
+				_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runner.RunCommandsWithInput).To(ContainElement(ContainElement("luksFormat")))
+			})
+
+			It("refuses to format device with existing filesystem", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("not luks")})
+				runner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{Stdout: `/dev/sdb1: UUID="fake-uuid" TYPE="ext4"`})
+
+				_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to format /dev/sdb1 with existing ext4 filesystem as LUKS volume"))
+				Expect(runner.RunCommandsWithInput).To(BeEmpty())
+			})
+
+			It("refuses to format device with partition table", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("not luks")})
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{Stdout: `/dev/sdb: PTUUID="fake-uuid" PTTYPE="gpt"`})
+
+				_, err := encryptor.Open("/dev/sdb", "fake-name", "fake-key")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to format /dev/sdb with existing gpt partition table as LUKS volume"))
+				Expect(runner.RunCommandsWithInput).To(BeEmpty())
+			})
+
+			It("refuses to format device with partitions", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("not luks")})
+				runner.AddCmdResult("lsblk -n -r -o TYPE /dev/sdb", fakesys.FakeCmdResult{Stdout: "disk\npart\npart\n"})
+
+				_, err := encryptor.Open("/dev/sdb", "fake-name", "fake-key")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Refusing to format /dev/sdb with existing partitions as LUKS volume"))
+				Expect(runner.RunCommandsWithInput).To(BeEmpty())
+			})
+
+			It("only opens device that already is a LUKS volume", func() {
+				_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+					{"fake-key", "cryptsetup", "luksOpen", "--key-file", "-", "/dev/sdb1", "fake-name"},
+				}))
+			})
+
+			It("does not format device if checking for LUKS header fails", func() {
+				runner.AddCmdResult("cryptsetup isLuks /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("fake-isluks-err")})
+
+				_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-isluks-err"))
+				Expect(runner.RunCommandsWithInput).To(BeEmpty())
+			})
+
+			It("returns error if opening fails", func() {
+				runner.AddCmdResult("fake-key cryptsetup luksOpen --key-file - /dev/sdb1 fake-name", fakesys.FakeCmdResult{Error: errors.New("fake-open-err")})
+
+				_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-open-err"))
+			})
+		})
+
+		It("does nothing if mapping is already open", func() {
+			path, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("/dev/mapper/fake-name"))
+			Expect(runner.RunCommands).To(Equal([][]string{{"cryptsetup", "status", "fake-name"}}))
+			Expect(runner.RunCommandsWithInput).To(BeEmpty())
+		})
+	})
+
+	Describe("Resize", func() {
+		It("resizes mapping", func() {
+			err := encryptor.Resize("fake-name", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "resize", "--key-file", "-", "fake-name"},
+			}))
+		})
+	})
+
+	Describe("Close", func() {
+		It("closes open mapping", func() {
+			err := encryptor.Close("fake-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"cryptsetup", "status", "fake-name"},
+				{"cryptsetup", "luksClose", "fake-name"},
+			}))
+		})
+
+		It("does nothing if mapping is not open", func() {
+			runner.AddCmdResult("cryptsetup status fake-name", fakesys.FakeCmdResult{ExitStatus: 4, Error: errors.New("inactive")})
+
+			err := encryptor.Close("fake-name")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{{"cryptsetup", "status", "fake-name"}}))
+		})
+	})
+})
@@ -0,0 +1,67 @@
+package disk
+
+import (
+	"path"
+	"strconv"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type linuxMdadmRAIDManager struct {
+	runner boshsys.CmdRunner
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxMdadmRAIDManager(runner boshsys.CmdRunner, logger boshlog.Logger) RAIDManager {
+	return linuxMdadmRAIDManager{
+		runner: runner,
+		logger: logger,
+		logTag: "LinuxMdadmRAIDManager",
+	}
+}
+
+func (m linuxMdadmRAIDManager) Assemble(name string, devicePaths []string) (string, error) {
+	arrayPath := m.Path(name)
+
+	if m.IsActive(name) {
+		m.logger.Debug(m.logTag, "Array %s is already active", arrayPath)
+		return arrayPath, nil
+	}
+
+	args := append([]string{"--assemble", arrayPath}, devicePaths...)
+	_, _, _, err := m.runner.RunCommand("mdadm", args...)
+	if err == nil {
+		m.logger.Info(m.logTag, "Reassembled array %s from %v", arrayPath, devicePaths)
+		return arrayPath, nil
+	}
+
+	// Devices without an array superblock (e.g. fresh instance storage) cannot be
+	// assembled; striped data does not survive losing a device so nothing is lost
+	m.logger.Info(m.logTag, "Creating array %s on %v: %s", arrayPath, devicePaths, err.Error())
+
+	args = append([]string{
+		"--create", arrayPath,
+		"--run",
+		"--level=0",
+		"--raid-devices=" + strconv.Itoa(len(devicePaths)),
+	}, devicePaths...)
+
+	_, _, _, err = m.runner.RunCommand("mdadm", args...)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Creating array %s", arrayPath)
+	}
+
+	return arrayPath, nil
+}
+
+func (m linuxMdadmRAIDManager) IsActive(name string) bool {
+	_, _, _, err := m.runner.RunCommand("mdadm", "--detail", m.Path(name))
+	return err == nil
+}
+
+func (m linuxMdadmRAIDManager) Path(name string) string {
+	return path.Join("/dev/md", name)
+}
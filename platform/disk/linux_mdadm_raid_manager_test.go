@@ -0,0 +1,69 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxMdadmRAIDManager", func() {
+	var (
+		runner      *fakesys.FakeCmdRunner
+		raidManager RAIDManager
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		raidManager = NewLinuxMdadmRAIDManager(runner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Assemble", func() {
+		devicePaths := []string{"/dev/nvme1n1", "/dev/nvme2n1"}
+
+		It("does nothing if array is already active", func() {
+			path, err := raidManager.Assemble("fake-array", devicePaths)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("/dev/md/fake-array"))
+			Expect(runner.RunCommands).To(Equal([][]string{{"mdadm", "--detail", "/dev/md/fake-array"}}))
+		})
+
+		Context("when array is not active", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("mdadm --detail /dev/md/fake-array", fakesys.FakeCmdResult{Error: errors.New("not active")})
+			})
+
+			It("reassembles existing array", func() {
+				path, err := raidManager.Assemble("fake-array", devicePaths)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(path).To(Equal("/dev/md/fake-array"))
+				Expect(runner.RunCommands).To(ContainElement([]string{"mdadm", "--assemble", "/dev/md/fake-array", "/dev/nvme1n1", "/dev/nvme2n1"}))
+				Expect(runner.RunCommands).To(HaveLen(2))
+			})
+
+			It("creates array if it cannot be reassembled", func() {
+				runner.AddCmdResult("mdadm --assemble /dev/md/fake-array /dev/nvme1n1 /dev/nvme2n1", fakesys.FakeCmdResult{Error: errors.New("no superblock")})
+
+				path, err := raidManager.Assemble("fake-array", devicePaths)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(path).To(Equal("/dev/md/fake-array"))
+				Expect(runner.RunCommands[2]).To(Equal([]string{
+					"mdadm", "--create", "/dev/md/fake-array", "--run", "--level=0", "--raid-devices=2", "/dev/nvme1n1", "/dev/nvme2n1",
+				}))
+			})
+
+			It("returns error if creating array fails", func() {
+				runner.AddCmdResult("mdadm --assemble /dev/md/fake-array /dev/nvme1n1 /dev/nvme2n1", fakesys.FakeCmdResult{Error: errors.New("no superblock")})
+				runner.AddCmdResult("mdadm --create /dev/md/fake-array --run --level=0 --raid-devices=2 /dev/nvme1n1 /dev/nvme2n1", fakesys.FakeCmdResult{Error: errors.New("fake-create-err")})
+
+				_, err := raidManager.Assemble("fake-array", devicePaths)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+			})
+		})
+	})
+})
@@ -0,0 +1,165 @@
+package disk
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type linuxZFSPoolManager struct {
+	runner boshsys.CmdRunner
+	logger boshlog.Logger
+	logTag string
+}
+
+func NewLinuxZFSPoolManager(runner boshsys.CmdRunner, logger boshlog.Logger) ZFSPoolManager {
+	return linuxZFSPoolManager{
+		runner: runner,
+		logger: logger,
+		logTag: "LinuxZFSPoolManager",
+	}
+}
+
+// Create imports the pool if it exists and otherwise creates it on given devices
+// as long as none of them has a filesystem, a label of another pool or partitions
+func (m linuxZFSPoolManager) Create(pool, dataset string, devicePaths []string) (string, error) {
+	imported, err := m.importPool(pool)
+	if err != nil {
+		return "", err
+	}
+
+	if !imported {
+		for _, devicePath := range devicePaths {
+			existingData, err := existingDeviceData(m.runner, devicePath)
+			if err != nil {
+				return "", bosherr.WrapErrorf(err, "Checking existing data on %s", devicePath)
+			}
+
+			if existingData != "" {
+				return "", bosherr.Errorf("Refusing to create pool %s on %s with existing %s", pool, devicePath, existingData)
+			}
+		}
+
+		m.logger.Info(m.logTag, "Creating pool %s on %v", pool, devicePaths)
+
+		args := append([]string{"create", "-f", "-o", "ashift=12", "-O", "mountpoint=none", pool}, devicePaths...)
+		_, _, _, err := m.runner.RunCommand("zpool", args...)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating pool %s", pool)
+		}
+	}
+
+	datasetName := m.Dataset(pool, dataset)
+
+	_, _, _, err = m.runner.RunCommand("zfs", "list", "-H", "-o", "name", datasetName)
+	if err != nil {
+		m.logger.Info(m.logTag, "Creating dataset %s", datasetName)
+
+		_, _, _, err = m.runner.RunCommand("zfs", "create", "-o", "mountpoint=legacy", datasetName)
+		if err != nil {
+			return "", bosherr.WrapErrorf(err, "Creating dataset %s", datasetName)
+		}
+	}
+
+	return datasetName, nil
+}
+
+// Exists imports the pool if it was exported (e.g. on the previous unmount)
+// since datasets of pools that are not imported cannot be listed
+func (m linuxZFSPoolManager) Exists(pool, dataset string) (bool, error) {
+	imported, err := m.importPool(pool)
+	if err != nil || !imported {
+		return false, err
+	}
+
+	_, _, _, err = m.runner.RunCommand("zfs", "list", "-H", "-o", "name", m.Dataset(pool, dataset))
+	return err == nil, nil
+}
+
+func (m linuxZFSPoolManager) Grow(pool string, devicePaths []string) error {
+	for _, devicePath := range devicePaths {
+		_, _, _, err := m.runner.RunCommand("zpool", "online", "-e", pool, devicePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Expanding pool %s onto %s", pool, devicePath)
+		}
+	}
+
+	return nil
+}
+
+func (m linuxZFSPoolManager) Snapshot(dataset, snapshot string) error {
+	snapshotName := dataset + "@" + snapshot
+
+	_, _, _, err := m.runner.RunCommand("zfs", "list", "-H", "-t", "snapshot", "-o", "name", snapshotName)
+	if err == nil {
+		m.logger.Info(m.logTag, "Snapshot %s already exists", snapshotName)
+		return nil
+	}
+
+	_, _, _, err = m.runner.RunCommand("zfs", "snapshot", snapshotName)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating snapshot %s", snapshotName)
+	}
+
+	return nil
+}
+
+func (m linuxZFSPoolManager) Export(pool string) error {
+	_, _, _, err := m.runner.RunCommand("zpool", "export", pool)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Exporting pool %s", pool)
+	}
+
+	return nil
+}
+
+func (m linuxZFSPoolManager) Dataset(pool, dataset string) string {
+	return pool + "/" + dataset
+}
+
+// importPool returns whether the pool is imported after trying to import it
+// from devices it was created on; failing to import a pool that is found is an error
+// so that it is never mistaken for a missing pool
+func (m linuxZFSPoolManager) importPool(pool string) (bool, error) {
+	_, _, _, err := m.runner.RunCommand("zpool", "list", "-H", "-o", "name", pool)
+	if err == nil {
+		return true, nil
+	}
+
+	found, err := m.importablePool(pool)
+	if err != nil || !found {
+		return false, err
+	}
+
+	// pool was last imported by the previous VM which had a different hostid
+	_, _, _, err = m.runner.RunCommand("zpool", "import", "-f", pool)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Importing pool %s", pool)
+	}
+
+	return true, nil
+}
+
+// importablePool returns whether the pool is listed among pools that can be imported
+func (m linuxZFSPoolManager) importablePool(pool string) (bool, error) {
+	stdout, stderr, _, err := m.runner.RunCommand("zpool", "import")
+	if err != nil {
+		if strings.Contains(stderr, "no pools available") {
+			return false, nil
+		}
+
+		return false, bosherr.WrapError(err, "Listing pools to import")
+	}
+
+	// e.g. '   pool: bosh_disk1'
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "pool:" && fields[1] == pool {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
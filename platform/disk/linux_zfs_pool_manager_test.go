@@ -0,0 +1,223 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxZFSPoolManager", func() {
+	var (
+		runner *fakesys.FakeCmdRunner
+		zfs    ZFSPoolManager
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		zfs = NewLinuxZFSPoolManager(runner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Create", func() {
+		Context("when pool does not exist", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("zpool list -H -o name fake-pool", fakesys.FakeCmdResult{Error: errors.New("no such pool")})
+				runner.AddCmdResult("zpool import", fakesys.FakeCmdResult{
+					Stderr: "no pools available to import\n",
+					Error:  errors.New("exit status 1"),
+				})
+				runner.AddCmdResult("zfs list -H -o name fake-pool/fake-dataset", fakesys.FakeCmdResult{Error: errors.New("not found")})
+			})
+
+			It("creates pool and dataset with legacy mount point", func() {
+				dataset, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(dataset).To(Equal("fake-pool/fake-dataset"))
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"zpool", "list", "-H", "-o", "name", "fake-pool"},
+					{"zpool", "import"},
+					{"blkid", "-p", "/dev/sdb"},
+					{"lsblk", "-n", "-r", "-o", "TYPE", "/dev/sdb"},
+					{"zpool", "create", "-f", "-o", "ashift=12", "-O", "mountpoint=none", "fake-pool", "/dev/sdb"},
+					{"zfs", "list", "-H", "-o", "name", "fake-pool/fake-dataset"},
+					{"zfs", "create", "-o", "mountpoint=legacy", "fake-pool/fake-dataset"},
+				}))
+			})
+
+			It("refuses to create pool on device with existing filesystem", func() {
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{Stdout: `/dev/sdb: UUID="fake-uuid" TYPE="ext4"`})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Refusing to create pool fake-pool on /dev/sdb with existing ext4 filesystem"))
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("create")))
+			})
+
+			It("refuses to create pool on device with label of another pool", func() {
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{Stdout: `/dev/sdb: LABEL="other-pool" TYPE="zfs_member"`})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("with existing zfs_member filesystem"))
+			})
+
+			It("refuses to create pool on partitioned device", func() {
+				runner.AddCmdResult("blkid -p /dev/sdb", fakesys.FakeCmdResult{Stdout: `/dev/sdb: PTUUID="fake-uuid" PTTYPE="dos"`})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Refusing to create pool fake-pool on /dev/sdb with existing dos partition table"))
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("create")))
+			})
+
+			It("refuses to create pool on device with partitions", func() {
+				runner.AddCmdResult("lsblk -n -r -o TYPE /dev/sdb", fakesys.FakeCmdResult{Stdout: "disk\npart\n"})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal("Refusing to create pool fake-pool on /dev/sdb with existing partitions"))
+				Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("create")))
+			})
+
+			It("returns error if creating pool fails", func() {
+				runner.AddCmdResult("zpool create -f -o ashift=12 -O mountpoint=none fake-pool /dev/sdb", fakesys.FakeCmdResult{Error: errors.New("fake-zpool-create-err")})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-zpool-create-err"))
+			})
+
+			It("returns error if creating dataset fails", func() {
+				runner.AddCmdResult("zfs create -o mountpoint=legacy fake-pool/fake-dataset", fakesys.FakeCmdResult{Error: errors.New("fake-zfs-create-err")})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-zfs-create-err"))
+			})
+		})
+
+		Context("when pool was exported", func() {
+			BeforeEach(func() {
+				runner.AddCmdResult("zpool list -H -o name fake-pool", fakesys.FakeCmdResult{Error: errors.New("no such pool")})
+				runner.AddCmdResult("zpool import", fakesys.FakeCmdResult{
+					Stdout: "   pool: other-pool\n     id: 1\n  state: ONLINE\n\n   pool: fake-pool\n     id: 2\n  state: ONLINE\n",
+				})
+			})
+
+			It("imports pool last imported by another host instead of creating it", func() {
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(runner.RunCommands).To(Equal([][]string{
+					{"zpool", "list", "-H", "-o", "name", "fake-pool"},
+					{"zpool", "import"},
+					{"zpool", "import", "-f", "fake-pool"},
+					{"zfs", "list", "-H", "-o", "name", "fake-pool/fake-dataset"},
+				}))
+			})
+
+			It("returns error without creating pool if importing it fails", func() {
+				runner.AddCmdResult("zpool import -f fake-pool", fakesys.FakeCmdResult{Error: errors.New("fake-import-err")})
+
+				_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-import-err"))
+				Expect(runner.RunCommands).To(HaveLen(3))
+			})
+		})
+
+		It("returns error without creating pool if listing pools to import fails", func() {
+			runner.AddCmdResult("zpool list -H -o name fake-pool", fakesys.FakeCmdResult{Error: errors.New("no such pool")})
+			runner.AddCmdResult("zpool import", fakesys.FakeCmdResult{
+				Stderr: "fake-zpool-import-stderr",
+				Error:  errors.New("fake-zpool-import-err"),
+			})
+
+			_, err := zfs.Create("fake-pool", "fake-dataset", []string{"/dev/sdb"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-zpool-import-err"))
+			Expect(runner.RunCommands).To(HaveLen(2))
+		})
+	})
+
+	Describe("Exists", func() {
+		It("returns true when dataset exists", func() {
+			exists, err := zfs.Exists("fake-pool", "fake-dataset")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeTrue())
+		})
+
+		It("returns false when pool is not found", func() {
+			runner.AddCmdResult("zpool list -H -o name fake-pool", fakesys.FakeCmdResult{Error: errors.New("no such pool")})
+			runner.AddCmdResult("zpool import", fakesys.FakeCmdResult{Stdout: "   pool: other-pool\n     id: 1\n"})
+
+			exists, err := zfs.Exists("fake-pool", "fake-dataset")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+
+		It("returns error when pool is found but cannot be imported", func() {
+			runner.AddCmdResult("zpool list -H -o name fake-pool", fakesys.FakeCmdResult{Error: errors.New("no such pool")})
+			runner.AddCmdResult("zpool import", fakesys.FakeCmdResult{Stdout: "   pool: fake-pool\n     id: 1\n"})
+			runner.AddCmdResult("zpool import -f fake-pool", fakesys.FakeCmdResult{Error: errors.New("fake-import-err")})
+
+			_, err := zfs.Exists("fake-pool", "fake-dataset")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-import-err"))
+		})
+
+		It("returns false when dataset does not exist", func() {
+			runner.AddCmdResult("zfs list -H -o name fake-pool/fake-dataset", fakesys.FakeCmdResult{Error: errors.New("not found")})
+
+			exists, err := zfs.Exists("fake-pool", "fake-dataset")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(exists).To(BeFalse())
+		})
+	})
+
+	Describe("Grow", func() {
+		It("expands pool onto grown devices", func() {
+			err := zfs.Grow("fake-pool", []string{"/dev/sdb"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"zpool", "online", "-e", "fake-pool", "/dev/sdb"},
+			}))
+		})
+	})
+
+	Describe("Snapshot", func() {
+		It("creates snapshot of the dataset", func() {
+			runner.AddCmdResult("zfs list -H -t snapshot -o name fake-pool/fake-dataset@fake-snapshot", fakesys.FakeCmdResult{Error: errors.New("not found")})
+
+			err := zfs.Snapshot("fake-pool/fake-dataset", "fake-snapshot")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands[1]).To(Equal([]string{"zfs", "snapshot", "fake-pool/fake-dataset@fake-snapshot"}))
+		})
+
+		It("does not create snapshot again if it already exists", func() {
+			err := zfs.Snapshot("fake-pool/fake-dataset", "fake-snapshot")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"zfs", "list", "-H", "-t", "snapshot", "-o", "name", "fake-pool/fake-dataset@fake-snapshot"},
+			}))
+		})
+	})
+
+	Describe("Export", func() {
+		It("returns error if exporting pool fails", func() {
+			runner.AddCmdResult("zpool export fake-pool", fakesys.FakeCmdResult{Error: errors.New("fake-export-err")})
+
+			err := zfs.Export("fake-pool")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-export-err"))
+		})
+	})
+})
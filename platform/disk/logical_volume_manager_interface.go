@@ -0,0 +1,20 @@
+package disk
+
+type LogicalVolumeManager interface {
+	// Create makes sure that devices are physical volumes of the volume group
+	// and that the logical volume spanning the volume group exists and is active.
+	// Returns device path of the logical volume.
+	Create(volumeGroup, logicalVolume string, devicePaths []string) (string, error)
+
+	Exists(volumeGroup, logicalVolume string) (bool, error)
+
+	// Grow resizes physical volumes to their devices and extends
+	// the logical volume over all free space of the volume group.
+	Grow(volumeGroup, logicalVolume string, devicePaths []string) error
+
+	// Deactivate makes logical volumes of the volume group unavailable
+	// so that underlying devices can be safely detached.
+	Deactivate(volumeGroup string) error
+
+	Path(volumeGroup, logicalVolume string) string
+}
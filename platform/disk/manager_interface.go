@@ -11,5 +11,10 @@ type Manager interface {
 	GetFormatter() Formatter
 	GetMounter() Mounter
 	GetMountsSearcher() MountsSearcher
+	GetLogicalVolumeManager() LogicalVolumeManager
+	GetZFSPoolManager() ZFSPoolManager
+	GetEncryptor() Encryptor
+	GetRAIDManager() RAIDManager
+	GetFilesystemChecker() FilesystemChecker
 	GetDiskUtil(diskPath string) boshdevutil.DeviceUtil
 }
@@ -3,6 +3,9 @@ package disk
 type Mount struct {
 	PartitionPath string
 	MountPoint    string
+
+	// Options the filesystem is mounted with as reported by the kernel, e.g. [rw noatime]
+	Options []string
 }
 
 type MountsSearcher interface {
@@ -13,6 +13,13 @@ import (
 	"github.com/pivotal-golang/clock"
 )
 
+const (
+	partedAlignmentInBytes = uint64(1048576)
+
+	// msdos partition tables address at most 2^32 sectors of 512 bytes
+	msdosMaxAddressableBytes = uint64(2 * 1024 * 1024 * 1024 * 1024)
+)
+
 type partedPartitioner struct {
 	logger      boshlog.Logger
 	cmdRunner   boshsys.CmdRunner
@@ -30,18 +37,30 @@ func NewPartedPartitioner(logger boshlog.Logger, cmdRunner boshsys.CmdRunner, ti
 }
 
 func (p partedPartitioner) Partition(devicePath string, partitions []Partition) error {
-	existingPartitions, deviceFullSizeInBytes, err := p.getPartitions(devicePath)
+	existingPartitions, deviceFullSizeInBytes, partitionTableType, err := p.getPartitions(devicePath)
 	if err != nil {
 		return bosherr.WrapErrorf(err, "Getting existing partitions of `%s'", devicePath)
 	}
 
 	if p.partitionsMatch(existingPartitions, partitions) {
+		if partitionTableType == "msdos" && deviceFullSizeInBytes > msdosMaxAddressableBytes {
+			p.logger.Warn(p.logTag, "%s has an msdos partition table, space beyond 2TiB is not used", devicePath)
+		}
 		return nil
 	}
 
+	// Empty msdos partition tables (e.g. left by sfdisk) cannot address disks larger than 2TiB
+	if len(existingPartitions) == 0 && partitionTableType == "msdos" {
+		_, _, _, err = p.getPartitionTable(devicePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Replacing msdos partition table of `%s' with gpt", devicePath)
+		}
+		partitionTableType = "gpt"
+	}
+
 	partitionStart := p.decideFirstPartitionStartingPoint(existingPartitions)
 
-	if err = p.createEachPartition(partitions, partitionStart, deviceFullSizeInBytes, devicePath); err != nil {
+	if err = p.createEachPartition(partitions, partitionStart, deviceFullSizeInBytes, partitionTableType, devicePath); err != nil {
 		return err
 	}
 
@@ -92,6 +111,12 @@ func (p partedPartitioner) partitionsMatch(existingPartitions []existingPartitio
 
 	for index, partition := range partitions {
 		existingPartition := existingPartitions[index]
+
+		// Partitions without size take the rest of the disk, which may have been grown since
+		if partition.SizeInBytes == 0 {
+			continue
+		}
+
 		if !withinDelta(partition.SizeInBytes, existingPartition.SizeInBytes, p.convertFromMbToBytes(20)) {
 			return false
 		}
@@ -100,22 +125,27 @@ func (p partedPartitioner) partitionsMatch(existingPartitions []existingPartitio
 	return true
 }
 
-func (p partedPartitioner) getPartitions(devicePath string) (partitions []existingPartition, deviceFullSizeInBytes uint64, err error) {
+func (p partedPartitioner) getPartitions(devicePath string) (partitions []existingPartition, deviceFullSizeInBytes uint64, partitionTableType string, err error) {
 	stdout, _, _, err := p.runPartedPrint(devicePath)
 
 	if err != nil {
-		return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Running parted print")
+		return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Running parted print")
 	}
 
 	allLines := strings.Split(stdout, "\n")
 	if len(allLines) < 2 {
-		return partitions, deviceFullSizeInBytes, bosherr.Errorf("Parsing existing partitions")
+		return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.Errorf("Parsing existing partitions")
 	}
 
+	// e.g. '/dev/xvdf:221190815744B:xvd:512:512:gpt:Xen Virtual Block Device;'
 	deviceInfo := strings.Split(allLines[1], ":")
 	deviceFullSizeInBytes, err = strconv.ParseUint(strings.TrimRight(deviceInfo[1], "B"), 10, 64)
 	if err != nil {
-		return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Parsing device size")
+		return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Parsing device size")
+	}
+
+	if len(deviceInfo) > 5 {
+		partitionTableType = deviceInfo[5]
 	}
 
 	partitionLines := allLines[2 : len(allLines)-1]
@@ -129,22 +159,22 @@ func (p partedPartitioner) getPartitions(devicePath string) (partitions []existi
 		partitionIndex, err := strconv.Atoi(partitionInfo[0])
 
 		if err != nil {
-			return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Parsing existing partitions")
+			return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Parsing existing partitions")
 		}
 
 		partitionStartInBytes, err := strconv.Atoi(strings.TrimRight(partitionInfo[1], "B"))
 		if err != nil {
-			return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Parsing existing partitions")
+			return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Parsing existing partitions")
 		}
 
 		partitionEndInBytes, err := strconv.Atoi(strings.TrimRight(partitionInfo[2], "B"))
 		if err != nil {
-			return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Parsing existing partitions")
+			return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Parsing existing partitions")
 		}
 
 		partitionSizeInBytes, err := strconv.Atoi(strings.TrimRight(partitionInfo[3], "B"))
 		if err != nil {
-			return partitions, deviceFullSizeInBytes, bosherr.WrapErrorf(err, "Parsing existing partitions")
+			return partitions, deviceFullSizeInBytes, partitionTableType, bosherr.WrapErrorf(err, "Parsing existing partitions")
 		}
 
 		partitions = append(
@@ -158,7 +188,7 @@ func (p partedPartitioner) getPartitions(devicePath string) (partitions []existi
 		)
 	}
 
-	return partitions, deviceFullSizeInBytes, nil
+	return partitions, deviceFullSizeInBytes, partitionTableType, nil
 }
 
 func (p partedPartitioner) convertFromBytesToMb(sizeInBytes uint64) uint64 {
@@ -230,14 +260,20 @@ func (p partedPartitioner) decideFirstPartitionStartingPoint(existingPartitions
 		partitionStart = existingPartitions[len(existingPartitions)-1].EndInBytes + 1
 	}
 
-	alignmentInBytes := uint64(1048576)
-	partitionStart = p.roundUp(partitionStart, alignmentInBytes)
+	partitionStart = p.roundUp(partitionStart, partedAlignmentInBytes)
 	return partitionStart
 }
 
-func (p partedPartitioner) createEachPartition(partitions []Partition, partitionStart uint64, deviceFullSizeInBytes uint64, devicePath string) error {
+func (p partedPartitioner) createEachPartition(partitions []Partition, partitionStart uint64, deviceFullSizeInBytes uint64, partitionTableType string, devicePath string) error {
+	alignmentInBytes := partedAlignmentInBytes
+
+	type partitionBounds struct {
+		start, end uint64
+	}
+
+	var bounds []partitionBounds
+
 	//For each Parition
-	alignmentInBytes := uint64(1048576)
 	for index, partition := range partitions {
 
 		//Get end point for partition
@@ -245,7 +281,8 @@ func (p partedPartitioner) createEachPartition(partitions []Partition, partition
 
 		if partition.SizeInBytes == 0 {
 			// If no partitions were specified, use the whole disk space
-			partitionEnd = p.roundDown(deviceFullSizeInBytes-1, alignmentInBytes)
+			// leaving the last aligned MiB for the backup GPT header
+			partitionEnd = p.roundDown(deviceFullSizeInBytes-alignmentInBytes, alignmentInBytes) - 1
 		} else {
 			partitionEnd = partitionStart + partition.SizeInBytes
 			// If the partition size is greater than the remaining space on disk, truncate the partition to whatever size is left
@@ -256,6 +293,20 @@ func (p partedPartitioner) createEachPartition(partitions []Partition, partition
 			partitionEnd = p.roundDown(partitionEnd, alignmentInBytes) - 1
 		}
 
+		// Nothing is created unless all partitions fit, to avoid silently truncating the disk
+		if partitionTableType == "msdos" && partitionEnd >= msdosMaxAddressableBytes {
+			return bosherr.Errorf("Partition %d on `%s' would end beyond 2TiB which msdos partition table cannot address", index, devicePath)
+		}
+
+		bounds = append(bounds, partitionBounds{start: partitionStart, end: partitionEnd})
+
+		//increment
+		partitionStart = p.roundUp(partitionEnd+1, alignmentInBytes)
+	}
+
+	for index, partition := range bounds {
+		partitionStart, partitionEnd := partition.start, partition.end
+
 		// Create and run a retryable
 		partitionRetryable := boshretry.NewRetryable(func() (bool, error) {
 			_, _, _, err := p.cmdRunner.RunCommand(
@@ -284,9 +335,6 @@ func (p partedPartitioner) createEachPartition(partitions []Partition, partition
 		if err != nil {
 			return bosherr.WrapErrorf(err, "Partitioning disk `%s'", devicePath)
 		}
-
-		//increment
-		partitionStart = p.roundUp(partitionEnd+1, alignmentInBytes)
 	}
 	return nil
 }
@@ -164,6 +164,56 @@ var _ = Describe("PartedPartitioner", func() {
 				})
 			})
 
+			Context("when there is an empty msdos partition table on a disk larger than 2TiB", func() {
+				BeforeEach(func() {
+					fakeCmdRunner.AddCmdResult(
+						"parted -m /dev/sda unit B print",
+						fakesys.FakeCmdResult{
+							Stdout: `BYT;
+/dev/xvdf:3298534883328B:xvd:512:512:msdos:Xen Virtual Block Device;
+`},
+					)
+				})
+
+				It("replaces it with a gpt label and creates partition spanning the whole disk", func() {
+					err := partitioner.Partition("/dev/sda", []Partition{{Type: PartitionTypeLinux}})
+					Expect(err).ToNot(HaveOccurred())
+
+					// 3298534883328 - 1048576 = 3298533834752 (aligned, last MiB is left for the backup GPT header)
+					Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{
+						{"parted", "-m", "/dev/sda", "unit", "B", "print"},
+						{"parted", "-s", "/dev/sda", "mklabel", "gpt"},
+						{"parted", "-s", "/dev/sda", "unit", "B", "mkpart", "primary", "1048576", "3298533834751"},
+					}))
+				})
+			})
+
+			Context("when existing msdos partition table cannot address the partition", func() {
+				BeforeEach(func() {
+					fakeCmdRunner.AddCmdResult(
+						"parted -m /dev/sda unit B print",
+						fakesys.FakeCmdResult{
+							Stdout: `BYT;
+/dev/xvdf:3298534883328B:xvd:512:512:msdos:Xen Virtual Block Device;
+1:1048576B:1073741823B:1072693248B:ext4::;
+`},
+					)
+				})
+
+				It("returns error instead of truncating the partition", func() {
+					err := partitioner.Partition("/dev/sda", []Partition{
+						{SizeInBytes: 1073741824},
+						{Type: PartitionTypeLinux},
+					})
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("msdos partition table cannot address"))
+
+					Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{
+						{"parted", "-m", "/dev/sda", "unit", "B", "print"},
+					}))
+				})
+			})
+
 			Context("when the required partition over-flows the device", func() {
 				BeforeEach(func() {
 					fakeCmdRunner.AddCmdResult(
@@ -262,6 +312,28 @@ var _ = Describe("PartedPartitioner", func() {
 			})
 		})
 
+		Context("when existing partition takes the rest of a disk that has since grown", func() {
+			BeforeEach(func() {
+				fakeCmdRunner.AddCmdResult(
+					"parted -m /dev/sda unit B print",
+					fakesys.FakeCmdResult{
+						Stdout: `BYT;
+/dev/xvdf:4398046511104B:xvd:512:512:gpt:Xen Virtual Block Device;
+1:1048576B:3298533834751B:3298532786176B:ext4::;
+`},
+				)
+			})
+
+			It("does not repartition the disk", func() {
+				err := partitioner.Partition("/dev/sda", []Partition{{Type: PartitionTypeLinux}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{
+					{"parted", "-m", "/dev/sda", "unit", "B", "print"},
+				}))
+			})
+		})
+
 		Context("when getting existing partitions returns an error", func() {
 			Context("when the first call to parted print fails", func() {
 				BeforeEach(func() {
@@ -1,12 +1,16 @@
 package disk
 
 import (
+	"path"
+	"regexp"
 	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+var deviceMapperNodeRegexp = regexp.MustCompile(`^/dev/dm-\d+$`)
+
 type procMountsSearcher struct {
 	fs boshsys.FileSystem
 }
@@ -30,11 +34,32 @@ func (s procMountsSearcher) SearchMounts() ([]Mount, error) {
 
 		mountFields := strings.Fields(mountEntry)
 
-		mounts = append(mounts, Mount{
-			PartitionPath: mountFields[0],
+		mount := Mount{
+			PartitionPath: s.deviceMapperPath(mountFields[0]),
 			MountPoint:    mountFields[1],
-		})
+		}
+
+		if len(mountFields) > 3 {
+			mount.Options = strings.Split(mountFields[3], ",")
+		}
+
+		mounts = append(mounts, mount)
 	}
 
 	return mounts, nil
 }
+
+// deviceMapperPath returns /dev/mapper/<name> for device mapper nodes (e.g.
+// /dev/dm-3 of a dm-multipath map) since that is the path used when mounting
+func (s procMountsSearcher) deviceMapperPath(partitionPath string) string {
+	if !deviceMapperNodeRegexp.MatchString(partitionPath) {
+		return partitionPath
+	}
+
+	name, err := s.fs.ReadFileString(path.Join("/sys/block", path.Base(partitionPath), "dm", "name"))
+	if err != nil || strings.TrimSpace(name) == "" {
+		return partitionPath
+	}
+
+	return path.Join("/dev/mapper", strings.TrimSpace(name))
+}
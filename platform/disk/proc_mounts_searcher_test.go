@@ -35,10 +35,26 @@ none /tmp/warden/cgroup tmpfs rw,relatime 0 0`,
 				mounts, err := searcher.SearchMounts()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(mounts).To(Equal([]Mount{
-					Mount{PartitionPath: "none", MountPoint: "/run/lock"},
-					Mount{PartitionPath: "none", MountPoint: "/run/shm"},
-					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot"},
-					Mount{PartitionPath: "none", MountPoint: "/tmp/warden/cgroup"},
+					Mount{PartitionPath: "none", MountPoint: "/run/lock", Options: []string{"rw", "nosuid", "nodev", "noexec", "relatime", "size=5120k"}},
+					Mount{PartitionPath: "none", MountPoint: "/run/shm", Options: []string{"rw", "nosuid", "nodev", "relatime"}},
+					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot", Options: []string{"rw", "relatime", "errors=continue"}},
+					Mount{PartitionPath: "none", MountPoint: "/tmp/warden/cgroup", Options: []string{"rw", "relatime"}},
+				}))
+			})
+
+			It("returns device mapper paths for device mapper nodes", func() {
+				fs.WriteFileString("/sys/block/dm-3/dm/name", "mpatha-part1\n")
+				fs.WriteFileString(
+					"/proc/mounts",
+					`/dev/dm-3 /var/vcap/store ext4 rw,relatime 0 0
+/dev/dm-4 /var/vcap/data ext4 rw,relatime 0 0`,
+				)
+
+				mounts, err := searcher.SearchMounts()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounts).To(Equal([]Mount{
+					Mount{PartitionPath: "/dev/mapper/mpatha-part1", MountPoint: "/var/vcap/store", Options: []string{"rw", "relatime"}},
+					Mount{PartitionPath: "/dev/dm-4", MountPoint: "/var/vcap/data", Options: []string{"rw", "relatime"}},
 				}))
 			})
 
@@ -54,8 +70,8 @@ none /run/shm tmpfs rw,nosuid,nodev,relatime 0 0
 				mounts, err := searcher.SearchMounts()
 				Expect(err).ToNot(HaveOccurred())
 				Expect(mounts).To(Equal([]Mount{
-					Mount{PartitionPath: "none", MountPoint: "/run/shm"},
-					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot"},
+					Mount{PartitionPath: "none", MountPoint: "/run/shm", Options: []string{"rw", "nosuid", "nodev", "relatime"}},
+					Mount{PartitionPath: "/dev/sda1", MountPoint: "/boot", Options: []string{"rw", "relatime", "errors=continue"}},
 				}))
 			})
 		})
@@ -0,0 +1,12 @@
+package disk
+
+type RAIDManager interface {
+	// Assemble makes sure that the RAID-0 array striped across the devices is
+	// active; an existing array (e.g. after reboot) is reassembled, otherwise
+	// a new array is created. Returns device path of the array.
+	Assemble(name string, devicePaths []string) (string, error)
+
+	IsActive(name string) bool
+
+	Path(name string) string
+}
@@ -0,0 +1,23 @@
+package disk
+
+type ZFSPoolManager interface {
+	// Create makes sure that the pool spanning devices is imported
+	// and that the dataset exists with legacy mount point so that
+	// it can be mounted with mount command like any other filesystem.
+	// Returns name of the dataset.
+	Create(pool, dataset string, devicePaths []string) (string, error)
+
+	Exists(pool, dataset string) (bool, error)
+
+	// Grow expands pool devices over space added to them.
+	Grow(pool string, devicePaths []string) error
+
+	// Snapshot creates snapshot of the dataset unless it already exists.
+	Snapshot(dataset, snapshot string) error
+
+	// Export makes the pool unavailable so that
+	// underlying devices can be safely detached.
+	Export(pool string) error
+
+	Dataset(pool, dataset string) string
+}
@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+)
+
+// DiskMigrationProgress describes copying of data onto a new persistent disk
+type DiskMigrationProgress struct {
+	BytesCopied uint64 `json:"bytes_copied"`
+	BytesTotal  uint64 `json:"bytes_total"`
+
+	// Omitted until anything is copied and copying rate is known
+	ETAInSeconds uint64 `json:"eta_in_seconds,omitempty"`
+}
+
+// diskMigrationTracker estimates progress from disk usage of both disks;
+// data already on the new disk (e.g. when resuming) does not count towards copying rate
+type diskMigrationTracker struct {
+	collector   boshstats.Collector
+	timeService clock.Clock
+
+	lock sync.Mutex
+
+	active             bool
+	fromMountPoint     string
+	toMountPoint       string
+	startedAt          time.Time
+	initialBytesCopied uint64
+}
+
+func newDiskMigrationTracker(collector boshstats.Collector, timeService clock.Clock) *diskMigrationTracker {
+	return &diskMigrationTracker{
+		collector:   collector,
+		timeService: timeService,
+	}
+}
+
+func (t *diskMigrationTracker) Start(fromMountPoint, toMountPoint string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.active = true
+	t.fromMountPoint = fromMountPoint
+	t.toMountPoint = toMountPoint
+	t.startedAt = t.timeService.Now()
+	t.initialBytesCopied = t.usedBytes(toMountPoint)
+}
+
+func (t *diskMigrationTracker) Stop() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.active = false
+}
+
+func (t *diskMigrationTracker) Progress() (DiskMigrationProgress, bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if !t.active {
+		return DiskMigrationProgress{}, false
+	}
+
+	progress := DiskMigrationProgress{
+		BytesCopied: t.usedBytes(t.toMountPoint),
+		BytesTotal:  t.usedBytes(t.fromMountPoint),
+	}
+
+	// Filesystems may account for the same files slightly differently
+	if progress.BytesCopied > progress.BytesTotal {
+		progress.BytesCopied = progress.BytesTotal
+	}
+
+	elapsedSeconds := uint64(t.timeService.Now().Sub(t.startedAt).Seconds())
+
+	if progress.BytesCopied > t.initialBytesCopied && elapsedSeconds > 0 {
+		bytesPerSecond := (progress.BytesCopied - t.initialBytesCopied) / elapsedSeconds
+		if bytesPerSecond > 0 {
+			progress.ETAInSeconds = (progress.BytesTotal - progress.BytesCopied) / bytesPerSecond
+		}
+	}
+
+	return progress, true
+}
+
+func (t *diskMigrationTracker) usedBytes(mountPoint string) uint64 {
+	stats, err := t.collector.GetDiskStats(mountPoint)
+	if err != nil {
+		return 0
+	}
+	// Disk usage is collected in KB
+	return stats.DiskUsage.Used * 1024
+}
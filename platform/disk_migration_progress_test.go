@@ -0,0 +1,76 @@
+package platform_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/cloudfoundry/bosh-agent/platform"
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+)
+
+var _ = Describe("diskMigrationTracker", func() {
+	var (
+		collector   *fakestats.FakeCollector
+		timeService *fakeclock.FakeClock
+		tracker     DiskMigrationTracker
+	)
+
+	// Disk usage is in KB
+	usage := func(used uint64) boshstats.DiskStats {
+		return boshstats.DiskStats{DiskUsage: boshstats.Usage{Used: used, Total: 10000}}
+	}
+
+	BeforeEach(func() {
+		collector = &fakestats.FakeCollector{
+			DiskStats: map[string]boshstats.DiskStats{
+				"/from": usage(1000),
+				"/to":   usage(100),
+			},
+		}
+		timeService = fakeclock.NewFakeClock(time.Now())
+		tracker = NewDiskMigrationTracker(collector, timeService)
+	})
+
+	It("does not report progress unless migration is running", func() {
+		_, found := tracker.Progress()
+		Expect(found).To(BeFalse())
+
+		tracker.Start("/from", "/to")
+		tracker.Stop()
+
+		_, found = tracker.Progress()
+		Expect(found).To(BeFalse())
+	})
+
+	It("reports bytes copied without ETA until anything is copied", func() {
+		tracker.Start("/from", "/to")
+
+		progress, found := tracker.Progress()
+		Expect(found).To(BeTrue())
+		Expect(progress).To(Equal(DiskMigrationProgress{BytesCopied: 100 * 1024, BytesTotal: 1000 * 1024}))
+	})
+
+	It("estimates remaining time from bytes copied since migration started", func() {
+		tracker.Start("/from", "/to")
+
+		timeService.Increment(10 * time.Second)
+		collector.DiskStats["/to"] = usage(300)
+
+		progress, found := tracker.Progress()
+		Expect(found).To(BeTrue())
+		Expect(progress).To(Equal(DiskMigrationProgress{BytesCopied: 300 * 1024, BytesTotal: 1000 * 1024, ETAInSeconds: 35}))
+	})
+
+	It("does not report more bytes copied than there are in total", func() {
+		tracker.Start("/from", "/to")
+
+		collector.DiskStats["/to"] = usage(1010)
+
+		progress, _ := tracker.Progress()
+		Expect(progress.BytesCopied).To(Equal(uint64(1000 * 1024)))
+	})
+})
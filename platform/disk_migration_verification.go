@@ -0,0 +1,246 @@
+package platform
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	diskMigrationChecksumSampleSize = 100
+
+	// Number of paths of each kind of difference included in the error message
+	diskMigrationMaxReportedPaths = 5
+)
+
+// DiskMigrationVerificationError describes differences between
+// files on the old and the new persistent disk after migration
+type DiskMigrationVerificationError struct {
+	FilesExpected int `json:"files_expected"`
+	FilesCopied   int `json:"files_copied"`
+
+	MissingFiles       []string `json:"missing_files,omitempty"`
+	UnexpectedFiles    []string `json:"unexpected_files,omitempty"`
+	SizeMismatches     []string `json:"size_mismatches,omitempty"`
+	ChecksumMismatches []string `json:"checksum_mismatches,omitempty"`
+}
+
+func (e DiskMigrationVerificationError) Error() string {
+	differences := []string{}
+
+	describe := func(kind string, paths []string) {
+		if len(paths) == 0 {
+			return
+		}
+
+		reported := paths
+		if len(reported) > diskMigrationMaxReportedPaths {
+			reported = reported[:diskMigrationMaxReportedPaths]
+		}
+
+		description := fmt.Sprintf("%d %s (%s", len(paths), kind, strings.Join(reported, ", "))
+		if len(paths) > len(reported) {
+			description += ", ..."
+		}
+
+		differences = append(differences, description+")")
+	}
+
+	describe("missing files", e.MissingFiles)
+	describe("unexpected files", e.UnexpectedFiles)
+	describe("files with different size", e.SizeMismatches)
+	describe("files with different checksum", e.ChecksumMismatches)
+
+	return fmt.Sprintf(
+		"Copied %d of %d files with differences: %s",
+		e.FilesCopied, e.FilesExpected, strings.Join(differences, "; "),
+	)
+}
+
+func (e DiskMigrationVerificationError) hasDifferences() bool {
+	return len(e.MissingFiles) > 0 || len(e.UnexpectedFiles) > 0 ||
+		len(e.SizeMismatches) > 0 || len(e.ChecksumMismatches) > 0
+}
+
+// verifyPersistentDiskCopy compares file lists and sizes of both disks and then
+// checksums of either a sample or all of the files
+func (p linux) verifyPersistentDiskCopy(fromMountPoint, toMountPoint string) error {
+	fromFiles, err := p.listFileSizes(fromMountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Listing files on %s", fromMountPoint)
+	}
+
+	toFiles, err := p.listFileSizes(toMountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Listing files on %s", toMountPoint)
+	}
+
+	verificationErr := DiskMigrationVerificationError{
+		FilesExpected: len(fromFiles),
+		FilesCopied:   len(toFiles),
+	}
+
+	var comparableFiles []string
+
+	for path, size := range fromFiles {
+		copiedSize, found := toFiles[path]
+
+		switch {
+		case !found:
+			verificationErr.MissingFiles = append(verificationErr.MissingFiles, path)
+		case copiedSize != size:
+			verificationErr.SizeMismatches = append(verificationErr.SizeMismatches, path)
+		default:
+			comparableFiles = append(comparableFiles, path)
+		}
+	}
+
+	for path := range toFiles {
+		if _, found := fromFiles[path]; !found {
+			verificationErr.UnexpectedFiles = append(verificationErr.UnexpectedFiles, path)
+		}
+	}
+
+	sort.Strings(comparableFiles)
+
+	checksummedFiles := comparableFiles
+	if !p.options.FullPersistentDiskMigrationVerification {
+		checksummedFiles = sampleFiles(comparableFiles, diskMigrationChecksumSampleSize)
+	}
+
+	if len(checksummedFiles) > 0 {
+		fromChecksums, err := p.fileChecksums(fromMountPoint, checksummedFiles)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Calculating checksums of files on %s", fromMountPoint)
+		}
+
+		toChecksums, err := p.fileChecksums(toMountPoint, checksummedFiles)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Calculating checksums of files on %s", toMountPoint)
+		}
+
+		for _, path := range checksummedFiles {
+			if fromChecksums[path] != toChecksums[path] {
+				verificationErr.ChecksumMismatches = append(verificationErr.ChecksumMismatches, path)
+			}
+		}
+	}
+
+	if verificationErr.hasDifferences() {
+		sort.Strings(verificationErr.MissingFiles)
+		sort.Strings(verificationErr.UnexpectedFiles)
+		sort.Strings(verificationErr.SizeMismatches)
+
+		p.logger.Error(logTag, "Persistent disk migration verification failed: %#v", verificationErr)
+		return verificationErr
+	}
+
+	p.logger.Info(logTag, "Verified %d files and checksums of %d files copied onto new disk", len(toFiles), len(checksummedFiles))
+
+	return nil
+}
+
+// listFileSizes returns sizes of regular files keyed by path relative to mount point;
+// paths are NUL separated since file names may contain newlines
+func (p linux) listFileSizes(mountPoint string) (map[string]uint64, error) {
+	stdout, _, _, err := p.cmdRunner.RunCommand("find", mountPoint, "-xdev", "-type", "f", "-print0")
+	if err != nil {
+		return nil, err
+	}
+
+	paths := splitNULSeparated(stdout)
+
+	files := map[string]uint64{}
+	if len(paths) == 0 {
+		return files, nil
+	}
+
+	// stat prints sizes in the same order as paths are given
+	stdout, err = p.runForEachPath("/", paths, "stat", "--printf", `%s\0`, "--")
+	if err != nil {
+		return nil, err
+	}
+
+	sizes := splitNULSeparated(stdout)
+	if len(sizes) != len(paths) {
+		return nil, bosherr.Errorf("Expected sizes of %d files but got %d", len(paths), len(sizes))
+	}
+
+	for i, path := range paths {
+		size, err := strconv.ParseUint(sizes[i], 10, 64)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing size of '%s'", path)
+		}
+
+		relativePath := strings.TrimPrefix(strings.TrimPrefix(path, mountPoint), "/")
+		files[relativePath] = size
+	}
+
+	return files, nil
+}
+
+// fileChecksums returns SHA-256 checksums of given files keyed by path relative to mount point
+func (p linux) fileChecksums(mountPoint string, paths []string) (map[string]string, error) {
+	// sha256sum prints checksums in the same order as paths are given
+	stdout, err := p.runForEachPath(mountPoint, paths, "sha256sum", "-z", "--")
+	if err != nil {
+		return nil, err
+	}
+
+	// e.g. '9f86d08...  path/to/file\0'
+	lines := splitNULSeparated(stdout)
+	if len(lines) != len(paths) {
+		return nil, bosherr.Errorf("Expected checksums of %d files but got %d", len(paths), len(lines))
+	}
+
+	checksums := map[string]string{}
+
+	for i, path := range paths {
+		checksums[path] = strings.SplitN(lines[i], " ", 2)[0]
+	}
+
+	return checksums, nil
+}
+
+// runForEachPath runs command with given paths appended as arguments;
+// paths are passed on stdin since there may be too many of them for a command line
+func (p linux) runForEachPath(workingDir string, paths []string, name string, args ...string) (string, error) {
+	stdout, _, _, err := p.cmdRunner.RunComplexCommand(boshsys.Command{
+		Name:       "xargs",
+		Args:       append([]string{"-0", "-r", name}, args...),
+		WorkingDir: workingDir,
+		Stdin:      strings.NewReader(strings.Join(paths, "\x00")),
+	})
+
+	return stdout, err
+}
+
+func splitNULSeparated(output string) []string {
+	var values []string
+
+	for _, value := range strings.Split(output, "\x00") {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+
+	return values
+}
+
+// sampleFiles picks at most sampleSize files spread evenly over given sorted files
+func sampleFiles(files []string, sampleSize int) []string {
+	if len(files) <= sampleSize {
+		return files
+	}
+
+	sample := make([]string, 0, sampleSize)
+	for i := 0; i < sampleSize; i++ {
+		sample = append(sample, files[i*len(files)/sampleSize])
+	}
+
+	return sample
+}
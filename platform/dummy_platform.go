@@ -3,9 +3,13 @@ package platform
 import (
 	"encoding/json"
 	"path"
+	"sort"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -29,6 +33,19 @@ type diskMigration struct {
 
 const CredentialFileName = "password"
 
+// Dummy platform keeps its state in json files under bosh dir so that
+// it survives agent restarts and can be inspected by integration tests.
+const (
+	dummyMountsFileName         = "mounts.json"
+	dummyDiskMigrationsFileName = "disk_migrations.json"
+	dummyNetworksFileName       = "dummy-networks.json"
+	dummyTrustedCertsFileName   = "dummy-trusted-certs.pem"
+
+	// Maps operation names (e.g. "MountPersistentDisk") to error messages
+	// that the operation should fail with until the entry is removed.
+	dummyFaultsFileName = "dummy-faults.json"
+)
+
 type dummyPlatform struct {
 	collector          boshstats.Collector
 	fs                 boshsys.FileSystem
@@ -40,6 +57,7 @@ type dummyPlatform struct {
 	devicePathResolver boshdpresolv.DevicePathResolver
 	logger             boshlog.Logger
 	certManager        boshcert.Manager
+	cgroupManager      boshcgroup.Manager
 }
 
 func NewDummyPlatform(
@@ -59,7 +77,8 @@ func NewDummyPlatform(
 		dirProvider:        dirProvider,
 		devicePathResolver: devicePathResolver,
 		vitalsService:      boshvitals.NewService(collector, dirProvider),
-		certManager:        boshcert.NewDummyCertManager(fs, cmdRunner, 0, logger),
+		certManager:        newDummyCertManager(fs, dirProvider),
+		cgroupManager:      boshcgroup.NewDummyManager(),
 	}
 }
 
@@ -95,6 +114,10 @@ func (p dummyPlatform) SetupRuntimeConfiguration() (err error) {
 	return
 }
 
+func (p dummyPlatform) RunHooks(phase string) (err error) {
+	return
+}
+
 func (p dummyPlatform) CreateUser(username, password, basePath string) (err error) {
 	return
 }
@@ -124,18 +147,76 @@ func (p dummyPlatform) SetupHostname(hostname string) (err error) {
 	return
 }
 
-func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks) (err error) {
+func (p dummyPlatform) SetupTimezone(timezone string) (err error) {
+	return
+}
+
+func (p dummyPlatform) SetupLocale(locale string) (err error) {
+	return
+}
+
+func (p dummyPlatform) SetupProxy(proxy boshsettings.Proxy) (err error) {
 	return
 }
 
-func (p dummyPlatform) GetConfiguredNetworkInterfaces() (interfaces []string, err error) {
+func (p dummyPlatform) SetupHostsEntries(entries []boshsettings.HostsEntry) (err error) {
 	return
 }
 
+func (p dummyPlatform) SetupDNSCache(dnsCache boshsettings.DNSCache, networks boshsettings.Networks) (boshsettings.Networks, error) {
+	return networks, nil
+}
+
+func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks) error {
+	err := injectedFault(p.fs, p.dirProvider, "SetupNetworking")
+	if err != nil {
+		return err
+	}
+
+	networksJSON, err := json.Marshal(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling networks")
+	}
+
+	return p.fs.WriteFile(path.Join(p.dirProvider.BoshDir(), dummyNetworksFileName), networksJSON)
+}
+
+func (p dummyPlatform) GetConfiguredNetworkInterfaces() ([]string, error) {
+	networksPath := path.Join(p.dirProvider.BoshDir(), dummyNetworksFileName)
+	interfaces := []string{}
+
+	if !p.fs.FileExists(networksPath) {
+		return interfaces, nil
+	}
+
+	bytes, err := p.fs.ReadFile(networksPath)
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Reading networks")
+	}
+
+	var networks boshsettings.Networks
+
+	err = json.Unmarshal(bytes, &networks)
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Unmarshalling networks")
+	}
+
+	for name := range networks {
+		interfaces = append(interfaces, name)
+	}
+	sort.Strings(interfaces)
+
+	return interfaces, nil
+}
+
 func (p dummyPlatform) GetCertManager() (certManager boshcert.Manager) {
 	return p.certManager
 }
 
+func (p dummyPlatform) GetCgroupManager() boshcgroup.Manager {
+	return p.cgroupManager
+}
+
 func (p dummyPlatform) SetupLogrotate(groupName, basePath, size string) (err error) {
 	return
 }
@@ -144,7 +225,7 @@ func (p dummyPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p dummyPlatform) SetupEphemeralDiskWithPath(devicePath string) (err error) {
+func (p dummyPlatform) SetupEphemeralDiskWithPath(devicePath string, diskSettings boshsettings.DiskSettings) (err error) {
 	return
 }
 
@@ -160,7 +241,68 @@ func (p dummyPlatform) SetupTmpDir() error {
 	return nil
 }
 
+func (p dummyPlatform) SetupReadOnlyRoot() error {
+	return nil
+}
+
+func (p dummyPlatform) SetupBindMounts() error {
+	return nil
+}
+
+func (p dummyPlatform) SetupFilesystemTrimming() error {
+	return nil
+}
+
+func (p dummyPlatform) SetupJobDiskQuotas(quotasInMB map[string]uint64) error {
+	return nil
+}
+
+func (p dummyPlatform) ValidateNetworking(networks boshsettings.Networks, probeHostname string) error {
+	return nil
+}
+
+func (p dummyPlatform) SetupFirewall(firewall boshsettings.Firewall) error {
+	return nil
+}
+
+func (p dummyPlatform) SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) error {
+	return nil
+}
+
+func (p dummyPlatform) SetupJobCgroups(jobLimits map[string]boshcgroup.Limits) error {
+	return nil
+}
+
+func (p dummyPlatform) AddJobProcessesToCgroups(jobNames []string) error {
+	return nil
+}
+
+func (p dummyPlatform) TrackJobProcessGroups(jobNames []string) error {
+	return nil
+}
+
+func (p dummyPlatform) KillOrphanedJobProcesses() error {
+	return nil
+}
+
+func (p dummyPlatform) SetupHugePages(hugePages boshsettings.HugePages) error {
+	return nil
+}
+
+func (p dummyPlatform) SetupKernelArgs(kernelArgs boshsettings.KernelArgs) (bool, error) {
+	return false, nil
+}
+
+func (p dummyPlatform) SetupTuningProfile(profile boshsettings.TuningProfile) error {
+	return nil
+}
+
 func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	err := injectedFault(p.fs, p.dirProvider, "MountPersistentDisk")
+	if err != nil {
+		return err
+	}
+
 	mounts, err := p.existingMounts()
 	if err != nil {
 		return err
@@ -172,7 +314,7 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 	}
 
 	if isMountPoint {
-		mountPoint = p.dirProvider.StoreMigrationDir()
+		mountPoint = p.dirProvider.MigrationDir(mountPoint)
 	}
 
 	mounts = append(mounts, mount{MountDir: mountPoint, DiskCid: diskSettings.ID})
@@ -185,6 +327,11 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 }
 
 func (p dummyPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error) {
+	err = injectedFault(p.fs, p.dirProvider, "UnmountPersistentDisk")
+	if err != nil {
+		return false, err
+	}
+
 	mounts, err := p.existingMounts()
 	if err != nil {
 		return false, err
@@ -194,6 +341,8 @@ func (p dummyPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSetti
 	for _, mount := range mounts {
 		if mount.DiskCid != diskSettings.ID {
 			updatedMounts = append(updatedMounts, mount)
+		} else {
+			didUnmount = true
 		}
 	}
 
@@ -207,7 +356,7 @@ func (p dummyPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSetti
 		return false, err
 	}
 
-	return true, nil
+	return didUnmount, nil
 }
 
 func (p dummyPlatform) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
@@ -222,8 +371,17 @@ func (p dummyPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []str
 	return
 }
 
+func (p dummyPlatform) GetPersistentDiskMigrationProgress() (DiskMigrationProgress, bool) {
+	return DiskMigrationProgress{}, false
+}
+
 func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
-	diskMigrationsPath := path.Join(p.dirProvider.BoshDir(), "disk_migrations.json")
+	err = injectedFault(p.fs, p.dirProvider, "MigratePersistentDisk")
+	if err != nil {
+		return err
+	}
+
+	diskMigrationsPath := path.Join(p.dirProvider.BoshDir(), dummyDiskMigrationsFileName)
 	var diskMigrations []diskMigration
 	if p.fs.FileExists(diskMigrationsPath) {
 		bytes, err := p.fs.ReadFile(diskMigrationsPath)
@@ -269,13 +427,42 @@ func (p dummyPlatform) IsMountPoint(mountPointPath string) (partitionPath string
 }
 
 func (p dummyPlatform) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (bool, error) {
-	return true, nil
+	mounts, err := p.existingMounts()
+	if err != nil {
+		return false, err
+	}
+
+	for _, mount := range mounts {
+		if mount.DiskCid == diskSettings.ID {
+			return true, nil
+		}
+	}
+
+	return false, nil
 }
 
 func (p dummyPlatform) IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings) (bool, error) {
 	return false, nil
 }
 
+func (p dummyPlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) error {
+	err := injectedFault(p.fs, p.dirProvider, "ResizePersistentDisk")
+	if err != nil {
+		return err
+	}
+
+	mounted, err := p.IsPersistentDiskMounted(diskSettings)
+	if err != nil {
+		return err
+	}
+
+	if !mounted {
+		return bosherr.Errorf("Persistent disk %s is not mounted", diskSettings.ID)
+	}
+
+	return nil
+}
+
 func (p dummyPlatform) StartMonit() (err error) {
 	return
 }
@@ -289,13 +476,30 @@ func (p dummyPlatform) GetMonitCredentials() (username, password string, err err
 }
 
 func (p dummyPlatform) PrepareForNetworkingChange() error {
-	return nil
+	err := injectedFault(p.fs, p.dirProvider, "PrepareForNetworkingChange")
+	if err != nil {
+		return err
+	}
+
+	return p.fs.RemoveAll(path.Join(p.dirProvider.BoshDir(), dummyNetworksFileName))
 }
 
 func (p dummyPlatform) DeleteARPEntryWithIP(ip string) error {
 	return nil
 }
 
+func (p dummyPlatform) PreviewNetworking(networks boshsettings.Networks) (boshnet.NetworkChanges, error) {
+	return boshnet.NetworkChanges{Files: map[string]string{}}, nil
+}
+
+func (p dummyPlatform) AddAlias(networks boshsettings.Networks, networkName, address string) error {
+	return nil
+}
+
+func (p dummyPlatform) RemoveAlias(networks boshsettings.Networks, networkName, address string) error {
+	return nil
+}
+
 func (p dummyPlatform) GetDefaultNetwork() (boshsettings.Network, error) {
 	var network boshsettings.Network
 
@@ -332,7 +536,7 @@ func (p dummyPlatform) getDiskCidByMountPoint(mountPoint string, mounts []mount)
 }
 
 func (p dummyPlatform) mountsPath() string {
-	return path.Join(p.dirProvider.BoshDir(), "mounts.json")
+	return path.Join(p.dirProvider.BoshDir(), dummyMountsFileName)
 }
 
 func (p dummyPlatform) existingMounts() ([]mount, error) {
@@ -350,3 +554,56 @@ func (p dummyPlatform) existingMounts() ([]mount, error) {
 	err = json.Unmarshal(bytes, &mounts)
 	return mounts, err
 }
+
+// injectedFault returns an error if the faults file
+// configures the given operation to fail.
+func injectedFault(fs boshsys.FileSystem, dirProvider boshdirs.Provider, operation string) error {
+	faultsPath := path.Join(dirProvider.BoshDir(), dummyFaultsFileName)
+	if !fs.FileExists(faultsPath) {
+		return nil
+	}
+
+	bytes, err := fs.ReadFile(faultsPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading dummy faults")
+	}
+
+	var faults map[string]string
+
+	err = json.Unmarshal(bytes, &faults)
+	if err != nil {
+		return bosherr.WrapError(err, "Unmarshalling dummy faults")
+	}
+
+	if message, found := faults[operation]; found {
+		return bosherr.Error(message)
+	}
+
+	return nil
+}
+
+type dummyCertManager struct {
+	fs          boshsys.FileSystem
+	dirProvider boshdirs.Provider
+}
+
+func newDummyCertManager(fs boshsys.FileSystem, dirProvider boshdirs.Provider) boshcert.Manager {
+	return dummyCertManager{fs: fs, dirProvider: dirProvider}
+}
+
+func (c dummyCertManager) UpdateCertificates(certs string) (boshcert.Changes, error) {
+	changes := boshcert.Changes{Added: []string{}, Removed: []string{}}
+
+	err := injectedFault(c.fs, c.dirProvider, "UpdateCertificates")
+	if err != nil {
+		return changes, err
+	}
+
+	certsPath := path.Join(c.dirProvider.BoshDir(), dummyTrustedCertsFileName)
+
+	if certs == "" {
+		return changes, c.fs.RemoveAll(certsPath)
+	}
+
+	return changes, c.fs.WriteFileString(certsPath, certs)
+}
@@ -72,7 +72,133 @@ func describeDummyPlatform() {
 		It("returs a dummy cert manager", func() {
 			certManager := platform.GetCertManager()
 
-			Expect(certManager.UpdateCertificates("")).Should(BeNil())
+			_, err := certManager.UpdateCertificates("")
+			Expect(err).Should(BeNil())
+		})
+
+		It("returns a cert manager that records trusted certs", func() {
+			certManager := platform.GetCertManager()
+
+			_, err := certManager.UpdateCertificates("fake-cert")
+			Expect(err).NotTo(HaveOccurred())
+
+			certs, err := fs.ReadFileString("/fake-dir/bosh/dummy-trusted-certs.pem")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(certs).To(Equal("fake-cert"))
+
+			_, err = certManager.UpdateCertificates("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/fake-dir/bosh/dummy-trusted-certs.pem")).To(BeFalse())
+		})
+
+		It("returns a cert manager that fails when fault is injected", func() {
+			fs.WriteFileString("/fake-dir/bosh/dummy-faults.json", `{"UpdateCertificates": "fake-cert-err"}`)
+
+			_, err := platform.GetCertManager().UpdateCertificates("fake-cert")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-cert-err"))
+		})
+	})
+
+	Describe("SetupNetworking", func() {
+		networks := settings.Networks{
+			"net-b": settings.Network{IP: "1.2.3.5"},
+			"net-a": settings.Network{IP: "1.2.3.4"},
+		}
+
+		It("records networks so that they are reported as configured interfaces", func() {
+			err := platform.SetupNetworking(networks)
+			Expect(err).NotTo(HaveOccurred())
+
+			interfaces, err := platform.GetConfiguredNetworkInterfaces()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{"net-a", "net-b"}))
+		})
+
+		It("forgets configured networks when preparing for networking change", func() {
+			err := platform.SetupNetworking(networks)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = platform.PrepareForNetworkingChange()
+			Expect(err).NotTo(HaveOccurred())
+
+			interfaces, err := platform.GetConfiguredNetworkInterfaces()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interfaces).To(BeEmpty())
+		})
+
+		It("returns error when fault is injected", func() {
+			fs.WriteFileString("/fake-dir/bosh/dummy-faults.json", `{"SetupNetworking": "fake-network-err"}`)
+
+			err := platform.SetupNetworking(networks)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-network-err"))
+
+			interfaces, err := platform.GetConfiguredNetworkInterfaces()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(interfaces).To(BeEmpty())
+		})
+	})
+
+	Describe("MountPersistentDisk", func() {
+		It("records the mount so that the disk is reported as mounted", func() {
+			err := platform.MountPersistentDisk(settings.DiskSettings{ID: "cid1"}, "/fake-dir/store")
+			Expect(err).NotTo(HaveOccurred())
+
+			mounted, err := platform.IsPersistentDiskMounted(settings.DiskSettings{ID: "cid1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(BeTrue())
+
+			mounted, err = platform.IsPersistentDiskMounted(settings.DiskSettings{ID: "cid2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(BeFalse())
+		})
+
+		It("mounts second disk onto the migration dir", func() {
+			err := platform.MountPersistentDisk(settings.DiskSettings{ID: "cid1"}, "/fake-dir/store")
+			Expect(err).NotTo(HaveOccurred())
+			err = platform.MountPersistentDisk(settings.DiskSettings{ID: "cid2"}, "/fake-dir/store")
+			Expect(err).NotTo(HaveOccurred())
+
+			_, isMountPoint, err := platform.IsMountPoint(dirProvider.StoreMigrationDir())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(isMountPoint).To(BeTrue())
+		})
+
+		It("returns error when fault is injected", func() {
+			fs.WriteFileString("/fake-dir/bosh/dummy-faults.json", `{"MountPersistentDisk": "fake-mount-err"}`)
+
+			err := platform.MountPersistentDisk(settings.DiskSettings{ID: "cid1"}, "/fake-dir/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-mount-err"))
+
+			mounted, err := platform.IsPersistentDiskMounted(settings.DiskSettings{ID: "cid1"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounted).To(BeFalse())
+		})
+	})
+
+	Describe("MigratePersistentDisk", func() {
+		It("records the migration between mounted disks", func() {
+			err := platform.MountPersistentDisk(settings.DiskSettings{ID: "cid1"}, "/fake-dir/store")
+			Expect(err).NotTo(HaveOccurred())
+			err = platform.MountPersistentDisk(settings.DiskSettings{ID: "cid2"}, "/fake-dir/store")
+			Expect(err).NotTo(HaveOccurred())
+
+			err = platform.MigratePersistentDisk("/fake-dir/store", dirProvider.StoreMigrationDir())
+			Expect(err).NotTo(HaveOccurred())
+
+			migrations, err := fs.ReadFileString("/fake-dir/bosh/disk_migrations.json")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(migrations).To(MatchJSON(`[{"FromDiskCid": "cid1", "ToDiskCid": "cid2"}]`))
+		})
+
+		It("returns error when fault is injected", func() {
+			fs.WriteFileString("/fake-dir/bosh/dummy-faults.json", `{"MigratePersistentDisk": "fake-migrate-err"}`)
+
+			err := platform.MigratePersistentDisk("/fake-dir/store", dirProvider.StoreMigrationDir())
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("fake-migrate-err"))
 		})
 	})
 
@@ -89,6 +215,23 @@ func describeDummyPlatform() {
 				fs.WriteFile(mountsPath, mountsJSON)
 			})
 
+			It("does not report unmounting a disk that is not mounted", func() {
+				unmounted, err := platform.UnmountPersistentDisk(settings.DiskSettings{ID: "cid3"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(unmounted).To(BeFalse())
+			})
+
+			It("returns error when fault is injected", func() {
+				fs.WriteFileString("/fake-dir/bosh/dummy-faults.json", `{"UnmountPersistentDisk": "fake-unmount-err"}`)
+
+				_, err := platform.UnmountPersistentDisk(settings.DiskSettings{ID: "cid1"})
+				Expect(err).To(HaveOccurred())
+
+				_, isMountPoint, err := platform.IsMountPoint("dir1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMountPoint).To(BeTrue())
+			})
+
 			It("removes one of the disks from the mounts json", func() {
 				unmounted, err := platform.UnmountPersistentDisk(settings.DiskSettings{ID: "cid1"})
 				Expect(err).NotTo(HaveOccurred())
@@ -0,0 +1,48 @@
+package platform
+
+/*
+Exports private items of the platform package for tests in the platform_test package.
+Because this is a *_test file it will not be included when you build the package.
+*/
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type DiskMigrationTracker interface {
+	Start(fromMountPoint, toMountPoint string)
+	Stop()
+	Progress() (DiskMigrationProgress, bool)
+}
+
+func NewDiskMigrationTracker(collector boshstats.Collector, timeService clock.Clock) DiskMigrationTracker {
+	return newDiskMigrationTracker(collector, timeService)
+}
+
+func DevicePathResolutionTimeouts(options LinuxOptions) (time.Duration, time.Duration) {
+	return options.devicePathResolutionTimeouts()
+}
+
+func StatsCollectionIntervalOf(options StatsOptions) time.Duration {
+	return options.collectionInterval()
+}
+
+func StatsCollectorOf(options StatsOptions, collector boshstats.Collector, runner boshsys.CmdRunner, fs boshsys.FileSystem, dirProvider boshdirs.Provider, logger boshlog.Logger) boshstats.Collector {
+	return options.statsCollector(collector, runner, fs, dirProvider, logger)
+}
+
+func StopFilesystemTrimming(p Platform) {
+	p.(*linux).stopFilesystemTrimming()
+}
+
+func TrustStore(options LinuxOptions, defaultTrustStore boshcert.TrustStore) (boshcert.TrustStore, error) {
+	return options.trustStore(defaultTrustStore)
+}
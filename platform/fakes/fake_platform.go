@@ -5,8 +5,13 @@ import (
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup/fakes"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -31,6 +36,9 @@ type FakePlatform struct {
 
 	SetupRuntimeConfigurationWasInvoked bool
 
+	RunHooksPhases []string
+	RunHooksErrs   map[string]error
+
 	CreateUserUsername string
 	CreateUserPassword string
 	CreateUserBasePath string
@@ -47,10 +55,29 @@ type FakePlatform struct {
 	UserPasswords         map[string]string
 	SetupHostnameHostname string
 
+	SetupHostsEntriesEntries []boshsettings.HostsEntry
+	SetupHostsEntriesErr     error
+
+	SetupTimezoneTimezone string
+	SetupTimezoneErr      error
+
+	SetupLocaleLocale string
+	SetupLocaleErr    error
+
+	SetupProxyProxy boshsettings.Proxy
+	SetupProxyErr   error
+
+	SetupDNSCacheDNSCache boshsettings.DNSCache
+	SetupDNSCacheNetworks boshsettings.Networks
+	// SetupDNSCacheResult is returned instead of given networks when set
+	SetupDNSCacheResult boshsettings.Networks
+	SetupDNSCacheErr    error
+
 	SetTimeWithNtpServersServers []string
 
-	SetupEphemeralDiskWithPathDevicePath string
-	SetupEphemeralDiskWithPathErr        error
+	SetupEphemeralDiskWithPathDevicePath   string
+	SetupEphemeralDiskWithPathDiskSettings boshsettings.DiskSettings
+	SetupEphemeralDiskWithPathErr          error
 
 	SetupRawEphemeralDisksDevices   []boshsettings.DiskSettings
 	SetupRawEphemeralDisksErr       error
@@ -62,14 +89,65 @@ type FakePlatform struct {
 	SetupTmpDirCalled bool
 	SetupTmpDirErr    error
 
+	SetupReadOnlyRootCalled bool
+	SetupReadOnlyRootErr    error
+
+	SetupBindMountsCalled bool
+	SetupBindMountsErr    error
+
+	SetupFilesystemTrimmingCalled bool
+	SetupFilesystemTrimmingErr    error
+
+	SetupJobDiskQuotasCalled     bool
+	SetupJobDiskQuotasQuotasInMB map[string]uint64
+	SetupJobDiskQuotasErr        error
+
+	ValidateNetworkingCalled        bool
+	ValidateNetworkingNetworks      boshsettings.Networks
+	ValidateNetworkingProbeHostname string
+	ValidateNetworkingErr           error
+
+	SetupFirewallCalled   bool
+	SetupFirewallFirewall boshsettings.Firewall
+	SetupFirewallErr      error
+
+	SetupJobFirewallPortsCalled   bool
+	SetupJobFirewallPortsJobPorts map[string][]boshfirewall.Port
+	SetupJobFirewallPortsErr      error
+
+	SetupJobCgroupsCalled    bool
+	SetupJobCgroupsJobLimits map[string]boshcgroup.Limits
+	SetupJobCgroupsErr       error
+
+	AddJobProcessesToCgroupsJobNames []string
+	AddJobProcessesToCgroupsErr      error
+
+	TrackJobProcessGroupsJobNames []string
+	TrackJobProcessGroupsErr      error
+
+	KillOrphanedJobProcessesCalled bool
+	KillOrphanedJobProcessesErr    error
+
+	SetupHugePagesCalled    bool
+	SetupHugePagesHugePages boshsettings.HugePages
+	SetupHugePagesErr       error
+
+	SetupKernelArgsKernelArgs     boshsettings.KernelArgs
+	SetupKernelArgsRebootRequired bool
+	SetupKernelArgsErr            error
+
+	SetupTuningProfileProfile boshsettings.TuningProfile
+	SetupTuningProfileErr     error
+
 	SetupNetworkingCalled   bool
 	SetupNetworkingNetworks boshsettings.Networks
 	SetupNetworkingErr      error
 
-	MountPersistentDiskCalled     bool
-	MountPersistentDiskSettings   boshsettings.DiskSettings
-	MountPersistentDiskMountPoint string
-	MountPersistentDiskErr        error
+	MountPersistentDiskCalled      bool
+	MountPersistentDiskSettings    boshsettings.DiskSettings
+	MountPersistentDiskMountPoint  string
+	MountPersistentDiskMountPoints []string
+	MountPersistentDiskErr         error
 
 	UnmountPersistentDiskDidUnmount bool
 	UnmountPersistentDiskSettings   boshsettings.DiskSettings
@@ -93,10 +171,17 @@ type FakePlatform struct {
 
 	MigratePersistentDiskFromMountPoint string
 	MigratePersistentDiskToMountPoint   string
+	MigratePersistentDiskErr            error
+
+	GetPersistentDiskMigrationProgressProgress boshplatform.DiskMigrationProgress
+	GetPersistentDiskMigrationProgressFound    bool
 
 	IsPersistentDiskMountableResult bool
 	IsPersistentDiskMountableErr    error
 
+	ResizePersistentDiskSettings boshsettings.DiskSettings
+	ResizePersistentDiskErr      error
+
 	IsMountPointPath          string
 	IsMountPointPartitionPath string
 	IsMountPointResult        bool
@@ -116,6 +201,20 @@ type FakePlatform struct {
 	PrepareForNetworkingChangeCalled bool
 	PrepareForNetworkingChangeErr    error
 
+	PreviewNetworkingNetworks boshsettings.Networks
+	PreviewNetworkingChanges  boshnet.NetworkChanges
+	PreviewNetworkingErr      error
+
+	AddAliasNetworks    boshsettings.Networks
+	AddAliasNetworkName string
+	AddAliasAddress     string
+	AddAliasErr         error
+
+	RemoveAliasNetworks    boshsettings.Networks
+	RemoveAliasNetworkName string
+	RemoveAliasAddress     string
+	RemoveAliasErr         error
+
 	GetDefaultNetworkNetwork boshsettings.Network
 	GetDefaultNetworkErr     error
 
@@ -127,6 +226,8 @@ type FakePlatform struct {
 
 	certManager boshcert.Manager
 
+	FakeCgroupManager *fakecgroup.FakeManager
+
 	GetHostPublicKeyValue string
 	GetHostPublicKeyError error
 
@@ -146,11 +247,13 @@ func NewFakePlatform() (platform *FakePlatform) {
 	platform.SetupSSHPublicKeys = make(map[string]string)
 	platform.UserPasswords = make(map[string]string)
 	platform.ScsiDiskMap = make(map[string]string)
+	platform.RunHooksErrs = make(map[string]error)
 	platform.GetFileContentsFromDiskDiskPaths = []string{}
 	platform.GetFileContentsFromDiskFileNames = [][]string{}
 	platform.GetFileContentsFromDiskContents = map[string][]byte{}
 	platform.GetFileContentsFromDiskErrs = map[string]error{}
 	platform.certManager = new(fakecert.FakeManager)
+	platform.FakeCgroupManager = fakecgroup.NewFakeManager()
 	platform.SetupRawEphemeralDisksCallCount = 0
 	platform.SetupRawEphemeralDisksDevices = nil
 	platform.SetupRawEphemeralDisksErr = nil
@@ -198,6 +301,11 @@ func (p *FakePlatform) SetupRuntimeConfiguration() (err error) {
 	return
 }
 
+func (p *FakePlatform) RunHooks(phase string) error {
+	p.RunHooksPhases = append(p.RunHooksPhases, phase)
+	return p.RunHooksErrs[phase]
+}
+
 func (p *FakePlatform) CreateUser(username, password, basePath string) (err error) {
 	p.CreateUserUsername = username
 	p.CreateUserPassword = password
@@ -241,6 +349,35 @@ func (p *FakePlatform) SetupHostname(hostname string) (err error) {
 	return
 }
 
+func (p *FakePlatform) SetupHostsEntries(entries []boshsettings.HostsEntry) error {
+	p.SetupHostsEntriesEntries = entries
+	return p.SetupHostsEntriesErr
+}
+
+func (p *FakePlatform) SetupTimezone(timezone string) error {
+	p.SetupTimezoneTimezone = timezone
+	return p.SetupTimezoneErr
+}
+
+func (p *FakePlatform) SetupLocale(locale string) error {
+	p.SetupLocaleLocale = locale
+	return p.SetupLocaleErr
+}
+
+func (p *FakePlatform) SetupProxy(proxy boshsettings.Proxy) error {
+	p.SetupProxyProxy = proxy
+	return p.SetupProxyErr
+}
+
+func (p *FakePlatform) SetupDNSCache(dnsCache boshsettings.DNSCache, networks boshsettings.Networks) (boshsettings.Networks, error) {
+	p.SetupDNSCacheDNSCache = dnsCache
+	p.SetupDNSCacheNetworks = networks
+	if p.SetupDNSCacheResult != nil {
+		return p.SetupDNSCacheResult, p.SetupDNSCacheErr
+	}
+	return networks, p.SetupDNSCacheErr
+}
+
 func (p *FakePlatform) SetupNetworking(networks boshsettings.Networks) error {
 	p.SetupNetworkingCalled = true
 	p.SetupNetworkingNetworks = networks
@@ -255,6 +392,10 @@ func (p *FakePlatform) GetCertManager() (certManager boshcert.Manager) {
 	return p.certManager
 }
 
+func (p *FakePlatform) GetCgroupManager() boshcgroup.Manager {
+	return p.FakeCgroupManager
+}
+
 func (p *FakePlatform) SetupLogrotate(groupName, basePath, size string) (err error) {
 	return
 }
@@ -264,8 +405,9 @@ func (p *FakePlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p *FakePlatform) SetupEphemeralDiskWithPath(devicePath string) (err error) {
+func (p *FakePlatform) SetupEphemeralDiskWithPath(devicePath string, diskSettings boshsettings.DiskSettings) (err error) {
 	p.SetupEphemeralDiskWithPathDevicePath = devicePath
+	p.SetupEphemeralDiskWithPathDiskSettings = diskSettings
 	return p.SetupEphemeralDiskWithPathErr
 }
 
@@ -285,10 +427,88 @@ func (p *FakePlatform) SetupTmpDir() error {
 	return p.SetupTmpDirErr
 }
 
+func (p *FakePlatform) SetupReadOnlyRoot() error {
+	p.SetupReadOnlyRootCalled = true
+	return p.SetupReadOnlyRootErr
+}
+
+func (p *FakePlatform) SetupBindMounts() error {
+	p.SetupBindMountsCalled = true
+	return p.SetupBindMountsErr
+}
+
+func (p *FakePlatform) SetupFilesystemTrimming() error {
+	p.SetupFilesystemTrimmingCalled = true
+	return p.SetupFilesystemTrimmingErr
+}
+
+func (p *FakePlatform) SetupJobDiskQuotas(quotasInMB map[string]uint64) error {
+	p.SetupJobDiskQuotasCalled = true
+	p.SetupJobDiskQuotasQuotasInMB = quotasInMB
+	return p.SetupJobDiskQuotasErr
+}
+
+func (p *FakePlatform) ValidateNetworking(networks boshsettings.Networks, probeHostname string) error {
+	p.ValidateNetworkingCalled = true
+	p.ValidateNetworkingNetworks = networks
+	p.ValidateNetworkingProbeHostname = probeHostname
+	return p.ValidateNetworkingErr
+}
+
+func (p *FakePlatform) SetupFirewall(firewall boshsettings.Firewall) error {
+	p.SetupFirewallCalled = true
+	p.SetupFirewallFirewall = firewall
+	return p.SetupFirewallErr
+}
+
+func (p *FakePlatform) SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) error {
+	p.SetupJobFirewallPortsCalled = true
+	p.SetupJobFirewallPortsJobPorts = jobPorts
+	return p.SetupJobFirewallPortsErr
+}
+
+func (p *FakePlatform) SetupJobCgroups(jobLimits map[string]boshcgroup.Limits) error {
+	p.SetupJobCgroupsCalled = true
+	p.SetupJobCgroupsJobLimits = jobLimits
+	return p.SetupJobCgroupsErr
+}
+
+func (p *FakePlatform) AddJobProcessesToCgroups(jobNames []string) error {
+	p.AddJobProcessesToCgroupsJobNames = jobNames
+	return p.AddJobProcessesToCgroupsErr
+}
+
+func (p *FakePlatform) TrackJobProcessGroups(jobNames []string) error {
+	p.TrackJobProcessGroupsJobNames = jobNames
+	return p.TrackJobProcessGroupsErr
+}
+
+func (p *FakePlatform) KillOrphanedJobProcesses() error {
+	p.KillOrphanedJobProcessesCalled = true
+	return p.KillOrphanedJobProcessesErr
+}
+
+func (p *FakePlatform) SetupHugePages(hugePages boshsettings.HugePages) error {
+	p.SetupHugePagesCalled = true
+	p.SetupHugePagesHugePages = hugePages
+	return p.SetupHugePagesErr
+}
+
+func (p *FakePlatform) SetupKernelArgs(kernelArgs boshsettings.KernelArgs) (bool, error) {
+	p.SetupKernelArgsKernelArgs = kernelArgs
+	return p.SetupKernelArgsRebootRequired, p.SetupKernelArgsErr
+}
+
+func (p *FakePlatform) SetupTuningProfile(profile boshsettings.TuningProfile) error {
+	p.SetupTuningProfileProfile = profile
+	return p.SetupTuningProfileErr
+}
+
 func (p *FakePlatform) MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) (err error) {
 	p.MountPersistentDiskCalled = true
 	p.MountPersistentDiskSettings = diskSettings
 	p.MountPersistentDiskMountPoint = mountPoint
+	p.MountPersistentDiskMountPoints = append(p.MountPersistentDiskMountPoints, mountPoint)
 	return p.MountPersistentDiskErr
 }
 
@@ -335,10 +555,14 @@ func (p *FakePlatform) SetGetFilesContentsFromDisk(fileName string, contents []b
 	p.GetFileContentsFromDiskErrs[fileName] = err
 }
 
+func (p *FakePlatform) GetPersistentDiskMigrationProgress() (boshplatform.DiskMigrationProgress, bool) {
+	return p.GetPersistentDiskMigrationProgressProgress, p.GetPersistentDiskMigrationProgressFound
+}
+
 func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
 	p.MigratePersistentDiskFromMountPoint = fromMountPoint
 	p.MigratePersistentDiskToMountPoint = toMountPoint
-	return
+	return p.MigratePersistentDiskErr
 }
 
 func (p *FakePlatform) IsMountPoint(path string) (string, bool, error) {
@@ -364,6 +588,11 @@ func (p *FakePlatform) IsPersistentDiskMountable(diskSettings boshsettings.DiskS
 	return p.IsPersistentDiskMountableResult, p.IsPersistentDiskMountableErr
 }
 
+func (p *FakePlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) error {
+	p.ResizePersistentDiskSettings = diskSettings
+	return p.ResizePersistentDiskErr
+}
+
 func (p *FakePlatform) StartMonit() (err error) {
 	p.StartMonitStarted = true
 	return
@@ -390,6 +619,25 @@ func (p *FakePlatform) PrepareForNetworkingChange() error {
 	return p.PrepareForNetworkingChangeErr
 }
 
+func (p *FakePlatform) PreviewNetworking(networks boshsettings.Networks) (boshnet.NetworkChanges, error) {
+	p.PreviewNetworkingNetworks = networks
+	return p.PreviewNetworkingChanges, p.PreviewNetworkingErr
+}
+
+func (p *FakePlatform) AddAlias(networks boshsettings.Networks, networkName, address string) error {
+	p.AddAliasNetworks = networks
+	p.AddAliasNetworkName = networkName
+	p.AddAliasAddress = address
+	return p.AddAliasErr
+}
+
+func (p *FakePlatform) RemoveAlias(networks boshsettings.Networks, networkName, address string) error {
+	p.RemoveAliasNetworks = networks
+	p.RemoveAliasNetworkName = networkName
+	p.RemoveAliasAddress = address
+	return p.RemoveAliasErr
+}
+
 func (p *FakePlatform) GetDefaultNetwork() (boshsettings.Network, error) {
 	return p.GetDefaultNetworkNetwork, p.GetDefaultNetworkErr
 }
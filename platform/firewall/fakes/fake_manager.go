@@ -0,0 +1,37 @@
+package fakes
+
+import (
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+)
+
+type FakeManager struct {
+	EnableCalled       bool
+	EnableAllowedPorts []boshfirewall.Port
+	EnableErr          error
+
+	DisableCalled bool
+	DisableErr    error
+
+	AllowJobPortsJobPorts map[string][]boshfirewall.Port
+	AllowJobPortsErr      error
+}
+
+func NewFakeManager() *FakeManager {
+	return &FakeManager{}
+}
+
+func (m *FakeManager) Enable(allowedPorts []boshfirewall.Port) error {
+	m.EnableCalled = true
+	m.EnableAllowedPorts = allowedPorts
+	return m.EnableErr
+}
+
+func (m *FakeManager) Disable() error {
+	m.DisableCalled = true
+	return m.DisableErr
+}
+
+func (m *FakeManager) AllowJobPorts(jobPorts map[string][]boshfirewall.Port) error {
+	m.AllowJobPortsJobPorts = jobPorts
+	return m.AllowJobPortsErr
+}
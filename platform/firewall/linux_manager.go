@@ -0,0 +1,322 @@
+package firewall
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	nftablesTable = "bosh_agent"
+	iptablesChain = "BOSH-AGENT-INPUT"
+
+	maxPort = 65535
+)
+
+type state struct {
+	Enabled      bool              `json:"enabled"`
+	AllowedPorts []Port            `json:"allowed_ports"`
+	JobPorts     map[string][]Port `json:"job_ports"`
+}
+
+type rule struct {
+	Protocol string
+	Port     int
+	// Comment is a name of the job that needs the port or 'env' for ports from env settings
+	Comment string
+}
+
+type linuxManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	statePath string
+	logger    boshlog.Logger
+	logTag    string
+}
+
+// NewLinuxManager returns manager that uses nftables when nft is available
+// and falls back to iptables otherwise
+func NewLinuxManager(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, statePath string, logger boshlog.Logger) Manager {
+	return linuxManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		statePath: statePath,
+		logger:    logger,
+		logTag:    "firewallManager",
+	}
+}
+
+func (m linuxManager) Enable(allowedPorts []Port) error {
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	s.Enabled = true
+	s.AllowedPorts = allowedPorts
+
+	err = m.apply(s)
+	if err != nil {
+		return err
+	}
+
+	return m.saveState(s)
+}
+
+func (m linuxManager) Disable() error {
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	if !s.Enabled {
+		return nil
+	}
+
+	s.Enabled = false
+
+	err = m.apply(s)
+	if err != nil {
+		return err
+	}
+
+	return m.saveState(s)
+}
+
+func (m linuxManager) AllowJobPorts(jobPorts map[string][]Port) error {
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	s.JobPorts = jobPorts
+
+	if s.Enabled {
+		err = m.apply(s)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.saveState(s)
+}
+
+func (m linuxManager) loadState() (state, error) {
+	var s state
+
+	if !m.fs.FileExists(m.statePath) {
+		return s, nil
+	}
+
+	bytes, err := m.fs.ReadFile(m.statePath)
+	if err != nil {
+		return s, bosherr.WrapError(err, "Reading firewall state file")
+	}
+
+	err = json.Unmarshal(bytes, &s)
+	if err != nil {
+		return s, bosherr.WrapError(err, "Unmarshalling firewall state")
+	}
+
+	return s, nil
+}
+
+func (m linuxManager) saveState(s state) error {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling firewall state")
+	}
+
+	err = m.fs.WriteFile(m.statePath, bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing firewall state file")
+	}
+
+	return nil
+}
+
+func (m linuxManager) apply(s state) error {
+	rules, err := m.rules(s)
+	if err != nil {
+		return err
+	}
+
+	if m.cmdRunner.CommandExists("nft") {
+		return m.applyNftables(s.Enabled, rules)
+	}
+
+	if s.Enabled {
+		return m.applyIptables(rules)
+	}
+
+	m.removeIptables()
+
+	return nil
+}
+
+// rules orders ports from env settings before job ports sorted by job name
+func (m linuxManager) rules(s state) ([]rule, error) {
+	rules := []rule{}
+
+	for _, port := range s.AllowedPorts {
+		r, err := newRule(port, "env")
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+
+	jobNames := []string{}
+	for jobName := range s.JobPorts {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		for _, port := range s.JobPorts[jobName] {
+			r, err := newRule(port, jobName)
+			if err != nil {
+				return nil, err
+			}
+			rules = append(rules, r)
+		}
+	}
+
+	return rules, nil
+}
+
+func newRule(port Port, comment string) (rule, error) {
+	protocol := port.Protocol
+	if protocol == "" {
+		protocol = "tcp"
+	}
+
+	if protocol != "tcp" && protocol != "udp" {
+		return rule{}, bosherr.Errorf("Protocol '%s' of port allowed for '%s' is not supported, expected tcp or udp", protocol, comment)
+	}
+
+	if port.Port < 1 || port.Port > maxPort {
+		return rule{}, bosherr.Errorf("Port '%d' allowed for '%s' is out of range 1-%d", port.Port, comment, maxPort)
+	}
+
+	return rule{Protocol: protocol, Port: port.Port, Comment: comment}, nil
+}
+
+// Table is always created before it is deleted so that ruleset
+// can be loaded regardless of whether table already exists.
+// DHCP replies are not tracked by conntrack hence they are allowed explicitly.
+const nftablesRulesetTemplate = `table inet {{ .Table }}
+delete table inet {{ .Table }}{{ if .Enabled }}
+table inet {{ .Table }} {
+	chain input {
+		type filter hook input priority 0; policy drop;
+		iif "lo" accept
+		ct state established,related accept
+		meta l4proto { icmp, ipv6-icmp } accept
+		udp sport 67 udp dport 68 accept
+		udp dport 546 accept{{ range .Rules }}
+		{{ .Protocol }} dport {{ .Port }} accept comment "{{ .Comment }}"{{ end }}
+	}
+}{{ end }}
+`
+
+func (m linuxManager) applyNftables(enabled bool, rules []rule) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("nftables-ruleset").Parse(nftablesRulesetTemplate))
+
+	err := t.Execute(buffer, struct {
+		Table   string
+		Enabled bool
+		Rules   []rule
+	}{nftablesTable, enabled, rules})
+	if err != nil {
+		return bosherr.WrapError(err, "Generating nftables ruleset from template")
+	}
+
+	_, _, _, err = m.cmdRunner.RunCommandWithInput(buffer.String(), "nft", "-f", "-")
+	if err != nil {
+		return bosherr.WrapError(err, "Loading nftables ruleset")
+	}
+
+	return nil
+}
+
+// Declaring a chain in iptables-restore input creates it or flushes its
+// rules; --noflush keeps rules that do not belong to the agent
+const iptablesRestoreTemplate = `*filter
+:{{ .Chain }} - [0:0]
+-A {{ .Chain }} -i lo -j ACCEPT
+-A {{ .Chain }} -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+-A {{ .Chain }} -p {{ .ICMPProtocol }} -j ACCEPT
+-A {{ .Chain }} {{ .DHCPMatch }} -j ACCEPT
+{{ range .Rules }}-A {{ $.Chain }} -p {{ .Protocol }} --dport {{ .Port }} -m comment --comment "{{ .Comment }}" -j ACCEPT
+{{ end }}-A {{ .Chain }} -j DROP
+COMMIT
+`
+
+type iptablesFamily struct {
+	Command      string
+	ICMPProtocol string
+	DHCPMatch    string
+}
+
+var iptablesFamilies = []iptablesFamily{
+	{Command: "iptables", ICMPProtocol: "icmp", DHCPMatch: "-p udp --sport 67 --dport 68"},
+	{Command: "ip6tables", ICMPProtocol: "ipv6-icmp", DHCPMatch: "-p udp --dport 546"},
+}
+
+func (m linuxManager) applyIptables(rules []rule) error {
+	t := template.Must(template.New("iptables-restore").Parse(iptablesRestoreTemplate))
+
+	for _, family := range iptablesFamilies {
+		buffer := bytes.NewBuffer([]byte{})
+
+		err := t.Execute(buffer, struct {
+			iptablesFamily
+			Chain string
+			Rules []rule
+		}{family, iptablesChain, rules})
+		if err != nil {
+			return bosherr.WrapError(err, "Generating iptables rules from template")
+		}
+
+		_, _, _, err = m.cmdRunner.RunCommandWithInput(buffer.String(), fmt.Sprintf("%s-restore", family.Command), "--noflush")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Loading %s rules", family.Command)
+		}
+
+		// Jump is inserted once so that existing INPUT rules are not duplicated
+		_, _, _, err = m.cmdRunner.RunCommand(family.Command, "-C", "INPUT", "-j", iptablesChain)
+		if err != nil {
+			_, _, _, err = m.cmdRunner.RunCommand(family.Command, "-I", "INPUT", "1", "-j", iptablesChain)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Inserting %s jump to %s", family.Command, iptablesChain)
+			}
+		}
+	}
+
+	return nil
+}
+
+// removeIptables ignores errors since chain may not exist
+func (m linuxManager) removeIptables() {
+	for _, family := range iptablesFamilies {
+		for _, args := range [][]string{
+			{"-D", "INPUT", "-j", iptablesChain},
+			{"-F", iptablesChain},
+			{"-X", iptablesChain},
+		} {
+			_, _, _, err := m.cmdRunner.RunCommand(family.Command, args...)
+			if err != nil {
+				m.logger.Debug(m.logTag, "Ignoring %s failure: %s", family.Command, err.Error())
+			}
+		}
+	}
+}
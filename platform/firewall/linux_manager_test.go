@@ -0,0 +1,236 @@
+package firewall_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/firewall"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxManager", func() {
+	var (
+		fs        *fakesys.FakeFileSystem
+		cmdRunner *fakesys.FakeCmdRunner
+		manager   Manager
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		manager = NewLinuxManager(fs, cmdRunner, "/fake-etc/firewall.json", boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Context("when nftables is available", func() {
+		BeforeEach(func() {
+			cmdRunner.AvailableCommands = map[string]bool{"nft": true}
+		})
+
+		Describe("Enable", func() {
+			It("loads ruleset that drops inbound traffic except to allowed ports", func() {
+				err := manager.Enable([]Port{{Port: 22}, {Protocol: "udp", Port: 53}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{{`table inet bosh_agent
+delete table inet bosh_agent
+table inet bosh_agent {
+	chain input {
+		type filter hook input priority 0; policy drop;
+		iif "lo" accept
+		ct state established,related accept
+		meta l4proto { icmp, ipv6-icmp } accept
+		udp sport 67 udp dport 68 accept
+		udp dport 546 accept
+		tcp dport 22 accept comment "env"
+		udp dport 53 accept comment "env"
+	}
+}
+`, "nft", "-f", "-"}}))
+			})
+
+			It("allows ports of jobs that were previously allowed", func() {
+				err := manager.AllowJobPorts(map[string][]Port{
+					"router": {{Port: 80}, {Port: 443}},
+					"nats":   {{Port: 4222}},
+				})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommandsWithInput).To(BeEmpty())
+
+				err = manager.Enable([]Port{{Port: 22}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommandsWithInput).To(HaveLen(1))
+				Expect(cmdRunner.RunCommandsWithInput[0][0]).To(ContainSubstring(`
+		tcp dport 22 accept comment "env"
+		tcp dport 4222 accept comment "nats"
+		tcp dport 80 accept comment "router"
+		tcp dport 443 accept comment "router"
+	}
+`))
+			})
+
+			It("persists state so that rules can be restored by a new manager", func() {
+				err := manager.Enable([]Port{{Port: 22}})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = manager.AllowJobPorts(map[string][]Port{"router": {{Port: 80}}})
+				Expect(err).ToNot(HaveOccurred())
+
+				cmdRunner.RunCommandsWithInput = nil
+				manager = NewLinuxManager(fs, cmdRunner, "/fake-etc/firewall.json", boshlog.NewLogger(boshlog.LevelNone))
+
+				err = manager.Enable([]Port{{Port: 22}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommandsWithInput).To(HaveLen(1))
+				Expect(cmdRunner.RunCommandsWithInput[0][0]).To(ContainSubstring(`tcp dport 80 accept comment "router"`))
+			})
+
+			It("returns error when port is out of range", func() {
+				err := manager.Enable([]Port{{Port: 70000}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Port '70000' allowed for 'env' is out of range 1-65535"))
+				Expect(fs.FileExists("/fake-etc/firewall.json")).To(BeFalse())
+			})
+
+			It("returns error when protocol is not supported", func() {
+				err := manager.AllowJobPorts(map[string][]Port{"router": {{Protocol: "sctp", Port: 80}}})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = manager.Enable(nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Protocol 'sctp' of port allowed for 'router' is not supported, expected tcp or udp"))
+			})
+
+			It("returns error when loading ruleset fails", func() {
+				cmdRunner.AddCmdResult(`table inet bosh_agent
+delete table inet bosh_agent
+table inet bosh_agent {
+	chain input {
+		type filter hook input priority 0; policy drop;
+		iif "lo" accept
+		ct state established,related accept
+		meta l4proto { icmp, ipv6-icmp } accept
+		udp sport 67 udp dport 68 accept
+		udp dport 546 accept
+	}
+}
+ nft -f -`, fakesys.FakeCmdResult{Error: errors.New("fake-nft-err")})
+
+				err := manager.Enable(nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Loading nftables ruleset: fake-nft-err"))
+				Expect(fs.FileExists("/fake-etc/firewall.json")).To(BeFalse())
+			})
+
+			It("returns error when state file cannot be read", func() {
+				fs.WriteFileString("/fake-etc/firewall.json", "fake-garbage")
+
+				err := manager.Enable(nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unmarshalling firewall state"))
+			})
+		})
+
+		Describe("Disable", func() {
+			It("does nothing when firewall was not enabled", func() {
+				err := manager.Disable()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommandsWithInput).To(BeEmpty())
+			})
+
+			It("removes table installed by the agent", func() {
+				err := manager.Enable([]Port{{Port: 22}})
+				Expect(err).ToNot(HaveOccurred())
+
+				cmdRunner.RunCommandsWithInput = nil
+
+				err = manager.Disable()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{
+					{"table inet bosh_agent\ndelete table inet bosh_agent\n", "nft", "-f", "-"},
+				}))
+
+				cmdRunner.RunCommandsWithInput = nil
+
+				err = manager.AllowJobPorts(map[string][]Port{"router": {{Port: 80}}})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(cmdRunner.RunCommandsWithInput).To(BeEmpty())
+			})
+		})
+
+		Describe("AllowJobPorts", func() {
+			It("reloads ruleset when firewall is enabled", func() {
+				err := manager.Enable([]Port{{Port: 22}})
+				Expect(err).ToNot(HaveOccurred())
+
+				err = manager.AllowJobPorts(map[string][]Port{"router": {{Port: 80}}})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommandsWithInput).To(HaveLen(2))
+				Expect(cmdRunner.RunCommandsWithInput[1][0]).To(ContainSubstring(`tcp dport 80 accept comment "router"`))
+			})
+		})
+	})
+
+	Context("when nftables is not available", func() {
+		It("restores iptables and ip6tables chains and jumps to them from INPUT chain", func() {
+			cmdRunner.AddCmdResult("iptables -C INPUT -j BOSH-AGENT-INPUT", fakesys.FakeCmdResult{Error: errors.New("fake-missing-rule-err")})
+
+			err := manager.Enable([]Port{{Port: 22}})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommandsWithInput).To(Equal([][]string{
+				{`*filter
+:BOSH-AGENT-INPUT - [0:0]
+-A BOSH-AGENT-INPUT -i lo -j ACCEPT
+-A BOSH-AGENT-INPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+-A BOSH-AGENT-INPUT -p icmp -j ACCEPT
+-A BOSH-AGENT-INPUT -p udp --sport 67 --dport 68 -j ACCEPT
+-A BOSH-AGENT-INPUT -p tcp --dport 22 -m comment --comment "env" -j ACCEPT
+-A BOSH-AGENT-INPUT -j DROP
+COMMIT
+`, "iptables-restore", "--noflush"},
+				{`*filter
+:BOSH-AGENT-INPUT - [0:0]
+-A BOSH-AGENT-INPUT -i lo -j ACCEPT
+-A BOSH-AGENT-INPUT -m conntrack --ctstate ESTABLISHED,RELATED -j ACCEPT
+-A BOSH-AGENT-INPUT -p ipv6-icmp -j ACCEPT
+-A BOSH-AGENT-INPUT -p udp --dport 546 -j ACCEPT
+-A BOSH-AGENT-INPUT -p tcp --dport 22 -m comment --comment "env" -j ACCEPT
+-A BOSH-AGENT-INPUT -j DROP
+COMMIT
+`, "ip6tables-restore", "--noflush"},
+			}))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"iptables", "-C", "INPUT", "-j", "BOSH-AGENT-INPUT"},
+				{"iptables", "-I", "INPUT", "1", "-j", "BOSH-AGENT-INPUT"},
+				{"ip6tables", "-C", "INPUT", "-j", "BOSH-AGENT-INPUT"},
+			}))
+		})
+
+		It("removes chains when firewall is disabled", func() {
+			err := manager.Enable(nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = nil
+			cmdRunner.AddCmdResult("ip6tables -D INPUT -j BOSH-AGENT-INPUT", fakesys.FakeCmdResult{Error: errors.New("fake-missing-rule-err")})
+
+			err = manager.Disable()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"iptables", "-D", "INPUT", "-j", "BOSH-AGENT-INPUT"},
+				{"iptables", "-F", "BOSH-AGENT-INPUT"},
+				{"iptables", "-X", "BOSH-AGENT-INPUT"},
+				{"ip6tables", "-D", "INPUT", "-j", "BOSH-AGENT-INPUT"},
+				{"ip6tables", "-F", "BOSH-AGENT-INPUT"},
+				{"ip6tables", "-X", "BOSH-AGENT-INPUT"},
+			}))
+		})
+	})
+})
@@ -0,0 +1,22 @@
+package firewall
+
+// Port is an inbound port allowed through the firewall
+type Port struct {
+	// tcp or udp (defaults to tcp)
+	Protocol string `json:"protocol"`
+	Port     int    `json:"port"`
+}
+
+// Manager applies deny-by-default inbound rules. Enabled state and allowed
+// ports are persisted so that rules can be restored after reboot.
+type Manager interface {
+	// Enable drops inbound traffic except to given ports and ports allowed for jobs
+	Enable(allowedPorts []Port) error
+
+	// Disable removes rules installed by the agent
+	Disable() error
+
+	// AllowJobPorts replaces ports allowed for jobs keyed by job name;
+	// rules are only applied while firewall is enabled
+	AllowJobPorts(jobPorts map[string][]Port) error
+}
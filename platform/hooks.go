@@ -0,0 +1,13 @@
+package platform
+
+// Hook phases are subdirectories of <bosh dir>/hooks containing executables
+// that stemcell builders can provide to customize bootstrap; hooks in a phase
+// are executed in lexical order and bootstrap fails if any of them fails.
+const (
+	HookPhasePreNetwork    = "pre-network"
+	HookPhasePostNetwork   = "post-network"
+	HookPhasePostDiskSetup = "post-disk-setup"
+	HookPhasePreMonit      = "pre-monit"
+)
+
+const defaultHookTimeoutInSeconds = 300
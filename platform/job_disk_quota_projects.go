@@ -0,0 +1,110 @@
+package platform
+
+import (
+	"encoding/json"
+	"path"
+	"sort"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const jobDiskQuotaProjectsFileName = "job_disk_quota_projects.json"
+
+// jobDiskQuotaProjects keeps project quota ids assigned to jobs so that
+// a job keeps its id when other jobs are added to or removed from the instance
+type jobDiskQuotaProjects struct {
+	fs   boshsys.FileSystem
+	path string
+
+	IDs map[string]int
+}
+
+func loadJobDiskQuotaProjects(fs boshsys.FileSystem, boshDir string) (*jobDiskQuotaProjects, error) {
+	projects := &jobDiskQuotaProjects{
+		fs:   fs,
+		path: path.Join(boshDir, jobDiskQuotaProjectsFileName),
+		IDs:  map[string]int{},
+	}
+
+	if !fs.FileExists(projects.path) {
+		return projects, nil
+	}
+
+	contents, err := fs.ReadFile(projects.path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading '%s'", projects.path)
+	}
+
+	err = json.Unmarshal(contents, &projects.IDs)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling '%s'", projects.path)
+	}
+
+	return projects, nil
+}
+
+// Assign returns ids of given jobs keeping ids already assigned to them
+// and picking the lowest unused id for new jobs
+func (p *jobDiskQuotaProjects) Assign(jobNames []string) map[string]int {
+	usedIDs := map[int]bool{}
+	for _, id := range p.IDs {
+		usedIDs[id] = true
+	}
+
+	sortedJobNames := append([]string{}, jobNames...)
+	sort.Strings(sortedJobNames)
+
+	nextID := jobDiskQuotaProjectIDOffset
+
+	for _, jobName := range sortedJobNames {
+		if _, found := p.IDs[jobName]; found {
+			continue
+		}
+
+		for usedIDs[nextID] {
+			nextID++
+		}
+
+		p.IDs[jobName] = nextID
+		usedIDs[nextID] = true
+	}
+
+	return p.IDs
+}
+
+// Removed returns sorted names of jobs that have ids assigned but are not given
+func (p *jobDiskQuotaProjects) Removed(jobNames []string) []string {
+	current := map[string]bool{}
+	for _, jobName := range jobNames {
+		current[jobName] = true
+	}
+
+	removed := []string{}
+	for jobName := range p.IDs {
+		if !current[jobName] {
+			removed = append(removed, jobName)
+		}
+	}
+	sort.Strings(removed)
+
+	return removed
+}
+
+func (p *jobDiskQuotaProjects) Release(jobName string) {
+	delete(p.IDs, jobName)
+}
+
+func (p *jobDiskQuotaProjects) Save() error {
+	contents, err := json.Marshal(p.IDs)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling job disk quota projects")
+	}
+
+	err = p.fs.WriteFile(p.path, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing '%s'", p.path)
+	}
+
+	return nil
+}
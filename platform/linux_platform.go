@@ -3,18 +3,25 @@ package platform
 import (
 	"bytes"
 	"fmt"
+	"net"
 	"os"
 	"path"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
 	"time"
 
+	"github.com/pivotal-golang/clock"
+
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
 	boshdevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -32,16 +39,40 @@ const (
 	ephemeralDiskPermissions  = os.FileMode(0750)
 	persistentDiskPermissions = os.FileMode(0700)
 
-	logDirPermissions      = os.FileMode(0750)
-	runDirPermissions      = os.FileMode(0750)
-	userBaseDirPermissions = os.FileMode(0755)
-	tmpDirPermissions      = os.FileMode(0755) // 0755 to make sure that vcap user can use new temp dir
+	logDirPermissions       = os.FileMode(0750)
+	runDirPermissions       = os.FileMode(0750)
+	userBaseDirPermissions  = os.FileMode(0755)
+	tmpDirPermissions       = os.FileMode(0755) // 0755 to make sure that vcap user can use new temp dir
+	hugePagesDirPermissions = os.FileMode(0755)
+	rootOverlayPermissions  = os.FileMode(0700)
+	bindMountDirPermissions = os.FileMode(0755)
 
 	sshDirPermissions          = os.FileMode(0700)
 	sshAuthKeysFilePermissions = os.FileMode(0600)
 
 	minRootEphemeralSpaceInBytes = uint64(1024 * 1024 * 1024)
 	maxFdiskPartitionSize        = uint64(2 * 1024 * 1024 * 1024 * 1024)
+
+	persistentDiskLogicalVolume = "store"
+	persistentDiskCryptPrefix   = "bosh_crypt_"
+
+	persistentDiskMigrationSnapshot = "bosh-migration"
+
+	ephemeralRAIDName = "bosh-ephemeral"
+
+	defaultTmpfsTmpDirSize = "128m"
+
+	zramSwapDevicePath   = "/dev/zram0"
+	zramSwapDiskSizePath = "/sys/block/zram0/disksize"
+
+	jobDataDirPermissions = os.FileMode(0755)
+
+	defaultDevicePathResolutionTimeout = 50000 * time.Millisecond
+	defaultDevicePathProbeTimeout      = 500 * time.Millisecond
+
+	// Project quota ids assigned to jobs start from this value
+	// to avoid conflicting with ids configured by the stemcell
+	jobDiskQuotaProjectIDOffset = 1000
 )
 
 type LinuxOptions struct {
@@ -65,13 +96,170 @@ type LinuxOptions struct {
 	SkipDiskSetup bool
 
 	// Strategy for resolving device paths;
-	// possible values: virtio, scsi, ''
+	// possible values: virtio, scsi, iscsi, nvme, azure, gce, xen, ''
 	DevicePathResolutionType string
 
+	// Ordered strategies for resolving device paths; strategies are tried
+	// until one of them finds the device (takes precedence over DevicePathResolutionType)
+	DevicePathResolutionStrategies []DevicePathResolutionStrategy
+
+	// Maximum time device path resolvers wait for an attached disk to show up
+	// (defaults to 50000)
+	DevicePathResolutionTimeoutInMilliseconds int
+
+	// Maximum time device path resolvers wait when probing alternative device
+	// names, e.g. /dev/xvdX for /dev/sdX (defaults to 500)
+	DevicePathProbeTimeoutInMilliseconds int
+
+	// Network manager used instead of the distro specific one;
+	// possible values: netplan, systemd-networkd, '' (defaults to netplan on Ubuntu
+	// stemcells without /etc/network/interfaces, e.g. Bionic)
+	NetManagerType string
+
+	// DNS backend used by Ubuntu network manager;
+	// possible values: systemd-resolved, '' (writes /etc/resolv.conf via resolvconf)
+	DNSManagerType string
+
 	// Device prexix when using virtio (defaults to 'virtio')
 	VirtioDevicePrefix string
+
+	// When set to true the agent remounts the root filesystem read-only
+	// after redirecting mutable paths to the ephemeral disk. Redirection
+	// also happens when the root filesystem is already mounted read-only.
+	ReadOnlyRootFilesystem bool
+
+	// Paths that are overlaid with writable directories on the ephemeral disk
+	// when the root filesystem is read-only (defaults to defaultReadOnlyRootWritablePaths).
+	// Paths the agent writes to after bootstrap (/etc and bosh, jobs and monit dirs)
+	// are always overlaid.
+	ReadOnlyRootWritablePaths []string
+
+	// Maximum time a single bootstrap hook may run (defaults to 300)
+	HookTimeoutInSeconds int
+
+	// When set to true persistent disks are managed as LVM logical volumes
+	// (one volume group per disk) instead of being partitioned
+	UseLVMForPersistentDisk bool
+
+	// When set to true persistent disks are managed as ZFS datasets
+	// (one pool per disk) instead of being partitioned; the old disk's
+	// dataset is snapshotted before its data is migrated to a new disk
+	UseZFSForPersistentDisk bool
+
+	// Command invoked with a disk's encryption key reference as its only
+	// argument to obtain the LUKS passphrase (e.g. from a KMS); the key is read from stdout
+	DiskEncryptionKeyCommand string
+
+	// When set to true and there is more than one raw ephemeral disk
+	// (e.g. NVMe instance storage) they are striped into a RAID-0 array
+	// which is used for the data dir instead of the ephemeral disk
+	StripeRawEphemeralDisks bool
+
+	// When greater than 0 the agent runs fstrim on mounted ephemeral
+	// and persistent filesystems at this interval so that SSD-backed
+	// disks reclaim freed blocks (defaults to 0, i.e. disabled)
+	FilesystemTrimIntervalInSeconds int
+
+	// When set to true checksums of all files are compared after persistent
+	// disk migration; otherwise only checksums of a sample of files are compared
+	FullPersistentDiskMigrationVerification bool
+
+	// When set to true /tmp and /var/tmp are mounted as size limited tmpfs
+	// instead of overlaying /tmp with a loop back device
+	UseTmpfsForTmpDirs bool
+
+	// Size of each tmpfs mounted over /tmp and /var/tmp
+	// (defaults to 128m)
+	TmpfsTmpDirSize string
+
+	// When set to true swap is set up on a compressed in-memory zram device
+	// instead of a swap partition on the ephemeral disk
+	UseZramSwap bool
+
+	// Size of zram swap device, e.g. 512M
+	// (defaults to half of total memory)
+	ZramSwapSize string
+
+	// Paths (e.g. /var/log, /home) that are relocated onto the ephemeral disk
+	// by bind-mounting directories from the data dir over them
+	EphemeralDiskBindMountPaths []string
+
+	// Paths that are relocated onto the persistent disk by bind-mounting
+	// directories from the store dir over them once persistent disk is mounted
+	PersistentDiskBindMountPaths []string
+
+	// Whether fsck is run when mounting persistent disk fails: "check" reports
+	// filesystem errors, "repair" also repairs errors that can be fixed without
+	// operator input and retries mounting (defaults to not running fsck)
+	PersistentDiskFsckPolicy string
+
+	// Trust store used for trusted certificates instead of the distro specific one;
+	// possible values: ubuntu, centos, suse, alpine, photon, amazon-linux, ''
+	TrustStoreType string
+
+	// Directory of trusted certificates and command that regenerates certificate bundle
+	// from it for trust stores that are not known to the agent (takes precedence over TrustStoreType)
+	TrustStorePath          string
+	TrustStoreUpdateCommand []string
+
+	// Time processes left in process groups of stopped jobs get to exit on their own
+	// and again after being sent SIGTERM before they are killed (defaults to 30)
+	OrphanedProcessGracePeriodInSeconds int
+}
+
+type DevicePathResolutionStrategy struct {
+	// Same values as DevicePathResolutionType
+	Type string
+
+	// Maximum time to wait for device to show up with this strategy
+	// (defaults to the strategy's own timeout)
+	TimeoutInSeconds int
+}
+
+// devicePathResolutionTimeouts returns configured resolver timeouts
+// falling back to defaults for ones that are not set
+func (o LinuxOptions) devicePathResolutionTimeouts() (diskWaitTimeout, probeTimeout time.Duration) {
+	diskWaitTimeout = defaultDevicePathResolutionTimeout
+	if o.DevicePathResolutionTimeoutInMilliseconds > 0 {
+		diskWaitTimeout = time.Duration(o.DevicePathResolutionTimeoutInMilliseconds) * time.Millisecond
+	}
+
+	probeTimeout = defaultDevicePathProbeTimeout
+	if o.DevicePathProbeTimeoutInMilliseconds > 0 {
+		probeTimeout = time.Duration(o.DevicePathProbeTimeoutInMilliseconds) * time.Millisecond
+	}
+
+	return diskWaitTimeout, probeTimeout
+}
+
+// trustStore returns configured trust store falling back to the distro specific one
+func (o LinuxOptions) trustStore(defaultTrustStore boshcert.TrustStore) (boshcert.TrustStore, error) {
+	if o.TrustStorePath != "" {
+		if len(o.TrustStoreUpdateCommand) == 0 {
+			return defaultTrustStore, bosherr.Errorf("Trust store '%s' requires an update command", o.TrustStorePath)
+		}
+
+		return boshcert.TrustStore{
+			Name:          "Custom",
+			Path:          o.TrustStorePath,
+			UpdateCommand: o.TrustStoreUpdateCommand,
+		}, nil
+	}
+
+	if o.TrustStoreType == "" {
+		return defaultTrustStore, nil
+	}
+
+	trustStore, found := boshcert.TrustStores[o.TrustStoreType]
+	if !found {
+		return defaultTrustStore, bosherr.Errorf("Trust store type '%s' is not supported", o.TrustStoreType)
+	}
+
+	return trustStore, nil
 }
 
+var defaultReadOnlyRootWritablePaths = []string{"/home", "/root", "/var/lib", "/var/log", "/var/spool"}
+
 type linux struct {
 	fs                     boshsys.FileSystem
 	cmdRunner              boshsys.CmdRunner
@@ -83,14 +271,30 @@ type linux struct {
 	cdutil                 boshdevutil.DeviceUtil
 	diskManager            boshdisk.Manager
 	netManager             boshnet.Manager
+	networkChangePreviewer boshnet.NetworkChangePreviewer
 	certManager            boshcert.Manager
+	cgroupManager          boshcgroup.Manager
+	firewallManager        boshfirewall.Manager
 	monitRetryStrategy     boshretry.RetryStrategy
 	devicePathResolver     boshdpresolv.DevicePathResolver
 	diskScanDuration       time.Duration
+	timeService            clock.Clock
 	options                LinuxOptions
 	state                  *BootstrapState
 	logger                 boshlog.Logger
 	defaultNetworkResolver boshsettings.DefaultNetworkResolver
+	diskMigrationTracker   *diskMigrationTracker
+	connectivityValidator  boshnet.ConnectivityValidator
+	wireGuardManager       boshnet.WireGuardManager
+	aliasManager           boshnet.AliasManager
+	dnsCacheManager        boshnet.DNSCacheManager
+
+	trimStopCh   chan struct{}
+	trimStopOnce *sync.Once
+	trimWG       *sync.WaitGroup
+
+	// Job processes ticker tracks process groups while stop kills orphans
+	jobProcessGroupsLock *sync.Mutex
 }
 
 func NewLinuxPlatform(
@@ -104,10 +308,15 @@ func NewLinuxPlatform(
 	cdutil boshdevutil.DeviceUtil,
 	diskManager boshdisk.Manager,
 	netManager boshnet.Manager,
+	networkChangePreviewer boshnet.NetworkChangePreviewer,
+	wireGuardManager boshnet.WireGuardManager,
 	certManager boshcert.Manager,
+	cgroupManager boshcgroup.Manager,
+	firewallManager boshfirewall.Manager,
 	monitRetryStrategy boshretry.RetryStrategy,
 	devicePathResolver boshdpresolv.DevicePathResolver,
 	diskScanDuration time.Duration,
+	timeService clock.Clock,
 	state *BootstrapState,
 	options LinuxOptions,
 	logger boshlog.Logger,
@@ -124,14 +333,29 @@ func NewLinuxPlatform(
 		cdutil:                 cdutil,
 		diskManager:            diskManager,
 		netManager:             netManager,
+		networkChangePreviewer: networkChangePreviewer,
+		wireGuardManager:       wireGuardManager,
 		certManager:            certManager,
+		cgroupManager:          cgroupManager,
+		firewallManager:        firewallManager,
 		monitRetryStrategy:     monitRetryStrategy,
 		devicePathResolver:     devicePathResolver,
 		diskScanDuration:       diskScanDuration,
+		timeService:            timeService,
 		state:                  state,
 		options:                options,
 		logger:                 logger,
 		defaultNetworkResolver: defaultNetworkResolver,
+		diskMigrationTracker:   newDiskMigrationTracker(collector, timeService),
+		connectivityValidator:  boshnet.NewConnectivityValidator(cmdRunner, logger),
+		aliasManager:           boshnet.NewAliasManager(fs, cmdRunner, path.Join(dirProvider.EtcDir(), "aliases.json"), logger),
+		dnsCacheManager:        boshnet.NewDNSCacheManager(fs, cmdRunner, logger),
+
+		trimStopCh:   make(chan struct{}),
+		trimStopOnce: &sync.Once{},
+		trimWG:       &sync.WaitGroup{},
+
+		jobProcessGroupsLock: &sync.Mutex{},
 	}
 }
 
@@ -178,8 +402,221 @@ func (p linux) GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePa
 	return p.devicePathResolver
 }
 
+// SetupDNSCache starts dns cache forwarding to dns servers of the default dns network
+// and returns networks in which that network resolves through the dns cache
+func (p linux) SetupDNSCache(dnsCache boshsettings.DNSCache, networks boshsettings.Networks) (boshsettings.Networks, error) {
+	dnsNetworkName := ""
+	for networkName, networkSettings := range networks {
+		if len(networks) == 1 || networkSettings.IsDefaultFor("dns") {
+			dnsNetworkName = networkName
+			break
+		}
+	}
+
+	err := p.dnsCacheManager.SetupDNSCache(dnsCache, networks[dnsNetworkName].DNS)
+	if err != nil {
+		return nil, err
+	}
+
+	if !dnsCache.Enabled {
+		return networks, nil
+	}
+
+	cachedNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkName == dnsNetworkName {
+			networkSettings.DNS = []string{boshnet.DNSCacheListenAddress}
+		}
+		cachedNetworks[networkName] = networkSettings
+	}
+
+	return cachedNetworks, nil
+}
+
+// SetupNetworking configures wireguard networks after networks they are overlaid on
+// and assigns aliases once interfaces of all networks have their addresses
 func (p linux) SetupNetworking(networks boshsettings.Networks) (err error) {
-	return p.netManager.SetupNetworking(networks, nil)
+	overlaidNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if !networkSettings.IsWireGuard() {
+			overlaidNetworks[networkName] = networkSettings
+		}
+	}
+
+	err = p.netManager.SetupNetworking(overlaidNetworks, nil)
+	if err != nil {
+		return err
+	}
+
+	err = p.wireGuardManager.SetupWireGuard(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up WireGuard networks")
+	}
+
+	err = p.aliasManager.SetupAliases(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up aliases")
+	}
+
+	return nil
+}
+
+// ValidateNetworking checks that default gateway is reachable and probe hostname resolves;
+// gateway of dynamic networks is taken from the default route
+func (p linux) ValidateNetworking(networks boshsettings.Networks, probeHostname string) error {
+	gatewayNetwork, _ := networks.DefaultNetworkFor("gateway")
+	gateway := gatewayNetwork.Gateway
+
+	if gateway == "" {
+		defaultNetwork, err := p.defaultNetworkResolver.GetDefaultNetwork()
+		if err != nil {
+			return bosherr.WrapError(err, "Resolving default gateway")
+		}
+
+		gateway = defaultNetwork.Gateway
+	}
+
+	return p.connectivityValidator.Validate(gateway, probeHostname)
+}
+
+// SetupFirewall drops inbound traffic except for ports allowed in the env
+// when firewall is enabled and removes agent managed rules otherwise
+func (p linux) SetupFirewall(firewall boshsettings.Firewall) error {
+	if !firewall.Enabled {
+		err := p.firewallManager.Disable()
+		if err != nil {
+			return bosherr.WrapError(err, "Disabling firewall")
+		}
+
+		return nil
+	}
+
+	err := p.firewallManager.Enable(firewall.AllowedPorts)
+	if err != nil {
+		return bosherr.WrapError(err, "Enabling firewall")
+	}
+
+	return nil
+}
+
+// SetupJobFirewallPorts allows inbound traffic to ports declared by jobs
+func (p linux) SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) error {
+	err := p.firewallManager.AllowJobPorts(jobPorts)
+	if err != nil {
+		return bosherr.WrapError(err, "Allowing job firewall ports")
+	}
+
+	return nil
+}
+
+// SetupJobCgroups creates a cgroup with resource limits for each job; job supervisors
+// start processes in them, others are moved by AddJobProcessesToCgroups
+func (p linux) SetupJobCgroups(jobLimits map[string]boshcgroup.Limits) error {
+	jobNames := []string{}
+	for jobName := range jobLimits {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	for _, jobName := range jobNames {
+		limits := jobLimits[jobName]
+
+		p.logger.Info(logTag, "Limiting resources of job '%s' to %+v", jobName, limits)
+
+		err := p.cgroupManager.CreateGroup(jobName)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating cgroup of job '%s'", jobName)
+		}
+
+		err = p.cgroupManager.ApplyLimits(jobName, limits)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Applying limits to cgroup of job '%s'", jobName)
+		}
+	}
+
+	return nil
+}
+
+// AddJobProcessesToCgroups moves processes found by pid files in jobs' run dirs
+// together with their descendants into cgroups of the jobs; processes forked
+// afterwards inherit the cgroup. Processes may exit meanwhile, so failing to move
+// a process is only logged.
+func (p linux) AddJobProcessesToCgroups(jobNames []string) error {
+	for _, jobName := range jobNames {
+		pids, err := p.jobPids(jobName)
+		if err != nil {
+			return err
+		}
+
+		for _, pid := range pids {
+			for _, processPid := range p.processTree(pid) {
+				err = p.cgroupManager.AddProcess(jobName, processPid)
+				if err != nil {
+					p.logger.Debug(logTag, "Moving process %d into cgroup of job '%s': %s", processPid, jobName, err.Error())
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// jobPids returns pids found in pid files in job's run dir; unreadable pid files are skipped
+func (p linux) jobPids(jobName string) ([]int, error) {
+	runDir := path.Join(p.dirProvider.DataDir(), "sys", "run", jobName)
+
+	pidFiles, err := p.fs.Glob(path.Join(runDir, "*.pid"))
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Finding pid files of job '%s'", jobName)
+	}
+
+	var pids []int
+
+	for _, pidFile := range pidFiles {
+		pidContent, err := p.fs.ReadFileString(pidFile)
+		if err != nil {
+			p.logger.Debug(logTag, "Reading pid file '%s': %s", pidFile, err.Error())
+			continue
+		}
+
+		pid, err := strconv.Atoi(strings.TrimSpace(pidContent))
+		if err != nil {
+			p.logger.Warn(logTag, "Parsing pid file '%s': %s", pidFile, err.Error())
+			continue
+		}
+
+		pids = append(pids, pid)
+	}
+
+	return pids, nil
+}
+
+// processTree returns pid followed by pids of its descendants
+func (p linux) processTree(pid int) []int {
+	pids := []int{pid}
+
+	for i := 0; i < len(pids); i++ {
+		childrenPaths, err := p.fs.Glob(fmt.Sprintf("/proc/%d/task/*/children", pids[i]))
+		if err != nil {
+			continue
+		}
+
+		for _, childrenPath := range childrenPaths {
+			children, err := p.fs.ReadFileString(childrenPath)
+			if err != nil {
+				continue
+			}
+
+			for _, field := range strings.Fields(children) {
+				childPid, err := strconv.Atoi(field)
+				if err == nil {
+					pids = append(pids, childPid)
+				}
+			}
+		}
+	}
+
+	return pids
 }
 
 func (p linux) GetConfiguredNetworkInterfaces() ([]string, error) {
@@ -190,6 +627,10 @@ func (p linux) GetCertManager() boshcert.Manager {
 	return p.certManager
 }
 
+func (p linux) GetCgroupManager() boshcgroup.Manager {
+	return p.cgroupManager
+}
+
 func (p linux) GetHostPublicKey() (string, error) {
 	hostPublicKeyPath := "/etc/ssh/ssh_host_rsa_key.pub"
 	hostPublicKey, err := p.fs.ReadFileString(hostPublicKeyPath)
@@ -207,6 +648,51 @@ func (p linux) SetupRuntimeConfiguration() (err error) {
 	return
 }
 
+func (p linux) RunHooks(phase string) error {
+	hooksDir := path.Join(p.dirProvider.BoshDir(), "hooks", phase)
+
+	hookPaths, err := p.fs.Glob(path.Join(hooksDir, "*"))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Globbing hooks in %s", hooksDir)
+	}
+
+	sort.Strings(hookPaths)
+
+	timeoutInSeconds := p.options.HookTimeoutInSeconds
+	if timeoutInSeconds <= 0 {
+		timeoutInSeconds = defaultHookTimeoutInSeconds
+	}
+	timeout := time.Duration(timeoutInSeconds) * time.Second
+
+	for _, hookPath := range hookPaths {
+		p.logger.Info(logTag, "Running %s hook `%s'", phase, hookPath)
+
+		process, err := p.cmdRunner.RunComplexCommandAsync(boshsys.Command{
+			Name: hookPath,
+			Env:  map[string]string{"BOSH_HOOK_PHASE": phase},
+		})
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Starting %s hook %s", phase, hookPath)
+		}
+
+		select {
+		case result := <-process.Wait():
+			p.logger.Debug(logTag, "Hook `%s' stdout: %s", hookPath, result.Stdout)
+			p.logger.Debug(logTag, "Hook `%s' stderr: %s", hookPath, result.Stderr)
+
+			if result.Error != nil {
+				return bosherr.WrapErrorf(result.Error, "Running %s hook %s", phase, hookPath)
+			}
+
+		case <-time.After(timeout):
+			_ = process.TerminateNicely(5 * time.Second)
+			return bosherr.Errorf("Running %s hook %s: timed out after %s", phase, hookPath, timeout)
+		}
+	}
+
+	return nil
+}
+
 func (p linux) CreateUser(username, password, basePath string) error {
 	err := p.fs.MkdirAll(basePath, userBaseDirPermissions)
 	if err != nil {
@@ -303,10 +789,21 @@ func (p linux) SetupRootDisk(ephemeralDiskPath string) error {
 		return bosherr.WrapError(err, "findRootDevicePath")
 	}
 
+	err = p.growPartition(rootDevicePath, 1)
+	if err != nil {
+		return err
+	}
+
+	return p.growFilesystem(fmt.Sprintf("%s1", rootDevicePath), "/")
+}
+
+// growPartition extends the partition to the end of the device;
+// growpart reports NOCHANGE when there is no space to grow into
+func (p linux) growPartition(devicePath string, partitionNumber int) error {
 	stdout, _, _, err := p.cmdRunner.RunCommand(
 		"growpart",
-		rootDevicePath,
-		"1",
+		devicePath,
+		strconv.Itoa(partitionNumber),
 	)
 
 	if err != nil {
@@ -315,12 +812,27 @@ func (p linux) SetupRootDisk(ephemeralDiskPath string) error {
 		}
 	}
 
-	_, _, _, err = p.cmdRunner.RunCommand(
-		"resize2fs",
-		"-f",
-		fmt.Sprintf("%s1", rootDevicePath),
-	)
+	return nil
+}
+
+// growFilesystem resizes mounted filesystem to fill its partition
+func (p linux) growFilesystem(partitionPath, mountPoint string) error {
+	fsType, err := p.mountedFileSystemType(mountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Determining filesystem type of %s", mountPoint)
+	}
+
+	if fsType == boshdisk.FileSystemXFS {
+		// xfs can only be grown while mounted, via its mount point
+		_, _, _, err = p.cmdRunner.RunCommand("xfs_growfs", mountPoint)
+		if err != nil {
+			return bosherr.WrapError(err, "xfs_growfs")
+		}
+
+		return nil
+	}
 
+	_, _, _, err = p.cmdRunner.RunCommand("resize2fs", "-f", partitionPath)
 	if err != nil {
 		return bosherr.WrapError(err, "resize2fs")
 	}
@@ -416,79 +928,294 @@ ff02::2 ip6-allrouters
 ff02::3 ip6-allhosts
 `
 
-func (p linux) SetupLogrotate(groupName, basePath, size string) (err error) {
-	buffer := bytes.NewBuffer([]byte{})
-	t := template.Must(template.New("logrotate-d-config").Parse(etcLogrotateDTemplate))
+const (
+	managedEntriesBeginMarker = "# BEGIN bosh-agent managed entries"
+	managedEntriesEndMarker   = "# END bosh-agent managed entries"
+)
 
-	type logrotateArgs struct {
-		BasePath string
-		Size     string
-	}
+func (p linux) SetupHostsEntries(entries []boshsettings.HostsEntry) error {
+	var lines []string
 
-	err = t.Execute(buffer, logrotateArgs{basePath, size})
-	if err != nil {
-		err = bosherr.WrapError(err, "Generating logrotate config")
-		return
-	}
+	for _, entry := range entries {
+		if net.ParseIP(entry.IP) == nil {
+			return bosherr.Errorf("Invalid IP '%s' in hosts entry", entry.IP)
+		}
 
-	err = p.fs.WriteFile(path.Join("/etc/logrotate.d", groupName), buffer.Bytes())
-	if err != nil {
-		err = bosherr.WrapError(err, "Writing to /etc/logrotate.d")
-		return
+		if len(entry.Hostnames) == 0 {
+			return bosherr.Errorf("Hosts entry for '%s' must have at least one hostname", entry.IP)
+		}
+
+		lines = append(lines, fmt.Sprintf("%s %s", entry.IP, strings.Join(entry.Hostnames, " ")))
 	}
 
-	return
+	return p.convergeManagedEntries("/etc/hosts", lines)
 }
 
-// Logrotate config file - /etc/logrotate.d/<group-name>
-// Stemcell stage logrotate_config configures logrotate to run every hour
-const etcLogrotateDTemplate = `# Generated by bosh-agent
+// convergeManagedEntries replaces agent managed block of lines in a file
+// keeping lines outside of it; block is removed when there are no managed lines
+func (p linux) convergeManagedEntries(filePath string, managedLines []string) error {
+	var lines []string
 
-{{ .BasePath }}/data/sys/log/*.log {{ .BasePath }}/data/sys/log/.*.log {{ .BasePath }}/data/sys/log/*/*.log {{ .BasePath }}/data/sys/log/*/.*.log {{ .BasePath }}/data/sys/log/*/*/*.log {{ .BasePath }}/data/sys/log/*/*/.*.log {
-  missingok
-  rotate 7
-  compress
-  delaycompress
-  copytruncate
-  size={{ .Size }}
-}
-`
+	if p.fs.FileExists(filePath) {
+		contents, err := p.fs.ReadFileString(filePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading %s", filePath)
+		}
 
-func (p linux) SetTimeWithNtpServers(servers []string) (err error) {
-	serversFilePath := path.Join(p.dirProvider.BaseDir(), "/bosh/etc/ntpserver")
-	if len(servers) == 0 {
-		return
+		inManagedBlock := false
+		for _, line := range strings.Split(strings.TrimRight(contents, "\n"), "\n") {
+			switch {
+			case line == managedEntriesBeginMarker:
+				inManagedBlock = true
+			case line == managedEntriesEndMarker:
+				inManagedBlock = false
+			case !inManagedBlock:
+				lines = append(lines, line)
+			}
+		}
 	}
 
-	err = p.fs.WriteFileString(serversFilePath, strings.Join(servers, " "))
+	if len(managedLines) > 0 {
+		lines = append(lines, managedEntriesBeginMarker)
+		lines = append(lines, managedLines...)
+		lines = append(lines, managedEntriesEndMarker)
+	}
+
+	_, err := p.fs.ConvergeFileContents(filePath, []byte(strings.Join(lines, "\n")+"\n"))
 	if err != nil {
-		err = bosherr.WrapErrorf(err, "Writing to %s", serversFilePath)
-		return
+		return bosherr.WrapErrorf(err, "Writing to %s", filePath)
 	}
 
-	// Make a best effort to sync time now but don't error
-	_, _, _, _ = p.cmdRunner.RunCommand("ntpdate")
-	return
+	return nil
 }
 
-func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
-	if p.options.SkipDiskSetup {
-		return nil
-	}
+const systemdProxyConfigPath = "/etc/systemd/system.conf.d/bosh-proxy.conf"
+
+// SetupProxy renders proxy variables into /etc/environment for login shells and into default
+// environment of systemd services for jobs; proxy is removed when it is not configured
+func (p linux) SetupProxy(proxy boshsettings.Proxy) error {
+	values := map[string]string{}
+	if !proxy.IsEmpty() {
+		values["http_proxy"] = proxy.HTTPProxy
+		values["https_proxy"] = proxy.HTTPSProxy
+		values["no_proxy"] = strings.Join(proxy.NoProxy, ",")
+	}
+
+	var variables []string
+
+	for _, name := range []string{"http_proxy", "https_proxy", "no_proxy"} {
+		for _, variableName := range []string{name, strings.ToUpper(name)} {
+			// Agent inherited its environment before settings were fetched,
+			// blobstore clients and other commands are run with agent environment
+			if values[name] == "" {
+				err := os.Unsetenv(variableName)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Unsetting %s", variableName)
+				}
+				continue
+			}
 
-	p.logger.Info(logTag, "Setting up ephemeral disk...")
-	mountPoint := p.dirProvider.DataDir()
+			err := os.Setenv(variableName, values[name])
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Setting %s", variableName)
+			}
 
-	mountPointGlob := path.Join(mountPoint, "*")
-	contents, err := p.fs.Glob(mountPointGlob)
+			variables = append(variables, fmt.Sprintf("%s=%s", variableName, values[name]))
+		}
+	}
+
+	err := p.convergeManagedEntries("/etc/environment", variables)
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Globbing ephemeral disk mount point `%s'", mountPointGlob)
+		return err
 	}
 
-	if contents != nil && len(contents) > 0 {
-		// When agent bootstraps for the first time data directory should be empty.
-		// It might be non-empty on subsequent agent restarts. The ephemeral disk setup
-		// should be idempotent and partitioning will be skipped if disk is already
+	changed := false
+
+	if len(variables) == 0 {
+		changed = p.fs.FileExists(systemdProxyConfigPath)
+
+		err = p.fs.RemoveAll(systemdProxyConfigPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", systemdProxyConfigPath)
+		}
+	} else {
+		quotedVariables := []string{}
+		for _, variable := range variables {
+			quotedVariables = append(quotedVariables, fmt.Sprintf("%q", variable))
+		}
+
+		config := fmt.Sprintf("# Generated by bosh-agent\n[Manager]\nDefaultEnvironment=%s\n", strings.Join(quotedVariables, " "))
+
+		changed, err = p.fs.ConvergeFileContents(systemdProxyConfigPath, []byte(config))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", systemdProxyConfigPath)
+		}
+	}
+
+	if changed && p.cmdRunner.CommandExists("systemctl") {
+		_, _, _, err = p.cmdRunner.RunCommand("systemctl", "daemon-reload")
+		if err != nil {
+			return bosherr.WrapError(err, "Reloading systemd manager configuration")
+		}
+	}
+
+	return nil
+}
+
+func (p linux) SetupTimezone(timezone string) error {
+	if timezone == "" {
+		return nil
+	}
+
+	zoneInfoPath := path.Join("/usr/share/zoneinfo", timezone)
+	if !p.fs.FileExists(zoneInfoPath) {
+		return bosherr.Errorf("Timezone '%s' is not available in /usr/share/zoneinfo", timezone)
+	}
+
+	err := p.fs.Symlink(zoneInfoPath, "/etc/localtime")
+	if err != nil {
+		return bosherr.WrapError(err, "Symlinking /etc/localtime")
+	}
+
+	_, err = p.fs.ConvergeFileContents("/etc/timezone", []byte(timezone+"\n"))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to /etc/timezone")
+	}
+
+	return nil
+}
+
+func (p linux) SetupLocale(locale string) error {
+	if locale == "" {
+		return nil
+	}
+
+	defaultChanged, err := p.fs.ConvergeFileContents("/etc/default/locale", []byte(fmt.Sprintf("LANG=%s\n", locale)))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to /etc/default/locale")
+	}
+
+	// CentOS reads the system locale from /etc/locale.conf instead
+	confChanged, err := p.fs.ConvergeFileContents("/etc/locale.conf", []byte(fmt.Sprintf("LANG=%s\n", locale)))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing to /etc/locale.conf")
+	}
+
+	if !p.cmdRunner.CommandExists("locale-gen") {
+		return nil
+	}
+
+	// Locale is generated again when it went missing even though config did not change
+	if !defaultChanged && !confChanged && p.localeInstalled(locale) {
+		return nil
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("locale-gen", locale)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to locale-gen")
+	}
+
+	return nil
+}
+
+// localeInstalled checks 'locale -a' which lists e.g. en_US.UTF-8 as en_US.utf8
+func (p linux) localeInstalled(locale string) bool {
+	stdout, _, _, err := p.cmdRunner.RunCommand("locale", "-a")
+	if err != nil {
+		return false
+	}
+
+	normalize := func(name string) string {
+		return strings.Replace(strings.ToLower(strings.TrimSpace(name)), "-", "", -1)
+	}
+
+	for _, installed := range strings.Split(stdout, "\n") {
+		if normalize(installed) == normalize(locale) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p linux) SetupLogrotate(groupName, basePath, size string) (err error) {
+	buffer := bytes.NewBuffer([]byte{})
+	t := template.Must(template.New("logrotate-d-config").Parse(etcLogrotateDTemplate))
+
+	type logrotateArgs struct {
+		BasePath string
+		Size     string
+	}
+
+	err = t.Execute(buffer, logrotateArgs{basePath, size})
+	if err != nil {
+		err = bosherr.WrapError(err, "Generating logrotate config")
+		return
+	}
+
+	err = p.fs.WriteFile(path.Join("/etc/logrotate.d", groupName), buffer.Bytes())
+	if err != nil {
+		err = bosherr.WrapError(err, "Writing to /etc/logrotate.d")
+		return
+	}
+
+	return
+}
+
+// Logrotate config file - /etc/logrotate.d/<group-name>
+// Stemcell stage logrotate_config configures logrotate to run every hour
+const etcLogrotateDTemplate = `# Generated by bosh-agent
+
+{{ .BasePath }}/data/sys/log/*.log {{ .BasePath }}/data/sys/log/.*.log {{ .BasePath }}/data/sys/log/*/*.log {{ .BasePath }}/data/sys/log/*/.*.log {{ .BasePath }}/data/sys/log/*/*/*.log {{ .BasePath }}/data/sys/log/*/*/.*.log {
+  missingok
+  rotate 7
+  compress
+  delaycompress
+  copytruncate
+  size={{ .Size }}
+}
+`
+
+func (p linux) SetTimeWithNtpServers(servers []string) (err error) {
+	serversFilePath := path.Join(p.dirProvider.BaseDir(), "/bosh/etc/ntpserver")
+	if len(servers) == 0 {
+		return
+	}
+
+	err = p.fs.WriteFileString(serversFilePath, strings.Join(servers, " "))
+	if err != nil {
+		err = bosherr.WrapErrorf(err, "Writing to %s", serversFilePath)
+		return
+	}
+
+	// Make a best effort to sync time now but don't error
+	_, _, _, _ = p.cmdRunner.RunCommand("ntpdate")
+	return
+}
+
+func (p linux) SetupEphemeralDiskWithPath(realPath string, diskSettings boshsettings.DiskSettings) error {
+	if p.options.SkipDiskSetup {
+		return nil
+	}
+
+	dataDiskFS, err := p.diskFileSystem(diskSettings)
+	if err != nil {
+		return err
+	}
+
+	p.logger.Info(logTag, "Setting up ephemeral disk...")
+	mountPoint := p.dirProvider.DataDir()
+
+	mountPointGlob := path.Join(mountPoint, "*")
+	contents, err := p.fs.Glob(mountPointGlob)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Globbing ephemeral disk mount point `%s'", mountPointGlob)
+	}
+
+	if contents != nil && len(contents) > 0 {
+		// When agent bootstraps for the first time data directory should be empty.
+		// It might be non-empty on subsequent agent restarts. The ephemeral disk setup
+		// should be idempotent and partitioning will be skipped if disk is already
 		// partitioned as needed. If disk is not partitioned as needed we still want to
 		// partition it even if data directory is not empty.
 		p.logger.Debug(logTag, "Existing ephemeral mount `%s' is not empty. Contents: %s", mountPoint, contents)
@@ -515,6 +1242,9 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
 		if err != nil {
 			return bosherr.WrapError(err, "Creating ephemeral partitions on root device")
 		}
+	} else if p.isEphemeralRAID(realPath) {
+		// Array is used whole for the data dir, without swap
+		dataPartitionPath = realPath
 	} else {
 		swapPartitionPath, dataPartitionPath, err = p.partitionEphemeralDisk(realPath)
 		if err != nil {
@@ -522,30 +1252,85 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string) error {
 		}
 	}
 
-	p.logger.Info(logTag, "Formatting `%s' as swap", swapPartitionPath)
-	err = p.diskManager.GetFormatter().Format(swapPartitionPath, boshdisk.FileSystemSwap)
-	if err != nil {
-		return bosherr.WrapError(err, "Formatting swap")
+	if swapPartitionPath != "" {
+		p.logger.Info(logTag, "Formatting `%s' as swap", swapPartitionPath)
+		err = p.diskManager.GetFormatter().Format(swapPartitionPath, boshdisk.FileSystemSwap, boshdisk.MkfsTuning{})
+		if err != nil {
+			return bosherr.WrapError(err, "Formatting swap")
+		}
 	}
 
-	p.logger.Info(logTag, "Formatting `%s' as ext4", dataPartitionPath)
-	err = p.diskManager.GetFormatter().Format(dataPartitionPath, boshdisk.FileSystemExt4)
+	p.logger.Info(logTag, "Formatting `%s' as %s", dataPartitionPath, dataDiskFS)
+	err = p.diskManager.GetFormatter().Format(dataPartitionPath, dataDiskFS, diskSettings.MkfsTuning, diskSettings.MkfsOptions...)
 	if err != nil {
-		return bosherr.WrapError(err, "Formatting data partition with ext4")
+		return bosherr.WrapErrorf(err, "Formatting data partition with %s", dataDiskFS)
 	}
 
-	p.logger.Info(logTag, "Mounting `%s' as swap", swapPartitionPath)
-	err = p.diskManager.GetMounter().SwapOn(swapPartitionPath)
-	if err != nil {
-		return bosherr.WrapError(err, "Mounting swap")
+	if swapPartitionPath != "" {
+		p.logger.Info(logTag, "Mounting `%s' as swap", swapPartitionPath)
+		err = p.diskManager.GetMounter().SwapOn(swapPartitionPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Mounting swap")
+		}
 	}
 
 	p.logger.Info(logTag, "Mounting `%s' at `%s'", dataPartitionPath, mountPoint)
-	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint, mountOptionArgs(diskSettings.MountOptions)...)
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting data partition")
 	}
 
+	if p.options.UseZramSwap {
+		p.logger.Info(logTag, "Setting up swap on `%s'", zramSwapDevicePath)
+		err = p.setupZramSwap()
+		if err != nil {
+			return bosherr.WrapError(err, "Setting up zram swap")
+		}
+	}
+
+	return nil
+}
+
+func (p linux) setupZramSwap() error {
+	size := p.options.ZramSwapSize
+	if size == "" {
+		memStats, err := p.collector.GetMemStats()
+		if err != nil {
+			return bosherr.WrapError(err, "Getting mem stats")
+		}
+
+		size = strconv.FormatUint(memStats.Total/2, 10)
+	}
+
+	_, _, _, err := p.cmdRunner.RunCommand("modprobe", "zram", "num_devices=1")
+	if err != nil {
+		return bosherr.WrapError(err, "Loading zram module")
+	}
+
+	// Size can only be set on an uninitialized device;
+	// device is left as is when agent restarts
+	diskSize, err := p.fs.ReadFileString(zramSwapDiskSizePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading zram device size")
+	}
+
+	if strings.TrimSpace(diskSize) == "0" {
+		err = p.fs.WriteFileString(zramSwapDiskSizePath, size)
+		if err != nil {
+			return bosherr.WrapError(err, "Setting zram device size")
+		}
+	}
+
+	err = p.diskManager.GetFormatter().Format(zramSwapDevicePath, boshdisk.FileSystemSwap, boshdisk.MkfsTuning{})
+	if err != nil {
+		return bosherr.WrapError(err, "Formatting zram swap")
+	}
+
+	err = p.diskManager.GetMounter().SwapOn(zramSwapDevicePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Mounting zram swap")
+	}
+
 	return nil
 }
 
@@ -556,6 +1341,10 @@ func (p linux) SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err
 
 	p.logger.Info(logTag, "Setting up raw ephemeral disks")
 
+	if p.options.StripeRawEphemeralDisks && len(devices) > 1 {
+		return p.setupEphemeralRAID(devices)
+	}
+
 	for i, device := range devices {
 		realPath, _, err := p.devicePathResolver.GetRealDevicePath(device)
 		if err != nil {
@@ -606,6 +1395,34 @@ func (p linux) SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err
 	return nil
 }
 
+func (p linux) setupEphemeralRAID(devices []boshsettings.DiskSettings) error {
+	var realPaths []string
+
+	for _, device := range devices {
+		realPath, _, err := p.devicePathResolver.GetRealDevicePath(device)
+		if err != nil {
+			return bosherr.WrapError(err, "Getting real device path")
+		}
+
+		realPaths = append(realPaths, realPath)
+	}
+
+	p.logger.Info(logTag, "Striping raw ephemeral disks %v", realPaths)
+
+	_, err := p.diskManager.GetRAIDManager().Assemble(ephemeralRAIDName, realPaths)
+	if err != nil {
+		return bosherr.WrapError(err, "Assembling raw ephemeral disks array")
+	}
+
+	return nil
+}
+
+// isEphemeralRAID returns true when the device is the array
+// striped across raw ephemeral disks
+func (p linux) isEphemeralRAID(realPath string) bool {
+	return p.options.StripeRawEphemeralDisks && realPath == p.diskManager.GetRAIDManager().Path(ephemeralRAIDName)
+}
+
 func (p linux) SetupDataDir() error {
 	dataDir := p.dirProvider.DataDir()
 
@@ -660,198 +1477,1334 @@ func (p linux) setupRunDir(sysDir string) error {
 			return bosherr.WrapErrorf(err, "Mounting tmpfs to %s", runDir)
 		}
 
-		_, _, _, err = p.cmdRunner.RunCommand("chown", "root:vcap", runDir)
-		if err != nil {
-			return bosherr.WrapErrorf(err, "chown %s", runDir)
-		}
+		_, _, _, err = p.cmdRunner.RunCommand("chown", "root:vcap", runDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "chown %s", runDir)
+		}
+	}
+
+	return nil
+}
+
+func (p linux) SetupTmpDir() error {
+	systemTmpDir := "/tmp"
+	boshTmpDir := p.dirProvider.TmpDir()
+	boshRootTmpPath := path.Join(p.dirProvider.DataDir(), "root_tmp")
+
+	err := p.fs.MkdirAll(boshTmpDir, tmpDirPermissions)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating temp dir")
+	}
+
+	err = os.Setenv("TMPDIR", boshTmpDir)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting TMPDIR")
+	}
+
+	err = p.changeTmpDirPermissions(systemTmpDir)
+	if err != nil {
+		return err
+	}
+
+	// /var/tmp is used for preserving temporary files between system reboots
+	_, _, _, err = p.cmdRunner.RunCommand("chmod", "0700", "/var/tmp")
+	if err != nil {
+		return bosherr.WrapError(err, "chmod /var/tmp")
+	}
+
+	if p.options.UseDefaultTmpDir {
+		return nil
+	}
+
+	if p.options.UseTmpfsForTmpDirs {
+		return p.setupTmpfsTmpDirs()
+	}
+
+	_, systemTmpDirIsMounted, err := p.IsMountPoint(systemTmpDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking for mount point %s", systemTmpDir)
+	}
+
+	if !systemTmpDirIsMounted {
+		// If it's not mounted on /tmp, blow it away
+		_, _, _, err = p.cmdRunner.RunCommand("truncate", "-s", "128M", boshRootTmpPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Truncating root tmp dir")
+		}
+
+		_, _, _, err = p.cmdRunner.RunCommand("chmod", "0700", boshRootTmpPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Chmoding root tmp dir")
+		}
+
+		_, _, _, err = p.cmdRunner.RunCommand("mke2fs", "-t", "ext4", "-m", "1", "-F", boshRootTmpPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating root tmp dir filesystem")
+		}
+
+		err = p.diskManager.GetMounter().Mount(boshRootTmpPath, systemTmpDir, "-t", "ext4", "-o", "loop,noexec")
+		if err != nil {
+			return bosherr.WrapError(err, "Mounting root tmp dir over /tmp")
+		}
+
+		// Change permissions for new mount point
+		err = p.changeTmpDirPermissions(systemTmpDir)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p linux) SetupFilesystemTrimming() error {
+	if p.options.FilesystemTrimIntervalInSeconds <= 0 {
+		return nil
+	}
+
+	if !p.cmdRunner.CommandExists("fstrim") {
+		return bosherr.Error("fstrim is not installed")
+	}
+
+	interval := time.Duration(p.options.FilesystemTrimIntervalInSeconds) * time.Second
+
+	p.trimWG.Add(1)
+
+	go func() {
+		defer p.trimWG.Done()
+		defer p.logger.HandlePanic("Filesystem Trimming")
+
+		for {
+			timer := p.timeService.NewTimer(interval)
+
+			select {
+			case <-p.trimStopCh:
+				timer.Stop()
+				return
+			case <-timer.C():
+				p.trimFilesystems()
+			}
+		}
+	}()
+
+	return nil
+}
+
+// stopFilesystemTrimming waits for trimming in progress to finish
+func (p linux) stopFilesystemTrimming() {
+	p.trimStopOnce.Do(func() { close(p.trimStopCh) })
+	p.trimWG.Wait()
+}
+
+// trimFilesystems trims every filesystem backed by a block device other
+// than the root filesystem, which covers the ephemeral disk and all persistent disks.
+// Bind mounts (e.g. read-only root overlays) are trimmed once through their first mount point.
+func (p linux) trimFilesystems() {
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		p.logger.Warn(logTag, "Searching mounts for trimming: %s", err.Error())
+		return
+	}
+
+	trimmedPartitions := map[string]bool{}
+
+	for _, mount := range mounts {
+		if !strings.HasPrefix(mount.PartitionPath, "/dev/") || mount.MountPoint == "/" {
+			continue
+		}
+
+		if trimmedPartitions[mount.PartitionPath] {
+			continue
+		}
+
+		trimmedPartitions[mount.PartitionPath] = true
+
+		stdout, _, _, err := p.cmdRunner.RunCommand("fstrim", "-v", mount.MountPoint)
+		if err != nil {
+			p.logger.Warn(logTag, "Trimming filesystem mounted on %s: %s", mount.MountPoint, err.Error())
+			continue
+		}
+
+		p.logger.Debug(logTag, "Trimmed filesystem: %s", strings.TrimSpace(stdout))
+	}
+}
+
+// SetupJobDiskQuotas limits how much of the ephemeral disk each job can use
+// by assigning the job's data and log directories to a project quota.
+// Ephemeral disk has to be mounted with prjquota option (and for ext4
+// formatted with project quota feature) for limits to be enforced.
+// Limits of jobs that no longer have a quota are cleared.
+func (p linux) SetupJobDiskQuotas(quotasInMB map[string]uint64) error {
+	projects, err := loadJobDiskQuotaProjects(p.fs, p.dirProvider.BoshDir())
+	if err != nil {
+		return bosherr.WrapError(err, "Loading job disk quota projects")
+	}
+
+	jobNames := []string{}
+	for jobName := range quotasInMB {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	removedJobNames := projects.Removed(jobNames)
+
+	if len(jobNames) == 0 && len(removedJobNames) == 0 {
+		return nil
+	}
+
+	dataDir := p.dirProvider.DataDir()
+
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		return bosherr.WrapError(err, "Searching mounts")
+	}
+
+	var dataMount *boshdisk.Mount
+	for i, mount := range mounts {
+		if mount.MountPoint == dataDir {
+			dataMount = &mounts[i]
+			break
+		}
+	}
+
+	if dataMount == nil {
+		return bosherr.Errorf("Ephemeral disk is not mounted at '%s'", dataDir)
+	}
+
+	if !stringSliceContains(dataMount.Options, "prjquota") {
+		return bosherr.Errorf("Ephemeral disk mounted at '%s' must be mounted with prjquota option to enforce job disk quotas", dataDir)
+	}
+
+	stdout, _, _, err := p.cmdRunner.RunCommand("stat", "-f", "-c", "%T", dataDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Determining filesystem type of '%s'", dataDir)
+	}
+
+	fsType := strings.TrimSpace(stdout)
+
+	if fsType != "xfs" {
+		err = p.checkExtProjectQuotaFeature(dataMount.PartitionPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	setLimit := func(projectID string, quotaInMB uint64) error {
+		if fsType == "xfs" {
+			_, _, _, err := p.cmdRunner.RunCommand("xfs_quota", "-x", "-c", fmt.Sprintf("limit -p bhard=%dm %s", quotaInMB, projectID), dataDir)
+			return err
+		}
+
+		quotaInKB := strconv.FormatUint(quotaInMB*1024, 10)
+		_, _, _, err := p.cmdRunner.RunCommand("setquota", "-P", projectID, "0", quotaInKB, "0", "0", dataDir)
+		return err
+	}
+
+	for _, jobName := range removedJobNames {
+		projectID := strconv.Itoa(projects.IDs[jobName])
+
+		p.logger.Info(logTag, "Clearing ephemeral disk limit of removed job '%s' (project %s)", jobName, projectID)
+
+		err = setLimit(projectID, 0)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Clearing disk quota of removed job '%s'", jobName)
+		}
+
+		projects.Release(jobName)
+	}
+
+	projectIDs := projects.Assign(jobNames)
+
+	err = projects.Save()
+	if err != nil {
+		return bosherr.WrapError(err, "Saving job disk quota projects")
+	}
+
+	for _, jobName := range jobNames {
+		projectID := strconv.Itoa(projectIDs[jobName])
+		quotaInMB := quotasInMB[jobName]
+
+		p.logger.Info(logTag, "Limiting ephemeral disk usage of job '%s' to %dMB (project %s)", jobName, quotaInMB, projectID)
+
+		jobDirs := []string{
+			path.Join(dataDir, jobName),
+			path.Join(dataDir, "sys", "log", jobName),
+		}
+
+		for _, jobDir := range jobDirs {
+			err = p.fs.MkdirAll(jobDir, jobDataDirPermissions)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Creating job dir '%s'", jobDir)
+			}
+
+			if fsType == "xfs" {
+				_, _, _, err = p.cmdRunner.RunCommand("xfs_quota", "-x", "-c", fmt.Sprintf("project -s -p %s %s", jobDir, projectID), dataDir)
+			} else {
+				_, _, _, err = p.cmdRunner.RunCommand("chattr", "-R", "+P", "-p", projectID, jobDir)
+			}
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Assigning job dir '%s' to project %s", jobDir, projectID)
+			}
+		}
+
+		err = setLimit(projectID, quotaInMB)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting disk quota for job '%s'", jobName)
+		}
+	}
+
+	return nil
+}
+
+// checkExtProjectQuotaFeature fails when ext4 filesystem was formatted without
+// project feature since chattr and setquota would not enforce limits otherwise
+func (p linux) checkExtProjectQuotaFeature(devicePath string) error {
+	stdout, _, _, err := p.cmdRunner.RunCommand("tune2fs", "-l", devicePath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Listing filesystem features of '%s'", devicePath)
+	}
+
+	// e.g. 'Filesystem features:      has_journal ext_attr ... project quota'
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.SplitN(line, ":", 2)
+		if len(fields) != 2 || strings.TrimSpace(fields[0]) != "Filesystem features" {
+			continue
+		}
+
+		if stringSliceContains(strings.Fields(fields[1]), "project") {
+			return nil
+		}
+	}
+
+	return bosherr.Errorf("Ephemeral disk '%s' must be formatted with ext4 project feature to enforce job disk quotas", devicePath)
+}
+
+func (p linux) SetupHugePages(hugePages boshsettings.HugePages) error {
+	if hugePages.Count <= 0 {
+		return nil
+	}
+
+	p.logger.Info(logTag, "Reserving %d huge pages (page size: '%s')", hugePages.Count, hugePages.PageSize)
+
+	if hugePages.PageSize == "" {
+		_, _, _, err := p.cmdRunner.RunCommand("sysctl", "-w", fmt.Sprintf("vm.nr_hugepages=%d", hugePages.Count))
+		if err != nil {
+			return bosherr.WrapError(err, "Setting vm.nr_hugepages")
+		}
+	} else {
+		pageSizeInKB, err := hugePageSizeInKB(hugePages.PageSize)
+		if err != nil {
+			return err
+		}
+
+		nrHugePagesPath := fmt.Sprintf("/sys/kernel/mm/hugepages/hugepages-%dkB/nr_hugepages", pageSizeInKB)
+		err = p.fs.WriteFileString(nrHugePagesPath, strconv.Itoa(hugePages.Count))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", nrHugePagesPath)
+		}
+	}
+
+	mountPath := hugePages.MountPath
+	if mountPath == "" {
+		mountPath = "/dev/hugepages"
+	}
+
+	_, isMounted, err := p.IsMountPoint(mountPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking for mount point %s", mountPath)
+	}
+
+	if isMounted {
+		return nil
+	}
+
+	err = p.fs.MkdirAll(mountPath, hugePagesDirPermissions)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Making %s dir", mountPath)
+	}
+
+	mountOptions := []string{"-t", "hugetlbfs"}
+	if hugePages.PageSize != "" {
+		mountOptions = append(mountOptions, "-o", "pagesize="+hugePages.PageSize)
+	}
+
+	err = p.diskManager.GetMounter().Mount("hugetlbfs", mountPath, mountOptions...)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Mounting hugetlbfs to %s", mountPath)
+	}
+
+	return nil
+}
+
+func hugePageSizeInKB(pageSize string) (int, error) {
+	if len(pageSize) < 2 {
+		return 0, bosherr.Errorf("Invalid huge page size '%s'", pageSize)
+	}
+
+	multipliers := map[string]int{"K": 1, "M": 1024, "G": 1024 * 1024}
+
+	multiplier, found := multipliers[strings.ToUpper(pageSize[len(pageSize)-1:])]
+	if !found {
+		return 0, bosherr.Errorf("Invalid huge page size '%s'", pageSize)
+	}
+
+	size, err := strconv.Atoi(pageSize[:len(pageSize)-1])
+	if err != nil || size <= 0 {
+		return 0, bosherr.Errorf("Invalid huge page size '%s'", pageSize)
+	}
+
+	return size * multiplier, nil
+}
+
+const (
+	grubDefaultsPath = "/etc/default/grub"
+	grubCmdlineKey   = "GRUB_CMDLINE_LINUX"
+)
+
+func (p linux) SetupKernelArgs(kernelArgs boshsettings.KernelArgs) (bool, error) {
+	if kernelArgs.IsEmpty() {
+		return false, nil
+	}
+
+	grubDefaults, err := p.fs.ReadFileString(grubDefaultsPath)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Reading %s", grubDefaultsPath)
+	}
+
+	lines := strings.Split(grubDefaults, "\n")
+	cmdlineIndex := -1
+	var currentArgs []string
+
+	for i, line := range lines {
+		if strings.HasPrefix(line, grubCmdlineKey+"=") {
+			cmdlineIndex = i
+			value := strings.Trim(strings.TrimPrefix(line, grubCmdlineKey+"="), `"'`)
+			currentArgs = strings.Fields(value)
+		}
+	}
+
+	desiredArgs := applyKernelArgs(currentArgs, kernelArgs)
+	desiredLine := fmt.Sprintf(`%s="%s"`, grubCmdlineKey, strings.Join(desiredArgs, " "))
+
+	if cmdlineIndex == -1 {
+		lines = append(lines, desiredLine)
+	} else {
+		lines[cmdlineIndex] = desiredLine
+	}
+
+	changed, err := p.fs.ConvergeFileContents(grubDefaultsPath, []byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Writing to %s", grubDefaultsPath)
+	}
+
+	if changed {
+		p.logger.Info(logTag, "Updating grub configuration with kernel args: %v", desiredArgs)
+
+		if p.cmdRunner.CommandExists("update-grub") {
+			_, _, _, err = p.cmdRunner.RunCommand("update-grub")
+		} else {
+			_, _, _, err = p.cmdRunner.RunCommand("grub2-mkconfig", "-o", "/boot/grub2/grub.cfg")
+		}
+		if err != nil {
+			return false, bosherr.WrapError(err, "Updating grub configuration")
+		}
+	}
+
+	runningCmdline, err := p.fs.ReadFileString("/proc/cmdline")
+	if err != nil {
+		return false, bosherr.WrapError(err, "Reading /proc/cmdline")
+	}
+
+	runningArgs := strings.Fields(runningCmdline)
+	rebootRequired := !kernelArgsSatisfied(runningArgs, kernelArgs)
+
+	rebootRequiredPath := path.Join(p.dirProvider.BoshDir(), "reboot_required")
+	if rebootRequired {
+		err = p.fs.WriteFileString(rebootRequiredPath, "Kernel command line parameters changed\n")
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Writing %s", rebootRequiredPath)
+		}
+	} else {
+		err = p.fs.RemoveAll(rebootRequiredPath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Removing %s", rebootRequiredPath)
+		}
+	}
+
+	return rebootRequired, nil
+}
+
+func kernelArgName(arg string) string {
+	return strings.SplitN(arg, "=", 2)[0]
+}
+
+func kernelArgMatches(arg, pattern string) bool {
+	if strings.Contains(pattern, "=") {
+		return arg == pattern
+	}
+	return kernelArgName(arg) == pattern
+}
+
+// applyKernelArgs removes requested parameters and then adds (or replaces by name)
+// requested parameters while preserving the order of untouched ones
+func applyKernelArgs(args []string, kernelArgs boshsettings.KernelArgs) []string {
+	var result []string
+
+	for _, arg := range args {
+		keep := true
+
+		for _, pattern := range kernelArgs.Remove {
+			if kernelArgMatches(arg, pattern) {
+				keep = false
+			}
+		}
+
+		for _, added := range kernelArgs.Add {
+			if kernelArgName(arg) == kernelArgName(added) {
+				keep = false
+			}
+		}
+
+		if keep {
+			result = append(result, arg)
+		}
+	}
+
+	return append(result, kernelArgs.Add...)
+}
+
+func kernelArgsSatisfied(runningArgs []string, kernelArgs boshsettings.KernelArgs) bool {
+	for _, added := range kernelArgs.Add {
+		if !stringSliceContains(runningArgs, added) {
+			return false
+		}
+	}
+
+	for _, arg := range runningArgs {
+		for _, pattern := range kernelArgs.Remove {
+			if kernelArgMatches(arg, pattern) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+func stringSliceContains(slice []string, str string) bool {
+	for _, s := range slice {
+		if s == str {
+			return true
+		}
+	}
+	return false
+}
+
+func (p linux) SetupTuningProfile(profile boshsettings.TuningProfile) error {
+	if profile.IsEmpty() {
+		return nil
+	}
+
+	p.logger.Info(logTag, "Applying tuning profile '%s'", profile.Name)
+
+	if profile.CPUGovernor != "" {
+		governorPaths, err := p.fs.Glob("/sys/devices/system/cpu/cpu*/cpufreq/scaling_governor")
+		if err != nil {
+			return bosherr.WrapError(err, "Globbing cpufreq scaling governors")
+		}
+
+		if len(governorPaths) == 0 {
+			p.logger.Warn(logTag, "cpufreq is not available, skipping setting CPU governor '%s'", profile.CPUGovernor)
+		}
+
+		for _, governorPath := range governorPaths {
+			err = p.fs.WriteFileString(governorPath, profile.CPUGovernor)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Setting CPU governor in %s", governorPath)
+			}
+		}
+	}
+
+	if profile.TransparentHugePages != "" {
+		switch profile.TransparentHugePages {
+		case "always", "madvise", "never":
+		default:
+			return bosherr.Errorf("Invalid transparent huge pages mode '%s'", profile.TransparentHugePages)
+		}
+
+		thpPath := "/sys/kernel/mm/transparent_hugepage/enabled"
+		err := p.fs.WriteFileString(thpPath, profile.TransparentHugePages)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", thpPath)
+		}
+	}
+
+	for irq, cpuList := range profile.IRQAffinity {
+		if _, err := strconv.Atoi(irq); err != nil {
+			return bosherr.Errorf("Invalid IRQ number '%s'", irq)
+		}
+
+		affinityPath := path.Join("/proc/irq", irq, "smp_affinity_list")
+		err := p.fs.WriteFileString(affinityPath, cpuList)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting affinity of IRQ %s", irq)
+		}
+	}
+
+	return nil
+}
+
+func (p linux) SetupBindMounts() error {
+	return p.setupBindMounts(p.dirProvider.DataDir(), p.options.EphemeralDiskBindMountPaths)
+}
+
+// setupBindMounts relocates paths onto the disk mounted at baseDir; contents of
+// a path are copied onto the disk only when it is bind-mounted for the first time
+func (p linux) setupBindMounts(baseDir string, bindMountPaths []string) error {
+	for _, bindMountPath := range bindMountPaths {
+		_, isMounted, err := p.IsMountPoint(bindMountPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking for mount point %s", bindMountPath)
+		}
+
+		if isMounted {
+			continue
+		}
+
+		sourceDir := path.Join(baseDir, "bind_mounts", strings.Replace(strings.Trim(bindMountPath, "/"), "/", "_", -1))
+
+		if !p.fs.FileExists(sourceDir) {
+			err = p.relocateBindMountPath(bindMountPath, sourceDir)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Relocating %s to %s", bindMountPath, sourceDir)
+			}
+		}
+
+		err = p.fs.MkdirAll(bindMountPath, bindMountDirPermissions)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Making %s dir", bindMountPath)
+		}
+
+		p.logger.Info(logTag, "Bind-mounting `%s' over `%s'", sourceDir, bindMountPath)
+
+		_, _, _, err = p.cmdRunner.RunCommand("mount", "--bind", sourceDir, bindMountPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Bind-mounting %s", bindMountPath)
+		}
+	}
+
+	return nil
+}
+
+// relocateBindMountPath copies contents into a temporary directory first
+// so that interrupted copy is retried on next boot
+func (p linux) relocateBindMountPath(bindMountPath, sourceDir string) error {
+	tmpSourceDir := sourceDir + ".tmp"
+
+	err := p.fs.RemoveAll(tmpSourceDir)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing %s dir", tmpSourceDir)
+	}
+
+	for _, dir := range []string{path.Dir(sourceDir), tmpSourceDir} {
+		err = p.fs.MkdirAll(dir, bindMountDirPermissions)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Making %s dir", dir)
+		}
+	}
+
+	if p.fs.FileExists(bindMountPath) {
+		_, _, _, err = p.cmdRunner.RunCommand("cp", "-a", bindMountPath+"/.", tmpSourceDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Copying contents of %s", bindMountPath)
+		}
+	}
+
+	return p.fs.Rename(tmpSourceDir, sourceDir)
+}
+
+func (p linux) unmountBindMounts(bindMountPaths []string) error {
+	for i := len(bindMountPaths) - 1; i >= 0; i-- {
+		_, err := p.diskManager.GetMounter().Unmount(bindMountPaths[i])
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Unmounting bind mount %s", bindMountPaths[i])
+		}
+	}
+
+	return nil
+}
+
+func (p linux) SetupReadOnlyRoot() error {
+	rootIsReadOnly, err := p.isRootReadOnly()
+	if err != nil {
+		return bosherr.WrapError(err, "Checking whether root filesystem is read-only")
+	}
+
+	if !p.options.ReadOnlyRootFilesystem && !rootIsReadOnly {
+		return nil
+	}
+
+	writablePaths := append([]string{}, p.options.ReadOnlyRootWritablePaths...)
+	if len(writablePaths) == 0 {
+		writablePaths = append(writablePaths, defaultReadOnlyRootWritablePaths...)
+	}
+
+	// Agent keeps writing users, networking, settings and job configs there
+	agentWritablePaths := []string{"/etc", p.dirProvider.BoshDir(), p.dirProvider.JobsDir(), p.dirProvider.MonitDir()}
+	for _, agentPath := range agentWritablePaths {
+		if !stringSliceContains(writablePaths, agentPath) {
+			writablePaths = append(writablePaths, agentPath)
+		}
+	}
+
+	overlaysDir := path.Join(p.dirProvider.DataDir(), "root_overlay")
+
+	for _, writablePath := range writablePaths {
+		_, isMounted, err := p.IsMountPoint(writablePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking for mount point %s", writablePath)
+		}
+
+		if isMounted {
+			continue
+		}
+
+		overlayDir := path.Join(overlaysDir, strings.Replace(strings.Trim(writablePath, "/"), "/", "_", -1))
+		upperDir := path.Join(overlayDir, "upper")
+		workDir := path.Join(overlayDir, "work")
+
+		for _, dir := range []string{upperDir, workDir} {
+			err = p.fs.MkdirAll(dir, rootOverlayPermissions)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Making %s dir", dir)
+			}
+		}
+
+		p.logger.Info(logTag, "Overlaying `%s' with writable directory `%s'", writablePath, upperDir)
+
+		overlayOptions := fmt.Sprintf("lowerdir=%s,upperdir=%s,workdir=%s", writablePath, upperDir, workDir)
+		_, _, _, err = p.cmdRunner.RunCommand("mount", "-t", "overlay", "overlay", "-o", overlayOptions, writablePath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting overlay on %s", writablePath)
+		}
+	}
+
+	if !rootIsReadOnly {
+		p.logger.Info(logTag, "Remounting root filesystem read-only")
+
+		_, _, _, err = p.cmdRunner.RunCommand("mount", "-o", "remount,ro", "/")
+		if err != nil {
+			return bosherr.WrapError(err, "Remounting root filesystem read-only")
+		}
+	}
+
+	return nil
+}
+
+func (p linux) isRootReadOnly() (bool, error) {
+	entry, err := p.procMountsEntry("/")
+	if err != nil || entry == nil {
+		return false, err
+	}
+
+	return stringSliceContains(strings.Split(entry[3], ","), "ro"), nil
+}
+
+// mountedFileSystemType returns type of the filesystem mounted at the mount point
+func (p linux) mountedFileSystemType(mountPoint string) (boshdisk.FileSystemType, error) {
+	entry, err := p.procMountsEntry(mountPoint)
+	if err != nil || entry == nil {
+		return boshdisk.FileSystemDefault, err
+	}
+
+	return boshdisk.FileSystemType(entry[2]), nil
+}
+
+// procMountsEntry returns fields of the /proc/mounts entry for the mount point
+// or nil if nothing is mounted there (or /proc/mounts is not available)
+func (p linux) procMountsEntry(mountPoint string) ([]string, error) {
+	if !p.fs.FileExists("/proc/mounts") {
+		return nil, nil
+	}
+
+	mounts, err := p.fs.ReadFileString("/proc/mounts")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading /proc/mounts")
+	}
+
+	var entry []string
+
+	// Later entries for the same mount point shadow earlier ones (e.g. rootfs)
+	for _, line := range strings.Split(mounts, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[1] != mountPoint {
+			continue
+		}
+
+		entry = fields
+	}
+
+	return entry, nil
+}
+
+func (p linux) setupTmpfsTmpDirs() error {
+	size := p.options.TmpfsTmpDirSize
+	if size == "" {
+		size = defaultTmpfsTmpDirSize
+	}
+
+	for _, tmpDir := range []string{"/tmp", "/var/tmp"} {
+		_, isMounted, err := p.IsMountPoint(tmpDir)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking for mount point %s", tmpDir)
+		}
+
+		if isMounted {
+			continue
+		}
+
+		err = p.diskManager.GetMounter().Mount("tmpfs", tmpDir, "-t", "tmpfs", "-o", "size="+size+",noexec,nosuid,nodev")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting tmpfs over %s", tmpDir)
+		}
+	}
+
+	// Change permissions for new mount points
+	err := p.changeTmpDirPermissions("/tmp")
+	if err != nil {
+		return err
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("chmod", "0700", "/var/tmp")
+	if err != nil {
+		return bosherr.WrapError(err, "chmod /var/tmp")
+	}
+
+	return nil
+}
+
+func (p linux) changeTmpDirPermissions(path string) error {
+	_, _, _, err := p.cmdRunner.RunCommand("chown", "root:vcap", path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "chown %s", path)
+	}
+
+	_, _, _, err = p.cmdRunner.RunCommand("chmod", "0770", path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "chmod %s", path)
+	}
+
+	return nil
+}
+
+func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountPoint string) error {
+	err := p.mountPersistentDisk(diskSetting, mountPoint)
+	if err != nil {
+		return err
+	}
+
+	if mountPoint != p.dirProvider.StoreDir() {
+		return nil
+	}
+
+	err = p.setupBindMounts(mountPoint, p.options.PersistentDiskBindMountPaths)
+	if err != nil {
+		return bosherr.WrapError(err, "Setting up persistent disk bind mounts")
+	}
+
+	return nil
+}
+
+func (p linux) mountPersistentDisk(diskSetting boshsettings.DiskSettings, mountPoint string) error {
+	p.logger.Debug(logTag, "Mounting persistent disk %+v at %s", diskSetting, mountPoint)
+
+	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSetting)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting real device path")
+	}
+
+	if diskSetting.IsEncrypted() && p.options.UsePreformattedPersistentDisk {
+		return bosherr.Error("Encrypting pre-formatted persistent disks is not supported")
+	}
+
+	if diskSetting.IsEncrypted() && p.options.UseZFSForPersistentDisk {
+		return bosherr.Error("Encrypting ZFS persistent disks is not supported")
+	}
+
+	devicePath, isMountPoint, err := p.IsMountPoint(mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking mount point")
+	}
+	p.logger.Info(logTag, "realPath = %s, devicePath = %s, isMountPoint = %s", realPath, devicePath, isMountPoint)
+
+	partitionPath := p.persistentDiskPartitionPath(realPath)
+
+	if p.options.UseLVMForPersistentDisk {
+		partitionPath = p.persistentDiskLogicalVolumePath(diskSetting)
+	}
+
+	if p.options.UseZFSForPersistentDisk {
+		partitionPath = p.persistentDiskDataset(diskSetting)
+	}
+
+	mountDevicePath := partitionPath
+	if diskSetting.IsEncrypted() {
+		mountDevicePath = p.persistentDiskCryptPath(diskSetting)
+	}
+
+	if isMountPoint {
+		if mountDevicePath == devicePath {
+			p.logger.Info(logTag, "device: %s is already mounted on %s, skipping mounting", devicePath, mountPoint)
+			return nil
+		}
+
+		mountPoint = p.dirProvider.MigrationDir(mountPoint)
+	}
+
+	err = p.fs.MkdirAll(mountPoint, persistentDiskPermissions)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating directory %s", mountPoint)
+	}
+
+	if p.options.UseZFSForPersistentDisk {
+		dataset, err := p.diskManager.GetZFSPoolManager().Create(
+			persistentDiskVolumeGroup(diskSetting),
+			persistentDiskLogicalVolume,
+			[]string{realPath},
+		)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating ZFS dataset")
+		}
+
+		mountOptions := append([]string{"-t", "zfs"}, mountOptionArgs(diskSetting.MountOptions)...)
+
+		err = p.diskManager.GetMounter().Mount(dataset, mountPoint, mountOptions...)
+		if err != nil {
+			return bosherr.WrapError(err, "Mounting ZFS dataset")
+		}
+
+		return nil
+	}
+
+	if p.options.UseLVMForPersistentDisk {
+		persistentDiskFS, err := p.diskFileSystem(diskSetting)
+		if err != nil {
+			return err
+		}
+
+		realPath, err = p.diskManager.GetLogicalVolumeManager().Create(
+			persistentDiskVolumeGroup(diskSetting),
+			persistentDiskLogicalVolume,
+			[]string{realPath},
+		)
+		if err != nil {
+			return bosherr.WrapError(err, "Creating logical volume")
+		}
+
+		realPath, err = p.openEncryptedPersistentDisk(diskSetting, realPath)
+		if err != nil {
+			return err
+		}
+
+		err = p.diskManager.GetFormatter().Format(realPath, persistentDiskFS, diskSetting.MkfsTuning, diskSetting.MkfsOptions...)
+		if err != nil {
+			return bosherr.WrapError(err, fmt.Sprintf("Formatting logical volume with %s", persistentDiskFS))
+		}
+	} else if !p.options.UsePreformattedPersistentDisk {
+		partitions := []boshdisk.Partition{
+			{Type: boshdisk.PartitionTypeLinux},
+		}
+
+		diskSize, err := p.diskManager.GetDiskUtil(realPath).GetBlockDeviceSize()
+
+		p.logger.Debug(logTag, "Persistent disk size to be partitioned is: %d, and error is: %v", diskSize, err)
+
+		if err != nil || diskSize < maxFdiskPartitionSize {
+			p.logger.Debug(logTag, "fdisk partitioner was chosen")
+			err = p.diskManager.GetPartitioner().Partition(realPath, partitions)
+		} else {
+			p.logger.Debug(logTag, "parted partitioner was chosen")
+			err = p.diskManager.GetPartedPartitioner().Partition(realPath, partitions)
+		}
+
+		if err != nil {
+			return bosherr.WrapError(err, "Partitioning disk")
+		}
+
+		persistentDiskFS, err := p.diskFileSystem(diskSetting)
+		if err != nil {
+			return err
+		}
+
+		partitionPath, err = p.openEncryptedPersistentDisk(diskSetting, partitionPath)
+		if err != nil {
+			return err
+		}
+
+		err = p.diskManager.GetFormatter().Format(partitionPath, persistentDiskFS, diskSetting.MkfsTuning, diskSetting.MkfsOptions...)
+		if err != nil {
+			return bosherr.WrapError(err, fmt.Sprintf("Formatting partition with %s", diskSetting.FileSystemType))
+		}
+
+		realPath = partitionPath
+	}
+
+	err = p.diskManager.GetMounter().Mount(realPath, mountPoint, mountOptionArgs(diskSetting.MountOptions)...)
+	if err != nil {
+		return p.checkPersistentDiskFilesystem(realPath, mountPoint, diskSetting.MountOptions, err)
+	}
+
+	return nil
+}
+
+// mountOptionArgs returns mount arguments for options given in disk settings
+func mountOptionArgs(mountOptions []string) []string {
+	if len(mountOptions) == 0 {
+		return nil
+	}
+	return []string{"-o", strings.Join(mountOptions, ",")}
+}
+
+// diskMountOptions are options disk settings commonly specify which
+// the kernel reports back verbatim, so they can be carried over on remount
+var diskMountOptions = map[string]bool{
+	"noatime":    true,
+	"nodiratime": true,
+	"lazytime":   true,
+	"nobarrier":  true,
+	"discard":    true,
+	"nodev":      true,
+	"nosuid":     true,
+	"noexec":     true,
+}
+
+// diskMountOptionsOf returns disk mount options the filesystem on given mount point is mounted with
+func (p linux) diskMountOptionsOf(mountPoint string) ([]string, error) {
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Searching mounts")
+	}
+
+	var mountOptions []string
+
+	for _, mount := range mounts {
+		if mount.MountPoint != mountPoint {
+			continue
+		}
+
+		for _, option := range mount.Options {
+			if diskMountOptions[option] {
+				mountOptions = append(mountOptions, option)
+			}
+		}
+	}
+
+	return mountOptions, nil
+}
+
+// diskFileSystem returns filesystem type data and persistent disks
+// should be formatted with; ext4 is used unless xfs is requested
+func (p linux) diskFileSystem(diskSetting boshsettings.DiskSettings) (boshdisk.FileSystemType, error) {
+	switch diskSetting.FileSystemType {
+	case boshdisk.FileSystemExt4, boshdisk.FileSystemXFS:
+		return diskSetting.FileSystemType, nil
+	case boshdisk.FileSystemDefault:
+		return boshdisk.FileSystemExt4, nil
+	default:
+		return "", bosherr.Error(fmt.Sprintf(`The filesystem type "%s" is not supported`, diskSetting.FileSystemType))
+	}
+}
+
+// persistentDiskVolumeGroup returns name of the volume group backing
+// the persistent disk; disk IDs are sanitized to characters LVM allows
+func persistentDiskVolumeGroup(diskSettings boshsettings.DiskSettings) string {
+	return "bosh_" + sanitizedDiskID(diskSettings)
+}
+
+// persistentDiskCryptName returns name of the device mapping
+// of the unlocked LUKS volume of the persistent disk
+func persistentDiskCryptName(diskSettings boshsettings.DiskSettings) string {
+	return persistentDiskCryptPrefix + sanitizedDiskID(diskSettings)
+}
+
+func sanitizedDiskID(diskSettings boshsettings.DiskSettings) string {
+	return regexp.MustCompile(`[^a-zA-Z0-9_]`).ReplaceAllString(diskSettings.ID, "_")
+}
+
+func (p linux) persistentDiskCryptPath(diskSettings boshsettings.DiskSettings) string {
+	return p.diskManager.GetEncryptor().Path(persistentDiskCryptName(diskSettings))
+}
+
+// openEncryptedPersistentDisk unlocks LUKS volume on the device, creating it
+// if necessary, and returns path of the unlocked device; devices of
+// unencrypted disks are returned as is
+func (p linux) openEncryptedPersistentDisk(diskSettings boshsettings.DiskSettings, devicePath string) (string, error) {
+	if !diskSettings.IsEncrypted() {
+		return devicePath, nil
+	}
+
+	key, err := p.persistentDiskEncryptionKey(diskSettings)
+	if err != nil {
+		return "", err
+	}
+
+	cryptPath, err := p.diskManager.GetEncryptor().Open(devicePath, persistentDiskCryptName(diskSettings), key)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Opening encrypted persistent disk")
 	}
 
-	return nil
+	return cryptPath, nil
 }
 
-func (p linux) SetupTmpDir() error {
-	systemTmpDir := "/tmp"
-	boshTmpDir := p.dirProvider.TmpDir()
-	boshRootTmpPath := path.Join(p.dirProvider.DataDir(), "root_tmp")
+func (p linux) persistentDiskEncryptionKey(diskSettings boshsettings.DiskSettings) (string, error) {
+	if diskSettings.EncryptionKey != "" {
+		return string(diskSettings.EncryptionKey), nil
+	}
 
-	err := p.fs.MkdirAll(boshTmpDir, tmpDirPermissions)
-	if err != nil {
-		return bosherr.WrapError(err, "Creating temp dir")
+	if p.options.DiskEncryptionKeyCommand == "" {
+		return "", bosherr.Errorf("Resolving encryption key reference '%s': no key command configured", diskSettings.EncryptionKeyRef)
 	}
 
-	err = os.Setenv("TMPDIR", boshTmpDir)
+	// Command runner logs output of commands unless it is written to a custom writer,
+	// so the key is read into a private buffer and never logged
+	var keyBuffer bytes.Buffer
+
+	_, _, _, err := p.cmdRunner.RunComplexCommand(boshsys.Command{
+		Name:   p.options.DiskEncryptionKeyCommand,
+		Args:   []string{diskSettings.EncryptionKeyRef},
+		Stdout: &keyBuffer,
+	})
 	if err != nil {
-		return bosherr.WrapError(err, "Setting TMPDIR")
+		return "", bosherr.WrapErrorf(err, "Resolving encryption key reference '%s'", diskSettings.EncryptionKeyRef)
 	}
 
-	err = p.changeTmpDirPermissions(systemTmpDir)
-	if err != nil {
-		return err
+	key := strings.TrimSpace(keyBuffer.String())
+	if key == "" {
+		return "", bosherr.Errorf("Resolving encryption key reference '%s': empty key", diskSettings.EncryptionKeyRef)
 	}
 
-	// /var/tmp is used for preserving temporary files between system reboots
-	_, _, _, err = p.cmdRunner.RunCommand("chmod", "0700", "/var/tmp")
+	return key, nil
+}
+
+func (p linux) closeEncryptedPersistentDisk(diskSettings boshsettings.DiskSettings) error {
+	if !diskSettings.IsEncrypted() {
+		return nil
+	}
+
+	err := p.diskManager.GetEncryptor().Close(persistentDiskCryptName(diskSettings))
 	if err != nil {
-		return bosherr.WrapError(err, "chmod /var/tmp")
+		return bosherr.WrapError(err, "Closing encrypted persistent disk")
 	}
 
-	if p.options.UseDefaultTmpDir {
-		return nil
+	return nil
+}
+
+// persistentDiskPartitionPath returns path of the first partition on the disk;
+// partitions of device mapper devices (e.g. dm-multipath maps) are named <map>-part1
+func (p linux) persistentDiskPartitionPath(realPath string) string {
+	if strings.Contains(realPath, "/dev/mapper/") {
+		return realPath + "-part1"
 	}
 
-	_, systemTmpDirIsMounted, err := p.IsMountPoint(systemTmpDir)
+	return realPath + "1"
+}
+
+func (p linux) persistentDiskLogicalVolumePath(diskSettings boshsettings.DiskSettings) string {
+	return p.diskManager.GetLogicalVolumeManager().Path(persistentDiskVolumeGroup(diskSettings), persistentDiskLogicalVolume)
+}
+
+// persistentDiskDataset returns ZFS dataset name since that is what shows up in /proc/mounts
+func (p linux) persistentDiskDataset(diskSettings boshsettings.DiskSettings) string {
+	return p.diskManager.GetZFSPoolManager().Dataset(persistentDiskVolumeGroup(diskSettings), persistentDiskLogicalVolume)
+}
+
+func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
+	p.logger.Debug(logTag, "Unmounting persistent disk %+v", diskSettings)
+
+	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
+	if timedOut {
+		return false, nil
+	}
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Checking for mount point %s", systemTmpDir)
+		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
-	if !systemTmpDirIsMounted {
-		// If it's not mounted on /tmp, blow it away
-		_, _, _, err = p.cmdRunner.RunCommand("truncate", "-s", "128M", boshRootTmpPath)
+	// Bind mounts keep persistent disk busy; they only exist for the disk mounted at store dir
+	if p.dirProvider.PersistentDiskMountPoint(diskSettings.MountPoint) == p.dirProvider.StoreDir() {
+		err = p.unmountBindMounts(p.options.PersistentDiskBindMountPaths)
 		if err != nil {
-			return bosherr.WrapError(err, "Truncating root tmp dir")
+			return false, err
 		}
+	}
 
-		_, _, _, err = p.cmdRunner.RunCommand("chmod", "0700", boshRootTmpPath)
+	if p.options.UseZFSForPersistentDisk {
+		didUnmount, err := p.diskManager.GetMounter().Unmount(p.persistentDiskDataset(diskSettings))
 		if err != nil {
-			return bosherr.WrapError(err, "Chmoding root tmp dir")
+			return false, err
 		}
 
-		_, _, _, err = p.cmdRunner.RunCommand("mke2fs", "-t", "ext4", "-m", "1", "-F", boshRootTmpPath)
+		// Pool is only imported while its dataset is mounted
+		if didUnmount {
+			err = p.diskManager.GetZFSPoolManager().Export(persistentDiskVolumeGroup(diskSettings))
+			if err != nil {
+				return didUnmount, bosherr.WrapError(err, "Exporting ZFS pool")
+			}
+		}
+
+		return didUnmount, nil
+	}
+
+	if p.options.UseLVMForPersistentDisk {
+		mountDevicePath := p.persistentDiskLogicalVolumePath(diskSettings)
+		if diskSettings.IsEncrypted() {
+			mountDevicePath = p.persistentDiskCryptPath(diskSettings)
+		}
+
+		didUnmount, err := p.diskManager.GetMounter().Unmount(mountDevicePath)
 		if err != nil {
-			return bosherr.WrapError(err, "Creating root tmp dir filesystem")
+			return false, err
 		}
 
-		err = p.diskManager.GetMounter().Mount(boshRootTmpPath, systemTmpDir, "-t", "ext4", "-o", "loop,noexec")
+		err = p.closeEncryptedPersistentDisk(diskSettings)
 		if err != nil {
-			return bosherr.WrapError(err, "Mounting root tmp dir over /tmp")
+			return didUnmount, err
 		}
 
-		// Change permissions for new mount point
-		err = p.changeTmpDirPermissions(systemTmpDir)
+		err = p.diskManager.GetLogicalVolumeManager().Deactivate(persistentDiskVolumeGroup(diskSettings))
 		if err != nil {
-			return err
+			return didUnmount, bosherr.WrapError(err, "Deactivating logical volume")
 		}
+
+		return didUnmount, nil
 	}
 
-	return nil
-}
+	if diskSettings.IsEncrypted() {
+		didUnmount, err := p.diskManager.GetMounter().Unmount(p.persistentDiskCryptPath(diskSettings))
+		if err != nil {
+			return false, err
+		}
 
-func (p linux) changeTmpDirPermissions(path string) error {
-	_, _, _, err := p.cmdRunner.RunCommand("chown", "root:vcap", path)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "chown %s", path)
+		return didUnmount, p.closeEncryptedPersistentDisk(diskSettings)
 	}
 
-	_, _, _, err = p.cmdRunner.RunCommand("chmod", "0770", path)
-	if err != nil {
-		return bosherr.WrapErrorf(err, "chmod %s", path)
+	if !p.options.UsePreformattedPersistentDisk {
+		realPath = p.persistentDiskPartitionPath(realPath)
 	}
 
-	return nil
+	return p.diskManager.GetMounter().Unmount(realPath)
 }
 
-func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountPoint string) error {
-	p.logger.Debug(logTag, "Mounting persistent disk %+v at %s", diskSetting, mountPoint)
+func (p linux) ResizePersistentDisk(diskSettings boshsettings.DiskSettings) error {
+	p.logger.Debug(logTag, "Resizing persistent disk %+v", diskSettings)
 
-	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSetting)
+	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
 	if err != nil {
 		return bosherr.WrapError(err, "Getting real device path")
 	}
 
-	devicePath, isMountPoint, err := p.IsMountPoint(mountPoint)
-	if err != nil {
-		return bosherr.WrapError(err, "Checking mount point")
-	}
-	p.logger.Info(logTag, "realPath = %s, devicePath = %s, isMountPoint = %s", realPath, devicePath, isMountPoint)
+	partitionPath := realPath
 
-	partitionPath := realPath + "1"
-	if strings.Contains(realPath, "/dev/mapper/") {
-		partitionPath = realPath + "-part1"
+	if p.options.UseLVMForPersistentDisk {
+		partitionPath = p.persistentDiskLogicalVolumePath(diskSettings)
+	} else if p.options.UseZFSForPersistentDisk {
+		partitionPath = p.persistentDiskDataset(diskSettings)
+	} else if !p.options.UsePreformattedPersistentDisk {
+		partitionPath = p.persistentDiskPartitionPath(realPath)
 	}
 
-	if isMountPoint {
-		if partitionPath == devicePath {
-			p.logger.Info(logTag, "device: %s is already mounted on %s, skipping mounting", devicePath, mountPoint)
-			return nil
-		}
-
-		mountPoint = p.dirProvider.StoreMigrationDir()
+	if diskSettings.IsEncrypted() {
+		partitionPath = p.persistentDiskCryptPath(diskSettings)
 	}
 
-	err = p.fs.MkdirAll(mountPoint, persistentDiskPermissions)
+	mountPoint, err := p.findMountPoint(partitionPath)
 	if err != nil {
-		return bosherr.WrapErrorf(err, "Creating directory %s", mountPoint)
+		return bosherr.WrapError(err, "Finding persistent disk mount point")
 	}
 
-	if !p.options.UsePreformattedPersistentDisk {
-		partitions := []boshdisk.Partition{
-			{Type: boshdisk.PartitionTypeLinux},
-		}
-
-		diskSize, err := p.diskManager.GetDiskUtil(realPath).GetBlockDeviceSize()
+	if mountPoint == "" {
+		return bosherr.Errorf("Persistent disk %s is not mounted", partitionPath)
+	}
 
-		p.logger.Debug(logTag, "Persistent disk size to be partitioned is: %d, and error is: %v", diskSize, err)
+	p.rescanBlockDevice(realPath)
 
-		if err != nil || diskSize < maxFdiskPartitionSize {
-			p.logger.Debug(logTag, "fdisk partitioner was chosen")
-			err = p.diskManager.GetPartitioner().Partition(realPath, partitions)
-		} else {
-			p.logger.Debug(logTag, "parted partitioner was chosen")
-			err = p.diskManager.GetPartedPartitioner().Partition(realPath, partitions)
+	if p.options.UseZFSForPersistentDisk {
+		// Datasets grow together with their pool so there is no filesystem to grow
+		err = p.diskManager.GetZFSPoolManager().Grow(persistentDiskVolumeGroup(diskSettings), []string{realPath})
+		if err != nil {
+			return bosherr.WrapError(err, "Growing ZFS pool")
 		}
 
+		return nil
+	}
+
+	if p.options.UseLVMForPersistentDisk {
+		err = p.diskManager.GetLogicalVolumeManager().Grow(
+			persistentDiskVolumeGroup(diskSettings),
+			persistentDiskLogicalVolume,
+			[]string{realPath},
+		)
 		if err != nil {
-			return bosherr.WrapError(err, "Partitioning disk")
+			return bosherr.WrapError(err, "Growing logical volume")
 		}
-
-		persistentDiskFS := diskSetting.FileSystemType
-		switch persistentDiskFS {
-		case boshdisk.FileSystemExt4, boshdisk.FileSystemXFS:
-		case boshdisk.FileSystemDefault:
-			persistentDiskFS = boshdisk.FileSystemExt4
-		default:
-			return bosherr.Error(fmt.Sprintf(`The filesystem type "%s" is not supported`, diskSetting.FileSystemType))
+	} else if !p.options.UsePreformattedPersistentDisk {
+		err = p.growPartition(realPath, 1)
+		if err != nil {
+			return err
 		}
+	}
 
-		err = p.diskManager.GetFormatter().Format(partitionPath, persistentDiskFS)
+	if diskSettings.IsEncrypted() {
+		key, err := p.persistentDiskEncryptionKey(diskSettings)
 		if err != nil {
-			return bosherr.WrapError(err, fmt.Sprintf("Formatting partition with %s", diskSetting.FileSystemType))
+			return err
 		}
 
-		realPath = partitionPath
+		err = p.diskManager.GetEncryptor().Resize(persistentDiskCryptName(diskSettings), key)
+		if err != nil {
+			return bosherr.WrapError(err, "Resizing encrypted persistent disk")
+		}
 	}
 
-	err = p.diskManager.GetMounter().Mount(realPath, mountPoint)
+	return p.growFilesystem(partitionPath, mountPoint)
+}
+
+func (p linux) findMountPoint(partitionPath string) (string, error) {
+	mounts, err := p.diskManager.GetMountsSearcher().SearchMounts()
 	if err != nil {
-		return bosherr.WrapError(err, "Mounting partition")
+		return "", err
 	}
 
-	return nil
-}
+	for _, mount := range mounts {
+		if mount.PartitionPath == partitionPath {
+			return mount.MountPoint, nil
+		}
+	}
 
-func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
-	p.logger.Debug(logTag, "Unmounting persistent disk %+v", diskSettings)
+	return "", nil
+}
 
-	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
-	if timedOut {
-		return false, nil
+// rescanBlockDevice asks the kernel to re-read size of a device that
+// was grown by the IaaS; not all drivers support it so failures are ignored
+func (p linux) rescanBlockDevice(devicePath string) {
+	rescanPath := path.Join("/sys/class/block", path.Base(devicePath), "device", "rescan")
+	if !p.fs.FileExists(rescanPath) {
+		return
 	}
+
+	err := p.fs.WriteFileString(rescanPath, "1")
 	if err != nil {
-		return false, bosherr.WrapError(err, "Getting real device path")
+		p.logger.Warn(logTag, "Failed to rescan block device %s: %s", devicePath, err.Error())
 	}
+}
 
-	if !p.options.UsePreformattedPersistentDisk {
-		if strings.Contains(realPath, "/dev/mapper/") {
-			realPath = realPath + "-part1"
-		} else {
-			realPath += "1"
+func (p linux) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
+	if p.options.StripeRawEphemeralDisks {
+		raidManager := p.diskManager.GetRAIDManager()
+		if raidManager.IsActive(ephemeralRAIDName) {
+			return raidManager.Path(ephemeralRAIDName)
 		}
 	}
 
-	return p.diskManager.GetMounter().Unmount(realPath)
-}
-
-func (p linux) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
 	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
 	if err != nil {
 		return ""
@@ -866,6 +2819,14 @@ func (p linux) IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings)
 		return false, bosherr.WrapErrorf(err, "Validating path: %s", diskSettings.Path)
 	}
 
+	if p.options.UseLVMForPersistentDisk {
+		return p.diskManager.GetLogicalVolumeManager().Exists(persistentDiskVolumeGroup(diskSettings), persistentDiskLogicalVolume)
+	}
+
+	if p.options.UseZFSForPersistentDisk {
+		return p.diskManager.GetZFSPoolManager().Exists(persistentDiskVolumeGroup(diskSettings), persistentDiskLogicalVolume)
+	}
+
 	stdout, stderr, _, _ := p.cmdRunner.RunCommand("sfdisk", "-d", realPath)
 	if strings.Contains(stderr, "unrecognized partition table type") {
 		return false, nil
@@ -882,34 +2843,134 @@ func (p linux) IsMountPoint(path string) (string, bool, error) {
 func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
 	p.logger.Debug(logTag, "Migrating persistent disk %v to %v", fromMountPoint, toMountPoint)
 
+	if fromMountPoint == p.dirProvider.StoreDir() {
+		err = p.unmountBindMounts(p.options.PersistentDiskBindMountPaths)
+		if err != nil {
+			return
+		}
+	}
+
 	err = p.diskManager.GetMounter().RemountAsReadonly(fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting persistent disk as readonly")
 		return
 	}
 
-	// Golang does not implement a file copy that would allow us to preserve dates...
-	// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
-	tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
-	_, _, _, err = p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+	if p.options.UseZFSForPersistentDisk {
+		err = p.snapshotPersistentDiskDataset(fromMountPoint)
+		if err != nil {
+			err = bosherr.WrapError(err, "Snapshotting old persistent disk")
+			return
+		}
+	}
+
+	p.diskMigrationTracker.Start(fromMountPoint, toMountPoint)
+
+	err = p.copyPersistentDiskData(fromMountPoint, toMountPoint)
+
+	p.diskMigrationTracker.Stop()
+
 	if err != nil {
 		err = bosherr.WrapError(err, "Copying files from old disk to new disk")
 		return
 	}
 
+	err = p.verifyPersistentDiskCopy(fromMountPoint, toMountPoint)
+	if err != nil {
+		// differences are returned as is so that callers can report them
+		if _, ok := err.(DiskMigrationVerificationError); !ok {
+			err = bosherr.WrapError(err, "Verifying files copied onto new disk")
+		}
+		return
+	}
+
+	fromDevicePath, _, err := p.IsMountPoint(fromMountPoint)
+	if err != nil {
+		err = bosherr.WrapError(err, "Checking old persistent disk mount point")
+		return
+	}
+
 	_, err = p.diskManager.GetMounter().Unmount(fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Unmounting old persistent disk")
 		return
 	}
 
-	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint)
+	// Old disk is not going to be mounted again so its LUKS volume is locked
+	cryptName := path.Base(fromDevicePath)
+	if path.Dir(fromDevicePath) == "/dev/mapper" && strings.HasPrefix(cryptName, persistentDiskCryptPrefix) {
+		err = p.diskManager.GetEncryptor().Close(cryptName)
+		if err != nil {
+			err = bosherr.WrapError(err, "Closing old encrypted persistent disk")
+			return
+		}
+	}
+
+	// New disk was mounted with options from its disk settings
+	mountOptions, err := p.diskMountOptionsOf(toMountPoint)
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting mount options of new persistent disk")
+		return
+	}
+
+	remountOptions := mountOptionArgs(mountOptions)
+	if p.options.UseZFSForPersistentDisk {
+		remountOptions = append([]string{"-t", "zfs"}, remountOptions...)
+	}
+
+	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint, remountOptions...)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting new disk on original mountpoint")
+		return
+	}
+
+	if fromMountPoint == p.dirProvider.StoreDir() {
+		err = p.setupBindMounts(fromMountPoint, p.options.PersistentDiskBindMountPaths)
+		if err != nil {
+			err = bosherr.WrapError(err, "Setting up persistent disk bind mounts")
+		}
 	}
 	return
 }
 
+// snapshotPersistentDiskDataset keeps a snapshot of the old disk's dataset so that
+// its data can still be recovered if the migrated data turns out to be incomplete
+func (p linux) snapshotPersistentDiskDataset(mountPoint string) error {
+	dataset, _, err := p.IsMountPoint(mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking old persistent disk mount point")
+	}
+
+	// Old disk is not a dataset if it was attached before ZFS was used
+	if strings.HasPrefix(dataset, "/") {
+		return nil
+	}
+
+	return p.diskManager.GetZFSPoolManager().Snapshot(dataset, persistentDiskMigrationSnapshot)
+}
+
+// copyPersistentDiskData uses rsync when available so that an interrupted
+// migration only copies files that have not been copied yet when it is retried
+func (p linux) copyPersistentDiskData(fromMountPoint, toMountPoint string) error {
+	if p.cmdRunner.CommandExists("rsync") {
+		_, _, _, err := p.cmdRunner.RunCommand(
+			"rsync", "-aHAX", "--numeric-ids", "--delete",
+			fromMountPoint+"/", toMountPoint+"/",
+		)
+		return err
+	}
+
+	// Golang does not implement a file copy that would allow us to preserve dates...
+	// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
+	tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
+	_, _, _, err := p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+	return err
+}
+
+func (p linux) GetPersistentDiskMigrationProgress() (DiskMigrationProgress, bool) {
+	return p.diskMigrationTracker.Progress()
+}
+
 func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (bool, error) {
 	p.logger.Debug(logTag, "Checking whether persistent disk %+v is mounted", diskSettings)
 	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
@@ -921,12 +2982,20 @@ func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
+	if diskSettings.IsEncrypted() {
+		return p.diskManager.GetMounter().IsMounted(p.persistentDiskCryptPath(diskSettings))
+	}
+
+	if p.options.UseLVMForPersistentDisk {
+		return p.diskManager.GetMounter().IsMounted(p.persistentDiskLogicalVolumePath(diskSettings))
+	}
+
+	if p.options.UseZFSForPersistentDisk {
+		return p.diskManager.GetMounter().IsMounted(p.persistentDiskDataset(diskSettings))
+	}
+
 	if !p.options.UsePreformattedPersistentDisk {
-		if strings.Contains(realPath, "/dev/mapper/") {
-			realPath = realPath + "-part1"
-		} else {
-			realPath += "1"
-		}
+		realPath = p.persistentDiskPartitionPath(realPath)
 	}
 
 	return p.diskManager.GetMounter().IsMounted(realPath)
@@ -984,6 +3053,27 @@ func (p linux) PrepareForNetworkingChange() error {
 	return nil
 }
 
+// PreviewNetworking returns changes that setting up networks other than
+// wireguard networks would make without applying them
+func (p linux) PreviewNetworking(networks boshsettings.Networks) (boshnet.NetworkChanges, error) {
+	overlaidNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if !networkSettings.IsWireGuard() {
+			overlaidNetworks[networkName] = networkSettings
+		}
+	}
+
+	return p.networkChangePreviewer.PreviewNetworking(overlaidNetworks)
+}
+
+func (p linux) AddAlias(networks boshsettings.Networks, networkName, address string) error {
+	return p.aliasManager.AddAlias(networks, networkName, address)
+}
+
+func (p linux) RemoveAlias(networks boshsettings.Networks, networkName, address string) error {
+	return p.aliasManager.RemoveAlias(networks, networkName, address)
+}
+
 func (p linux) DeleteARPEntryWithIP(ip string) error {
 	_, _, _, err := p.cmdRunner.RunCommand("arp", "-d", ip)
 	if err != nil {
@@ -998,6 +3088,11 @@ func (p linux) GetDefaultNetwork() (boshsettings.Network, error) {
 }
 
 func (p linux) calculateEphemeralDiskPartitionSizes(diskSizeInBytes uint64) (uint64, uint64, error) {
+	if p.options.UseZramSwap {
+		// Swap lives on zram device so whole disk is used for data
+		return uint64(0), diskSizeInBytes, nil
+	}
+
 	memStats, err := p.collector.GetMemStats()
 	if err != nil {
 		return uint64(0), uint64(0), bosherr.WrapError(err, "Getting mem stats")
@@ -1077,10 +3172,7 @@ func (p linux) createEphemeralPartitionsOnRootDevice() (string, string, error) {
 		return "", "", bosherr.WrapError(err, "Calculating partition sizes")
 	}
 
-	partitions := []boshdisk.Partition{
-		{SizeInBytes: swapSizeInBytes, Type: boshdisk.PartitionTypeSwap},
-		{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
-	}
+	partitions := p.ephemeralDiskPartitions(swapSizeInBytes, linuxSizeInBytes)
 
 	for _, partition := range partitions {
 		p.logger.Info(logTag, "Partitioning root device `%s': %s", rootDevicePath, partition)
@@ -1091,6 +3183,10 @@ func (p linux) createEphemeralPartitionsOnRootDevice() (string, string, error) {
 		return "", "", bosherr.WrapErrorf(err, "Partitioning root device `%s'", rootDevicePath)
 	}
 
+	if p.options.UseZramSwap {
+		return "", rootDevicePath + strconv.Itoa(rootDeviceNumber+1), nil
+	}
+
 	swapPartitionPath := rootDevicePath + strconv.Itoa(rootDeviceNumber+1)
 	dataPartitionPath := rootDevicePath + strconv.Itoa(rootDeviceNumber+2)
 	return swapPartitionPath, dataPartitionPath, nil
@@ -1110,10 +3206,7 @@ func (p linux) partitionEphemeralDisk(realPath string) (string, string, error) {
 		return "", "", bosherr.WrapError(err, "Calculating partition sizes")
 	}
 
-	partitions := []boshdisk.Partition{
-		{SizeInBytes: swapSizeInBytes, Type: boshdisk.PartitionTypeSwap},
-		{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
-	}
+	partitions := p.ephemeralDiskPartitions(swapSizeInBytes, linuxSizeInBytes)
 
 	p.logger.Info(logTag, "Partitioning ephemeral disk `%s' with %s", realPath, partitions)
 	err = p.diskManager.GetPartitioner().Partition(realPath, partitions)
@@ -1121,11 +3214,28 @@ func (p linux) partitionEphemeralDisk(realPath string) (string, string, error) {
 		return "", "", bosherr.WrapErrorf(err, "Partitioning ephemeral disk `%s'", realPath)
 	}
 
+	if p.options.UseZramSwap {
+		return "", realPath + "1", nil
+	}
+
 	swapPartitionPath := realPath + "1"
 	dataPartitionPath := realPath + "2"
 	return swapPartitionPath, dataPartitionPath, nil
 }
 
+// ephemeralDiskPartitions returns swap partition followed by data partition;
+// swap partition is omitted when swap is set up on zram device
+func (p linux) ephemeralDiskPartitions(swapSizeInBytes, linuxSizeInBytes uint64) []boshdisk.Partition {
+	if p.options.UseZramSwap {
+		return []boshdisk.Partition{{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux}}
+	}
+
+	return []boshdisk.Partition{
+		{SizeInBytes: swapSizeInBytes, Type: boshdisk.PartitionTypeSwap},
+		{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
+	}
+}
+
 func (p linux) RemoveDevTools(packageFileListPath string) error {
 	content, err := p.fs.ReadFileString(packageFileListPath)
 	if err != nil {
@@ -2,8 +2,10 @@ package platform_test
 
 import (
 	"errors"
+	"io/ioutil"
 	"os"
 	"path"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -11,10 +13,16 @@ import (
 
 	fakedpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver/fakes"
 	. "github.com/cloudfoundry/bosh-agent/platform"
+	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	fakecgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup/fakes"
 	fakedevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil/fakes"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+	fakefirewall "github.com/cloudfoundry/bosh-agent/platform/firewall/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
 	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
@@ -23,11 +31,74 @@ import (
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakeretry "github.com/cloudfoundry/bosh-utils/retrystrategy/fakes"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
 )
 
 var _ = Describe("LinuxPlatform", describeLinuxPlatform)
 
+var _ = Describe("LinuxOptions", func() {
+	Describe("device path resolution timeouts", func() {
+		It("defaults to 50s to wait for disks and 500ms to probe device names", func() {
+			diskWaitTimeout, probeTimeout := DevicePathResolutionTimeouts(LinuxOptions{})
+			Expect(diskWaitTimeout).To(Equal(50 * time.Second))
+			Expect(probeTimeout).To(Equal(500 * time.Millisecond))
+		})
+
+		It("uses configured timeouts", func() {
+			diskWaitTimeout, probeTimeout := DevicePathResolutionTimeouts(LinuxOptions{
+				DevicePathResolutionTimeoutInMilliseconds: 180000,
+				DevicePathProbeTimeoutInMilliseconds:      2000,
+			})
+			Expect(diskWaitTimeout).To(Equal(3 * time.Minute))
+			Expect(probeTimeout).To(Equal(2 * time.Second))
+		})
+	})
+
+	Describe("trust store", func() {
+		It("defaults to the distro specific trust store", func() {
+			trustStore, err := TrustStore(LinuxOptions{}, boshcert.UbuntuTrustStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trustStore).To(Equal(boshcert.UbuntuTrustStore))
+		})
+
+		It("uses known trust store of configured type", func() {
+			trustStore, err := TrustStore(LinuxOptions{TrustStoreType: "suse"}, boshcert.UbuntuTrustStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trustStore).To(Equal(boshcert.SUSETrustStore))
+		})
+
+		It("uses configured trust store path and update command", func() {
+			trustStore, err := TrustStore(LinuxOptions{
+				TrustStoreType:          "suse",
+				TrustStorePath:          "/fake-anchors",
+				TrustStoreUpdateCommand: []string{"/fake-update", "--fake-arg"},
+			}, boshcert.UbuntuTrustStore)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(trustStore).To(Equal(boshcert.TrustStore{
+				Name:          "Custom",
+				Path:          "/fake-anchors",
+				UpdateCommand: []string{"/fake-update", "--fake-arg"},
+			}))
+		})
+
+		It("returns an error and the distro specific trust store when trust store path has no update command", func() {
+			trustStore, err := TrustStore(LinuxOptions{TrustStorePath: "/fake-anchors"}, boshcert.UbuntuTrustStore)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Trust store '/fake-anchors' requires an update command"))
+			Expect(trustStore).To(Equal(boshcert.UbuntuTrustStore))
+		})
+
+		It("returns an error and the distro specific trust store when trust store type is unknown", func() {
+			trustStore, err := TrustStore(LinuxOptions{TrustStoreType: "fake-type"}, boshcert.UbuntuTrustStore)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Trust store type 'fake-type' is not supported"))
+			Expect(trustStore).To(Equal(boshcert.UbuntuTrustStore))
+		})
+	})
+})
+
 func describeLinuxPlatform() {
 	var (
 		collector                  *fakestats.FakeCollector
@@ -43,8 +114,13 @@ func describeLinuxPlatform() {
 		vitalsService              boshvitals.Service
 		netManager                 *fakenet.FakeManager
 		certManager                *fakecert.FakeManager
+		cgroupManager              *fakecgroup.FakeManager
+		firewallManager            *fakefirewall.FakeManager
 		monitRetryStrategy         *fakeretry.FakeRetryStrategy
 		fakeDefaultNetworkResolver *fakenet.FakeDefaultNetworkResolver
+		networkChangePreviewer     *fakenet.FakeNetworkChangePreviewer
+		wireGuardManager           *fakenet.FakeWireGuardManager
+		timeService                *fakeclock.FakeClock
 
 		state    *BootstrapState
 		stateErr error
@@ -67,9 +143,14 @@ func describeLinuxPlatform() {
 		vitalsService = boshvitals.NewService(collector, dirProvider)
 		netManager = &fakenet.FakeManager{}
 		certManager = new(fakecert.FakeManager)
+		cgroupManager = fakecgroup.NewFakeManager()
+		firewallManager = fakefirewall.NewFakeManager()
 		monitRetryStrategy = fakeretry.NewFakeRetryStrategy()
 		devicePathResolver = fakedpresolv.NewFakeDevicePathResolver()
 		fakeDefaultNetworkResolver = &fakenet.FakeDefaultNetworkResolver{}
+		networkChangePreviewer = &fakenet.FakeNetworkChangePreviewer{}
+		wireGuardManager = &fakenet.FakeWireGuardManager{}
+		timeService = fakeclock.NewFakeClock(time.Now())
 
 		state, stateErr = NewBootstrapState(fs, "/agent-state.json")
 		Expect(stateErr).NotTo(HaveOccurred())
@@ -99,10 +180,15 @@ func describeLinuxPlatform() {
 			cdutil,
 			diskManager,
 			netManager,
+			networkChangePreviewer,
+			wireGuardManager,
 			certManager,
+			cgroupManager,
+			firewallManager,
 			monitRetryStrategy,
 			devicePathResolver,
 			5*time.Millisecond,
+			timeService,
 			state,
 			options,
 			logger,
@@ -120,6 +206,61 @@ func describeLinuxPlatform() {
 		})
 	})
 
+	Describe("RunHooks", func() {
+		BeforeEach(func() {
+			fs.SetGlob("/fake-dir/bosh/hooks/pre-network/*", []string{
+				"/fake-dir/bosh/hooks/pre-network/20-second",
+				"/fake-dir/bosh/hooks/pre-network/10-first",
+			})
+		})
+
+		It("runs hooks of the phase in lexical order with the phase in the environment", func() {
+			cmdRunner.AddProcess("/fake-dir/bosh/hooks/pre-network/10-first", &fakesys.FakeProcess{})
+			cmdRunner.AddProcess("/fake-dir/bosh/hooks/pre-network/20-second", &fakesys.FakeProcess{})
+
+			err := platform.RunHooks("pre-network")
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmdRunner.RunComplexCommands).To(HaveLen(2))
+			Expect(cmdRunner.RunComplexCommands[0].Name).To(Equal("/fake-dir/bosh/hooks/pre-network/10-first"))
+			Expect(cmdRunner.RunComplexCommands[0].Env).To(Equal(map[string]string{"BOSH_HOOK_PHASE": "pre-network"}))
+			Expect(cmdRunner.RunComplexCommands[1].Name).To(Equal("/fake-dir/bosh/hooks/pre-network/20-second"))
+		})
+
+		It("does nothing when there are no hooks for the phase", func() {
+			err := platform.RunHooks("pre-monit")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+		})
+
+		It("stops and returns error if a hook fails", func() {
+			cmdRunner.AddProcess("/fake-dir/bosh/hooks/pre-network/10-first", &fakesys.FakeProcess{
+				WaitResult: boshsys.Result{Error: errors.New("fake-hook-err")},
+			})
+
+			err := platform.RunHooks("pre-network")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-hook-err"))
+			Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+		})
+
+		Context("when hook runs longer than the timeout", func() {
+			BeforeEach(func() {
+				options.HookTimeoutInSeconds = 1
+			})
+
+			It("terminates hook and returns error", func() {
+				process := &fakesys.FakeProcess{TerminatedNicelyCallBack: func(*fakesys.FakeProcess) {}}
+				cmdRunner.AddProcess("/fake-dir/bosh/hooks/pre-network/10-first", process)
+
+				err := platform.RunHooks("pre-network")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("timed out after 1s"))
+				Expect(process.TerminatedNicely).To(BeTrue())
+			})
+		})
+	})
+
 	Describe("CreateUser", func() {
 		It("creates user", func() {
 			expectedUseradd := []string{
@@ -226,6 +367,21 @@ bosh_foobar:...`
 				Expect(cmdRunner.RunCommands[2]).To(Equal([]string{"resize2fs", "-f", "/dev/sda1"}))
 			})
 
+			It("runs growpart and xfs_growfs if root filesystem is xfs", func() {
+				cmdRunner.AddCmdResult(
+					"readlink -f /dev/sda1",
+					fakesys.FakeCmdResult{Error: nil, Stdout: "/dev/sda1"},
+				)
+				fs.WriteFileString("/proc/mounts", "rootfs / rootfs rw 0 0\n/dev/sda1 / xfs rw,relatime 0 0\n")
+
+				err := platform.SetupRootDisk("/dev/sdb")
+
+				Expect(err).NotTo(HaveOccurred())
+				Expect(len(cmdRunner.RunCommands)).To(Equal(3))
+				Expect(cmdRunner.RunCommands[1]).To(Equal([]string{"growpart", "/dev/sda", "1"}))
+				Expect(cmdRunner.RunCommands[2]).To(Equal([]string{"xfs_growfs", "/"}))
+			})
+
 			It("returns error if it can't find the root device", func() {
 				cmdRunner.AddCmdResult(
 					"readlink -f /dev/sda1",
@@ -287,10 +443,15 @@ bosh_foobar:...`
 					cdutil,
 					diskManager,
 					netManager,
+					networkChangePreviewer,
+					wireGuardManager,
 					certManager,
+					cgroupManager,
+					firewallManager,
 					monitRetryStrategy,
 					devicePathResolver,
 					5*time.Millisecond,
+					timeService,
 					state,
 					options,
 					logger,
@@ -454,6 +615,274 @@ ff02::3 ip6-allhosts
 		})
 	})
 
+	Describe("SetupHostsEntries", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/etc/hosts", "127.0.0.1 localhost fake-hostname\n")
+		})
+
+		It("appends managed entries between markers", func() {
+			err := platform.SetupHostsEntries([]boshsettings.HostsEntry{
+				{IP: "10.0.0.5", Hostnames: []string{"fake-host", "fake-host.internal"}},
+				{IP: "fd00::5", Hostnames: []string{"fake-host-v6"}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`127.0.0.1 localhost fake-hostname
+# BEGIN bosh-agent managed entries
+10.0.0.5 fake-host fake-host.internal
+fd00::5 fake-host-v6
+# END bosh-agent managed entries
+`))
+		})
+
+		It("replaces previously managed entries and keeps other lines", func() {
+			fs.WriteFileString("/etc/hosts", `127.0.0.1 localhost fake-hostname
+# BEGIN bosh-agent managed entries
+10.0.0.5 old-host
+# END bosh-agent managed entries
+10.0.0.9 user-added-host
+`)
+
+			err := platform.SetupHostsEntries([]boshsettings.HostsEntry{{IP: "10.0.0.6", Hostnames: []string{"new-host"}}})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`127.0.0.1 localhost fake-hostname
+10.0.0.9 user-added-host
+# BEGIN bosh-agent managed entries
+10.0.0.6 new-host
+# END bosh-agent managed entries
+`))
+		})
+
+		It("removes managed block when there are no entries", func() {
+			fs.WriteFileString("/etc/hosts", "127.0.0.1 localhost\n# BEGIN bosh-agent managed entries\n10.0.0.5 old-host\n# END bosh-agent managed entries\n")
+
+			err := platform.SetupHostsEntries(nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/hosts")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("127.0.0.1 localhost\n"))
+		})
+
+		It("returns error for invalid IP", func() {
+			err := platform.SetupHostsEntries([]boshsettings.HostsEntry{{IP: "fake-ip", Hostnames: []string{"fake-host"}}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid IP 'fake-ip'"))
+		})
+
+		It("returns error when entry has no hostnames", func() {
+			err := platform.SetupHostsEntries([]boshsettings.HostsEntry{{IP: "10.0.0.5"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("at least one hostname"))
+		})
+	})
+
+	Describe("SetupTimezone", func() {
+		It("does nothing when timezone is not specified", func() {
+			err := platform.SetupTimezone("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/etc/localtime")).To(BeFalse())
+		})
+
+		It("links /etc/localtime to the zoneinfo file and writes /etc/timezone", func() {
+			fs.WriteFileString("/usr/share/zoneinfo/America/Los_Angeles", "fake-zone-info")
+
+			err := platform.SetupTimezone("America/Los_Angeles")
+			Expect(err).NotTo(HaveOccurred())
+
+			localtime := fs.GetFileTestStat("/etc/localtime")
+			Expect(localtime.FileType).To(Equal(fakesys.FakeFileTypeSymlink))
+			Expect(localtime.SymlinkTarget).To(Equal("/usr/share/zoneinfo/America/Los_Angeles"))
+
+			contents, err := fs.ReadFileString("/etc/timezone")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("America/Los_Angeles\n"))
+		})
+
+		It("returns error if the timezone is unknown", func() {
+			err := platform.SetupTimezone("Fake/Zone")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timezone 'Fake/Zone' is not available"))
+		})
+
+		It("returns error if symlinking /etc/localtime fails", func() {
+			fs.WriteFileString("/usr/share/zoneinfo/Etc/UTC", "fake-zone-info")
+			fs.SymlinkError = errors.New("fake-symlink-err")
+
+			err := platform.SetupTimezone("Etc/UTC")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-symlink-err"))
+		})
+	})
+
+	Describe("SetupLocale", func() {
+		BeforeEach(func() {
+			cmdRunner.AvailableCommands["locale-gen"] = true
+		})
+
+		It("does nothing when locale is not specified", func() {
+			err := platform.SetupLocale("")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fs.FileExists("/etc/default/locale")).To(BeFalse())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("writes locale configuration and generates the locale", func() {
+			err := platform.SetupLocale("en_US.UTF-8")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/default/locale")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("LANG=en_US.UTF-8\n"))
+
+			contents, err = fs.ReadFileString("/etc/locale.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("LANG=en_US.UTF-8\n"))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"locale-gen", "en_US.UTF-8"}}))
+		})
+
+		It("does not regenerate the locale if it is already configured and installed", func() {
+			fs.WriteFileString("/etc/default/locale", "LANG=en_US.UTF-8\n")
+			fs.WriteFileString("/etc/locale.conf", "LANG=en_US.UTF-8\n")
+			cmdRunner.AddCmdResult("locale -a", fakesys.FakeCmdResult{Stdout: "C\nC.utf8\nen_US.utf8\nPOSIX\n"})
+
+			err := platform.SetupLocale("en_US.UTF-8")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"locale", "-a"}}))
+		})
+
+		It("repairs locale config that went missing even though the other one is up to date", func() {
+			fs.WriteFileString("/etc/default/locale", "LANG=en_US.UTF-8\n")
+
+			err := platform.SetupLocale("en_US.UTF-8")
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/locale.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("LANG=en_US.UTF-8\n"))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"locale-gen", "en_US.UTF-8"}}))
+		})
+
+		It("generates the locale again when it is configured but not installed", func() {
+			fs.WriteFileString("/etc/default/locale", "LANG=en_US.UTF-8\n")
+			fs.WriteFileString("/etc/locale.conf", "LANG=en_US.UTF-8\n")
+			cmdRunner.AddCmdResult("locale -a", fakesys.FakeCmdResult{Stdout: "C\nC.utf8\nPOSIX\n"})
+
+			err := platform.SetupLocale("en_US.UTF-8")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"locale", "-a"}, {"locale-gen", "en_US.UTF-8"}}))
+		})
+
+		It("returns error if locale-gen fails", func() {
+			cmdRunner.AddCmdResult("locale-gen en_US.UTF-8", fakesys.FakeCmdResult{Error: errors.New("fake-locale-gen-err")})
+
+			err := platform.SetupLocale("en_US.UTF-8")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-locale-gen-err"))
+		})
+	})
+
+	Describe("SetupProxy", func() {
+		var proxy boshsettings.Proxy
+
+		BeforeEach(func() {
+			cmdRunner.AvailableCommands["systemctl"] = true
+
+			proxy = boshsettings.Proxy{
+				HTTPProxy:  "http://proxy.internal:3128",
+				HTTPSProxy: "http://proxy.internal:3129",
+				NoProxy:    []string{"localhost", "10.0.0.6"},
+			}
+		})
+
+		AfterEach(func() {
+			for _, name := range []string{"http_proxy", "HTTP_PROXY", "https_proxy", "HTTPS_PROXY", "no_proxy", "NO_PROXY"} {
+				os.Unsetenv(name)
+			}
+		})
+
+		It("renders proxy variables into /etc/environment keeping existing variables", func() {
+			fs.WriteFileString("/etc/environment", "PATH=\"/usr/bin:/bin\"\n")
+
+			err := platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/environment")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`PATH="/usr/bin:/bin"
+# BEGIN bosh-agent managed entries
+http_proxy=http://proxy.internal:3128
+HTTP_PROXY=http://proxy.internal:3128
+https_proxy=http://proxy.internal:3129
+HTTPS_PROXY=http://proxy.internal:3129
+no_proxy=localhost,10.0.0.6
+NO_PROXY=localhost,10.0.0.6
+# END bosh-agent managed entries
+`))
+		})
+
+		It("renders default environment of systemd services and reloads systemd", func() {
+			err := platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/systemd/system.conf.d/bosh-proxy.conf")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal(`# Generated by bosh-agent
+[Manager]
+DefaultEnvironment="http_proxy=http://proxy.internal:3128" "HTTP_PROXY=http://proxy.internal:3128" "https_proxy=http://proxy.internal:3129" "HTTPS_PROXY=http://proxy.internal:3129" "no_proxy=localhost,10.0.0.6" "NO_PROXY=localhost,10.0.0.6"
+`))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "daemon-reload"}}))
+		})
+
+		It("does not reload systemd when proxy did not change", func() {
+			err := platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(HaveLen(1))
+		})
+
+		It("sets proxy variables in agent environment", func() {
+			err := platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(os.Getenv("https_proxy")).To(Equal("http://proxy.internal:3129"))
+			Expect(os.Getenv("NO_PROXY")).To(Equal("localhost,10.0.0.6"))
+		})
+
+		It("removes proxy when it is not configured", func() {
+			err := platform.SetupProxy(proxy)
+			Expect(err).NotTo(HaveOccurred())
+
+			err = platform.SetupProxy(boshsettings.Proxy{})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/environment")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).ToNot(ContainSubstring("proxy"))
+
+			Expect(fs.FileExists("/etc/systemd/system.conf.d/bosh-proxy.conf")).To(BeFalse())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "daemon-reload"}, {"systemctl", "daemon-reload"}}))
+			Expect(os.Getenv("http_proxy")).To(BeEmpty())
+		})
+
+		It("returns error if reloading systemd fails", func() {
+			cmdRunner.AddCmdResult("systemctl daemon-reload", fakesys.FakeCmdResult{Error: errors.New("fake-systemctl-err")})
+
+			err := platform.SetupProxy(proxy)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-systemctl-err"))
+		})
+	})
+
 	Describe("SetupLogrotate", func() {
 		const expectedEtcLogrotate = `# Generated by bosh-agent
 
@@ -510,6 +939,24 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 			mounter = diskManager.FakeMounter
 		})
 
+		Context("when StripeRawEphemeralDisks is set and ephemeral disk path is the array", func() {
+			BeforeEach(func() {
+				options.StripeRawEphemeralDisks = true
+			})
+
+			It("formats and mounts the whole array as data dir without swap", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/md/bosh-ephemeral", boshsettings.DiskSettings{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/md/bosh-ephemeral"}))
+				Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt4}))
+				Expect(mounter.SwapOnPartitionPaths).To(BeEmpty())
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/md/bosh-ephemeral"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/data"}))
+			})
+		})
+
 		itSetsUpEphemeralDisk := func(act func() error) {
 			It("sets up ephemeral disk with path", func() {
 				err := act()
@@ -532,7 +979,7 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 		}
 
 		Context("when ephemeral disk path is provided", func() {
-			act := func() error { return platform.SetupEphemeralDiskWithPath("/dev/xvda") }
+			act := func() error { return platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{}) }
 
 			itSetsUpEphemeralDisk(act)
 
@@ -593,6 +1040,46 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 				Expect(formatter.FormatFsTypes[1]).To(Equal(boshdisk.FileSystemExt4))
 			})
 
+			It("formats data partition with requested filesystem type and mkfs options", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{
+					FileSystemType: boshdisk.FileSystemXFS,
+					MkfsOptions:    []string{"-K"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemSwap, boshdisk.FileSystemXFS}))
+				Expect(formatter.FormatMkfsOptions[1]).To(Equal([]string{"-K"}))
+			})
+
+			It("formats data partition with mkfs tuning from disk settings", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{
+					MkfsTuning: boshdisk.MkfsTuning{BytesPerInode: 8192},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(formatter.FormatMkfsTunings).To(Equal([]boshdisk.MkfsTuning{{}, {BytesPerInode: 8192}}))
+			})
+
+			It("mounts data partition with mount options from disk settings", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{
+					MountOptions: []string{"noatime", "discard"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+
+				mounter := diskManager.FakeMounter
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/xvda2"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "noatime,discard"}}))
+			})
+
+			It("returns error if requested filesystem type is not supported", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{
+					FileSystemType: boshdisk.FileSystemType("btrfs"),
+				})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring(`The filesystem type "btrfs" is not supported`))
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+			})
+
 			It("mounts swap and data partitions", func() {
 				err := act()
 				Expect(err).NotTo(HaveOccurred())
@@ -637,8 +1124,72 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 			})
 		})
 
+		Context("when UseZramSwap is set", func() {
+			BeforeEach(func() {
+				options.UseZramSwap = true
+				partitioner.GetDeviceSizeInBytesSizes["/dev/xvda"] = 1024 * 1024 * 1024
+				collector.MemStats.Total = 512 * 1024 * 1024
+				fs.WriteFileString("/sys/block/zram0/disksize", "0\n")
+			})
+
+			It("uses whole ephemeral disk for data", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(partitioner.PartitionPartitions).To(Equal([]boshdisk.Partition{
+					{SizeInBytes: 1024 * 1024 * 1024, Type: boshdisk.PartitionTypeLinux},
+				}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/xvda1"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/data"}))
+			})
+
+			It("sets up swap on zram device sized to half of memory", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"modprobe", "zram", "num_devices=1"}))
+				Expect(fs.ReadFileString("/sys/block/zram0/disksize")).To(Equal("268435456"))
+
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/xvda1", "/dev/zram0"}))
+				Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt4, boshdisk.FileSystemSwap}))
+				Expect(mounter.SwapOnPartitionPaths).To(Equal([]string{"/dev/zram0"}))
+			})
+
+			Context("when ZramSwapSize is set", func() {
+				BeforeEach(func() {
+					options.ZramSwapSize = "64M"
+				})
+
+				It("sizes zram device accordingly", func() {
+					err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fs.ReadFileString("/sys/block/zram0/disksize")).To(Equal("64M"))
+				})
+			})
+
+			It("does not resize zram device that is already initialized", func() {
+				fs.WriteFileString("/sys/block/zram0/disksize", "1048576\n")
+
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fs.ReadFileString("/sys/block/zram0/disksize")).To(Equal("1048576\n"))
+				Expect(mounter.SwapOnPartitionPaths).To(Equal([]string{"/dev/zram0"}))
+			})
+
+			It("returns error when loading zram module fails", func() {
+				cmdRunner.AddCmdResult("modprobe zram num_devices=1", fakesys.FakeCmdResult{Error: errors.New("fake-modprobe-err")})
+
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Loading zram module"))
+				Expect(mounter.SwapOnPartitionPaths).To(BeEmpty())
+			})
+		})
+
 		Context("when ephemeral disk path is not provided", func() {
-			act := func() error { return platform.SetupEphemeralDiskWithPath("") }
+			act := func() error { return platform.SetupEphemeralDiskWithPath("", boshsettings.DiskSettings{}) }
 
 			Context("when agent should partition ephemeral disk on root disk", func() {
 				BeforeEach(func() {
@@ -992,7 +1543,7 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 			})
 
 			It("does nothing", func() {
-				err := platform.SetupEphemeralDiskWithPath("/dev/xvda")
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", boshsettings.DiskSettings{})
 
 				Expect(err).ToNot(HaveOccurred())
 				Expect(partitioner.PartitionCalled).To(BeFalse())
@@ -1003,18 +1554,53 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 	})
 
 	Describe("SetupRawEphemeralDisks", func() {
-		It("labels the raw ephemeral paths for unpartitioned disks", func() {
-			result := fakesys.FakeCmdResult{
-				Error:      nil,
-				ExitStatus: 0,
-				Stderr:     "",
-				Stdout: `Model: Xen Virtual Block Device (xvd)
-Disk /dev/xvdb: 40.3GB
-Sector size (logical/physical): 512B/512B
-Partition Table: loop
+		Context("when StripeRawEphemeralDisks is set to true", func() {
+			BeforeEach(func() {
+				options.StripeRawEphemeralDisks = true
+			})
 
-Number  Start  End     Size    File system  Flags
-1      0.00B  40.3GB  40.3GB  ext3
+			It("stripes raw ephemeral disks into an array without partitioning them", func() {
+				devicePathResolver.GetRealDevicePathStub = func(diskSettings boshsettings.DiskSettings) (string, bool, error) {
+					return diskSettings.Path, false, nil
+				}
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}, {Path: "/dev/nvme2n1"}})
+				Expect(err).NotTo(HaveOccurred())
+
+				raidManager := diskManager.FakeRAIDManager
+				Expect(raidManager.AssembleName).To(Equal("bosh-ephemeral"))
+				Expect(raidManager.AssembleDevicePaths).To(Equal([]string{"/dev/nvme1n1", "/dev/nvme2n1"}))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("returns error if assembling array fails", func() {
+				diskManager.FakeRAIDManager.AssembleErr = errors.New("fake-assemble-err")
+
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}, {Path: "/dev/nvme2n1"}})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-assemble-err"))
+			})
+
+			It("partitions a single raw ephemeral disk as usual", func() {
+				err := platform.SetupRawEphemeralDisks([]boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(diskManager.FakeRAIDManager.AssembleName).To(BeEmpty())
+				Expect(cmdRunner.RunCommands).ToNot(BeEmpty())
+			})
+		})
+
+		It("labels the raw ephemeral paths for unpartitioned disks", func() {
+			result := fakesys.FakeCmdResult{
+				Error:      nil,
+				ExitStatus: 0,
+				Stderr:     "",
+				Stdout: `Model: Xen Virtual Block Device (xvd)
+Disk /dev/xvdb: 40.3GB
+Sector size (logical/physical): 512B/512B
+Partition Table: loop
+
+Number  Start  End     Size    File system  Flags
+1      0.00B  40.3GB  40.3GB  ext3
 `,
 			}
 
@@ -1397,6 +1983,97 @@ Number  Start   End     Size    File system  Name             Flags
 				ItDoesNotTryToUseLoopDevice()
 			})
 
+			Context("when UseTmpfsForTmpDirs option is set to true", func() {
+				BeforeEach(func() {
+					options.UseTmpfsForTmpDirs = true
+				})
+
+				Context("when /tmp and /var/tmp are not mount points", func() {
+					BeforeEach(func() {
+						mounter.IsMountPointResult = false
+					})
+
+					It("mounts size limited tmpfs over /tmp and /var/tmp", func() {
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"tmpfs", "tmpfs"}))
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/tmp", "/var/tmp"}))
+						Expect(mounter.MountMountOptions).To(Equal([][]string{
+							{"-t", "tmpfs", "-o", "size=128m,noexec,nosuid,nodev"},
+							{"-t", "tmpfs", "-o", "size=128m,noexec,nosuid,nodev"},
+						}))
+					})
+
+					Context("when TmpfsTmpDirSize option is set", func() {
+						BeforeEach(func() {
+							options.TmpfsTmpDirSize = "1g"
+						})
+
+						It("uses configured tmpfs size", func() {
+							err := act()
+							Expect(err).NotTo(HaveOccurred())
+
+							Expect(mounter.MountMountOptions[0]).To(Equal([]string{"-t", "tmpfs", "-o", "size=1g,noexec,nosuid,nodev"}))
+						})
+					})
+
+					It("changes permissions on /tmp and /var/tmp again because they are new mounts", func() {
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(cmdRunner.RunCommands[3:]).To(Equal([][]string{
+							{"chown", "root:vcap", "/tmp"},
+							{"chmod", "0770", "/tmp"},
+							{"chmod", "0700", "/var/tmp"},
+						}))
+					})
+
+					It("does not create root tmp filesystem in data dir", func() {
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+
+						for _, cmd := range cmdRunner.RunCommands {
+							Expect(cmd[0]).ToNot(Equal("truncate"))
+							Expect(cmd[0]).ToNot(Equal("mke2fs"))
+						}
+					})
+
+					It("returns error if mounting tmpfs fails", func() {
+						mounter.MountErr = errors.New("fake-mount-error")
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-mount-error"))
+					})
+				})
+
+				Context("when /tmp and /var/tmp are mount points", func() {
+					BeforeEach(func() {
+						mounter.IsMountPointResult = true
+					})
+
+					It("does not mount tmpfs", func() {
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(mounter.MountCalled).To(BeFalse())
+					})
+				})
+
+				Context("when it cannot be determined if /tmp is a mount point", func() {
+					BeforeEach(func() {
+						mounter.IsMountPointErr = errors.New("fake-is-mount-point-error")
+					})
+
+					It("returns error", func() {
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-is-mount-point-error"))
+					})
+				})
+			})
+
 			Context("when /tmp cannot be determined if it is a mount point", func() {
 				BeforeEach(func() {
 					mounter.IsMountPointErr = errors.New("fake-is-mount-point-error")
@@ -1426,197 +2103,1169 @@ Number  Start   End     Size    File system  Name             Flags
 		})
 	})
 
-	Describe("MountPersistentDisk", func() {
-		act := func() error {
-			return platform.MountPersistentDisk(
-				boshsettings.DiskSettings{Path: "fake-volume-id"},
-				"/mnt/point",
-			)
-		}
-
-		var (
-			partitioner *fakedisk.FakePartitioner
-			formatter   *fakedisk.FakeFormatter
-			mounter     *fakedisk.FakeMounter
-		)
+	Describe("SetupBindMounts", func() {
+		var mounter *fakedisk.FakeMounter
 		BeforeEach(func() {
-			partitioner = diskManager.FakePartitioner
-			formatter = diskManager.FakeFormatter
 			mounter = diskManager.FakeMounter
+			options.EphemeralDiskBindMountPaths = []string{"/var/log", "/home"}
 		})
 
-		Context("when the size of the disk is larger than or equals 2 Terrabytes", func() {
-
+		Context("when no paths are configured", func() {
 			BeforeEach(func() {
-				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(2199023255552)
+				options.EphemeralDiskBindMountPaths = nil
 			})
 
-			It("uses parted partitioner", func() {
-				err := act()
-				Expect(err).ToNot(HaveOccurred())
-
-				Expect(diskManager.PartedPartitionerCalled).To(BeTrue())
-				Expect(diskManager.PartitionerCalled).To(BeFalse())
+			It("does nothing", func() {
+				err := platform.SetupBindMounts()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
 			})
 		})
 
-		Context("when the size of the disk is less than 2 Terabytes", func() {
+		It("relocates contents of paths onto ephemeral disk and bind-mounts them", func() {
+			fs.MkdirAll("/var/log", os.FileMode(0755))
 
-			BeforeEach(func() {
-				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(2199023255551)
-			})
+			err := platform.SetupBindMounts()
+			Expect(err).NotTo(HaveOccurred())
 
-			It("uses fdisk partitioner", func() {
-				err := act()
-				Expect(err).ToNot(HaveOccurred())
+			Expect(fs.GetFileTestStat("/fake-dir/data/bind_mounts/var_log").FileType).To(Equal(fakesys.FakeFileTypeDir))
+			Expect(fs.GetFileTestStat("/fake-dir/data/bind_mounts/home").FileType).To(Equal(fakesys.FakeFileTypeDir))
+			Expect(fs.FileExists("/fake-dir/data/bind_mounts/var_log.tmp")).To(BeFalse())
 
-				Expect(diskManager.PartitionerCalled).To(BeTrue())
-				Expect(diskManager.PartedPartitionerCalled).To(BeFalse())
-			})
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"cp", "-a", "/var/log/.", "/fake-dir/data/bind_mounts/var_log.tmp"},
+				{"mount", "--bind", "/fake-dir/data/bind_mounts/var_log", "/var/log"},
+				{"mount", "--bind", "/fake-dir/data/bind_mounts/home", "/home"},
+			}))
 		})
 
-		Context("when the lsblk command returns an error", func() {
-
-			BeforeEach(func() {
-				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(3199023255556)
-				diskManager.FakeDiskUtil.GetBlockDeviceSizeError = errors.New("Some error")
-			})
+		It("does not copy contents again when paths were already relocated", func() {
+			fs.MkdirAll("/var/log", os.FileMode(0755))
+			fs.MkdirAll("/fake-dir/data/bind_mounts/var_log", os.FileMode(0755))
+			fs.MkdirAll("/fake-dir/data/bind_mounts/home", os.FileMode(0755))
 
-			It("uses fdisk partitioner", func() {
-				err := act()
-				Expect(err).ToNot(HaveOccurred())
+			err := platform.SetupBindMounts()
+			Expect(err).NotTo(HaveOccurred())
 
-				Expect(diskManager.PartitionerCalled).To(BeTrue())
-				Expect(diskManager.PartedPartitionerCalled).To(BeFalse())
-			})
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"mount", "--bind", "/fake-dir/data/bind_mounts/var_log", "/var/log"},
+				{"mount", "--bind", "/fake-dir/data/bind_mounts/home", "/home"},
+			}))
 		})
 
-		Context("when device real path contains /dev/mapper/ and is successfully resolved", func() {
-			BeforeEach(func() {
-				devicePathResolver.RealDevicePath = "/dev/mapper/fake-real-device-path"
-			})
+		It("skips paths that are already mounted", func() {
+			mounter.IsMountPointResult = true
 
-			Context("when store directory is already mounted", func() {
-				BeforeEach(func() {
-					mounter.IsMountPointResult = true
-				})
+			err := platform.SetupBindMounts()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
 
-				Context("when mounting the same device", func() {
-					BeforeEach(func() {
-						mounter.IsMountPointPartitionPath = "/dev/mapper/fake-real-device-path-part1"
-					})
+		It("returns error and does not bind-mount path if copying its contents fails", func() {
+			fs.MkdirAll("/var/log", os.FileMode(0755))
+			cmdRunner.AddCmdResult("cp -a /var/log/. /fake-dir/data/bind_mounts/var_log.tmp", fakesys.FakeCmdResult{Error: errors.New("fake-cp-err")})
 
-					It("skips mounting", func() {
-						err := act()
-						Expect(err).ToNot(HaveOccurred())
-						Expect(mounter.MountCalled).To(BeFalse())
-					})
-				})
+			err := platform.SetupBindMounts()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-cp-err"))
+			Expect(fs.FileExists("/fake-dir/data/bind_mounts/var_log")).To(BeFalse())
+			Expect(cmdRunner.RunCommands).To(HaveLen(1))
+		})
 
-				Context("when mounting a different device", func() {
-					BeforeEach(func() {
-						mounter.IsMountPointPartitionPath = "/dev/mapper/another-device"
-					})
+		It("returns error if bind-mounting fails", func() {
+			cmdRunner.AddCmdResult("mount --bind /fake-dir/data/bind_mounts/var_log /var/log", fakesys.FakeCmdResult{Error: errors.New("fake-mount-err")})
 
-					It("mounts the store migration directory", func() {
-						err := act()
-						Expect(err).ToNot(HaveOccurred())
-						Expect(fs.GetFileTestStat("/fake-dir/store_migration_target").FileType).To(Equal(fakesys.FakeFileTypeDir))
-						Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
-						Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store_migration_target"}))
-						Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
-					})
-				})
-			})
+			err := platform.SetupBindMounts()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+		})
+	})
 
-			Context("when failing to determine if store directory is mounted", func() {
-				BeforeEach(func() {
-					mounter.IsMountPointErr = errors.New("fake-is-mount-point-err")
-				})
+	Describe("SetupReadOnlyRoot", func() {
+		var mounter *fakedisk.FakeMounter
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+			options.ReadOnlyRootWritablePaths = []string{"/var/lib", "/home"}
+		})
 
-				It("returns an error", func() {
-					err := act()
-					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("fake-is-mount-point-err"))
-					Expect(mounter.MountCalled).To(BeFalse())
-				})
+		Context("when root filesystem is writable", func() {
+			BeforeEach(func() {
+				fs.WriteFileString("/proc/mounts", "rootfs / rootfs rw 0 0\n/dev/sda1 / ext4 rw,relatime 0 0\n")
 			})
 
-			Context("when UsePreformattedPersistentDisk set to false", func() {
-				It("creates the mount directory with the correct permissions", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
+			It("does nothing when read-only root is not enabled", func() {
+				err := platform.SetupReadOnlyRoot()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
 
-					mountPoint := fs.GetFileTestStat("/mnt/point")
-					Expect(mountPoint.FileType).To(Equal(fakesys.FakeFileTypeDir))
-					Expect(mountPoint.FileMode).To(Equal(os.FileMode(0700)))
+			Context("when read-only root is enabled", func() {
+				BeforeEach(func() {
+					options.ReadOnlyRootFilesystem = true
 				})
 
-				It("returns error when creating mount directory fails", func() {
-					fs.MkdirAllError = errors.New("fake-mkdir-all-err")
+				It("overlays writable paths and remounts root read-only", func() {
+					err := platform.SetupReadOnlyRoot()
+					Expect(err).NotTo(HaveOccurred())
 
-					err := act()
-					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("fake-mkdir-all-err"))
+					Expect(fs.GetFileTestStat("/fake-dir/data/root_overlay/var_lib/upper").FileType).To(Equal(fakesys.FakeFileTypeDir))
+					Expect(fs.GetFileTestStat("/fake-dir/data/root_overlay/var_lib/work").FileType).To(Equal(fakesys.FakeFileTypeDir))
+
+					Expect(cmdRunner.RunCommands).To(Equal([][]string{
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/var/lib,upperdir=/fake-dir/data/root_overlay/var_lib/upper,workdir=/fake-dir/data/root_overlay/var_lib/work", "/var/lib"},
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/home,upperdir=/fake-dir/data/root_overlay/home/upper,workdir=/fake-dir/data/root_overlay/home/work", "/home"},
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/etc,upperdir=/fake-dir/data/root_overlay/etc/upper,workdir=/fake-dir/data/root_overlay/etc/work", "/etc"},
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/fake-dir/bosh,upperdir=/fake-dir/data/root_overlay/fake-dir_bosh/upper,workdir=/fake-dir/data/root_overlay/fake-dir_bosh/work", "/fake-dir/bosh"},
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/fake-dir/jobs,upperdir=/fake-dir/data/root_overlay/fake-dir_jobs/upper,workdir=/fake-dir/data/root_overlay/fake-dir_jobs/work", "/fake-dir/jobs"},
+						{"mount", "-t", "overlay", "overlay", "-o", "lowerdir=/fake-dir/monit,upperdir=/fake-dir/data/root_overlay/fake-dir_monit/upper,workdir=/fake-dir/data/root_overlay/fake-dir_monit/work", "/fake-dir/monit"},
+						{"mount", "-o", "remount,ro", "/"},
+					}))
 				})
 
-				It("partitions the disk", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
+				It("skips paths that are already mounted", func() {
+					mounter.IsMountPointResult = true
 
-					partitions := []boshdisk.Partition{{Type: boshdisk.PartitionTypeLinux}}
-					Expect(partitioner.PartitionDevicePath).To(Equal("/dev/mapper/fake-real-device-path"))
-					Expect(partitioner.PartitionPartitions).To(Equal(partitions))
+					err := platform.SetupReadOnlyRoot()
+					Expect(err).NotTo(HaveOccurred())
+					Expect(cmdRunner.RunCommands).To(Equal([][]string{{"mount", "-o", "remount,ro", "/"}}))
 				})
 
-				It("formats the disk", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
-					Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
-					Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt4}))
-				})
+				It("returns error if mounting overlay fails", func() {
+					cmdRunner.AddCmdResult(
+						"mount -t overlay overlay -o lowerdir=/var/lib,upperdir=/fake-dir/data/root_overlay/var_lib/upper,workdir=/fake-dir/data/root_overlay/var_lib/work /var/lib",
+						fakesys.FakeCmdResult{Error: errors.New("fake-mount-err")},
+					)
 
-				It("mounts the disk", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
-					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
-					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
-					Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
+					err := platform.SetupReadOnlyRoot()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
 				})
 			})
 		})
 
-		Context("when device path is successfully resolved", func() {
+		Context("when root filesystem is already read-only", func() {
 			BeforeEach(func() {
-				devicePathResolver.RealDevicePath = "fake-real-device-path"
+				fs.WriteFileString("/proc/mounts", "/dev/sda1 / ext4 ro,relatime 0 0\n")
 			})
 
-			Context("when UsePreformattedPersistentDisk set to false", func() {
-				It("creates the mount directory with the correct permissions", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
+			It("overlays writable paths without remounting root", func() {
+				err := platform.SetupReadOnlyRoot()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(HaveLen(6))
+				Expect(cmdRunner.RunCommands[1][len(cmdRunner.RunCommands[1])-1]).To(Equal("/home"))
+				Expect(cmdRunner.RunCommands[5][len(cmdRunner.RunCommands[5])-1]).To(Equal("/fake-dir/monit"))
+			})
+		})
 
-					mountPoint := fs.GetFileTestStat("/mnt/point")
-					Expect(mountPoint.FileType).To(Equal(fakesys.FakeFileTypeDir))
-					Expect(mountPoint.FileMode).To(Equal(os.FileMode(0700)))
-				})
+		It("returns error if /proc/mounts cannot be read", func() {
+			fs.WriteFileString("/proc/mounts", "")
+			fs.RegisterReadFileError("/proc/mounts", errors.New("fake-read-err"))
 
-				It("returns error when creating mount directory fails", func() {
-					fs.MkdirAllError = errors.New("fake-mkdir-all-err")
+			err := platform.SetupReadOnlyRoot()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading /proc/mounts"))
+		})
+	})
 
-					err := act()
-					Expect(err).To(HaveOccurred())
-					Expect(err.Error()).To(ContainSubstring("fake-mkdir-all-err"))
-				})
+	Describe("SetupFilesystemTrimming", func() {
+		It("does nothing when trimming interval is not configured", func() {
+			err := platform.SetupFilesystemTrimming()
+			Expect(err).NotTo(HaveOccurred())
 
-				It("partitions the disk", func() {
-					err := act()
-					Expect(err).ToNot(HaveOccurred())
+			Consistently(func() [][]string { return cmdRunner.RunCommands }, 100*time.Millisecond).Should(BeEmpty())
+		})
 
-					partitions := []boshdisk.Partition{{Type: boshdisk.PartitionTypeLinux}}
-					Expect(partitioner.PartitionDevicePath).To(Equal("fake-real-device-path"))
-					Expect(partitioner.PartitionPartitions).To(Equal(partitions))
-				})
+		Context("when trimming interval is configured", func() {
+			BeforeEach(func() {
+				options.FilesystemTrimIntervalInSeconds = 1
+				cmdRunner.CommandExistsValue = true
+			})
+
+			AfterEach(func() {
+				StopFilesystemTrimming(platform)
+			})
+
+			It("periodically trims ephemeral and persistent filesystems", func() {
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/sda1", MountPoint: "/"},
+					{PartitionPath: "/dev/sdb2", MountPoint: "/var/vcap/data"},
+					{PartitionPath: "tmpfs", MountPoint: "/var/vcap/data/sys/run"},
+					{PartitionPath: "/dev/sdb2", MountPoint: "/home"},
+					{PartitionPath: "/dev/sdc1", MountPoint: "/var/vcap/store"},
+				}
+
+				err := platform.SetupFilesystemTrimming()
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(timeService.WatcherCount).Should(Equal(1))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+
+				timeService.Increment(1 * time.Second)
+
+				// Next trim is scheduled after trimming finished
+				Eventually(timeService.WatcherCount).Should(Equal(1))
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"fstrim", "-v", "/var/vcap/data"},
+					{"fstrim", "-v", "/var/vcap/store"},
+				}))
+			})
+
+			It("stops trimming when stopped", func() {
+				err := platform.SetupFilesystemTrimming()
+				Expect(err).NotTo(HaveOccurred())
+
+				Eventually(timeService.WatcherCount).Should(Equal(1))
+
+				StopFilesystemTrimming(platform)
+				Expect(timeService.WatcherCount()).To(Equal(0))
+			})
+
+			It("returns error when fstrim is not installed", func() {
+				cmdRunner.CommandExistsValue = false
+
+				err := platform.SetupFilesystemTrimming()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fstrim is not installed"))
+			})
+		})
+	})
+
+	Describe("SetupJobDiskQuotas", func() {
+		quotas := map[string]uint64{"fake-job-2": 2048, "fake-job-1": 1024}
+
+		BeforeEach(func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+				{PartitionPath: "/dev/sda1", MountPoint: "/"},
+				{PartitionPath: "/dev/sdb2", MountPoint: "/fake-dir/data", Options: []string{"rw", "prjquota"}},
+			}
+		})
+
+		It("does nothing when no quotas are configured", func() {
+			err := platform.SetupJobDiskQuotas(map[string]uint64{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		Context("when ephemeral disk is formatted with ext4", func() {
+			BeforeEach(func() {
+				cmdRunner.AddCmdResult("stat -f -c %T /fake-dir/data", fakesys.FakeCmdResult{Stdout: "ext2/ext3\n"})
+				cmdRunner.AddCmdResult("tune2fs -l /dev/sdb2", fakesys.FakeCmdResult{
+					Stdout: "Filesystem volume name:   <none>\nFilesystem features:      has_journal ext_attr extent project quota\n",
+				})
+			})
+
+			It("assigns job data and log dirs to per job projects and limits them", func() {
+				err := platform.SetupJobDiskQuotas(quotas)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands[2:]).To(Equal([][]string{
+					{"chattr", "-R", "+P", "-p", "1000", "/fake-dir/data/fake-job-1"},
+					{"chattr", "-R", "+P", "-p", "1000", "/fake-dir/data/sys/log/fake-job-1"},
+					{"setquota", "-P", "1000", "0", "1048576", "0", "0", "/fake-dir/data"},
+					{"chattr", "-R", "+P", "-p", "1001", "/fake-dir/data/fake-job-2"},
+					{"chattr", "-R", "+P", "-p", "1001", "/fake-dir/data/sys/log/fake-job-2"},
+					{"setquota", "-P", "1001", "0", "2097152", "0", "0", "/fake-dir/data"},
+				}))
+			})
+
+			It("creates job dirs that do not exist yet", func() {
+				err := platform.SetupJobDiskQuotas(quotas)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fs.FileExists("/fake-dir/data/fake-job-1")).To(BeTrue())
+				Expect(fs.FileExists("/fake-dir/data/sys/log/fake-job-2")).To(BeTrue())
+			})
+
+			It("keeps project ids of jobs when other jobs are added or removed", func() {
+				err := platform.SetupJobDiskQuotas(quotas)
+				Expect(err).NotTo(HaveOccurred())
+
+				cmdRunner.AddCmdResult("stat -f -c %T /fake-dir/data", fakesys.FakeCmdResult{Stdout: "ext2/ext3\n"})
+				cmdRunner.AddCmdResult("tune2fs -l /dev/sdb2", fakesys.FakeCmdResult{
+					Stdout: "Filesystem features:      has_journal project quota\n",
+				})
+				cmdRunner.RunCommands = nil
+
+				err = platform.SetupJobDiskQuotas(map[string]uint64{"fake-job-0": 512, "fake-job-2": 2048})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands[2:]).To(Equal([][]string{
+					{"setquota", "-P", "1000", "0", "0", "0", "0", "/fake-dir/data"},
+					{"chattr", "-R", "+P", "-p", "1000", "/fake-dir/data/fake-job-0"},
+					{"chattr", "-R", "+P", "-p", "1000", "/fake-dir/data/sys/log/fake-job-0"},
+					{"setquota", "-P", "1000", "0", "524288", "0", "0", "/fake-dir/data"},
+					{"chattr", "-R", "+P", "-p", "1001", "/fake-dir/data/fake-job-2"},
+					{"chattr", "-R", "+P", "-p", "1001", "/fake-dir/data/sys/log/fake-job-2"},
+					{"setquota", "-P", "1001", "0", "2097152", "0", "0", "/fake-dir/data"},
+				}))
+			})
+
+			It("clears limits of removed jobs when no quotas are configured anymore", func() {
+				err := platform.SetupJobDiskQuotas(map[string]uint64{"fake-job-1": 1024})
+				Expect(err).NotTo(HaveOccurred())
+
+				cmdRunner.AddCmdResult("stat -f -c %T /fake-dir/data", fakesys.FakeCmdResult{Stdout: "ext2/ext3\n"})
+				cmdRunner.AddCmdResult("tune2fs -l /dev/sdb2", fakesys.FakeCmdResult{
+					Stdout: "Filesystem features:      has_journal project quota\n",
+				})
+				cmdRunner.RunCommands = nil
+
+				err = platform.SetupJobDiskQuotas(map[string]uint64{})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands[2:]).To(Equal([][]string{
+					{"setquota", "-P", "1000", "0", "0", "0", "0", "/fake-dir/data"},
+				}))
+
+				cmdRunner.RunCommands = nil
+
+				err = platform.SetupJobDiskQuotas(map[string]uint64{})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("returns error when setting quota fails", func() {
+				cmdRunner.AddCmdResult("setquota -P 1000 0 1048576 0 0 /fake-dir/data", fakesys.FakeCmdResult{Error: errors.New("fake-setquota-error")})
+
+				err := platform.SetupJobDiskQuotas(quotas)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-setquota-error"))
+			})
+		})
+
+		It("returns error when ext4 ephemeral disk was formatted without project feature", func() {
+			cmdRunner.AddCmdResult("stat -f -c %T /fake-dir/data", fakesys.FakeCmdResult{Stdout: "ext2/ext3\n"})
+			cmdRunner.AddCmdResult("tune2fs -l /dev/sdb2", fakesys.FakeCmdResult{
+				Stdout: "Filesystem features:      has_journal ext_attr extent quota\n",
+			})
+
+			err := platform.SetupJobDiskQuotas(quotas)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Ephemeral disk '/dev/sdb2' must be formatted with ext4 project feature"))
+			Expect(cmdRunner.RunCommands).To(HaveLen(2))
+		})
+
+		Context("when ephemeral disk is formatted with xfs", func() {
+			BeforeEach(func() {
+				cmdRunner.AddCmdResult("stat -f -c %T /fake-dir/data", fakesys.FakeCmdResult{Stdout: "xfs\n"})
+			})
+
+			It("assigns job data and log dirs to per job projects and limits them", func() {
+				err := platform.SetupJobDiskQuotas(map[string]uint64{"fake-job-1": 1024})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands[1:]).To(Equal([][]string{
+					{"xfs_quota", "-x", "-c", "project -s -p /fake-dir/data/fake-job-1 1000", "/fake-dir/data"},
+					{"xfs_quota", "-x", "-c", "project -s -p /fake-dir/data/sys/log/fake-job-1 1000", "/fake-dir/data"},
+					{"xfs_quota", "-x", "-c", "limit -p bhard=1024m 1000", "/fake-dir/data"},
+				}))
+			})
+		})
+
+		It("returns error when ephemeral disk is not mounted with prjquota", func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+				{PartitionPath: "/dev/sdb2", MountPoint: "/fake-dir/data", Options: []string{"rw"}},
+			}
+
+			err := platform.SetupJobDiskQuotas(quotas)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("must be mounted with prjquota option"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns error when ephemeral disk is not mounted", func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{}
+
+			err := platform.SetupJobDiskQuotas(quotas)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Ephemeral disk is not mounted at '/fake-dir/data'"))
+		})
+	})
+
+	Describe("SetupHugePages", func() {
+		var mounter *fakedisk.FakeMounter
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		It("does nothing when no huge pages are requested", func() {
+			err := platform.SetupHugePages(boshsettings.HugePages{})
+			Expect(err).NotTo(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(mounter.MountCalled).To(BeFalse())
+		})
+
+		Context("when page size is not specified", func() {
+			It("reserves huge pages via sysctl and mounts hugetlbfs at the default path", func() {
+				err := platform.SetupHugePages(boshsettings.HugePages{Count: 512})
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"sysctl", "-w", "vm.nr_hugepages=512"}}))
+
+				Expect(fs.GetFileTestStat("/dev/hugepages").FileType).To(Equal(fakesys.FakeFileTypeDir))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"hugetlbfs"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/dev/hugepages"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "hugetlbfs"}}))
+			})
+
+			It("returns error if sysctl fails", func() {
+				cmdRunner.AddCmdResult("sysctl -w vm.nr_hugepages=512", fakesys.FakeCmdResult{Error: errors.New("fake-sysctl-err")})
+
+				err := platform.SetupHugePages(boshsettings.HugePages{Count: 512})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-sysctl-err"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+		})
+
+		Context("when page size is specified", func() {
+			It("reserves huge pages of that size and mounts hugetlbfs with the page size", func() {
+				err := platform.SetupHugePages(boshsettings.HugePages{Count: 4, PageSize: "1G", MountPath: "/fake-hugepages"})
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := fs.ReadFileString("/sys/kernel/mm/hugepages/hugepages-1048576kB/nr_hugepages")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(Equal("4"))
+
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-hugepages"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "hugetlbfs", "-o", "pagesize=1G"}}))
+			})
+
+			It("returns error if page size is invalid", func() {
+				err := platform.SetupHugePages(boshsettings.HugePages{Count: 4, PageSize: "fake-size"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Invalid huge page size 'fake-size'"))
+			})
+		})
+
+		It("does not mount hugetlbfs again if it is already mounted", func() {
+			mounter.IsMountPointResult = true
+
+			err := platform.SetupHugePages(boshsettings.HugePages{Count: 512})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mounter.MountCalled).To(BeFalse())
+		})
+
+		It("returns error if mounting hugetlbfs fails", func() {
+			mounter.MountErr = errors.New("fake-mount-err")
+
+			err := platform.SetupHugePages(boshsettings.HugePages{Count: 512})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+		})
+	})
+
+	Describe("SetupKernelArgs", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/etc/default/grub", "GRUB_DEFAULT=0\nGRUB_CMDLINE_LINUX=\"console=ttyS0 quiet isolcpus=1\"\n")
+			fs.WriteFileString("/proc/cmdline", "BOOT_IMAGE=/vmlinuz console=ttyS0 quiet isolcpus=1\n")
+			cmdRunner.AvailableCommands["update-grub"] = true
+		})
+
+		It("does nothing when no kernel args are specified", func() {
+			rebootRequired, err := platform.SetupKernelArgs(boshsettings.KernelArgs{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rebootRequired).To(BeFalse())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("adds, replaces and removes parameters in grub defaults and updates grub", func() {
+			rebootRequired, err := platform.SetupKernelArgs(boshsettings.KernelArgs{
+				Add:    []string{"transparent_hugepage=never", "isolcpus=2,3"},
+				Remove: []string{"quiet"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rebootRequired).To(BeTrue())
+
+			contents, err := fs.ReadFileString("/etc/default/grub")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("GRUB_DEFAULT=0\nGRUB_CMDLINE_LINUX=\"console=ttyS0 transparent_hugepage=never isolcpus=2,3\"\n"))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"update-grub"}}))
+			Expect(fs.FileExists("/fake-dir/bosh/reboot_required")).To(BeTrue())
+		})
+
+		It("uses grub2-mkconfig when update-grub is not available", func() {
+			cmdRunner.AvailableCommands["update-grub"] = false
+
+			_, err := platform.SetupKernelArgs(boshsettings.KernelArgs{Add: []string{"nosmt"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"grub2-mkconfig", "-o", "/boot/grub2/grub.cfg"}}))
+		})
+
+		It("does not update grub or require reboot when the running kernel already has the parameters", func() {
+			fs.WriteFileString("/fake-dir/bosh/reboot_required", "fake-reason")
+
+			rebootRequired, err := platform.SetupKernelArgs(boshsettings.KernelArgs{Add: []string{"isolcpus=1"}})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rebootRequired).To(BeFalse())
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(fs.FileExists("/fake-dir/bosh/reboot_required")).To(BeFalse())
+		})
+
+		It("returns error if grub defaults cannot be read", func() {
+			fs.RemoveAll("/etc/default/grub")
+
+			_, err := platform.SetupKernelArgs(boshsettings.KernelArgs{Add: []string{"nosmt"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reading /etc/default/grub"))
+		})
+
+		It("returns error if updating grub fails", func() {
+			cmdRunner.AddCmdResult("update-grub", fakesys.FakeCmdResult{Error: errors.New("fake-update-grub-err")})
+
+			_, err := platform.SetupKernelArgs(boshsettings.KernelArgs{Add: []string{"nosmt"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-update-grub-err"))
+		})
+	})
+
+	Describe("SetupTuningProfile", func() {
+		It("does nothing when profile is empty", func() {
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{Name: "fake-profile"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("sets CPU governor on all CPUs", func() {
+			fs.SetGlob("/sys/devices/system/cpu/cpu*/cpufreq/scaling_governor", []string{
+				"/sys/devices/system/cpu/cpu0/cpufreq/scaling_governor",
+				"/sys/devices/system/cpu/cpu1/cpufreq/scaling_governor",
+			})
+
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{CPUGovernor: "performance"})
+			Expect(err).NotTo(HaveOccurred())
+
+			for _, cpu := range []string{"cpu0", "cpu1"} {
+				contents, err := fs.ReadFileString("/sys/devices/system/cpu/" + cpu + "/cpufreq/scaling_governor")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(Equal("performance"))
+			}
+		})
+
+		It("sets transparent huge pages mode", func() {
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{TransparentHugePages: "never"})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/sys/kernel/mm/transparent_hugepage/enabled")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("never"))
+		})
+
+		It("returns error for invalid transparent huge pages mode", func() {
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{TransparentHugePages: "fake-mode"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid transparent huge pages mode 'fake-mode'"))
+		})
+
+		It("sets IRQ affinity", func() {
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{IRQAffinity: map[string]string{"24": "0-1"}})
+			Expect(err).NotTo(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/proc/irq/24/smp_affinity_list")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(contents).To(Equal("0-1"))
+		})
+
+		It("returns error for invalid IRQ number", func() {
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{IRQAffinity: map[string]string{"../fake": "0"}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid IRQ number"))
+		})
+
+		It("returns error if writing fails", func() {
+			fs.WriteFileError = errors.New("fake-write-err")
+
+			err := platform.SetupTuningProfile(boshsettings.TuningProfile{TransparentHugePages: "never"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+		})
+	})
+
+	Describe("MountPersistentDisk", func() {
+		act := func() error {
+			return platform.MountPersistentDisk(
+				boshsettings.DiskSettings{Path: "fake-volume-id"},
+				"/mnt/point",
+			)
+		}
+
+		var (
+			partitioner *fakedisk.FakePartitioner
+			formatter   *fakedisk.FakeFormatter
+			mounter     *fakedisk.FakeMounter
+		)
+		BeforeEach(func() {
+			partitioner = diskManager.FakePartitioner
+			formatter = diskManager.FakeFormatter
+			mounter = diskManager.FakeMounter
+		})
+
+		Context("when mounting partition fails", func() {
+			var checker *fakedisk.FakeFilesystemChecker
+
+			BeforeEach(func() {
+				checker = diskManager.FakeFilesystemChecker
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+				mounter.MountErrs = []error{errors.New("fake-mount-err")}
+			})
+
+			It("returns mount error without checking filesystem when fsck policy is not set", func() {
+				err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Mounting partition: fake-mount-err"))
+				Expect(checker.CheckPartitionPaths).To(BeEmpty())
+			})
+
+			Context("when fsck policy is check", func() {
+				BeforeEach(func() {
+					options.PersistentDiskFsckPolicy = "check"
+				})
+
+				It("returns result of filesystem check without repairing", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{
+						PartitionPath:  "/dev/sdf1",
+						FileSystemType: boshdisk.FileSystemExt4,
+						ExitStatus:     4,
+					}
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err).To(Equal(PersistentDiskFilesystemError{MountError: "fake-mount-err", Check: checker.CheckResult}))
+					Expect(err.Error()).To(Equal("Mounting partition: fake-mount-err; filesystem check of /dev/sdf1 (ext4): errors were found and not repaired (exit status 4)"))
+
+					Expect(checker.CheckPartitionPaths).To(Equal([]string{"/dev/sdf1"}))
+					Expect(checker.CheckRepairs).To(Equal([]bool{false}))
+					Expect(mounter.MountPartitionPaths).To(HaveLen(1))
+				})
+			})
+
+			Context("when fsck policy is repair", func() {
+				BeforeEach(func() {
+					options.PersistentDiskFsckPolicy = "repair"
+				})
+
+				It("repairs filesystem and mounts partition again", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{Repaired: true, Clean: true, ExitStatus: 1}
+
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(checker.CheckRepairs).To(Equal([]bool{true}))
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/sdf1", "/dev/sdf1"}))
+					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point", "/mnt/point"}))
+				})
+
+				It("does not mount partition again when errors remain", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{PartitionPath: "/dev/sdf1", FileSystemType: boshdisk.FileSystemExt4, Repaired: true, ExitStatus: 5}
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("some errors were repaired, remaining errors require manual repair (exit status 5)"))
+					Expect(mounter.MountPartitionPaths).To(HaveLen(1))
+				})
+
+				It("does not mount partition again when filesystem has no errors", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{Clean: true}
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("no errors were found"))
+					Expect(mounter.MountPartitionPaths).To(HaveLen(1))
+				})
+
+				It("returns error when mounting repaired partition fails", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{Repaired: true, Clean: true}
+					mounter.MountErrs = append(mounter.MountErrs, errors.New("fake-remount-err"))
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Mounting partition: fake-remount-err"))
+					Expect(err.Error()).To(ContainSubstring("errors were repaired but mounting still failed"))
+				})
+
+				It("returns error when checking filesystem fails", func() {
+					checker.CheckErr = errors.New("fake-check-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-check-err"))
+					Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+				})
+			})
+		})
+
+		Context("when PersistentDiskBindMountPaths is set", func() {
+			BeforeEach(func() {
+				options.PersistentDiskBindMountPaths = []string{"/var/lib/mysql"}
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("bind-mounts paths from persistent disk mounted at store dir", func() {
+				err := platform.MountPersistentDisk(boshsettings.DiskSettings{Path: "fake-volume-id"}, "/fake-dir/store")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"mount", "--bind", "/fake-dir/store/bind_mounts/var_lib_mysql", "/var/lib/mysql"}))
+			})
+
+			It("does not bind-mount paths when persistent disk is mounted elsewhere", func() {
+				err := platform.MountPersistentDisk(boshsettings.DiskSettings{Path: "fake-volume-id"}, "/mnt/point")
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).ToNot(ContainElement(ContainElement("--bind")))
+			})
+		})
+
+		Context("when UseLVMForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseLVMForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			mountDisk := func(diskSettings boshsettings.DiskSettings) error {
+				return platform.MountPersistentDisk(diskSettings, "/mnt/point")
+			}
+
+			It("creates logical volume on the device, formats and mounts it without partitioning", func() {
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk-1.a", Path: "fake-volume-id", FileSystemType: "xfs"})
+				Expect(err).ToNot(HaveOccurred())
+
+				lvm := diskManager.FakeLogicalVolumeManager
+				Expect(lvm.CreateVolumeGroup).To(Equal("bosh_disk_1_a"))
+				Expect(lvm.CreateLogicalVolume).To(Equal("store"))
+				Expect(lvm.CreateDevicePaths).To(Equal([]string{"/dev/sdf"}))
+
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_disk_1_a-store"}))
+				Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemXFS}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_disk_1_a-store"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+			})
+
+			It("skips mounting if logical volume is already mounted on mount point", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "/dev/mapper/bosh_disk1-store"
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("mounts logical volume of another disk on the migration dir", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "/dev/mapper/bosh_disk1-store"
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk2", Path: "fake-volume-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_disk2-store"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point_migration_target"}))
+			})
+
+			It("returns error if creating logical volume fails", func() {
+				diskManager.FakeLogicalVolumeManager.CreateErr = errors.New("fake-lvm-err")
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Creating logical volume: fake-lvm-err"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+		})
+
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			mountDisk := func(diskSettings boshsettings.DiskSettings) error {
+				return platform.MountPersistentDisk(diskSettings, "/mnt/point")
+			}
+
+			It("creates ZFS dataset on the device and mounts it without partitioning or formatting", func() {
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk-1.a", Path: "fake-volume-id", MountOptions: []string{"noatime"}})
+				Expect(err).ToNot(HaveOccurred())
+
+				zfs := diskManager.FakeZFSPoolManager
+				Expect(zfs.CreatePool).To(Equal("bosh_disk_1_a"))
+				Expect(zfs.CreateDataset).To(Equal("store"))
+				Expect(zfs.CreateDevicePaths).To(Equal([]string{"/dev/sdf"}))
+
+				Expect(partitioner.PartitionCalled).To(BeFalse())
+				Expect(formatter.FormatCalled).To(BeFalse())
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"bosh_disk_1_a/store"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"-t", "zfs", "-o", "noatime"}}))
+			})
+
+			It("skips mounting if dataset is already mounted on mount point", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "bosh_disk1/store"
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("mounts dataset of another disk on the migration dir", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "bosh_disk1/store"
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk2", Path: "fake-volume-id"})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"bosh_disk2/store"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point_migration_target"}))
+			})
+
+			It("returns error if creating dataset fails", func() {
+				diskManager.FakeZFSPoolManager.CreateErr = errors.New("fake-zfs-err")
+
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Creating ZFS dataset: fake-zfs-err"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("returns error if disk settings request encryption", func() {
+				err := mountDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Encrypting ZFS persistent disks is not supported"))
+			})
+		})
+
+		Context("when disk settings request encryption", func() {
+			BeforeEach(func() {
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("opens LUKS volume on the partition and formats and mounts the unlocked device", func() {
+				err := platform.MountPersistentDisk(
+					boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"},
+					"/mnt/point",
+				)
+				Expect(err).ToNot(HaveOccurred())
+
+				encryptor := diskManager.FakeEncryptor
+				Expect(encryptor.OpenDevicePaths).To(Equal([]string{"/dev/sdf1"}))
+				Expect(encryptor.OpenName).To(Equal("bosh_crypt_disk1"))
+				Expect(encryptor.OpenKey).To(Equal("fake-key"))
+
+				Expect(partitioner.PartitionDevicePath).To(Equal("/dev/sdf"))
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_crypt_disk1"}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_crypt_disk1"}))
+			})
+
+			Context("when UseLVMForPersistentDisk is set to true", func() {
+				BeforeEach(func() {
+					options.UseLVMForPersistentDisk = true
+				})
+
+				It("opens LUKS volume on the logical volume", func() {
+					err := platform.MountPersistentDisk(
+						boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"},
+						"/mnt/point",
+					)
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(diskManager.FakeEncryptor.OpenDevicePaths).To(Equal([]string{"/dev/mapper/bosh_disk1-store"}))
+					Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_crypt_disk1"}))
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh_crypt_disk1"}))
+				})
+			})
+
+			It("skips mounting if unlocked device is already mounted on mount point", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "/dev/mapper/bosh_crypt_disk1"
+
+				err := platform.MountPersistentDisk(
+					boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"},
+					"/mnt/point",
+				)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			Context("when DiskEncryptionKeyCommand is configured", func() {
+				BeforeEach(func() {
+					options.DiskEncryptionKeyCommand = "/var/vcap/bosh/bin/fetch-disk-key"
+				})
+
+				It("resolves encryption key reference with the key command", func() {
+					cmdRunner.AddCmdResult("/var/vcap/bosh/bin/fetch-disk-key kms://fake-key-ref", fakesys.FakeCmdResult{Stdout: "fake-kms-key\n"})
+
+					err := platform.MountPersistentDisk(
+						boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKeyRef: "kms://fake-key-ref"},
+						"/mnt/point",
+					)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(diskManager.FakeEncryptor.OpenKey).To(Equal("fake-kms-key"))
+
+					// Output written to a custom writer is not logged by command runner
+					Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+					Expect(cmdRunner.RunComplexCommands[0].Name).To(Equal("/var/vcap/bosh/bin/fetch-disk-key"))
+					Expect(cmdRunner.RunComplexCommands[0].Stdout).ToNot(BeNil())
+				})
+
+				It("returns error if key command fails", func() {
+					cmdRunner.AddCmdResult("/var/vcap/bosh/bin/fetch-disk-key kms://fake-key-ref", fakesys.FakeCmdResult{Error: errors.New("fake-kms-err")})
+
+					err := platform.MountPersistentDisk(
+						boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKeyRef: "kms://fake-key-ref"},
+						"/mnt/point",
+					)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-kms-err"))
+					Expect(diskManager.FakeEncryptor.OpenDevicePaths).To(BeEmpty())
+				})
+			})
+
+			It("returns error if key reference is given but no key command is configured", func() {
+				err := platform.MountPersistentDisk(
+					boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKeyRef: "kms://fake-key-ref"},
+					"/mnt/point",
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("no key command configured"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("returns error if opening LUKS volume fails", func() {
+				diskManager.FakeEncryptor.OpenErr = errors.New("fake-open-err")
+
+				err := platform.MountPersistentDisk(
+					boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"},
+					"/mnt/point",
+				)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-open-err"))
+				Expect(formatter.FormatCalled).To(BeFalse())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			Context("when UsePreformattedPersistentDisk is set to true", func() {
+				BeforeEach(func() {
+					options.UsePreformattedPersistentDisk = true
+				})
+
+				It("returns error", func() {
+					err := platform.MountPersistentDisk(
+						boshsettings.DiskSettings{ID: "disk1", Path: "fake-volume-id", EncryptionKey: "fake-key"},
+						"/mnt/point",
+					)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Encrypting pre-formatted persistent disks is not supported"))
+				})
+			})
+		})
+
+		It("formats partition with mkfs options from disk settings", func() {
+			err := platform.MountPersistentDisk(
+				boshsettings.DiskSettings{Path: "fake-volume-id", FileSystemType: "xfs", MkfsOptions: []string{"-i", "size=512"}},
+				"/mnt/point",
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemXFS}))
+			Expect(formatter.FormatMkfsOptions).To(Equal([][]string{{"-i", "size=512"}}))
+		})
+
+		It("formats partition with mkfs tuning from disk settings", func() {
+			reservedBlocksPercent := 0
+
+			err := platform.MountPersistentDisk(
+				boshsettings.DiskSettings{Path: "fake-volume-id", MkfsTuning: boshdisk.MkfsTuning{ReservedBlocksPercent: &reservedBlocksPercent}},
+				"/mnt/point",
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(formatter.FormatMkfsTunings).To(Equal([]boshdisk.MkfsTuning{{ReservedBlocksPercent: &reservedBlocksPercent}}))
+		})
+
+		It("mounts partition with mount options from disk settings", func() {
+			err := platform.MountPersistentDisk(
+				boshsettings.DiskSettings{Path: "fake-volume-id", MountOptions: []string{"nodev", "nobarrier"}},
+				"/mnt/point",
+			)
+			Expect(err).ToNot(HaveOccurred())
+
+			mounter := diskManager.FakeMounter
+			Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+			Expect(mounter.MountMountOptions).To(Equal([][]string{{"-o", "nodev,nobarrier"}}))
+		})
+
+		Context("when the size of the disk is larger than or equals 2 Terrabytes", func() {
+
+			BeforeEach(func() {
+				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(2199023255552)
+			})
+
+			It("uses parted partitioner", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskManager.PartedPartitionerCalled).To(BeTrue())
+				Expect(diskManager.PartitionerCalled).To(BeFalse())
+			})
+		})
+
+		Context("when the size of the disk is less than 2 Terabytes", func() {
+
+			BeforeEach(func() {
+				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(2199023255551)
+			})
+
+			It("uses fdisk partitioner", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskManager.PartitionerCalled).To(BeTrue())
+				Expect(diskManager.PartedPartitionerCalled).To(BeFalse())
+			})
+		})
+
+		Context("when the lsblk command returns an error", func() {
+
+			BeforeEach(func() {
+				diskManager.FakeDiskUtil.GetBlockDeviceSizeSize = uint64(3199023255556)
+				diskManager.FakeDiskUtil.GetBlockDeviceSizeError = errors.New("Some error")
+			})
+
+			It("uses fdisk partitioner", func() {
+				err := act()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskManager.PartitionerCalled).To(BeTrue())
+				Expect(diskManager.PartedPartitionerCalled).To(BeFalse())
+			})
+		})
+
+		Context("when device real path contains /dev/mapper/ and is successfully resolved", func() {
+			BeforeEach(func() {
+				devicePathResolver.RealDevicePath = "/dev/mapper/fake-real-device-path"
+			})
+
+			Context("when store directory is already mounted", func() {
+				BeforeEach(func() {
+					mounter.IsMountPointResult = true
+				})
+
+				Context("when mounting the same device", func() {
+					BeforeEach(func() {
+						mounter.IsMountPointPartitionPath = "/dev/mapper/fake-real-device-path-part1"
+					})
+
+					It("skips mounting", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(mounter.MountCalled).To(BeFalse())
+					})
+				})
+
+				Context("when mounting a different device", func() {
+					BeforeEach(func() {
+						mounter.IsMountPointPartitionPath = "/dev/mapper/another-device"
+					})
+
+					It("mounts the migration directory of the mount point", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(fs.GetFileTestStat("/mnt/point_migration_target").FileType).To(Equal(fakesys.FakeFileTypeDir))
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point_migration_target"}))
+						Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
+					})
+				})
+			})
+
+			Context("when failing to determine if store directory is mounted", func() {
+				BeforeEach(func() {
+					mounter.IsMountPointErr = errors.New("fake-is-mount-point-err")
+				})
+
+				It("returns an error", func() {
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-is-mount-point-err"))
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+			})
+
+			Context("when UsePreformattedPersistentDisk set to false", func() {
+				It("creates the mount directory with the correct permissions", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					mountPoint := fs.GetFileTestStat("/mnt/point")
+					Expect(mountPoint.FileType).To(Equal(fakesys.FakeFileTypeDir))
+					Expect(mountPoint.FileMode).To(Equal(os.FileMode(0700)))
+				})
+
+				It("returns error when creating mount directory fails", func() {
+					fs.MkdirAllError = errors.New("fake-mkdir-all-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-mkdir-all-err"))
+				})
+
+				It("partitions the disk", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					partitions := []boshdisk.Partition{{Type: boshdisk.PartitionTypeLinux}}
+					Expect(partitioner.PartitionDevicePath).To(Equal("/dev/mapper/fake-real-device-path"))
+					Expect(partitioner.PartitionPartitions).To(Equal(partitions))
+				})
+
+				It("formats the disk", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
+					Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt4}))
+				})
+
+				It("mounts the disk", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/fake-real-device-path-part1"}))
+					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+					Expect(mounter.MountMountOptions).To(Equal([][]string{nil}))
+				})
+			})
+		})
+
+		Context("when device path is successfully resolved", func() {
+			BeforeEach(func() {
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+			})
+
+			Context("when UsePreformattedPersistentDisk set to false", func() {
+				It("creates the mount directory with the correct permissions", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					mountPoint := fs.GetFileTestStat("/mnt/point")
+					Expect(mountPoint.FileType).To(Equal(fakesys.FakeFileTypeDir))
+					Expect(mountPoint.FileMode).To(Equal(os.FileMode(0700)))
+				})
+
+				It("returns error when creating mount directory fails", func() {
+					fs.MkdirAllError = errors.New("fake-mkdir-all-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("fake-mkdir-all-err"))
+				})
+
+				It("partitions the disk", func() {
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					partitions := []boshdisk.Partition{{Type: boshdisk.PartitionTypeLinux}}
+					Expect(partitioner.PartitionDevicePath).To(Equal("fake-real-device-path"))
+					Expect(partitioner.PartitionPartitions).To(Equal(partitions))
+				})
 
 				Context("when settings do NOT specify persistentDiskFS", func() {
 					It("formats in ext4 format", func() {
@@ -1755,9 +3404,157 @@ Number  Start   End     Size    File system  Name             Flags
 			return platform.UnmountPersistentDisk(boshsettings.DiskSettings{Path: "fake-device-path"})
 		}
 
-		var mounter *fakedisk.FakeMounter
-		BeforeEach(func() {
-			mounter = diskManager.FakeMounter
+		var mounter *fakedisk.FakeMounter
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+		})
+
+		Context("when PersistentDiskBindMountPaths is set", func() {
+			BeforeEach(func() {
+				options.PersistentDiskBindMountPaths = []string{"/var/lib/mysql", "/home"}
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("unmounts bind mounts before unmounting persistent disk", func() {
+				_, err := act()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/home", "/var/lib/mysql", "/dev/sdf1"}))
+			})
+
+			It("does not unmount persistent disk if unmounting bind mounts fails", func() {
+				mounter.UnmountErr = errors.New("fake-unmount-err")
+
+				_, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Unmounting bind mount /home"))
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/home"}))
+			})
+
+			It("does not unmount bind mounts when unmounting disk mounted elsewhere", func() {
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{Path: "fake-device-path", MountPoint: "/data/db"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/dev/sdf1"}))
+			})
+		})
+
+		Context("when UseLVMForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseLVMForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("unmounts logical volume and deactivates its volume group", func() {
+				mounter.UnmountDidUnmount = true
+
+				didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/dev/mapper/bosh_disk1-store"))
+				Expect(diskManager.FakeLogicalVolumeManager.DeactivateVolumeGroups).To(Equal([]string{"bosh_disk1"}))
+			})
+
+			It("unmounts unlocked device and closes LUKS volume of encrypted disk before deactivating volume group", func() {
+				mounter.UnmountDidUnmount = true
+
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path", EncryptionKey: "fake-key"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/dev/mapper/bosh_crypt_disk1"))
+				Expect(diskManager.FakeEncryptor.CloseNames).To(Equal([]string{"bosh_crypt_disk1"}))
+				Expect(diskManager.FakeLogicalVolumeManager.DeactivateVolumeGroups).To(Equal([]string{"bosh_disk1"}))
+			})
+
+			It("does not deactivate volume group if closing LUKS volume fails", func() {
+				diskManager.FakeEncryptor.CloseErr = errors.New("fake-close-err")
+
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path", EncryptionKey: "fake-key"})
+				Expect(err).To(HaveOccurred())
+				Expect(diskManager.FakeLogicalVolumeManager.DeactivateVolumeGroups).To(BeEmpty())
+			})
+
+			It("does not deactivate volume group if unmounting fails", func() {
+				mounter.UnmountErr = errors.New("fake-unmount-err")
+
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).To(HaveOccurred())
+				Expect(diskManager.FakeLogicalVolumeManager.DeactivateVolumeGroups).To(BeEmpty())
+			})
+
+			It("returns error if deactivating volume group fails", func() {
+				diskManager.FakeLogicalVolumeManager.DeactivateErr = errors.New("fake-deactivate-err")
+
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-deactivate-err"))
+			})
+		})
+
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("unmounts dataset and exports its pool", func() {
+				mounter.UnmountDidUnmount = true
+
+				didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("bosh_disk1/store"))
+				Expect(diskManager.FakeZFSPoolManager.ExportPools).To(Equal([]string{"bosh_disk1"}))
+			})
+
+			It("does not export pool if dataset was not mounted", func() {
+				mounter.UnmountDidUnmount = false
+
+				didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeFalse())
+				Expect(diskManager.FakeZFSPoolManager.ExportPools).To(BeEmpty())
+			})
+
+			It("returns error if exporting pool fails", func() {
+				mounter.UnmountDidUnmount = true
+				diskManager.FakeZFSPoolManager.ExportErr = errors.New("fake-export-err")
+
+				_, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-export-err"))
+			})
+		})
+
+		Context("when disk is encrypted", func() {
+			encryptedDisk := boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path", EncryptionKey: "fake-key"}
+
+			BeforeEach(func() {
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("unmounts unlocked device and closes LUKS volume", func() {
+				mounter.UnmountDidUnmount = true
+
+				didUnmount, err := platform.UnmountPersistentDisk(encryptedDisk)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/dev/mapper/bosh_crypt_disk1"))
+				Expect(diskManager.FakeEncryptor.CloseNames).To(Equal([]string{"bosh_crypt_disk1"}))
+			})
+
+			It("does not close LUKS volume if unmounting fails", func() {
+				mounter.UnmountErr = errors.New("fake-unmount-err")
+
+				_, err := platform.UnmountPersistentDisk(encryptedDisk)
+				Expect(err).To(HaveOccurred())
+				Expect(diskManager.FakeEncryptor.CloseNames).To(BeEmpty())
+			})
+
+			It("returns error if closing LUKS volume fails", func() {
+				diskManager.FakeEncryptor.CloseErr = errors.New("fake-close-err")
+
+				_, err := platform.UnmountPersistentDisk(encryptedDisk)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-close-err"))
+			})
 		})
 
 		Context("when device real path contains /dev/mapper/ and can be resolved", func() {
@@ -1914,6 +3711,24 @@ Number  Start   End     Size    File system  Name             Flags
 	})
 
 	Describe("GetEphemeralDiskPath", func() {
+		Context("when StripeRawEphemeralDisks is set to true", func() {
+			BeforeEach(func() {
+				options.StripeRawEphemeralDisks = true
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+			})
+
+			It("returns path of the array when it is active", func() {
+				diskManager.FakeRAIDManager.IsActiveResult = true
+				realPath := platform.GetEphemeralDiskPath(boshsettings.DiskSettings{Path: "fake-device-path"})
+				Expect(realPath).To(Equal("/dev/md/bosh-ephemeral"))
+			})
+
+			It("returns real device path when there is no array", func() {
+				realPath := platform.GetEphemeralDiskPath(boshsettings.DiskSettings{Path: "fake-device-path"})
+				Expect(realPath).To(Equal("fake-real-device-path"))
+			})
+		})
+
 		Context("when real device path was resolved without an error", func() {
 			It("returns real device path and true", func() {
 				devicePathResolver.RealDevicePath = "fake-real-device-path"
@@ -1943,12 +3758,230 @@ Number  Start   End     Size    File system  Name             Flags
 
 			Expect(mounter.RemountAsReadonlyPath).To(Equal("/from/path"))
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
 			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"sh", "-c", "(tar -C /from/path -cf - .) | (tar -C /to/path -xpf -)"}))
 
 			Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/from/path"))
 			Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
 			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+			Expect(mounter.RemountMountOptions).To(BeEmpty())
+			Expect(diskManager.FakeEncryptor.CloseNames).To(BeEmpty())
+		})
+
+		It("copies files with rsync when it is available so that interrupted migration can be resumed", func() {
+			cmdRunner.CommandExistsValue = true
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands[0]).To(Equal(
+				[]string{"rsync", "-aHAX", "--numeric-ids", "--delete", "/from/path/", "/to/path/"},
+			))
+		})
+
+		Context("when PersistentDiskBindMountPaths is set", func() {
+			BeforeEach(func() {
+				options.PersistentDiskBindMountPaths = []string{"/var/lib/mysql"}
+			})
+
+			It("unmounts and restores bind mounts when migrating disk mounted at store dir", func() {
+				err := platform.MigratePersistentDisk("/fake-dir/store", "/fake-dir/store_migration_target")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(ContainElement("/var/lib/mysql"))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"mount", "--bind", "/fake-dir/store/bind_mounts/var_lib_mysql", "/var/lib/mysql"}))
+			})
+
+			It("does not touch bind mounts when migrating disk mounted elsewhere", func() {
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).ToNot(ContainElement("/var/lib/mysql"))
+				Expect(cmdRunner.RunCommands).ToNot(ContainElement(ContainElement("--bind")))
+			})
+		})
+
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+			})
+
+			It("snapshots old dataset before copying files and remounts new dataset as zfs", func() {
+				mounter.IsMountPointPartitionPath = "bosh_disk1/store"
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				zfs := diskManager.FakeZFSPoolManager
+				Expect(zfs.SnapshotDatasets).To(Equal([]string{"bosh_disk1/store"}))
+				Expect(zfs.SnapshotSnapshots).To(Equal([]string{"bosh-migration"}))
+				Expect(mounter.RemountMountOptions).To(Equal([]string{"-t", "zfs"}))
+			})
+
+			It("does not snapshot old disk that is not a dataset", func() {
+				mounter.IsMountPointPartitionPath = "/dev/sdf1"
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskManager.FakeZFSPoolManager.SnapshotDatasets).To(BeEmpty())
+			})
+
+			It("does not copy files if snapshotting fails", func() {
+				mounter.IsMountPointPartitionPath = "bosh_disk1/store"
+				diskManager.FakeZFSPoolManager.SnapshotErr = errors.New("fake-snapshot-err")
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-snapshot-err"))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+		})
+
+		Context("when verifying copied files", func() {
+			// files are given as path and size pairs
+			addFiles := func(mountPoint string, files ...string) {
+				var paths, sizes []string
+				for i := 0; i < len(files); i += 2 {
+					paths = append(paths, mountPoint+"/"+files[i])
+					sizes = append(sizes, files[i+1])
+				}
+
+				cmdRunner.AddCmdResult("find "+mountPoint+" -xdev -type f -print0", fakesys.FakeCmdResult{
+					Stdout: strings.Join(paths, "\x00") + "\x00",
+				})
+				cmdRunner.AddCmdResult(`xargs -0 -r stat --printf %s\0 --`, fakesys.FakeCmdResult{
+					Stdout: strings.Join(sizes, "\x00") + "\x00",
+				})
+			}
+
+			addChecksums := func(checksums string) {
+				cmdRunner.AddCmdResult("xargs -0 -r sha256sum -z --", fakesys.FakeCmdResult{Stdout: checksums})
+			}
+
+			It("compares checksums of copied files", func() {
+				addFiles("/from/path", "a", "10", "dir/b", "20")
+				addFiles("/to/path", "dir/b", "20", "a", "10")
+				addChecksums("aaa  a\x00bbb  dir/b\x00")
+				addChecksums("aaa  a\x00bbb  dir/b\x00")
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunComplexCommands).To(HaveLen(4))
+				Expect(cmdRunner.RunComplexCommands[2].WorkingDir).To(Equal("/from/path"))
+				Expect(cmdRunner.RunComplexCommands[3].WorkingDir).To(Equal("/to/path"))
+
+				stdin, err := ioutil.ReadAll(cmdRunner.RunComplexCommands[3].Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(stdin)).To(Equal("a\x00dir/b"))
+
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+			})
+
+			It("handles file names with newlines", func() {
+				addFiles("/from/path", "a\nb", "10")
+				addFiles("/to/path", "a\nb", "10")
+				addChecksums("aaa  a\nb\x00")
+				addChecksums("aaa  a\nb\x00")
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).ToNot(HaveOccurred())
+
+				stdin, err := ioutil.ReadAll(cmdRunner.RunComplexCommands[0].Stdin)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(string(stdin)).To(Equal("/from/path/a\nb"))
+			})
+
+			It("returns differences without remounting the new disk", func() {
+				addFiles("/from/path", "a", "10", "b", "20", "c", "30")
+				addFiles("/to/path", "a", "10", "b", "21", "d", "40")
+				addChecksums("aaa  a\x00")
+				addChecksums("abc  a\x00")
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(Equal(DiskMigrationVerificationError{
+					FilesExpected:      3,
+					FilesCopied:        3,
+					MissingFiles:       []string{"c"},
+					UnexpectedFiles:    []string{"d"},
+					SizeMismatches:     []string{"b"},
+					ChecksumMismatches: []string{"a"},
+				}))
+
+				Expect(err.Error()).To(Equal("Copied 3 of 3 files with differences: " +
+					"1 missing files (c); 1 unexpected files (d); " +
+					"1 files with different size (b); 1 files with different checksum (a)"))
+
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+				Expect(mounter.RemountToMountPoint).To(BeEmpty())
+			})
+
+			It("returns error if listing files fails", func() {
+				cmdRunner.AddCmdResult("find /to/path -xdev -type f -print0", fakesys.FakeCmdResult{
+					Error: errors.New("fake-find-err"),
+				})
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-find-err"))
+			})
+		})
+
+		It("returns error if copying files fails", func() {
+			cmdRunner.AddCmdResult("sh -c (tar -C /from/path -cf - .) | (tar -C /to/path -xpf -)", fakesys.FakeCmdResult{
+				Error: errors.New("fake-copy-err"),
+			})
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-copy-err"))
+			Expect(mounter.RemountToMountPoint).To(BeEmpty())
+		})
+
+		It("does not report progress when migration is not running", func() {
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+
+			_, found := platform.GetPersistentDiskMigrationProgress()
+			Expect(found).To(BeFalse())
+		})
+
+		It("keeps disk mount options of the new disk when remounting it", func() {
+			diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+				{PartitionPath: "/dev/sdc1", MountPoint: "/from/path", Options: []string{"ro", "relatime"}},
+				{PartitionPath: "/dev/sdd1", MountPoint: "/to/path", Options: []string{"rw", "nodev", "noatime", "data=ordered"}},
+			}
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(mounter.RemountMountOptions).To(Equal([]string{"-o", "nodev,noatime"}))
+		})
+
+		It("returns error if searching mounts of the new disk fails", func() {
+			diskManager.FakeMountsSearcher.SearchMountsErr = errors.New("fake-search-err")
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-search-err"))
+		})
+
+		It("closes LUKS volume of the old encrypted disk after unmounting it", func() {
+			mounter.IsMountPointResult = true
+			mounter.IsMountPointPartitionPath = "/dev/mapper/bosh_crypt_disk1"
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskManager.FakeEncryptor.CloseNames).To(Equal([]string{"bosh_crypt_disk1"}))
+			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+		})
+
+		It("returns error if closing LUKS volume of the old disk fails", func() {
+			mounter.IsMountPointResult = true
+			mounter.IsMountPointPartitionPath = "/dev/mapper/bosh_crypt_disk1"
+			diskManager.FakeEncryptor.CloseErr = errors.New("fake-close-err")
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-close-err"))
 		})
 	})
 
@@ -1962,6 +3995,44 @@ Number  Start   End     Size    File system  Name             Flags
 			mounter = diskManager.FakeMounter
 		})
 
+		Context("when UseLVMForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseLVMForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("checks whether unlocked device of encrypted disk is mounted", func() {
+				_, err := platform.IsPersistentDiskMounted(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path", EncryptionKey: "fake-key"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal("/dev/mapper/bosh_crypt_disk1"))
+			})
+
+			It("checks whether logical volume is mounted", func() {
+				mounter.IsMountedResult = true
+
+				isMounted, err := platform.IsPersistentDiskMounted(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMounted).To(BeTrue())
+				Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal("/dev/mapper/bosh_disk1-store"))
+			})
+		})
+
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+				devicePathResolver.RealDevicePath = "/dev/sdf"
+			})
+
+			It("checks whether dataset is mounted", func() {
+				mounter.IsMountedResult = true
+
+				isMounted, err := platform.IsPersistentDiskMounted(boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMounted).To(BeTrue())
+				Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal("bosh_disk1/store"))
+			})
+		})
+
 		Context("when device real path contains /dev/mapper/ and can be resolved", func() {
 			BeforeEach(func() {
 				devicePathResolver.RealDevicePath = "/dev/mapper/fake-real-device-path"
@@ -2017,75 +4088,273 @@ Number  Start   End     Size    File system  Name             Flags
 					It("returns true if mount point exists", func() {
 						mounter.IsMountedResult = true
 
-						isMounted, err := act()
-						Expect(err).NotTo(HaveOccurred())
-						Expect(isMounted).To(BeTrue())
-						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
-					})
+						isMounted, err := act()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(isMounted).To(BeTrue())
+						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
+					})
+
+					It("returns false if mount point does not exist", func() {
+						mounter.IsMountedResult = false
+
+						isMounted, err := act()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(isMounted).To(BeFalse())
+						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
+					})
+				})
+
+				Context("checking persistent disk mount points fails", func() {
+					It("returns error", func() {
+						mounter.IsMountedResult = false
+						mounter.IsMountedErr = errors.New("fake-is-mounted-err")
+
+						isMounted, err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-is-mounted-err"))
+						Expect(isMounted).To(BeFalse())
+						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
+					})
+				})
+			}
+
+			Context("UsePreformattedPersistentDisk is set to false", func() {
+				ItChecksPersistentDiskMountPoint("fake-real-device-path1") // note partition '1'
+			})
+
+			Context("UsePreformattedPersistentDisk is set to true", func() {
+				BeforeEach(func() {
+					options.UsePreformattedPersistentDisk = true
+				})
+
+				ItChecksPersistentDiskMountPoint("fake-real-device-path") // note no '1'; no partitions
+			})
+		})
+
+		Context("when device path cannot be resolved", func() {
+			BeforeEach(func() {
+				devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
+				devicePathResolver.GetRealDevicePathTimedOut = false
+			})
+
+			It("returns error", func() {
+				isMounted, err := act()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-get-real-device-path-err"))
+				Expect(isMounted).To(BeFalse())
+			})
+		})
+
+		Context("when device path cannot be resolved due to timeout", func() {
+			BeforeEach(func() {
+				devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
+				devicePathResolver.GetRealDevicePathTimedOut = true
+			})
+
+			It("does not return error", func() {
+				isMounted, err := act()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMounted).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("ResizePersistentDisk", func() {
+		diskSettings := boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path"}
+
+		BeforeEach(func() {
+			devicePathResolver.RealDevicePath = "/dev/sdf"
+		})
+
+		Context("when persistent disk is partitioned", func() {
+			BeforeEach(func() {
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/sdf1", MountPoint: "/var/vcap/store"},
+				}
+			})
+
+			It("grows partition and ext4 filesystem", func() {
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"growpart", "/dev/sdf", "1"},
+					{"resize2fs", "-f", "/dev/sdf1"},
+				}))
+			})
+
+			It("grows xfs filesystem via its mount point", func() {
+				fs.WriteFileString("/proc/mounts", "/dev/sdf1 /var/vcap/store xfs rw,relatime 0 0\n")
+
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"growpart", "/dev/sdf", "1"},
+					{"xfs_growfs", "/var/vcap/store"},
+				}))
+			})
+
+			It("rescans the block device when supported", func() {
+				fs.WriteFileString("/sys/class/block/sdf/device/rescan", "")
+
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
+
+				contents, err := fs.ReadFileString("/sys/class/block/sdf/device/rescan")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(contents).To(Equal("1"))
+			})
+
+			It("still grows filesystem if partition already fills the device", func() {
+				cmdRunner.AddCmdResult("growpart /dev/sdf 1", fakesys.FakeCmdResult{
+					Stdout: "NOCHANGE: partition 1 is size 100. it cannot be grown",
+					Error:  errors.New("fake-growpart-err"),
+				})
+
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"resize2fs", "-f", "/dev/sdf1"}))
+			})
+
+			It("returns error if growing partition fails", func() {
+				cmdRunner.AddCmdResult("growpart /dev/sdf 1", fakesys.FakeCmdResult{
+					Error: errors.New("fake-growpart-err"),
+				})
+
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-growpart-err"))
+				Expect(cmdRunner.RunCommands).To(HaveLen(1))
+			})
+
+			It("returns error if growing filesystem fails", func() {
+				cmdRunner.AddCmdResult("resize2fs -f /dev/sdf1", fakesys.FakeCmdResult{
+					Error: errors.New("fake-resize2fs-err"),
+				})
+
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-resize2fs-err"))
+			})
+		})
+
+		Context("when UseLVMForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseLVMForPersistentDisk = true
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/mapper/bosh_disk1-store", MountPoint: "/var/vcap/store"},
+				}
+			})
+
+			It("grows logical volume and its filesystem", func() {
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
 
-					It("returns false if mount point does not exist", func() {
-						mounter.IsMountedResult = false
+				lvm := diskManager.FakeLogicalVolumeManager
+				Expect(lvm.GrowVolumeGroup).To(Equal("bosh_disk1"))
+				Expect(lvm.GrowLogicalVolume).To(Equal("store"))
+				Expect(lvm.GrowDevicePaths).To(Equal([]string{"/dev/sdf"}))
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"resize2fs", "-f", "/dev/mapper/bosh_disk1-store"},
+				}))
+			})
 
-						isMounted, err := act()
-						Expect(err).NotTo(HaveOccurred())
-						Expect(isMounted).To(BeFalse())
-						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
-					})
-				})
+			It("returns error if growing logical volume fails", func() {
+				diskManager.FakeLogicalVolumeManager.GrowErr = errors.New("fake-grow-err")
 
-				Context("checking persistent disk mount points fails", func() {
-					It("returns error", func() {
-						mounter.IsMountedResult = false
-						mounter.IsMountedErr = errors.New("fake-is-mounted-err")
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-grow-err"))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+		})
 
-						isMounted, err := act()
-						Expect(err).To(HaveOccurred())
-						Expect(err.Error()).To(ContainSubstring("fake-is-mounted-err"))
-						Expect(isMounted).To(BeFalse())
-						Expect(mounter.IsMountedDevicePathOrMountPoint).To(Equal(expectedCheckedMountPoint))
-					})
-				})
-			}
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "bosh_disk1/store", MountPoint: "/var/vcap/store"},
+				}
+			})
 
-			Context("UsePreformattedPersistentDisk is set to false", func() {
-				ItChecksPersistentDiskMountPoint("fake-real-device-path1") // note partition '1'
+			It("grows pool without growing a filesystem", func() {
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).NotTo(HaveOccurred())
+
+				zfs := diskManager.FakeZFSPoolManager
+				Expect(zfs.GrowPool).To(Equal("bosh_disk1"))
+				Expect(zfs.GrowDevicePaths).To(Equal([]string{"/dev/sdf"}))
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
 			})
 
-			Context("UsePreformattedPersistentDisk is set to true", func() {
-				BeforeEach(func() {
-					options.UsePreformattedPersistentDisk = true
-				})
+			It("returns error if growing pool fails", func() {
+				diskManager.FakeZFSPoolManager.GrowErr = errors.New("fake-grow-err")
 
-				ItChecksPersistentDiskMountPoint("fake-real-device-path") // note no '1'; no partitions
+				err := platform.ResizePersistentDisk(diskSettings)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-grow-err"))
 			})
 		})
 
-		Context("when device path cannot be resolved", func() {
+		Context("when disk is encrypted", func() {
+			encryptedDisk := boshsettings.DiskSettings{ID: "disk1", Path: "fake-device-path", EncryptionKey: "fake-key"}
+
 			BeforeEach(func() {
-				devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
-				devicePathResolver.GetRealDevicePathTimedOut = false
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/mapper/bosh_crypt_disk1", MountPoint: "/var/vcap/store"},
+				}
 			})
 
-			It("returns error", func() {
-				isMounted, err := act()
+			It("grows partition, LUKS volume and filesystem of the unlocked device", func() {
+				err := platform.ResizePersistentDisk(encryptedDisk)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(diskManager.FakeEncryptor.ResizeName).To(Equal("bosh_crypt_disk1"))
+				Expect(diskManager.FakeEncryptor.ResizeKey).To(Equal("fake-key"))
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"growpart", "/dev/sdf", "1"},
+					{"resize2fs", "-f", "/dev/mapper/bosh_crypt_disk1"},
+				}))
+			})
+
+			It("returns error if resizing LUKS volume fails", func() {
+				diskManager.FakeEncryptor.ResizeErr = errors.New("fake-resize-err")
+
+				err := platform.ResizePersistentDisk(encryptedDisk)
 				Expect(err).To(HaveOccurred())
-				Expect(err.Error()).To(ContainSubstring("fake-get-real-device-path-err"))
-				Expect(isMounted).To(BeFalse())
+				Expect(err.Error()).To(ContainSubstring("fake-resize-err"))
 			})
 		})
 
-		Context("when device path cannot be resolved due to timeout", func() {
+		Context("when UsePreformattedPersistentDisk is set to true", func() {
 			BeforeEach(func() {
-				devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
-				devicePathResolver.GetRealDevicePathTimedOut = true
+				options.UsePreformattedPersistentDisk = true
+				diskManager.FakeMountsSearcher.SearchMountsMounts = []boshdisk.Mount{
+					{PartitionPath: "/dev/sdf", MountPoint: "/var/vcap/store"},
+				}
 			})
 
-			It("does not return error", func() {
-				isMounted, err := act()
+			It("only grows the filesystem", func() {
+				err := platform.ResizePersistentDisk(diskSettings)
 				Expect(err).NotTo(HaveOccurred())
-				Expect(isMounted).To(BeFalse())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"resize2fs", "-f", "/dev/sdf"},
+				}))
 			})
 		})
+
+		It("returns error if persistent disk is not mounted", func() {
+			err := platform.ResizePersistentDisk(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Persistent disk /dev/sdf1 is not mounted"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns error if searching mounts fails", func() {
+			diskManager.FakeMountsSearcher.SearchMountsErr = errors.New("fake-search-err")
+
+			err := platform.ResizePersistentDisk(diskSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-search-err"))
+		})
 	})
 
 	Describe("IsPersistentDiskMountable", func() {
@@ -2093,6 +4362,36 @@ Number  Start   End     Size    File system  Name             Flags
 			devicePathResolver.RealDevicePath = "/fake/device"
 		})
 
+		Context("when UseLVMForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseLVMForPersistentDisk = true
+			})
+
+			It("returns whether logical volume exists instead of checking partitions", func() {
+				diskManager.FakeLogicalVolumeManager.ExistsResult = true
+
+				isMountable, err := platform.IsPersistentDiskMountable(boshsettings.DiskSettings{ID: "disk1", Path: "/fake/device"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMountable).To(BeTrue())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+		})
+
+		Context("when UseZFSForPersistentDisk is set to true", func() {
+			BeforeEach(func() {
+				options.UseZFSForPersistentDisk = true
+			})
+
+			It("returns whether dataset exists instead of checking partitions", func() {
+				diskManager.FakeZFSPoolManager.ExistsResult = true
+
+				isMountable, err := platform.IsPersistentDiskMountable(boshsettings.DiskSettings{ID: "disk1", Path: "/fake/device"})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMountable).To(BeTrue())
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+		})
+
 		Context("when the specified drive does not exist", func() {
 			It("returns error", func() {
 				devicePathResolver.GetRealDevicePathTimedOut = true
@@ -2252,6 +4551,384 @@ unit: sectors
 
 			Expect(netManager.SetupNetworkingNetworks).To(Equal(networks))
 		})
+
+		It("sets up wireguard networks after networks they are overlaid on", func() {
+			networks := boshsettings.Networks{
+				"default": boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0"},
+				"overlay": boshsettings.Network{
+					Type:      boshsettings.NetworkTypeWireGuard,
+					IP:        "10.255.0.6",
+					Netmask:   "255.255.255.0",
+					WireGuard: &boshsettings.WireGuard{PrivateKey: "fake-private-key"},
+				},
+			}
+
+			err := platform.SetupNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(netManager.SetupNetworkingNetworks).To(Equal(boshsettings.Networks{"default": networks["default"]}))
+			Expect(wireGuardManager.SetupWireGuardNetworks).To(Equal(networks))
+		})
+
+		It("returns error if setting up wireguard networks fails", func() {
+			wireGuardManager.SetupWireGuardErr = errors.New("fake-wireguard-err")
+
+			err := platform.SetupNetworking(boshsettings.Networks{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-wireguard-err"))
+		})
+
+		It("does not set up wireguard networks when setting up other networks fails", func() {
+			netManager.SetupNetworkingErr = errors.New("fake-net-err")
+			networks := boshsettings.Networks{
+				"overlay": boshsettings.Network{Type: boshsettings.NetworkTypeWireGuard, WireGuard: &boshsettings.WireGuard{}},
+			}
+
+			err := platform.SetupNetworking(networks)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-net-err"))
+			Expect(wireGuardManager.SetupWireGuardCalled).To(BeFalse())
+		})
+
+		It("assigns aliases to interfaces of networks", func() {
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: "2: eth0    inet 10.0.0.6/24 brd 10.0.0.255 scope global eth0\n"})
+			networks := boshsettings.Networks{
+				"default": boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0", Aliases: []string{"10.0.0.100"}},
+			}
+
+			err := platform.SetupNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "add", "10.0.0.100/24", "dev", "eth0"}))
+		})
+	})
+
+	Describe("SetupDNSCache", func() {
+		var networks boshsettings.Networks
+
+		BeforeEach(func() {
+			cmdRunner.AvailableCommands = map[string]bool{"dnsmasq": true}
+			networks = boshsettings.Networks{
+				"default": boshsettings.Network{IP: "10.0.0.6", DNS: []string{"8.8.8.8"}, Default: []string{"dns", "gateway"}},
+				"other":   boshsettings.Network{IP: "10.0.1.6", DNS: []string{"9.9.9.9"}},
+			}
+		})
+
+		It("forwards to dns servers of the default dns network and points it at dns cache", func() {
+			cachedNetworks, err := platform.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			config, err := fs.ReadFileString("/etc/bosh-dns-cache.conf")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(config).To(ContainSubstring("server=8.8.8.8\n"))
+			Expect(config).ToNot(ContainSubstring("9.9.9.9"))
+
+			Expect(cachedNetworks["default"].DNS).To(Equal([]string{"127.0.0.1"}))
+			Expect(cachedNetworks["other"]).To(Equal(networks["other"]))
+			Expect(networks["default"].DNS).To(Equal([]string{"8.8.8.8"}))
+		})
+
+		It("returns networks as they are when dns cache is disabled", func() {
+			cachedNetworks, err := platform.SetupDNSCache(boshsettings.DNSCache{}, networks)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cachedNetworks).To(Equal(networks))
+			Expect(fs.FileExists("/etc/bosh-dns-cache.conf")).To(BeFalse())
+		})
+	})
+
+	Describe("AddAlias", func() {
+		It("keeps alias assigned when networking is set up again", func() {
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: "2: eth0    inet 10.0.0.6/24 brd 10.0.0.255 scope global eth0\n"})
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: "2: eth0    inet 10.0.0.6/24 brd 10.0.0.255 scope global eth0\n"})
+			networks := boshsettings.Networks{
+				"default": boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0"},
+			}
+
+			err := platform.AddAlias(networks, "default", "10.0.0.150")
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = [][]string{}
+
+			err = platform.SetupNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "add", "10.0.0.150/24", "dev", "eth0"}))
+		})
+	})
+
+	Describe("PreviewNetworking", func() {
+		It("previews networks other than wireguard networks", func() {
+			networkChangePreviewer.PreviewNetworkingChanges = boshnet.NetworkChanges{Commands: []string{"fake-command"}}
+			networks := boshsettings.Networks{
+				"default": boshsettings.Network{IP: "10.0.0.6"},
+				"overlay": boshsettings.Network{Type: boshsettings.NetworkTypeWireGuard},
+			}
+
+			changes, err := platform.PreviewNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(changes).To(Equal(networkChangePreviewer.PreviewNetworkingChanges))
+
+			Expect(networkChangePreviewer.PreviewNetworkingNetworks).To(Equal(boshsettings.Networks{"default": networks["default"]}))
+		})
+	})
+
+	Describe("ValidateNetworking", func() {
+		It("checks that gateway of the default gateway network is reachable and probe hostname resolves", func() {
+			cmdRunner.AddCmdResult("getent hosts fake-probe-host", fakesys.FakeCmdResult{Stdout: "10.0.0.5 fake-probe-host\n"})
+			networks := boshsettings.Networks{
+				"fake-net": boshsettings.Network{IP: "10.0.0.2", Gateway: "10.0.0.1", Default: []string{"gateway"}},
+			}
+
+			err := platform.ValidateNetworking(networks, "fake-probe-host")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ping", "-c", "3", "-W", "1", "10.0.0.1"},
+				{"getent", "hosts", "fake-probe-host"},
+			}))
+			Expect(fakeDefaultNetworkResolver.GetDefaultNetworkCalled).To(BeFalse())
+		})
+
+		It("checks gateway of the default route when network does not have a gateway", func() {
+			fakeDefaultNetworkResolver.GetDefaultNetworkNetwork = boshsettings.Network{Gateway: "10.0.0.254"}
+			networks := boshsettings.Networks{
+				"fake-net": boshsettings.Network{Type: "dynamic"},
+			}
+
+			err := platform.ValidateNetworking(networks, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ping", "-c", "3", "-W", "1", "10.0.0.254"},
+			}))
+		})
+
+		It("returns connectivity error when gateway is not reachable", func() {
+			cmdRunner.AddCmdResult("ping -c 3 -W 1 10.0.0.1", fakesys.FakeCmdResult{Error: errors.New("fake-ping-err")})
+			networks := boshsettings.Networks{
+				"fake-net": boshsettings.Network{IP: "10.0.0.2", Gateway: "10.0.0.1"},
+			}
+
+			err := platform.ValidateNetworking(networks, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Network is not functional: default gateway '10.0.0.1' is not reachable"))
+		})
+
+		It("returns error when default gateway cannot be resolved", func() {
+			fakeDefaultNetworkResolver.GetDefaultNetworkErr = errors.New("fake-resolve-err")
+
+			err := platform.ValidateNetworking(boshsettings.Networks{}, "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-resolve-err"))
+		})
+	})
+
+	Describe("SetupFirewall", func() {
+		It("enables firewall with allowed ports when firewall is enabled", func() {
+			allowedPorts := []boshfirewall.Port{{Protocol: "tcp", Port: 22}}
+
+			err := platform.SetupFirewall(boshsettings.Firewall{Enabled: true, AllowedPorts: allowedPorts})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(firewallManager.EnableCalled).To(BeTrue())
+			Expect(firewallManager.EnableAllowedPorts).To(Equal(allowedPorts))
+			Expect(firewallManager.DisableCalled).To(BeFalse())
+		})
+
+		It("disables firewall when firewall is not enabled", func() {
+			err := platform.SetupFirewall(boshsettings.Firewall{})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(firewallManager.DisableCalled).To(BeTrue())
+			Expect(firewallManager.EnableCalled).To(BeFalse())
+		})
+
+		It("returns error if enabling firewall fails", func() {
+			firewallManager.EnableErr = errors.New("fake-enable-err")
+
+			err := platform.SetupFirewall(boshsettings.Firewall{Enabled: true})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-enable-err"))
+		})
+	})
+
+	Describe("SetupJobFirewallPorts", func() {
+		It("delegates to the firewall manager", func() {
+			jobPorts := map[string][]boshfirewall.Port{"fake-job": {{Protocol: "udp", Port: 53}}}
+
+			err := platform.SetupJobFirewallPorts(jobPorts)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(firewallManager.AllowJobPortsJobPorts).To(Equal(jobPorts))
+		})
+
+		It("returns error if allowing job ports fails", func() {
+			firewallManager.AllowJobPortsErr = errors.New("fake-allow-err")
+
+			err := platform.SetupJobFirewallPorts(nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-allow-err"))
+		})
+	})
+
+	Describe("SetupJobCgroups", func() {
+		It("creates cgroup with limits for each job", func() {
+			jobLimits := map[string]boshcgroup.Limits{
+				"fake-job-1": {MemoryBytes: 1024},
+				"fake-job-2": {CPUQuotaPercent: 50},
+			}
+
+			err := platform.SetupJobCgroups(jobLimits)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cgroupManager.CreateGroupNames).To(Equal([]string{"fake-job-1", "fake-job-2"}))
+			Expect(cgroupManager.ApplyLimitsLimits).To(Equal(jobLimits))
+		})
+
+		It("returns error if creating cgroup fails", func() {
+			cgroupManager.CreateGroupErr = errors.New("fake-create-err")
+
+			err := platform.SetupJobCgroups(map[string]boshcgroup.Limits{"fake-job": {}})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-create-err"))
+		})
+	})
+
+	Describe("AddJobProcessesToCgroups", func() {
+		It("adds processes in job's pid files and their descendants to job's cgroup", func() {
+			fs.SetGlob("/fake-dir/data/sys/run/fake-job/*.pid", []string{
+				"/fake-dir/data/sys/run/fake-job/fake-job.pid",
+				"/fake-dir/data/sys/run/fake-job/stale.pid",
+			})
+			fs.WriteFileString("/fake-dir/data/sys/run/fake-job/fake-job.pid", "100\n")
+			fs.SetGlob("/proc/100/task/*/children", []string{"/proc/100/task/100/children", "/proc/100/task/101/children"})
+			fs.WriteFileString("/proc/100/task/100/children", "200 201 ")
+			fs.WriteFileString("/proc/100/task/101/children", "")
+			fs.SetGlob("/proc/200/task/*/children", []string{"/proc/200/task/200/children"})
+			fs.WriteFileString("/proc/200/task/200/children", "300")
+
+			err := platform.AddJobProcessesToCgroups([]string{"fake-job"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cgroupManager.AddProcessPids).To(Equal(map[string][]int{"fake-job": {100, 200, 201, 300}}))
+		})
+
+		It("continues when processes cannot be added", func() {
+			fs.SetGlob("/fake-dir/data/sys/run/fake-job/*.pid", []string{"/fake-dir/data/sys/run/fake-job/fake-job.pid"})
+			fs.WriteFileString("/fake-dir/data/sys/run/fake-job/fake-job.pid", "100")
+			cgroupManager.AddProcessErr = errors.New("fake-add-err")
+
+			err := platform.AddJobProcessesToCgroups([]string{"fake-job"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cgroupManager.AddProcessPids).To(Equal(map[string][]int{"fake-job": {100}}))
+		})
+	})
+
+	Describe("TrackJobProcessGroups", func() {
+		BeforeEach(func() {
+			fs.SetGlob("/proc/[0-9]*/stat", []string{"/proc/1/stat", "/proc/50/stat", "/proc/100/stat", "/proc/200/stat", "/proc/201/stat"})
+			fs.WriteFileString("/proc/1/stat", "1 (init) S 0 1 1 0")
+			fs.WriteFileString("/proc/50/stat", "50 (monit) S 1 50 50 0")
+			fs.WriteFileString("/proc/100/stat", "100 (redis-server) S 1 90 90 0")
+			fs.WriteFileString("/proc/200/stat", "200 (redis (bgsave)) S 100 200 200 0")
+			fs.WriteFileString("/proc/201/stat", "201 (helper) S 100 50 50 0")
+		})
+
+		It("records process groups of job processes and their descendants", func() {
+			fs.SetGlob("/fake-dir/data/sys/run/fake-job/*.pid", []string{"/fake-dir/data/sys/run/fake-job/fake-job.pid"})
+			fs.WriteFileString("/fake-dir/data/sys/run/fake-job/fake-job.pid", "100\n")
+			fs.SetGlob("/proc/100/task/*/children", []string{"/proc/100/task/100/children"})
+			fs.WriteFileString("/proc/100/task/100/children", "200 201")
+
+			err := platform.TrackJobProcessGroups([]string{"fake-job"})
+			Expect(err).ToNot(HaveOccurred())
+
+			// Group of monit is led by a process outside of job's process tree
+			Expect(fs.ReadFileString("/fake-dir/bosh/job_process_groups.json")).To(Equal(`{"fake-job":[90,200]}`))
+		})
+
+		It("forgets tracked process groups without processes", func() {
+			fs.WriteFileString("/fake-dir/bosh/job_process_groups.json", `{"fake-job":[90,400]}`)
+
+			err := platform.TrackJobProcessGroups([]string{"fake-job"})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.ReadFileString("/fake-dir/bosh/job_process_groups.json")).To(Equal(`{"fake-job":[90]}`))
+		})
+	})
+
+	Describe("KillOrphanedJobProcesses", func() {
+		BeforeEach(func() {
+			options.OrphanedProcessGracePeriodInSeconds = 1
+
+			fs.SetGlob("/proc/[0-9]*/stat", []string{"/proc/1/stat", "/proc/100/stat", "/proc/300/stat"})
+			fs.WriteFileString("/proc/1/stat", "1 (init) S 0 1 1 0")
+			fs.WriteFileString("/proc/100/stat", "100 (redis-server) S 1 90 90 0")
+			fs.WriteFileString("/proc/100/cmdline", "/var/vcap/packages/redis/bin/redis-server\x00--port\x006379\x00")
+			fs.WriteFileString("/proc/300/stat", "300 (sshd) S 1 300 300 0")
+		})
+
+		It("terminates and then kills processes left in tracked process groups after grace periods and records them in audit log", func() {
+			fs.WriteFileString("/fake-dir/bosh/job_process_groups.json", `{"fake-job":[90]}`)
+			auditLogPath := "/fake-dir/data/sys/log/bosh-agent/orphaned_processes.log"
+
+			errCh := make(chan error)
+			go func() { errCh <- platform.KillOrphanedJobProcesses() }()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+
+			timeService.Increment(1 * time.Second)
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"kill", "-TERM", "100"}}))
+			Expect(fs.ReadFileString(auditLogPath)).To(ContainSubstring(
+				`job=fake-job pid=100 pgid=90 signal=TERM cmdline="/var/vcap/packages/redis/bin/redis-server --port 6379"`))
+
+			timeService.Increment(1 * time.Second)
+
+			Eventually(errCh).Should(Receive(BeNil()))
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"kill", "-TERM", "100"},
+				{"kill", "-KILL", "100"},
+			}))
+			Expect(fs.ReadFileString(auditLogPath)).To(ContainSubstring(`job=fake-job pid=100 pgid=90 signal=KILL`))
+			Expect(fs.GetFileTestStat(auditLogPath).Flags).To(Equal(os.O_WRONLY | os.O_CREATE | os.O_APPEND))
+
+			Expect(fs.FileExists("/fake-dir/bosh/job_process_groups.json")).To(BeFalse())
+		})
+
+		It("does not track process groups until orphans were killed", func() {
+			fs.WriteFileString("/fake-dir/bosh/job_process_groups.json", `{"fake-job":[90]}`)
+
+			killErrCh := make(chan error)
+			go func() { killErrCh <- platform.KillOrphanedJobProcesses() }()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+
+			trackErrCh := make(chan error)
+			go func() { trackErrCh <- platform.TrackJobProcessGroups([]string{"fake-job"}) }()
+
+			Consistently(trackErrCh).ShouldNot(Receive())
+
+			timeService.Increment(1 * time.Second)
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			timeService.Increment(1 * time.Second)
+
+			Eventually(killErrCh).Should(Receive(BeNil()))
+			Eventually(trackErrCh).Should(Receive(BeNil()))
+			Expect(fs.ReadFileString("/fake-dir/bosh/job_process_groups.json")).To(Equal(`{"fake-job":[]}`))
+		})
+
+		It("does not kill anything when tracked process groups have no processes left", func() {
+			fs.WriteFileString("/fake-dir/bosh/job_process_groups.json", `{"fake-job":[400]}`)
+
+			err := platform.KillOrphanedJobProcesses()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+			Expect(fs.FileExists("/fake-dir/data/sys/log/bosh-agent/orphaned_processes.log")).To(BeFalse())
+		})
 	})
 
 	Describe("GetConfiguredNetworkInterfaces", func() {
@@ -0,0 +1,360 @@
+package net
+
+import (
+	"encoding/json"
+	"fmt"
+	gonet "net"
+	"sort"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const aliasManagerLogTag = "aliasManager"
+
+// AliasManager maintains secondary addresses of interfaces that carry networks,
+// e.g. virtual IPs that HA jobs move between instances on failover
+type AliasManager interface {
+	SetupAliases(networks boshsettings.Networks) error
+
+	// AddAlias assigns address to the interface of the network until it is removed,
+	// also across re-applying networking
+	AddAlias(networks boshsettings.Networks, networkName, address string) error
+
+	// RemoveAlias unassigns address added with AddAlias
+	RemoveAlias(networks boshsettings.Networks, networkName, address string) error
+}
+
+type aliasState struct {
+	// RuntimeAliases are added with AddAlias keyed by network name
+	RuntimeAliases map[string][]string `json:"runtime_aliases"`
+	// Assigned are aliases that were assigned when aliases were last set up
+	Assigned []aliasAssignment `json:"assigned"`
+}
+
+type aliasAssignment struct {
+	Interface string `json:"interface"`
+	// Address is in CIDR notation (e.g. 10.0.0.100/24)
+	Address string `json:"address"`
+}
+
+type aliasManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	statePath string
+	logger    boshlog.Logger
+}
+
+func NewAliasManager(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, statePath string, logger boshlog.Logger) AliasManager {
+	return aliasManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		statePath: statePath,
+		logger:    logger,
+	}
+}
+
+// SetupAliases assigns aliases from network settings and runtime aliases to interfaces
+// that carry addresses of their networks and unassigns previously assigned aliases that are no longer desired
+func (m aliasManager) SetupAliases(networks boshsettings.Networks) error {
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	return m.apply(networks, s)
+}
+
+func (m aliasManager) AddAlias(networks boshsettings.Networks, networkName, address string) error {
+	networkSettings, found := networks[networkName]
+	if !found {
+		return bosherr.Errorf("Network '%s' does not exist", networkName)
+	}
+
+	alias, err := aliasAddress(networkSettings, address)
+	if err != nil {
+		return err
+	}
+
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	if s.RuntimeAliases == nil {
+		s.RuntimeAliases = map[string][]string{}
+	}
+
+	for _, runtimeAlias := range s.RuntimeAliases[networkName] {
+		if runtimeAlias == alias {
+			return m.apply(networks, s)
+		}
+	}
+
+	s.RuntimeAliases[networkName] = append(s.RuntimeAliases[networkName], alias)
+
+	return m.apply(networks, s)
+}
+
+func (m aliasManager) RemoveAlias(networks boshsettings.Networks, networkName, address string) error {
+	networkSettings, found := networks[networkName]
+	if !found {
+		return bosherr.Errorf("Network '%s' does not exist", networkName)
+	}
+
+	alias, err := aliasAddress(networkSettings, address)
+	if err != nil {
+		return err
+	}
+
+	for _, settingsAlias := range networkSettings.Aliases {
+		if declaredAlias, err := aliasAddress(networkSettings, settingsAlias); err == nil && declaredAlias == alias {
+			return bosherr.Errorf("Alias '%s' is declared in settings of network '%s'", address, networkName)
+		}
+	}
+
+	s, err := m.loadState()
+	if err != nil {
+		return err
+	}
+
+	runtimeAliases := []string{}
+	for _, runtimeAlias := range s.RuntimeAliases[networkName] {
+		if runtimeAlias != alias {
+			runtimeAliases = append(runtimeAliases, runtimeAlias)
+		}
+	}
+
+	if len(runtimeAliases) > 0 {
+		s.RuntimeAliases[networkName] = runtimeAliases
+	} else {
+		delete(s.RuntimeAliases, networkName)
+	}
+
+	return m.apply(networks, s)
+}
+
+func (m aliasManager) apply(networks boshsettings.Networks, s aliasState) error {
+	ifaceAddresses, err := m.interfaceAddresses()
+	if err != nil {
+		return err
+	}
+
+	networkNames := []string{}
+	for name := range networks {
+		networkNames = append(networkNames, name)
+	}
+
+	sort.Strings(networkNames)
+
+	desired := []aliasAssignment{}
+	runtimeAliases := map[string][]string{}
+
+	for _, name := range networkNames {
+		networkSettings := networks[name]
+
+		aliases := append(append([]string{}, networkSettings.Aliases...), s.RuntimeAliases[name]...)
+		if len(aliases) == 0 {
+			continue
+		}
+
+		if len(s.RuntimeAliases[name]) > 0 {
+			runtimeAliases[name] = s.RuntimeAliases[name]
+		}
+
+		ifaceName, err := networkInterfaceName(name, networkSettings, ifaceAddresses)
+		if err != nil {
+			return err
+		}
+
+		for _, address := range aliases {
+			alias, err := aliasAddress(networkSettings, address)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Configuring aliases of network '%s'", name)
+			}
+
+			assignment := aliasAssignment{Interface: ifaceName, Address: alias}
+			if !containsAliasAssignment(desired, assignment) {
+				desired = append(desired, assignment)
+			}
+		}
+	}
+
+	for _, assignment := range s.Assigned {
+		if containsAliasAssignment(desired, assignment) || !hasInterfaceAddress(ifaceAddresses, assignment) {
+			continue
+		}
+
+		m.logger.Info(aliasManagerLogTag, "Removing alias '%s' from interface '%s'", assignment.Address, assignment.Interface)
+
+		_, _, _, err = m.cmdRunner.RunCommand("ip", "address", "del", assignment.Address, "dev", assignment.Interface)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing alias '%s' from interface '%s'", assignment.Address, assignment.Interface)
+		}
+	}
+
+	for _, assignment := range desired {
+		if hasInterfaceAddress(ifaceAddresses, assignment) {
+			continue
+		}
+
+		m.logger.Info(aliasManagerLogTag, "Adding alias '%s' to interface '%s'", assignment.Address, assignment.Interface)
+
+		_, _, _, err = m.cmdRunner.RunCommand("ip", "address", "add", assignment.Address, "dev", assignment.Interface)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Adding alias '%s' to interface '%s'", assignment.Address, assignment.Interface)
+		}
+
+		m.announce(assignment)
+	}
+
+	return m.saveState(aliasState{RuntimeAliases: runtimeAliases, Assigned: desired})
+}
+
+// announce updates ARP caches of neighbors so that traffic to the alias
+// moves to this instance right after failover; IPv6 aliases are announced by the kernel
+func (m aliasManager) announce(assignment aliasAssignment) {
+	ip, _, _ := gonet.ParseCIDR(assignment.Address)
+	if ip.To4() == nil {
+		return
+	}
+
+	_, _, _, err := m.cmdRunner.RunCommand("arping", "-c", "3", "-U", "-I", assignment.Interface, ip.String())
+	if err != nil {
+		m.logger.Info(aliasManagerLogTag, "Ignoring arping failure: %s", err.Error())
+	}
+}
+
+// interfaceAddresses returns addresses in CIDR notation keyed by interface name
+func (m aliasManager) interfaceAddresses() (map[string][]string, error) {
+	stdout, _, _, err := m.cmdRunner.RunCommand("ip", "-o", "address", "show")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing interface addresses")
+	}
+
+	ifaceAddresses := map[string][]string{}
+
+	// e.g. '2: eth0    inet 10.0.0.6/24 brd 10.0.0.255 scope global eth0\       valid_lft forever preferred_lft forever'
+	for _, line := range strings.Split(stdout, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || (fields[2] != "inet" && fields[2] != "inet6") {
+			continue
+		}
+
+		ifaceName := strings.SplitN(fields[1], "@", 2)[0]
+		ifaceAddresses[ifaceName] = append(ifaceAddresses[ifaceName], fields[3])
+	}
+
+	return ifaceAddresses, nil
+}
+
+func (m aliasManager) loadState() (aliasState, error) {
+	var s aliasState
+
+	if !m.fs.FileExists(m.statePath) {
+		return s, nil
+	}
+
+	bytes, err := m.fs.ReadFile(m.statePath)
+	if err != nil {
+		return s, bosherr.WrapError(err, "Reading alias state file")
+	}
+
+	err = json.Unmarshal(bytes, &s)
+	if err != nil {
+		return s, bosherr.WrapError(err, "Unmarshalling alias state")
+	}
+
+	return s, nil
+}
+
+func (m aliasManager) saveState(s aliasState) error {
+	bytes, err := json.Marshal(s)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling alias state")
+	}
+
+	err = m.fs.WriteFile(m.statePath, bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing alias state file")
+	}
+
+	return nil
+}
+
+// networkInterfaceName finds interface by the address of the network
+// since aliases are assigned after the network is configured
+func networkInterfaceName(networkName string, networkSettings boshsettings.Network, ifaceAddresses map[string][]string) (string, error) {
+	if networkSettings.IP == "" {
+		return "", bosherr.Errorf("Network '%s' has no IP address to find its interface", networkName)
+	}
+
+	for ifaceName, addresses := range ifaceAddresses {
+		for _, address := range addresses {
+			ip, _, err := gonet.ParseCIDR(address)
+			if err == nil && ip.Equal(gonet.ParseIP(networkSettings.IP)) {
+				return ifaceName, nil
+			}
+		}
+	}
+
+	return "", bosherr.Errorf("No interface has IP address '%s' of network '%s'", networkSettings.IP, networkName)
+}
+
+// aliasAddress returns address in CIDR notation; addresses without
+// prefix length use netmask of the network or a host prefix without netmask
+func aliasAddress(networkSettings boshsettings.Network, address string) (string, error) {
+	if strings.Contains(address, "/") {
+		ip, ipNet, err := gonet.ParseCIDR(address)
+		if err != nil {
+			return "", bosherr.Errorf("Alias '%s' is not a valid IP address", address)
+		}
+
+		prefixLength, _ := ipNet.Mask.Size()
+
+		return fmt.Sprintf("%s/%d", ip.String(), prefixLength), nil
+	}
+
+	ip := gonet.ParseIP(address)
+	if ip == nil {
+		return "", bosherr.Errorf("Alias '%s' is not a valid IP address", address)
+	}
+
+	prefixLength := 128
+	if ip.To4() != nil {
+		prefixLength = 32
+	}
+
+	if networkSettings.Netmask != "" && isIPv6(networkSettings.IP) == (ip.To4() == nil) {
+		var err error
+		prefixLength, err = netmaskPrefixLength(networkSettings.Netmask)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return fmt.Sprintf("%s/%d", ip.String(), prefixLength), nil
+}
+
+func containsAliasAssignment(assignments []aliasAssignment, assignment aliasAssignment) bool {
+	for _, a := range assignments {
+		if a == assignment {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasInterfaceAddress(ifaceAddresses map[string][]string, assignment aliasAssignment) bool {
+	for _, address := range ifaceAddresses[assignment.Interface] {
+		if address == assignment.Address {
+			return true
+		}
+	}
+
+	return false
+}
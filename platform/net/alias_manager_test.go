@@ -0,0 +1,179 @@
+package net_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+var _ = Describe("AliasManager", func() {
+	const (
+		statePath = "/var/vcap/bosh/etc/aliases.json"
+
+		ipAddresses = `1: lo    inet 127.0.0.1/8 scope host lo\       valid_lft forever preferred_lft forever
+2: eth0    inet 10.0.0.6/24 brd 10.0.0.255 scope global eth0\       valid_lft forever preferred_lft forever
+2: eth0    inet6 fe80::1/64 scope link \       valid_lft forever preferred_lft forever
+`
+	)
+
+	var (
+		fs           *fakesys.FakeFileSystem
+		cmdRunner    *fakesys.FakeCmdRunner
+		aliasManager AliasManager
+		networks     boshsettings.Networks
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		aliasManager = NewAliasManager(fs, cmdRunner, statePath, boshlog.NewLogger(boshlog.LevelNone))
+
+		networks = boshsettings.Networks{
+			"default": boshsettings.Network{
+				IP:      "10.0.0.6",
+				Netmask: "255.255.255.0",
+				Aliases: []string{"10.0.0.100", "10.0.1.100/32"},
+			},
+		}
+
+		cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: ipAddresses})
+	})
+
+	Describe("SetupAliases", func() {
+		It("adds aliases from settings to the interface that has address of the network", func() {
+			err := aliasManager.SetupAliases(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ip", "-o", "address", "show"},
+				{"ip", "address", "add", "10.0.0.100/24", "dev", "eth0"},
+				{"arping", "-c", "3", "-U", "-I", "eth0", "10.0.0.100"},
+				{"ip", "address", "add", "10.0.1.100/32", "dev", "eth0"},
+				{"arping", "-c", "3", "-U", "-I", "eth0", "10.0.1.100"},
+			}))
+		})
+
+		It("does not add aliases that are already assigned", func() {
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: ipAddresses + "2: eth0    inet 10.0.0.100/24 scope global secondary eth0\n"})
+			networks["default"] = boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0", Aliases: []string{"10.0.0.100"}}
+
+			err := aliasManager.SetupAliases(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = aliasManager.SetupAliases(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"ip", "-o", "address", "show"},
+				{"ip", "address", "add", "10.0.0.100/24", "dev", "eth0"},
+				{"arping", "-c", "3", "-U", "-I", "eth0", "10.0.0.100"},
+				{"ip", "-o", "address", "show"},
+			}))
+		})
+
+		It("removes previously assigned aliases that are no longer in settings", func() {
+			fs.WriteFileString(statePath, `{"assigned":[{"interface":"eth0","address":"10.0.0.200/24"}]}`)
+			networks["default"] = boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0"}
+
+			cmdRunner = fakesys.NewFakeCmdRunner()
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: ipAddresses + "2: eth0    inet 10.0.0.200/24 scope global secondary eth0\n"})
+			aliasManager = NewAliasManager(fs, cmdRunner, statePath, boshlog.NewLogger(boshlog.LevelNone))
+
+			err := aliasManager.SetupAliases(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "del", "10.0.0.200/24", "dev", "eth0"}))
+
+			state, err := fs.ReadFileString(statePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(`{"runtime_aliases":{},"assigned":[]}`))
+		})
+
+		It("returns an error when no interface has address of the network", func() {
+			networks["default"] = boshsettings.Network{IP: "10.0.0.7", Aliases: []string{"10.0.0.100"}}
+
+			err := aliasManager.SetupAliases(networks)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No interface has IP address '10.0.0.7' of network 'default'"))
+		})
+
+		It("returns an error when alias is not an IP address", func() {
+			networks["default"] = boshsettings.Network{IP: "10.0.0.6", Aliases: []string{"fake-alias"}}
+
+			err := aliasManager.SetupAliases(networks)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Configuring aliases of network 'default': Alias 'fake-alias' is not a valid IP address"))
+		})
+	})
+
+	Describe("AddAlias", func() {
+		BeforeEach(func() {
+			networks["default"] = boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0"}
+		})
+
+		It("adds alias and keeps it across setting up aliases again", func() {
+			err := aliasManager.AddAlias(networks, "default", "10.0.0.150")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "add", "10.0.0.150/24", "dev", "eth0"}))
+
+			cmdRunner.RunCommands = [][]string{}
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: ipAddresses})
+
+			err = aliasManager.SetupAliases(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "add", "10.0.0.150/24", "dev", "eth0"}))
+		})
+
+		It("returns an error when network does not exist", func() {
+			err := aliasManager.AddAlias(networks, "fake-network", "10.0.0.150")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Network 'fake-network' does not exist"))
+		})
+
+		It("returns an error when adding alias fails", func() {
+			cmdRunner.AddCmdResult("ip address add 10.0.0.150/24 dev eth0", fakesys.FakeCmdResult{Error: errors.New("fake-ip-err")})
+
+			err := aliasManager.AddAlias(networks, "default", "10.0.0.150")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Adding alias '10.0.0.150/24' to interface 'eth0': fake-ip-err"))
+		})
+	})
+
+	Describe("RemoveAlias", func() {
+		BeforeEach(func() {
+			networks["default"] = boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0", Aliases: []string{"10.0.0.100"}}
+		})
+
+		It("removes alias that was added at runtime", func() {
+			fs.WriteFileString(statePath, `{"runtime_aliases":{"default":["10.0.0.150/24"]},"assigned":[{"interface":"eth0","address":"10.0.0.150/24"}]}`)
+
+			cmdRunner = fakesys.NewFakeCmdRunner()
+			cmdRunner.AddCmdResult("ip -o address show", fakesys.FakeCmdResult{Stdout: ipAddresses + "2: eth0    inet 10.0.0.150/24 scope global secondary eth0\n"})
+			aliasManager = NewAliasManager(fs, cmdRunner, statePath, boshlog.NewLogger(boshlog.LevelNone))
+
+			err := aliasManager.RemoveAlias(networks, "default", "10.0.0.150/24")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "address", "del", "10.0.0.150/24", "dev", "eth0"}))
+
+			state, err := fs.ReadFileString(statePath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(state).To(Equal(`{"runtime_aliases":{},"assigned":[{"interface":"eth0","address":"10.0.0.100/24"}]}`))
+		})
+
+		It("returns an error when alias is declared in settings", func() {
+			err := aliasManager.RemoveAlias(networks, "default", "10.0.0.100")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Alias '10.0.0.100' is declared in settings of network 'default'"))
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package arp
+
+import (
+	"time"
+
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+// Announcement overrides how an address is announced to its neighbors;
+// zero Iterations and Delay keep defaults of the broadcaster
+type Announcement struct {
+	Strategy   boshsettings.AddressAnnouncementStrategy
+	Iterations int
+	Delay      time.Duration
+	// Gateway is solicited from IPv6 address so that it updates its neighbor entry
+	Gateway string
+}
+
+// AnnouncedInterfaceAddress is an interface address announced according to its own Announcement
+type AnnouncedInterfaceAddress interface {
+	boship.InterfaceAddress
+	GetAnnouncement() Announcement
+}
+
+type announcedInterfaceAddress struct {
+	boship.InterfaceAddress
+	announcement Announcement
+}
+
+func NewAnnouncedInterfaceAddress(address boship.InterfaceAddress, announcement Announcement) AnnouncedInterfaceAddress {
+	return announcedInterfaceAddress{InterfaceAddress: address, announcement: announcement}
+}
+
+func (a announcedInterfaceAddress) GetAnnouncement() Announcement {
+	return a.announcement
+}
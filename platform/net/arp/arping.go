@@ -1,11 +1,13 @@
 package arp
 
 import (
+	gonet "net"
 	"path"
 	"sync"
 	"time"
 
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -50,23 +52,48 @@ func (a arping) BroadcastMACAddresses(addresses []boship.InterfaceAddress) {
 		wg.Add(1) // Outside of goroutine
 
 		go func(address boship.InterfaceAddress) {
+			defer wg.Done()
+
+			announcement := a.announcement(address)
+			if announcement.Strategy == boshsettings.AddressAnnouncementNone {
+				return
+			}
+
 			a.blockUntilInterfaceExists(address.GetInterfaceName())
 
-			for i := 0; i < a.iterations; i++ {
-				a.broadcastMACAddress(address)
-				if i < a.iterations-1 {
+			for i := 0; i < announcement.Iterations; i++ {
+				a.broadcastMACAddress(address, announcement)
+				if i < announcement.Iterations-1 {
 					// Sleep between iterations
-					time.Sleep(a.iterationDelay)
+					time.Sleep(announcement.Delay)
 				}
 			}
-
-			wg.Done()
 		}(addr)
 	}
 
 	wg.Wait()
 }
 
+// announcement returns announcement of the address with defaults of the broadcaster filled in
+func (a arping) announcement(address boship.InterfaceAddress) Announcement {
+	announcement := Announcement{}
+	if announcedAddress, ok := address.(AnnouncedInterfaceAddress); ok {
+		announcement = announcedAddress.GetAnnouncement()
+	}
+
+	if announcement.Strategy == "" {
+		announcement.Strategy = boshsettings.AddressAnnouncementArping
+	}
+	if announcement.Iterations == 0 {
+		announcement.Iterations = a.iterations
+	}
+	if announcement.Delay == 0 {
+		announcement.Delay = a.iterationDelay
+	}
+
+	return announcement
+}
+
 // blockUntilInterfaceExists block until the specified network interface exists
 // at /sys/class/net/<interfaceName>
 func (a arping) blockUntilInterfaceExists(interfaceName string) {
@@ -77,7 +104,7 @@ func (a arping) blockUntilInterfaceExists(interfaceName string) {
 }
 
 // broadcastMACAddress broadcasts an IP/MAC pair to the specified network and logs any failure
-func (a arping) broadcastMACAddress(address boship.InterfaceAddress) {
+func (a arping) broadcastMACAddress(address boship.InterfaceAddress, announcement Announcement) {
 	ip, err := address.GetIP()
 	if err != nil {
 		a.logger.Info(arpingLogTag, "Ignoring GetIP failure: %s", err.Error())
@@ -86,6 +113,21 @@ func (a arping) broadcastMACAddress(address boship.InterfaceAddress) {
 
 	ifaceName := address.GetInterfaceName()
 
+	// ARP only exists for IPv4; IPv6 neighbors are updated via neighbor discovery
+	if parsedIP := gonet.ParseIP(ip); parsedIP != nil && parsedIP.To4() == nil {
+		if announcement.Strategy != boshsettings.AddressAnnouncementNdisc6 || announcement.Gateway == "" {
+			a.logger.Debug(arpingLogTag, "Skipping arping for IPv6 address %s on %s", ip, ifaceName)
+			return
+		}
+
+		// Solicitation carries MAC address of the source so that gateway updates its neighbor entry
+		_, _, _, err = a.cmdRunner.RunCommand("ndisc6", "-1", "-q", "-s", ip, announcement.Gateway, ifaceName)
+		if err != nil {
+			a.logger.Info(arpingLogTag, "Ignoring ndisc6 failure: %s", err.Error())
+		}
+		return
+	}
+
 	_, _, _, err = a.cmdRunner.RunCommand("arping", "-c", "1", "-U", "-I", ifaceName, ip)
 	if err != nil {
 		a.logger.Info(arpingLogTag, "Ignoring arping failure: %s", err.Error())
@@ -10,6 +10,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-agent/platform/net/arp"
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
@@ -79,5 +80,71 @@ var _ = Describe("arping", func() {
 			arping.BroadcastMACAddresses(addresses)
 			Expect(cmdRunner.RunCommands).To(BeEmpty())
 		})
+
+		It("does not run arping command for IPv6 addresses", func() {
+			addresses := []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::1234"),
+			}
+
+			arping.BroadcastMACAddresses(addresses)
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		Context("when address has its own announcement", func() {
+			It("runs arping command as many times as announcement requests", func() {
+				addresses := []boship.InterfaceAddress{
+					NewAnnouncedInterfaceAddress(
+						boship.NewSimpleInterfaceAddress("eth0", "192.168.195.6"),
+						Announcement{Strategy: boshsettings.AddressAnnouncementArping, Iterations: 2},
+					),
+				}
+
+				arping.BroadcastMACAddresses(addresses)
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"arping", "-c", "1", "-U", "-I", "eth0", "192.168.195.6"},
+					{"arping", "-c", "1", "-U", "-I", "eth0", "192.168.195.6"},
+				}))
+			})
+
+			It("does not announce address when strategy is none", func() {
+				addresses := []boship.InterfaceAddress{
+					NewAnnouncedInterfaceAddress(
+						boship.NewSimpleInterfaceAddress("eth0", "192.168.195.6"),
+						Announcement{Strategy: boshsettings.AddressAnnouncementNone},
+					),
+				}
+
+				arping.BroadcastMACAddresses(addresses)
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("solicits gateway from IPv6 address when strategy is ndisc6", func() {
+				addresses := []boship.InterfaceAddress{
+					NewAnnouncedInterfaceAddress(
+						boship.NewSimpleInterfaceAddress("eth0", "2001:db8::1234"),
+						Announcement{Strategy: boshsettings.AddressAnnouncementNdisc6, Iterations: 1, Gateway: "2001:db8::1"},
+					),
+				}
+
+				arping.BroadcastMACAddresses(addresses)
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{
+					{"ndisc6", "-1", "-q", "-s", "2001:db8::1234", "2001:db8::1", "eth0"},
+				}))
+			})
+
+			It("does not solicit anything from IPv6 address without gateway", func() {
+				addresses := []boship.InterfaceAddress{
+					NewAnnouncedInterfaceAddress(
+						boship.NewSimpleInterfaceAddress("eth0", "2001:db8::1234"),
+						Announcement{Strategy: boshsettings.AddressAnnouncementNdisc6},
+					),
+				}
+
+				arping.BroadcastMACAddresses(addresses)
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+		})
 	})
 })
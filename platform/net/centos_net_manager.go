@@ -2,6 +2,7 @@ package net
 
 import (
 	"bytes"
+	"fmt"
 	"path"
 	"strings"
 	"text/template"
@@ -59,6 +60,11 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		nonVipNetworks[networkName] = networkSettings
 	}
 
+	err := configureSRIOV(net.fs, net.cmdRunner, nonVipNetworks)
+	if err != nil {
+		return bosherr.WrapError(err, "Configuring SR-IOV virtual functions")
+	}
+
 	staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := net.buildInterfaces(nonVipNetworks)
 	if err != nil {
 		return err
@@ -72,6 +78,16 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return bosherr.WrapError(err, "Writing network configuration")
 	}
 
+	err = net.pinInterfaceNames(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return bosherr.WrapError(err, "Pinning interface names")
+	}
+
+	err = writeIPv6Sysctls(net.fs, net.cmdRunner, staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing IPv6 kernel parameters")
+	}
+
 	dhcpChanged := false
 	if len(dhcpInterfaceConfigurations) > 0 {
 		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers, dhcpInterfaceConfigurations)
@@ -84,6 +100,11 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		net.restartNetworkingInterfaces()
 	}
 
+	err = applyInterfaceTuning(net.fs, net.cmdRunner, staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying interface tuning")
+	}
+
 	staticAddresses, dynamicAddresses := net.ifaceAddresses(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
 
 	err = net.interfaceAddressesValidator.Validate(staticAddresses)
@@ -91,6 +112,14 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return bosherr.WrapError(err, "Validating static network configuration")
 	}
 
+	dynamicIPv6Addrs := dynamicIPv6Addresses(dhcpInterfaceConfigurations)
+	if len(dynamicIPv6Addrs) > 0 {
+		err = net.interfaceAddressesValidator.Validate(dynamicIPv6Addrs)
+		if err != nil {
+			return bosherr.WrapError(err, "Validating dynamic IPv6 network configuration")
+		}
+	}
+
 	err = net.dnsValidator.Validate(dnsServers)
 	if err != nil {
 		return bosherr.WrapError(err, "Validating dns configuration")
@@ -104,7 +133,7 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 func (net centosNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -119,24 +148,77 @@ func (net centosNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 }
 
 const centosDHCPIfcfgTemplate = `DEVICE={{ .Name }}
-BOOTPROTO=dhcp
+BOOTPROTO=dhcp{{ if .IsVLAN }}
+VLAN=yes
+PHYSDEV={{ .Parent }}{{ end }}{{ with .Bond }}
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="{{ bondingOpts . }}"{{ end }}{{ if .Bridge }}
+TYPE=Bridge{{ end }}{{ if .MTU }}
+MTU={{ .MTU }}{{ end }}{{ if .IPv6Mode }}
+IPV6INIT=yes
+IPV6_AUTOCONF={{ if .IsSLAAC }}yes{{ else }}no{{ end }}{{ if .IsDHCPv6 }}
+DHCPV6C=yes{{ end }}{{ end }}
 ONBOOT=yes
 PEERDNS=yes
 `
 
+// Physical interface that only carries VLAN sub-interfaces has no addresses of its own
+const centosVLANParentIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=none
+ONBOOT=yes
+`
+
+const centosBondSlaveIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=none
+MASTER={{ .Master }}
+SLAVE=yes
+ONBOOT=yes
+`
+
+const centosBridgePortIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=none
+BRIDGE={{ .Master }}
+ONBOOT=yes
+`
+
 const centosStaticIfcfgTemplate = `DEVICE={{ .Name }}
-BOOTPROTO=static
+BOOTPROTO=static{{ if .Parent }}
+VLAN=yes
+PHYSDEV={{ .Parent }}{{ end }}{{ with .Bond }}
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="{{ bondingOpts . }}"{{ end }}{{ if .Bridge }}
+TYPE=Bridge{{ end }}{{ with .IPv4 }}
 IPADDR={{ .Address }}
 NETMASK={{ .Netmask }}
 BROADCAST={{ .Broadcast }}
-GATEWAY={{ .Gateway }}
+GATEWAY={{ .Gateway }}{{ end }}{{ with .IPv6 }}
+IPV6INIT=yes
+IPV6_AUTOCONF=no
+IPV6ADDR={{ .Address }}/{{ .NetmaskOrPrefixLength }}{{ if .IsDefaultForGateway }}
+IPV6_DEFAULTGW={{ .Gateway }}{{ end }}{{ end }}{{ if .MTU }}
+MTU={{ .MTU }}{{ end }}
 ONBOOT=yes
 PEERDNS=no{{ range .DNSServers }}
 DNS{{ .Index }}={{ .Address }}{{ end }}
 `
 
+const centosRouteTemplate = `{{ range . }}{{ .Destination }}{{ if .Gateway }} via {{ .Gateway }}{{ end }} dev {{ .Interface }}{{ if .Table }} table {{ .Table }}{{ end }}
+{{ end }}`
+
+const centosRuleTemplate = `from {{ .Address }} table {{ .RoutingTable }}
+`
+
+// centosStaticIfcfg has both IPv4 and IPv6 configurations set for dual-stack interfaces
 type centosStaticIfcfg struct {
-	*StaticInterfaceConfiguration
+	Name       string
+	Parent     string
+	Bond       *BondConfiguration
+	Bridge     *BridgeConfiguration
+	MTU        int
+	IPv4       *StaticInterfaceConfiguration
+	IPv6       *StaticInterfaceConfiguration
 	DNSServers []dnsConfig
 }
 
@@ -153,10 +235,39 @@ func newDNSConfigs(dnsServers []string) []dnsConfig {
 	return dnsConfigs
 }
 
+var centosIfcfgFuncs = template.FuncMap{
+	"bondingOpts": func(bond BondConfiguration) string {
+		opts := []string{}
+		if bond.Mode != "" {
+			opts = append(opts, "mode="+bond.Mode)
+		}
+		if bond.Miimon != 0 {
+			opts = append(opts, fmt.Sprintf("miimon=%d", bond.Miimon))
+		}
+		return strings.Join(opts, " ")
+	},
+}
+
 func ifcfgFilePath(name string) string {
 	return path.Join("/etc/sysconfig/network-scripts", "ifcfg-"+name)
 }
 
+// routeFilePath returns path of route-<name> or route6-<name> file read by ifup-routes
+func routeFilePath(name string, isVersion6 bool) string {
+	if isVersion6 {
+		return path.Join("/etc/sysconfig/network-scripts", "route6-"+name)
+	}
+	return path.Join("/etc/sysconfig/network-scripts", "route-"+name)
+}
+
+// ruleFilePath returns path of rule-<name> or rule6-<name> file read by ifup-routes
+func ruleFilePath(name string, isVersion6 bool) string {
+	if isVersion6 {
+		return path.Join("/etc/sysconfig/network-scripts", "rule6-"+name)
+	}
+	return path.Join("/etc/sysconfig/network-scripts", "rule-"+name)
+}
+
 func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, config interface{}) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 
@@ -177,14 +288,30 @@ func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, co
 func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string) (bool, error) {
 	anyInterfaceChanged := false
 
-	staticConfig := centosStaticIfcfg{}
-	staticConfig.DNSServers = newDNSConfigs(dnsServers)
-	staticTemplate := template.Must(template.New("ifcfg").Parse(centosStaticIfcfgTemplate))
+	staticConfigs := []*centosStaticIfcfg{}
+	staticConfigsByName := map[string]*centosStaticIfcfg{}
 
 	for i := range staticInterfaceConfigurations {
-		staticConfig.StaticInterfaceConfiguration = &staticInterfaceConfigurations[i]
+		config := &staticInterfaceConfigurations[i]
+
+		staticConfig, found := staticConfigsByName[config.Name]
+		if !found {
+			staticConfig = &centosStaticIfcfg{Name: config.Name, Parent: config.Parent, Bond: config.Bond, Bridge: config.Bridge, MTU: config.MTU, DNSServers: newDNSConfigs(dnsServers)}
+			staticConfigsByName[config.Name] = staticConfig
+			staticConfigs = append(staticConfigs, staticConfig)
+		}
+
+		if config.IsVersion6() {
+			staticConfig.IPv6 = config
+		} else {
+			staticConfig.IPv4 = config
+		}
+	}
 
-		changed, err := net.writeIfcfgFile(staticConfig.StaticInterfaceConfiguration.Name, staticTemplate, staticConfig)
+	staticTemplate := template.Must(template.New("ifcfg").Funcs(centosIfcfgFuncs).Parse(centosStaticIfcfgTemplate))
+
+	for _, staticConfig := range staticConfigs {
+		changed, err := net.writeIfcfgFile(staticConfig.Name, staticTemplate, staticConfig)
 		if err != nil {
 			return false, bosherr.WrapError(err, "Writing static config")
 		}
@@ -192,7 +319,7 @@ func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations [
 		anyInterfaceChanged = anyInterfaceChanged || changed
 	}
 
-	dhcpTemplate := template.Must(template.New("ifcfg").Parse(centosDHCPIfcfgTemplate))
+	dhcpTemplate := template.Must(template.New("ifcfg").Funcs(centosIfcfgFuncs).Parse(centosDHCPIfcfgTemplate))
 
 	for i := range dhcpInterfaceConfigurations {
 		config := &dhcpInterfaceConfigurations[i]
@@ -205,11 +332,178 @@ func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations [
 		anyInterfaceChanged = anyInterfaceChanged || changed
 	}
 
+	vlanParentTemplate := template.Must(template.New("ifcfg").Parse(centosVLANParentIfcfgTemplate))
+
+	for _, name := range unconfiguredVLANParents(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(name, vlanParentTemplate, DHCPInterfaceConfiguration{Name: name})
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing VLAN parent config")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed
+	}
+
+	bondSlaveTemplate := template.Must(template.New("ifcfg").Parse(centosBondSlaveIfcfgTemplate))
+
+	for _, slave := range bondSlaves(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(slave.Name, bondSlaveTemplate, slave)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing bond slave config")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed
+	}
+
+	routesChanged, err := net.writeRouteFiles(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Writing route config")
+	}
+
+	anyInterfaceChanged = anyInterfaceChanged || routesChanged
+
+	rulesChanged, err := net.writeRuleFiles(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Writing routing policy config")
+	}
+
+	anyInterfaceChanged = anyInterfaceChanged || rulesChanged
+
+	bridgePortTemplate := template.Must(template.New("ifcfg").Parse(centosBridgePortIfcfgTemplate))
+
+	for _, port := range bridgePorts(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(port.Name, bridgePortTemplate, port)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing bridge port config")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed
+	}
+
 	return anyInterfaceChanged, nil
 }
 
+// writeRouteFiles writes static routes of each interface into separate files per address family
+// and removes route files of interfaces that no longer have routes
+func (net centosNetManager) writeRouteFiles(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) (bool, error) {
+	names := []string{}
+	routesByName := map[string][]RouteConfiguration{}
+
+	for _, config := range staticConfigs {
+		if _, found := routesByName[config.Name]; !found {
+			names = append(names, config.Name)
+		}
+		routesByName[config.Name] = append(routesByName[config.Name], config.Routes...)
+	}
+
+	for _, config := range dhcpConfigs {
+		if _, found := routesByName[config.Name]; !found {
+			names = append(names, config.Name)
+		}
+		routesByName[config.Name] = append(routesByName[config.Name], config.Routes...)
+	}
+
+	routeTemplate := template.Must(template.New("route").Parse(centosRouteTemplate))
+	anyChanged := false
+
+	for _, name := range names {
+		for _, isVersion6 := range []bool{false, true} {
+			routes := []RouteConfiguration{}
+			for _, route := range routesByName[name] {
+				if route.IsVersion6() == isVersion6 {
+					routes = append(routes, route)
+				}
+			}
+
+			filePath := routeFilePath(name, isVersion6)
+
+			if len(routes) == 0 {
+				if net.fs.FileExists(filePath) {
+					err := net.fs.RemoveAll(filePath)
+					if err != nil {
+						return anyChanged, bosherr.WrapErrorf(err, "Removing '%s'", filePath)
+					}
+					anyChanged = true
+				}
+				continue
+			}
+
+			buffer := bytes.NewBuffer([]byte{})
+
+			err := routeTemplate.Execute(buffer, routes)
+			if err != nil {
+				return anyChanged, bosherr.WrapErrorf(err, "Generating '%s' routes from template", name)
+			}
+
+			changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes())
+			if err != nil {
+				return anyChanged, bosherr.WrapErrorf(err, "Writing routes to '%s'", filePath)
+			}
+
+			anyChanged = anyChanged || changed
+		}
+	}
+
+	return anyChanged, nil
+}
+
+// writeRuleFiles writes policy routing rules of interfaces with their own routing tables
+// and removes rule files of other interfaces
+func (net centosNetManager) writeRuleFiles(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) (bool, error) {
+	ruleTemplate := template.Must(template.New("rule").Parse(centosRuleTemplate))
+	writtenPaths := map[string]bool{}
+	anyChanged := false
+
+	for _, config := range staticConfigs {
+		if config.RoutingTable == 0 {
+			continue
+		}
+
+		buffer := bytes.NewBuffer([]byte{})
+
+		err := ruleTemplate.Execute(buffer, config)
+		if err != nil {
+			return anyChanged, bosherr.WrapErrorf(err, "Generating '%s' rules from template", config.Name)
+		}
+
+		filePath := ruleFilePath(config.Name, config.IsVersion6())
+		changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes())
+		if err != nil {
+			return anyChanged, bosherr.WrapErrorf(err, "Writing rules to '%s'", filePath)
+		}
+
+		anyChanged = anyChanged || changed
+		writtenPaths[filePath] = true
+	}
+
+	names := []string{}
+	for _, config := range staticConfigs {
+		names = append(names, config.Name)
+	}
+	for _, config := range dhcpConfigs {
+		names = append(names, config.Name)
+	}
+
+	for _, name := range names {
+		for _, isVersion6 := range []bool{false, true} {
+			filePath := ruleFilePath(name, isVersion6)
+			if writtenPaths[filePath] || !net.fs.FileExists(filePath) {
+				continue
+			}
+
+			err := net.fs.RemoveAll(filePath)
+			if err != nil {
+				return anyChanged, bosherr.WrapErrorf(err, "Removing '%s'", filePath)
+			}
+
+			anyChanged = true
+		}
+	}
+
+	return anyChanged, nil
+}
+
 func (net centosNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -252,8 +546,12 @@ request subnet-mask, broadcast-address, time-offset, routers,
 	domain-name, domain-name-servers, domain-search, host-name,
 	netbios-name-servers, netbios-scope, interface-mtu,
 	rfc3442-classless-static-routes, ntp-servers;
-{{ if . }}
-prepend domain-name-servers {{ . }};{{ end }}
+{{ if .DNSServers }}
+prepend domain-name-servers {{ .DNSServers }};{{ end }}{{ range .DHCPConfigs }}{{ if .MTU }}
+
+interface "{{ .Name }}" {
+	supersede interface-mtu {{ .MTU }};
+}{{ end }}{{ end }}
 `
 
 func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) (bool, error) {
@@ -263,7 +561,7 @@ func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInte
 	// Keep DNS servers in the order specified by the network
 	// because they are added by a *single* DHCP's prepend command
 	dnsServersList := strings.Join(dnsServers, ", ")
-	err := t.Execute(buffer, dnsServersList)
+	err := t.Execute(buffer, dhclientConfigArg{DNSServers: dnsServersList, DHCPConfigs: dhcpInterfaceConfigurations})
 	if err != nil {
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
@@ -286,43 +584,15 @@ func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInte
 	return changed, nil
 }
 
-func (net centosNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
+func (net centosNetManager) pinInterfaceNames(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
+		return bosherr.WrapError(err, "Getting network interfaces")
 	}
 
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(path.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(path.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := path.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
+	return writeInterfaceNamesUdevRules(net.fs, interfacesByMacAddress, staticConfigs, dhcpConfigs)
 }
 
 func (net centosNetManager) ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
-	staticAddresses := []boship.InterfaceAddress{}
-	for _, iface := range staticConfigs {
-		staticAddresses = append(staticAddresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
-	}
-	dynamicAddresses := []boship.InterfaceAddress{}
-	for _, iface := range dhcpConfigs {
-		dynamicAddresses = append(dynamicAddresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
-	}
-
-	return staticAddresses, dynamicAddresses
+	return staticInterfaceAddresses(staticConfigs), dynamicInterfaceAddresses(dhcpConfigs, net.ipResolver)
 }
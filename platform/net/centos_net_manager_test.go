@@ -162,6 +162,440 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			Expect(dhcpConfig.StringContents()).To(Equal(expectedNetworkConfigurationForDHCP))
 		})
 
+		It("writes MTU into network scripts and supersedes MTU offered by dhcp server", func() {
+			dhcpNetwork.MTU = 9000
+			staticNetwork.MTU = 1400
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+MTU=1400
+ONBOOT=yes
+PEERDNS=no
+DNS1=8.8.8.8
+DNS2=9.9.9.9
+`))
+
+			dhcpConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethdhcp")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(Equal(`DEVICE=ethdhcp
+BOOTPROTO=dhcp
+MTU=9000
+ONBOOT=yes
+PEERDNS=yes
+`))
+
+			dhclientConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+			Expect(dhclientConfig).ToNot(BeNil())
+			Expect(dhclientConfig.StringContents()).To(Equal(expectedDhclientConfiguration + `
+interface "ethdhcp" {
+	supersede interface-mtu 9000;
+}
+`))
+		})
+
+		It("writes route files of interfaces with static routes", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0/8", Gateway: "1.2.3.1"},
+				{Destination: "172.16.0.0/12"},
+				{Destination: "2001:db8::/32", Gateway: "2001:db8::1"},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			fs.WriteFileString("/etc/sysconfig/network-scripts/route-ethdhcp", "fake-stale-route")
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			routeConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route-ethstatic")
+			Expect(routeConfig).ToNot(BeNil())
+			Expect(routeConfig.StringContents()).To(Equal(`10.0.0.0/8 via 1.2.3.1 dev ethstatic
+172.16.0.0/12 dev ethstatic
+`))
+
+			route6Config := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route6-ethstatic")
+			Expect(route6Config).ToNot(BeNil())
+			Expect(route6Config.StringContents()).To(Equal("2001:db8::/32 via 2001:db8::1 dev ethstatic\n"))
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethdhcp")).To(BeFalse())
+		})
+
+		It("writes policy routing rules and routing tables when multiple networks have gateways", func() {
+			secondStaticNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Gateway: "5.6.7.1",
+				Mac:     "fake-second-static-mac-address",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": staticNetwork,
+				"eth1": secondStaticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth1", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-1": staticNetwork, "static-2": secondStaticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			routeConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route-eth1")
+			Expect(routeConfig).ToNot(BeNil())
+			Expect(routeConfig.StringContents()).To(Equal(`5.6.7.0/24 dev eth1 table 101
+0.0.0.0/0 via 5.6.7.1 dev eth1 table 101
+`))
+
+			ruleConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/rule-eth0")
+			Expect(ruleConfig).ToNot(BeNil())
+			Expect(ruleConfig.StringContents()).To(Equal("from 1.2.3.4 table 100\n"))
+		})
+
+		It("writes an IPv6 network script and disables autoconfiguration for IPv6 interfaces", func() {
+			staticNetwork.IP = "2001:db8::1234"
+			staticNetwork.Netmask = "ffff:ffff:ffff:ffff::"
+			staticNetwork.Gateway = "2001:db8::1"
+			staticNetwork.Default = []string{"gateway"}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPV6INIT=yes
+IPV6_AUTOCONF=no
+IPV6ADDR=2001:db8::1234/64
+IPV6_DEFAULTGW=2001:db8::1
+ONBOOT=yes
+PEERDNS=no
+DNS1=8.8.8.8
+DNS2=9.9.9.9
+`))
+
+			sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+			Expect(sysctlConfig).ToNot(BeNil())
+			Expect(sysctlConfig.StringContents()).To(ContainSubstring("net.ipv6.conf.ethstatic.accept_ra = 0\n"))
+		})
+
+		It("writes a network script that acquires IPv6 address via DHCPv6 for dynamic interfaces", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeDHCPv6
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::10"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethdhcp")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(Equal(`DEVICE=ethdhcp
+BOOTPROTO=dhcp
+IPV6INIT=yes
+IPV6_AUTOCONF=no
+DHCPV6C=yes
+ONBOOT=yes
+PEERDNS=yes
+`))
+
+			sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+			Expect(sysctlConfig).ToNot(BeNil())
+			Expect(sysctlConfig.StringContents()).To(ContainSubstring("net.ipv6.conf.ethdhcp.autoconf = 0\nnet.ipv6.conf.ethdhcp.accept_ra = 1\n"))
+		})
+
+		It("writes a network script that autoconfigures IPv6 address for dynamic interfaces using SLAAC", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeSLAAC
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::f816:3eff:fe00:1"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethdhcp")
+			Expect(dhcpConfig).ToNot(BeNil())
+			Expect(dhcpConfig.StringContents()).To(Equal(`DEVICE=ethdhcp
+BOOTPROTO=dhcp
+IPV6INIT=yes
+IPV6_AUTOCONF=yes
+ONBOOT=yes
+PEERDNS=yes
+`))
+		})
+
+		It("writes IPv4 and IPv6 addresses of dual-stack interface into a single network script", func() {
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::1234",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     staticNetwork.Mac,
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network":   dhcpNetwork,
+				"static-network": staticNetwork,
+				"ipv6-network":   ipv6Network,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+IPV6INIT=yes
+IPV6_AUTOCONF=no
+IPV6ADDR=2001:db8::1234/64
+ONBOOT=yes
+PEERDNS=no
+DNS1=8.8.8.8
+DNS2=9.9.9.9
+`))
+		})
+
+		It("writes network scripts for VLAN sub-interfaces and their parent interface", func() {
+			vlanNetwork := boshsettings.Network{
+				Type:   "dynamic",
+				Mac:    staticNetwork.Mac,
+				VLANID: 123,
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "vlan-network": vlanNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			vlanConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic.123")
+			Expect(vlanConfig).ToNot(BeNil())
+			Expect(vlanConfig.StringContents()).To(Equal(`DEVICE=ethstatic.123
+BOOTPROTO=dhcp
+VLAN=yes
+PHYSDEV=ethstatic
+ONBOOT=yes
+PEERDNS=yes
+`))
+
+			parentConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(parentConfig).ToNot(BeNil())
+			Expect(parentConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=none
+ONBOOT=yes
+`))
+		})
+
+		It("writes network scripts for bonds and their slaves", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Bond: &boshsettings.Bond{
+					Mode:   "active-backup",
+					Miimon: 100,
+					Slaves: []string{staticNetwork.Mac, dhcpNetwork.Mac},
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bond-network": bondNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			bondConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-bond0")
+			Expect(bondConfig).ToNot(BeNil())
+			Expect(bondConfig.StringContents()).To(Equal(`DEVICE=bond0
+BOOTPROTO=static
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode=active-backup miimon=100"
+IPADDR=5.6.7.8
+NETMASK=255.255.255.0
+BROADCAST=5.6.7.255
+GATEWAY=
+ONBOOT=yes
+PEERDNS=no
+`))
+
+			slaveConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethdhcp")
+			Expect(slaveConfig).ToNot(BeNil())
+			Expect(slaveConfig.StringContents()).To(Equal(`DEVICE=ethdhcp
+BOOTPROTO=none
+MASTER=bond0
+SLAVE=yes
+ONBOOT=yes
+`))
+		})
+
+		It("writes network scripts for bridges and their ports", func() {
+			staticNetwork.Bridge = "br0"
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("br0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			bridgeConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-br0")
+			Expect(bridgeConfig).ToNot(BeNil())
+			Expect(bridgeConfig.StringContents()).To(Equal(`DEVICE=br0
+BOOTPROTO=static
+TYPE=Bridge
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+ONBOOT=yes
+PEERDNS=no
+`))
+
+			portConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(portConfig).ToNot(BeNil())
+			Expect(portConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=none
+BRIDGE=br0
+ONBOOT=yes
+`))
+		})
+
+		It("writes udev rules pinning names of bond slaves to their MAC addresses", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Bond: &boshsettings.Bond{
+					Slaves: []string{staticNetwork.Mac, dhcpNetwork.Mac},
+				},
+			}
+
+			stubInterfacesWithVirtual(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			}, []string{"lo"})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bond-network": bondNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			udevRules := fs.GetFileTestStat("/etc/udev/rules.d/70-bosh-persistent-net.rules")
+			Expect(udevRules).ToNot(BeNil())
+			Expect(udevRules.StringContents()).To(Equal(`# Generated by bosh-agent
+SUBSYSTEM=="net", ACTION=="add", DRIVERS=="?*", ATTR{address}=="fake-dhcp-mac-address", ATTR{type}=="1", NAME="ethdhcp"
+SUBSYSTEM=="net", ACTION=="add", DRIVERS=="?*", ATTR{address}=="fake-static-mac-address", ATTR{type}=="1", NAME="ethstatic"
+`))
+		})
+
+		Context("when network requests SR-IOV virtual functions", func() {
+			BeforeEach(func() {
+				staticNetwork.SRIOV = &boshsettings.SRIOV{
+					NumVFs: 2,
+					VFs:    []boshsettings.SRIOVVirtualFunction{{MAC: "cc:cc:cc:cc:cc:cc", VLANID: 100}},
+				}
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_totalvfs", "8\n")
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_numvfs", "0\n")
+			})
+
+			It("creates virtual functions on the physical function and assigns their MAC addresses and VLANs", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				numVFs, err := fs.ReadFileString("/sys/class/net/ethstatic/device/sriov_numvfs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numVFs).To(Equal("2"))
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "mac", "cc:cc:cc:cc:cc:cc"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "vlan", "100"}))
+			})
+
+			It("does not write network scripts for virtual functions", func() {
+				vfPath := writeNetworkDevice("ethvf0", "cc:cc:cc:cc:cc:cc", true)
+				fs.WriteFile("/sys/class/net/ethvf0/device/physfn", []byte{})
+				fs.SetGlob("/sys/class/net/*", []string{"/sys/class/net/ethstatic", vfPath})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/ifcfg-ethstatic")).To(BeTrue())
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/ifcfg-ethvf0")).To(BeFalse())
+			})
+
+			It("returns error if physical function does not support SR-IOV", func() {
+				fs.RemoveAll("/sys/class/net/ethstatic/device/sriov_totalvfs")
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Interface 'ethstatic' does not support SR-IOV"))
+			})
+		})
+
 		It("returns errors from glob /sys/class/net/", func() {
 			fs.GlobErr = errors.New("fs-glob-error")
 			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
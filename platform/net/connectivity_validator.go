@@ -0,0 +1,116 @@
+package net
+
+import (
+	"fmt"
+	"strings"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const connectivityValidatorLogTag = "connectivityValidator"
+
+// ConnectivityValidator checks that configured network is actually usable
+type ConnectivityValidator interface {
+	Validate(gateway, probeHostname string) error
+}
+
+// ConnectivityError reports which connectivity checks failed
+type ConnectivityError struct {
+	Gateway          string
+	GatewayReachable bool
+	ProbeHostname    string
+	ProbeResolved    bool
+}
+
+func (e ConnectivityError) Error() string {
+	failures := []string{}
+
+	if e.Gateway != "" && !e.GatewayReachable {
+		failures = append(failures, fmt.Sprintf("default gateway '%s' is not reachable", e.Gateway))
+	}
+
+	if e.ProbeHostname != "" && !e.ProbeResolved {
+		failures = append(failures, fmt.Sprintf("probe hostname '%s' cannot be resolved", e.ProbeHostname))
+	}
+
+	return fmt.Sprintf("Network is not functional: %s", strings.Join(failures, ", "))
+}
+
+type cmdConnectivityValidator struct {
+	cmdRunner boshsys.CmdRunner
+	logger    boshlog.Logger
+}
+
+func NewConnectivityValidator(cmdRunner boshsys.CmdRunner, logger boshlog.Logger) ConnectivityValidator {
+	return cmdConnectivityValidator{
+		cmdRunner: cmdRunner,
+		logger:    logger,
+	}
+}
+
+// Validate returns ConnectivityError when gateway is not reachable or probe hostname does not resolve;
+// empty gateway or probe hostname skips the corresponding check
+func (v cmdConnectivityValidator) Validate(gateway, probeHostname string) error {
+	result := ConnectivityError{
+		Gateway:       gateway,
+		ProbeHostname: probeHostname,
+	}
+
+	if gateway != "" {
+		result.GatewayReachable = v.gatewayReachable(gateway)
+	}
+
+	if probeHostname != "" {
+		result.ProbeResolved = v.hostnameResolves(probeHostname)
+	}
+
+	if (gateway != "" && !result.GatewayReachable) || (probeHostname != "" && !result.ProbeResolved) {
+		v.logger.Error(connectivityValidatorLogTag, result.Error())
+		return result
+	}
+
+	return nil
+}
+
+func (v cmdConnectivityValidator) gatewayReachable(gateway string) bool {
+	_, _, _, err := v.cmdRunner.RunCommand("ping", "-c", "3", "-W", "1", gateway)
+	if err == nil {
+		return true
+	}
+
+	// Gateways often drop ICMP, however their neighbor entry is only
+	// resolved when they answered ARP or neighbor solicitation sent by ping
+	stdout, _, _, err := v.cmdRunner.RunCommand("ip", "neigh", "show", gateway)
+	if err != nil {
+		v.logger.Warn(connectivityValidatorLogTag, "Failed to show neighbor entry of gateway '%s': %s", gateway, err.Error())
+		return false
+	}
+
+	fields := strings.Fields(stdout)
+	if len(fields) == 0 {
+		return false
+	}
+
+	switch fields[len(fields)-1] {
+	case "FAILED", "INCOMPLETE":
+		return false
+	}
+
+	for _, field := range fields {
+		if field == "lladdr" {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v cmdConnectivityValidator) hostnameResolves(hostname string) bool {
+	stdout, _, _, err := v.cmdRunner.RunCommand("getent", "hosts", hostname)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimSpace(stdout) != ""
+}
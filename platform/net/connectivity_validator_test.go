@@ -0,0 +1,88 @@
+package net_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+)
+
+var _ = Describe("ConnectivityValidator", func() {
+	var (
+		cmdRunner             *fakesys.FakeCmdRunner
+		connectivityValidator ConnectivityValidator
+	)
+
+	BeforeEach(func() {
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		connectivityValidator = NewConnectivityValidator(cmdRunner, boshlog.NewLogger(boshlog.LevelNone))
+
+		cmdRunner.AddCmdResult("getent hosts fake-probe-host", fakesys.FakeCmdResult{Stdout: "10.0.0.5      fake-probe-host\n"})
+	})
+
+	It("returns nil when gateway answers ping and probe hostname resolves", func() {
+		err := connectivityValidator.Validate("10.0.0.1", "fake-probe-host")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"ping", "-c", "3", "-W", "1", "10.0.0.1"},
+			{"getent", "hosts", "fake-probe-host"},
+		}))
+	})
+
+	It("skips checks of empty gateway and probe hostname", func() {
+		err := connectivityValidator.Validate("", "")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	Context("when gateway does not answer ping", func() {
+		BeforeEach(func() {
+			cmdRunner.AddCmdResult("ping -c 3 -W 1 10.0.0.1", fakesys.FakeCmdResult{Error: errors.New("fake-ping-err")})
+		})
+
+		It("returns nil when gateway neighbor entry is resolved", func() {
+			cmdRunner.AddCmdResult("ip neigh show 10.0.0.1", fakesys.FakeCmdResult{Stdout: "10.0.0.1 dev eth0 lladdr 52:54:00:12:34:56 REACHABLE\n"})
+
+			err := connectivityValidator.Validate("10.0.0.1", "fake-probe-host")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns connectivity error when gateway neighbor entry failed to resolve", func() {
+			cmdRunner.AddCmdResult("ip neigh show 10.0.0.1", fakesys.FakeCmdResult{Stdout: "10.0.0.1 dev eth0  FAILED\n"})
+
+			err := connectivityValidator.Validate("10.0.0.1", "fake-probe-host")
+			Expect(err).To(Equal(ConnectivityError{
+				Gateway:          "10.0.0.1",
+				GatewayReachable: false,
+				ProbeHostname:    "fake-probe-host",
+				ProbeResolved:    true,
+			}))
+			Expect(err.Error()).To(Equal("Network is not functional: default gateway '10.0.0.1' is not reachable"))
+		})
+
+		It("returns connectivity error when gateway has no neighbor entry", func() {
+			err := connectivityValidator.Validate("10.0.0.1", "")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("default gateway '10.0.0.1' is not reachable"))
+		})
+	})
+
+	It("returns connectivity error when probe hostname does not resolve", func() {
+		cmdRunner.AddCmdResult("getent hosts fake-unknown-host", fakesys.FakeCmdResult{Error: errors.New("fake-getent-err")})
+
+		err := connectivityValidator.Validate("10.0.0.1", "fake-unknown-host")
+		Expect(err).To(Equal(ConnectivityError{
+			Gateway:          "10.0.0.1",
+			GatewayReachable: true,
+			ProbeHostname:    "fake-unknown-host",
+			ProbeResolved:    false,
+		}))
+		Expect(err.Error()).To(Equal("Network is not functional: probe hostname 'fake-unknown-host' cannot be resolved"))
+	})
+})
@@ -9,6 +9,12 @@ type dnsConfigArg struct {
 	DNSServers []string
 }
 
+type dhclientConfigArg struct {
+	// DNSServers is a comma separated list of DNS servers
+	DNSServers  string
+	DHCPConfigs []DHCPInterfaceConfiguration
+}
+
 type customNetwork struct {
 	boshsettings.Network
 	Interface         string
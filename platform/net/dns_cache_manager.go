@@ -0,0 +1,189 @@
+package net
+
+import (
+	"bytes"
+	"text/template"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// DNSCacheListenAddress is written to resolv.conf instead of dns servers of the default dns network
+	DNSCacheListenAddress = "127.0.0.1"
+
+	dnsCacheManagerLogTag = "dnsCacheManager"
+
+	dnsCacheConfigPath = "/etc/bosh-dns-cache.conf"
+	dnsCacheUnitName   = "bosh-dns-cache.service"
+	dnsCacheUnitPath   = "/etc/systemd/system/" + dnsCacheUnitName
+
+	defaultDNSCacheSize = 10000
+)
+
+// Names from /etc/hosts are resolved before dns, dnsmasq only forwards to upstream servers
+const dnsCacheConfigTemplate = `# Generated by bosh-agent
+listen-address={{ .ListenAddress }}
+bind-interfaces
+no-resolv
+no-hosts
+cache-size={{ .CacheSize }}
+{{ range .Servers }}server={{ . }}
+{{ end }}`
+
+// systemd restarts dnsmasq if it exits so that resolution through the cache keeps working
+const dnsCacheUnitTemplate = `# Generated by bosh-agent
+[Unit]
+Description=DNS cache managed by bosh-agent
+Before=nss-lookup.target
+Wants=nss-lookup.target
+
+[Service]
+ExecStart=/usr/sbin/dnsmasq --keep-in-foreground --conf-file={{ .ConfigPath }}
+Restart=always
+RestartSec=1
+
+[Install]
+WantedBy=multi-user.target
+`
+
+type dnsCacheConfig struct {
+	ListenAddress string
+	CacheSize     int
+	Servers       []string
+	ConfigPath    string
+}
+
+// DNSCacheManager runs caching resolver in front of upstream dns servers
+type DNSCacheManager interface {
+	// SetupDNSCache starts dns cache forwarding to given servers
+	// or stops and removes it when dns cache is disabled
+	SetupDNSCache(dnsCache boshsettings.DNSCache, servers []string) error
+}
+
+type dnsCacheManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	logger    boshlog.Logger
+}
+
+func NewDNSCacheManager(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, logger boshlog.Logger) DNSCacheManager {
+	return dnsCacheManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		logger:    logger,
+	}
+}
+
+func (m dnsCacheManager) SetupDNSCache(dnsCache boshsettings.DNSCache, servers []string) error {
+	if !dnsCache.Enabled {
+		return m.removeDNSCache()
+	}
+
+	if !m.cmdRunner.CommandExists("dnsmasq") {
+		return bosherr.Error("dnsmasq is not installed")
+	}
+
+	if len(servers) == 0 {
+		return bosherr.Error("DNS cache requires dns servers of the default dns network")
+	}
+
+	if dnsCache.CacheSize < 0 {
+		return bosherr.Errorf("DNS cache size '%d' cannot be negative", dnsCache.CacheSize)
+	}
+
+	config := dnsCacheConfig{
+		ListenAddress: DNSCacheListenAddress,
+		CacheSize:     dnsCache.CacheSize,
+		Servers:       servers,
+		ConfigPath:    dnsCacheConfigPath,
+	}
+
+	if config.CacheSize == 0 {
+		config.CacheSize = defaultDNSCacheSize
+	}
+
+	configChanged, err := m.writeTemplate(dnsCacheConfigPath, dnsCacheConfigTemplate, config)
+	if err != nil {
+		return err
+	}
+
+	unitChanged, err := m.writeTemplate(dnsCacheUnitPath, dnsCacheUnitTemplate, config)
+	if err != nil {
+		return err
+	}
+
+	if unitChanged {
+		_, _, _, err = m.cmdRunner.RunCommand("systemctl", "daemon-reload")
+		if err != nil {
+			return bosherr.WrapError(err, "Reloading systemd units")
+		}
+
+		_, _, _, err = m.cmdRunner.RunCommand("systemctl", "enable", dnsCacheUnitName)
+		if err != nil {
+			return bosherr.WrapError(err, "Enabling dns cache")
+		}
+	}
+
+	// Starting is a no-op when dns cache is already running
+	action := "start"
+	if configChanged || unitChanged {
+		action = "restart"
+	}
+
+	m.logger.Debug(dnsCacheManagerLogTag, "Running systemctl %s %s", action, dnsCacheUnitName)
+
+	_, _, _, err = m.cmdRunner.RunCommand("systemctl", action, dnsCacheUnitName)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Running systemctl %s %s", action, dnsCacheUnitName)
+	}
+
+	return nil
+}
+
+func (m dnsCacheManager) removeDNSCache() error {
+	if !m.fs.FileExists(dnsCacheUnitPath) {
+		return nil
+	}
+
+	m.logger.Info(dnsCacheManagerLogTag, "Removing disabled dns cache")
+
+	_, _, _, err := m.cmdRunner.RunCommand("systemctl", "disable", "--now", dnsCacheUnitName)
+	if err != nil {
+		return bosherr.WrapError(err, "Disabling dns cache")
+	}
+
+	for _, path := range []string{dnsCacheUnitPath, dnsCacheConfigPath} {
+		err = m.fs.RemoveAll(path)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", path)
+		}
+	}
+
+	_, _, _, err = m.cmdRunner.RunCommand("systemctl", "daemon-reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd units")
+	}
+
+	return nil
+}
+
+func (m dnsCacheManager) writeTemplate(path, templateText string, config dnsCacheConfig) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("dns-cache").Parse(templateText))
+
+	err := t.Execute(buffer, config)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := m.fs.ConvergeFileContents(path, buffer.Bytes())
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Writing to %s", path)
+	}
+
+	return changed, nil
+}
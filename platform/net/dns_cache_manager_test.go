@@ -0,0 +1,123 @@
+package net_test
+
+import (
+	"errors"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+var _ = Describe("DNSCacheManager", func() {
+	var (
+		fs              *fakesys.FakeFileSystem
+		cmdRunner       *fakesys.FakeCmdRunner
+		dnsCacheManager DNSCacheManager
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		cmdRunner.AvailableCommands = map[string]bool{"dnsmasq": true}
+		dnsCacheManager = NewDNSCacheManager(fs, cmdRunner, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("writes dnsmasq configuration and unit and starts dns cache", func() {
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, []string{"8.8.8.8", "9.9.9.9"})
+		Expect(err).ToNot(HaveOccurred())
+
+		config, err := fs.ReadFileString("/etc/bosh-dns-cache.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(Equal(`# Generated by bosh-agent
+listen-address=127.0.0.1
+bind-interfaces
+no-resolv
+no-hosts
+cache-size=10000
+server=8.8.8.8
+server=9.9.9.9
+`))
+
+		unit, err := fs.ReadFileString("/etc/systemd/system/bosh-dns-cache.service")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(unit).To(ContainSubstring("ExecStart=/usr/sbin/dnsmasq --keep-in-foreground --conf-file=/etc/bosh-dns-cache.conf\n"))
+		Expect(unit).To(ContainSubstring("Restart=always\n"))
+
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"systemctl", "daemon-reload"},
+			{"systemctl", "enable", "bosh-dns-cache.service"},
+			{"systemctl", "restart", "bosh-dns-cache.service"},
+		}))
+	})
+
+	It("uses cache size from settings", func() {
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true, CacheSize: 500}, []string{"8.8.8.8"})
+		Expect(err).ToNot(HaveOccurred())
+
+		config, err := fs.ReadFileString("/etc/bosh-dns-cache.conf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(config).To(ContainSubstring("cache-size=500\n"))
+	})
+
+	It("only makes sure that dns cache is running when configuration did not change", func() {
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, []string{"8.8.8.8"})
+		Expect(err).ToNot(HaveOccurred())
+
+		cmdRunner.RunCommands = [][]string{}
+
+		err = dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, []string{"8.8.8.8"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"systemctl", "start", "bosh-dns-cache.service"},
+		}))
+	})
+
+	It("stops and removes dns cache when it is disabled", func() {
+		fs.WriteFileString("/etc/systemd/system/bosh-dns-cache.service", "fake-unit")
+		fs.WriteFileString("/etc/bosh-dns-cache.conf", "fake-config")
+
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{}, []string{"8.8.8.8"})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.FileExists("/etc/systemd/system/bosh-dns-cache.service")).To(BeFalse())
+		Expect(fs.FileExists("/etc/bosh-dns-cache.conf")).To(BeFalse())
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"systemctl", "disable", "--now", "bosh-dns-cache.service"},
+			{"systemctl", "daemon-reload"},
+		}))
+	})
+
+	It("does nothing when dns cache is disabled and was never set up", func() {
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{}, []string{"8.8.8.8"})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	It("returns an error when dnsmasq is not installed", func() {
+		cmdRunner.AvailableCommands = map[string]bool{}
+
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, []string{"8.8.8.8"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("dnsmasq is not installed"))
+	})
+
+	It("returns an error when there are no dns servers to forward to", func() {
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, nil)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("DNS cache requires dns servers of the default dns network"))
+	})
+
+	It("returns an error when starting dns cache fails", func() {
+		cmdRunner.AddCmdResult("systemctl restart bosh-dns-cache.service", fakesys.FakeCmdResult{Error: errors.New("fake-systemctl-err")})
+
+		err := dnsCacheManager.SetupDNSCache(boshsettings.DNSCache{Enabled: true}, []string{"8.8.8.8"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Running systemctl restart bosh-dns-cache.service: fake-systemctl-err"))
+	})
+})
@@ -12,12 +12,21 @@ type DNSValidator interface {
 }
 
 type dnsValidator struct {
-	fs boshsys.FileSystem
+	fs             boshsys.FileSystem
+	resolvConfPath string
 }
 
 func NewDNSValidator(fs boshsys.FileSystem) DNSValidator {
+	return NewResolvConfDNSValidator(fs, "/etc/resolv.conf")
+}
+
+// NewResolvConfDNSValidator returns validator that looks for dns servers in given
+// resolv.conf, e.g. /run/systemd/resolve/resolv.conf that lists upstream servers
+// when /etc/resolv.conf only points to systemd-resolved stub resolver
+func NewResolvConfDNSValidator(fs boshsys.FileSystem, resolvConfPath string) DNSValidator {
 	return &dnsValidator{
-		fs: fs,
+		fs:             fs,
+		resolvConfPath: resolvConfPath,
 	}
 }
 
@@ -26,9 +35,9 @@ func (d *dnsValidator) Validate(dnsServers []string) error {
 		return nil
 	}
 
-	resolvConfContents, err := d.fs.ReadFileString("/etc/resolv.conf")
+	resolvConfContents, err := d.fs.ReadFileString(d.resolvConfPath)
 	if err != nil {
-		return bosherr.WrapError(err, "Reading /etc/resolv.conf")
+		return bosherr.WrapErrorf(err, "Reading %s", d.resolvConfPath)
 	}
 
 	for _, dnsServer := range dnsServers {
@@ -37,5 +46,45 @@ func (d *dnsValidator) Validate(dnsServers []string) error {
 		}
 	}
 
-	return bosherr.WrapError(err, "No specified dns servers found in /etc/resolv.conf")
+	return bosherr.WrapErrorf(err, "No specified dns servers found in %s", d.resolvConfPath)
+}
+
+type resolvedDNSValidator struct {
+	cmdRunner boshsys.CmdRunner
+}
+
+// NewResolvedDNSValidator returns validator that looks for dns servers
+// in global and per-link configuration effectively used by systemd-resolved
+func NewResolvedDNSValidator(cmdRunner boshsys.CmdRunner) DNSValidator {
+	return resolvedDNSValidator{cmdRunner: cmdRunner}
+}
+
+func (d resolvedDNSValidator) Validate(dnsServers []string) error {
+	if len(dnsServers) == 0 {
+		return nil
+	}
+
+	// resolvectl replaced systemd-resolve in systemd 239
+	cmd := []string{"resolvectl", "dns"}
+	if !d.cmdRunner.CommandExists("resolvectl") {
+		cmd = []string{"systemd-resolve", "--status"}
+	}
+
+	stdout, _, _, err := d.cmdRunner.RunCommand(cmd[0], cmd[1:]...)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting systemd-resolved dns servers")
+	}
+
+	resolvedServers := map[string]bool{}
+	for _, field := range strings.Fields(stdout) {
+		resolvedServers[field] = true
+	}
+
+	for _, dnsServer := range dnsServers {
+		if resolvedServers[dnsServer] {
+			return nil
+		}
+	}
+
+	return bosherr.Error("No specified dns servers found in systemd-resolved configuration")
 }
@@ -1,6 +1,8 @@
 package net_test
 
 import (
+	"errors"
+
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 
 	. "github.com/onsi/ginkgo"
@@ -52,4 +54,94 @@ var _ = Describe("DNSValidator", func() {
 			Expect(err.Error()).To(ContainSubstring("No specified dns servers found in /etc/resolv.conf"))
 		})
 	})
+
+	Context("when validating against other resolv.conf", func() {
+		BeforeEach(func() {
+			dnsValidator = NewResolvConfDNSValidator(fs, "/run/systemd/resolve/resolv.conf")
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.53")
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 8.8.8.8")
+		})
+
+		It("looks for dns servers in that resolv.conf", func() {
+			err := dnsValidator.Validate([]string{"8.8.8.8"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = dnsValidator.Validate([]string{"9.9.9.9"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No specified dns servers found in /run/systemd/resolve/resolv.conf"))
+		})
+	})
+})
+
+var _ = Describe("ResolvedDNSValidator", func() {
+	var (
+		dnsValidator DNSValidator
+		cmdRunner    *fakesys.FakeCmdRunner
+	)
+
+	BeforeEach(func() {
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		cmdRunner.CommandExistsValue = true
+		dnsValidator = NewResolvedDNSValidator(cmdRunner)
+	})
+
+	It("does not query systemd-resolved when there are no dns servers", func() {
+		err := dnsValidator.Validate([]string{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	Context("when systemd-resolved uses at least one dns server", func() {
+		BeforeEach(func() {
+			cmdRunner.AddCmdResult("resolvectl dns", fakesys.FakeCmdResult{
+				Stdout: "Global:\nLink 2 (eth0): 8.8.8.8 9.9.9.9\nLink 3 (eth1):\n",
+			})
+		})
+
+		It("returns nil", func() {
+			err := dnsValidator.Validate([]string{"10.10.10.10", "9.9.9.9"})
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Context("when systemd-resolved does not use specified dns servers", func() {
+		BeforeEach(func() {
+			cmdRunner.AddCmdResult("resolvectl dns", fakesys.FakeCmdResult{
+				Stdout: "Global: 8.8.8.88\nLink 2 (eth0):\n",
+			})
+		})
+
+		It("returns error", func() {
+			err := dnsValidator.Validate([]string{"8.8.8.8"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No specified dns servers found in systemd-resolved configuration"))
+		})
+	})
+
+	Context("when resolvectl is not available", func() {
+		BeforeEach(func() {
+			cmdRunner.CommandExistsValue = false
+			cmdRunner.AddCmdResult("systemd-resolve --status", fakesys.FakeCmdResult{
+				Stdout: "Link 2 (eth0)\n      Current Scopes: DNS\n         DNS Servers: 8.8.8.8\n",
+			})
+		})
+
+		It("looks for dns servers in systemd-resolve status", func() {
+			err := dnsValidator.Validate([]string{"8.8.8.8"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemd-resolve", "--status"}}))
+		})
+	})
+
+	Context("when querying systemd-resolved fails", func() {
+		BeforeEach(func() {
+			cmdRunner.AddCmdResult("resolvectl dns", fakesys.FakeCmdResult{Error: errors.New("fake-resolvectl-err")})
+		})
+
+		It("returns error", func() {
+			err := dnsValidator.Validate([]string{"8.8.8.8"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Getting systemd-resolved dns servers: fake-resolvectl-err"))
+		})
+	})
 })
@@ -0,0 +1,19 @@
+package net
+
+import (
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+)
+
+// dynamicIPv6Addresses returns addresses that interfaces acquire via DHCPv6 or SLAAC;
+// they are not known upfront hence only presence of a stable address is validated
+func dynamicIPv6Addresses(dhcpConfigs []DHCPInterfaceConfiguration) []boship.InterfaceAddress {
+	addresses := []boship.InterfaceAddress{}
+
+	for _, dhcpConfig := range dhcpConfigs {
+		if dhcpConfig.IPv6Mode != "" {
+			addresses = append(addresses, boship.NewStableIPv6InterfaceAddress(dhcpConfig.Name))
+		}
+	}
+
+	return addresses
+}
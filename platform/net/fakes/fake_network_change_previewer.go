@@ -0,0 +1,17 @@
+package fakes
+
+import (
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type FakeNetworkChangePreviewer struct {
+	PreviewNetworkingNetworks boshsettings.Networks
+	PreviewNetworkingChanges  boshnet.NetworkChanges
+	PreviewNetworkingErr      error
+}
+
+func (p *FakeNetworkChangePreviewer) PreviewNetworking(networks boshsettings.Networks) (boshnet.NetworkChanges, error) {
+	p.PreviewNetworkingNetworks = networks
+	return p.PreviewNetworkingChanges, p.PreviewNetworkingErr
+}
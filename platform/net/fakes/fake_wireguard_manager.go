@@ -0,0 +1,17 @@
+package fakes
+
+import (
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+type FakeWireGuardManager struct {
+	SetupWireGuardCalled   bool
+	SetupWireGuardNetworks boshsettings.Networks
+	SetupWireGuardErr      error
+}
+
+func (m *FakeWireGuardManager) SetupWireGuard(networks boshsettings.Networks) error {
+	m.SetupWireGuardCalled = true
+	m.SetupWireGuardNetworks = networks
+	return m.SetupWireGuardErr
+}
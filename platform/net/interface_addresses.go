@@ -0,0 +1,53 @@
+package net
+
+import (
+	"time"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+// staticInterfaceAddresses returns configured addresses of static interfaces
+func staticInterfaceAddresses(staticConfigs []StaticInterfaceConfiguration) []boship.InterfaceAddress {
+	addresses := []boship.InterfaceAddress{}
+
+	for _, staticConfig := range staticConfigs {
+		var gateway string
+		if staticConfig.IsVersion6() {
+			gateway = staticConfig.Gateway
+		}
+
+		address := boship.NewSimpleInterfaceAddress(staticConfig.Name, staticConfig.Address)
+		addresses = append(addresses, announcedInterfaceAddress(address, staticConfig.AddressAnnouncement, gateway))
+	}
+
+	return addresses
+}
+
+// dynamicInterfaceAddresses returns addresses that dynamic interfaces acquire via DHCP
+func dynamicInterfaceAddresses(dhcpConfigs []DHCPInterfaceConfiguration, ipResolver boship.Resolver) []boship.InterfaceAddress {
+	addresses := []boship.InterfaceAddress{}
+
+	for _, dhcpConfig := range dhcpConfigs {
+		address := boship.NewResolvingInterfaceAddress(dhcpConfig.Name, ipResolver)
+		addresses = append(addresses, announcedInterfaceAddress(address, dhcpConfig.AddressAnnouncement, ""))
+	}
+
+	return addresses
+}
+
+// announcedInterfaceAddress attaches address announcement of the network to the address
+// unless network announces its addresses with defaults of the broadcaster
+func announcedInterfaceAddress(address boship.InterfaceAddress, announcement boshsettings.AddressAnnouncement, gateway string) boship.InterfaceAddress {
+	if announcement.IsEmpty() {
+		return address
+	}
+
+	return bosharp.NewAnnouncedInterfaceAddress(address, bosharp.Announcement{
+		Strategy:   announcement.Strategy,
+		Iterations: announcement.Iterations,
+		Delay:      time.Duration(announcement.Delay) * time.Millisecond,
+		Gateway:    gateway,
+	})
+}
@@ -1,12 +1,34 @@
 package net
 
 import (
+	"fmt"
+	gonet "net"
+	"sort"
+	"strconv"
+	"strings"
+
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
+// maxVLANID is the largest VLAN ID that can be used (IEEE 802.1Q reserves 4095)
+const maxVLANID = 4094
+
+// maxInterfaceNameLength is the longest interface name accepted by the kernel (IFNAMSIZ - 1)
+const maxInterfaceNameLength = 15
+
+// firstRoutingTable is the first routing table used for policy routing
+// (tables 253-255 are reserved by the kernel)
+const firstRoutingTable = 100
+
+// minMTU and maxMTU bound MTU accepted by the kernel for IPv4 interfaces
+const (
+	minMTU = 68
+	maxMTU = 65535
+)
+
 type StaticInterfaceConfiguration struct {
 	Name                string
 	Address             string
@@ -16,6 +38,69 @@ type StaticInterfaceConfiguration struct {
 	IsDefaultForGateway bool
 	Mac                 string
 	Gateway             string
+	VLANID              int
+	// Parent is a name of the physical interface that carries VLAN sub-interface
+	Parent string
+	Bond   *BondConfiguration
+	Bridge *BridgeConfiguration
+	// MTU is left unset (0) to keep MTU of the interface as is
+	MTU    int
+	Routes []RouteConfiguration
+	// RoutingTable is set when multiple interfaces have gateways so that
+	// traffic from Address is routed by its own table through its own gateway
+	RoutingTable int
+	// AddressAnnouncement is left empty to announce Address with defaults of the broadcaster
+	AddressAnnouncement boshsettings.AddressAnnouncement
+	Tuning              boshsettings.InterfaceTuning
+}
+
+// RouteConfiguration is a static route to Destination network (in CIDR notation) through Interface
+type RouteConfiguration struct {
+	Destination string
+	Gateway     string
+	Interface   string
+	// Table is left unset (0) for routes in the main routing table
+	Table int
+}
+
+// IsVersion6 returns true when route is to IPv6 network
+func (r RouteConfiguration) IsVersion6() bool {
+	return strings.Contains(r.Destination, ":")
+}
+
+// BondConfiguration describes bonded interface created over physical interfaces
+type BondConfiguration struct {
+	Mode   string
+	Miimon int
+	// Slaves are names of bonded physical interfaces
+	Slaves []string
+}
+
+// BridgeConfiguration describes Linux bridge that carries address of the interfaces attached to it
+type BridgeConfiguration struct {
+	// Ports are names of interfaces attached to the bridge
+	Ports []string
+}
+
+// IsVersion6 returns true when interface is configured with an IPv6 address
+func (c StaticInterfaceConfiguration) IsVersion6() bool {
+	return isIPv6(c.Address)
+}
+
+// NetmaskOrPrefixLength returns dotted netmask for IPv4 interfaces
+// and prefix length for IPv6 interfaces (e.g. 64)
+// since IPv6 netmasks are not understood by most network configuration tools
+func (c StaticInterfaceConfiguration) NetmaskOrPrefixLength() string {
+	if !c.IsVersion6() {
+		return c.Netmask
+	}
+
+	prefixLength, err := netmaskPrefixLength(c.Netmask)
+	if err != nil {
+		return c.Netmask
+	}
+
+	return strconv.Itoa(prefixLength)
 }
 
 type StaticInterfaceConfigurations []StaticInterfaceConfiguration
@@ -24,16 +109,52 @@ func (configs StaticInterfaceConfigurations) Len() int {
 	return len(configs)
 }
 
+// Less orders configurations by interface name and
+// IPv4 configuration before IPv6 configuration of the same dual-stack interface
 func (configs StaticInterfaceConfigurations) Less(i, j int) bool {
-	return configs[i].Name < configs[j].Name
+	if configs[i].Name != configs[j].Name {
+		return configs[i].Name < configs[j].Name
+	}
+	return !configs[i].IsVersion6() && configs[j].IsVersion6()
 }
 
 func (configs StaticInterfaceConfigurations) Swap(i, j int) {
 	configs[i], configs[j] = configs[j], configs[i]
 }
 
+// IsVLAN returns true when interface is a tagged VLAN sub-interface (e.g. eth0.123)
+func (c StaticInterfaceConfiguration) IsVLAN() bool {
+	return c.VLANID != 0
+}
+
 type DHCPInterfaceConfiguration struct {
-	Name string
+	Name   string
+	VLANID int
+	Parent string
+	Bond   *BondConfiguration
+	Bridge *BridgeConfiguration
+	MTU    int
+	Routes []RouteConfiguration
+	// IPv6Mode is empty when interface only acquires IPv4 address
+	IPv6Mode boshsettings.IPv6Mode
+	// AddressAnnouncement is left empty to announce acquired address with defaults of the broadcaster
+	AddressAnnouncement boshsettings.AddressAnnouncement
+	Tuning              boshsettings.InterfaceTuning
+}
+
+// IsVLAN returns true when interface is a tagged VLAN sub-interface (e.g. eth0.123)
+func (c DHCPInterfaceConfiguration) IsVLAN() bool {
+	return c.VLANID != 0
+}
+
+// IsDHCPv6 returns true when interface acquires IPv6 address from DHCPv6 server
+func (c DHCPInterfaceConfiguration) IsDHCPv6() bool {
+	return c.IPv6Mode == boshsettings.IPv6ModeDHCPv6
+}
+
+// IsSLAAC returns true when interface autoconfigures IPv6 address from router advertisements
+func (c DHCPInterfaceConfiguration) IsSLAAC() bool {
+	return c.IPv6Mode == boshsettings.IPv6ModeSLAAC
 }
 
 type DHCPInterfaceConfigurations []DHCPInterfaceConfiguration
@@ -69,16 +190,103 @@ func NewInterfaceConfigurationCreator(logger boshlog.Logger) InterfaceConfigurat
 func (creator interfaceConfigurationCreator) createInterfaceConfiguration(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration, ifaceName string, networkSettings boshsettings.Network) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	creator.logger.Debug(creator.logTag, "Creating network configuration with settings: %s", networkSettings)
 
-	if networkSettings.IsDHCP() || networkSettings.Mac == "" {
+	var parentName string
+
+	if networkSettings.VLANID < 0 || networkSettings.VLANID > maxVLANID {
+		return nil, nil, bosherr.Errorf("VLAN ID '%d' is out of range 1-%d", networkSettings.VLANID, maxVLANID)
+	}
+
+	if networkSettings.VLANID != 0 {
+		creator.logger.Debug(creator.logTag, "Using VLAN '%d' sub-interface of '%s'", networkSettings.VLANID, ifaceName)
+		parentName = ifaceName
+		ifaceName = vlanInterfaceName(parentName, networkSettings.VLANID)
+	}
+
+	if networkSettings.MTU != 0 && (networkSettings.MTU < minMTU || networkSettings.MTU > maxMTU) {
+		return nil, nil, bosherr.Errorf("MTU '%d' is out of range %d-%d", networkSettings.MTU, minMTU, maxMTU)
+	}
+
+	var bridge *BridgeConfiguration
+
+	if networkSettings.Bridge != "" {
+		if networkSettings.VLANID != 0 {
+			return nil, nil, bosherr.Errorf("Bridge '%s' cannot be tagged with VLAN", networkSettings.Bridge)
+		}
+
+		if len(networkSettings.Bridge) > maxInterfaceNameLength {
+			return nil, nil, bosherr.Errorf("Bridge name '%s' is longer than %d characters", networkSettings.Bridge, maxInterfaceNameLength)
+		}
+
+		creator.logger.Debug(creator.logTag, "Using bridge '%s' with '%s' attached", networkSettings.Bridge, ifaceName)
+		bridge = &BridgeConfiguration{Ports: []string{ifaceName}}
+		ifaceName = networkSettings.Bridge
+	}
+
+	routes, err := routeConfigurations(ifaceName, networkSettings.Routes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch networkSettings.IPv6Mode {
+	case "", boshsettings.IPv6ModeDHCPv6, boshsettings.IPv6ModeSLAAC:
+	default:
+		return nil, nil, bosherr.Errorf("IPv6 mode '%s' is not supported, expected %s or %s", networkSettings.IPv6Mode, boshsettings.IPv6ModeDHCPv6, boshsettings.IPv6ModeSLAAC)
+	}
+
+	switch networkSettings.AddressAnnouncement.Strategy {
+	case "", boshsettings.AddressAnnouncementArping, boshsettings.AddressAnnouncementNdisc6, boshsettings.AddressAnnouncementNone:
+	default:
+		return nil, nil, bosherr.Errorf("Address announcement strategy '%s' is not supported, expected %s, %s or %s", networkSettings.AddressAnnouncement.Strategy,
+			boshsettings.AddressAnnouncementArping, boshsettings.AddressAnnouncementNdisc6, boshsettings.AddressAnnouncementNone)
+	}
+
+	if networkSettings.AddressAnnouncement.Iterations < 0 || networkSettings.AddressAnnouncement.Delay < 0 {
+		return nil, nil, bosherr.Error("Address announcement iterations and delay cannot be negative")
+	}
+
+	if rpFilter := networkSettings.Tuning.RPFilter; rpFilter != nil && (*rpFilter < 0 || *rpFilter > 2) {
+		return nil, nil, bosherr.Errorf("Reverse path filter '%d' is not supported, expected 0, 1 or 2", *rpFilter)
+	}
+
+	if networkSettings.Tuning.TxQueueLen < 0 {
+		return nil, nil, bosherr.Errorf("Transmit queue length '%d' cannot be negative", networkSettings.Tuning.TxQueueLen)
+	}
+
+	isDHCP := networkSettings.IsDHCP() || networkSettings.Mac == ""
+
+	if networkSettings.IPv6Mode != "" && !isDHCP {
+		return nil, nil, bosherr.Errorf("IPv6 mode '%s' can only be used with dynamic networks", networkSettings.IPv6Mode)
+	}
+
+	if isDHCP {
 		creator.logger.Debug(creator.logTag, "Using dhcp networking")
 		dhcpConfigs = append(dhcpConfigs, DHCPInterfaceConfiguration{
-			Name: ifaceName,
+			Name:                ifaceName,
+			VLANID:              networkSettings.VLANID,
+			Parent:              parentName,
+			Bridge:              bridge,
+			MTU:                 networkSettings.MTU,
+			Routes:              routes,
+			IPv6Mode:            networkSettings.IPv6Mode,
+			AddressAnnouncement: networkSettings.AddressAnnouncement,
+			Tuning:              networkSettings.Tuning,
 		})
 	} else {
 		creator.logger.Debug(creator.logTag, "Using static networking")
-		networkAddress, broadcastAddress, err := boshsys.CalculateNetworkAndBroadcast(networkSettings.IP, networkSettings.Netmask)
-		if err != nil {
-			return nil, nil, bosherr.WrapError(err, "Calculating Network and Broadcast")
+
+		var networkAddress, broadcastAddress string
+
+		if isIPv6(networkSettings.IP) {
+			// IPv6 has no broadcast addresses
+			networkAddress, err = calculateNetwork6(networkSettings.IP, networkSettings.Netmask)
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Calculating IPv6 Network")
+			}
+		} else {
+			networkAddress, broadcastAddress, err = boshsys.CalculateNetworkAndBroadcast(networkSettings.IP, networkSettings.Netmask)
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Calculating Network and Broadcast")
+			}
 		}
 
 		staticConfigs = append(staticConfigs, StaticInterfaceConfiguration{
@@ -90,12 +298,129 @@ func (creator interfaceConfigurationCreator) createInterfaceConfiguration(static
 			Broadcast:           broadcastAddress,
 			Mac:                 networkSettings.Mac,
 			Gateway:             networkSettings.Gateway,
+			VLANID:              networkSettings.VLANID,
+			Parent:              parentName,
+			Bridge:              bridge,
+			MTU:                 networkSettings.MTU,
+			Routes:              routes,
+			AddressAnnouncement: networkSettings.AddressAnnouncement,
+			Tuning:              networkSettings.Tuning,
 		})
 	}
 	return staticConfigs, dhcpConfigs, nil
 }
 
 func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
+	bondStaticConfigs, bondDHCPConfigs, networks, interfacesByMAC, err := creator.createBondInterfaceConfigurations(networks, interfacesByMAC)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Creating bond interface configurations")
+	}
+
+	staticConfigs, dhcpConfigs, err := creator.createUnbondedInterfaceConfigurations(networks, interfacesByMAC)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	staticConfigs = append(staticConfigs, bondStaticConfigs...)
+	dhcpConfigs = append(dhcpConfigs, bondDHCPConfigs...)
+
+	err = moveRoutesToInterfaces(staticConfigs, dhcpConfigs)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Configuring routes")
+	}
+
+	err = creator.configurePolicyRouting(staticConfigs)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Configuring policy routing")
+	}
+
+	return staticConfigs, dhcpConfigs, nil
+}
+
+// createBondInterfaceConfigurations configures networks with bond settings on bonded interfaces (bond0, bond1, ...)
+// and returns remaining networks and interfaces that are not bonded
+func (creator interfaceConfigurationCreator) createBondInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, boshsettings.Networks, map[string]string, error) {
+	bondNetworkNames := []string{}
+	unbondedNetworks := boshsettings.Networks{}
+
+	for name, networkSettings := range networks {
+		if networkSettings.Bond != nil {
+			bondNetworkNames = append(bondNetworkNames, name)
+		} else {
+			unbondedNetworks[name] = networkSettings
+		}
+	}
+
+	if len(bondNetworkNames) == 0 {
+		return nil, nil, networks, interfacesByMAC, nil
+	}
+
+	sort.Strings(bondNetworkNames)
+
+	unbondedInterfacesByMAC := map[string]string{}
+	for mac, ifaceName := range interfacesByMAC {
+		unbondedInterfacesByMAC[mac] = ifaceName
+	}
+
+	var staticConfigs []StaticInterfaceConfiguration
+	var dhcpConfigs []DHCPInterfaceConfiguration
+	var err error
+
+	for i, name := range bondNetworkNames {
+		networkSettings := networks[name]
+
+		if networkSettings.VLANID != 0 {
+			return nil, nil, nil, nil, bosherr.Errorf("Network '%s' cannot be both bonded and tagged with VLAN", name)
+		}
+
+		if networkSettings.Bridge != "" {
+			return nil, nil, nil, nil, bosherr.Errorf("Network '%s' cannot be both bonded and bridged", name)
+		}
+
+		if len(networkSettings.Bond.Slaves) == 0 {
+			return nil, nil, nil, nil, bosherr.Errorf("Bonded network '%s' has no slaves", name)
+		}
+
+		bond := &BondConfiguration{
+			Mode:   networkSettings.Bond.Mode,
+			Miimon: networkSettings.Bond.Miimon,
+		}
+
+		for _, slaveMAC := range networkSettings.Bond.Slaves {
+			slaveName, found := unbondedInterfacesByMAC[slaveMAC]
+			if !found {
+				return nil, nil, nil, nil, bosherr.Errorf("No unbonded device found for slave of network '%s' with MAC address '%s'", name, slaveMAC)
+			}
+
+			bond.Slaves = append(bond.Slaves, slaveName)
+			delete(unbondedInterfacesByMAC, slaveMAC)
+		}
+
+		// Bond takes MAC address of its first slave
+		if networkSettings.Mac == "" {
+			networkSettings.Mac = networkSettings.Bond.Slaves[0]
+		}
+
+		bondName := fmt.Sprintf("bond%d", i)
+
+		staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, bondName, networkSettings)
+		if err != nil {
+			return nil, nil, nil, nil, err
+		}
+
+		if n := len(staticConfigs); n > 0 && staticConfigs[n-1].Name == bondName {
+			staticConfigs[n-1].Bond = bond
+		}
+
+		if n := len(dhcpConfigs); n > 0 && dhcpConfigs[n-1].Name == bondName {
+			dhcpConfigs[n-1].Bond = bond
+		}
+	}
+
+	return staticConfigs, dhcpConfigs, unbondedNetworks, unbondedInterfacesByMAC, nil
+}
+
+func (creator interfaceConfigurationCreator) createUnbondedInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	// In cases where we only have one network and it has no MAC address (either because the IAAS doesn't give us one or
 	// it's an old CPI), if we only have one interface, we should map them
 	if len(networks) == 1 && len(interfacesByMAC) == 1 {
@@ -111,8 +436,8 @@ func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(netwo
 }
 
 func (creator interfaceConfigurationCreator) createMultipleInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	if len(interfacesByMAC) < len(networks) {
-		return nil, nil, bosherr.Errorf("Number of network settings '%d' is greater than the number of network devices '%d'", len(networks), len(interfacesByMAC))
+	if requiredInterfaces := creator.requiredInterfaceCount(networks); len(interfacesByMAC) < requiredInterfaces {
+		return nil, nil, bosherr.Errorf("Number of network settings '%d' is greater than the number of network devices '%d'", requiredInterfaces, len(interfacesByMAC))
 	}
 
 	for name := range networks {
@@ -131,16 +456,124 @@ func (creator interfaceConfigurationCreator) createMultipleInterfaceConfiguratio
 	dhcpConfigs := []DHCPInterfaceConfiguration{}
 
 	for mac, ifaceName := range interfacesByMAC {
-		networkSettings, _ = networks.NetworkForMac(mac)
-		staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, ifaceName, networkSettings)
-		if err != nil {
-			return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+		macNetworks := networks.NetworksForMac(mac)
+		if len(macNetworks) == 0 {
+			networkSettings, _ = networks.NetworkForMac(mac)
+			staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, ifaceName, networkSettings)
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+			}
+			continue
+		}
+
+		// Untagged networks are configured on the interface itself
+		// and tagged networks on its VLAN sub-interfaces
+		for _, vlanNetworks := range creator.groupNetworksByVLAN(macNetworks) {
+			if len(vlanNetworks) > 1 {
+				staticConfigs, err = creator.createDualStackInterfaceConfigurations(staticConfigs, ifaceName, vlanNetworks)
+				if err != nil {
+					return nil, nil, bosherr.WrapErrorf(err, "Creating dual-stack interface configuration for MAC address '%s'", mac)
+				}
+				continue
+			}
+
+			staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, ifaceName, vlanNetworks[0])
+			if err != nil {
+				return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+			}
 		}
 	}
 
 	return staticConfigs, dhcpConfigs, nil
 }
 
+// groupNetworksByVLAN groups networks sharing the same MAC address by VLAN ID
+// with untagged networks first followed by tagged networks in VLAN ID order
+func (creator interfaceConfigurationCreator) groupNetworksByVLAN(networks []boshsettings.Network) [][]boshsettings.Network {
+	vlanIDs := []int{}
+	networksByVLAN := map[int][]boshsettings.Network{}
+
+	for _, networkSettings := range networks {
+		if _, found := networksByVLAN[networkSettings.VLANID]; !found {
+			vlanIDs = append(vlanIDs, networkSettings.VLANID)
+		}
+		networksByVLAN[networkSettings.VLANID] = append(networksByVLAN[networkSettings.VLANID], networkSettings)
+	}
+
+	sort.Ints(vlanIDs)
+
+	groups := [][]boshsettings.Network{}
+	for _, vlanID := range vlanIDs {
+		groups = append(groups, networksByVLAN[vlanID])
+	}
+
+	return groups
+}
+
+// createDualStackInterfaceConfigurations configures IPv4 and IPv6 addresses
+// of networks sharing the same MAC address on a single interface
+func (creator interfaceConfigurationCreator) createDualStackInterfaceConfigurations(staticConfigs []StaticInterfaceConfiguration, ifaceName string, networks []boshsettings.Network) ([]StaticInterfaceConfiguration, error) {
+	if len(networks) != 2 {
+		return nil, bosherr.Errorf("Expected one IPv4 and one IPv6 network, found '%d' networks", len(networks))
+	}
+
+	for _, networkSettings := range networks {
+		if networkSettings.IsDHCP() {
+			return nil, bosherr.Error("Dual-stack networks must be statically configured")
+		}
+	}
+
+	if isIPv6(networks[0].IP) == isIPv6(networks[1].IP) {
+		return nil, bosherr.Errorf("Expected one IPv4 and one IPv6 network, found '%s' and '%s'", networks[0].IP, networks[1].IP)
+	}
+
+	if networks[0].MTU != networks[1].MTU {
+		return nil, bosherr.Errorf("Expected dual-stack networks to use the same MTU, found '%d' and '%d'", networks[0].MTU, networks[1].MTU)
+	}
+
+	if networks[0].Bridge != networks[1].Bridge {
+		return nil, bosherr.Errorf("Expected dual-stack networks to use the same bridge, found '%s' and '%s'", networks[0].Bridge, networks[1].Bridge)
+	}
+
+	var err error
+
+	for _, networkSettings := range networks {
+		staticConfigs, _, err = creator.createInterfaceConfiguration(staticConfigs, nil, ifaceName, networkSettings)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Default routes are per address family hence
+	// interface that is default for gateway gets both of them
+	dualStackConfigs := staticConfigs[len(staticConfigs)-2:]
+	if dualStackConfigs[0].IsDefaultForGateway || dualStackConfigs[1].IsDefaultForGateway {
+		for i := range dualStackConfigs {
+			dualStackConfigs[i].IsDefaultForGateway = dualStackConfigs[i].Gateway != ""
+		}
+	}
+
+	return staticConfigs, nil
+}
+
+// requiredInterfaceCount counts networks that need a separate interface;
+// networks sharing a MAC address are configured on the same interface
+func (creator interfaceConfigurationCreator) requiredInterfaceCount(networks boshsettings.Networks) int {
+	count := 0
+	macs := map[string]bool{}
+
+	for _, networkSettings := range networks {
+		if networkSettings.Mac == "" {
+			count++
+		} else if !macs[networkSettings.Mac] {
+			macs[networkSettings.Mac] = true
+			count++
+		}
+	}
+
+	return count
+}
+
 func (creator interfaceConfigurationCreator) getFirstNetwork(networks boshsettings.Networks) boshsettings.Network {
 	for networkName := range networks {
 		return networks[networkName]
@@ -154,3 +587,273 @@ func (creator interfaceConfigurationCreator) getFirstInterface(interfacesByMAC m
 	}
 	return "", ""
 }
+
+func vlanInterfaceName(parentName string, vlanID int) string {
+	return fmt.Sprintf("%s.%d", parentName, vlanID)
+}
+
+// configurePolicyRouting gives each interface its own routing table when multiple interfaces
+// of the same address family have gateways so that replies leave through the interface
+// that received the request instead of the interface with the default route
+func (creator interfaceConfigurationCreator) configurePolicyRouting(staticConfigs StaticInterfaceConfigurations) error {
+	gatewayCounts := map[bool]int{}
+	for _, config := range staticConfigs {
+		if config.Gateway != "" {
+			gatewayCounts[config.IsVersion6()]++
+		}
+	}
+
+	// Tables are assigned in interface name order to keep them stable across restarts
+	indices := []int{}
+	for i, config := range staticConfigs {
+		if config.Gateway != "" && gatewayCounts[config.IsVersion6()] > 1 {
+			indices = append(indices, i)
+		}
+	}
+
+	sort.Slice(indices, func(i, j int) bool {
+		return staticConfigs.Less(indices[i], indices[j])
+	})
+
+	for n, i := range indices {
+		config := &staticConfigs[i]
+
+		prefixLength, err := netmaskPrefixLength(config.Netmask)
+		if err != nil {
+			return err
+		}
+
+		defaultDestination := "0.0.0.0/0"
+		if config.IsVersion6() {
+			defaultDestination = "::/0"
+		}
+
+		config.RoutingTable = firstRoutingTable + n
+		config.Routes = append(config.Routes,
+			RouteConfiguration{
+				Destination: fmt.Sprintf("%s/%d", config.Network, prefixLength),
+				Interface:   config.Name,
+				Table:       config.RoutingTable,
+			},
+			RouteConfiguration{
+				Destination: defaultDestination,
+				Gateway:     config.Gateway,
+				Interface:   config.Name,
+				Table:       config.RoutingTable,
+			},
+		)
+
+		creator.logger.Debug(creator.logTag, "Routing traffic from '%s' by table '%d'", config.Address, config.RoutingTable)
+	}
+
+	return nil
+}
+
+// routeConfigurations validates routes of the network and defaults their interface to ifaceName
+func routeConfigurations(ifaceName string, routes []boshsettings.Route) ([]RouteConfiguration, error) {
+	var routeConfigs []RouteConfiguration
+
+	for _, route := range routes {
+		destination, _, err := gonet.ParseCIDR(route.Destination)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing route destination '%s'", route.Destination)
+		}
+
+		if route.Gateway != "" {
+			gateway := gonet.ParseIP(route.Gateway)
+			if gateway == nil {
+				return nil, bosherr.Errorf("Parsing gateway '%s' of route to '%s'", route.Gateway, route.Destination)
+			}
+
+			if isIPv6(route.Gateway) != isIPv6(destination.String()) {
+				return nil, bosherr.Errorf("Gateway '%s' and destination '%s' of route have different address families", route.Gateway, route.Destination)
+			}
+		}
+
+		routeConfig := RouteConfiguration{
+			Destination: route.Destination,
+			Gateway:     route.Gateway,
+			Interface:   route.Interface,
+		}
+		if routeConfig.Interface == "" {
+			routeConfig.Interface = ifaceName
+		}
+
+		routeConfigs = append(routeConfigs, routeConfig)
+	}
+
+	return routeConfigs, nil
+}
+
+// moveRoutesToInterfaces moves routes that go through other interfaces than their network's
+// to configurations of those interfaces so that managers render routes together with their interface
+func moveRoutesToInterfaces(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	var movedRoutes []RouteConfiguration
+
+	for i := range staticConfigs {
+		var routes []RouteConfiguration
+		for _, route := range staticConfigs[i].Routes {
+			if route.Interface == staticConfigs[i].Name {
+				routes = append(routes, route)
+			} else {
+				movedRoutes = append(movedRoutes, route)
+			}
+		}
+		staticConfigs[i].Routes = routes
+	}
+
+	for i := range dhcpConfigs {
+		var routes []RouteConfiguration
+		for _, route := range dhcpConfigs[i].Routes {
+			if route.Interface == dhcpConfigs[i].Name {
+				routes = append(routes, route)
+			} else {
+				movedRoutes = append(movedRoutes, route)
+			}
+		}
+		dhcpConfigs[i].Routes = routes
+	}
+
+	for _, route := range movedRoutes {
+		if !addRouteToInterface(route, staticConfigs, dhcpConfigs) {
+			return bosherr.Errorf("Route to '%s' refers to interface '%s' that is not configured", route.Destination, route.Interface)
+		}
+	}
+
+	return nil
+}
+
+// addRouteToInterface prefers static configuration of the route's address family
+// since dual-stack interfaces have a configuration per address family
+func addRouteToInterface(route RouteConfiguration, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) bool {
+	target := -1
+
+	for i := range staticConfigs {
+		if staticConfigs[i].Name != route.Interface {
+			continue
+		}
+		if target == -1 || staticConfigs[i].IsVersion6() == route.IsVersion6() {
+			target = i
+		}
+	}
+
+	if target != -1 {
+		staticConfigs[target].Routes = append(staticConfigs[target].Routes, route)
+		return true
+	}
+
+	for i := range dhcpConfigs {
+		if dhcpConfigs[i].Name == route.Interface {
+			dhcpConfigs[i].Routes = append(dhcpConfigs[i].Routes, route)
+			return true
+		}
+	}
+
+	return false
+}
+
+// enslavedInterface is an interface enslaved by bond or bridge named Master
+type enslavedInterface struct {
+	Name   string
+	Master string
+}
+
+// bondSlaves returns physical interfaces of all bonds
+func bondSlaves(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) []enslavedInterface {
+	slaves := []enslavedInterface{}
+
+	for _, config := range staticConfigs {
+		if config.Bond != nil {
+			for _, name := range config.Bond.Slaves {
+				slaves = append(slaves, enslavedInterface{Name: name, Master: config.Name})
+			}
+		}
+	}
+
+	for _, config := range dhcpConfigs {
+		if config.Bond != nil {
+			for _, name := range config.Bond.Slaves {
+				slaves = append(slaves, enslavedInterface{Name: name, Master: config.Name})
+			}
+		}
+	}
+
+	return slaves
+}
+
+// bridgePorts returns interfaces attached to all bridges
+func bridgePorts(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) []enslavedInterface {
+	ports := []enslavedInterface{}
+	seen := map[string]bool{}
+
+	for _, config := range staticConfigs {
+		// Dual-stack bridges have a configuration per address family
+		if config.Bridge != nil && !seen[config.Name] {
+			seen[config.Name] = true
+			for _, name := range config.Bridge.Ports {
+				ports = append(ports, enslavedInterface{Name: name, Master: config.Name})
+			}
+		}
+	}
+
+	for _, config := range dhcpConfigs {
+		if config.Bridge != nil {
+			for _, name := range config.Bridge.Ports {
+				ports = append(ports, enslavedInterface{Name: name, Master: config.Name})
+			}
+		}
+	}
+
+	return ports
+}
+
+// unconfiguredVLANParents returns sorted names of physical interfaces
+// that carry VLAN sub-interfaces but have no untagged network configured
+func unconfiguredVLANParents(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) []string {
+	configured := map[string]bool{}
+	parents := map[string]bool{}
+
+	for _, config := range staticConfigs {
+		configured[config.Name] = true
+		if config.IsVLAN() {
+			parents[config.Parent] = true
+		}
+	}
+
+	for _, config := range dhcpConfigs {
+		configured[config.Name] = true
+		if config.IsVLAN() {
+			parents[config.Parent] = true
+		}
+	}
+
+	names := []string{}
+	for parent := range parents {
+		if !configured[parent] {
+			names = append(names, parent)
+		}
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func isIPv6(address string) bool {
+	ip := gonet.ParseIP(address)
+	return ip != nil && ip.To4() == nil
+}
+
+func calculateNetwork6(address, netmask string) (string, error) {
+	ip := gonet.ParseIP(address)
+	if ip == nil {
+		return "", bosherr.Errorf("Parsing IP address '%s'", address)
+	}
+
+	prefixLength, err := netmaskPrefixLength(netmask)
+	if err != nil {
+		return "", err
+	}
+
+	return ip.Mask(gonet.CIDRMask(prefixLength, 8*gonet.IPv6len)).String(), nil
+}
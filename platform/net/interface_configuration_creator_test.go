@@ -179,6 +179,11 @@ func describeInterfaceConfigurationCreator() {
 								IsDefaultForGateway: false,
 								Mac:                 "fake-static-mac-address",
 								Gateway:             "3.4.5.6",
+								Routes: []RouteConfiguration{
+									{Destination: "1.2.3.0/24", Interface: "static-interface-name", Table: 100},
+									{Destination: "0.0.0.0/0", Gateway: "3.4.5.6", Interface: "static-interface-name", Table: 100},
+								},
+								RoutingTable: 100,
 							},
 							StaticInterfaceConfiguration{
 								Name:                "static-interface-name-with-default-gateway",
@@ -189,6 +194,11 @@ func describeInterfaceConfigurationCreator() {
 								Broadcast:           "5.6.7.255",
 								Mac:                 "fake-static-mac-address-with-default-gateway",
 								Gateway:             "5.6.7.1",
+								Routes: []RouteConfiguration{
+									{Destination: "5.6.7.0/24", Interface: "static-interface-name-with-default-gateway", Table: 101},
+									{Destination: "0.0.0.0/0", Gateway: "5.6.7.1", Interface: "static-interface-name-with-default-gateway", Table: 101},
+								},
+								RoutingTable: 101,
 							},
 						}))
 
@@ -241,6 +251,607 @@ func describeInterfaceConfigurationCreator() {
 			})
 		})
 
+		Context("when the network has an IPv6 address", func() {
+			BeforeEach(func() {
+				networks["foo"] = boshsettings.Network{
+					IP:      "2001:db8::1234",
+					Netmask: "ffff:ffff:ffff:ffff::",
+					Default: []string{"gateway"},
+					Gateway: "2001:db8::1",
+					Mac:     "fake-static-mac-address",
+				}
+				interfacesByMAC["fake-static-mac-address"] = "static-interface-name"
+			})
+
+			It("creates an interface configuration with IPv6 network and without broadcast address", func() {
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					StaticInterfaceConfiguration{
+						Name:                "static-interface-name",
+						Address:             "2001:db8::1234",
+						Netmask:             "ffff:ffff:ffff:ffff::",
+						Network:             "2001:db8::",
+						IsDefaultForGateway: true,
+						Mac:                 "fake-static-mac-address",
+						Gateway:             "2001:db8::1",
+					},
+				}))
+				Expect(staticInterfaceConfigurations[0].IsVersion6()).To(BeTrue())
+				Expect(staticInterfaceConfigurations[0].NetmaskOrPrefixLength()).To(Equal("64"))
+
+				Expect(dhcpInterfaceConfigurations).To(BeEmpty())
+			})
+
+			It("returns an error when netmask is invalid", func() {
+				network := networks["foo"]
+				network.Netmask = "ffff::ffff"
+				networks["foo"] = network
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Calculating IPv6 Network"))
+			})
+		})
+
+		Context("when the dynamic network acquires IPv6 address", func() {
+			BeforeEach(func() {
+				networks["foo"] = boshsettings.Network{
+					Type:     "dynamic",
+					Mac:      "fake-dhcp-mac-address",
+					IPv6Mode: boshsettings.IPv6ModeSLAAC,
+				}
+				interfacesByMAC["fake-dhcp-mac-address"] = "dhcp-interface-name"
+			})
+
+			It("creates a DHCP interface configuration with IPv6 mode", func() {
+				_, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{
+						Name:     "dhcp-interface-name",
+						IPv6Mode: boshsettings.IPv6ModeSLAAC,
+					},
+				}))
+				Expect(dhcpInterfaceConfigurations[0].IsSLAAC()).To(BeTrue())
+				Expect(dhcpInterfaceConfigurations[0].IsDHCPv6()).To(BeFalse())
+			})
+
+			It("returns an error when IPv6 mode is not supported", func() {
+				network := networks["foo"]
+				network.IPv6Mode = "fake-mode"
+				networks["foo"] = network
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("IPv6 mode 'fake-mode' is not supported, expected dhcpv6 or slaac"))
+			})
+
+			It("returns an error when network is configured statically", func() {
+				networks["foo"] = boshsettings.Network{
+					IP:       "1.2.3.4",
+					Netmask:  "255.255.255.0",
+					Mac:      "fake-dhcp-mac-address",
+					IPv6Mode: boshsettings.IPv6ModeDHCPv6,
+				}
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("IPv6 mode 'dhcpv6' can only be used with dynamic networks"))
+			})
+		})
+
+		Context("when IPv4 and IPv6 networks have the same MAC address", func() {
+			var ipv6Network boshsettings.Network
+
+			BeforeEach(func() {
+				ipv6Network = boshsettings.Network{
+					IP:      "2001:db8::1234",
+					Netmask: "ffff:ffff:ffff:ffff::",
+					Gateway: "2001:db8::1",
+					Mac:     staticNetworkWithDefaultGateway.Mac,
+				}
+				networks["ipv4"] = staticNetworkWithDefaultGateway
+				networks["ipv6"] = ipv6Network
+				networks["bar"] = dhcpNetwork
+				interfacesByMAC[staticNetworkWithDefaultGateway.Mac] = "dual-stack-interface-name"
+				interfacesByMAC[dhcpNetwork.Mac] = "dhcp-interface-name"
+			})
+
+			It("creates IPv4 and IPv6 configurations for the same interface with default gateways of both families", func() {
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					StaticInterfaceConfiguration{
+						Name:                "dual-stack-interface-name",
+						Address:             "5.6.7.8",
+						Netmask:             "255.255.255.0",
+						Network:             "5.6.7.0",
+						IsDefaultForGateway: true,
+						Broadcast:           "5.6.7.255",
+						Mac:                 "fake-static-mac-address-with-default-gateway",
+						Gateway:             "5.6.7.1",
+					},
+					StaticInterfaceConfiguration{
+						Name:                "dual-stack-interface-name",
+						Address:             "2001:db8::1234",
+						Netmask:             "ffff:ffff:ffff:ffff::",
+						Network:             "2001:db8::",
+						IsDefaultForGateway: true,
+						Mac:                 "fake-static-mac-address-with-default-gateway",
+						Gateway:             "2001:db8::1",
+					},
+				}))
+
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "dhcp-interface-name"},
+				}))
+			})
+
+			It("returns an error when both networks have the same address family", func() {
+				ipv6Network.IP = "5.6.7.9"
+				ipv6Network.Netmask = "255.255.255.0"
+				networks["ipv6"] = ipv6Network
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Expected one IPv4 and one IPv6 network, found '5.6.7.8' and '5.6.7.9'"))
+			})
+
+			It("returns an error when one of the networks is dynamic", func() {
+				networks["ipv6"] = boshsettings.Network{Type: "dynamic", Mac: ipv6Network.Mac}
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Dual-stack networks must be statically configured"))
+			})
+		})
+
+		Context("when networks have VLAN IDs", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+			})
+
+			It("creates VLAN sub-interface configurations next to untagged interface configuration", func() {
+				vlanNetwork := staticNetworkWithDefaultGateway
+				vlanNetwork.Mac = staticNetwork.Mac
+				vlanNetwork.VLANID = 123
+				dhcpVLANNetwork := dhcpNetwork
+				dhcpVLANNetwork.Mac = staticNetwork.Mac
+				dhcpVLANNetwork.VLANID = 456
+
+				networks["untagged"] = staticNetwork
+				networks["tagged"] = vlanNetwork
+				networks["tagged-dhcp"] = dhcpVLANNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					StaticInterfaceConfiguration{
+						Name:      "eth0",
+						Address:   "1.2.3.4",
+						Netmask:   "255.255.255.0",
+						Network:   "1.2.3.0",
+						Broadcast: "1.2.3.255",
+						Mac:       "fake-static-mac-address",
+						Gateway:   "3.4.5.6",
+						Routes: []RouteConfiguration{
+							{Destination: "1.2.3.0/24", Interface: "eth0", Table: 100},
+							{Destination: "0.0.0.0/0", Gateway: "3.4.5.6", Interface: "eth0", Table: 100},
+						},
+						RoutingTable: 100,
+					},
+					StaticInterfaceConfiguration{
+						Name:                "eth0.123",
+						Address:             "5.6.7.8",
+						Netmask:             "255.255.255.0",
+						Network:             "5.6.7.0",
+						IsDefaultForGateway: true,
+						Broadcast:           "5.6.7.255",
+						Mac:                 "fake-static-mac-address",
+						Gateway:             "5.6.7.1",
+						VLANID:              123,
+						Parent:              "eth0",
+						Routes: []RouteConfiguration{
+							{Destination: "5.6.7.0/24", Interface: "eth0.123", Table: 101},
+							{Destination: "0.0.0.0/0", Gateway: "5.6.7.1", Interface: "eth0.123", Table: 101},
+						},
+						RoutingTable: 101,
+					},
+				}))
+
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "eth0.456", VLANID: 456, Parent: "eth0"},
+				}))
+			})
+
+			It("creates only VLAN sub-interface configuration when there is no untagged network", func() {
+				vlanNetwork := staticNetwork
+				vlanNetwork.VLANID = 123
+				networks["tagged"] = vlanNetwork
+				interfacesByMAC["fake-other-mac-address"] = "eth1"
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(1))
+				Expect(staticInterfaceConfigurations[0].Name).To(Equal("eth0.123"))
+				Expect(staticInterfaceConfigurations[0].Parent).To(Equal("eth0"))
+				Expect(staticInterfaceConfigurations[0].IsVLAN()).To(BeTrue())
+			})
+
+			It("returns an error when VLAN ID is out of range", func() {
+				vlanNetwork := staticNetwork
+				vlanNetwork.VLANID = 4095
+				networks["tagged"] = vlanNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("VLAN ID '4095' is out of range 1-4094"))
+			})
+		})
+
+		Context("when network is bonded", func() {
+			var bondNetwork boshsettings.Network
+
+			BeforeEach(func() {
+				bondNetwork = boshsettings.Network{
+					Type:    "manual",
+					IP:      "5.6.7.8",
+					Netmask: "255.255.255.0",
+					Gateway: "5.6.7.1",
+					Default: []string{"gateway"},
+					Bond: &boshsettings.Bond{
+						Mode:   "802.3ad",
+						Miimon: 100,
+						Slaves: []string{"fake-slave-mac-1", "fake-slave-mac-2"},
+					},
+				}
+				networks["bonded"] = bondNetwork
+				networks["foo"] = staticNetwork
+				interfacesByMAC["fake-slave-mac-1"] = "eth1"
+				interfacesByMAC["fake-slave-mac-2"] = "eth2"
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+			})
+
+			It("creates bond interface configuration over slaves and configures remaining interfaces", func() {
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					StaticInterfaceConfiguration{
+						Name:      "eth0",
+						Address:   "1.2.3.4",
+						Netmask:   "255.255.255.0",
+						Network:   "1.2.3.0",
+						Broadcast: "1.2.3.255",
+						Mac:       "fake-static-mac-address",
+						Gateway:   "3.4.5.6",
+						Routes: []RouteConfiguration{
+							{Destination: "1.2.3.0/24", Interface: "eth0", Table: 101},
+							{Destination: "0.0.0.0/0", Gateway: "3.4.5.6", Interface: "eth0", Table: 101},
+						},
+						RoutingTable: 101,
+					},
+					StaticInterfaceConfiguration{
+						Name:                "bond0",
+						Address:             "5.6.7.8",
+						Netmask:             "255.255.255.0",
+						Network:             "5.6.7.0",
+						Broadcast:           "5.6.7.255",
+						IsDefaultForGateway: true,
+						Mac:                 "fake-slave-mac-1",
+						Gateway:             "5.6.7.1",
+						Bond: &BondConfiguration{
+							Mode:   "802.3ad",
+							Miimon: 100,
+							Slaves: []string{"eth1", "eth2"},
+						},
+						Routes: []RouteConfiguration{
+							{Destination: "5.6.7.0/24", Interface: "bond0", Table: 100},
+							{Destination: "0.0.0.0/0", Gateway: "5.6.7.1", Interface: "bond0", Table: 100},
+						},
+						RoutingTable: 100,
+					},
+				}))
+				Expect(dhcpInterfaceConfigurations).To(BeEmpty())
+			})
+
+			It("returns an error when slave device is not found", func() {
+				delete(interfacesByMAC, "fake-slave-mac-2")
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("No unbonded device found for slave of network 'bonded' with MAC address 'fake-slave-mac-2'"))
+			})
+
+			It("returns an error when bond has no slaves", func() {
+				bondNetwork.Bond = &boshsettings.Bond{Mode: "active-backup"}
+				networks["bonded"] = bondNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Bonded network 'bonded' has no slaves"))
+			})
+
+			It("returns an error when bonded network is also bridged", func() {
+				bondNetwork.Bridge = "br0"
+				networks["bonded"] = bondNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Network 'bonded' cannot be both bonded and bridged"))
+			})
+		})
+
+		Context("when network has MTU", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[dhcpNetwork.Mac] = "eth1"
+			})
+
+			It("creates interface configurations with MTU", func() {
+				staticNetwork.MTU = 9000
+				dhcpNetwork.MTU = 1400
+				networks["static"] = staticNetwork
+				networks["dhcp"] = dhcpNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(1))
+				Expect(staticInterfaceConfigurations[0].MTU).To(Equal(9000))
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "eth1", MTU: 1400},
+				}))
+			})
+
+			It("returns an error when MTU is out of range", func() {
+				staticNetwork.MTU = 42
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("MTU '42' is out of range 68-65535"))
+			})
+		})
+
+		Context("when network has address announcement", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[dhcpNetwork.Mac] = "eth1"
+			})
+
+			It("creates interface configurations with address announcement", func() {
+				staticNetwork.AddressAnnouncement = boshsettings.AddressAnnouncement{Strategy: boshsettings.AddressAnnouncementArping, Iterations: 3, Delay: 500}
+				dhcpNetwork.AddressAnnouncement = boshsettings.AddressAnnouncement{Strategy: boshsettings.AddressAnnouncementNone}
+				networks["static"] = staticNetwork
+				networks["dhcp"] = dhcpNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(1))
+				Expect(staticInterfaceConfigurations[0].AddressAnnouncement).To(Equal(staticNetwork.AddressAnnouncement))
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "eth1", AddressAnnouncement: dhcpNetwork.AddressAnnouncement},
+				}))
+			})
+
+			It("returns an error when strategy is not supported", func() {
+				staticNetwork.AddressAnnouncement = boshsettings.AddressAnnouncement{Strategy: "fake-strategy"}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Address announcement strategy 'fake-strategy' is not supported, expected arping, ndisc6 or none"))
+			})
+
+			It("returns an error when iterations are negative", func() {
+				staticNetwork.AddressAnnouncement = boshsettings.AddressAnnouncement{Iterations: -1}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Address announcement iterations and delay cannot be negative"))
+			})
+		})
+
+		Context("when network has tuning", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[dhcpNetwork.Mac] = "eth1"
+			})
+
+			It("creates interface configurations with tuning", func() {
+				rpFilter := 2
+				gro := false
+				staticNetwork.Tuning = boshsettings.InterfaceTuning{RPFilter: &rpFilter, TxQueueLen: 10000}
+				dhcpNetwork.Tuning = boshsettings.InterfaceTuning{GRO: &gro}
+				networks["static"] = staticNetwork
+				networks["dhcp"] = dhcpNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(1))
+				Expect(staticInterfaceConfigurations[0].Tuning).To(Equal(staticNetwork.Tuning))
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "eth1", Tuning: dhcpNetwork.Tuning},
+				}))
+			})
+
+			It("returns an error when reverse path filter is not supported", func() {
+				rpFilter := 3
+				staticNetwork.Tuning = boshsettings.InterfaceTuning{RPFilter: &rpFilter}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Reverse path filter '3' is not supported, expected 0, 1 or 2"))
+			})
+
+			It("returns an error when transmit queue length is negative", func() {
+				staticNetwork.Tuning = boshsettings.InterfaceTuning{TxQueueLen: -1}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Transmit queue length '-1' cannot be negative"))
+			})
+		})
+
+		Context("when network has routes", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[dhcpNetwork.Mac] = "eth1"
+			})
+
+			It("creates routes through the interface of the network unless another interface is given", func() {
+				staticNetwork.Routes = []boshsettings.Route{
+					{Destination: "10.0.0.0/8", Gateway: "1.2.3.1"},
+					{Destination: "172.16.0.0/12", Interface: "eth1"},
+				}
+				networks["static"] = staticNetwork
+				networks["dhcp"] = dhcpNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(1))
+				Expect(staticInterfaceConfigurations[0].Routes).To(Equal([]RouteConfiguration{
+					{Destination: "10.0.0.0/8", Gateway: "1.2.3.1", Interface: "eth0"},
+				}))
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{
+						Name:   "eth1",
+						Routes: []RouteConfiguration{{Destination: "172.16.0.0/12", Interface: "eth1"}},
+					},
+				}))
+			})
+
+			It("returns an error when route destination is not a network", func() {
+				staticNetwork.Routes = []boshsettings.Route{{Destination: "10.0.0.1", Gateway: "1.2.3.1"}}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Parsing route destination '10.0.0.1'"))
+			})
+
+			It("returns an error when route gateway has different address family", func() {
+				staticNetwork.Routes = []boshsettings.Route{{Destination: "2001:db8::/32", Gateway: "1.2.3.1"}}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Gateway '1.2.3.1' and destination '2001:db8::/32' of route have different address families"))
+			})
+
+			It("returns an error when route interface is not configured", func() {
+				staticNetwork.Routes = []boshsettings.Route{{Destination: "10.0.0.0/8", Interface: "eth7"}}
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Route to '10.0.0.0/8' refers to interface 'eth7' that is not configured"))
+			})
+		})
+
+		Context("when multiple networks have gateways", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[staticNetworkWithDefaultGateway.Mac] = "eth1"
+			})
+
+			It("does not configure policy routing when only one network has gateway", func() {
+				staticNetwork.Gateway = ""
+				networks["static"] = staticNetwork
+				networks["default"] = staticNetworkWithDefaultGateway
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(staticInterfaceConfigurations).To(HaveLen(2))
+				for _, config := range staticInterfaceConfigurations {
+					Expect(config.RoutingTable).To(BeZero())
+					Expect(config.Routes).To(BeEmpty())
+				}
+			})
+
+			It("configures policy routing per address family", func() {
+				ipv6Network := boshsettings.Network{
+					IP:      "2001:db8::1234",
+					Netmask: "ffff:ffff:ffff:ffff::",
+					Gateway: "2001:db8::1",
+					Mac:     "fake-ipv6-mac-address",
+				}
+				interfacesByMAC[ipv6Network.Mac] = "eth2"
+				networks["static"] = staticNetwork
+				networks["default"] = staticNetworkWithDefaultGateway
+				networks["ipv6"] = ipv6Network
+
+				staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				routingTables := map[string]int{}
+				for _, config := range staticInterfaceConfigurations {
+					routingTables[config.Name] = config.RoutingTable
+				}
+				Expect(routingTables).To(Equal(map[string]int{"eth0": 100, "eth1": 101, "eth2": 0}))
+			})
+		})
+
+		Context("when network is bridged", func() {
+			BeforeEach(func() {
+				interfacesByMAC[staticNetwork.Mac] = "eth0"
+				interfacesByMAC[dhcpNetwork.Mac] = "eth1"
+			})
+
+			It("creates bridge interface configurations with the interfaces attached", func() {
+				staticNetwork.Bridge = "br0"
+				dhcpNetwork.Bridge = "br1"
+				networks["static"] = staticNetwork
+				networks["dhcp"] = dhcpNetwork
+
+				staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+					StaticInterfaceConfiguration{
+						Name:      "br0",
+						Address:   "1.2.3.4",
+						Netmask:   "255.255.255.0",
+						Network:   "1.2.3.0",
+						Broadcast: "1.2.3.255",
+						Mac:       "fake-static-mac-address",
+						Gateway:   "3.4.5.6",
+						Bridge:    &BridgeConfiguration{Ports: []string{"eth0"}},
+					},
+				}))
+				Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{
+					DHCPInterfaceConfiguration{Name: "br1", Bridge: &BridgeConfiguration{Ports: []string{"eth1"}}},
+				}))
+			})
+
+			It("returns an error when bridged network is tagged with VLAN", func() {
+				staticNetwork.Bridge = "br0"
+				staticNetwork.VLANID = 123
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Bridge 'br0' cannot be tagged with VLAN"))
+			})
+
+			It("returns an error when bridge name is too long", func() {
+				staticNetwork.Bridge = "br-too-long-name"
+				networks["static"] = staticNetwork
+
+				_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Bridge name 'br-too-long-name' is longer than 15 characters"))
+			})
+		})
+
 		Context("when the number of networks does not match the number of devices", func() {
 			BeforeEach(func() {
 				networks["foo"] = staticNetwork
@@ -0,0 +1,141 @@
+package net
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const interfaceNamesUdevRulesPath = "/etc/udev/rules.d/70-bosh-persistent-net.rules"
+
+// Rules are matched when interfaces are added so that renaming
+// takes effect on next boot and does not disturb configured interfaces
+const interfaceNamesUdevRulesTemplate = `# Generated by bosh-agent
+{{ range . }}SUBSYSTEM=="net", ACTION=="add", DRIVERS=="?*", ATTR{address}=="{{ .MacAddress }}", ATTR{type}=="1", NAME="{{ .Name }}"
+{{ end }}`
+
+// interfaceName pins name of a physical interface to its MAC address
+type interfaceName struct {
+	Name       string
+	MacAddress string
+}
+
+// pinnedInterfaceNames returns names of physical interfaces that carry configured networks
+// so that adding or removing NICs and kernel upgrades do not reorder them
+func pinnedInterfaceNames(interfacesByMacAddress map[string]string, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) []interfaceName {
+	configuredNames := map[string]bool{}
+
+	addConfiguredNames := func(name, parent string, bond *BondConfiguration, bridge *BridgeConfiguration) {
+		configuredNames[name] = true
+		configuredNames[parent] = true
+		if bond != nil {
+			for _, slave := range bond.Slaves {
+				configuredNames[slave] = true
+			}
+		}
+		if bridge != nil {
+			for _, port := range bridge.Ports {
+				configuredNames[port] = true
+			}
+		}
+	}
+
+	for _, staticConfig := range staticConfigs {
+		addConfiguredNames(staticConfig.Name, staticConfig.Parent, staticConfig.Bond, staticConfig.Bridge)
+	}
+	for _, dhcpConfig := range dhcpConfigs {
+		addConfiguredNames(dhcpConfig.Name, dhcpConfig.Parent, dhcpConfig.Bond, dhcpConfig.Bridge)
+	}
+
+	names := []interfaceName{}
+	for mac, name := range interfacesByMacAddress {
+		if configuredNames[name] {
+			names = append(names, interfaceName{Name: name, MacAddress: mac})
+		}
+	}
+
+	sort.Slice(names, func(i, j int) bool {
+		return names[i].Name < names[j].Name
+	})
+
+	return names
+}
+
+// writeInterfaceNamesUdevRules writes udev rules pinning names of configured physical interfaces
+// to their MAC addresses; used on stemcells where udev does not apply systemd .link units
+func writeInterfaceNamesUdevRules(fs boshsys.FileSystem, interfacesByMacAddress map[string]string, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	names := pinnedInterfaceNames(interfacesByMacAddress, staticConfigs, dhcpConfigs)
+
+	if len(names) == 0 {
+		err := fs.RemoveAll(interfaceNamesUdevRulesPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", interfaceNamesUdevRulesPath)
+		}
+
+		return nil
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("interface-names-udev-rules").Parse(interfaceNamesUdevRulesTemplate))
+
+	err := t.Execute(buffer, names)
+	if err != nil {
+		return bosherr.WrapError(err, "Generating config from template")
+	}
+
+	_, err = fs.ConvergeFileContents(interfaceNamesUdevRulesPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", interfaceNamesUdevRulesPath)
+	}
+
+	return nil
+}
+
+// writeInterfaceNamesLinkUnits writes systemd .link units pinning names of configured
+// physical interfaces to their MAC addresses and removes units of interfaces no longer configured
+func writeInterfaceNamesLinkUnits(fs boshsys.FileSystem, interfacesByMacAddress map[string]string, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	writtenUnitPaths := map[string]bool{}
+
+	t := template.Must(template.New("interface-names-link-unit").Parse(systemdLinkUnitTemplate))
+
+	for _, name := range pinnedInterfaceNames(interfacesByMacAddress, staticConfigs, dhcpConfigs) {
+		buffer := bytes.NewBuffer([]byte{})
+
+		err := t.Execute(buffer, name)
+		if err != nil {
+			return bosherr.WrapError(err, "Generating config from template")
+		}
+
+		unitPath := systemdNetworkUnitPath(name.Name, "link")
+
+		_, err = fs.ConvergeFileContents(unitPath, buffer.Bytes())
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", unitPath)
+		}
+
+		writtenUnitPaths[unitPath] = true
+	}
+
+	unitPaths, err := fs.Glob(path.Join(systemdNetworkDir, systemdNetworkUnitPrefix+"*.link"))
+	if err != nil {
+		return bosherr.WrapError(err, "Listing link units")
+	}
+
+	for _, unitPath := range unitPaths {
+		if writtenUnitPaths[unitPath] {
+			continue
+		}
+
+		err = fs.RemoveAll(unitPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", unitPath)
+		}
+	}
+
+	return nil
+}
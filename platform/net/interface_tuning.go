@@ -0,0 +1,120 @@
+package net
+
+import (
+	"bytes"
+	"sort"
+	"strconv"
+	"text/template"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const interfaceTuningSysctlConfigPath = "/etc/sysctl.d/60-bosh-interface-tuning.conf"
+
+// Reverse path filtering is persisted so that it is in effect on boot before networking is re-applied
+const interfaceTuningSysctlConfigTemplate = `# Generated by bosh-agent
+{{ range . }}net.ipv4.conf.{{ sysctlInterfaceName .Name }}.rp_filter = {{ .RPFilter }}
+{{ end }}`
+
+type rpFilterSysctlConfig struct {
+	Name     string
+	RPFilter int
+}
+
+// applyInterfaceTuning sets reverse path filtering, generic receive offload and transmit queue length
+// of interfaces; it runs after interfaces are (re)created since recreating them resets these parameters
+func applyInterfaceTuning(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	tunings := map[string]boshsettings.InterfaceTuning{}
+	for _, staticConfig := range staticConfigs {
+		if !staticConfig.Tuning.IsEmpty() {
+			tunings[staticConfig.Name] = staticConfig.Tuning
+		}
+	}
+	for _, dhcpConfig := range dhcpConfigs {
+		if !dhcpConfig.Tuning.IsEmpty() {
+			tunings[dhcpConfig.Name] = dhcpConfig.Tuning
+		}
+	}
+
+	ifaceNames := []string{}
+	for name := range tunings {
+		ifaceNames = append(ifaceNames, name)
+	}
+
+	sort.Strings(ifaceNames)
+
+	rpFilterConfigs := []rpFilterSysctlConfig{}
+	for _, name := range ifaceNames {
+		if rpFilter := tunings[name].RPFilter; rpFilter != nil {
+			rpFilterConfigs = append(rpFilterConfigs, rpFilterSysctlConfig{Name: name, RPFilter: *rpFilter})
+		}
+	}
+
+	err := writeRPFilterSysctls(fs, cmdRunner, rpFilterConfigs)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range ifaceNames {
+		tuning := tunings[name]
+
+		if tuning.GRO != nil {
+			gro := "off"
+			if *tuning.GRO {
+				gro = "on"
+			}
+
+			_, _, _, err = cmdRunner.RunCommand("ethtool", "-K", name, "gro", gro)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Setting generic receive offload of interface '%s'", name)
+			}
+		}
+
+		if tuning.TxQueueLen != 0 {
+			_, _, _, err = cmdRunner.RunCommand("ip", "link", "set", "dev", name, "txqueuelen", strconv.Itoa(tuning.TxQueueLen))
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Setting transmit queue length of interface '%s'", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// writeRPFilterSysctls applies reverse path filtering every time since
+// interfaces recreated by networking restart come up with the default mode
+func writeRPFilterSysctls(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, rpFilterConfigs []rpFilterSysctlConfig) error {
+	if len(rpFilterConfigs) == 0 {
+		err := fs.RemoveAll(interfaceTuningSysctlConfigPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", interfaceTuningSysctlConfigPath)
+		}
+
+		return nil
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("interface-tuning-sysctl").Funcs(template.FuncMap{
+		"sysctlInterfaceName": sysctlInterfaceName,
+	}).Parse(interfaceTuningSysctlConfigTemplate))
+
+	err := t.Execute(buffer, rpFilterConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Generating config from template")
+	}
+
+	_, err = fs.ConvergeFileContents(interfaceTuningSysctlConfigPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", interfaceTuningSysctlConfigPath)
+	}
+
+	_, _, _, err = cmdRunner.RunCommand("sysctl", "-p", interfaceTuningSysctlConfigPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying reverse path filtering kernel parameters")
+	}
+
+	return nil
+}
@@ -0,0 +1,10 @@
+package ip
+
+/*
+Exports private items of the ip package for tests in the ip_test package.
+Because this is a *_test file it will not be included when you build the package.
+*/
+
+func ParseTemporaryIPv6Addresses(contents string) map[string]bool {
+	return parseTemporaryIPv6Addresses(contents)
+}
@@ -54,3 +54,29 @@ func (s *resolvingInterfaceAddress) GetIP() (string, error) {
 
 	return s.ip, nil
 }
+
+// temporaryInterfaceAddress is an IPv6 privacy address (RFC 4941)
+// that is periodically regenerated by the kernel
+type temporaryInterfaceAddress struct {
+	simpleInterfaceAddress
+}
+
+func NewTemporaryInterfaceAddress(interfaceName string, ip string) InterfaceAddress {
+	return temporaryInterfaceAddress{simpleInterfaceAddress{interfaceName: interfaceName, ip: ip}}
+}
+
+// stableIPv6InterfaceAddress stands for any stable global IPv6 address
+// that the interface acquires via DHCPv6 or SLAAC and thus is not known upfront
+type stableIPv6InterfaceAddress struct {
+	interfaceName string
+}
+
+func NewStableIPv6InterfaceAddress(interfaceName string) InterfaceAddress {
+	return stableIPv6InterfaceAddress{interfaceName: interfaceName}
+}
+
+func (s stableIPv6InterfaceAddress) GetInterfaceName() string { return s.interfaceName }
+
+func (s stableIPv6InterfaceAddress) GetIP() (string, error) {
+	return "", bosherr.Errorf("IPv6 address of interface '%s' is acquired dynamically", s.interfaceName)
+}
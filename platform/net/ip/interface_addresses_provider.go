@@ -1,7 +1,11 @@
 package ip
 
 import (
+	"encoding/hex"
+	"io/ioutil"
 	"net"
+	"strconv"
+	"strings"
 
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
@@ -22,6 +26,8 @@ func (s *systemInterfaceAddrs) Get() ([]InterfaceAddress, error) {
 		return []InterfaceAddress{}, bosherr.WrapError(err, "Getting network interfaces")
 	}
 
+	temporaryIPv6Addresses := readTemporaryIPv6Addresses()
+
 	interfaceAddrs := []InterfaceAddress{}
 
 	for _, iface := range ifaces {
@@ -38,6 +44,13 @@ func (s *systemInterfaceAddrs) Get() ([]InterfaceAddress, error) {
 
 			if ipv4 := ip.To4(); ipv4 != nil {
 				interfaceAddrs = append(interfaceAddrs, NewSimpleInterfaceAddress(iface.Name, ipv4.String()))
+			} else if ip.IsGlobalUnicast() {
+				// Link-local IPv6 addresses are assigned automatically and are never configured by the agent
+				if temporaryIPv6Addresses[ip.String()] {
+					interfaceAddrs = append(interfaceAddrs, NewTemporaryInterfaceAddress(iface.Name, ip.String()))
+				} else {
+					interfaceAddrs = append(interfaceAddrs, NewSimpleInterfaceAddress(iface.Name, ip.String()))
+				}
 			}
 		}
 
@@ -45,3 +58,45 @@ func (s *systemInterfaceAddrs) Get() ([]InterfaceAddress, error) {
 
 	return interfaceAddrs, nil
 }
+
+// ifaTemporary is IFA_F_TEMPORARY flag of IPv6 privacy addresses
+const ifaTemporary = 0x01
+
+// readTemporaryIPv6Addresses returns temporary addresses listed in /proc/net/if_inet6
+// since Go does not expose address flags; none are returned on systems without it
+func readTemporaryIPv6Addresses() map[string]bool {
+	contents, err := ioutil.ReadFile("/proc/net/if_inet6")
+	if err != nil {
+		return map[string]bool{}
+	}
+
+	return parseTemporaryIPv6Addresses(string(contents))
+}
+
+// parseTemporaryIPv6Addresses parses lines such as
+// "20010db8000000000000000000000001 02 40 00 01 eth0"
+// (address, interface index, prefix length, scope, flags, interface name)
+func parseTemporaryIPv6Addresses(contents string) map[string]bool {
+	addresses := map[string]bool{}
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 6 {
+			continue
+		}
+
+		flags, err := strconv.ParseUint(fields[4], 16, 32)
+		if err != nil || flags&ifaTemporary == 0 {
+			continue
+		}
+
+		ipBytes, err := hex.DecodeString(fields[0])
+		if err != nil || len(ipBytes) != net.IPv6len {
+			continue
+		}
+
+		addresses[net.IP(ipBytes).String()] = true
+	}
+
+	return addresses
+}
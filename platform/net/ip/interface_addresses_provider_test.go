@@ -35,3 +35,19 @@ var _ = Describe("InterfaceAddressesProvider", func() {
 		Expect([]string{"lo", "lo0", "Loopback Pseudo-Interface 1"}).To(ContainElement(loopBackInterface.GetInterfaceName()))
 	})
 })
+
+var _ = Describe("ParseTemporaryIPv6Addresses", func() {
+	It("returns addresses flagged as temporary in /proc/net/if_inet6", func() {
+		addresses := ParseTemporaryIPv6Addresses(`20010db8000000000000000000000001 02 40 00 01     eth0
+20010db80000000000000000a1b2c3d4 02 40 00 01     eth0
+20010db8000000000000f8163efffe00 02 40 00 00     eth0
+00000000000000000000000000000001 01 80 10 80       lo
+fe800000000000000000f8163efffe00 02 40 20 80     eth0
+garbage
+`)
+		Expect(addresses).To(Equal(map[string]bool{
+			"2001:db8::1":         true,
+			"2001:db8::a1b2:c3d4": true,
+		}))
+	})
+})
@@ -1,6 +1,9 @@
 package ip
 
 import (
+	gonet "net"
+	"strings"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
@@ -26,26 +29,76 @@ func (i *interfaceAddressesValidator) Validate(desiredInterfaceAddresses []Inter
 
 	for _, desiredInterfaceAddress := range desiredInterfaceAddresses {
 		ifaceName := desiredInterfaceAddress.GetInterfaceName()
-		iface, found := i.findInterfaceByName(ifaceName, systemInterfaceAddresses)
-		if !found {
+		actualIPs := i.findIPsByInterfaceName(ifaceName, systemInterfaceAddresses)
+		if len(actualIPs) == 0 {
 			return bosherr.WrapErrorf(err, "Validating network interface '%s' IP addresses, no interface configured with that name", ifaceName)
 		}
+		if _, ok := desiredInterfaceAddress.(stableIPv6InterfaceAddress); ok {
+			if !i.hasStableIPv6(ifaceName, systemInterfaceAddresses) {
+				return bosherr.Errorf("Validating network interface '%s' IP addresses, no stable IPv6 address acquired, actual: '%s'", ifaceName, strings.Join(actualIPs, "', '"))
+			}
+			continue
+		}
 		desiredIP, _ := desiredInterfaceAddress.GetIP()
-		actualIP, _ := iface.GetIP()
-		if desiredIP != actualIP {
-			return bosherr.WrapErrorf(err, "Validating network interface '%s' IP addresses, expected: '%s', actual: '%s'", ifaceName, desiredIP, actualIP)
+		if !i.containsIP(actualIPs, desiredIP) {
+			return bosherr.WrapErrorf(err, "Validating network interface '%s' IP addresses, expected: '%s', actual: '%s'", ifaceName, desiredIP, strings.Join(actualIPs, "', '"))
 		}
 	}
 
 	return nil
 }
 
-func (i *interfaceAddressesValidator) findInterfaceByName(ifaceName string, ifaces []InterfaceAddress) (InterfaceAddress, bool) {
+// findIPsByInterfaceName returns all addresses of the interface
+// since it may have both IPv4 and IPv6 addresses assigned
+func (i *interfaceAddressesValidator) findIPsByInterfaceName(ifaceName string, ifaces []InterfaceAddress) []string {
+	ips := []string{}
+
 	for _, iface := range ifaces {
 		if iface.GetInterfaceName() == ifaceName {
-			return iface, true
+			ip, _ := iface.GetIP()
+			ips = append(ips, ip)
+		}
+	}
+
+	return ips
+}
+
+// hasStableIPv6 ignores temporary addresses since they are
+// only generated in addition to stable addresses acquired via SLAAC
+func (i *interfaceAddressesValidator) hasStableIPv6(ifaceName string, ifaces []InterfaceAddress) bool {
+	for _, iface := range ifaces {
+		if iface.GetInterfaceName() != ifaceName {
+			continue
+		}
+
+		if _, isTemporary := iface.(temporaryInterfaceAddress); isTemporary {
+			continue
+		}
+
+		ip, _ := iface.GetIP()
+		parsedIP := gonet.ParseIP(ip)
+		if parsedIP != nil && parsedIP.To4() == nil && parsedIP.IsGlobalUnicast() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// containsIP compares parsed addresses since IPv6 addresses
+// have several textual representations (e.g. leading zeros)
+func (i *interfaceAddressesValidator) containsIP(ips []string, desiredIP string) bool {
+	parsedDesiredIP := gonet.ParseIP(desiredIP)
+
+	for _, ip := range ips {
+		if ip == desiredIP {
+			return true
+		}
+
+		if parsedDesiredIP != nil && parsedDesiredIP.Equal(gonet.ParseIP(ip)) {
+			return true
 		}
 	}
 
-	return nil, false
+	return false
 }
@@ -38,6 +38,43 @@ var _ = Describe("InterfaceAddressesValidator", func() {
 		})
 	})
 
+	Context("when interface acquires IPv6 address dynamically", func() {
+		It("returns nil when interface has stable global IPv6 address", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewTemporaryInterfaceAddress("eth0", "2001:db8::a1b2:c3d4"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::f816:3eff:fe00:1"),
+			}
+
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewStableIPv6InterfaceAddress("eth0"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails when interface only has temporary IPv6 addresses", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewTemporaryInterfaceAddress("eth0", "2001:db8::a1b2:c3d4"),
+				boship.NewSimpleInterfaceAddress("eth1", "2001:db8::f816:3eff:fe00:1"),
+			}
+
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewStableIPv6InterfaceAddress("eth0"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating network interface 'eth0' IP addresses, no stable IPv6 address acquired, actual: '1.2.3.4', '2001:db8::a1b2:c3d4'"))
+		})
+
+		It("fails when interface does not exist", func() {
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewStableIPv6InterfaceAddress("eth0"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("no interface configured with that name"))
+		})
+	})
+
 	Context("when desired networks do not match actual network IP address", func() {
 		BeforeEach(func() {
 			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
@@ -54,6 +91,30 @@ var _ = Describe("InterfaceAddressesValidator", func() {
 		})
 	})
 
+	Context("when interface has several addresses", func() {
+		BeforeEach(func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8:0:0::1234"),
+			}
+		})
+
+		It("returns nil when desired IPv6 address is one of them", func() {
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::1234"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails listing all actual addresses when desired address is not one of them", func() {
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::5678"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("expected: '2001:db8::5678', actual: '1.2.3.4', '2001:db8:0:0::1234'"))
+		})
+	})
+
 	Context("when validating manual networks fails", func() {
 		BeforeEach(func() {
 			interfaceAddrsProvider.GetErr = errors.New("interface-error")
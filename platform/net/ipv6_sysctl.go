@@ -0,0 +1,83 @@
+package net
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const ipv6SysctlConfigPath = "/etc/sysctl.d/60-bosh-ipv6.conf"
+
+// Statically configured interfaces should not pick up additional addresses
+// and default routes from router advertisements, dynamically configured ones
+// need router advertisements for default route and autoconfigure addresses only with SLAAC
+const ipv6SysctlConfigTemplate = `# Generated by bosh-agent
+{{ range . }}{{ $name := sysctlInterfaceName .Name }}net.ipv6.conf.{{ $name }}.disable_ipv6 = 0
+net.ipv6.conf.{{ $name }}.autoconf = {{ if .Autoconf }}1{{ else }}0{{ end }}
+net.ipv6.conf.{{ $name }}.accept_ra = {{ if .AcceptRA }}1{{ else }}0{{ end }}
+{{ end }}`
+
+type ipv6SysctlConfig struct {
+	Name     string
+	Autoconf bool
+	AcceptRA bool
+}
+
+// sysctlInterfaceName escapes dots in interface names (e.g. VLAN sub-interface eth0.123)
+// since sysctl uses them as key separators
+func sysctlInterfaceName(name string) string {
+	return strings.Replace(name, ".", "/", -1)
+}
+
+// writeIPv6Sysctls enables IPv6 on interfaces with static or dynamic IPv6 addresses,
+// configures autoconfiguration accordingly and applies kernel parameters if they changed
+func writeIPv6Sysctls(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	ipv6Configs := []ipv6SysctlConfig{}
+	for _, staticConfig := range staticConfigs {
+		if staticConfig.IsVersion6() {
+			ipv6Configs = append(ipv6Configs, ipv6SysctlConfig{Name: staticConfig.Name})
+		}
+	}
+	for _, dhcpConfig := range dhcpConfigs {
+		if dhcpConfig.IPv6Mode != "" {
+			ipv6Configs = append(ipv6Configs, ipv6SysctlConfig{Name: dhcpConfig.Name, Autoconf: dhcpConfig.IsSLAAC(), AcceptRA: true})
+		}
+	}
+
+	if len(ipv6Configs) == 0 {
+		err := fs.RemoveAll(ipv6SysctlConfigPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", ipv6SysctlConfigPath)
+		}
+
+		return nil
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("ipv6-sysctl").Funcs(template.FuncMap{
+		"sysctlInterfaceName": sysctlInterfaceName,
+	}).Parse(ipv6SysctlConfigTemplate))
+
+	err := t.Execute(buffer, ipv6Configs)
+	if err != nil {
+		return bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := fs.ConvergeFileContents(ipv6SysctlConfigPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", ipv6SysctlConfigPath)
+	}
+
+	if changed {
+		_, _, _, err = cmdRunner.RunCommand("sysctl", "-p", ipv6SysctlConfigPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Applying IPv6 kernel parameters")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package net
+
+import (
+	"path"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// detectMacAddresses returns names of physical network interfaces by their MAC address
+func detectMacAddresses(fs boshsys.FileSystem) (map[string]string, error) {
+	addresses := map[string]string{}
+
+	filePaths, err := fs.Glob("/sys/class/net/*")
+	if err != nil {
+		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
+	}
+
+	for _, filePath := range filePaths {
+		isPhysicalDevice := fs.FileExists(path.Join(filePath, "device"))
+
+		// SR-IOV virtual functions are left to workloads instead of being configured with networks
+		isVirtualFunction := fs.FileExists(path.Join(filePath, "device", "physfn"))
+
+		if isPhysicalDevice && !isVirtualFunction {
+			macAddress, err := fs.ReadFileString(path.Join(filePath, "address"))
+			if err != nil {
+				return addresses, bosherr.WrapError(err, "Reading mac address from file")
+			}
+
+			macAddress = strings.Trim(macAddress, "\n")
+
+			interfaceName := path.Base(filePath)
+			addresses[macAddress] = interfaceName
+		}
+	}
+
+	return addresses, nil
+}
@@ -0,0 +1,372 @@
+package net
+
+import (
+	"bytes"
+	"fmt"
+	gonet "net"
+	"sort"
+	"strings"
+	"text/template"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	netplanNetManagerLogTag = "netplanNetManager"
+
+	// Sorted after configuration written by cloud-init (50-cloud-init.yaml)
+	// so that settings of the same interfaces are overridden
+	netplanConfigPath = "/etc/netplan/99-bosh.yaml"
+)
+
+type netplanNetManager struct {
+	fs                            boshsys.FileSystem
+	cmdRunner                     boshsys.CmdRunner
+	ipResolver                    boship.Resolver
+	interfaceConfigurationCreator InterfaceConfigurationCreator
+	interfaceAddressesValidator   boship.InterfaceAddressesValidator
+	dnsValidator                  DNSValidator
+	addressBroadcaster            bosharp.AddressBroadcaster
+	logger                        boshlog.Logger
+}
+
+// NewNetplanNetManager returns manager that renders netplan configuration
+// and applies it with netplan; used on Ubuntu Bionic and newer which
+// no longer configure networking with /etc/network/interfaces
+func NewNetplanNetManager(
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	ipResolver boship.Resolver,
+	interfaceConfigurationCreator InterfaceConfigurationCreator,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	dnsValidator DNSValidator,
+	addressBroadcaster bosharp.AddressBroadcaster,
+	logger boshlog.Logger,
+) Manager {
+	return netplanNetManager{
+		fs:                            fs,
+		cmdRunner:                     cmdRunner,
+		ipResolver:                    ipResolver,
+		interfaceConfigurationCreator: interfaceConfigurationCreator,
+		interfaceAddressesValidator:   interfaceAddressesValidator,
+		dnsValidator:                  dnsValidator,
+		addressBroadcaster:            addressBroadcaster,
+		logger:                        logger,
+	}
+}
+
+func (net netplanNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
+	if networks.IsPreconfigured() {
+		// Note in this case IPs are not broadcasted
+		dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+		return writeResolvedConf(net.fs, net.cmdRunner, dnsNetwork.DNS, dnsNetwork.Domains)
+	}
+
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	err := configureSRIOV(net.fs, net.cmdRunner, nonVipNetworks)
+	if err != nil {
+		return bosherr.WrapError(err, "Configuring SR-IOV virtual functions")
+	}
+
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	staticConfigs, dhcpConfigs, err := net.interfaceConfigurationCreator.CreateInterfaceConfigurations(nonVipNetworks, interfacesByMacAddress)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating interface configurations")
+	}
+
+	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
+	dnsServers := dnsNetwork.DNS
+
+	changed, err := net.writeNetplanConfig(dhcpConfigs, staticConfigs, dnsServers, dnsNetwork.Domains)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	err = writeInterfaceNamesLinkUnits(net.fs, interfacesByMacAddress, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Pinning interface names")
+	}
+
+	err = writeIPv6Sysctls(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing IPv6 kernel parameters")
+	}
+
+	if changed {
+		_, _, _, err = net.cmdRunner.RunCommand("netplan", "apply")
+		if err != nil {
+			return bosherr.WrapError(err, "Applying netplan configuration")
+		}
+	}
+
+	err = applyInterfaceTuning(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying interface tuning")
+	}
+
+	staticAddresses := staticInterfaceAddresses(staticConfigs)
+	dynamicAddresses := dynamicInterfaceAddresses(dhcpConfigs, net.ipResolver)
+
+	err = net.interfaceAddressesValidator.Validate(staticAddresses)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating static network configuration")
+	}
+
+	dynamicIPv6Addrs := dynamicIPv6Addresses(dhcpConfigs)
+	if len(dynamicIPv6Addrs) > 0 {
+		err = net.interfaceAddressesValidator.Validate(dynamicIPv6Addrs)
+		if err != nil {
+			return bosherr.WrapError(err, "Validating dynamic IPv6 network configuration")
+		}
+	}
+
+	err = net.dnsValidator.Validate(dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating dns configuration")
+	}
+
+	go func() {
+		net.addressBroadcaster.BroadcastMACAddresses(append(staticAddresses, dynamicAddresses...))
+		if errCh != nil {
+			errCh <- nil
+		}
+	}()
+
+	return nil
+}
+
+func (net netplanNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
+	interfaces := []string{}
+
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	if !net.fs.FileExists(netplanConfigPath) {
+		return interfaces, nil
+	}
+
+	config, err := net.fs.ReadFileString(netplanConfigPath)
+	if err != nil {
+		return interfaces, bosherr.WrapErrorf(err, "Reading %s", netplanConfigPath)
+	}
+
+	for _, iface := range interfacesByMacAddress {
+		if strings.Contains(config, "\n    "+iface+":\n") {
+			interfaces = append(interfaces, iface)
+		}
+	}
+
+	return interfaces, nil
+}
+
+// netplanInterfaceConfig holds all addresses of an interface
+// since dual-stack interfaces have IPv4 and IPv6 configurations
+type netplanInterfaceConfig struct {
+	Name       string
+	VLANID     int
+	Parent     string
+	Bond       *BondConfiguration
+	Bridge     *BridgeConfiguration
+	MTU        int
+	Routes     []RouteConfiguration
+	DHCP       bool
+	Addresses  []string
+	IPv6       bool
+	Gateway4   string
+	Gateway6   string
+	DNSServers []string
+	Domains    []string
+	// DHCP6 and AcceptRA acquire IPv6 address of dynamic interface via DHCPv6 or SLAAC
+	DHCP6    bool
+	AcceptRA bool
+	// RoutingPolicies are configurations of addresses routed by their own routing tables
+	RoutingPolicies []StaticInterfaceConfiguration
+}
+
+type netplanConfig struct {
+	Ethernets []netplanInterfaceConfig
+	Bonds     []netplanInterfaceConfig
+	Bridges   []netplanInterfaceConfig
+	VLANs     []netplanInterfaceConfig
+}
+
+const netplanConfigTemplate = `{{ define "interface" }}
+    {{ .Name }}:{{ if .VLANID }}
+      id: {{ .VLANID }}
+      link: {{ .Parent }}{{ end }}{{ with .Bond }}
+      interfaces: [{{ range $i, $s := .Slaves }}{{ if $i }}, {{ end }}{{ $s }}{{ end }}]{{ if or .Mode .Miimon }}
+      parameters:{{ if .Mode }}
+        mode: {{ .Mode }}{{ end }}{{ if .Miimon }}
+        mii-monitor-interval: {{ .Miimon }}{{ end }}{{ end }}{{ end }}{{ with .Bridge }}
+      interfaces: [{{ range $i, $p := .Ports }}{{ if $i }}, {{ end }}{{ $p }}{{ end }}]{{ end }}{{ if .DHCP }}
+      dhcp4: true{{ else }}
+      dhcp4: false{{ end }}{{ if .DHCP6 }}
+      dhcp6: true{{ end }}{{ if .AcceptRA }}
+      accept-ra: true{{ end }}{{ if .Addresses }}
+      addresses: [{{ range $i, $a := .Addresses }}{{ if $i }}, {{ end }}{{ $a }}{{ end }}]{{ end }}{{ if .IPv6 }}
+      accept-ra: false{{ end }}{{ if .Gateway4 }}
+      gateway4: {{ .Gateway4 }}{{ end }}{{ if .Gateway6 }}
+      gateway6: {{ .Gateway6 }}{{ end }}{{ if .MTU }}
+      mtu: {{ .MTU }}{{ end }}{{ if .Routes }}
+      routes:{{ range .Routes }}
+      - to: {{ .Destination }}{{ if .Gateway }}
+        via: {{ .Gateway }}{{ else }}
+        scope: link{{ end }}{{ if .Table }}
+        table: {{ .Table }}{{ end }}{{ end }}{{ end }}{{ if .RoutingPolicies }}
+      routing-policy:{{ range .RoutingPolicies }}
+      - from: {{ .Address }}
+        table: {{ .RoutingTable }}{{ end }}{{ end }}{{ if .DNSServers }}
+      nameservers:
+        addresses: [{{ range $i, $s := .DNSServers }}{{ if $i }}, {{ end }}{{ $s }}{{ end }}]{{ if .Domains }}
+        search: [{{ range $i, $d := .Domains }}{{ if $i }}, {{ end }}{{ $d }}{{ end }}]{{ end }}{{ end }}{{ end }}# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:{{ range .Ethernets }}{{ template "interface" . }}{{ end }}{{ if .Bonds }}
+  bonds:{{ range .Bonds }}{{ template "interface" . }}{{ end }}{{ end }}{{ if .Bridges }}
+  bridges:{{ range .Bridges }}{{ template "interface" . }}{{ end }}{{ end }}{{ if .VLANs }}
+  vlans:{{ range .VLANs }}{{ template "interface" . }}{{ end }}{{ end }}
+`
+
+func (net netplanNetManager) writeNetplanConfig(dhcpConfigs DHCPInterfaceConfigurations, staticConfigs StaticInterfaceConfigurations, dnsServers, domains []string) (bool, error) {
+	sort.Stable(dhcpConfigs)
+	sort.Stable(staticConfigs)
+
+	ifaceConfigs := []netplanInterfaceConfig{}
+
+	for _, dhcpConfig := range dhcpConfigs {
+		ifaceConfigs = append(ifaceConfigs, netplanInterfaceConfig{
+			Name:       dhcpConfig.Name,
+			VLANID:     dhcpConfig.VLANID,
+			Parent:     dhcpConfig.Parent,
+			Bond:       dhcpConfig.Bond,
+			Bridge:     dhcpConfig.Bridge,
+			MTU:        dhcpConfig.MTU,
+			Routes:     dhcpConfig.Routes,
+			DHCP:       true,
+			DNSServers: dnsServers,
+			Domains:    domains,
+			DHCP6:      dhcpConfig.IsDHCPv6(),
+			AcceptRA:   dhcpConfig.IPv6Mode != "",
+		})
+	}
+
+	for _, staticConfig := range staticConfigs {
+		prefixLength, err := netmaskPrefixLength(staticConfig.Netmask)
+		if err != nil {
+			return false, err
+		}
+
+		if n := len(ifaceConfigs); n == 0 || ifaceConfigs[n-1].Name != staticConfig.Name {
+			ifaceConfigs = append(ifaceConfigs, netplanInterfaceConfig{
+				Name:       staticConfig.Name,
+				VLANID:     staticConfig.VLANID,
+				Parent:     staticConfig.Parent,
+				Bond:       staticConfig.Bond,
+				Bridge:     staticConfig.Bridge,
+				MTU:        staticConfig.MTU,
+				DNSServers: dnsServers,
+				Domains:    domains,
+			})
+		}
+
+		ifaceConfig := &ifaceConfigs[len(ifaceConfigs)-1]
+		ifaceConfig.Addresses = append(ifaceConfig.Addresses, fmt.Sprintf("%s/%d", staticConfig.Address, prefixLength))
+		ifaceConfig.Routes = append(ifaceConfig.Routes, staticConfig.Routes...)
+
+		if staticConfig.RoutingTable != 0 {
+			ifaceConfig.RoutingPolicies = append(ifaceConfig.RoutingPolicies, staticConfig)
+		}
+
+		if staticConfig.IsVersion6() {
+			ifaceConfig.IPv6 = true
+			if staticConfig.IsDefaultForGateway {
+				ifaceConfig.Gateway6 = staticConfig.Gateway
+			}
+		} else if staticConfig.IsDefaultForGateway {
+			ifaceConfig.Gateway4 = staticConfig.Gateway
+		}
+	}
+
+	// VLANs must link to interfaces defined in ethernets
+	for _, name := range unconfiguredVLANParents(staticConfigs, dhcpConfigs) {
+		ifaceConfigs = append(ifaceConfigs, netplanInterfaceConfig{Name: name})
+	}
+
+	// Bonds must refer to interfaces defined in ethernets
+	for _, slave := range bondSlaves(staticConfigs, dhcpConfigs) {
+		ifaceConfigs = append(ifaceConfigs, netplanInterfaceConfig{Name: slave.Name})
+	}
+
+	// Bridges must refer to interfaces defined in ethernets
+	for _, port := range bridgePorts(staticConfigs, dhcpConfigs) {
+		ifaceConfigs = append(ifaceConfigs, netplanInterfaceConfig{Name: port.Name})
+	}
+
+	config := netplanConfig{}
+
+	for _, ifaceConfig := range ifaceConfigs {
+		switch {
+		case ifaceConfig.VLANID != 0:
+			config.VLANs = append(config.VLANs, ifaceConfig)
+		case ifaceConfig.Bond != nil:
+			config.Bonds = append(config.Bonds, ifaceConfig)
+		case ifaceConfig.Bridge != nil:
+			config.Bridges = append(config.Bridges, ifaceConfig)
+		default:
+			config.Ethernets = append(config.Ethernets, ifaceConfig)
+		}
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("netplan-config").Parse(netplanConfigTemplate))
+
+	err := t.Execute(buffer, config)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := net.fs.ConvergeFileContents(netplanConfigPath, buffer.Bytes())
+	if err != nil {
+		return changed, bosherr.WrapErrorf(err, "Writing to %s", netplanConfigPath)
+	}
+
+	return changed, nil
+}
+
+// netmaskPrefixLength converts netmask (e.g. 255.255.255.0 or ffff:ffff:ffff:ffff::) to prefix length (e.g. 24 or 64)
+func netmaskPrefixLength(netmask string) (int, error) {
+	ip := gonet.ParseIP(netmask)
+	if ip == nil {
+		return 0, bosherr.Errorf("Parsing netmask '%s'", netmask)
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	ones, bits := gonet.IPMask(ip).Size()
+	if bits == 0 {
+		return 0, bosherr.Errorf("Netmask '%s' is not canonical", netmask)
+	}
+
+	return ones, nil
+}
@@ -0,0 +1,692 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("netplanNetManager", func() {
+	var (
+		fs                     *fakesys.FakeFileSystem
+		cmdRunner              *fakesys.FakeCmdRunner
+		ipResolver             *fakeip.FakeResolver
+		addressBroadcaster     *fakearp.FakeAddressBroadcaster
+		interfaceAddrsProvider *fakeip.FakeInterfaceAddressesProvider
+		netManager             Manager
+	)
+
+	writeNetworkDevice := func(iface string, macAddress string) string {
+		interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+		fs.WriteFile(interfacePath, []byte{})
+		fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+		fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), fmt.Sprintf("%s\n", macAddress))
+
+		return interfacePath
+	}
+
+	stubInterfaces := func(physicalInterfaces map[string]boshsettings.Network) {
+		interfacePaths := []string{}
+		for iface, networkSettings := range physicalInterfaces {
+			interfacePaths = append(interfacePaths, writeNetworkDevice(iface, networkSettings.Mac))
+		}
+		fs.SetGlob("/sys/class/net/*", interfacePaths)
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		ipResolver = &fakeip.FakeResolver{}
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		interfaceAddrsProvider = &fakeip.FakeInterfaceAddressesProvider{}
+		netManager = NewNetplanNetManager(
+			fs,
+			cmdRunner,
+			ipResolver,
+			NewInterfaceConfigurationCreator(logger),
+			boship.NewInterfaceAddressesValidator(interfaceAddrsProvider),
+			NewResolvConfDNSValidator(fs, "/run/systemd/resolve/resolv.conf"),
+			addressBroadcaster,
+			logger,
+		)
+	})
+
+	Describe("SetupNetworking", func() {
+		var (
+			dhcpNetwork   boshsettings.Network
+			staticNetwork boshsettings.Network
+		)
+
+		BeforeEach(func() {
+			dhcpNetwork = boshsettings.Network{
+				Type:    "dynamic",
+				Default: []string{"dns"},
+				DNS:     []string{"8.8.8.8", "9.9.9.9"},
+				Mac:     "fake-dhcp-mac-address",
+			}
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Default: []string{"gateway"},
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Mac:     "fake-static-mac-address",
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 8.8.8.8\nnameserver 9.9.9.9\n")
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+		})
+
+		It("writes netplan configuration and applies it", func() {
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+      addresses: [1.2.3.4/24]
+      gateway4: 3.4.5.6
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"netplan", "apply"}}))
+		})
+
+		It("does not apply netplan configuration if it did not change", func() {
+			networks := boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred()) // wait for all arpings
+
+			err = netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"netplan", "apply"}}))
+		})
+
+		It("returns error if applying netplan configuration fails", func() {
+			cmdRunner.AddCmdResult("netplan apply", fakesys.FakeCmdResult{Error: errors.New("fake-netplan-err")})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Applying netplan configuration"))
+		})
+
+		It("writes link units pinning names of configured interfaces to their MAC addresses", func() {
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			linkUnit := fs.GetFileTestStat("/etc/systemd/network/10-bosh-ethstatic.link")
+			Expect(linkUnit).ToNot(BeNil())
+			Expect(linkUnit.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+MACAddress=fake-static-mac-address
+
+[Link]
+Name=ethstatic
+`))
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethdhcp.link")).To(BeTrue())
+		})
+
+		It("removes link units of interfaces that are no longer configured", func() {
+			fs.WriteFileString("/etc/systemd/network/10-bosh-ethold.link", "fake-link-unit")
+			fs.SetGlob("/etc/systemd/network/10-bosh-*.link", []string{
+				"/etc/systemd/network/10-bosh-ethold.link",
+				"/etc/systemd/network/10-bosh-ethstatic.link",
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethold.link")).To(BeFalse())
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethstatic.link")).To(BeTrue())
+		})
+
+		It("configures MTU of interfaces", func() {
+			dhcpNetwork.MTU = 9000
+			staticNetwork.MTU = 1400
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      mtu: 9000
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+      addresses: [1.2.3.4/24]
+      gateway4: 3.4.5.6
+      mtu: 1400
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+		})
+
+		It("configures static routes of interfaces", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0/8", Gateway: "1.2.3.1"},
+				{Destination: "172.16.0.0/12"},
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(ContainSubstring(`
+      gateway4: 3.4.5.6
+      routes:
+      - to: 10.0.0.0/8
+        via: 1.2.3.1
+      - to: 172.16.0.0/12
+        scope: link
+`))
+		})
+
+		It("configures routing policy when multiple networks have gateways", func() {
+			secondStaticNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Gateway: "5.6.7.1",
+				Mac:     dhcpNetwork.Mac,
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-1": staticNetwork, "static-2": secondStaticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(ContainSubstring(`
+    ethdhcp:
+      dhcp4: false
+      addresses: [5.6.7.8/24]
+      routes:
+      - to: 5.6.7.0/24
+        scope: link
+        table: 100
+      - to: 0.0.0.0/0
+        via: 5.6.7.1
+        table: 100
+      routing-policy:
+      - from: 5.6.7.8
+        table: 100
+`))
+		})
+
+		It("configures IPv6 gateway and disables autoconfiguration for IPv6 networks", func() {
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::1234",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     "fake-static-mac-address",
+				DNS:     []string{"2001:4860:4860::8888"},
+				Default: []string{"gateway", "dns"},
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 2001:4860:4860::8888\n")
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": ipv6Network}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [2001:4860:4860::8888]
+    ethstatic:
+      dhcp4: false
+      addresses: [2001:db8::1234/64]
+      accept-ra: false
+      gateway6: 2001:db8::1
+      nameservers:
+        addresses: [2001:4860:4860::8888]
+`))
+
+			sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+			Expect(sysctlConfig).ToNot(BeNil())
+			Expect(sysctlConfig.StringContents()).To(ContainSubstring("net.ipv6.conf.ethstatic.autoconf = 0\n"))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"sysctl", "-p", "/etc/sysctl.d/60-bosh-ipv6.conf"},
+				{"netplan", "apply"},
+			}))
+		})
+
+		It("configures IPv4 and IPv6 addresses of dual-stack interface together", func() {
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::1234",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     staticNetwork.Mac,
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network":   dhcpNetwork,
+				"static-network": staticNetwork,
+				"ipv6-network":   ipv6Network,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(ContainSubstring(`
+    ethstatic:
+      dhcp4: false
+      addresses: [1.2.3.4/24, 2001:db8::1234/64]
+      accept-ra: false
+      gateway4: 3.4.5.6
+      gateway6: 2001:db8::1
+`))
+		})
+
+		It("writes VLAN sub-interfaces linked to interfaces without networks of their own", func() {
+			vlanNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::1234",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Mac:     staticNetwork.Mac,
+				VLANID:  123,
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic.123", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network": dhcpNetwork,
+				"vlan-network": vlanNetwork,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+  vlans:
+    ethstatic.123:
+      id: 123
+      link: ethstatic
+      dhcp4: false
+      addresses: [2001:db8::1234/64]
+      accept-ra: false
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+
+			sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+			Expect(sysctlConfig).ToNot(BeNil())
+			Expect(sysctlConfig.StringContents()).To(ContainSubstring("net.ipv6.conf.ethstatic/123.autoconf = 0\n"))
+		})
+
+		It("writes bonds over interfaces defined in ethernets", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Bond: &boshsettings.Bond{
+					Mode:   "active-backup",
+					Miimon: 100,
+					Slaves: []string{staticNetwork.Mac},
+				},
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network": dhcpNetwork,
+				"bond-network": bondNetwork,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+  bonds:
+    bond0:
+      interfaces: [ethstatic]
+      parameters:
+        mode: active-backup
+        mii-monitor-interval: 100
+      dhcp4: false
+      addresses: [5.6.7.8/24]
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+		})
+
+		It("writes bridges over interfaces defined in ethernets", func() {
+			staticNetwork.Bridge = "br0"
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("br0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network":   dhcpNetwork,
+				"static-network": staticNetwork,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+  bridges:
+    br0:
+      interfaces: [ethstatic]
+      dhcp4: false
+      addresses: [1.2.3.4/24]
+      gateway4: 3.4.5.6
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+		})
+
+		It("skips vip networks and broadcasts MAC addresses", func() {
+			vipNetwork := boshsettings.Network{Type: "vip", IP: "9.8.7.6", Mac: "fake-vip-mac-address"}
+
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network":   dhcpNetwork,
+				"static-network": staticNetwork,
+				"vip-network":    vipNetwork,
+			}, errCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			broadcastErr := <-errCh
+			Expect(broadcastErr).ToNot(HaveOccurred())
+
+			Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(Equal([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewResolvingInterfaceAddress("ethdhcp", ipResolver),
+			}))
+		})
+
+		Context("when network requests SR-IOV virtual functions", func() {
+			BeforeEach(func() {
+				staticNetwork.SRIOV = &boshsettings.SRIOV{
+					NumVFs: 2,
+					VFs:    []boshsettings.SRIOVVirtualFunction{{MAC: "cc:cc:cc:cc:cc:cc", VLANID: 100}},
+				}
+
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_totalvfs", "8\n")
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_numvfs", "0\n")
+			})
+
+			It("creates virtual functions on the physical function and assigns their MAC addresses and VLANs", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				numVFs, err := fs.ReadFileString("/sys/class/net/ethstatic/device/sriov_numvfs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numVFs).To(Equal("2"))
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "mac", "cc:cc:cc:cc:cc:cc"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "vlan", "100"}))
+			})
+
+			It("does not configure virtual functions with networks", func() {
+				vfPath := writeNetworkDevice("ethvf0", "cc:cc:cc:cc:cc:cc")
+				fs.WriteFile("/sys/class/net/ethvf0/device/physfn", []byte{})
+				fs.SetGlob("/sys/class/net/*", []string{"/sys/class/net/ethdhcp", "/sys/class/net/ethstatic", vfPath})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				netplanConfig, err := fs.ReadFileString("/etc/netplan/99-bosh.yaml")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(netplanConfig).To(ContainSubstring("ethstatic"))
+				Expect(netplanConfig).ToNot(ContainSubstring("ethvf0"))
+			})
+
+			It("returns error if physical function does not support SR-IOV", func() {
+				fs.RemoveAll("/sys/class/net/ethstatic/device/sriov_totalvfs")
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Configuring SR-IOV virtual functions"))
+				Expect(err.Error()).To(ContainSubstring("Interface 'ethstatic' does not support SR-IOV"))
+			})
+		})
+
+		It("acquires IPv6 address of dynamic interfaces via DHCPv6 or SLAAC", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeDHCPv6
+			slaacNetwork := boshsettings.Network{
+				Type:     "dynamic",
+				Mac:      "fake-slaac-mac-address",
+				IPv6Mode: boshsettings.IPv6ModeSLAAC,
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethslaac":  slaacNetwork,
+				"ethstatic": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::10"),
+				boship.NewSimpleInterfaceAddress("ethslaac", "2001:db8::f816:3eff:fe00:1"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"dhcp-network":   dhcpNetwork,
+				"slaac-network":  slaacNetwork,
+				"static-network": staticNetwork,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      dhcp6: true
+      accept-ra: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethslaac:
+      dhcp4: true
+      accept-ra: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+      addresses: [1.2.3.4/24]
+      gateway4: 3.4.5.6
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+`))
+		})
+
+		It("fails when dynamic interface only acquired temporary IPv6 addresses", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeSLAAC
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewTemporaryInterfaceAddress("ethdhcp", "2001:db8::a1b2:c3d4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating dynamic IPv6 network configuration"))
+		})
+
+		It("writes search domains of the default dns network", func() {
+			dhcpNetwork.Domains = []string{"example.com", "internal"}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netplanConfig := fs.GetFileTestStat("/etc/netplan/99-bosh.yaml")
+			Expect(netplanConfig).ToNot(BeNil())
+			Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+        search: [example.com, internal]
+    ethstatic:
+      dhcp4: false
+      addresses: [1.2.3.4/24]
+      gateway4: 3.4.5.6
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+        search: [example.com, internal]
+`))
+		})
+
+		It("fails when dns servers are not used by systemd-resolved", func() {
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "")
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating dns configuration"))
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				dhcpNetwork.Preconfigured = true
+				staticNetwork.Preconfigured = true
+			})
+
+			It("configures dns servers for systemd-resolved", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedConf := fs.GetFileTestStat("/etc/systemd/resolved.conf.d/bosh.conf")
+				Expect(resolvedConf).ToNot(BeNil())
+				Expect(resolvedConf.StringContents()).To(Equal("# Generated by bosh-agent\n[Resolve]\nDNS=8.8.8.8 9.9.9.9\n"))
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-resolved"}}))
+				Expect(fs.FileExists("/etc/netplan/99-bosh.yaml")).To(BeFalse())
+			})
+
+			It("configures search domains for systemd-resolved", func() {
+				dhcpNetwork.Domains = []string{"example.com", "internal"}
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedConf := fs.GetFileTestStat("/etc/systemd/resolved.conf.d/bosh.conf")
+				Expect(resolvedConf).ToNot(BeNil())
+				Expect(resolvedConf.StringContents()).To(Equal("# Generated by bosh-agent\n[Resolve]\nDNS=8.8.8.8 9.9.9.9\nDomains=example.com internal\n"))
+			})
+		})
+	})
+
+	Describe("GetConfiguredNetworkInterfaces", func() {
+		BeforeEach(func() {
+			fs.SetGlob("/sys/class/net/*", []string{
+				writeNetworkDevice("fake-eth0", "aa:bb"),
+				writeNetworkDevice("fake-eth1", "cc:dd"),
+			})
+		})
+
+		It("returns interfaces that are defined in netplan configuration", func() {
+			fs.WriteFileString("/etc/netplan/99-bosh.yaml", `# Generated by bosh-agent
+network:
+  version: 2
+  ethernets:
+    fake-eth1:
+      dhcp4: true
+`)
+
+			interfaces, err := netManager.GetConfiguredNetworkInterfaces()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{"fake-eth1"}))
+		})
+
+		It("returns empty list when netplan configuration has not been written", func() {
+			interfaces, err := netManager.GetConfiguredNetworkInterfaces()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{}))
+		})
+	})
+})
@@ -0,0 +1,312 @@
+package net
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// NetworkChanges are changes that setting up networking would make without applying them
+type NetworkChanges struct {
+	// Files are diffs of files that would be written or removed keyed by their path
+	Files map[string]string `json:"files"`
+
+	// Commands would be run to apply configuration, e.g. restarting networking service
+	Commands []string `json:"commands"`
+
+	// LiveState describes interfaces whose current addresses differ from desired ones
+	LiveState []string `json:"live_state"`
+}
+
+// NetworkChangePreviewer renders network configuration without applying it
+type NetworkChangePreviewer interface {
+	PreviewNetworking(networks boshsettings.Networks) (NetworkChanges, error)
+}
+
+// DryRunManagerFactory returns manager that makes all changes through given
+// file system and command runner and validates through given validators
+type DryRunManagerFactory func(
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	dnsValidator DNSValidator,
+	addressBroadcaster bosharp.AddressBroadcaster,
+) Manager
+
+type networkChangePreviewer struct {
+	fs                          boshsys.FileSystem
+	cmdRunner                   boshsys.CmdRunner
+	interfaceAddressesValidator boship.InterfaceAddressesValidator
+	newManager                  DryRunManagerFactory
+}
+
+func NewNetworkChangePreviewer(
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	newManager DryRunManagerFactory,
+) NetworkChangePreviewer {
+	return networkChangePreviewer{
+		fs:                          fs,
+		cmdRunner:                   cmdRunner,
+		interfaceAddressesValidator: interfaceAddressesValidator,
+		newManager:                  newManager,
+	}
+}
+
+// PreviewNetworking sets up networking with a manager that records file changes and commands
+// instead of applying them; address validation failures are reported as live state differences
+func (p networkChangePreviewer) PreviewNetworking(networks boshsettings.Networks) (NetworkChanges, error) {
+	dryRunFs := newDryRunFileSystem(p.fs)
+	dryRunCmdRunner := &dryRunCmdRunner{CmdRunner: p.cmdRunner}
+	dryRunValidator := &dryRunInterfaceAddressesValidator{delegate: p.interfaceAddressesValidator}
+
+	manager := p.newManager(dryRunFs, dryRunCmdRunner, dryRunValidator, dryRunDNSValidator{}, dryRunAddressBroadcaster{})
+
+	err := manager.SetupNetworking(networks, nil)
+	if err != nil {
+		return NetworkChanges{}, bosherr.WrapError(err, "Rendering network configuration")
+	}
+
+	changes := NetworkChanges{
+		Files:     map[string]string{},
+		Commands:  dryRunCmdRunner.commands,
+		LiveState: dryRunValidator.differences,
+	}
+
+	for _, filePath := range dryRunFs.changedPaths() {
+		currentExists := p.fs.FileExists(filePath)
+		desiredContents, desiredExists := dryRunFs.contents[filePath]
+
+		if !currentExists && !desiredExists {
+			continue
+		}
+
+		var currentContents []byte
+		if currentExists {
+			currentContents, err = p.fs.ReadFile(filePath)
+			if err != nil {
+				return NetworkChanges{}, bosherr.WrapErrorf(err, "Reading %s", filePath)
+			}
+
+			if desiredExists && bytes.Equal(currentContents, desiredContents) {
+				continue
+			}
+		}
+
+		changes.Files[filePath] = diffLines(filePath, currentContents, desiredContents, currentExists, desiredExists)
+	}
+
+	return changes, nil
+}
+
+// dryRunFileSystem keeps written and removed files in memory
+// and reads other files from the underlying file system
+type dryRunFileSystem struct {
+	boshsys.FileSystem
+
+	// contents of removed files are deleted from the map while their path stays recorded
+	contents map[string][]byte
+	paths    map[string]bool
+}
+
+func newDryRunFileSystem(fs boshsys.FileSystem) *dryRunFileSystem {
+	return &dryRunFileSystem{
+		FileSystem: fs,
+		contents:   map[string][]byte{},
+		paths:      map[string]bool{},
+	}
+}
+
+func (fs *dryRunFileSystem) WriteFile(path string, content []byte) error {
+	fs.contents[path] = content
+	fs.paths[path] = true
+	return nil
+}
+
+func (fs *dryRunFileSystem) WriteFileString(path, content string) error {
+	return fs.WriteFile(path, []byte(content))
+}
+
+func (fs *dryRunFileSystem) ConvergeFileContents(path string, content []byte) (bool, error) {
+	if fs.FileExists(path) {
+		currentContent, err := fs.ReadFile(path)
+		if err == nil && bytes.Equal(currentContent, content) {
+			return false, nil
+		}
+	}
+
+	return true, fs.WriteFile(path, content)
+}
+
+func (fs *dryRunFileSystem) ReadFile(path string) ([]byte, error) {
+	if content, found := fs.contents[path]; found {
+		return content, nil
+	}
+
+	if fs.paths[path] {
+		return nil, bosherr.Errorf("File %s is removed", path)
+	}
+
+	return fs.FileSystem.ReadFile(path)
+}
+
+func (fs *dryRunFileSystem) ReadFileString(path string) (string, error) {
+	content, err := fs.ReadFile(path)
+	return string(content), err
+}
+
+func (fs *dryRunFileSystem) FileExists(path string) bool {
+	if _, found := fs.contents[path]; found {
+		return true
+	}
+
+	if fs.paths[path] {
+		return false
+	}
+
+	return fs.FileSystem.FileExists(path)
+}
+
+func (fs *dryRunFileSystem) RemoveAll(path string) error {
+	delete(fs.contents, path)
+	fs.paths[path] = true
+	return nil
+}
+
+func (fs *dryRunFileSystem) MkdirAll(path string, perm os.FileMode) error { return nil }
+
+func (fs *dryRunFileSystem) Chown(path, username string) error { return nil }
+
+func (fs *dryRunFileSystem) Chmod(path string, perm os.FileMode) error { return nil }
+
+func (fs *dryRunFileSystem) Rename(oldPath, newPath string) error { return nil }
+
+func (fs *dryRunFileSystem) Symlink(oldPath, newPath string) error { return nil }
+
+func (fs *dryRunFileSystem) changedPaths() []string {
+	paths := []string{}
+	for path := range fs.paths {
+		paths = append(paths, path)
+	}
+
+	sort.Strings(paths)
+
+	return paths
+}
+
+// dryRunCmdRunner records commands instead of running them
+type dryRunCmdRunner struct {
+	boshsys.CmdRunner
+	commands []string
+}
+
+func (r *dryRunCmdRunner) RunComplexCommand(cmd boshsys.Command) (string, string, int, error) {
+	return r.RunCommand(cmd.Name, cmd.Args...)
+}
+
+func (r *dryRunCmdRunner) RunComplexCommandAsync(cmd boshsys.Command) (boshsys.Process, error) {
+	return nil, bosherr.Errorf("Running '%s' asynchronously is not supported in dry run", cmd.Name)
+}
+
+func (r *dryRunCmdRunner) RunCommand(cmdName string, args ...string) (string, string, int, error) {
+	r.commands = append(r.commands, strings.Join(append([]string{cmdName}, args...), " "))
+	return "", "", 0, nil
+}
+
+func (r *dryRunCmdRunner) RunCommandWithInput(input, cmdName string, args ...string) (string, string, int, error) {
+	return r.RunCommand(cmdName, args...)
+}
+
+// dryRunInterfaceAddressesValidator records differences from current addresses instead of failing
+type dryRunInterfaceAddressesValidator struct {
+	delegate    boship.InterfaceAddressesValidator
+	differences []string
+}
+
+func (v *dryRunInterfaceAddressesValidator) Validate(desiredInterfaceAddresses []boship.InterfaceAddress) error {
+	err := v.delegate.Validate(desiredInterfaceAddresses)
+	if err != nil {
+		v.differences = append(v.differences, err.Error())
+	}
+
+	return nil
+}
+
+// dryRunDNSValidator skips validation since dns servers are only configured when changes are applied
+type dryRunDNSValidator struct{}
+
+func (v dryRunDNSValidator) Validate([]string) error { return nil }
+
+type dryRunAddressBroadcaster struct{}
+
+func (b dryRunAddressBroadcaster) BroadcastMACAddresses([]boship.InterfaceAddress) {}
+
+// diffLines returns lines of both contents prefixed with '-' when only current contents have them,
+// '+' when only desired contents have them and ' ' when both have them
+func diffLines(filePath string, currentContents, desiredContents []byte, currentExists, desiredExists bool) string {
+	currentLabel, desiredLabel := filePath, filePath
+	if !currentExists {
+		currentLabel = "/dev/null"
+	}
+	if !desiredExists {
+		desiredLabel = "/dev/null"
+	}
+
+	current := splitLines(currentContents)
+	desired := splitLines(desiredContents)
+
+	// lengths[i][j] is a length of the longest common subsequence of current[i:] and desired[j:]
+	lengths := make([][]int, len(current)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(desired)+1)
+	}
+
+	for i := len(current) - 1; i >= 0; i-- {
+		for j := len(desired) - 1; j >= 0; j-- {
+			switch {
+			case current[i] == desired[j]:
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			case lengths[i+1][j] >= lengths[i][j+1]:
+				lengths[i][j] = lengths[i+1][j]
+			default:
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	lines := []string{"--- " + currentLabel, "+++ " + desiredLabel}
+
+	i, j := 0, 0
+	for i < len(current) || j < len(desired) {
+		switch {
+		case i < len(current) && j < len(desired) && current[i] == desired[j]:
+			lines = append(lines, " "+current[i])
+			i++
+			j++
+		case j == len(desired) || (i < len(current) && lengths[i+1][j] >= lengths[i][j+1]):
+			lines = append(lines, "-"+current[i])
+			i++
+		default:
+			lines = append(lines, "+"+desired[j])
+			j++
+		}
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func splitLines(contents []byte) []string {
+	if len(contents) == 0 {
+		return []string{}
+	}
+
+	return strings.Split(strings.TrimSuffix(string(contents), "\n"), "\n")
+}
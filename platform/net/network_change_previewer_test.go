@@ -0,0 +1,170 @@
+package net_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("NetworkChangePreviewer", func() {
+	var (
+		fs                     *fakesys.FakeFileSystem
+		cmdRunner              *fakesys.FakeCmdRunner
+		interfaceAddrsProvider *fakeip.FakeInterfaceAddressesProvider
+		previewer              NetworkChangePreviewer
+		networks               boshsettings.Networks
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		interfaceAddrsProvider = &fakeip.FakeInterfaceAddressesProvider{}
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+
+		previewer = NewNetworkChangePreviewer(fs, cmdRunner, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider), func(
+			fs boshsys.FileSystem,
+			cmdRunner boshsys.CmdRunner,
+			interfaceAddressesValidator boship.InterfaceAddressesValidator,
+			dnsValidator DNSValidator,
+			addressBroadcaster bosharp.AddressBroadcaster,
+		) Manager {
+			return NewSystemdNetworkdNetManager(
+				fs,
+				cmdRunner,
+				&fakeip.FakeResolver{},
+				NewInterfaceConfigurationCreator(logger),
+				interfaceAddressesValidator,
+				dnsValidator,
+				addressBroadcaster,
+				logger,
+			)
+		})
+
+		fs.WriteFile("/sys/class/net/eth0", []byte{})
+		fs.WriteFile("/sys/class/net/eth0/device", []byte{})
+		fs.WriteFileString("/sys/class/net/eth0/address", "aa:aa:aa:aa:aa:aa\n")
+		fs.SetGlob("/sys/class/net/*", []string{"/sys/class/net/eth0"})
+
+		networks = boshsettings.Networks{
+			"default": boshsettings.Network{
+				Type:    "manual",
+				IP:      "10.0.0.6",
+				Netmask: "255.255.255.0",
+				Gateway: "10.0.0.1",
+				Default: []string{"gateway", "dns"},
+				DNS:     []string{"8.8.8.8"},
+				Mac:     "aa:aa:aa:aa:aa:aa",
+			},
+		}
+
+		interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+			boship.NewSimpleInterfaceAddress("eth0", "10.0.0.6"),
+		}
+	})
+
+	It("returns diffs of files that would be written without writing them", func() {
+		fs.WriteFileString("/etc/systemd/network/10-bosh-eth0.network", `# Generated by bosh-agent
+[Match]
+Name=eth0
+
+[Network]
+Address=10.0.0.5/24
+Gateway=10.0.0.1
+DNS=8.8.8.8
+`)
+
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(changes.Files["/etc/systemd/network/10-bosh-eth0.network"]).To(Equal(`--- /etc/systemd/network/10-bosh-eth0.network
++++ /etc/systemd/network/10-bosh-eth0.network
+ # Generated by bosh-agent
+ [Match]
+ Name=eth0
+ 
+ [Network]
+-Address=10.0.0.5/24
++Address=10.0.0.6/24
+ Gateway=10.0.0.1
+ DNS=8.8.8.8
+`))
+
+		contents, err := fs.ReadFileString("/etc/systemd/network/10-bosh-eth0.network")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(ContainSubstring("Address=10.0.0.5/24"))
+	})
+
+	It("returns new files as added to /dev/null", func() {
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(changes.Files["/etc/systemd/network/10-bosh-eth0.link"]).To(HavePrefix("--- /dev/null\n+++ /etc/systemd/network/10-bosh-eth0.link\n+# Generated by bosh-agent\n"))
+		Expect(fs.FileExists("/etc/systemd/network/10-bosh-eth0.link")).To(BeFalse())
+	})
+
+	It("returns files that would be removed", func() {
+		fs.WriteFileString("/etc/systemd/network/10-bosh-eth1.network", "fake-unit\n")
+		fs.SetGlob("/etc/systemd/network/10-bosh-*", []string{"/etc/systemd/network/10-bosh-eth1.network"})
+
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(changes.Files["/etc/systemd/network/10-bosh-eth1.network"]).To(Equal(`--- /etc/systemd/network/10-bosh-eth1.network
++++ /dev/null
+-fake-unit
+`))
+		Expect(fs.FileExists("/etc/systemd/network/10-bosh-eth1.network")).To(BeTrue())
+	})
+
+	It("omits files that would not change", func() {
+		fs.WriteFileString("/etc/systemd/network/10-bosh-eth0.network", `# Generated by bosh-agent
+[Match]
+Name=eth0
+
+[Network]
+Address=10.0.0.6/24
+Gateway=10.0.0.1
+DNS=8.8.8.8
+`)
+
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(changes.Files).ToNot(HaveKey("/etc/systemd/network/10-bosh-eth0.network"))
+	})
+
+	It("returns commands that would be run without running them", func() {
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(changes.Commands).To(ContainElement("systemctl restart systemd-networkd"))
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	It("returns differences of live interface addresses", func() {
+		interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+			boship.NewSimpleInterfaceAddress("eth0", "10.0.0.5"),
+		}
+
+		changes, err := previewer.PreviewNetworking(networks)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(changes.LiveState).To(HaveLen(1))
+		Expect(changes.LiveState[0]).To(ContainSubstring("Validating network interface 'eth0' IP addresses, expected: '10.0.0.6', actual: '10.0.0.5'"))
+	})
+
+	It("returns an error when network configuration cannot be rendered", func() {
+		networks["default"] = boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0", Mac: "bb:bb:bb:bb:bb:bb"}
+
+		_, err := previewer.PreviewNetworking(networks)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Rendering network configuration"))
+	})
+})
@@ -0,0 +1,64 @@
+package net
+
+import (
+	"bytes"
+	"text/template"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const resolvedConfigPath = "/etc/systemd/resolved.conf.d/bosh.conf"
+
+const resolvedConfTemplate = `# Generated by bosh-agent
+[Resolve]
+DNS={{ range $i, $s := .DNSServers }}{{ if $i }} {{ end }}{{ $s }}{{ end }}{{ if .Domains }}
+Domains={{ range $i, $d := .Domains }}{{ if $i }} {{ end }}{{ $d }}{{ end }}{{ end }}
+`
+
+// DNSManager configures name resolution of the system
+type DNSManager interface {
+	SetupDNS(dnsServers, domains []string) error
+}
+
+type resolvedDNSManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+}
+
+// NewResolvedDNSManager returns manager that programs systemd-resolved
+// with a drop-in configuration instead of rewriting /etc/resolv.conf
+func NewResolvedDNSManager(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner) DNSManager {
+	return resolvedDNSManager{fs: fs, cmdRunner: cmdRunner}
+}
+
+func (m resolvedDNSManager) SetupDNS(dnsServers, domains []string) error {
+	return writeResolvedConf(m.fs, m.cmdRunner, dnsServers, domains)
+}
+
+// writeResolvedConf configures dns servers and search domains for systemd-resolved
+// and restarts it if configuration changed
+func writeResolvedConf(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, dnsServers, domains []string) error {
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("resolved-conf").Parse(resolvedConfTemplate))
+
+	err := t.Execute(buffer, struct{ DNSServers, Domains []string }{dnsServers, domains})
+	if err != nil {
+		return bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := fs.ConvergeFileContents(resolvedConfigPath, buffer.Bytes())
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", resolvedConfigPath)
+	}
+
+	if changed {
+		_, _, _, err = cmdRunner.RunCommand("systemctl", "restart", "systemd-resolved")
+		if err != nil {
+			return bosherr.WrapError(err, "Restarting systemd-resolved")
+		}
+	}
+
+	return nil
+}
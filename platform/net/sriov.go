@@ -0,0 +1,134 @@
+package net
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// configureSRIOV creates virtual functions requested by networks on their physical functions
+// and assigns MAC addresses and VLANs to virtual functions; physical functions keep their networks
+func configureSRIOV(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, networks boshsettings.Networks) error {
+	networkNames := []string{}
+	for name, networkSettings := range networks {
+		if networkSettings.SRIOV != nil {
+			networkNames = append(networkNames, name)
+		}
+	}
+
+	if len(networkNames) == 0 {
+		return nil
+	}
+
+	sort.Strings(networkNames)
+
+	interfacesByMacAddress, err := detectMacAddresses(fs)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	for _, name := range networkNames {
+		networkSettings := networks[name]
+		sriov := networkSettings.SRIOV
+
+		if networkSettings.Mac == "" {
+			return bosherr.Errorf("SR-IOV network '%s' has no MAC address of physical function", name)
+		}
+
+		pfName, found := interfacesByMacAddress[networkSettings.Mac]
+		if !found {
+			return bosherr.Errorf("No device found for SR-IOV network '%s' with MAC address '%s'", name, networkSettings.Mac)
+		}
+
+		if sriov.NumVFs < 0 {
+			return bosherr.Errorf("Number of virtual functions '%d' of network '%s' cannot be negative", sriov.NumVFs, name)
+		}
+
+		if len(sriov.VFs) > sriov.NumVFs {
+			return bosherr.Errorf("Network '%s' assigns %d virtual functions but only %d are created", name, len(sriov.VFs), sriov.NumVFs)
+		}
+
+		for i, vf := range sriov.VFs {
+			if vf.VLANID < 0 || vf.VLANID > maxVLANID {
+				return bosherr.Errorf("VLAN ID '%d' of virtual function %d of network '%s' is out of range 1-%d", vf.VLANID, i, name, maxVLANID)
+			}
+		}
+
+		err = setNumVFs(fs, pfName, sriov.NumVFs)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating virtual functions of network '%s'", name)
+		}
+
+		for i, vf := range sriov.VFs {
+			if vf.MAC != "" {
+				_, _, _, err = cmdRunner.RunCommand("ip", "link", "set", pfName, "vf", strconv.Itoa(i), "mac", vf.MAC)
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Assigning MAC address to virtual function %d of '%s'", i, pfName)
+				}
+			}
+
+			if vf.VLANID != 0 {
+				_, _, _, err = cmdRunner.RunCommand("ip", "link", "set", pfName, "vf", strconv.Itoa(i), "vlan", strconv.Itoa(vf.VLANID))
+				if err != nil {
+					return bosherr.WrapErrorf(err, "Assigning VLAN to virtual function %d of '%s'", i, pfName)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func setNumVFs(fs boshsys.FileSystem, pfName string, numVFs int) error {
+	devicePath := path.Join("/sys/class/net", pfName, "device")
+	totalVFsPath := path.Join(devicePath, "sriov_totalvfs")
+	numVFsPath := path.Join(devicePath, "sriov_numvfs")
+
+	if !fs.FileExists(totalVFsPath) {
+		return bosherr.Errorf("Interface '%s' does not support SR-IOV", pfName)
+	}
+
+	totalVFs, err := fs.ReadFileString(totalVFsPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading %s", totalVFsPath)
+	}
+
+	maxVFs, err := strconv.Atoi(strings.TrimSpace(totalVFs))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Parsing %s", totalVFsPath)
+	}
+
+	if numVFs > maxVFs {
+		return bosherr.Errorf("Interface '%s' supports at most %d virtual functions", pfName, maxVFs)
+	}
+
+	currentVFs, err := fs.ReadFileString(numVFsPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Reading %s", numVFsPath)
+	}
+
+	currentVFs = strings.TrimSpace(currentVFs)
+	if currentVFs == strconv.Itoa(numVFs) {
+		return nil
+	}
+
+	// Kernel refuses to change number of virtual functions without removing existing ones first
+	if currentVFs != "0" {
+		err = fs.WriteFileString(numVFsPath, "0")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", numVFsPath)
+		}
+	}
+
+	err = fs.WriteFileString(numVFsPath, strconv.Itoa(numVFs))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", numVFsPath)
+	}
+
+	return nil
+}
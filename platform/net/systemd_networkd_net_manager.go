@@ -0,0 +1,450 @@
+package net
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"sort"
+	"text/template"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	systemdNetworkdNetManagerLogTag = "systemdNetworkdNetManager"
+
+	systemdNetworkDir = "/etc/systemd/network"
+
+	// Units generated by the agent are prefixed so that they sort before
+	// units shipped with the stemcell and can be told apart from them
+	systemdNetworkUnitPrefix = "10-bosh-"
+)
+
+type systemdNetworkdNetManager struct {
+	fs                            boshsys.FileSystem
+	cmdRunner                     boshsys.CmdRunner
+	ipResolver                    boship.Resolver
+	interfaceConfigurationCreator InterfaceConfigurationCreator
+	interfaceAddressesValidator   boship.InterfaceAddressesValidator
+	dnsValidator                  DNSValidator
+	addressBroadcaster            bosharp.AddressBroadcaster
+	logger                        boshlog.Logger
+}
+
+// NewSystemdNetworkdNetManager returns manager that writes systemd-networkd units
+// (.link units pinning interface names to MAC addresses and .network units with
+// addresses) and restarts systemd-networkd; usable on any systemd based stemcell
+func NewSystemdNetworkdNetManager(
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	ipResolver boship.Resolver,
+	interfaceConfigurationCreator InterfaceConfigurationCreator,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	dnsValidator DNSValidator,
+	addressBroadcaster bosharp.AddressBroadcaster,
+	logger boshlog.Logger,
+) Manager {
+	return systemdNetworkdNetManager{
+		fs:                            fs,
+		cmdRunner:                     cmdRunner,
+		ipResolver:                    ipResolver,
+		interfaceConfigurationCreator: interfaceConfigurationCreator,
+		interfaceAddressesValidator:   interfaceAddressesValidator,
+		dnsValidator:                  dnsValidator,
+		addressBroadcaster:            addressBroadcaster,
+		logger:                        logger,
+	}
+}
+
+func (net systemdNetworkdNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
+	if networks.IsPreconfigured() {
+		// Note in this case IPs are not broadcasted
+		dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+		return writeResolvedConf(net.fs, net.cmdRunner, dnsNetwork.DNS, dnsNetwork.Domains)
+	}
+
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	err := configureSRIOV(net.fs, net.cmdRunner, nonVipNetworks)
+	if err != nil {
+		return bosherr.WrapError(err, "Configuring SR-IOV virtual functions")
+	}
+
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	staticConfigs, dhcpConfigs, err := net.interfaceConfigurationCreator.CreateInterfaceConfigurations(nonVipNetworks, interfacesByMacAddress)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating interface configurations")
+	}
+
+	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
+	dnsServers := dnsNetwork.DNS
+
+	changed, err := net.writeNetworkUnits(interfacesByMacAddress, dhcpConfigs, staticConfigs, dnsServers, dnsNetwork.Domains)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	err = writeIPv6Sysctls(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing IPv6 kernel parameters")
+	}
+
+	if changed {
+		_, _, _, err = net.cmdRunner.RunCommand("systemctl", "restart", "systemd-networkd")
+		if err != nil {
+			return bosherr.WrapError(err, "Restarting systemd-networkd")
+		}
+	}
+
+	err = applyInterfaceTuning(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying interface tuning")
+	}
+
+	staticAddresses := staticInterfaceAddresses(staticConfigs)
+	dynamicAddresses := dynamicInterfaceAddresses(dhcpConfigs, net.ipResolver)
+
+	err = net.interfaceAddressesValidator.Validate(staticAddresses)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating static network configuration")
+	}
+
+	dynamicIPv6Addrs := dynamicIPv6Addresses(dhcpConfigs)
+	if len(dynamicIPv6Addrs) > 0 {
+		err = net.interfaceAddressesValidator.Validate(dynamicIPv6Addrs)
+		if err != nil {
+			return bosherr.WrapError(err, "Validating dynamic IPv6 network configuration")
+		}
+	}
+
+	err = net.dnsValidator.Validate(dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating dns configuration")
+	}
+
+	go func() {
+		net.addressBroadcaster.BroadcastMACAddresses(append(staticAddresses, dynamicAddresses...))
+		if errCh != nil {
+			errCh <- nil
+		}
+	}()
+
+	return nil
+}
+
+func (net systemdNetworkdNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
+	interfaces := []string{}
+
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	for _, iface := range interfacesByMacAddress {
+		if net.fs.FileExists(systemdNetworkUnitPath(iface, "network")) {
+			interfaces = append(interfaces, iface)
+		}
+	}
+
+	return interfaces, nil
+}
+
+func systemdNetworkUnitPath(ifaceName, unitType string) string {
+	return path.Join(systemdNetworkDir, fmt.Sprintf("%s%s.%s", systemdNetworkUnitPrefix, ifaceName, unitType))
+}
+
+type systemdNetworkUnitConfig struct {
+	Name       string
+	MacAddress string
+	DHCP       bool
+	// DHCP6 and AcceptRA acquire IPv6 address of dynamic interface via DHCPv6 or SLAAC
+	DHCP6      bool
+	AcceptRA   bool
+	Addresses  []string
+	IPv6       bool
+	Gateways   []string
+	DNSServers []string
+	Domains    []string
+	VLANID     int
+	Parent     string
+	// VLANs are names of VLAN sub-interfaces carried by the interface
+	VLANs []string
+	Bond  *BondConfiguration
+	// BondMaster is a name of the bond that enslaves the interface
+	BondMaster string
+	Bridge     *BridgeConfiguration
+	// BridgeMaster is a name of the bridge that the interface is attached to
+	BridgeMaster string
+	MTU          int
+	Routes       []RouteConfiguration
+	// RoutingPolicies are configurations of addresses routed by their own routing tables
+	RoutingPolicies []StaticInterfaceConfiguration
+}
+
+const systemdLinkUnitTemplate = `# Generated by bosh-agent
+[Match]
+MACAddress={{ .MacAddress }}
+
+[Link]
+Name={{ .Name }}
+`
+
+const systemdNetworkUnitTemplate = `# Generated by bosh-agent
+[Match]
+Name={{ .Name }}
+
+[Network]{{ if .DHCP }}
+DHCP={{ if .DHCP6 }}yes{{ else }}ipv4{{ end }}{{ if .AcceptRA }}
+IPv6AcceptRA=yes{{ end }}{{ else }}{{ range .Addresses }}
+Address={{ . }}{{ end }}{{ if .IPv6 }}
+IPv6AcceptRA=no{{ end }}{{ range .Gateways }}
+Gateway={{ . }}{{ end }}{{ end }}{{ range .DNSServers }}
+DNS={{ . }}{{ end }}{{ if .Domains }}
+Domains={{ range $i, $d := .Domains }}{{ if $i }} {{ end }}{{ $d }}{{ end }}{{ end }}{{ range .VLANs }}
+VLAN={{ . }}{{ end }}{{ if .BondMaster }}
+Bond={{ .BondMaster }}{{ end }}{{ if .BridgeMaster }}
+Bridge={{ .BridgeMaster }}{{ end }}{{ range .Routes }}
+
+[Route]
+Destination={{ .Destination }}{{ if .Gateway }}
+Gateway={{ .Gateway }}{{ else }}
+Scope=link{{ end }}{{ if .Table }}
+Table={{ .Table }}{{ end }}{{ end }}{{ range .RoutingPolicies }}
+
+[RoutingPolicyRule]
+From={{ .Address }}
+Table={{ .RoutingTable }}{{ end }}{{ if .MTU }}
+
+[Link]
+MTUBytes={{ .MTU }}{{ end }}
+`
+
+const systemdBondNetdevTemplate = `# Generated by bosh-agent
+[NetDev]
+Name={{ .Name }}
+Kind=bond
+{{ with .Bond }}
+[Bond]{{ if .Mode }}
+Mode={{ .Mode }}{{ end }}{{ if .Miimon }}
+MIIMonitorSec={{ .Miimon }}ms{{ end }}
+{{ end }}`
+
+const systemdBridgeNetdevTemplate = `# Generated by bosh-agent
+[NetDev]
+Name={{ .Name }}
+Kind=bridge
+`
+
+const systemdVLANNetdevTemplate = `# Generated by bosh-agent
+[NetDev]
+Name={{ .Name }}
+Kind=vlan
+
+[VLAN]
+Id={{ .VLANID }}
+`
+
+func (net systemdNetworkdNetManager) writeNetworkUnits(
+	interfacesByMacAddress map[string]string,
+	dhcpConfigs DHCPInterfaceConfigurations,
+	staticConfigs StaticInterfaceConfigurations,
+	dnsServers []string,
+	domains []string,
+) (bool, error) {
+	sort.Stable(dhcpConfigs)
+	sort.Stable(staticConfigs)
+
+	macAddressesByInterface := map[string]string{}
+	for mac, iface := range interfacesByMacAddress {
+		macAddressesByInterface[iface] = mac
+	}
+
+	unitConfigs := []systemdNetworkUnitConfig{}
+
+	for _, dhcpConfig := range dhcpConfigs {
+		unitConfigs = append(unitConfigs, systemdNetworkUnitConfig{
+			Name:       dhcpConfig.Name,
+			MacAddress: macAddressesByInterface[dhcpConfig.Name],
+			DHCP:       true,
+			DHCP6:      dhcpConfig.IsDHCPv6(),
+			AcceptRA:   dhcpConfig.IPv6Mode != "",
+			DNSServers: dnsServers,
+			Domains:    domains,
+			VLANID:     dhcpConfig.VLANID,
+			Parent:     dhcpConfig.Parent,
+			Bond:       dhcpConfig.Bond,
+			Bridge:     dhcpConfig.Bridge,
+			MTU:        dhcpConfig.MTU,
+			Routes:     dhcpConfig.Routes,
+		})
+	}
+
+	for _, staticConfig := range staticConfigs {
+		prefixLength, err := netmaskPrefixLength(staticConfig.Netmask)
+		if err != nil {
+			return false, err
+		}
+
+		// Dual-stack interfaces have IPv4 and IPv6 configurations in a single unit
+		if n := len(unitConfigs); n == 0 || unitConfigs[n-1].Name != staticConfig.Name {
+			unitConfigs = append(unitConfigs, systemdNetworkUnitConfig{
+				Name:       staticConfig.Name,
+				MacAddress: macAddressesByInterface[staticConfig.Name],
+				DNSServers: dnsServers,
+				Domains:    domains,
+				VLANID:     staticConfig.VLANID,
+				Parent:     staticConfig.Parent,
+				Bond:       staticConfig.Bond,
+				Bridge:     staticConfig.Bridge,
+				MTU:        staticConfig.MTU,
+			})
+		}
+
+		unitConfig := &unitConfigs[len(unitConfigs)-1]
+		unitConfig.Addresses = append(unitConfig.Addresses, fmt.Sprintf("%s/%d", staticConfig.Address, prefixLength))
+		unitConfig.IPv6 = unitConfig.IPv6 || staticConfig.IsVersion6()
+		unitConfig.Routes = append(unitConfig.Routes, staticConfig.Routes...)
+
+		if staticConfig.RoutingTable != 0 {
+			unitConfig.RoutingPolicies = append(unitConfig.RoutingPolicies, staticConfig)
+		}
+
+		if staticConfig.IsDefaultForGateway {
+			unitConfig.Gateways = append(unitConfig.Gateways, staticConfig.Gateway)
+		}
+	}
+
+	// Physical interfaces without networks of their own still need a unit that creates their VLANs
+	for _, name := range unconfiguredVLANParents(staticConfigs, dhcpConfigs) {
+		unitConfigs = append(unitConfigs, systemdNetworkUnitConfig{
+			Name:       name,
+			MacAddress: macAddressesByInterface[name],
+		})
+	}
+
+	for _, slave := range bondSlaves(staticConfigs, dhcpConfigs) {
+		unitConfigs = append(unitConfigs, systemdNetworkUnitConfig{
+			Name:       slave.Name,
+			MacAddress: macAddressesByInterface[slave.Name],
+			BondMaster: slave.Master,
+		})
+	}
+
+	for _, port := range bridgePorts(staticConfigs, dhcpConfigs) {
+		unitConfigs = append(unitConfigs, systemdNetworkUnitConfig{
+			Name:         port.Name,
+			MacAddress:   macAddressesByInterface[port.Name],
+			BridgeMaster: port.Master,
+		})
+	}
+
+	for i := range unitConfigs {
+		if unitConfigs[i].VLANID == 0 {
+			continue
+		}
+
+		for j := range unitConfigs {
+			if unitConfigs[j].Name == unitConfigs[i].Parent {
+				unitConfigs[j].VLANs = append(unitConfigs[j].VLANs, unitConfigs[i].Name)
+			}
+		}
+	}
+
+	anyChanged := false
+	writtenUnitPaths := map[string]bool{}
+
+	for _, unitConfig := range unitConfigs {
+		units := map[string]string{"network": systemdNetworkUnitTemplate}
+		if unitConfig.MacAddress != "" {
+			units["link"] = systemdLinkUnitTemplate
+		}
+		if unitConfig.VLANID != 0 {
+			units["netdev"] = systemdVLANNetdevTemplate
+		}
+		if unitConfig.Bond != nil {
+			units["netdev"] = systemdBondNetdevTemplate
+		}
+		if unitConfig.Bridge != nil {
+			units["netdev"] = systemdBridgeNetdevTemplate
+		}
+
+		for unitType, unitTemplate := range units {
+			unitPath := systemdNetworkUnitPath(unitConfig.Name, unitType)
+
+			changed, err := net.writeUnit(unitPath, unitTemplate, unitConfig)
+			if err != nil {
+				return anyChanged, err
+			}
+
+			anyChanged = anyChanged || changed
+			writtenUnitPaths[unitPath] = true
+		}
+	}
+
+	removed, err := net.removeStaleUnits(writtenUnitPaths)
+	if err != nil {
+		return anyChanged, err
+	}
+
+	return anyChanged || removed, nil
+}
+
+func (net systemdNetworkdNetManager) writeUnit(unitPath, unitTemplate string, unitConfig systemdNetworkUnitConfig) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New(path.Base(unitPath)).Parse(unitTemplate))
+
+	err := t.Execute(buffer, unitConfig)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := net.fs.ConvergeFileContents(unitPath, buffer.Bytes())
+	if err != nil {
+		return changed, bosherr.WrapErrorf(err, "Writing to %s", unitPath)
+	}
+
+	return changed, nil
+}
+
+// removeStaleUnits removes units generated for interfaces that are no longer configured
+func (net systemdNetworkdNetManager) removeStaleUnits(writtenUnitPaths map[string]bool) (bool, error) {
+	unitPaths, err := net.fs.Glob(path.Join(systemdNetworkDir, systemdNetworkUnitPrefix+"*"))
+	if err != nil {
+		return false, bosherr.WrapError(err, "Listing network units")
+	}
+
+	removed := false
+
+	for _, unitPath := range unitPaths {
+		if writtenUnitPaths[unitPath] {
+			continue
+		}
+
+		net.logger.Debug(systemdNetworkdNetManagerLogTag, "Removing stale network unit %s", unitPath)
+
+		err = net.fs.RemoveAll(unitPath)
+		if err != nil {
+			return removed, bosherr.WrapErrorf(err, "Removing %s", unitPath)
+		}
+
+		removed = true
+	}
+
+	return removed, nil
+}
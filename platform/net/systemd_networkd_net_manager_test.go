@@ -0,0 +1,742 @@
+package net_test
+
+import (
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("systemdNetworkdNetManager", func() {
+	var (
+		fs                     *fakesys.FakeFileSystem
+		cmdRunner              *fakesys.FakeCmdRunner
+		ipResolver             *fakeip.FakeResolver
+		addressBroadcaster     *fakearp.FakeAddressBroadcaster
+		interfaceAddrsProvider *fakeip.FakeInterfaceAddressesProvider
+		netManager             Manager
+	)
+
+	writeNetworkDevice := func(iface string, macAddress string) string {
+		interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+		fs.WriteFile(interfacePath, []byte{})
+		fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+		fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), fmt.Sprintf("%s\n", macAddress))
+
+		return interfacePath
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		ipResolver = &fakeip.FakeResolver{}
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		interfaceAddrsProvider = &fakeip.FakeInterfaceAddressesProvider{}
+		netManager = NewSystemdNetworkdNetManager(
+			fs,
+			cmdRunner,
+			ipResolver,
+			NewInterfaceConfigurationCreator(logger),
+			boship.NewInterfaceAddressesValidator(interfaceAddrsProvider),
+			NewResolvConfDNSValidator(fs, "/run/systemd/resolve/resolv.conf"),
+			addressBroadcaster,
+			logger,
+		)
+	})
+
+	Describe("SetupNetworking", func() {
+		var (
+			dhcpNetwork   boshsettings.Network
+			staticNetwork boshsettings.Network
+			networks      boshsettings.Networks
+		)
+
+		BeforeEach(func() {
+			dhcpNetwork = boshsettings.Network{
+				Type:    "dynamic",
+				Default: []string{"dns"},
+				DNS:     []string{"8.8.8.8", "9.9.9.9"},
+				Mac:     "aa:aa:aa:aa:aa:aa",
+			}
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Default: []string{"gateway"},
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Mac:     "bb:bb:bb:bb:bb:bb",
+			}
+			networks = boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 8.8.8.8\nnameserver 9.9.9.9\n")
+
+			fs.SetGlob("/sys/class/net/*", []string{
+				writeNetworkDevice("ethdhcp", dhcpNetwork.Mac),
+				writeNetworkDevice("ethstatic", staticNetwork.Mac),
+			})
+		})
+
+		It("writes network units for dhcp and static interfaces", func() {
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethdhcp.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dhcpUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=ipv4
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("acquires IPv6 address of dynamic interface via DHCPv6", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeDHCPv6
+			networks["dhcp-network"] = dhcpNetwork
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::10"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethdhcp.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dhcpUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=yes
+IPv6AcceptRA=yes
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("autoconfigures IPv6 address of dynamic interface using SLAAC", func() {
+			dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeSLAAC
+			networks["dhcp-network"] = dhcpNetwork
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::f816:3eff:fe00:1"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			dhcpUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethdhcp.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(dhcpUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=ipv4
+IPv6AcceptRA=yes
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+
+			sysctlConfig, err := fs.ReadFileString("/etc/sysctl.d/60-bosh-ipv6.conf")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sysctlConfig).To(ContainSubstring("net.ipv6.conf.ethdhcp.autoconf = 1\n"))
+		})
+
+		It("writes search domains of the default dns network into network units", func() {
+			dhcpNetwork.Domains = []string{"example.com", "internal"}
+			networks["dhcp-network"] = dhcpNetwork
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+DNS=8.8.8.8
+DNS=9.9.9.9
+Domains=example.com internal
+`))
+		})
+
+		It("writes MTU of interfaces into their network units", func() {
+			staticNetwork.MTU = 9000
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+DNS=8.8.8.8
+DNS=9.9.9.9
+
+[Link]
+MTUBytes=9000
+`))
+		})
+
+		It("writes static routes of interfaces into their network units", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0/8", Gateway: "1.2.3.1"},
+				{Destination: "172.16.0.0/12"},
+			}
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+DNS=8.8.8.8
+DNS=9.9.9.9
+
+[Route]
+Destination=10.0.0.0/8
+Gateway=1.2.3.1
+
+[Route]
+Destination=172.16.0.0/12
+Scope=link
+`))
+		})
+
+		It("writes routing policy rules when multiple networks have gateways", func() {
+			networks["dhcp-network"] = boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Gateway: "5.6.7.1",
+				Mac:     dhcpNetwork.Mac,
+			}
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethdhcp", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Gateway=3.4.5.6
+
+[Route]
+Destination=1.2.3.0/24
+Scope=link
+Table=101
+
+[Route]
+Destination=0.0.0.0/0
+Gateway=3.4.5.6
+Table=101
+
+[RoutingPolicyRule]
+From=1.2.3.4
+Table=101
+`))
+		})
+
+		It("writes network unit that does not accept router advertisements for IPv6 interfaces", func() {
+			staticNetwork.IP = "2001:db8::1234"
+			staticNetwork.Netmask = "ffff:ffff:ffff:ffff::"
+			staticNetwork.Gateway = "2001:db8::1"
+			networks["static-network"] = staticNetwork
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=2001:db8::1234/64
+IPv6AcceptRA=no
+Gateway=2001:db8::1
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+
+			Expect(fs.FileExists("/etc/sysctl.d/60-bosh-ipv6.conf")).To(BeTrue())
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"sysctl", "-p", "/etc/sysctl.d/60-bosh-ipv6.conf"}))
+		})
+
+		It("applies and persists interface tuning after restarting systemd-networkd", func() {
+			rpFilter := 2
+			gro := false
+			staticNetwork.Tuning = boshsettings.InterfaceTuning{RPFilter: &rpFilter, GRO: &gro, TxQueueLen: 10000}
+			networks["static-network"] = staticNetwork
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			sysctlConfig, err := fs.ReadFileString("/etc/sysctl.d/60-bosh-interface-tuning.conf")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(sysctlConfig).To(Equal(`# Generated by bosh-agent
+net.ipv4.conf.ethstatic.rp_filter = 2
+`))
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"systemctl", "restart", "systemd-networkd"},
+				{"sysctl", "-p", "/etc/sysctl.d/60-bosh-interface-tuning.conf"},
+				{"ethtool", "-K", "ethstatic", "gro", "off"},
+				{"ip", "link", "set", "dev", "ethstatic", "txqueuelen", "10000"},
+			}))
+		})
+
+		It("reapplies interface tuning when configuration did not change", func() {
+			rpFilter := 0
+			staticNetwork.Tuning = boshsettings.InterfaceTuning{RPFilter: &rpFilter}
+			networks["static-network"] = staticNetwork
+
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred()) // wait for all arpings
+
+			cmdRunner.RunCommands = [][]string{}
+
+			err = netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"sysctl", "-p", "/etc/sysctl.d/60-bosh-interface-tuning.conf"},
+			}))
+		})
+
+		It("removes interface tuning kernel parameters when no interface is tuned", func() {
+			fs.WriteFileString("/etc/sysctl.d/60-bosh-interface-tuning.conf", "fake-sysctls")
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/sysctl.d/60-bosh-interface-tuning.conf")).To(BeFalse())
+		})
+
+		It("returns an error when applying interface tuning fails", func() {
+			gro := true
+			staticNetwork.Tuning = boshsettings.InterfaceTuning{GRO: &gro}
+			networks["static-network"] = staticNetwork
+			cmdRunner.AddCmdResult("ethtool -K ethstatic gro on", fakesys.FakeCmdResult{Error: errors.New("fake-ethtool-err")})
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Applying interface tuning: Setting generic receive offload of interface 'ethstatic': fake-ethtool-err"))
+		})
+
+		It("writes IPv4 and IPv6 addresses of dual-stack interface into a single network unit", func() {
+			networks["ipv6-network"] = boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::1234",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     staticNetwork.Mac,
+			}
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::1234"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+Address=2001:db8::1234/64
+IPv6AcceptRA=no
+Gateway=3.4.5.6
+Gateway=2001:db8::1
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+		})
+
+		It("writes netdev units for VLAN sub-interfaces and attaches them to their parent", func() {
+			networks["vlan-network"] = boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Mac:     staticNetwork.Mac,
+				VLANID:  123,
+			}
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic.123", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netdevUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.123.netdev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netdevUnit).To(Equal(`# Generated by bosh-agent
+[NetDev]
+Name=ethstatic.123
+Kind=vlan
+
+[VLAN]
+Id=123
+`))
+
+			vlanUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.123.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vlanUnit).To(ContainSubstring("Name=ethstatic.123\n"))
+			Expect(vlanUnit).To(ContainSubstring("Address=5.6.7.8/24\n"))
+
+			staticUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(staticUnit).To(HaveSuffix("DNS=9.9.9.9\nVLAN=ethstatic.123\n"))
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethstatic.123.link")).To(BeFalse())
+		})
+
+		It("writes netdev unit for bonds and enslaves their interfaces", func() {
+			networks["bond-network"] = boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Bond: &boshsettings.Bond{
+					Mode:   "active-backup",
+					Miimon: 100,
+					Slaves: []string{staticNetwork.Mac},
+				},
+			}
+			delete(networks, "static-network")
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netdevUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-bond0.netdev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netdevUnit).To(Equal(`# Generated by bosh-agent
+[NetDev]
+Name=bond0
+Kind=bond
+
+[Bond]
+Mode=active-backup
+MIIMonitorSec=100ms
+`))
+
+			bondUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-bond0.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bondUnit).To(ContainSubstring("Address=5.6.7.8/24\n"))
+
+			slaveUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(slaveUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Bond=bond0
+`))
+		})
+
+		It("writes netdev unit for bridges and attaches their ports", func() {
+			staticNetwork.Bridge = "br0"
+			networks["static-network"] = staticNetwork
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("br0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			netdevUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-br0.netdev")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(netdevUnit).To(Equal(`# Generated by bosh-agent
+[NetDev]
+Name=br0
+Kind=bridge
+`))
+
+			bridgeUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-br0.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(bridgeUnit).To(ContainSubstring("Address=1.2.3.4/24\n"))
+			Expect(bridgeUnit).To(ContainSubstring("Gateway=3.4.5.6\n"))
+
+			portUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.network")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(portUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Bridge=br0
+`))
+		})
+
+		It("writes link units that name interfaces by MAC address", func() {
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			linkUnit, err := fs.ReadFileString("/etc/systemd/network/10-bosh-ethstatic.link")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(linkUnit).To(Equal(`# Generated by bosh-agent
+[Match]
+MACAddress=bb:bb:bb:bb:bb:bb
+
+[Link]
+Name=ethstatic
+`))
+		})
+
+		It("restarts systemd-networkd only when units change", func() {
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred()) // wait for all arpings
+
+			err = netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(<-errCh).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-networkd"}}))
+		})
+
+		It("removes units of interfaces that are no longer configured", func() {
+			fs.WriteFileString("/etc/systemd/network/10-bosh-ethold.network", "")
+			fs.SetGlob("/etc/systemd/network/10-bosh-*", []string{
+				"/etc/systemd/network/10-bosh-ethdhcp.network",
+				"/etc/systemd/network/10-bosh-ethold.network",
+			})
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethold.network")).To(BeFalse())
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethdhcp.network")).To(BeTrue())
+		})
+
+		It("returns error if restarting systemd-networkd fails", func() {
+			cmdRunner.AddCmdResult("systemctl restart systemd-networkd", fakesys.FakeCmdResult{Error: errors.New("fake-restart-err")})
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Restarting systemd-networkd"))
+		})
+
+		It("broadcasts MAC addresses for all interfaces", func() {
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(networks, errCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			broadcastErr := <-errCh
+			Expect(broadcastErr).ToNot(HaveOccurred())
+
+			Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(Equal([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewResolvingInterfaceAddress("ethdhcp", ipResolver),
+			}))
+		})
+
+		Context("when network requests SR-IOV virtual functions", func() {
+			BeforeEach(func() {
+				staticNetwork.SRIOV = &boshsettings.SRIOV{
+					NumVFs: 4,
+					VFs: []boshsettings.SRIOVVirtualFunction{
+						{MAC: "cc:cc:cc:cc:cc:cc", VLANID: 100},
+						{VLANID: 200},
+					},
+				}
+				networks["static-network"] = staticNetwork
+
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_totalvfs", "8\n")
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_numvfs", "0\n")
+			})
+
+			It("creates virtual functions on the physical function and assigns their MAC addresses and VLANs", func() {
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				numVFs, err := fs.ReadFileString("/sys/class/net/ethstatic/device/sriov_numvfs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numVFs).To(Equal("4"))
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "mac", "cc:cc:cc:cc:cc:cc"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "0", "vlan", "100"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "1", "vlan", "200"}))
+				Expect(cmdRunner.RunCommands).ToNot(ContainElement([]string{"ip", "link", "set", "ethstatic", "vf", "1", "mac", ""}))
+			})
+
+			It("keeps virtual functions when their number did not change", func() {
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_numvfs", "4\n")
+				fs.WriteFileErrors["/sys/class/net/ethstatic/device/sriov_numvfs"] = errors.New("fake-write-err")
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("does not configure virtual functions with networks", func() {
+				fs.WriteFile("/sys/class/net/ethvf0", []byte{})
+				fs.WriteFile("/sys/class/net/ethvf0/device", []byte{})
+				fs.WriteFile("/sys/class/net/ethvf0/device/physfn", []byte{})
+				fs.WriteFileString("/sys/class/net/ethvf0/address", "cc:cc:cc:cc:cc:cc\n")
+				fs.SetGlob("/sys/class/net/*", []string{
+					"/sys/class/net/ethdhcp",
+					"/sys/class/net/ethstatic",
+					"/sys/class/net/ethvf0",
+				})
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethvf0.network")).To(BeFalse())
+			})
+
+			It("returns error if physical function does not support SR-IOV", func() {
+				fs.RemoveAll("/sys/class/net/ethstatic/device/sriov_totalvfs")
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Configuring SR-IOV virtual functions"))
+				Expect(err.Error()).To(ContainSubstring("Interface 'ethstatic' does not support SR-IOV"))
+			})
+
+			It("returns error if physical function supports fewer virtual functions", func() {
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_totalvfs", "2\n")
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Interface 'ethstatic' supports at most 2 virtual functions"))
+			})
+
+			It("returns error if more virtual functions are assigned than created", func() {
+				staticNetwork.SRIOV.NumVFs = 1
+				networks["static-network"] = staticNetwork
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Network 'static-network' assigns 2 virtual functions but only 1 are created"))
+			})
+
+			It("returns error if VLAN ID of virtual function is out of range", func() {
+				staticNetwork.SRIOV.VFs[1].VLANID = 4095
+				networks["static-network"] = staticNetwork
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("VLAN ID '4095' of virtual function 1 of network 'static-network' is out of range 1-4094"))
+			})
+
+			It("returns error if assigning MAC address to virtual function fails", func() {
+				cmdRunner.AddCmdResult("ip link set ethstatic vf 0 mac cc:cc:cc:cc:cc:cc", fakesys.FakeCmdResult{Error: errors.New("fake-ip-err")})
+
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-ip-err"))
+			})
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				dhcpNetwork.Preconfigured = true
+				staticNetwork.Preconfigured = true
+				networks = boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}
+			})
+
+			It("only configures dns servers for systemd-resolved", func() {
+				err := netManager.SetupNetworking(networks, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/systemd/resolved.conf.d/bosh.conf")).To(BeTrue())
+				Expect(fs.FileExists("/etc/systemd/network/10-bosh-ethdhcp.network")).To(BeFalse())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-resolved"}}))
+			})
+		})
+	})
+
+	Describe("GetConfiguredNetworkInterfaces", func() {
+		It("returns interfaces that have network units", func() {
+			fs.SetGlob("/sys/class/net/*", []string{
+				writeNetworkDevice("fake-eth0", "aa:bb"),
+				writeNetworkDevice("fake-eth1", "cc:dd"),
+			})
+			fs.WriteFileString("/etc/systemd/network/10-bosh-fake-eth0.network", "")
+
+			interfaces, err := netManager.GetConfiguredNetworkInterfaces()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{"fake-eth0"}))
+		})
+	})
+})
@@ -2,7 +2,6 @@ package net
 
 import (
 	"bytes"
-	"path"
 	"sort"
 	"strings"
 	"text/template"
@@ -24,8 +23,10 @@ type UbuntuNetManager struct {
 	interfaceConfigurationCreator InterfaceConfigurationCreator
 	interfaceAddressesValidator   boship.InterfaceAddressesValidator
 	dnsValidator                  DNSValidator
-	addressBroadcaster            bosharp.AddressBroadcaster
-	logger                        boshlog.Logger
+	// dnsManager is nil when dns servers are written to /etc/resolv.conf via resolvconf
+	dnsManager         DNSManager
+	addressBroadcaster bosharp.AddressBroadcaster
+	logger             boshlog.Logger
 }
 
 func NewUbuntuNetManager(
@@ -35,6 +36,7 @@ func NewUbuntuNetManager(
 	interfaceConfigurationCreator InterfaceConfigurationCreator,
 	interfaceAddressesValidator boship.InterfaceAddressesValidator,
 	dnsValidator DNSValidator,
+	dnsManager DNSManager,
 	addressBroadcaster bosharp.AddressBroadcaster,
 	logger boshlog.Logger,
 ) Manager {
@@ -45,6 +47,7 @@ func NewUbuntuNetManager(
 		interfaceConfigurationCreator: interfaceConfigurationCreator,
 		interfaceAddressesValidator:   interfaceAddressesValidator,
 		dnsValidator:                  dnsValidator,
+		dnsManager:                    dnsManager,
 		addressBroadcaster:            addressBroadcaster,
 		logger:                        logger,
 	}
@@ -62,8 +65,12 @@ request subnet-mask, broadcast-address, time-offset, routers,
 	domain-name, domain-name-servers, domain-search, host-name,
 	netbios-name-servers, netbios-scope, interface-mtu,
 	rfc3442-classless-static-routes, ntp-servers;
-{{ if . }}
-prepend domain-name-servers {{ . }};{{ end }}
+{{ if .DNSServers }}
+prepend domain-name-servers {{ .DNSServers }};{{ end }}{{ range .DHCPConfigs }}{{ if .MTU }}
+
+interface "{{ .Name }}" {
+	supersede interface-mtu {{ .MTU }};
+}{{ end }}{{ end }}
 `
 
 func (net UbuntuNetManager) ComputeNetworkConfig(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, []string, error) {
@@ -88,22 +95,47 @@ func (net UbuntuNetManager) ComputeNetworkConfig(networks boshsettings.Networks)
 func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
 	if networks.IsPreconfigured() {
 		// Note in this case IPs are not broadcasted
+		if net.dnsManager != nil {
+			dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+			return net.dnsManager.SetupDNS(dnsNetwork.DNS, dnsNetwork.Domains)
+		}
 		return net.writeResolvConf(networks)
 	}
 
+	err := configureSRIOV(net.fs, net.cmdRunner, networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Configuring SR-IOV virtual functions")
+	}
+
 	staticConfigs, dhcpConfigs, dnsServers, err := net.ComputeNetworkConfig(networks)
 	if err != nil {
 		return bosherr.WrapError(err, "Computing network configuration")
 	}
 
-	interfacesChanged, err := net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, dnsServers)
+	// resolvconf and dhclient must not overwrite dns configuration programmed by dns manager
+	resolvConfDNSServers := dnsServers
+	if net.dnsManager != nil {
+		resolvConfDNSServers = nil
+	}
+
+	interfacesChanged, err := net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, resolvConfDNSServers)
 	if err != nil {
 		return bosherr.WrapError(err, "Writing network configuration")
 	}
 
+	err = net.pinInterfaceNames(staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Pinning interface names")
+	}
+
+	err = writeIPv6Sysctls(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing IPv6 kernel parameters")
+	}
+
 	dhcpChanged := false
 	if len(dhcpConfigs) > 0 {
-		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers)
+		dhcpChanged, err = net.writeDHCPConfiguration(resolvConfDNSServers, dhcpConfigs)
 		if err != nil {
 			return err
 		}
@@ -118,6 +150,20 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		net.restartNetworkingInterfaces(net.ifaceNames(dhcpConfigs, staticConfigs))
 	}
 
+	err = applyInterfaceTuning(net.fs, net.cmdRunner, staticConfigs, dhcpConfigs)
+	if err != nil {
+		return bosherr.WrapError(err, "Applying interface tuning")
+	}
+
+	if net.dnsManager != nil {
+		dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+
+		err = net.dnsManager.SetupDNS(dnsServers, dnsNetwork.Domains)
+		if err != nil {
+			return bosherr.WrapError(err, "Configuring dns")
+		}
+	}
+
 	staticAddresses, dynamicAddresses := net.ifaceAddresses(staticConfigs, dhcpConfigs)
 
 	err = net.interfaceAddressesValidator.Validate(staticAddresses)
@@ -125,6 +171,14 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return bosherr.WrapError(err, "Validating static network configuration")
 	}
 
+	dynamicIPv6Addrs := dynamicIPv6Addresses(dhcpConfigs)
+	if len(dynamicIPv6Addrs) > 0 {
+		err = net.interfaceAddressesValidator.Validate(dynamicIPv6Addrs)
+		if err != nil {
+			return bosherr.WrapError(err, "Validating dynamic IPv6 network configuration")
+		}
+	}
+
 	err = net.dnsValidator.Validate(dnsServers)
 	if err != nil {
 		return bosherr.WrapError(err, "Validating dns configuration")
@@ -138,7 +192,7 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 func (net UbuntuNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -166,7 +220,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure calling 'pkill dhclient': %s", err)
 	}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return err
 	}
@@ -185,7 +239,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 }
 
 func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -203,16 +257,7 @@ func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]S
 }
 
 func (net UbuntuNetManager) ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
-	staticAddresses := []boship.InterfaceAddress{}
-	for _, iface := range staticConfigs {
-		staticAddresses = append(staticAddresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
-	}
-	dynamicAddresses := []boship.InterfaceAddress{}
-	for _, iface := range dhcpConfigs {
-		dynamicAddresses = append(dynamicAddresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
-	}
-
-	return staticAddresses, dynamicAddresses
+	return staticInterfaceAddresses(staticConfigs), dynamicInterfaceAddresses(dhcpConfigs, net.ipResolver)
 }
 
 func (net UbuntuNetManager) broadcastIps(addresses []boship.InterfaceAddress, errCh chan error) {
@@ -238,14 +283,14 @@ func (net UbuntuNetManager) restartNetworkingInterfaces(ifaceNames []string) {
 	}
 }
 
-func (net UbuntuNetManager) writeDHCPConfiguration(dnsServers []string) (bool, error) {
+func (net UbuntuNetManager) writeDHCPConfiguration(dnsServers []string, dhcpConfigs []DHCPInterfaceConfiguration) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("dhcp-config").Parse(ubuntuDHCPConfigTemplate))
 
 	// Keep DNS servers in the order specified by the network
 	// because they are added by a *single* DHCP's prepend command
 	dnsServersList := strings.Join(dnsServers, ", ")
-	err := t.Execute(buffer, dnsServersList)
+	err := t.Execute(buffer, dhclientConfigArg{DNSServers: dnsServersList, DHCPConfigs: dhcpConfigs})
 	if err != nil {
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
@@ -261,22 +306,35 @@ func (net UbuntuNetManager) writeDHCPConfiguration(dnsServers []string) (bool, e
 
 type networkInterfaceConfig struct {
 	DNSServers        []string
-	StaticConfigs     []StaticInterfaceConfiguration
+	StaticConfigs     []ubuntuStaticInterfaceConfig
 	DHCPConfigs       []DHCPInterfaceConfiguration
 	HasDNSNameServers bool
 }
 
+type ubuntuStaticInterfaceConfig struct {
+	StaticInterfaceConfiguration
+	// Auto is false for IPv6 stanza of a dual-stack interface
+	// since interface is already brought up by its IPv4 stanza
+	Auto bool
+}
+
 func (net UbuntuNetManager) writeNetworkInterfaces(dhcpConfigs DHCPInterfaceConfigurations, staticConfigs StaticInterfaceConfigurations, dnsServers []string) (bool, error) {
 	sort.Stable(dhcpConfigs)
 	sort.Stable(staticConfigs)
 
 	networkInterfaceValues := networkInterfaceConfig{
 		DHCPConfigs:       dhcpConfigs,
-		StaticConfigs:     staticConfigs,
 		HasDNSNameServers: true,
 		DNSServers:        dnsServers,
 	}
 
+	for i, staticConfig := range staticConfigs {
+		networkInterfaceValues.StaticConfigs = append(networkInterfaceValues.StaticConfigs, ubuntuStaticInterfaceConfig{
+			StaticInterfaceConfiguration: staticConfig,
+			Auto:                         i == 0 || staticConfigs[i-1].Name != staticConfig.Name,
+		})
+	}
+
 	buffer := bytes.NewBuffer([]byte{})
 
 	t := template.Must(template.New("network-interfaces").Parse(networkInterfacesTemplate))
@@ -294,49 +352,49 @@ func (net UbuntuNetManager) writeNetworkInterfaces(dhcpConfigs DHCPInterfaceConf
 	return changed, nil
 }
 
-const networkInterfacesTemplate = `# Generated by bosh-agent
+// Bonds are created by ifenslave which also brings up slaves listed on the bond,
+// bridges are created by bridge-utils which does the same for bridge ports.
+// Static routes and policy routing rules are (re)added by up commands whenever interface is brought up.
+// Dynamic IPv6 address is acquired by dhclient -6 (inet6 dhcp) or from router advertisements (inet6 auto)
+const networkInterfacesTemplate = `{{ define "bond" }}{{ if .Mode }}    bond-mode {{ .Mode }}
+{{ end }}{{ if .Miimon }}    bond-miimon {{ .Miimon }}
+{{ end }}    bond-slaves{{ range .Slaves }} {{ . }}{{ end }}{{ end }}{{ define "bridge" }}    bridge_ports{{ range .Ports }} {{ . }}{{ end }}{{ end }}{{ define "route" }}    up ip route replace {{ .Destination }}{{ if .Gateway }} via {{ .Gateway }}{{ end }} dev {{ .Interface }}{{ if .Table }} table {{ .Table }}{{ end }}{{ end }}# Generated by bosh-agent
 auto lo
 iface lo inet loopback
 {{ range .DHCPConfigs }}
 auto {{ .Name }}
-iface {{ .Name }} inet dhcp
+iface {{ .Name }} inet dhcp{{ if .IsVLAN }}
+    vlan-raw-device {{ .Parent }}{{ end }}{{ with .Bond }}
+{{ template "bond" . }}{{ end }}{{ with .Bridge }}
+{{ template "bridge" . }}{{ end }}{{ if .MTU }}
+    mtu {{ .MTU }}{{ end }}{{ range .Routes }}
+{{ template "route" . }}{{ end }}{{ if .IPv6Mode }}
+iface {{ .Name }} inet6 {{ if .IsSLAAC }}auto{{ else }}dhcp{{ end }}{{ end }}
 {{ end }}{{ range .StaticConfigs }}
-auto {{ .Name }}
-iface {{ .Name }} inet static
-    address {{ .Address }}
-    network {{ .Network }}
-    netmask {{ .Netmask }}
-{{ if .IsDefaultForGateway }}    broadcast {{ .Broadcast }}
-    gateway {{ .Gateway }}{{ end }}{{ end }}
+{{ if .Auto }}auto {{ .Name }}
+{{ end }}iface {{ .Name }} inet{{ if .IsVersion6 }}6{{ end }} static
+    address {{ .Address }}{{ if not .IsVersion6 }}
+    network {{ .Network }}{{ end }}
+    netmask {{ .NetmaskOrPrefixLength }}
+{{ if .IsVLAN }}    vlan-raw-device {{ .Parent }}
+{{ end }}{{ if .Auto }}{{ with .Bond }}{{ template "bond" . }}
+{{ end }}{{ with .Bridge }}{{ template "bridge" . }}
+{{ end }}{{ if .MTU }}    mtu {{ .MTU }}
+{{ end }}{{ end }}{{ range .Routes }}{{ template "route" . }}
+{{ end }}{{ if .RoutingTable }}    up ip rule add from {{ .Address }} table {{ .RoutingTable }}
+    down ip rule del from {{ .Address }} table {{ .RoutingTable }}
+{{ end }}{{ if .IsDefaultForGateway }}{{ if not .IsVersion6 }}    broadcast {{ .Broadcast }}
+{{ end }}    gateway {{ .Gateway }}{{ end }}{{ end }}
 {{ if .DNSServers }}
 dns-nameservers{{ range .DNSServers }} {{ . }}{{ end }}{{ end }}`
 
-func (net UbuntuNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
+func (net UbuntuNetManager) pinInterfaceNames(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) error {
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(path.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(path.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := path.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
+		return bosherr.WrapError(err, "Getting network interfaces")
 	}
 
-	return addresses, nil
+	return writeInterfaceNamesUdevRules(net.fs, interfacesByMacAddress, staticConfigs, dhcpConfigs)
 }
 
 func (net UbuntuNetManager) ifaceNames(dhcpConfigs DHCPInterfaceConfigurations, staticConfigs StaticInterfaceConfigurations) []string {
@@ -344,8 +402,13 @@ func (net UbuntuNetManager) ifaceNames(dhcpConfigs DHCPInterfaceConfigurations,
 	for _, config := range dhcpConfigs {
 		ifaceNames = append(ifaceNames, config.Name)
 	}
+	seenStaticNames := map[string]bool{}
 	for _, config := range staticConfigs {
-		ifaceNames = append(ifaceNames, config.Name)
+		// Dual-stack interfaces have a configuration per address family
+		if !seenStaticNames[config.Name] {
+			seenStaticNames[config.Name] = true
+			ifaceNames = append(ifaceNames, config.Name)
+		}
 	}
 	return ifaceNames
 }
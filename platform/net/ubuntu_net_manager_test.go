@@ -76,6 +76,7 @@ func describeUbuntuNetManager() {
 			interfaceConfigurationCreator,
 			interfaceAddrsValidator,
 			dnsValidator,
+			nil,
 			addressBroadcaster,
 			logger,
 		).(UbuntuNetManager)
@@ -334,12 +335,20 @@ iface eth0 inet static
     address 1.2.3.4
     network 1.2.3.0
     netmask 255.255.255.0
+    up ip route replace 1.2.3.0/24 dev eth0 table 100
+    up ip route replace 0.0.0.0/0 via 3.4.5.6 dev eth0 table 100
+    up ip rule add from 1.2.3.4 table 100
+    down ip rule del from 1.2.3.4 table 100
 
 auto eth1
 iface eth1 inet static
     address 5.6.7.8
     network 5.6.7.0
     netmask 255.255.255.0
+    up ip route replace 5.6.7.0/24 dev eth1 table 101
+    up ip route replace 0.0.0.0/0 via 6.7.8.9 dev eth1 table 101
+    up ip rule add from 5.6.7.8 table 101
+    down ip rule del from 5.6.7.8 table 101
     broadcast 5.6.7.255
     gateway 6.7.8.9
 
@@ -347,6 +356,323 @@ dns-nameservers 8.8.8.8`))
 
 		})
 
+		Context("when network has an IPv6 address", func() {
+			var ipv6Network boshsettings.Network
+
+			BeforeEach(func() {
+				staticNetwork.Default = nil
+				ipv6Network = boshsettings.Network{
+					Type:    "manual",
+					IP:      "2001:db8::1234",
+					Netmask: "ffff:ffff:ffff:ffff::",
+					Gateway: "2001:db8::1",
+					Mac:     "fake-ipv6-mac-address",
+					DNS:     []string{"2001:4860:4860::8888"},
+					Default: []string{"gateway", "dns"},
+				}
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"eth0": staticNetwork,
+					"eth1": ipv6Network,
+				})
+
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+					boship.NewSimpleInterfaceAddress("eth1", "fe80::1"),
+					boship.NewSimpleInterfaceAddress("eth1", "2001:db8:0:0::1234"),
+				}
+
+				fs.WriteFileString("/etc/resolv.conf", "nameserver 2001:4860:4860::8888\n")
+			})
+
+			It("writes inet6 stanza with prefix length and IPv6 gateway", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{
+					"static-1": staticNetwork,
+					"static-2": ipv6Network,
+				}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig).ToNot(BeNil())
+				Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+
+auto eth1
+iface eth1 inet6 static
+    address 2001:db8::1234
+    netmask 64
+    gateway 2001:db8::1
+
+dns-nameservers 2001:4860:4860::8888`))
+			})
+
+			It("disables IPv6 autoconfiguration on static IPv6 interfaces", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{
+					"static-1": staticNetwork,
+					"static-2": ipv6Network,
+				}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+				Expect(sysctlConfig).ToNot(BeNil())
+				Expect(sysctlConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+net.ipv6.conf.eth1.disable_ipv6 = 0
+net.ipv6.conf.eth1.autoconf = 0
+net.ipv6.conf.eth1.accept_ra = 0
+`))
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"sysctl", "-p", "/etc/sysctl.d/60-bosh-ipv6.conf"}))
+			})
+
+			It("does not reapply kernel parameters when they did not change", func() {
+				networks := boshsettings.Networks{
+					"static-1": staticNetwork,
+					"static-2": ipv6Network,
+				}
+
+				errCh := make(chan error)
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred()) // wait for all arpings
+
+				cmdRunner.RunCommands = [][]string{}
+
+				err = netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).ToNot(ContainElement([]string{"sysctl", "-p", "/etc/sysctl.d/60-bosh-ipv6.conf"}))
+			})
+
+			Context("when IPv6 network has the same MAC address as IPv4 network", func() {
+				BeforeEach(func() {
+					ipv6Network.Mac = staticNetwork.Mac
+					stubInterfaces(map[string]boshsettings.Network{
+						"eth0": staticNetwork,
+					})
+
+					interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+						boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+						boship.NewSimpleInterfaceAddress("eth0", "2001:db8::1234"),
+					}
+				})
+
+				It("writes inet and inet6 stanzas for the same interface and restarts it once", func() {
+					err := netManager.SetupNetworking(boshsettings.Networks{
+						"static-1": staticNetwork,
+						"static-2": ipv6Network,
+					}, nil)
+					Expect(err).ToNot(HaveOccurred())
+
+					networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+					Expect(networkConfig).ToNot(BeNil())
+					Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+iface eth0 inet6 static
+    address 2001:db8::1234
+    netmask 64
+    gateway 2001:db8::1
+
+dns-nameservers 2001:4860:4860::8888`))
+
+					Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "eth0"}))
+				})
+
+				It("fails when IPv6 address did not come up", func() {
+					interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+						boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+					}
+
+					err := netManager.SetupNetworking(boshsettings.Networks{
+						"static-1": staticNetwork,
+						"static-2": ipv6Network,
+					}, nil)
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("expected: '2001:db8::1234', actual: '1.2.3.4'"))
+				})
+			})
+
+			It("returns error if applying kernel parameters fails", func() {
+				cmdRunner.AddCmdResult("sysctl -p /etc/sysctl.d/60-bosh-ipv6.conf", fakesys.FakeCmdResult{Error: errors.New("fake-sysctl-err")})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{
+					"static-1": staticNetwork,
+					"static-2": ipv6Network,
+				}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-sysctl-err"))
+			})
+		})
+
+		It("writes VLAN sub-interfaces with their raw device", func() {
+			vlanNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Gateway: "5.6.7.1",
+				Mac:     staticNetwork.Mac,
+				VLANID:  123,
+			}
+			dhcpVLANNetwork := boshsettings.Network{
+				Type:   "dynamic",
+				Mac:    staticNetwork.Mac,
+				VLANID: 456,
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": staticNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth0.123", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"static-network": staticNetwork,
+				"vlan-network":   vlanNetwork,
+				"dhcp-vlan":      dhcpVLANNetwork,
+			}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth0.456
+iface eth0.456 inet dhcp
+    vlan-raw-device eth0
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    up ip route replace 1.2.3.0/24 dev eth0 table 100
+    up ip route replace 0.0.0.0/0 via 3.4.5.6 dev eth0 table 100
+    up ip rule add from 1.2.3.4 table 100
+    down ip rule del from 1.2.3.4 table 100
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+auto eth0.123
+iface eth0.123 inet static
+    address 5.6.7.8
+    network 5.6.7.0
+    netmask 255.255.255.0
+    vlan-raw-device eth0
+    up ip route replace 5.6.7.0/24 dev eth0.123 table 101
+    up ip route replace 0.0.0.0/0 via 5.6.7.1 dev eth0.123 table 101
+    up ip rule add from 5.6.7.8 table 101
+    down ip rule del from 5.6.7.8 table 101
+
+`))
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "eth0.456", "eth0", "eth0.123"}))
+		})
+
+		It("writes bond interfaces with their slaves", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Default: []string{"gateway"},
+				Bond: &boshsettings.Bond{
+					Mode:   "802.3ad",
+					Miimon: 100,
+					Slaves: []string{"fake-slave-mac-1", "fake-slave-mac-2"},
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": boshsettings.Network{Mac: "fake-slave-mac-1"},
+				"eth1": boshsettings.Network{Mac: "fake-slave-mac-2"},
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bond-network": bondNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto bond0
+iface bond0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    bond-mode 802.3ad
+    bond-miimon 100
+    bond-slaves eth0 eth1
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+`))
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "bond0"}))
+		})
+
+		It("writes bridge interfaces with their ports", func() {
+			bridgeNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				Default: []string{"gateway"},
+				Mac:     "fake-eth0-mac",
+				Bridge:  "br0",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": bridgeNetwork,
+			})
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("br0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bridge-network": bridgeNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto br0
+iface br0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    bridge_ports eth0
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+`))
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "br0"}))
+		})
+
 		It("writes /etc/network/interfaces without dns-namservers if there are no dns servers", func() {
 			staticNetworkWithoutDNS := boshsettings.Network{
 				Type:    "manual",
@@ -466,6 +792,242 @@ request subnet-mask, broadcast-address, time-offset, routers,
 
 		})
 
+		It("adds static routes when interfaces are brought up", func() {
+			staticNetwork.Routes = []boshsettings.Route{
+				{Destination: "10.0.0.0/8", Gateway: "1.2.3.1"},
+				{Destination: "172.16.0.0/12"},
+			}
+			dhcpNetwork.Routes = []boshsettings.Route{{Destination: "192.168.0.0/16", Gateway: "192.168.1.1"}}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethdhcp":   dhcpNetwork,
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto ethdhcp
+iface ethdhcp inet dhcp
+    up ip route replace 192.168.0.0/16 via 192.168.1.1 dev ethdhcp
+
+auto ethstatic
+iface ethstatic inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    up ip route replace 10.0.0.0/8 via 1.2.3.1 dev ethstatic
+    up ip route replace 172.16.0.0/12 dev ethstatic
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+
+dns-nameservers 8.8.8.8 9.9.9.9`))
+		})
+
+		Context("when pinning interface names", func() {
+			It("writes udev rules pinning names of configured interfaces to their MAC addresses", func() {
+				stubInterfacesWithVirtual(map[string]boshsettings.Network{
+					"ethdhcp":   dhcpNetwork,
+					"ethstatic": staticNetwork,
+				}, []string{"lo"})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				udevRules := fs.GetFileTestStat("/etc/udev/rules.d/70-bosh-persistent-net.rules")
+				Expect(udevRules).ToNot(BeNil())
+				Expect(udevRules.StringContents()).To(Equal(`# Generated by bosh-agent
+SUBSYSTEM=="net", ACTION=="add", DRIVERS=="?*", ATTR{address}=="fake-dhcp-mac-address", ATTR{type}=="1", NAME="ethdhcp"
+SUBSYSTEM=="net", ACTION=="add", DRIVERS=="?*", ATTR{address}=="fake-static-mac-address", ATTR{type}=="1", NAME="ethstatic"
+`))
+			})
+
+			It("pins names of physical interfaces carrying VLAN sub-interfaces", func() {
+				staticNetwork.VLANID = 100
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("ethstatic.100", "1.2.3.4"),
+				}
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				udevRules := fs.GetFileTestStat("/etc/udev/rules.d/70-bosh-persistent-net.rules")
+				Expect(udevRules).ToNot(BeNil())
+				Expect(udevRules.StringContents()).To(ContainSubstring(`ATTR{address}=="fake-static-mac-address", ATTR{type}=="1", NAME="ethstatic"`))
+				Expect(udevRules.StringContents()).ToNot(ContainSubstring(`NAME="ethstatic.100"`))
+			})
+
+			It("returns error if writing udev rules fails", func() {
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+				fs.WriteFileErrors["/etc/udev/rules.d/70-bosh-persistent-net.rules"] = errors.New("fake-write-err")
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Pinning interface names"))
+				Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+			})
+		})
+
+		Context("when network requests SR-IOV virtual functions", func() {
+			It("creates virtual functions on the physical function of the network", func() {
+				staticNetwork.SRIOV = &boshsettings.SRIOV{NumVFs: 2}
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_totalvfs", "8\n")
+				fs.WriteFileString("/sys/class/net/ethstatic/device/sriov_numvfs", "0\n")
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				numVFs, err := fs.ReadFileString("/sys/class/net/ethstatic/device/sriov_numvfs")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(numVFs).To(Equal("2"))
+			})
+
+			It("returns error if network has no MAC address of physical function", func() {
+				staticNetwork.Mac = ""
+				staticNetwork.SRIOV = &boshsettings.SRIOV{NumVFs: 2}
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("SR-IOV network 'static-network' has no MAC address of physical function"))
+			})
+		})
+
+		Context("when networks have MTU", func() {
+			BeforeEach(func() {
+				dhcpNetwork.MTU = 9000
+				staticNetwork.MTU = 1400
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethdhcp":   dhcpNetwork,
+					"ethstatic": staticNetwork,
+				})
+			})
+
+			It("writes mtu of interfaces in /etc/network/interfaces", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig).ToNot(BeNil())
+				Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto ethdhcp
+iface ethdhcp inet dhcp
+    mtu 9000
+
+auto ethstatic
+iface ethstatic inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    mtu 1400
+    broadcast 1.2.3.255
+    gateway 3.4.5.6
+
+dns-nameservers 8.8.8.8 9.9.9.9`))
+			})
+
+			It("supersedes mtu offered by dhcp server", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+				Expect(dhcpConfig).ToNot(BeNil())
+				Expect(dhcpConfig.StringContents()).To(HaveSuffix(`
+prepend domain-name-servers 8.8.8.8, 9.9.9.9;
+
+interface "ethdhcp" {
+	supersede interface-mtu 9000;
+}
+`))
+			})
+		})
+
+		Context("when dynamic network acquires IPv6 address", func() {
+			BeforeEach(func() {
+				dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeSLAAC
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethdhcp":   dhcpNetwork,
+					"ethstatic": staticNetwork,
+				})
+
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+					boship.NewTemporaryInterfaceAddress("ethdhcp", "2001:db8::a1b2:c3d4"),
+					boship.NewSimpleInterfaceAddress("ethdhcp", "2001:db8::f816:3eff:fe00:1"),
+				}
+			})
+
+			It("writes IPv6 stanza of the interface in /etc/network/interfaces", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig).ToNot(BeNil())
+				Expect(networkConfig.StringContents()).To(ContainSubstring(`
+auto ethdhcp
+iface ethdhcp inet dhcp
+iface ethdhcp inet6 auto
+`))
+			})
+
+			It("uses dhclient for IPv6 when network acquires address via DHCPv6", func() {
+				dhcpNetwork.IPv6Mode = boshsettings.IPv6ModeDHCPv6
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig).ToNot(BeNil())
+				Expect(networkConfig.StringContents()).To(ContainSubstring(`
+auto ethdhcp
+iface ethdhcp inet dhcp
+iface ethdhcp inet6 dhcp
+`))
+			})
+
+			It("enables router advertisements and autoconfiguration on the interface", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				sysctlConfig := fs.GetFileTestStat("/etc/sysctl.d/60-bosh-ipv6.conf")
+				Expect(sysctlConfig).ToNot(BeNil())
+				Expect(sysctlConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+net.ipv6.conf.ethdhcp.disable_ipv6 = 0
+net.ipv6.conf.ethdhcp.autoconf = 1
+net.ipv6.conf.ethdhcp.accept_ra = 1
+`))
+			})
+
+			It("fails when interface did not acquire stable IPv6 address", func() {
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+					boship.NewTemporaryInterfaceAddress("ethdhcp", "2001:db8::a1b2:c3d4"),
+				}
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Validating dynamic IPv6 network configuration"))
+			})
+		})
+
 		It("returns an error if it can't write a dhcp configuration", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"ethdhcp":   dhcpNetwork,
@@ -671,6 +1233,63 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			})
 		})
 
+		Context("when dns is managed by systemd-resolved", func() {
+			BeforeEach(func() {
+				netManager = NewUbuntuNetManager(
+					fs,
+					cmdRunner,
+					ipResolver,
+					interfaceConfigurationCreator,
+					boship.NewInterfaceAddressesValidator(interfaceAddrsProvider),
+					NewResolvedDNSValidator(cmdRunner),
+					NewResolvedDNSManager(fs, cmdRunner),
+					addressBroadcaster,
+					boshlog.NewLogger(boshlog.LevelNone),
+				).(UbuntuNetManager)
+
+				cmdRunner.CommandExistsValue = true
+				cmdRunner.AddCmdResult("resolvectl dns", fakesys.FakeCmdResult{Stdout: "Global: 8.8.8.8 9.9.9.9\n"})
+
+				dhcpNetwork.Domains = []string{"example.com"}
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethdhcp":   dhcpNetwork,
+					"ethstatic": staticNetwork,
+				})
+			})
+
+			It("configures dns servers and search domains for systemd-resolved instead of resolvconf", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedConf := fs.GetFileTestStat("/etc/systemd/resolved.conf.d/bosh.conf")
+				Expect(resolvedConf).ToNot(BeNil())
+				Expect(resolvedConf.StringContents()).To(Equal("# Generated by bosh-agent\n[Resolve]\nDNS=8.8.8.8 9.9.9.9\nDomains=example.com\n"))
+
+				networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+				Expect(networkConfig).ToNot(BeNil())
+				Expect(networkConfig.StringContents()).ToNot(ContainSubstring("dns-nameservers"))
+
+				dhcpConfig := fs.GetFileTestStat("/etc/dhcp/dhclient.conf")
+				Expect(dhcpConfig).ToNot(BeNil())
+				Expect(dhcpConfig.StringContents()).ToNot(ContainSubstring("prepend domain-name-servers"))
+
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"systemctl", "restart", "systemd-resolved"}))
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"resolvectl", "dns"}))
+			})
+
+			It("only configures systemd-resolved when networks are preconfigured", func() {
+				dhcpNetwork.Preconfigured = true
+				staticNetwork.Preconfigured = true
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/systemd/resolved.conf.d/bosh.conf")).To(BeTrue())
+				Expect(fs.FileExists("/etc/resolvconf/resolv.conf.d/head")).To(BeFalse())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-resolved"}}))
+			})
+		})
+
 		Context("when no MAC address is provided in the settings", func() {
 			It("configures network for single device", func() {
 				staticNetworkWithoutMAC := boshsettings.Network{
@@ -0,0 +1,268 @@
+package net
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	wireGuardManagerLogTag = "wireGuardManager"
+
+	wireGuardConfigDir        = "/etc/wireguard"
+	wireGuardConfigPrefix     = "bosh-"
+	wireGuardConfigPermission = os.FileMode(0600)
+)
+
+// Configuration is applied with 'wg setconf' which does not accept addresses,
+// they are assigned to the interface separately
+const wireGuardConfigTemplate = `# Generated by bosh-agent
+[Interface]
+PrivateKey = {{ .PrivateKey }}
+{{ if .ListenPort }}ListenPort = {{ .ListenPort }}
+{{ end }}{{ range .Peers }}
+[Peer]
+PublicKey = {{ .PublicKey }}
+{{ if .PresharedKey }}PresharedKey = {{ .PresharedKey }}
+{{ end }}{{ if .Endpoint }}Endpoint = {{ .Endpoint }}
+{{ end }}{{ if .AllowedIPs }}AllowedIPs = {{ join .AllowedIPs ", " }}
+{{ end }}{{ if .PersistentKeepalive }}PersistentKeepalive = {{ .PersistentKeepalive }}
+{{ end }}{{ end }}`
+
+// WireGuardManager maintains interfaces of wireguard networks next to interfaces of other networks
+type WireGuardManager interface {
+	SetupWireGuard(networks boshsettings.Networks) error
+}
+
+type wireGuardManager struct {
+	fs        boshsys.FileSystem
+	cmdRunner boshsys.CmdRunner
+	logger    boshlog.Logger
+}
+
+func NewWireGuardManager(fs boshsys.FileSystem, cmdRunner boshsys.CmdRunner, logger boshlog.Logger) WireGuardManager {
+	return wireGuardManager{
+		fs:        fs,
+		cmdRunner: cmdRunner,
+		logger:    logger,
+	}
+}
+
+// SetupWireGuard creates and configures interfaces of wireguard networks
+// and deletes interfaces of wireguard networks that are no longer configured
+func (m wireGuardManager) SetupWireGuard(networks boshsettings.Networks) error {
+	networkNames := []string{}
+	for name, networkSettings := range networks {
+		if networkSettings.IsWireGuard() {
+			networkNames = append(networkNames, name)
+		}
+	}
+
+	sort.Strings(networkNames)
+
+	configPaths := map[string]bool{}
+
+	for i, name := range networkNames {
+		networkSettings := networks[name]
+		if networkSettings.WireGuard == nil {
+			return bosherr.Errorf("WireGuard network '%s' has no wireguard settings", name)
+		}
+
+		ifaceName := networkSettings.WireGuard.InterfaceName
+		if ifaceName == "" {
+			ifaceName = fmt.Sprintf("wg%d", i)
+		}
+
+		configPath := wireGuardConfigPath(ifaceName)
+		if configPaths[configPath] {
+			return bosherr.Errorf("WireGuard network '%s' uses interface '%s' of another network", name, ifaceName)
+		}
+		configPaths[configPath] = true
+
+		err := m.setupInterface(name, ifaceName, networkSettings)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting up WireGuard network '%s'", name)
+		}
+	}
+
+	existingConfigPaths, err := m.fs.Glob(path.Join(wireGuardConfigDir, wireGuardConfigPrefix+"*.conf"))
+	if err != nil {
+		return bosherr.WrapError(err, "Listing WireGuard configurations")
+	}
+
+	for _, configPath := range existingConfigPaths {
+		if configPaths[configPath] {
+			continue
+		}
+
+		ifaceName := strings.TrimSuffix(strings.TrimPrefix(path.Base(configPath), wireGuardConfigPrefix), ".conf")
+
+		if m.interfaceExists(ifaceName) {
+			m.logger.Info(wireGuardManagerLogTag, "Deleting interface '%s' of removed WireGuard network", ifaceName)
+
+			_, _, _, err = m.cmdRunner.RunCommand("ip", "link", "delete", "dev", ifaceName)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Deleting WireGuard interface '%s'", ifaceName)
+			}
+		}
+
+		err = m.fs.RemoveAll(configPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing %s", configPath)
+		}
+	}
+
+	return nil
+}
+
+func (m wireGuardManager) setupInterface(networkName, ifaceName string, networkSettings boshsettings.Network) error {
+	wireGuard := networkSettings.WireGuard
+
+	if len(ifaceName) > maxInterfaceNameLength {
+		return bosherr.Errorf("Interface name '%s' is longer than %d characters", ifaceName, maxInterfaceNameLength)
+	}
+
+	if wireGuard.PrivateKey == "" {
+		return bosherr.Error("Private key must be provided")
+	}
+
+	if networkSettings.IP == "" || networkSettings.Netmask == "" {
+		return bosherr.Error("IP and netmask of the overlay must be provided")
+	}
+
+	for i, peer := range wireGuard.Peers {
+		if peer.PublicKey == "" {
+			return bosherr.Errorf("Public key of peer %d must be provided", i)
+		}
+	}
+
+	prefixLength, err := netmaskPrefixLength(networkSettings.Netmask)
+	if err != nil {
+		return err
+	}
+
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("wireguard-config").Funcs(template.FuncMap{
+		"join": strings.Join,
+	}).Parse(wireGuardConfigTemplate))
+
+	err = t.Execute(buffer, wireGuard)
+	if err != nil {
+		return bosherr.WrapError(err, "Generating config from template")
+	}
+
+	configPath := wireGuardConfigPath(ifaceName)
+
+	changed, err := m.writeConfig(configPath, buffer.String())
+	if err != nil {
+		return err
+	}
+
+	if !m.interfaceExists(ifaceName) {
+		_, _, _, err = m.cmdRunner.RunCommand("ip", "link", "add", "dev", ifaceName, "type", "wireguard")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating interface '%s'", ifaceName)
+		}
+
+		changed = true
+	}
+
+	if changed {
+		_, _, _, err = m.cmdRunner.RunCommand("wg", "setconf", ifaceName, configPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Configuring interface '%s'", ifaceName)
+		}
+	}
+
+	address := fmt.Sprintf("%s/%d", networkSettings.IP, prefixLength)
+
+	_, _, _, err = m.cmdRunner.RunCommand("ip", "address", "replace", address, "dev", ifaceName)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Assigning address '%s' to interface '%s'", address, ifaceName)
+	}
+
+	if networkSettings.MTU != 0 {
+		_, _, _, err = m.cmdRunner.RunCommand("ip", "link", "set", "dev", ifaceName, "mtu", strconv.Itoa(networkSettings.MTU))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Setting MTU of interface '%s'", ifaceName)
+		}
+	}
+
+	_, _, _, err = m.cmdRunner.RunCommand("ip", "link", "set", "dev", ifaceName, "up")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Bringing up interface '%s'", ifaceName)
+	}
+
+	m.logger.Debug(wireGuardManagerLogTag, "Set up interface '%s' of WireGuard network '%s'", ifaceName, networkName)
+
+	return nil
+}
+
+// writeConfig replaces configuration only when it changed; since it contains private key
+// of the instance it is never readable by others, not even before the rename
+func (m wireGuardManager) writeConfig(configPath, contents string) (bool, error) {
+	if m.fs.FileExists(configPath) {
+		existingContents, err := m.fs.ReadFileString(configPath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Reading %s", configPath)
+		}
+
+		if existingContents == contents {
+			return false, nil
+		}
+	}
+
+	err := m.fs.MkdirAll(wireGuardConfigDir, os.FileMode(0700))
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Creating %s", wireGuardConfigDir)
+	}
+
+	tmpPath := configPath + ".bosh-new"
+
+	// Leftover file may have been created with other permissions
+	err = m.fs.RemoveAll(tmpPath)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Removing %s", tmpPath)
+	}
+
+	file, err := m.fs.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, wireGuardConfigPermission)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Opening %s for writing", tmpPath)
+	}
+
+	_, err = file.Write([]byte(contents))
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = m.fs.RemoveAll(tmpPath)
+		return false, bosherr.WrapErrorf(err, "Writing to %s", tmpPath)
+	}
+
+	err = m.fs.Rename(tmpPath, configPath)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Replacing %s", configPath)
+	}
+
+	return true, nil
+}
+
+func (m wireGuardManager) interfaceExists(ifaceName string) bool {
+	return m.fs.FileExists(path.Join("/sys/class/net", ifaceName))
+}
+
+func wireGuardConfigPath(ifaceName string) string {
+	return path.Join(wireGuardConfigDir, wireGuardConfigPrefix+ifaceName+".conf")
+}
@@ -0,0 +1,186 @@
+package net_test
+
+import (
+	"errors"
+	"os"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+var _ = Describe("WireGuardManager", func() {
+	var (
+		fs               *fakesys.FakeFileSystem
+		cmdRunner        *fakesys.FakeCmdRunner
+		wireGuardManager WireGuardManager
+		overlayNetwork   boshsettings.Network
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		wireGuardManager = NewWireGuardManager(fs, cmdRunner, boshlog.NewLogger(boshlog.LevelNone))
+
+		overlayNetwork = boshsettings.Network{
+			Type:    boshsettings.NetworkTypeWireGuard,
+			IP:      "10.255.0.6",
+			Netmask: "255.255.255.0",
+			WireGuard: &boshsettings.WireGuard{
+				PrivateKey: "fake-private-key",
+				ListenPort: 51820,
+				Peers: []boshsettings.WireGuardPeer{
+					{
+						PublicKey:           "fake-public-key-1",
+						PresharedKey:        "fake-preshared-key",
+						Endpoint:            "10.0.0.7:51820",
+						AllowedIPs:          []string{"10.255.0.7/32", "10.255.1.0/24"},
+						PersistentKeepalive: 25,
+					},
+					{
+						PublicKey:  "fake-public-key-2",
+						AllowedIPs: []string{"10.255.0.8/32"},
+					},
+				},
+			},
+		}
+	})
+
+	It("writes configuration, creates interface and assigns overlay address", func() {
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{
+			"default": boshsettings.Network{IP: "10.0.0.6", Netmask: "255.255.255.0"},
+			"overlay": overlayNetwork,
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		configFile := fs.GetFileTestStat("/etc/wireguard/bosh-wg0.conf")
+		Expect(configFile).ToNot(BeNil())
+		Expect(configFile.FileMode).To(Equal(os.FileMode(0600)))
+		Expect(configFile.StringContents()).To(Equal(`# Generated by bosh-agent
+[Interface]
+PrivateKey = fake-private-key
+ListenPort = 51820
+
+[Peer]
+PublicKey = fake-public-key-1
+PresharedKey = fake-preshared-key
+Endpoint = 10.0.0.7:51820
+AllowedIPs = 10.255.0.7/32, 10.255.1.0/24
+PersistentKeepalive = 25
+
+[Peer]
+PublicKey = fake-public-key-2
+AllowedIPs = 10.255.0.8/32
+`))
+
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"ip", "link", "add", "dev", "wg0", "type", "wireguard"},
+			{"wg", "setconf", "wg0", "/etc/wireguard/bosh-wg0.conf"},
+			{"ip", "address", "replace", "10.255.0.6/24", "dev", "wg0"},
+			{"ip", "link", "set", "dev", "wg0", "up"},
+		}))
+	})
+
+	It("creates configuration readable only by root before moving it into place", func() {
+		fs.WriteFileString("/etc/wireguard/bosh-wg0.conf.bosh-new", "fake-leftover")
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.RenameOldPaths).To(Equal([]string{"/etc/wireguard/bosh-wg0.conf.bosh-new"}))
+		Expect(fs.FileExists("/etc/wireguard/bosh-wg0.conf.bosh-new")).To(BeFalse())
+		Expect(fs.GetFileTestStat("/etc/wireguard/bosh-wg0.conf").FileMode).To(Equal(os.FileMode(0600)))
+	})
+
+	It("returns error if configuration cannot be moved into place", func() {
+		fs.RenameError = errors.New("fake-rename-err")
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-rename-err"))
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	It("does not reconfigure existing interface when configuration did not change", func() {
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).ToNot(HaveOccurred())
+
+		fs.WriteFile("/sys/class/net/wg0", []byte{})
+		cmdRunner.RunCommands = [][]string{}
+
+		err = wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cmdRunner.RunCommands).To(Equal([][]string{
+			{"ip", "address", "replace", "10.255.0.6/24", "dev", "wg0"},
+			{"ip", "link", "set", "dev", "wg0", "up"},
+		}))
+	})
+
+	It("uses interface name and MTU from settings", func() {
+		overlayNetwork.WireGuard.InterfaceName = "wg-overlay"
+		overlayNetwork.MTU = 1420
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(fs.FileExists("/etc/wireguard/bosh-wg-overlay.conf")).To(BeTrue())
+		Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "set", "dev", "wg-overlay", "mtu", "1420"}))
+	})
+
+	It("deletes interfaces of removed wireguard networks", func() {
+		fs.WriteFileString("/etc/wireguard/bosh-wg1.conf", "fake-config")
+		fs.WriteFile("/sys/class/net/wg1", []byte{})
+		fs.SetGlob("/etc/wireguard/bosh-*.conf", []string{"/etc/wireguard/bosh-wg0.conf", "/etc/wireguard/bosh-wg1.conf"})
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ip", "link", "delete", "dev", "wg1"}))
+		Expect(fs.FileExists("/etc/wireguard/bosh-wg1.conf")).To(BeFalse())
+		Expect(fs.FileExists("/etc/wireguard/bosh-wg0.conf")).To(BeTrue())
+	})
+
+	It("does nothing without wireguard networks", func() {
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"default": boshsettings.Network{}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(cmdRunner.RunCommands).To(BeEmpty())
+	})
+
+	It("returns an error when private key is missing", func() {
+		overlayNetwork.WireGuard.PrivateKey = ""
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Setting up WireGuard network 'overlay': Private key must be provided"))
+	})
+
+	It("returns an error when peer has no public key", func() {
+		overlayNetwork.WireGuard.Peers[1].PublicKey = ""
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Public key of peer 1 must be provided"))
+	})
+
+	It("returns an error when network has no wireguard settings", func() {
+		overlayNetwork.WireGuard = nil
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("WireGuard network 'overlay' has no wireguard settings"))
+	})
+
+	It("returns an error when creating interface fails", func() {
+		cmdRunner.AddCmdResult("ip link add dev wg0 type wireguard", fakesys.FakeCmdResult{Error: errors.New("fake-ip-err")})
+
+		err := wireGuardManager.SetupWireGuard(boshsettings.Networks{"overlay": overlayNetwork})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-ip-err"))
+	})
+})
@@ -0,0 +1,328 @@
+package platform
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const defaultOrphanedProcessGracePeriodInSeconds = 30
+
+// procProcess holds fields of /proc/<pid>/stat needed to find orphaned job processes
+type procProcess struct {
+	Pid          int
+	ProcessGroup int
+}
+
+// TrackJobProcessGroups records process groups of job processes and their descendants
+// so that children escaping supervision can be found after jobs are stopped.
+// Groups led by processes outside of job's process tree (e.g. a job supervisor)
+// are not tracked, and groups without processes left are forgotten so that reused
+// ids are not tracked.
+func (p linux) TrackJobProcessGroups(jobNames []string) error {
+	p.jobProcessGroupsLock.Lock()
+	defer p.jobProcessGroupsLock.Unlock()
+
+	tracked, err := p.trackedJobProcessGroups()
+	if err != nil {
+		return err
+	}
+
+	processes, err := p.listProcesses()
+	if err != nil {
+		return err
+	}
+
+	groupsByPid := map[int]int{}
+	liveGroups := map[int]bool{}
+	for _, process := range processes {
+		groupsByPid[process.Pid] = process.ProcessGroup
+		liveGroups[process.ProcessGroup] = true
+	}
+
+	for _, jobName := range jobNames {
+		groups := map[int]bool{}
+
+		for _, group := range tracked[jobName] {
+			if liveGroups[group] {
+				groups[group] = true
+			}
+		}
+
+		pids, err := p.jobPids(jobName)
+		if err != nil {
+			return err
+		}
+
+		jobProcesses := map[int]bool{}
+		for _, pid := range pids {
+			for _, processPid := range p.processTree(pid) {
+				jobProcesses[processPid] = true
+			}
+		}
+
+		for processPid := range jobProcesses {
+			group, found := groupsByPid[processPid]
+			if !found {
+				continue
+			}
+
+			if _, leaderRunning := groupsByPid[group]; leaderRunning && !jobProcesses[group] {
+				continue
+			}
+
+			groups[group] = true
+		}
+
+		tracked[jobName] = sortedInts(groups)
+	}
+
+	contents, err := json.Marshal(tracked)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling job process groups")
+	}
+
+	err = p.fs.WriteFile(p.jobProcessGroupsPath(), contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing job process groups")
+	}
+
+	return nil
+}
+
+// KillOrphanedJobProcesses kills processes left in tracked process groups once jobs are stopped;
+// processes get a grace period to exit on their own and another one after SIGTERM before SIGKILL.
+// Each signalled process is recorded in an audit log next to job logs.
+func (p linux) KillOrphanedJobProcesses() error {
+	p.jobProcessGroupsLock.Lock()
+	defer p.jobProcessGroupsLock.Unlock()
+
+	tracked, err := p.trackedJobProcessGroups()
+	if err != nil {
+		return err
+	}
+
+	gracePeriod := p.options.OrphanedProcessGracePeriodInSeconds
+	if gracePeriod <= 0 {
+		gracePeriod = defaultOrphanedProcessGracePeriodInSeconds
+	}
+
+	for _, signal := range []string{"TERM", "KILL"} {
+		orphans, err := p.waitForOrphansToExit(tracked, gracePeriod)
+		if err != nil {
+			return err
+		}
+
+		if len(orphans) == 0 {
+			break
+		}
+
+		err = p.signalOrphans(orphans, signal)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.fs.RemoveAll(p.jobProcessGroupsPath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing job process groups")
+	}
+
+	return nil
+}
+
+// waitForOrphansToExit returns processes of tracked groups keyed by job name
+// that are still running after the grace period
+func (p linux) waitForOrphansToExit(tracked map[string][]int, gracePeriod int) (map[string][]procProcess, error) {
+	for i := 0; ; i++ {
+		orphans, err := p.findOrphans(tracked)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(orphans) == 0 || i >= gracePeriod {
+			return orphans, nil
+		}
+
+		p.timeService.Sleep(1 * time.Second)
+	}
+}
+
+func (p linux) findOrphans(tracked map[string][]int) (map[string][]procProcess, error) {
+	jobsByGroup := map[int]string{}
+	for jobName, groups := range tracked {
+		for _, group := range groups {
+			jobsByGroup[group] = jobName
+		}
+	}
+
+	processes, err := p.listProcesses()
+	if err != nil {
+		return nil, err
+	}
+
+	orphans := map[string][]procProcess{}
+
+	for _, process := range processes {
+		// Never signal init or the agent itself even if it somehow ended up in a job's group
+		if process.Pid == 1 || process.Pid == os.Getpid() {
+			continue
+		}
+
+		if jobName, found := jobsByGroup[process.ProcessGroup]; found {
+			orphans[jobName] = append(orphans[jobName], process)
+		}
+	}
+
+	return orphans, nil
+}
+
+func (p linux) signalOrphans(orphans map[string][]procProcess, signal string) error {
+	jobNames := []string{}
+	for jobName := range orphans {
+		jobNames = append(jobNames, jobName)
+	}
+	sort.Strings(jobNames)
+
+	args := []string{"-" + signal}
+	var auditLines []string
+
+	for _, jobName := range jobNames {
+		for _, process := range orphans[jobName] {
+			cmdline, _ := p.fs.ReadFileString(fmt.Sprintf("/proc/%d/cmdline", process.Pid))
+			cmdline = strings.TrimSpace(strings.Replace(cmdline, "\x00", " ", -1))
+
+			p.logger.Warn(logTag, "Sending SIG%s to orphaned process %d of job '%s': %s", signal, process.Pid, jobName, cmdline)
+
+			args = append(args, strconv.Itoa(process.Pid))
+			auditLines = append(auditLines, fmt.Sprintf("%s job=%s pid=%d pgid=%d signal=%s cmdline=%q\n",
+				p.timeService.Now().UTC().Format(time.RFC3339), jobName, process.Pid, process.ProcessGroup, signal, cmdline))
+		}
+	}
+
+	// Processes may have exited meanwhile so kill is expected to fail at times
+	_, _, _, err := p.cmdRunner.RunCommand("kill", args...)
+	if err != nil {
+		p.logger.Debug(logTag, "Sending SIG%s to orphaned processes: %s", signal, err.Error())
+	}
+
+	return p.appendOrphanedProcessesLog(auditLines)
+}
+
+func (p linux) appendOrphanedProcessesLog(lines []string) error {
+	logPath := path.Join(p.dirProvider.LogsDir(), "bosh-agent", "orphaned_processes.log")
+
+	err := p.fs.MkdirAll(path.Dir(logPath), os.FileMode(0750))
+	if err != nil {
+		return bosherr.WrapError(err, "Creating orphaned processes log dir")
+	}
+
+	logFile, err := p.fs.OpenFile(logPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, os.FileMode(0640))
+	if err != nil {
+		return bosherr.WrapError(err, "Opening orphaned processes log")
+	}
+
+	defer logFile.Close()
+
+	_, err = logFile.Write([]byte(strings.Join(lines, "")))
+	if err != nil {
+		return bosherr.WrapError(err, "Writing orphaned processes log")
+	}
+
+	return nil
+}
+
+func (p linux) trackedJobProcessGroups() (map[string][]int, error) {
+	tracked := map[string][]int{}
+
+	if !p.fs.FileExists(p.jobProcessGroupsPath()) {
+		return tracked, nil
+	}
+
+	contents, err := p.fs.ReadFile(p.jobProcessGroupsPath())
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading job process groups")
+	}
+
+	err = json.Unmarshal(contents, &tracked)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling job process groups")
+	}
+
+	return tracked, nil
+}
+
+func (p linux) jobProcessGroupsPath() string {
+	return path.Join(p.dirProvider.BoshDir(), "job_process_groups.json")
+}
+
+// listProcesses reads process groups of all processes from /proc;
+// processes exiting while being listed are skipped
+func (p linux) listProcesses() ([]procProcess, error) {
+	statPaths, err := p.fs.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing processes")
+	}
+
+	var processes []procProcess
+
+	for _, statPath := range statPaths {
+		stat, err := p.fs.ReadFileString(statPath)
+		if err != nil {
+			continue
+		}
+
+		process, err := parseProcStat(stat)
+		if err != nil {
+			p.logger.Debug(logTag, "Parsing '%s': %s", statPath, err.Error())
+			continue
+		}
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+// parseProcStat parses '<pid> (<comm>) <state> <ppid> <pgrp> ...';
+// comm may contain spaces and parentheses so fields are read after the last ')'
+func parseProcStat(stat string) (procProcess, error) {
+	commEnd := strings.LastIndex(stat, ")")
+	if commEnd < 0 {
+		return procProcess{}, bosherr.Error("Missing command name")
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(strings.SplitN(stat, "(", 2)[0]))
+	if err != nil {
+		return procProcess{}, bosherr.WrapError(err, "Parsing pid")
+	}
+
+	fields := strings.Fields(stat[commEnd+1:])
+	if len(fields) < 3 {
+		return procProcess{}, bosherr.Error("Missing process group")
+	}
+
+	group, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return procProcess{}, bosherr.WrapError(err, "Parsing process group")
+	}
+
+	return procProcess{Pid: pid, ProcessGroup: group}, nil
+}
+
+func sortedInts(set map[int]bool) []int {
+	ints := []int{}
+	for i := range set {
+		ints = append(ints, i)
+	}
+	sort.Ints(ints)
+
+	return ints
+}
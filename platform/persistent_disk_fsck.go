@@ -0,0 +1,73 @@
+package platform
+
+import (
+	"fmt"
+
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	// Filesystem errors are only reported
+	PersistentDiskFsckPolicyCheck = "check"
+
+	// Filesystem errors that can be fixed without operator input are repaired and mounting is retried
+	PersistentDiskFsckPolicyRepair = "repair"
+)
+
+// PersistentDiskFilesystemError describes outcome of the filesystem check
+// run after persistent disk failed to mount
+type PersistentDiskFilesystemError struct {
+	MountError string                         `json:"mount_error"`
+	Check      boshdisk.FilesystemCheckResult `json:"check"`
+}
+
+func (e PersistentDiskFilesystemError) Error() string {
+	var outcome string
+
+	switch {
+	case e.Check.Repaired && e.Check.Clean:
+		outcome = "errors were repaired but mounting still failed"
+	case e.Check.Repaired:
+		outcome = "some errors were repaired, remaining errors require manual repair"
+	case !e.Check.Clean:
+		outcome = "errors were found and not repaired"
+	default:
+		outcome = "no errors were found"
+	}
+
+	return fmt.Sprintf(
+		"Mounting partition: %s; filesystem check of %s (%s): %s (exit status %d)",
+		e.MountError, e.Check.PartitionPath, e.Check.FileSystemType, outcome, e.Check.ExitStatus,
+	)
+}
+
+// checkPersistentDiskFilesystem runs fsck according to the configured policy after
+// mounting failed; the partition is mounted again if all errors were repaired
+func (p linux) checkPersistentDiskFilesystem(partitionPath, mountPoint string, mountOptions []string, mountErr error) error {
+	policy := p.options.PersistentDiskFsckPolicy
+	if policy != PersistentDiskFsckPolicyCheck && policy != PersistentDiskFsckPolicyRepair {
+		return bosherr.WrapError(mountErr, "Mounting partition")
+	}
+
+	p.logger.Info(logTag, "Checking filesystem on `%s' after mounting failed: %s", partitionPath, mountErr.Error())
+
+	result, err := p.diskManager.GetFilesystemChecker().Check(partitionPath, policy == PersistentDiskFsckPolicyRepair)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Checking filesystem after mounting failed with '%s'", mountErr.Error())
+	}
+
+	p.logger.Info(logTag, "Filesystem check of `%s': repaired=%t clean=%t exit status=%d\n%s",
+		partitionPath, result.Repaired, result.Clean, result.ExitStatus, result.Output)
+
+	if result.Repaired && result.Clean {
+		err = p.diskManager.GetMounter().Mount(partitionPath, mountPoint, mountOptionArgs(mountOptions)...)
+		if err == nil {
+			return nil
+		}
+
+		mountErr = err
+	}
+
+	return PersistentDiskFilesystemError{MountError: mountErr.Error(), Check: result}
+}
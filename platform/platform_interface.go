@@ -3,6 +3,9 @@ package platform
 import (
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	"github.com/cloudfoundry/bosh-agent/platform/cert"
+	"github.com/cloudfoundry/bosh-agent/platform/cgroup"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -26,17 +29,37 @@ type Platform interface {
 	DeleteEphemeralUsersMatching(regex string) (err error)
 
 	// Bootstrap functionality
+	RunHooks(phase string) (err error)
 	SetupRootDisk(ephemeralDiskPath string) (err error)
 	SetupSSH(publicKey, username string) (err error)
 	SetUserPassword(user, encryptedPwd string) (err error)
 	SetupHostname(hostname string) (err error)
+	SetupHostsEntries(entries []boshsettings.HostsEntry) (err error)
+	SetupTimezone(timezone string) (err error)
+	SetupLocale(locale string) (err error)
+	SetupProxy(proxy boshsettings.Proxy) (err error)
+	SetupDNSCache(dnsCache boshsettings.DNSCache, networks boshsettings.Networks) (boshsettings.Networks, error)
 	SetupNetworking(networks boshsettings.Networks) (err error)
+	ValidateNetworking(networks boshsettings.Networks, probeHostname string) (err error)
+	SetupFirewall(firewall boshsettings.Firewall) (err error)
 	SetupLogrotate(groupName, basePath, size string) (err error)
 	SetTimeWithNtpServers(servers []string) (err error)
-	SetupEphemeralDiskWithPath(devicePath string) (err error)
+	SetupEphemeralDiskWithPath(devicePath string, diskSettings boshsettings.DiskSettings) (err error)
 	SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err error)
 	SetupDataDir() (err error)
 	SetupTmpDir() (err error)
+	SetupReadOnlyRoot() (err error)
+	SetupBindMounts() (err error)
+	SetupHugePages(hugePages boshsettings.HugePages) (err error)
+	SetupFilesystemTrimming() (err error)
+	SetupJobDiskQuotas(quotasInMB map[string]uint64) (err error)
+	SetupJobFirewallPorts(jobPorts map[string][]boshfirewall.Port) (err error)
+	SetupJobCgroups(jobLimits map[string]cgroup.Limits) (err error)
+	AddJobProcessesToCgroups(jobNames []string) (err error)
+	TrackJobProcessGroups(jobNames []string) (err error)
+	KillOrphanedJobProcesses() (err error)
+	SetupKernelArgs(kernelArgs boshsettings.KernelArgs) (rebootRequired bool, err error)
+	SetupTuningProfile(profile boshsettings.TuningProfile) (err error)
 	SetupMonitUser() (err error)
 	StartMonit() (err error)
 	SetupRuntimeConfiguration() (err error)
@@ -45,10 +68,12 @@ type Platform interface {
 	MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
 	UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error)
 	MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error)
+	GetPersistentDiskMigrationProgress() (progress DiskMigrationProgress, found bool)
 	GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string
 	IsMountPoint(path string) (partitionPath string, result bool, err error)
 	IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (result bool, err error)
 	IsPersistentDiskMountable(diskSettings boshsettings.DiskSettings) (bool, error)
+	ResizePersistentDisk(diskSettings boshsettings.DiskSettings) (err error)
 
 	GetFileContentsFromCDROM(filePath string) (contents []byte, err error)
 	GetFilesContentsFromDisk(diskPath string, fileNames []string) (contents [][]byte, err error)
@@ -57,6 +82,9 @@ type Platform interface {
 	GetDefaultNetwork() (boshsettings.Network, error)
 	GetConfiguredNetworkInterfaces() ([]string, error)
 	PrepareForNetworkingChange() error
+	PreviewNetworking(networks boshsettings.Networks) (boshnet.NetworkChanges, error)
+	AddAlias(networks boshsettings.Networks, networkName, address string) error
+	RemoveAlias(networks boshsettings.Networks, networkName, address string) error
 	DeleteARPEntryWithIP(ip string) error
 
 	// Additional monit management
@@ -64,6 +92,8 @@ type Platform interface {
 
 	GetCertManager() cert.Manager
 
+	GetCgroupManager() cgroup.Manager
+
 	GetHostPublicKey() (string, error)
 
 	RemoveDevTools(packageFileListPath string) error
@@ -1,12 +1,17 @@
 package platform
 
 import (
+	"path"
 	"time"
 
+	"github.com/pivotal-golang/clock"
+
 	"github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcdrom "github.com/cloudfoundry/bosh-agent/platform/cdrom"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshcgroup "github.com/cloudfoundry/bosh-agent/platform/cgroup"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshfirewall "github.com/cloudfoundry/bosh-agent/platform/firewall"
 	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
 	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
@@ -28,7 +33,9 @@ const (
 )
 
 const (
-	SigarStatsCollectionInterval = 10 * time.Second
+	StatsCollectionInterval      = 10 * time.Second
+	SMARTStatsCollectionInterval = 5 * time.Minute
+	VitalsPluginTimeout          = 5 * time.Second
 )
 
 type Provider interface {
@@ -41,6 +48,7 @@ type provider struct {
 
 type Options struct {
 	Linux LinuxOptions
+	Stats StatsOptions
 }
 
 func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsCollector boshstats.Collector, fs boshsys.FileSystem, options Options, bootstrapState *BootstrapState) Provider {
@@ -55,8 +63,10 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	compressor := boshcmd.NewTarballCompressor(runner, fs)
 	copier := boshcmd.NewCpCopier(runner, fs, logger)
 
+	statsCollector = options.Stats.statsCollector(statsCollector, runner, fs, dirProvider, logger)
+
 	// Kick of stats collection as soon as possible
-	go statsCollector.StartCollecting(SigarStatsCollectionInterval, nil)
+	go statsCollector.StartCollecting(options.Stats.collectionInterval(), nil)
 
 	vitalsService := boshvitals.NewService(statsCollector, dirProvider)
 
@@ -69,11 +79,89 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	interfaceAddressesValidator := boship.NewInterfaceAddressesValidator(interfaceAddressesProvider)
 	dnsValidator := boshnet.NewDNSValidator(fs)
 
-	centosNetManager := boshnet.NewCentosNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, logger)
-	ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, logger)
+	// systemd-resolved keeps upstream dns servers per link outside of /etc/resolv.conf
+	resolvedDNSValidator := boshnet.NewResolvedDNSValidator(runner)
+
+	// Net managers are also built on top of dry run file system and command runner to preview network changes
+	newNetManagers := func(
+		fs boshsys.FileSystem,
+		runner boshsys.CmdRunner,
+		interfaceAddressesValidator boship.InterfaceAddressesValidator,
+		dnsValidator boshnet.DNSValidator,
+		resolvedDNSValidator boshnet.DNSValidator,
+		addressBroadcaster bosharp.AddressBroadcaster,
+	) (boshnet.Manager, boshnet.Manager) {
+		var ubuntuDNSManager boshnet.DNSManager
+		ubuntuDNSValidator := dnsValidator
+
+		if options.Linux.DNSManagerType == "systemd-resolved" {
+			ubuntuDNSManager = boshnet.NewResolvedDNSManager(fs, runner)
+			ubuntuDNSValidator = resolvedDNSValidator
+		}
+
+		centosNetManager := boshnet.NewCentosNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, addressBroadcaster, logger)
+		ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, ubuntuDNSValidator, ubuntuDNSManager, addressBroadcaster, logger)
+
+		netplanNetManager := boshnet.NewNetplanNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, resolvedDNSValidator, addressBroadcaster, logger)
+		systemdNetworkdNetManager := boshnet.NewSystemdNetworkdNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, resolvedDNSValidator, addressBroadcaster, logger)
+
+		switch options.Linux.NetManagerType {
+		case "netplan":
+			centosNetManager = netplanNetManager
+			ubuntuNetManager = netplanNetManager
+		case "systemd-networkd":
+			centosNetManager = systemdNetworkdNetManager
+			ubuntuNetManager = systemdNetworkdNetManager
+		case "":
+			if !fs.FileExists("/etc/network/interfaces") && fs.FileExists("/etc/netplan") {
+				ubuntuNetManager = netplanNetManager
+			}
+		}
+
+		return centosNetManager, ubuntuNetManager
+	}
+
+	centosNetManager, ubuntuNetManager := newNetManagers(fs, runner, interfaceAddressesValidator, dnsValidator, resolvedDNSValidator, arping)
 
-	centosCertManager := boshcert.NewCentOSCertManager(fs, runner, 0, logger)
-	ubuntuCertManager := boshcert.NewUbuntuCertManager(fs, runner, 60, logger)
+	centosNetworkChangePreviewer := boshnet.NewNetworkChangePreviewer(fs, runner, interfaceAddressesValidator, func(
+		fs boshsys.FileSystem,
+		runner boshsys.CmdRunner,
+		interfaceAddressesValidator boship.InterfaceAddressesValidator,
+		dnsValidator boshnet.DNSValidator,
+		addressBroadcaster bosharp.AddressBroadcaster,
+	) boshnet.Manager {
+		centosNetManager, _ := newNetManagers(fs, runner, interfaceAddressesValidator, dnsValidator, dnsValidator, addressBroadcaster)
+		return centosNetManager
+	})
+
+	ubuntuNetworkChangePreviewer := boshnet.NewNetworkChangePreviewer(fs, runner, interfaceAddressesValidator, func(
+		fs boshsys.FileSystem,
+		runner boshsys.CmdRunner,
+		interfaceAddressesValidator boship.InterfaceAddressesValidator,
+		dnsValidator boshnet.DNSValidator,
+		addressBroadcaster bosharp.AddressBroadcaster,
+	) boshnet.Manager {
+		_, ubuntuNetManager := newNetManagers(fs, runner, interfaceAddressesValidator, dnsValidator, dnsValidator, addressBroadcaster)
+		return ubuntuNetManager
+	})
+
+	newCertManager := func(defaultTrustStore boshcert.TrustStore, timeout time.Duration) boshcert.Manager {
+		trustStore, err := options.Linux.trustStore(defaultTrustStore)
+		if err != nil {
+			logger.Error(logTag, "Using %s trust store: %s", defaultTrustStore.Name, err.Error())
+		}
+
+		return boshcert.NewCertManager(fs, runner, trustStore, timeout, logger)
+	}
+
+	centosCertManager := newCertManager(boshcert.CentOSTrustStore, 0)
+	ubuntuCertManager := newCertManager(boshcert.UbuntuTrustStore, 60)
+
+	cgroupManager := boshcgroup.NewLinuxManager(fs, "/sys/fs/cgroup", logger)
+
+	wireGuardManager := boshnet.NewWireGuardManager(fs, runner, logger)
+
+	firewallManager := boshfirewall.NewLinuxManager(fs, runner, path.Join(dirProvider.EtcDir(), "firewall.json"), logger)
 
 	routesSearcher := boshnet.NewCmdRoutesSearcher(runner)
 	linuxDefaultNetworkResolver := boshnet.NewDefaultNetworkResolver(routesSearcher, ipResolver)
@@ -81,21 +169,32 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	monitRetryable := NewMonitRetryable(runner)
 	monitRetryStrategy := boshretry.NewAttemptRetryStrategy(10, 1*time.Second, monitRetryable, logger)
 
+	// Device path resolvers wait for udev events instead of polling for attached disks
+	eventMonitor := boshudev.NewNetlinkEventMonitor(1*time.Second, logger)
+
+	resolutionStrategies := options.Linux.DevicePathResolutionStrategies
+	if len(resolutionStrategies) == 0 {
+		resolutionStrategies = []DevicePathResolutionStrategy{{Type: options.Linux.DevicePathResolutionType}}
+	}
+
 	var devicePathResolver devicepathresolver.DevicePathResolver
-	switch options.Linux.DevicePathResolutionType {
-	case "virtio":
-		udev := boshudev.NewConcreteUdevDevice(runner, logger)
-		idDevicePathResolver := devicepathresolver.NewIDDevicePathResolver(500*time.Millisecond, options.Linux.VirtioDevicePrefix, udev, fs)
-		mappedDevicePathResolver := devicepathresolver.NewMappedDevicePathResolver(500*time.Millisecond, fs)
-		devicePathResolver = devicepathresolver.NewVirtioDevicePathResolver(idDevicePathResolver, mappedDevicePathResolver, logger)
-	case "scsi":
-		scsiIDPathResolver := devicepathresolver.NewSCSIIDDevicePathResolver(50000*time.Millisecond, fs, logger)
-		scsiVolumeIDPathResolver := devicepathresolver.NewSCSIVolumeIDDevicePathResolver(500*time.Millisecond, fs)
-		devicePathResolver = devicepathresolver.NewScsiDevicePathResolver(scsiVolumeIDPathResolver, scsiIDPathResolver)
-	default:
-		devicePathResolver = devicepathresolver.NewIdentityDevicePathResolver()
+
+	if len(resolutionStrategies) == 1 {
+		devicePathResolver = newDevicePathResolver(resolutionStrategies[0], options.Linux, eventMonitor, runner, fs, logger)
+	} else {
+		chainedStrategies := make([]devicepathresolver.ChainedStrategy, len(resolutionStrategies))
+		for i, strategy := range resolutionStrategies {
+			chainedStrategies[i] = devicepathresolver.ChainedStrategy{
+				Name:     strategy.Type,
+				Resolver: newDevicePathResolver(strategy, options.Linux, eventMonitor, runner, fs, logger),
+			}
+		}
+		devicePathResolver = devicepathresolver.NewChainedDevicePathResolver(chainedStrategies, logger)
 	}
 
+	diskWaitTimeout, _ := options.Linux.devicePathResolutionTimeouts()
+	devicePathResolver = devicepathresolver.NewMultipathDevicePathResolver(diskWaitTimeout, eventMonitor, devicePathResolver, runner, fs, logger)
+
 	centos := NewLinuxPlatform(
 		fs,
 		runner,
@@ -107,10 +206,15 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 		linuxCdutil,
 		linuxDiskManager,
 		centosNetManager,
+		centosNetworkChangePreviewer,
+		wireGuardManager,
 		centosCertManager,
+		cgroupManager,
+		firewallManager,
 		monitRetryStrategy,
 		devicePathResolver,
 		500*time.Millisecond,
+		clock.NewClock(),
 		bootstrapState,
 		options.Linux,
 		logger,
@@ -128,10 +232,15 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 		linuxCdutil,
 		linuxDiskManager,
 		ubuntuNetManager,
+		ubuntuNetworkChangePreviewer,
+		wireGuardManager,
 		ubuntuCertManager,
+		cgroupManager,
+		firewallManager,
 		monitRetryStrategy,
 		devicePathResolver,
 		500*time.Millisecond,
+		clock.NewClock(),
 		bootstrapState,
 		options.Linux,
 		logger,
@@ -154,3 +263,47 @@ func (p provider) Get(name string) (Platform, error) {
 	}
 	return plat, nil
 }
+
+func newDevicePathResolver(
+	strategy DevicePathResolutionStrategy,
+	options LinuxOptions,
+	eventMonitor boshudev.EventMonitor,
+	runner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	logger boshlog.Logger,
+) devicepathresolver.DevicePathResolver {
+	diskWaitTimeout, probeTimeout := options.devicePathResolutionTimeouts()
+
+	if strategy.TimeoutInSeconds > 0 {
+		diskWaitTimeout = time.Duration(strategy.TimeoutInSeconds) * time.Second
+		probeTimeout = diskWaitTimeout
+	}
+
+	switch strategy.Type {
+	case "virtio":
+		udev := boshudev.NewConcreteUdevDevice(runner, logger)
+		idDevicePathResolver := devicepathresolver.NewIDDevicePathResolver(probeTimeout, eventMonitor, options.VirtioDevicePrefix, udev, fs)
+		mappedDevicePathResolver := devicepathresolver.NewMappedDevicePathResolver(probeTimeout, eventMonitor, fs)
+		return devicepathresolver.NewVirtioDevicePathResolver(idDevicePathResolver, mappedDevicePathResolver, logger)
+	case "scsi":
+		scsiIDPathResolver := devicepathresolver.NewSCSIIDDevicePathResolver(diskWaitTimeout, eventMonitor, fs, logger)
+		scsiVolumeIDPathResolver := devicepathresolver.NewSCSIVolumeIDDevicePathResolver(probeTimeout, fs)
+		return devicepathresolver.NewScsiDevicePathResolver(scsiVolumeIDPathResolver, scsiIDPathResolver)
+	case "iscsi":
+		identityDevicePathResolver := devicepathresolver.NewIdentityDevicePathResolver()
+		return devicepathresolver.NewISCSIDevicePathResolver(diskWaitTimeout, eventMonitor, runner, fs, identityDevicePathResolver, logger)
+	case "azure":
+		identityDevicePathResolver := devicepathresolver.NewIdentityDevicePathResolver()
+		return devicepathresolver.NewAzureLUNDevicePathResolver(diskWaitTimeout, eventMonitor, fs, identityDevicePathResolver, logger)
+	case "gce":
+		identityDevicePathResolver := devicepathresolver.NewIdentityDevicePathResolver()
+		return devicepathresolver.NewGCEDevicePathResolver(diskWaitTimeout, eventMonitor, fs, identityDevicePathResolver, logger)
+	case "xen":
+		return devicepathresolver.NewXenDevicePathResolver(diskWaitTimeout, eventMonitor, fs, logger)
+	case "nvme":
+		mappedDevicePathResolver := devicepathresolver.NewMappedDevicePathResolver(probeTimeout, eventMonitor, fs)
+		return devicepathresolver.NewNVMeDevicePathResolver(diskWaitTimeout, eventMonitor, fs, mappedDevicePathResolver, logger)
+	default:
+		return devicepathresolver.NewIdentityDevicePathResolver()
+	}
+}
@@ -0,0 +1,238 @@
+package stats
+
+import (
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	diskIOStatsPath = "/proc/diskstats"
+	mountsPath      = "/proc/mounts"
+
+	diskIOSectorSize = 512
+)
+
+type diskIOCounters struct {
+	reads        uint64
+	readSectors  uint64
+	readMs       uint64
+	writes       uint64
+	writeSectors uint64
+	writeMs      uint64
+
+	// Sum of time each request spent in flight
+	weightedMs uint64
+}
+
+type diskIOSample struct {
+	takenAt  time.Time
+	counters map[string]diskIOCounters
+}
+
+type diskIOStatsCollector struct {
+	Collector
+
+	fs     boshsys.FileSystem
+	clock  clock.Clock
+	logger boshlog.Logger
+	logTag string
+
+	latestDiskIO     map[string]DiskIOStats
+	latestDiskIOLock sync.RWMutex
+}
+
+// NewDiskIOStatsCollector adds per device I/O rates sampled from /proc/diskstats
+// at collection interval to stats of given collector; other stats are delegated
+func NewDiskIOStatsCollector(
+	collector Collector,
+	fs boshsys.FileSystem,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) Collector {
+	return &diskIOStatsCollector{
+		Collector:    collector,
+		fs:           fs,
+		clock:        clock,
+		logger:       logger,
+		logTag:       "diskIOStatsCollector",
+		latestDiskIO: map[string]DiskIOStats{},
+	}
+}
+
+func (c *diskIOStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	if c.fs.FileExists(diskIOStatsPath) {
+		go c.pollDiskIO(collectionInterval)
+	} else {
+		c.logger.Debug(c.logTag, "%s does not exist, disk I/O is not going to be collected", diskIOStatsPath)
+	}
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+// GetDiskIOStats returns rates of device mounted at given path
+func (c *diskIOStatsCollector) GetDiskIOStats(mountedPath string) (DiskIOStats, error) {
+	device, err := c.mountedDevice(mountedPath)
+	if err != nil {
+		return DiskIOStats{}, err
+	}
+
+	c.latestDiskIOLock.RLock()
+	defer c.latestDiskIOLock.RUnlock()
+
+	stats, found := c.latestDiskIO[device]
+	if !found {
+		return DiskIOStats{}, bosherr.Errorf("Disk I/O of device '%s' was not collected", device)
+	}
+
+	return stats, nil
+}
+
+func (c *diskIOStatsCollector) pollDiskIO(interval time.Duration) {
+	previous, err := c.sampleDiskIO()
+	if err != nil {
+		c.logger.Warn(c.logTag, "Sampling disk I/O: %s", err.Error())
+	}
+
+	for {
+		c.clock.Sleep(interval)
+
+		current, err := c.sampleDiskIO()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Sampling disk I/O: %s", err.Error())
+			continue
+		}
+
+		if previous.counters != nil {
+			c.latestDiskIOLock.Lock()
+			c.latestDiskIO = diskIORates(previous, current)
+			c.latestDiskIOLock.Unlock()
+		}
+
+		previous = current
+	}
+}
+
+func (c *diskIOStatsCollector) sampleDiskIO() (diskIOSample, error) {
+	contents, err := c.fs.ReadFileString(diskIOStatsPath)
+	if err != nil {
+		return diskIOSample{}, bosherr.WrapErrorf(err, "Reading %s", diskIOStatsPath)
+	}
+
+	sample := diskIOSample{
+		takenAt:  c.clock.Now(),
+		counters: map[string]diskIOCounters{},
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g. "   8       0 sda 4751 2 374210 2012 8329 3701 227656 6956 0 5140 8968"
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+
+		values := make([]uint64, 11)
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i+3], 10, 64)
+			if err != nil {
+				return diskIOSample{}, bosherr.WrapErrorf(err, "Parsing counters of device '%s'", fields[2])
+			}
+		}
+
+		sample.counters[fields[2]] = diskIOCounters{
+			reads:        values[0],
+			readSectors:  values[2],
+			readMs:       values[3],
+			writes:       values[4],
+			writeSectors: values[6],
+			writeMs:      values[7],
+			weightedMs:   values[10],
+		}
+	}
+
+	return sample, nil
+}
+
+// mountedDevice returns name of device in /proc/diskstats
+// that is mounted at given path, e.g. 'sda1' or 'dm-0'
+func (c *diskIOStatsCollector) mountedDevice(mountedPath string) (string, error) {
+	contents, err := c.fs.ReadFileString(mountsPath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Reading %s", mountsPath)
+	}
+
+	var devicePath string
+
+	// Last mount of the path hides earlier ones
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[1] == mountedPath && strings.HasPrefix(fields[0], "/dev/") {
+			devicePath = fields[0]
+		}
+	}
+
+	if devicePath == "" {
+		return "", bosherr.Errorf("No device is mounted at '%s'", mountedPath)
+	}
+
+	// Device mapper and by-uuid paths link to kernel device, e.g. '../dm-0'
+	if targetPath, err := c.fs.ReadLink(devicePath); err == nil && targetPath != "" {
+		devicePath = targetPath
+	}
+
+	return filepath.Base(devicePath), nil
+}
+
+func diskIORates(previous, current diskIOSample) map[string]DiskIOStats {
+	rates := map[string]DiskIOStats{}
+
+	elapsedSecs := current.takenAt.Sub(previous.takenAt).Seconds()
+	if elapsedSecs <= 0 {
+		return rates
+	}
+
+	for device, currentCounters := range current.counters {
+		previousCounters, found := previous.counters[device]
+		if !found {
+			continue
+		}
+
+		reads := counterDelta(previousCounters.reads, currentCounters.reads)
+		writes := counterDelta(previousCounters.writes, currentCounters.writes)
+		requestMs := counterDelta(previousCounters.readMs, currentCounters.readMs) +
+			counterDelta(previousCounters.writeMs, currentCounters.writeMs)
+
+		stats := DiskIOStats{
+			ReadIOPS:         reads / elapsedSecs,
+			WriteIOPS:        writes / elapsedSecs,
+			ReadBytesPerSec:  counterDelta(previousCounters.readSectors, currentCounters.readSectors) * diskIOSectorSize / elapsedSecs,
+			WriteBytesPerSec: counterDelta(previousCounters.writeSectors, currentCounters.writeSectors) * diskIOSectorSize / elapsedSecs,
+			QueueDepth:       counterDelta(previousCounters.weightedMs, currentCounters.weightedMs) / (elapsedSecs * 1000),
+		}
+
+		if reads+writes > 0 {
+			stats.AwaitMs = requestMs / (reads + writes)
+		}
+
+		rates[device] = stats
+	}
+
+	return rates
+}
+
+// counterDelta treats counters that went back as reset, e.g. after device was re-attached
+func counterDelta(previous, current uint64) float64 {
+	if current < previous {
+		return 0
+	}
+
+	return float64(current - previous)
+}
@@ -0,0 +1,103 @@
+package stats_test
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("diskIOStatsCollector", func() {
+	var (
+		innerCollector *fakestats.FakeCollector
+		fs             *fakesys.FakeFileSystem
+		clock          *fakeclock.FakeClock
+		collector      Collector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		fs = fakesys.NewFakeFileSystem()
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewDiskIOStatsCollector(innerCollector, fs, clock, logger)
+
+		fs.WriteFileString("/proc/mounts", `sysfs /sys sysfs rw 0 0
+/dev/sda1 / ext4 rw,relatime 0 0
+/dev/mapper/data /var/vcap/data ext4 rw,relatime 0 0
+`)
+		fs.Symlink("../dm-0", "/dev/mapper/data")
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	It("returns an error before collecting", func() {
+		_, err := collector.GetDiskIOStats("/")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Disk I/O of device 'sda1' was not collected"))
+	})
+
+	Describe("StartCollecting", func() {
+		BeforeEach(func() {
+			fs.WriteFileString("/proc/diskstats", `   8       1 sda1 1000 0 8000 2000 500 0 4000 3000 0 4000 10000
+ 253       0 dm-0 100 0 800 100 100 0 800 100 0 200 200
+`)
+
+			collector.StartCollecting(10*time.Second, nil)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			fs.WriteFileString("/proc/diskstats", `   8       1 sda1 1100 0 10000 2300 900 0 12000 4100 2 5000 25000
+ 253       0 dm-0 100 0 800 100 100 0 800 100 0 200 200
+`)
+			clock.Increment(10 * time.Second)
+		})
+
+		It("returns rates of device mounted at path over collection interval", func() {
+			Eventually(func() error {
+				_, err := collector.GetDiskIOStats("/")
+				return err
+			}).Should(Succeed())
+
+			stats, err := collector.GetDiskIOStats("/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(DiskIOStats{
+				ReadIOPS:         10,
+				WriteIOPS:        40,
+				ReadBytesPerSec:  102400,
+				WriteBytesPerSec: 409600,
+				QueueDepth:       1.5,
+				AwaitMs:          2.8,
+			}))
+		})
+
+		It("resolves device mapper links to kernel device", func() {
+			Eventually(func() error {
+				_, err := collector.GetDiskIOStats("/var/vcap/data")
+				return err
+			}).Should(Succeed())
+
+			stats, err := collector.GetDiskIOStats("/var/vcap/data")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(DiskIOStats{}))
+		})
+
+		It("returns an error when no device is mounted at path", func() {
+			_, err := collector.GetDiskIOStats("/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No device is mounted at '/var/vcap/store'"))
+		})
+	})
+})
@@ -0,0 +1,66 @@
+package stats
+
+import (
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type dnsCacheStatsCollector struct {
+	Collector
+
+	runner        boshsys.CmdRunner
+	listenAddress string
+}
+
+// NewDNSCacheStatsCollector adds counters of dnsmasq listening on given address
+// to stats of given collector; other stats are delegated
+func NewDNSCacheStatsCollector(collector Collector, runner boshsys.CmdRunner, listenAddress string) Collector {
+	return dnsCacheStatsCollector{
+		Collector:     collector,
+		runner:        runner,
+		listenAddress: listenAddress,
+	}
+}
+
+// GetDNSCacheStats queries counters that dnsmasq answers in CHAOS class
+// instead of polling them since it only takes a local query
+func (c dnsCacheStatsCollector) GetDNSCacheStats() (DNSCacheStats, error) {
+	var stats DNSCacheStats
+
+	if !c.runner.CommandExists("dig") {
+		return stats, bosherr.Error("dig is not installed")
+	}
+
+	hits, err := c.queryCounter("hits.bind")
+	if err != nil {
+		return stats, err
+	}
+
+	misses, err := c.queryCounter("misses.bind")
+	if err != nil {
+		return stats, err
+	}
+
+	stats.Hits = hits
+	stats.Misses = misses
+
+	return stats, nil
+}
+
+func (c dnsCacheStatsCollector) queryCounter(name string) (uint64, error) {
+	stdout, _, _, err := c.runner.RunCommand("dig", "+short", "+time=1", "+tries=1", "@"+c.listenAddress, "chaos", "txt", name)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Querying %s", name)
+	}
+
+	// e.g. '"1234"'
+	counter, err := strconv.ParseUint(strings.Trim(strings.TrimSpace(stdout), `"`), 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Parsing %s", name)
+	}
+
+	return counter, nil
+}
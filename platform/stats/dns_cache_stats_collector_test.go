@@ -0,0 +1,50 @@
+package stats_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("dnsCacheStatsCollector", func() {
+	var (
+		runner    *fakesys.FakeCmdRunner
+		collector Collector
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		runner.AvailableCommands = map[string]bool{"dig": true}
+		collector = NewDNSCacheStatsCollector(&fakestats.FakeCollector{}, runner, "127.0.0.1")
+	})
+
+	It("returns hits and misses of dns cache", func() {
+		runner.AddCmdResult("dig +short +time=1 +tries=1 @127.0.0.1 chaos txt hits.bind", fakesys.FakeCmdResult{Stdout: "\"1234\"\n"})
+		runner.AddCmdResult("dig +short +time=1 +tries=1 @127.0.0.1 chaos txt misses.bind", fakesys.FakeCmdResult{Stdout: "\"56\"\n"})
+
+		stats, err := collector.GetDNSCacheStats()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(Equal(DNSCacheStats{Hits: 1234, Misses: 56}))
+	})
+
+	It("returns an error when dns cache does not answer", func() {
+		runner.AddCmdResult("dig +short +time=1 +tries=1 @127.0.0.1 chaos txt hits.bind", fakesys.FakeCmdResult{Error: errors.New("fake-dig-err")})
+
+		_, err := collector.GetDNSCacheStats()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Querying hits.bind: fake-dig-err"))
+	})
+
+	It("returns an error when dig is not installed", func() {
+		runner.AvailableCommands = map[string]bool{}
+
+		_, err := collector.GetDNSCacheStats()
+		Expect(err).To(HaveOccurred())
+		Expect(runner.RunCommands).To(BeEmpty())
+	})
+})
@@ -1,6 +1,7 @@
 package stats
 
 import (
+	"errors"
 	"time"
 )
 
@@ -37,3 +38,39 @@ func (p dummyStatsCollector) GetDiskStats(devicePath string) (stats DiskStats, e
 	stats.InodeUsage.Total = 1
 	return
 }
+
+func (p dummyStatsCollector) GetDiskIOStats(mountedPath string) (stats DiskIOStats, err error) {
+	return DiskIOStats{}, errors.New("Disk I/O is not supported")
+}
+
+func (p dummyStatsCollector) GetNetworkStats() (stats map[string]NetworkStats, err error) {
+	return map[string]NetworkStats{}, nil
+}
+
+func (p dummyStatsCollector) GetSwapIOStats() (stats SwapIOStats, err error) {
+	return SwapIOStats{}, errors.New("Swap I/O is not supported")
+}
+
+func (p dummyStatsCollector) GetPressureStats() (stats map[string]Pressure, err error) {
+	return map[string]Pressure{}, nil
+}
+
+func (p dummyStatsCollector) GetDiskHealth() (health map[string]DiskHealth, err error) {
+	return map[string]DiskHealth{}, nil
+}
+
+func (p dummyStatsCollector) GetGPUStats() (stats map[string]GPUStats, err error) {
+	return map[string]GPUStats{}, nil
+}
+
+func (p dummyStatsCollector) GetPluginStats() (stats map[string]map[string]string, err error) {
+	return map[string]map[string]string{}, nil
+}
+
+func (p dummyStatsCollector) GetSystemLimitsStats() (stats SystemLimitsStats, err error) {
+	return SystemLimitsStats{}, nil
+}
+
+func (p dummyStatsCollector) GetDNSCacheStats() (stats DNSCacheStats, err error) {
+	return DNSCacheStats{}, errors.New("DNS cache is not supported")
+}
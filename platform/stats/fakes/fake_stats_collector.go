@@ -18,6 +18,30 @@ type FakeCollector struct {
 
 	SwapStats boshstats.Usage
 	DiskStats map[string]boshstats.DiskStats
+
+	DiskIOStats map[string]boshstats.DiskIOStats
+
+	NetworkStats    map[string]boshstats.NetworkStats
+	NetworkStatsErr error
+
+	SwapIOStats      *boshstats.SwapIOStats
+	PressureStats    map[string]boshstats.Pressure
+	PressureStatsErr error
+
+	DiskHealth    map[string]boshstats.DiskHealth
+	DiskHealthErr error
+
+	GPUStats    map[string]boshstats.GPUStats
+	GPUStatsErr error
+
+	PluginStats    map[string]map[string]string
+	PluginStatsErr error
+
+	SystemLimitsStats    boshstats.SystemLimitsStats
+	SystemLimitsStatsErr error
+
+	DNSCacheStats    boshstats.DNSCacheStats
+	DNSCacheStatsErr error
 }
 
 func (c *FakeCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
@@ -50,3 +74,46 @@ func (c *FakeCollector) GetDiskStats(devicePath string) (stats boshstats.DiskSta
 	}
 	return
 }
+
+func (c *FakeCollector) GetDiskIOStats(mountedPath string) (boshstats.DiskIOStats, error) {
+	stats, found := c.DiskIOStats[mountedPath]
+	if !found {
+		return stats, errors.New("Disk I/O not collected")
+	}
+	return stats, nil
+}
+
+func (c *FakeCollector) GetNetworkStats() (map[string]boshstats.NetworkStats, error) {
+	return c.NetworkStats, c.NetworkStatsErr
+}
+
+func (c *FakeCollector) GetSwapIOStats() (boshstats.SwapIOStats, error) {
+	if c.SwapIOStats == nil {
+		return boshstats.SwapIOStats{}, errors.New("Swap I/O not collected")
+	}
+	return *c.SwapIOStats, nil
+}
+
+func (c *FakeCollector) GetPressureStats() (map[string]boshstats.Pressure, error) {
+	return c.PressureStats, c.PressureStatsErr
+}
+
+func (c *FakeCollector) GetDiskHealth() (map[string]boshstats.DiskHealth, error) {
+	return c.DiskHealth, c.DiskHealthErr
+}
+
+func (c *FakeCollector) GetGPUStats() (map[string]boshstats.GPUStats, error) {
+	return c.GPUStats, c.GPUStatsErr
+}
+
+func (c *FakeCollector) GetPluginStats() (map[string]map[string]string, error) {
+	return c.PluginStats, c.PluginStatsErr
+}
+
+func (c *FakeCollector) GetSystemLimitsStats() (boshstats.SystemLimitsStats, error) {
+	return c.SystemLimitsStats, c.SystemLimitsStatsErr
+}
+
+func (c *FakeCollector) GetDNSCacheStats() (boshstats.DNSCacheStats, error) {
+	return c.DNSCacheStats, c.DNSCacheStatsErr
+}
@@ -0,0 +1,146 @@
+package stats
+
+import (
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	nvidiaSMIQuery = "index,name,utilization.gpu,memory.used,memory.total,temperature.gpu"
+
+	nvidiaSMIMebibyte = 1024 * 1024
+)
+
+type gpuStatsCollector struct {
+	Collector
+
+	runner boshsys.CmdRunner
+	clock  clock.Clock
+	logger boshlog.Logger
+	logTag string
+
+	latestGPU     map[string]GPUStats
+	latestGPULock sync.RWMutex
+}
+
+// NewGPUStatsCollector adds NVIDIA GPU readings polled with nvidia-smi
+// at collection interval to stats of given collector; other stats are delegated
+func NewGPUStatsCollector(
+	collector Collector,
+	runner boshsys.CmdRunner,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) Collector {
+	return &gpuStatsCollector{
+		Collector: collector,
+		runner:    runner,
+		clock:     clock,
+		logger:    logger,
+		logTag:    "gpuStatsCollector",
+		latestGPU: map[string]GPUStats{},
+	}
+}
+
+func (c *gpuStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	if c.runner.CommandExists("nvidia-smi") {
+		go c.pollGPU(collectionInterval)
+	} else {
+		c.logger.Debug(c.logTag, "nvidia-smi is not installed, GPU stats are not going to be collected")
+	}
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+func (c *gpuStatsCollector) GetGPUStats() (map[string]GPUStats, error) {
+	c.latestGPULock.RLock()
+	defer c.latestGPULock.RUnlock()
+
+	stats := make(map[string]GPUStats, len(c.latestGPU))
+	for index, gpuStats := range c.latestGPU {
+		stats[index] = gpuStats
+	}
+
+	return stats, nil
+}
+
+// pollGPU drops previous readings when nvidia-smi fails since utilization is only meaningful while current
+func (c *gpuStatsCollector) pollGPU(interval time.Duration) {
+	for {
+		stats, err := c.collectGPU()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Collecting GPU stats: %s", err.Error())
+			stats = map[string]GPUStats{}
+		}
+
+		c.latestGPULock.Lock()
+		c.latestGPU = stats
+		c.latestGPULock.Unlock()
+
+		c.clock.Sleep(interval)
+	}
+}
+
+func (c *gpuStatsCollector) collectGPU() (map[string]GPUStats, error) {
+	stdout, _, _, err := c.runner.RunCommand(
+		"nvidia-smi",
+		"--query-gpu="+nvidiaSMIQuery,
+		"--format=csv,noheader,nounits",
+	)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Querying GPUs with nvidia-smi")
+	}
+
+	reader := csv.NewReader(strings.NewReader(stdout))
+	reader.TrimLeadingSpace = true
+
+	// e.g. "0, Tesla T4, 35, 1024, 15360, 45"
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing nvidia-smi output")
+	}
+
+	stats := map[string]GPUStats{}
+
+	for _, record := range records {
+		if len(record) != 6 {
+			return nil, bosherr.Errorf("Parsing nvidia-smi output line '%s'", strings.Join(record, ", "))
+		}
+
+		gpuStats := GPUStats{
+			Name:               record[1],
+			UtilizationPercent: parseNvidiaSMIValue(record[2]),
+			TemperatureCelsius: parseNvidiaSMIValue(record[5]),
+		}
+
+		memoryUsed, memoryTotal := parseNvidiaSMIValue(record[3]), parseNvidiaSMIValue(record[4])
+		if memoryUsed != nil && memoryTotal != nil {
+			gpuStats.MemoryUsage = &Usage{
+				Used:  *memoryUsed * nvidiaSMIMebibyte,
+				Total: *memoryTotal * nvidiaSMIMebibyte,
+			}
+		}
+
+		stats[record[0]] = gpuStats
+	}
+
+	return stats, nil
+}
+
+// parseNvidiaSMIValue returns nil for readings like '[N/A]' or '[Not Supported]'
+func parseNvidiaSMIValue(field string) *uint64 {
+	value, err := strconv.ParseUint(strings.TrimSpace(field), 10, 64)
+	if err != nil {
+		return nil
+	}
+
+	return &value
+}
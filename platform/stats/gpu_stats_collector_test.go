@@ -0,0 +1,105 @@
+package stats_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("gpuStatsCollector", func() {
+	const nvidiaSMICmd = "nvidia-smi --query-gpu=index,name,utilization.gpu,memory.used,memory.total,temperature.gpu --format=csv,noheader,nounits"
+
+	var (
+		innerCollector *fakestats.FakeCollector
+		runner         *fakesys.FakeCmdRunner
+		clock          *fakeclock.FakeClock
+		collector      Collector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		runner = fakesys.NewFakeCmdRunner()
+		runner.CommandExistsValue = true
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewGPUStatsCollector(innerCollector, runner, clock, logger)
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	It("returns no GPUs before collecting", func() {
+		stats, err := collector.GetGPUStats()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(BeEmpty())
+	})
+
+	Describe("StartCollecting", func() {
+		getGPUStats := func() map[string]GPUStats {
+			stats, _ := collector.GetGPUStats()
+			return stats
+		}
+
+		It("returns readings of each GPU keyed by index", func() {
+			runner.AddCmdResult(nvidiaSMICmd, fakesys.FakeCmdResult{
+				Stdout: "0, Tesla T4, 35, 1024, 15360, 45\n1, NVIDIA A100-SXM4-40GB, [N/A], [N/A], [N/A], [Not Supported]\n",
+			})
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			utilization, temperature := uint64(35), uint64(45)
+
+			Eventually(getGPUStats).Should(Equal(map[string]GPUStats{
+				"0": GPUStats{
+					Name:               "Tesla T4",
+					UtilizationPercent: &utilization,
+					MemoryUsage:        &Usage{Used: 1024 * 1024 * 1024, Total: 15360 * 1024 * 1024},
+					TemperatureCelsius: &temperature,
+				},
+				"1": GPUStats{
+					Name: "NVIDIA A100-SXM4-40GB",
+				},
+			}))
+		})
+
+		It("polls nvidia-smi at collection interval and drops readings when it fails", func() {
+			runner.AddCmdResult(nvidiaSMICmd, fakesys.FakeCmdResult{
+				Stdout: "0, Tesla T4, 35, 1024, 15360, 45\n",
+			})
+			runner.AddCmdResult(nvidiaSMICmd, fakesys.FakeCmdResult{
+				Error: errors.New("fake-nvidia-smi-err"),
+			})
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			Eventually(getGPUStats).Should(HaveLen(1))
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			clock.Increment(10 * time.Second)
+
+			Eventually(getGPUStats).Should(BeEmpty())
+		})
+
+		It("does not collect when nvidia-smi is not installed", func() {
+			runner.CommandExistsValue = false
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			Consistently(func() int { return len(runner.RunCommands) }).Should(Equal(0))
+		})
+	})
+})
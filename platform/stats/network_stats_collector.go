@@ -0,0 +1,191 @@
+package stats
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const netDevStatsPath = "/proc/net/dev"
+
+type networkCounters struct {
+	rxBytes   uint64
+	rxPackets uint64
+	rxErrors  uint64
+	rxDrops   uint64
+	txBytes   uint64
+	txPackets uint64
+	txErrors  uint64
+	txDrops   uint64
+}
+
+type networkSample struct {
+	takenAt  time.Time
+	counters map[string]networkCounters
+}
+
+type networkStatsCollector struct {
+	Collector
+
+	fs     boshsys.FileSystem
+	clock  clock.Clock
+	logger boshlog.Logger
+	logTag string
+
+	latestNetwork     map[string]NetworkStats
+	latestNetworkLock sync.RWMutex
+}
+
+// NewNetworkStatsCollector adds per interface rates sampled from /proc/net/dev
+// at collection interval to stats of given collector; other stats are delegated
+func NewNetworkStatsCollector(
+	collector Collector,
+	fs boshsys.FileSystem,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) Collector {
+	return &networkStatsCollector{
+		Collector:     collector,
+		fs:            fs,
+		clock:         clock,
+		logger:        logger,
+		logTag:        "networkStatsCollector",
+		latestNetwork: map[string]NetworkStats{},
+	}
+}
+
+func (c *networkStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	if c.fs.FileExists(netDevStatsPath) {
+		go c.pollNetwork(collectionInterval)
+	} else {
+		c.logger.Debug(c.logTag, "%s does not exist, network stats are not going to be collected", netDevStatsPath)
+	}
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+func (c *networkStatsCollector) GetNetworkStats() (map[string]NetworkStats, error) {
+	c.latestNetworkLock.RLock()
+	defer c.latestNetworkLock.RUnlock()
+
+	stats := make(map[string]NetworkStats, len(c.latestNetwork))
+	for iface, ifaceStats := range c.latestNetwork {
+		stats[iface] = ifaceStats
+	}
+
+	return stats, nil
+}
+
+func (c *networkStatsCollector) pollNetwork(interval time.Duration) {
+	previous, err := c.sampleNetwork()
+	if err != nil {
+		c.logger.Warn(c.logTag, "Sampling network stats: %s", err.Error())
+	}
+
+	for {
+		c.clock.Sleep(interval)
+
+		current, err := c.sampleNetwork()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Sampling network stats: %s", err.Error())
+			continue
+		}
+
+		if previous.counters != nil {
+			c.latestNetworkLock.Lock()
+			c.latestNetwork = networkRates(previous, current)
+			c.latestNetworkLock.Unlock()
+		}
+
+		previous = current
+	}
+}
+
+func (c *networkStatsCollector) sampleNetwork() (networkSample, error) {
+	contents, err := c.fs.ReadFileString(netDevStatsPath)
+	if err != nil {
+		return networkSample{}, bosherr.WrapErrorf(err, "Reading %s", netDevStatsPath)
+	}
+
+	sample := networkSample{
+		takenAt:  c.clock.Now(),
+		counters: map[string]networkCounters{},
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g. "  eth0: 1234 10 0 0 0 0 0 0 5678 20 0 0 0 0 0 0"; header lines have no counters
+		parts := strings.SplitN(line, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		iface := strings.TrimSpace(parts[0])
+
+		// Loopback traffic does not leave the VM
+		if iface == "lo" {
+			continue
+		}
+
+		fields := strings.Fields(parts[1])
+		if len(fields) < 12 {
+			continue
+		}
+
+		values := make([]uint64, 12)
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return networkSample{}, bosherr.WrapErrorf(err, "Parsing counters of interface '%s'", iface)
+			}
+		}
+
+		sample.counters[iface] = networkCounters{
+			rxBytes:   values[0],
+			rxPackets: values[1],
+			rxErrors:  values[2],
+			rxDrops:   values[3],
+			txBytes:   values[8],
+			txPackets: values[9],
+			txErrors:  values[10],
+			txDrops:   values[11],
+		}
+	}
+
+	return sample, nil
+}
+
+func networkRates(previous, current networkSample) map[string]NetworkStats {
+	rates := map[string]NetworkStats{}
+
+	elapsedSecs := current.takenAt.Sub(previous.takenAt).Seconds()
+	if elapsedSecs <= 0 {
+		return rates
+	}
+
+	for iface, currentCounters := range current.counters {
+		previousCounters, found := previous.counters[iface]
+		if !found {
+			continue
+		}
+
+		rates[iface] = NetworkStats{
+			RxBytesPerSec:   counterDelta(previousCounters.rxBytes, currentCounters.rxBytes) / elapsedSecs,
+			RxPacketsPerSec: counterDelta(previousCounters.rxPackets, currentCounters.rxPackets) / elapsedSecs,
+			RxErrorsPerSec:  counterDelta(previousCounters.rxErrors, currentCounters.rxErrors) / elapsedSecs,
+			RxDropsPerSec:   counterDelta(previousCounters.rxDrops, currentCounters.rxDrops) / elapsedSecs,
+			TxBytesPerSec:   counterDelta(previousCounters.txBytes, currentCounters.txBytes) / elapsedSecs,
+			TxPacketsPerSec: counterDelta(previousCounters.txPackets, currentCounters.txPackets) / elapsedSecs,
+			TxErrorsPerSec:  counterDelta(previousCounters.txErrors, currentCounters.txErrors) / elapsedSecs,
+			TxDropsPerSec:   counterDelta(previousCounters.txDrops, currentCounters.txDrops) / elapsedSecs,
+		}
+	}
+
+	return rates
+}
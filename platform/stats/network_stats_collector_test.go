@@ -0,0 +1,82 @@
+package stats_test
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("networkStatsCollector", func() {
+	var (
+		innerCollector *fakestats.FakeCollector
+		fs             *fakesys.FakeFileSystem
+		clock          *fakeclock.FakeClock
+		collector      Collector
+	)
+
+	const netDevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		fs = fakesys.NewFakeFileSystem()
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewNetworkStatsCollector(innerCollector, fs, clock, logger)
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	It("returns no interfaces before collecting", func() {
+		stats, err := collector.GetNetworkStats()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(BeEmpty())
+	})
+
+	Describe("StartCollecting", func() {
+		It("returns rates of interfaces except loopback over collection interval", func() {
+			fs.WriteFileString("/proc/net/dev", netDevHeader+`    lo: 5000 50 0 0 0 0 0 0 5000 50 0 0 0 0 0 0
+  eth0: 10000 100 0 0 0 0 0 0 20000 200 0 0 0 0 0 0
+`)
+
+			collector.StartCollecting(10*time.Second, nil)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			fs.WriteFileString("/proc/net/dev", netDevHeader+`    lo: 9000 90 0 0 0 0 0 0 9000 90 0 0 0 0 0 0
+  eth0: 30480 300 2 10 0 0 0 0 25120 250 1 5 0 0 0 0
+`)
+			clock.Increment(10 * time.Second)
+
+			Eventually(func() map[string]NetworkStats {
+				stats, _ := collector.GetNetworkStats()
+				return stats
+			}).Should(Equal(map[string]NetworkStats{
+				"eth0": NetworkStats{
+					RxBytesPerSec:   2048,
+					RxPacketsPerSec: 20,
+					RxErrorsPerSec:  0.2,
+					RxDropsPerSec:   1,
+					TxBytesPerSec:   512,
+					TxPacketsPerSec: 5,
+					TxErrorsPerSec:  0.1,
+					TxDropsPerSec:   0.5,
+				},
+			}))
+		})
+	})
+})
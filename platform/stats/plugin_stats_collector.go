@@ -0,0 +1,203 @@
+package stats
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	// Plugins are executables in jobs/<job>/bin/vitals/
+	pluginsDirName = "vitals"
+
+	// Limits size of heartbeats when a plugin reports too much
+	pluginMaxVitals = 50
+
+	pluginKillGracePeriod = 5 * time.Second
+)
+
+var pluginVitalNameRegexp = regexp.MustCompile(`\A[a-z0-9_]+\z`)
+
+type pluginStatsCollector struct {
+	Collector
+
+	runner  boshsys.CmdRunner
+	fs      boshsys.FileSystem
+	clock   clock.Clock
+	jobsDir string
+	timeout time.Duration
+	logger  boshlog.Logger
+	logTag  string
+
+	latestPlugins     map[string]map[string]string
+	latestPluginsLock sync.RWMutex
+}
+
+// NewPluginStatsCollector adds vitals reported by job provided plugins, run at
+// collection interval, to stats of given collector; other stats are delegated.
+// Plugins print a flat JSON object of string, number or boolean values to stdout.
+func NewPluginStatsCollector(
+	collector Collector,
+	runner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	clock clock.Clock,
+	jobsDir string,
+	timeout time.Duration,
+	logger boshlog.Logger,
+) Collector {
+	return &pluginStatsCollector{
+		Collector:     collector,
+		runner:        runner,
+		fs:            fs,
+		clock:         clock,
+		jobsDir:       jobsDir,
+		timeout:       timeout,
+		logger:        logger,
+		logTag:        "pluginStatsCollector",
+		latestPlugins: map[string]map[string]string{},
+	}
+}
+
+func (c *pluginStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	go c.pollPlugins(collectionInterval)
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+func (c *pluginStatsCollector) GetPluginStats() (map[string]map[string]string, error) {
+	c.latestPluginsLock.RLock()
+	defer c.latestPluginsLock.RUnlock()
+
+	stats := make(map[string]map[string]string, len(c.latestPlugins))
+	for jobName, jobStats := range c.latestPlugins {
+		stats[jobName] = jobStats
+	}
+
+	return stats, nil
+}
+
+func (c *pluginStatsCollector) pollPlugins(interval time.Duration) {
+	for {
+		stats := c.collectPlugins()
+
+		c.latestPluginsLock.Lock()
+		c.latestPlugins = stats
+		c.latestPluginsLock.Unlock()
+
+		c.clock.Sleep(interval)
+	}
+}
+
+// collectPlugins merges vitals of plugins of the same job;
+// plugins run in name order so later plugins win on conflicts
+func (c *pluginStatsCollector) collectPlugins() map[string]map[string]string {
+	stats := map[string]map[string]string{}
+
+	pluginPaths, err := c.fs.Glob(filepath.Join(c.jobsDir, "*", "bin", pluginsDirName, "*"))
+	if err != nil {
+		c.logger.Warn(c.logTag, "Finding vitals plugins: %s", err.Error())
+		return stats
+	}
+
+	sort.Strings(pluginPaths)
+
+	for _, pluginPath := range pluginPaths {
+		jobName := filepath.Base(filepath.Dir(filepath.Dir(filepath.Dir(pluginPath))))
+
+		pluginStats, err := c.runPlugin(pluginPath)
+		if err != nil {
+			c.logger.Warn(c.logTag, "Running vitals plugin '%s': %s", pluginPath, err.Error())
+			continue
+		}
+
+		if len(pluginStats) == 0 {
+			continue
+		}
+
+		if stats[jobName] == nil {
+			stats[jobName] = map[string]string{}
+		}
+
+		for name, value := range pluginStats {
+			stats[jobName][name] = value
+		}
+	}
+
+	return stats
+}
+
+func (c *pluginStatsCollector) runPlugin(pluginPath string) (map[string]string, error) {
+	process, err := c.runner.RunComplexCommandAsync(boshsys.Command{Name: pluginPath})
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Starting plugin")
+	}
+
+	var result boshsys.Result
+
+	resultCh := process.Wait()
+
+	timer := c.clock.NewTimer(c.timeout)
+	defer timer.Stop()
+
+	select {
+	case result = <-resultCh:
+	case <-timer.C():
+		err = process.TerminateNicely(pluginKillGracePeriod)
+		if err != nil {
+			c.logger.Warn(c.logTag, "Terminating vitals plugin '%s': %s", pluginPath, err.Error())
+		}
+		<-resultCh
+		return nil, bosherr.Errorf("Timed out after %s", c.timeout)
+	}
+
+	if result.Error != nil {
+		return nil, bosherr.WrapError(result.Error, "Running plugin")
+	}
+
+	return parsePluginOutput(result.Stdout)
+}
+
+func parsePluginOutput(stdout string) (map[string]string, error) {
+	var output map[string]interface{}
+
+	err := json.Unmarshal([]byte(stdout), &output)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling plugin output")
+	}
+
+	if len(output) > pluginMaxVitals {
+		return nil, bosherr.Errorf("Reporting %d vitals exceeds limit of %d", len(output), pluginMaxVitals)
+	}
+
+	stats := make(map[string]string, len(output))
+
+	for name, value := range output {
+		if !pluginVitalNameRegexp.MatchString(name) {
+			return nil, bosherr.Errorf("Vital name '%s' must only contain lowercase letters, digits and underscores", name)
+		}
+
+		switch typedValue := value.(type) {
+		case string:
+			stats[name] = strings.TrimSpace(typedValue)
+		case float64:
+			stats[name] = strconv.FormatFloat(typedValue, 'f', -1, 64)
+		case bool:
+			stats[name] = strconv.FormatBool(typedValue)
+		default:
+			return nil, bosherr.Errorf("Vital '%s' must be a string, number or boolean", name)
+		}
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,138 @@
+package stats_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("pluginStatsCollector", func() {
+	const pluginsGlob = "/var/vcap/jobs/*/bin/vitals/*"
+
+	var (
+		innerCollector *fakestats.FakeCollector
+		runner         *fakesys.FakeCmdRunner
+		fs             *fakesys.FakeFileSystem
+		clock          *fakeclock.FakeClock
+		collector      Collector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		runner = fakesys.NewFakeCmdRunner()
+		fs = fakesys.NewFakeFileSystem()
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewPluginStatsCollector(innerCollector, runner, fs, clock, "/var/vcap/jobs", 5*time.Second, logger)
+	})
+
+	addPlugin := func(path string, result boshsys.Result) {
+		runner.AddProcess(path, &fakesys.FakeProcess{WaitResult: result})
+	}
+
+	getPluginStats := func() map[string]map[string]string {
+		stats, _ := collector.GetPluginStats()
+		return stats
+	}
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	It("returns no jobs before collecting", func() {
+		stats, err := collector.GetPluginStats()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(stats).To(BeEmpty())
+	})
+
+	Describe("StartCollecting", func() {
+		It("merges vitals of plugins of each job", func() {
+			fs.SetGlob(pluginsGlob, []string{
+				"/var/vcap/jobs/redis/bin/vitals/replication",
+				"/var/vcap/jobs/redis/bin/vitals/clients",
+				"/var/vcap/jobs/nginx/bin/vitals/connections",
+			})
+			addPlugin("/var/vcap/jobs/redis/bin/vitals/clients", boshsys.Result{
+				Stdout: `{"connected_clients": 12, "role": "master"}`,
+			})
+			addPlugin("/var/vcap/jobs/redis/bin/vitals/replication", boshsys.Result{
+				Stdout: `{"role": "replica", "in_sync": true}`,
+			})
+			addPlugin("/var/vcap/jobs/nginx/bin/vitals/connections", boshsys.Result{
+				Stdout: `{"active": 1500000, "reading_ratio": 0.25}`,
+			})
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			Eventually(getPluginStats).Should(Equal(map[string]map[string]string{
+				"redis": {"connected_clients": "12", "role": "replica", "in_sync": "true"},
+				"nginx": {"active": "1500000", "reading_ratio": "0.25"},
+			}))
+		})
+
+		It("skips plugins that fail or print invalid output", func() {
+			fs.SetGlob(pluginsGlob, []string{
+				"/var/vcap/jobs/fake-job/bin/vitals/failing",
+				"/var/vcap/jobs/fake-job/bin/vitals/nested",
+				"/var/vcap/jobs/fake-job/bin/vitals/invalid-name",
+				"/var/vcap/jobs/fake-job/bin/vitals/not-json",
+				"/var/vcap/jobs/fake-job/bin/vitals/valid",
+			})
+			addPlugin("/var/vcap/jobs/fake-job/bin/vitals/failing", boshsys.Result{
+				Stdout: `{"failing": 1}`,
+				Error:  errors.New("fake-plugin-err"),
+			})
+			addPlugin("/var/vcap/jobs/fake-job/bin/vitals/nested", boshsys.Result{
+				Stdout: `{"nested": {"value": 1}}`,
+			})
+			addPlugin("/var/vcap/jobs/fake-job/bin/vitals/invalid-name", boshsys.Result{
+				Stdout: `{"Invalid Name": 1}`,
+			})
+			addPlugin("/var/vcap/jobs/fake-job/bin/vitals/not-json", boshsys.Result{
+				Stdout: `OK`,
+			})
+			addPlugin("/var/vcap/jobs/fake-job/bin/vitals/valid", boshsys.Result{
+				Stdout: `{"valid": 1}`,
+			})
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			Eventually(getPluginStats).Should(Equal(map[string]map[string]string{
+				"fake-job": {"valid": "1"},
+			}))
+		})
+
+		It("terminates plugins that time out", func() {
+			fs.SetGlob(pluginsGlob, []string{"/var/vcap/jobs/fake-job/bin/vitals/slow"})
+
+			process := &fakesys.FakeProcess{
+				TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
+					p.WaitCh <- boshsys.Result{Stdout: `{"slow": 1}`}
+				},
+			}
+			runner.AddProcess("/var/vcap/jobs/fake-job/bin/vitals/slow", process)
+
+			collector.StartCollecting(10*time.Second, nil)
+
+			Eventually(clock.WatcherCount).Should(Equal(1))
+			clock.Increment(5 * time.Second)
+
+			Eventually(func() bool { return process.TerminatedNicely }).Should(BeTrue())
+			Consistently(getPluginStats).Should(BeEmpty())
+		})
+	})
+})
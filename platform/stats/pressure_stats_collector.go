@@ -0,0 +1,244 @@
+package stats
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	pressureDir     = "/proc/pressure"
+	vmStatPath      = "/proc/vmstat"
+	swapInPagesKey  = "pswpin"
+	swapOutPagesKey = "pswpout"
+)
+
+var pressureResources = []string{"cpu", "memory", "io"}
+
+type swapIOSample struct {
+	takenAt  time.Time
+	inPages  uint64
+	outPages uint64
+}
+
+type pressureStatsCollector struct {
+	Collector
+
+	fs       boshsys.FileSystem
+	clock    clock.Clock
+	logger   boshlog.Logger
+	logTag   string
+	pageSize uint64
+
+	latestSwapIO     *SwapIOStats
+	latestSwapIOLock sync.RWMutex
+}
+
+// NewPressureStatsCollector adds pressure stall information read from /proc/pressure
+// and swap rates sampled from /proc/vmstat at collection interval to stats of given collector;
+// other stats are delegated
+func NewPressureStatsCollector(
+	collector Collector,
+	fs boshsys.FileSystem,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) Collector {
+	return &pressureStatsCollector{
+		Collector: collector,
+		fs:        fs,
+		clock:     clock,
+		logger:    logger,
+		logTag:    "pressureStatsCollector",
+		pageSize:  uint64(os.Getpagesize()),
+	}
+}
+
+func (c *pressureStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	if c.fs.FileExists(vmStatPath) {
+		go c.pollSwapIO(collectionInterval)
+	} else {
+		c.logger.Debug(c.logTag, "%s does not exist, swap I/O is not going to be collected", vmStatPath)
+	}
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+func (c *pressureStatsCollector) GetSwapIOStats() (SwapIOStats, error) {
+	c.latestSwapIOLock.RLock()
+	defer c.latestSwapIOLock.RUnlock()
+
+	if c.latestSwapIO == nil {
+		return SwapIOStats{}, bosherr.Error("Swap I/O was not collected")
+	}
+
+	return *c.latestSwapIO, nil
+}
+
+// GetPressureStats skips resources the kernel does not report,
+// e.g. when it was built without CONFIG_PSI or booted with psi=0
+func (c *pressureStatsCollector) GetPressureStats() (map[string]Pressure, error) {
+	stats := map[string]Pressure{}
+
+	for _, resource := range pressureResources {
+		path := filepath.Join(pressureDir, resource)
+
+		contents, err := c.fs.ReadFileString(path)
+		if err != nil {
+			continue
+		}
+
+		pressure, err := parsePressure(contents)
+		if err != nil {
+			c.logger.Warn(c.logTag, "Parsing %s: %s", path, err.Error())
+			continue
+		}
+
+		stats[resource] = pressure
+	}
+
+	return stats, nil
+}
+
+func (c *pressureStatsCollector) pollSwapIO(interval time.Duration) {
+	previous, err := c.sampleSwapIO()
+	if err != nil {
+		c.logger.Warn(c.logTag, "Sampling swap I/O: %s", err.Error())
+	}
+
+	for {
+		c.clock.Sleep(interval)
+
+		current, err := c.sampleSwapIO()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Sampling swap I/O: %s", err.Error())
+			continue
+		}
+
+		elapsedSecs := current.takenAt.Sub(previous.takenAt).Seconds()
+
+		if !previous.takenAt.IsZero() && elapsedSecs > 0 {
+			stats := SwapIOStats{
+				InBytesPerSec:  counterDelta(previous.inPages, current.inPages) * float64(c.pageSize) / elapsedSecs,
+				OutBytesPerSec: counterDelta(previous.outPages, current.outPages) * float64(c.pageSize) / elapsedSecs,
+			}
+
+			c.latestSwapIOLock.Lock()
+			c.latestSwapIO = &stats
+			c.latestSwapIOLock.Unlock()
+		}
+
+		previous = current
+	}
+}
+
+func (c *pressureStatsCollector) sampleSwapIO() (swapIOSample, error) {
+	contents, err := c.fs.ReadFileString(vmStatPath)
+	if err != nil {
+		return swapIOSample{}, bosherr.WrapErrorf(err, "Reading %s", vmStatPath)
+	}
+
+	sample := swapIOSample{takenAt: c.clock.Now()}
+
+	var inFound, outFound bool
+
+	for _, line := range strings.Split(contents, "\n") {
+		// e.g. "pswpin 1234"
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			continue
+		}
+
+		switch fields[0] {
+		case swapInPagesKey:
+			sample.inPages, err = strconv.ParseUint(fields[1], 10, 64)
+			inFound = true
+		case swapOutPagesKey:
+			sample.outPages, err = strconv.ParseUint(fields[1], 10, 64)
+			outFound = true
+		default:
+			continue
+		}
+
+		if err != nil {
+			return swapIOSample{}, bosherr.WrapErrorf(err, "Parsing counter '%s'", fields[0])
+		}
+	}
+
+	if !inFound || !outFound {
+		return swapIOSample{}, bosherr.Errorf("Finding swap counters in %s", vmStatPath)
+	}
+
+	return sample, nil
+}
+
+// parsePressure parses lines like "some avg10=0.12 avg60=0.05 avg300=0.01 total=12345"
+func parsePressure(contents string) (Pressure, error) {
+	var pressure Pressure
+	var someFound bool
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		averages, err := parsePressureAverages(fields[1:])
+		if err != nil {
+			return Pressure{}, bosherr.WrapErrorf(err, "Parsing '%s' line", fields[0])
+		}
+
+		switch fields[0] {
+		case "some":
+			pressure.Some = averages
+			someFound = true
+		case "full":
+			pressure.Full = &averages
+		}
+	}
+
+	if !someFound {
+		return Pressure{}, bosherr.Error("Finding 'some' line")
+	}
+
+	return pressure, nil
+}
+
+func parsePressureAverages(fields []string) (PressureAverages, error) {
+	var averages PressureAverages
+
+	values := map[string]*float64{
+		"avg10":  &averages.Avg10,
+		"avg60":  &averages.Avg60,
+		"avg300": &averages.Avg300,
+	}
+
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		value, found := values[parts[0]]
+		if !found {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil {
+			return PressureAverages{}, bosherr.WrapErrorf(err, "Parsing '%s'", field)
+		}
+
+		*value = parsed
+	}
+
+	return averages, nil
+}
@@ -0,0 +1,133 @@
+package stats_test
+
+import (
+	"os"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("pressureStatsCollector", func() {
+	var (
+		innerCollector *fakestats.FakeCollector
+		fs             *fakesys.FakeFileSystem
+		clock          *fakeclock.FakeClock
+		collector      Collector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		fs = fakesys.NewFakeFileSystem()
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		collector = NewPressureStatsCollector(innerCollector, fs, clock, logger)
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	Describe("GetPressureStats", func() {
+		It("returns some and full averages of each resource", func() {
+			fs.WriteFileString("/proc/pressure/cpu", `some avg10=1.50 avg60=0.75 avg300=0.10 total=123456
+`)
+			fs.WriteFileString("/proc/pressure/memory", `some avg10=30.25 avg60=10.00 avg300=2.00 total=987654
+full avg10=12.50 avg60=4.00 avg300=1.00 total=456789
+`)
+			fs.WriteFileString("/proc/pressure/io", `some avg10=0.00 avg60=0.00 avg300=0.00 total=0
+full avg10=0.00 avg60=0.00 avg300=0.00 total=0
+`)
+
+			stats, err := collector.GetPressureStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(map[string]Pressure{
+				"cpu": Pressure{
+					Some: PressureAverages{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1},
+				},
+				"memory": Pressure{
+					Some: PressureAverages{Avg10: 30.25, Avg60: 10, Avg300: 2},
+					Full: &PressureAverages{Avg10: 12.5, Avg60: 4, Avg300: 1},
+				},
+				"io": Pressure{
+					Some: PressureAverages{},
+					Full: &PressureAverages{},
+				},
+			}))
+		})
+
+		It("skips resources that cannot be read or parsed", func() {
+			fs.WriteFileString("/proc/pressure/memory", `some avg10=fake avg60=0.00 avg300=0.00 total=0
+`)
+			fs.WriteFileString("/proc/pressure/io", `some avg10=2.00 avg60=1.00 avg300=0.50 total=100
+`)
+
+			stats, err := collector.GetPressureStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(map[string]Pressure{
+				"io": Pressure{
+					Some: PressureAverages{Avg10: 2, Avg60: 1, Avg300: 0.5},
+				},
+			}))
+		})
+
+		It("returns no resources when kernel does not report pressure stall information", func() {
+			stats, err := collector.GetPressureStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(BeEmpty())
+		})
+	})
+
+	Describe("GetSwapIOStats", func() {
+		It("returns an error before collecting", func() {
+			_, err := collector.GetSwapIOStats()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns swap rates over collection interval", func() {
+			pageSize := float64(os.Getpagesize())
+
+			fs.WriteFileString("/proc/vmstat", `nr_free_pages 12345
+pswpin 100
+pswpout 200
+`)
+
+			collector.StartCollecting(10*time.Second, nil)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			fs.WriteFileString("/proc/vmstat", `nr_free_pages 12000
+pswpin 150
+pswpout 1200
+`)
+			clock.Increment(10 * time.Second)
+
+			Eventually(func() SwapIOStats {
+				stats, _ := collector.GetSwapIOStats()
+				return stats
+			}).Should(Equal(SwapIOStats{
+				InBytesPerSec:  5 * pageSize,
+				OutBytesPerSec: 100 * pageSize,
+			}))
+		})
+
+		It("does not collect when /proc/vmstat does not exist", func() {
+			collector.StartCollecting(10*time.Second, nil)
+
+			Consistently(clock.WatcherCount).Should(Equal(0))
+
+			_, err := collector.GetSwapIOStats()
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
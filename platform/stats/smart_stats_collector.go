@@ -0,0 +1,234 @@
+package stats
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	"github.com/pivotal-golang/clock"
+)
+
+const (
+	smartReallocatedSectorCountID = 5
+
+	// smartctl exit status bits for invalid command line and failure to open device;
+	// other bits describe disk problems while output is still valid
+	smartctlFatalExitStatusMask = 0x3
+
+	smartWearWarningPercentage = 90
+)
+
+type SMARTStatsCollector interface {
+	Collector
+
+	// Stop stops polling disk health
+	Stop()
+}
+
+type smartStatsCollector struct {
+	Collector
+
+	runner       boshsys.CmdRunner
+	pollInterval time.Duration
+	clock        clock.Clock
+	logger       boshlog.Logger
+	logTag       string
+
+	latestDiskHealth     map[string]DiskHealth
+	latestDiskHealthLock sync.RWMutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+// NewSMARTStatsCollector adds disk health polled with smartctl (7.0+ for
+// JSON output) to stats of given collector; other stats are delegated
+func NewSMARTStatsCollector(
+	collector Collector,
+	runner boshsys.CmdRunner,
+	pollInterval time.Duration,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) SMARTStatsCollector {
+	return &smartStatsCollector{
+		Collector:        collector,
+		runner:           runner,
+		pollInterval:     pollInterval,
+		clock:            clock,
+		logger:           logger,
+		logTag:           "smartStatsCollector",
+		latestDiskHealth: map[string]DiskHealth{},
+		stopCh:           make(chan struct{}),
+	}
+}
+
+func (c *smartStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	if c.runner.CommandExists("smartctl") {
+		go c.pollDiskHealth()
+	} else {
+		c.logger.Debug(c.logTag, "smartctl is not installed, disk health is not going to be collected")
+	}
+
+	c.Collector.StartCollecting(collectionInterval, latestGotUpdated)
+}
+
+func (c *smartStatsCollector) Stop() {
+	c.stopOnce.Do(func() { close(c.stopCh) })
+}
+
+func (c *smartStatsCollector) GetDiskHealth() (map[string]DiskHealth, error) {
+	c.latestDiskHealthLock.RLock()
+	defer c.latestDiskHealthLock.RUnlock()
+
+	health := make(map[string]DiskHealth, len(c.latestDiskHealth))
+	for device, deviceHealth := range c.latestDiskHealth {
+		health[device] = deviceHealth
+	}
+
+	return health, nil
+}
+
+func (c *smartStatsCollector) pollDiskHealth() {
+	for {
+		health, err := c.collectDiskHealth()
+		if err != nil {
+			c.logger.Warn(c.logTag, "Collecting disk health: %s", err.Error())
+		} else {
+			c.latestDiskHealthLock.Lock()
+			c.warnAboutDegradedDisks(c.latestDiskHealth, health)
+			c.latestDiskHealth = health
+			c.latestDiskHealthLock.Unlock()
+		}
+
+		timer := c.clock.NewTimer(c.pollInterval)
+
+		select {
+		case <-c.stopCh:
+			timer.Stop()
+			return
+		case <-timer.C():
+		}
+	}
+}
+
+type smartctlScan struct {
+	Devices []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	} `json:"devices"`
+}
+
+type smartctlReport struct {
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+
+	ATASmartAttributes *struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value uint64 `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+
+	NVMeSmartHealthInformationLog *struct {
+		MediaErrors    uint64 `json:"media_errors"`
+		PercentageUsed uint64 `json:"percentage_used"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+func (c *smartStatsCollector) collectDiskHealth() (map[string]DiskHealth, error) {
+	stdout, _, _, err := c.runner.RunCommand("smartctl", "--scan", "-j")
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Scanning for SMART devices")
+	}
+
+	var scan smartctlScan
+
+	err = json.Unmarshal([]byte(stdout), &scan)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling smartctl scan")
+	}
+
+	health := map[string]DiskHealth{}
+
+	for _, device := range scan.Devices {
+		args := []string{"-j", "-H", "-A", device.Name}
+		if device.Type != "" {
+			args = append([]string{"-d", device.Type}, args...)
+		}
+
+		stdout, _, exitStatus, _ := c.runner.RunCommand("smartctl", args...)
+		if exitStatus&smartctlFatalExitStatusMask != 0 {
+			c.logger.Debug(c.logTag, "Skipping device %s, smartctl exited with %d", device.Name, exitStatus)
+			continue
+		}
+
+		var report smartctlReport
+
+		err = json.Unmarshal([]byte(stdout), &report)
+		if err != nil {
+			c.logger.Debug(c.logTag, "Skipping device %s: %s", device.Name, err.Error())
+			continue
+		}
+
+		// Devices that do not support SMART do not report status
+		if report.SmartStatus == nil {
+			continue
+		}
+
+		deviceHealth := DiskHealth{Healthy: report.SmartStatus.Passed}
+
+		if report.ATASmartAttributes != nil {
+			for _, attribute := range report.ATASmartAttributes.Table {
+				if attribute.ID == smartReallocatedSectorCountID {
+					value := attribute.Raw.Value
+					deviceHealth.ReallocatedSectors = &value
+				}
+			}
+		}
+
+		if log := report.NVMeSmartHealthInformationLog; log != nil {
+			mediaErrors, percentageUsed := log.MediaErrors, log.PercentageUsed
+			deviceHealth.MediaErrors = &mediaErrors
+			deviceHealth.PercentageUsed = &percentageUsed
+		}
+
+		health[device.Name] = deviceHealth
+	}
+
+	return health, nil
+}
+
+func (c *smartStatsCollector) warnAboutDegradedDisks(previous, current map[string]DiskHealth) {
+	increased := func(previousValue, currentValue *uint64) bool {
+		if currentValue == nil || *currentValue == 0 {
+			return false
+		}
+		return previousValue == nil || *currentValue > *previousValue
+	}
+
+	for device, health := range current {
+		previousHealth := previous[device]
+
+		if !health.Healthy {
+			c.logger.Warn(c.logTag, "Disk %s failed SMART health check", device)
+		}
+
+		if increased(previousHealth.ReallocatedSectors, health.ReallocatedSectors) {
+			c.logger.Warn(c.logTag, "Disk %s has %d reallocated sectors", device, *health.ReallocatedSectors)
+		}
+
+		if increased(previousHealth.MediaErrors, health.MediaErrors) {
+			c.logger.Warn(c.logTag, "Disk %s has %d media errors", device, *health.MediaErrors)
+		}
+
+		if health.PercentageUsed != nil && *health.PercentageUsed >= smartWearWarningPercentage {
+			c.logger.Warn(c.logTag, "Disk %s has used %d%% of its endurance", device, *health.PercentageUsed)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package stats_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("smartStatsCollector", func() {
+	var (
+		innerCollector *fakestats.FakeCollector
+		runner         *fakesys.FakeCmdRunner
+		clock          *fakeclock.FakeClock
+		collector      SMARTStatsCollector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		runner = fakesys.NewFakeCmdRunner()
+		runner.CommandExistsValue = true
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		clock = fakeclock.NewFakeClock(time.Now())
+		collector = NewSMARTStatsCollector(innerCollector, runner, 1*time.Hour, clock, logger)
+	})
+
+	AfterEach(func() {
+		collector.Stop()
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	It("returns no disk health before collecting", func() {
+		health, err := collector.GetDiskHealth()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(health).To(BeEmpty())
+	})
+
+	Describe("StartCollecting", func() {
+		BeforeEach(func() {
+			runner.AddCmdResult("smartctl --scan -j", fakesys.FakeCmdResult{
+				Stdout: `{"devices": [
+					{"name": "/dev/sda", "type": "sat"},
+					{"name": "/dev/nvme0", "type": "nvme"},
+					{"name": "/dev/sdb", "type": "scsi"},
+					{"name": "/dev/sdc", "type": "scsi"}
+				]}`,
+			})
+
+			runner.AddCmdResult("smartctl -d sat -j -H -A /dev/sda", fakesys.FakeCmdResult{
+				Stdout: `{
+					"smart_status": {"passed": false},
+					"ata_smart_attributes": {"table": [
+						{"id": 1, "raw": {"value": 100}},
+						{"id": 5, "raw": {"value": 8}}
+					]}
+				}`,
+				ExitStatus: 8,
+			})
+
+			runner.AddCmdResult("smartctl -d nvme -j -H -A /dev/nvme0", fakesys.FakeCmdResult{
+				Stdout: `{
+					"smart_status": {"passed": true},
+					"nvme_smart_health_information_log": {"media_errors": 0, "percentage_used": 12}
+				}`,
+			})
+
+			// Virtual disk without SMART support
+			runner.AddCmdResult("smartctl -d scsi -j -H -A /dev/sdb", fakesys.FakeCmdResult{
+				Stdout:     `{}`,
+				ExitStatus: 4,
+			})
+
+			runner.AddCmdResult("smartctl -d scsi -j -H -A /dev/sdc", fakesys.FakeCmdResult{
+				Stdout:     `{}`,
+				ExitStatus: 2,
+			})
+		})
+
+		It("collects disk health of devices that support SMART", func() {
+			collector.StartCollecting(1*time.Millisecond, nil)
+
+			Eventually(func() map[string]DiskHealth {
+				health, _ := collector.GetDiskHealth()
+				return health
+			}).Should(HaveLen(2))
+
+			health, err := collector.GetDiskHealth()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(health["/dev/sda"].Healthy).To(BeFalse())
+			Expect(*health["/dev/sda"].ReallocatedSectors).To(Equal(uint64(8)))
+			Expect(health["/dev/sda"].MediaErrors).To(BeNil())
+
+			Expect(health["/dev/nvme0"].Healthy).To(BeTrue())
+			Expect(health["/dev/nvme0"].ReallocatedSectors).To(BeNil())
+			Expect(*health["/dev/nvme0"].MediaErrors).To(Equal(uint64(0)))
+			Expect(*health["/dev/nvme0"].PercentageUsed).To(Equal(uint64(12)))
+		})
+
+		It("polls disk health again after poll interval", func() {
+			collector.StartCollecting(1*time.Millisecond, nil)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			runner.AddCmdResult("smartctl --scan -j", fakesys.FakeCmdResult{
+				Stdout: `{"devices": [{"name": "/dev/sda", "type": "sat"}]}`,
+			})
+			runner.AddCmdResult("smartctl -d sat -j -H -A /dev/sda", fakesys.FakeCmdResult{
+				Stdout: `{"smart_status": {"passed": true}}`,
+			})
+
+			clock.Increment(1 * time.Hour)
+
+			Eventually(func() bool {
+				health, _ := collector.GetDiskHealth()
+				return health["/dev/sda"].Healthy
+			}).Should(BeTrue())
+		})
+
+		It("stops polling disk health when stopped", func() {
+			collector.StartCollecting(1*time.Millisecond, nil)
+			Eventually(clock.WatcherCount).Should(Equal(1))
+
+			collector.Stop()
+			Eventually(clock.WatcherCount).Should(Equal(0))
+
+			commandCount := len(runner.RunCommands)
+			clock.Increment(1 * time.Hour)
+			Consistently(func() int { return len(runner.RunCommands) }, 50*time.Millisecond).Should(Equal(commandCount))
+		})
+
+		It("does not collect disk health when smartctl is not installed", func() {
+			runner.CommandExistsValue = false
+
+			collector.StartCollecting(1*time.Millisecond, nil)
+
+			Consistently(func() map[string]DiskHealth {
+				health, _ := collector.GetDiskHealth()
+				return health
+			}, 50*time.Millisecond).Should(BeEmpty())
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+})
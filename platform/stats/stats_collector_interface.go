@@ -22,10 +22,102 @@ type Usage struct {
 }
 
 type DiskStats struct {
+	// in kilobytes
 	DiskUsage  Usage
 	InodeUsage Usage
 }
 
+// DiskHealth is SMART health of a block device;
+// counters not reported by the device are nil
+type DiskHealth struct {
+	Healthy            bool
+	ReallocatedSectors *uint64
+	MediaErrors        *uint64
+
+	// NVMe estimate of used endurance, may exceed 100
+	PercentageUsed *uint64
+}
+
+// DiskIOStats are averages over latest collection interval
+type DiskIOStats struct {
+	ReadIOPS         float64
+	WriteIOPS        float64
+	ReadBytesPerSec  float64
+	WriteBytesPerSec float64
+
+	// Average number of requests in flight
+	QueueDepth float64
+
+	// Average time requests were queued and serviced
+	AwaitMs float64
+}
+
+// NetworkStats are averages over latest collection interval
+type NetworkStats struct {
+	RxBytesPerSec   float64
+	RxPacketsPerSec float64
+	RxErrorsPerSec  float64
+	RxDropsPerSec   float64
+	TxBytesPerSec   float64
+	TxPacketsPerSec float64
+	TxErrorsPerSec  float64
+	TxDropsPerSec   float64
+}
+
+// SwapIOStats are averages over latest collection interval
+type SwapIOStats struct {
+	InBytesPerSec  float64
+	OutBytesPerSec float64
+}
+
+// PressureAverages are percentages of time tasks stalled over last 10s, 60s and 300s
+type PressureAverages struct {
+	Avg10  float64
+	Avg60  float64
+	Avg300 float64
+}
+
+// Pressure is Linux pressure stall information of a resource;
+// Full is nil when kernel does not report it, e.g. for cpu before 5.13
+type Pressure struct {
+	// Some tasks stalled
+	Some PressureAverages
+
+	// All non-idle tasks stalled
+	Full *PressureAverages
+}
+
+// GPUStats are latest readings of a GPU;
+// readings not supported by the GPU are nil
+type GPUStats struct {
+	Name               string
+	UtilizationPercent *uint64
+
+	// in bytes
+	MemoryUsage *Usage
+
+	TemperatureCelsius *uint64
+}
+
+// SystemLimitsStats are usages of kernel resources that fail jobs when exhausted;
+// resources the kernel does not report are nil
+type SystemLimitsStats struct {
+	// Bits of entropy in kernel pool
+	EntropyAvailable *uint64
+
+	// Tracked connections of maximum
+	Conntrack *Usage
+
+	// Open file handles of system-wide maximum
+	FileDescriptors *Usage
+}
+
+// DNSCacheStats are counters of the agent managed dns cache since it started
+type DNSCacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
 type Collector interface {
 	StartCollecting(time.Duration, chan struct{})
 
@@ -35,6 +127,33 @@ type Collector interface {
 	GetMemStats() (usage Usage, err error)
 	GetSwapStats() (usage Usage, err error)
 	GetDiskStats(mountedPath string) (stats DiskStats, err error)
+
+	// GetDiskIOStats returns an error when I/O of device mounted at path was not collected
+	GetDiskIOStats(mountedPath string) (stats DiskIOStats, err error)
+
+	// GetNetworkStats returns latest rates keyed by interface name
+	GetNetworkStats() (stats map[string]NetworkStats, err error)
+
+	// GetSwapIOStats returns an error when swap rates were not collected
+	GetSwapIOStats() (stats SwapIOStats, err error)
+
+	// GetPressureStats returns pressure stall information keyed by resource, i.e. cpu, memory and io
+	GetPressureStats() (stats map[string]Pressure, err error)
+
+	// GetDiskHealth returns latest disk health keyed by device path
+	GetDiskHealth() (health map[string]DiskHealth, err error)
+
+	// GetGPUStats returns latest readings keyed by GPU index
+	GetGPUStats() (stats map[string]GPUStats, err error)
+
+	// GetPluginStats returns latest vitals reported by job provided plugins keyed by job name
+	GetPluginStats() (stats map[string]map[string]string, err error)
+
+	// GetSystemLimitsStats returns current usage of kernel resources
+	GetSystemLimitsStats() (stats SystemLimitsStats, err error)
+
+	// GetDNSCacheStats returns an error when dns cache is not running
+	GetDNSCacheStats() (stats DNSCacheStats, err error)
 }
 
 func (cpuStats CPUStats) UserPercent() Percentage {
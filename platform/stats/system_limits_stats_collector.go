@@ -0,0 +1,97 @@
+package stats
+
+import (
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	entropyAvailPath   = "/proc/sys/kernel/random/entropy_avail"
+	conntrackCountPath = "/proc/sys/net/netfilter/nf_conntrack_count"
+	conntrackMaxPath   = "/proc/sys/net/netfilter/nf_conntrack_max"
+
+	// Allocated, unused (always 0 since 2.6) and maximum file handles
+	fileNrPath = "/proc/sys/fs/file-nr"
+)
+
+type systemLimitsStatsCollector struct {
+	Collector
+
+	fs boshsys.FileSystem
+}
+
+// NewSystemLimitsStatsCollector adds usage of kernel resources read from /proc
+// to stats of given collector; other stats are delegated
+func NewSystemLimitsStatsCollector(collector Collector, fs boshsys.FileSystem) Collector {
+	return &systemLimitsStatsCollector{
+		Collector: collector,
+		fs:        fs,
+	}
+}
+
+// GetSystemLimitsStats leaves out resources that cannot be read,
+// e.g. conntrack when nf_conntrack module is not loaded
+func (c *systemLimitsStatsCollector) GetSystemLimitsStats() (SystemLimitsStats, error) {
+	var stats SystemLimitsStats
+
+	if entropyAvail, err := c.readValue(entropyAvailPath); err == nil {
+		stats.EntropyAvailable = &entropyAvail
+	}
+
+	conntrackCount, countErr := c.readValue(conntrackCountPath)
+	conntrackMax, maxErr := c.readValue(conntrackMaxPath)
+	if countErr == nil && maxErr == nil {
+		stats.Conntrack = &Usage{Used: conntrackCount, Total: conntrackMax}
+	}
+
+	if fileDescriptors, err := c.readFileDescriptors(); err == nil {
+		stats.FileDescriptors = &fileDescriptors
+	}
+
+	return stats, nil
+}
+
+func (c *systemLimitsStatsCollector) readFileDescriptors() (Usage, error) {
+	contents, err := c.fs.ReadFileString(fileNrPath)
+	if err != nil {
+		return Usage{}, bosherr.WrapErrorf(err, "Reading %s", fileNrPath)
+	}
+
+	// e.g. "1184	0	9223372036854775807"
+	fields := strings.Fields(contents)
+	if len(fields) != 3 {
+		return Usage{}, bosherr.Errorf("Parsing %s", fileNrPath)
+	}
+
+	values := make([]uint64, 3)
+	for i := range values {
+		values[i], err = strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return Usage{}, bosherr.WrapErrorf(err, "Parsing %s", fileNrPath)
+		}
+	}
+
+	used := values[0]
+	if values[1] < used {
+		used -= values[1]
+	}
+
+	return Usage{Used: used, Total: values[2]}, nil
+}
+
+func (c *systemLimitsStatsCollector) readValue(path string) (uint64, error) {
+	contents, err := c.fs.ReadFileString(path)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Reading %s", path)
+	}
+
+	value, err := strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Parsing %s", path)
+	}
+
+	return value, nil
+}
@@ -0,0 +1,61 @@
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("systemLimitsStatsCollector", func() {
+	var (
+		innerCollector *fakestats.FakeCollector
+		fs             *fakesys.FakeFileSystem
+		collector      Collector
+	)
+
+	BeforeEach(func() {
+		innerCollector = &fakestats.FakeCollector{
+			CPULoad: CPULoad{One: 0.5},
+		}
+		fs = fakesys.NewFakeFileSystem()
+		collector = NewSystemLimitsStatsCollector(innerCollector, fs)
+	})
+
+	It("delegates other stats to the wrapped collector", func() {
+		load, err := collector.GetCPULoad()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(load.One).To(Equal(0.5))
+	})
+
+	Describe("GetSystemLimitsStats", func() {
+		It("returns entropy, conntrack and file descriptor usage", func() {
+			fs.WriteFileString("/proc/sys/kernel/random/entropy_avail", "256\n")
+			fs.WriteFileString("/proc/sys/net/netfilter/nf_conntrack_count", "60000\n")
+			fs.WriteFileString("/proc/sys/net/netfilter/nf_conntrack_max", "65536\n")
+			fs.WriteFileString("/proc/sys/fs/file-nr", "1184\t0\t9223372036854775807\n")
+
+			stats, err := collector.GetSystemLimitsStats()
+			Expect(err).ToNot(HaveOccurred())
+
+			entropyAvailable := uint64(256)
+			Expect(stats).To(Equal(SystemLimitsStats{
+				EntropyAvailable: &entropyAvailable,
+				Conntrack:        &Usage{Used: 60000, Total: 65536},
+				FileDescriptors:  &Usage{Used: 1184, Total: 9223372036854775807},
+			}))
+		})
+
+		It("leaves out resources that cannot be read", func() {
+			fs.WriteFileString("/proc/sys/kernel/random/entropy_avail", "fake-entropy")
+			fs.WriteFileString("/proc/sys/net/netfilter/nf_conntrack_max", "65536\n")
+			fs.WriteFileString("/proc/sys/fs/file-nr", "1184\t0\n")
+
+			stats, err := collector.GetSystemLimitsStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(SystemLimitsStats{}))
+		})
+	})
+})
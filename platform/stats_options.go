@@ -0,0 +1,106 @@
+package platform
+
+import (
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	StatsMetricGroupSMART    = "smart"
+	StatsMetricGroupDNSCache = "dns_cache"
+	StatsMetricGroupDiskIO   = "disk_io"
+	StatsMetricGroupNetwork  = "network"
+	StatsMetricGroupPressure = "pressure"
+	StatsMetricGroupGPU      = "gpu"
+	StatsMetricGroupPlugins  = "plugins"
+
+	StatsMetricGroupSystemLimits = "system_limits"
+)
+
+const statsOptionsLogTag = "statsOptions"
+
+// StatsOptions are read from agent config since stats are collected before settings are fetched
+type StatsOptions struct {
+	// Interval between samples of CPU, disk I/O and network rates (defaults to 10)
+	CollectionIntervalInSeconds int
+
+	// Metric groups that are not collected, e.g. to reduce agent overhead;
+	// possible values: smart, dns_cache, disk_io, network, pressure, gpu, plugins, system_limits
+	DisabledMetricGroups []string
+}
+
+func (o StatsOptions) collectionInterval() time.Duration {
+	if o.CollectionIntervalInSeconds > 0 {
+		return time.Duration(o.CollectionIntervalInSeconds) * time.Second
+	}
+
+	return StatsCollectionInterval
+}
+
+func (o StatsOptions) metricGroupEnabled(group string) bool {
+	for _, disabledGroup := range o.DisabledMetricGroups {
+		if disabledGroup == group {
+			return false
+		}
+	}
+
+	return true
+}
+
+// statsCollector wraps base collector with collectors of enabled metric groups
+func (o StatsOptions) statsCollector(
+	statsCollector boshstats.Collector,
+	runner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	dirProvider boshdirs.Provider,
+	logger boshlog.Logger,
+) boshstats.Collector {
+	for _, group := range o.DisabledMetricGroups {
+		switch group {
+		case StatsMetricGroupSMART, StatsMetricGroupDNSCache, StatsMetricGroupDiskIO, StatsMetricGroupNetwork, StatsMetricGroupPressure, StatsMetricGroupGPU, StatsMetricGroupPlugins, StatsMetricGroupSystemLimits:
+		default:
+			logger.Warn(statsOptionsLogTag, "Ignoring unknown disabled metric group '%s'", group)
+		}
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupSMART) {
+		statsCollector = boshstats.NewSMARTStatsCollector(statsCollector, runner, SMARTStatsCollectionInterval, clock.NewClock(), logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupDNSCache) {
+		statsCollector = boshstats.NewDNSCacheStatsCollector(statsCollector, runner, boshnet.DNSCacheListenAddress)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupDiskIO) {
+		statsCollector = boshstats.NewDiskIOStatsCollector(statsCollector, fs, clock.NewClock(), logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupNetwork) {
+		statsCollector = boshstats.NewNetworkStatsCollector(statsCollector, fs, clock.NewClock(), logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupPressure) {
+		statsCollector = boshstats.NewPressureStatsCollector(statsCollector, fs, clock.NewClock(), logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupGPU) {
+		statsCollector = boshstats.NewGPUStatsCollector(statsCollector, runner, clock.NewClock(), logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupPlugins) {
+		statsCollector = boshstats.NewPluginStatsCollector(statsCollector, runner, fs, clock.NewClock(), dirProvider.JobsDir(), VitalsPluginTimeout, logger)
+	}
+
+	if o.metricGroupEnabled(StatsMetricGroupSystemLimits) {
+		statsCollector = boshstats.NewSystemLimitsStatsCollector(statsCollector, fs)
+	}
+
+	return statsCollector
+}
@@ -0,0 +1,118 @@
+package platform_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform"
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("StatsOptions", func() {
+	Describe("collection interval", func() {
+		It("defaults to 10s", func() {
+			Expect(StatsCollectionIntervalOf(StatsOptions{})).To(Equal(10 * time.Second))
+		})
+
+		It("uses configured interval", func() {
+			interval := StatsCollectionIntervalOf(StatsOptions{CollectionIntervalInSeconds: 60})
+			Expect(interval).To(Equal(60 * time.Second))
+		})
+	})
+
+	Describe("stats collector", func() {
+		var (
+			innerCollector *fakestats.FakeCollector
+			fs             *fakesys.FakeFileSystem
+			runner         *fakesys.FakeCmdRunner
+			dirProvider    boshdirs.Provider
+			logger         boshlog.Logger
+		)
+
+		BeforeEach(func() {
+			innerCollector = &fakestats.FakeCollector{
+				DiskIOStats: map[string]boshstats.DiskIOStats{
+					"/": boshstats.DiskIOStats{ReadIOPS: 5},
+				},
+				NetworkStats: map[string]boshstats.NetworkStats{
+					"eth0": boshstats.NetworkStats{RxBytesPerSec: 1024},
+				},
+				SwapIOStats: &boshstats.SwapIOStats{InBytesPerSec: 4096},
+				GPUStats: map[string]boshstats.GPUStats{
+					"0": boshstats.GPUStats{Name: "fake-gpu"},
+				},
+				PluginStats: map[string]map[string]string{
+					"fake-job": {"fake-vital": "1"},
+				},
+				SystemLimitsStats: boshstats.SystemLimitsStats{
+					Conntrack: &boshstats.Usage{Used: 1, Total: 2},
+				},
+			}
+			fs = fakesys.NewFakeFileSystem()
+			runner = fakesys.NewFakeCmdRunner()
+			dirProvider = boshdirs.NewProvider("/var/vcap")
+			logger = boshlog.NewLogger(boshlog.LevelNone)
+		})
+
+		It("collects all metric groups by default", func() {
+			collector := StatsCollectorOf(StatsOptions{}, innerCollector, runner, fs, dirProvider, logger)
+
+			_, err := collector.GetDiskIOStats("/")
+			Expect(err).To(HaveOccurred())
+
+			networkStats, err := collector.GetNetworkStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkStats).To(BeEmpty())
+
+			_, err = collector.GetSwapIOStats()
+			Expect(err).To(HaveOccurred())
+
+			gpuStats, err := collector.GetGPUStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gpuStats).To(BeEmpty())
+
+			pluginStats, err := collector.GetPluginStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pluginStats).To(BeEmpty())
+
+			systemLimitsStats, err := collector.GetSystemLimitsStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(systemLimitsStats).To(Equal(boshstats.SystemLimitsStats{}))
+		})
+
+		It("leaves disabled metric groups to the base collector", func() {
+			options := StatsOptions{DisabledMetricGroups: []string{"disk_io", "network", "pressure", "gpu", "plugins", "system_limits"}}
+			collector := StatsCollectorOf(options, innerCollector, runner, fs, dirProvider, logger)
+
+			diskIOStats, err := collector.GetDiskIOStats("/")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskIOStats).To(Equal(boshstats.DiskIOStats{ReadIOPS: 5}))
+
+			networkStats, err := collector.GetNetworkStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkStats).To(Equal(innerCollector.NetworkStats))
+
+			swapIOStats, err := collector.GetSwapIOStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(swapIOStats).To(Equal(boshstats.SwapIOStats{InBytesPerSec: 4096}))
+
+			gpuStats, err := collector.GetGPUStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(gpuStats).To(Equal(innerCollector.GPUStats))
+
+			pluginStats, err := collector.GetPluginStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(pluginStats).To(Equal(innerCollector.PluginStats))
+
+			systemLimitsStats, err := collector.GetSystemLimitsStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(systemLimitsStats).To(Equal(innerCollector.SystemLimitsStats))
+		})
+	})
+})
@@ -0,0 +1,11 @@
+package udevdevice
+
+import (
+	"time"
+)
+
+type EventMonitor interface {
+	// WaitForEvent blocks until a block device is added, changed or removed,
+	// or until maxWait passes
+	WaitForEvent(maxWait time.Duration)
+}
@@ -0,0 +1,22 @@
+package fakes
+
+import (
+	"time"
+)
+
+type FakeEventMonitor struct {
+	WaitForEventCallCount int
+	WaitForEventStub      func(maxWait time.Duration)
+}
+
+func NewFakeEventMonitor() *FakeEventMonitor {
+	return &FakeEventMonitor{}
+}
+
+func (m *FakeEventMonitor) WaitForEvent(maxWait time.Duration) {
+	m.WaitForEventCallCount++
+
+	if m.WaitForEventStub != nil {
+		m.WaitForEventStub(maxWait)
+	}
+}
@@ -0,0 +1,67 @@
+package udevdevice
+
+import (
+	"sync"
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// defaultEventPollInterval bounds a single wait so that changes
+// which are not announced by a uevent are still noticed
+const defaultEventPollInterval = 1 * time.Second
+
+type netlinkEventMonitor struct {
+	pollInterval time.Duration
+	startOnce    sync.Once
+	events       chan struct{}
+	logger       boshlog.Logger
+	logTag       string
+}
+
+// NewNetlinkEventMonitor returns monitor that subscribes to kernel and udev
+// uevents over netlink once it is first waited on. When netlink socket cannot
+// be opened waiting falls back to sleeping for the poll interval.
+func NewNetlinkEventMonitor(pollInterval time.Duration, logger boshlog.Logger) EventMonitor {
+	if pollInterval <= 0 {
+		pollInterval = defaultEventPollInterval
+	}
+
+	return &netlinkEventMonitor{
+		pollInterval: pollInterval,
+		logger:       logger,
+		logTag:       "netlinkEventMonitor",
+	}
+}
+
+func (m *netlinkEventMonitor) WaitForEvent(maxWait time.Duration) {
+	m.startOnce.Do(m.start)
+
+	if maxWait > m.pollInterval {
+		maxWait = m.pollInterval
+	}
+
+	if maxWait <= 0 {
+		return
+	}
+
+	if m.events == nil {
+		time.Sleep(maxWait)
+		return
+	}
+
+	select {
+	case <-m.events:
+	case <-time.After(maxWait):
+	}
+}
+
+func (m *netlinkEventMonitor) start() {
+	events, err := subscribeToBlockDeviceEvents(m.logger, m.logTag)
+	if err != nil {
+		m.logger.Warn(m.logTag, "Falling back to polling for device changes: %s", err.Error())
+		return
+	}
+
+	m.events = events
+}
@@ -0,0 +1,68 @@
+//go:build linux
+// +build linux
+
+package udevdevice
+
+import (
+	"bytes"
+	"syscall"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	netlinkKernelEventGroup = 1
+	netlinkUdevEventGroup   = 2
+)
+
+var blockSubsystemProperty = []byte("SUBSYSTEM=block")
+
+// subscribeToBlockDeviceEvents returns channel that receives a value whenever
+// kernel or udev (once its rules, e.g. /dev/disk/by-id links, have been processed)
+// reports a block device event. Events that arrive while nobody waits are coalesced.
+func subscribeToBlockDeviceEvents(logger boshlog.Logger, logTag string) (chan struct{}, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_RAW|syscall.SOCK_CLOEXEC, syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Opening netlink socket")
+	}
+
+	err = syscall.Bind(fd, &syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: netlinkKernelEventGroup | netlinkUdevEventGroup,
+	})
+	if err != nil {
+		_ = syscall.Close(fd)
+		return nil, bosherr.WrapError(err, "Binding netlink socket")
+	}
+
+	events := make(chan struct{}, 1)
+
+	go func() {
+		defer syscall.Close(fd)
+
+		buf := make([]byte, 64*1024)
+
+		for {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				if err == syscall.EINTR || err == syscall.ENOBUFS {
+					continue
+				}
+				logger.Warn(logTag, "Stopped receiving uevents: %s", err.Error())
+				return
+			}
+
+			if !bytes.Contains(buf[:n], blockSubsystemProperty) {
+				continue
+			}
+
+			select {
+			case events <- struct{}{}:
+			default:
+			}
+		}
+	}()
+
+	return events, nil
+}
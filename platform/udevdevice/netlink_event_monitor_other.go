@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package udevdevice
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+func subscribeToBlockDeviceEvents(logger boshlog.Logger, logTag string) (chan struct{}, error) {
+	return nil, bosherr.Error("Uevents are only supported on Linux")
+}
@@ -0,0 +1,42 @@
+package udevdevice_test
+
+import (
+	"time"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("netlinkEventMonitor", func() {
+	var (
+		monitor EventMonitor
+	)
+
+	BeforeEach(func() {
+		monitor = NewNetlinkEventMonitor(200*time.Millisecond, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("WaitForEvent", func() {
+		It("returns no later than after max wait", func() {
+			startedAt := time.Now()
+			monitor.WaitForEvent(50 * time.Millisecond)
+			Expect(time.Since(startedAt)).To(BeNumerically("<", 200*time.Millisecond))
+		})
+
+		It("returns no later than after poll interval", func() {
+			startedAt := time.Now()
+			monitor.WaitForEvent(time.Minute)
+			Expect(time.Since(startedAt)).To(BeNumerically("<", time.Second))
+		})
+
+		It("returns immediately when max wait has already passed", func() {
+			startedAt := time.Now()
+			monitor.WaitForEvent(-time.Second)
+			Expect(time.Since(startedAt)).To(BeNumerically("<", 50*time.Millisecond))
+		})
+	})
+})
@@ -0,0 +1,20 @@
+package fakes
+
+import boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+
+type FakeHistory struct {
+	RecordedVitals []boshvitals.Vitals
+	RecordErr      error
+
+	HistorySamples []boshvitals.HistorySample
+	SamplesErr     error
+}
+
+func (h *FakeHistory) Record(vitals boshvitals.Vitals) error {
+	h.RecordedVitals = append(h.RecordedVitals, vitals)
+	return h.RecordErr
+}
+
+func (h *FakeHistory) Samples() ([]boshvitals.HistorySample, error) {
+	return h.HistorySamples, h.SamplesErr
+}
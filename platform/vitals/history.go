@@ -0,0 +1,120 @@
+package vitals
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/pivotal-golang/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// DefaultHistorySize keeps last 30 minutes of vitals at default heartbeat interval
+const DefaultHistorySize = 30
+
+type HistorySample struct {
+	// Unix time when vitals were sampled
+	Timestamp int64  `json:"timestamp"`
+	Vitals    Vitals `json:"vitals"`
+}
+
+type History interface {
+	// Record adds vitals sampled now dropping the oldest sample when history is full
+	Record(vitals Vitals) error
+
+	// Samples returns samples from oldest to newest
+	Samples() ([]HistorySample, error)
+}
+
+type fileHistory struct {
+	fs          boshsys.FileSystem
+	path        string
+	size        int
+	timeService clock.Clock
+
+	samples []HistorySample
+	loaded  bool
+	lock    sync.Mutex
+}
+
+// NewFileHistory keeps samples in a file so that they survive agent and VM restarts
+func NewFileHistory(fs boshsys.FileSystem, path string, size int, timeService clock.Clock) History {
+	return &fileHistory{
+		fs:          fs,
+		path:        path,
+		size:        size,
+		timeService: timeService,
+	}
+}
+
+func (h *fileHistory) Record(vitals Vitals) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.load()
+
+	h.samples = append(h.samples, HistorySample{
+		Timestamp: h.timeService.Now().Unix(),
+		Vitals:    vitals,
+	})
+
+	if len(h.samples) > h.size {
+		h.samples = h.samples[len(h.samples)-h.size:]
+	}
+
+	bytes, err := json.Marshal(h.samples)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling vitals history")
+	}
+
+	err = h.fs.WriteFile(h.path, bytes)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing vitals history")
+	}
+
+	return nil
+}
+
+func (h *fileHistory) Samples() ([]HistorySample, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.load()
+
+	samples := make([]HistorySample, len(h.samples))
+	copy(samples, h.samples)
+
+	return samples, nil
+}
+
+// load starts with empty history when file is missing or was not written completely
+func (h *fileHistory) load() {
+	if h.loaded {
+		return
+	}
+
+	h.loaded = true
+
+	if !h.fs.FileExists(h.path) {
+		return
+	}
+
+	bytes, err := h.fs.ReadFile(h.path)
+	if err != nil {
+		return
+	}
+
+	var samples []HistorySample
+
+	err = json.Unmarshal(bytes, &samples)
+	if err != nil {
+		return
+	}
+
+	if len(samples) > h.size {
+		samples = samples[len(samples)-h.size:]
+	}
+
+	h.samples = samples
+}
@@ -0,0 +1,84 @@
+package vitals_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("fileHistory", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		timeService *fakeclock.FakeClock
+		history     History
+	)
+
+	const historyPath = "/var/vcap/bosh/vitals_history.json"
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		timeService = fakeclock.NewFakeClock(time.Unix(1000, 0))
+		history = NewFileHistory(fs, historyPath, 2, timeService)
+	})
+
+	It("returns no samples when nothing was recorded", func() {
+		samples, err := history.Samples()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(samples).To(BeEmpty())
+	})
+
+	It("keeps only latest samples up to history size", func() {
+		for i, load := range []string{"1", "2", "3"} {
+			timeService.Increment(time.Duration(i+1) * time.Minute)
+
+			err := history.Record(Vitals{Load: []string{load}})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		samples, err := history.Samples()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(samples).To(Equal([]HistorySample{
+			{Timestamp: 1180, Vitals: Vitals{Load: []string{"2"}}},
+			{Timestamp: 1360, Vitals: Vitals{Load: []string{"3"}}},
+		}))
+	})
+
+	It("keeps samples across restarts", func() {
+		err := history.Record(Vitals{Load: []string{"1"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		restartedHistory := NewFileHistory(fs, historyPath, 2, timeService)
+
+		samples, err := restartedHistory.Samples()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(samples).To(Equal([]HistorySample{
+			{Timestamp: 1000, Vitals: Vitals{Load: []string{"1"}}},
+		}))
+	})
+
+	It("starts over when history file is corrupted", func() {
+		fs.WriteFileString(historyPath, "[{")
+
+		err := history.Record(Vitals{Load: []string{"1"}})
+		Expect(err).ToNot(HaveOccurred())
+
+		samples, err := history.Samples()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(samples).To(HaveLen(1))
+	})
+
+	It("returns an error when history cannot be written", func() {
+		fs.WriteFileError = errors.New("fake-write-err")
+
+		err := history.Record(Vitals{Load: []string{"1"}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+	})
+})
@@ -2,6 +2,7 @@ package vitals
 
 import (
 	"fmt"
+	"path/filepath"
 
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -63,6 +64,9 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		return
 	}
 
+	// System limits do not fail vitals since unreported resources are left out
+	systemLimits, _ := s.statsCollector.GetSystemLimitsStats()
+
 	vitals = Vitals{
 		Load: []string{
 			fmt.Sprintf("%.2f", loadStats.One),
@@ -77,10 +81,177 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		Mem:  createMemVitals(memStats),
 		Swap: createMemVitals(swapStats),
 		Disk: diskStats,
+
+		DiskHealth: s.getDiskHealth(),
+
+		SwapIO:   s.getSwapIO(),
+		Pressure: s.getPressure(),
+
+		EntropyAvail: formatOptionalCount(systemLimits.EntropyAvailable),
+		Conntrack:    createLimitVitals(systemLimits.Conntrack),
+		OpenFDs:      createLimitVitals(systemLimits.FileDescriptors),
+
+		Network: s.getNetwork(),
+
+		GPU: s.getGPU(),
+
+		Custom: s.getCustom(),
+
+		DNSCache: s.getDNSCache(),
 	}
 	return
 }
 
+// getDNSCache does not fail vitals since dns cache is optional
+func (s concreteService) getDNSCache() *DNSCacheVitals {
+	stats, err := s.statsCollector.GetDNSCacheStats()
+	if err != nil {
+		return nil
+	}
+
+	return &DNSCacheVitals{
+		Hits:       fmt.Sprintf("%d", stats.Hits),
+		Misses:     fmt.Sprintf("%d", stats.Misses),
+		HitPercent: boshstats.NewPercentage(stats.Hits, stats.Hits+stats.Misses).FormatFractionOf100(0),
+	}
+}
+
+// getNetwork does not fail vitals since rates are only known after second sample
+func (s concreteService) getNetwork() NetworkVitals {
+	stats, err := s.statsCollector.GetNetworkStats()
+	if err != nil || len(stats) == 0 {
+		return nil
+	}
+
+	networkVitals := make(NetworkVitals, len(stats))
+
+	for iface, ifaceStats := range stats {
+		networkVitals[iface] = SpecificNetworkVitals{
+			RxKbPerSec:      fmt.Sprintf("%.1f", ifaceStats.RxBytesPerSec/1024),
+			RxPacketsPerSec: fmt.Sprintf("%.1f", ifaceStats.RxPacketsPerSec),
+			RxErrorsPerSec:  fmt.Sprintf("%.1f", ifaceStats.RxErrorsPerSec),
+			RxDropsPerSec:   fmt.Sprintf("%.1f", ifaceStats.RxDropsPerSec),
+			TxKbPerSec:      fmt.Sprintf("%.1f", ifaceStats.TxBytesPerSec/1024),
+			TxPacketsPerSec: fmt.Sprintf("%.1f", ifaceStats.TxPacketsPerSec),
+			TxErrorsPerSec:  fmt.Sprintf("%.1f", ifaceStats.TxErrorsPerSec),
+			TxDropsPerSec:   fmt.Sprintf("%.1f", ifaceStats.TxDropsPerSec),
+		}
+	}
+
+	return networkVitals
+}
+
+// getSwapIO does not fail vitals since rates are only known after second sample
+func (s concreteService) getSwapIO() *SwapIOVitals {
+	stats, err := s.statsCollector.GetSwapIOStats()
+	if err != nil {
+		return nil
+	}
+
+	return &SwapIOVitals{
+		InKbPerSec:  fmt.Sprintf("%.1f", stats.InBytesPerSec/1024),
+		OutKbPerSec: fmt.Sprintf("%.1f", stats.OutBytesPerSec/1024),
+	}
+}
+
+// getPressure does not fail vitals since pressure stall information requires kernel 4.20
+func (s concreteService) getPressure() PressureVitals {
+	stats, err := s.statsCollector.GetPressureStats()
+	if err != nil || len(stats) == 0 {
+		return nil
+	}
+
+	pressureVitals := make(PressureVitals, len(stats))
+
+	for resource, pressure := range stats {
+		specificVitals := SpecificPressureVitals{
+			Some: createPressureAveragesVitals(pressure.Some),
+		}
+
+		if pressure.Full != nil {
+			full := createPressureAveragesVitals(*pressure.Full)
+			specificVitals.Full = &full
+		}
+
+		pressureVitals[resource] = specificVitals
+	}
+
+	return pressureVitals
+}
+
+func createPressureAveragesVitals(averages boshstats.PressureAverages) PressureAveragesVitals {
+	return PressureAveragesVitals{
+		Avg10:  fmt.Sprintf("%.2f", averages.Avg10),
+		Avg60:  fmt.Sprintf("%.2f", averages.Avg60),
+		Avg300: fmt.Sprintf("%.2f", averages.Avg300),
+	}
+}
+
+// getCustom does not fail vitals since plugins are provided by jobs
+func (s concreteService) getCustom() CustomVitals {
+	stats, err := s.statsCollector.GetPluginStats()
+	if err != nil || len(stats) == 0 {
+		return nil
+	}
+
+	return CustomVitals(stats)
+}
+
+// getGPU does not fail vitals since most VMs have no GPU
+func (s concreteService) getGPU() GPUVitals {
+	stats, err := s.statsCollector.GetGPUStats()
+	if err != nil || len(stats) == 0 {
+		return nil
+	}
+
+	gpuVitals := make(GPUVitals, len(stats))
+
+	for index, gpuStats := range stats {
+		specificVitals := SpecificGPUVitals{
+			Name:               gpuStats.Name,
+			UtilizationPercent: formatOptionalCount(gpuStats.UtilizationPercent),
+			TemperatureCelsius: formatOptionalCount(gpuStats.TemperatureCelsius),
+		}
+
+		if gpuStats.MemoryUsage != nil {
+			memVitals := createMemVitals(*gpuStats.MemoryUsage)
+			specificVitals.Mem = &memVitals
+		}
+
+		gpuVitals[index] = specificVitals
+	}
+
+	return gpuVitals
+}
+
+// getDiskHealth does not fail vitals since SMART data is not available on most IaaSes
+func (s concreteService) getDiskHealth() DiskHealthVitals {
+	health, err := s.statsCollector.GetDiskHealth()
+	if err != nil || len(health) == 0 {
+		return nil
+	}
+
+	healthVitals := make(DiskHealthVitals, len(health))
+
+	for device, deviceHealth := range health {
+		healthVitals[filepath.Base(device)] = SpecificDiskHealthVitals{
+			Healthy:            deviceHealth.Healthy,
+			ReallocatedSectors: formatOptionalCount(deviceHealth.ReallocatedSectors),
+			MediaErrors:        formatOptionalCount(deviceHealth.MediaErrors),
+			WearPercent:        formatOptionalCount(deviceHealth.PercentageUsed),
+		}
+	}
+
+	return healthVitals
+}
+
+func formatOptionalCount(count *uint64) string {
+	if count == nil {
+		return ""
+	}
+	return fmt.Sprintf("%d", *count)
+}
+
 func (s concreteService) getDiskStats() (diskStats DiskVitals, err error) {
 	disks := map[string]string{
 		"/": "system",
@@ -110,13 +281,49 @@ func (s concreteService) addDiskStats(diskStats DiskVitals, path, name string) (
 		return
 	}
 
-	updated[name] = SpecificDiskVitals{
-		Percent:      stat.DiskUsage.Percent().FormatFractionOf100(0),
-		InodePercent: stat.InodeUsage.Percent().FormatFractionOf100(0),
+	diskVitals := SpecificDiskVitals{
+		Percent: stat.DiskUsage.Percent().FormatFractionOf100(0),
+		IO:      s.getDiskIO(path),
+	}
+
+	// File systems like btrfs allocate inodes dynamically and report no total
+	if stat.InodeUsage.Total > 0 {
+		diskVitals.InodePercent = stat.InodeUsage.Percent().FormatFractionOf100(0)
 	}
+
+	updated[name] = diskVitals
 	return
 }
 
+// getDiskIO does not fail vitals since I/O rates are only known after second sample
+func (s concreteService) getDiskIO(path string) *DiskIOVitals {
+	stats, err := s.statsCollector.GetDiskIOStats(path)
+	if err != nil {
+		return nil
+	}
+
+	return &DiskIOVitals{
+		ReadIOPS:      fmt.Sprintf("%.1f", stats.ReadIOPS),
+		WriteIOPS:     fmt.Sprintf("%.1f", stats.WriteIOPS),
+		ReadKbPerSec:  fmt.Sprintf("%.1f", stats.ReadBytesPerSec/1024),
+		WriteKbPerSec: fmt.Sprintf("%.1f", stats.WriteBytesPerSec/1024),
+		QueueDepth:    fmt.Sprintf("%.2f", stats.QueueDepth),
+		AwaitMs:       fmt.Sprintf("%.1f", stats.AwaitMs),
+	}
+}
+
+func createLimitVitals(usage *boshstats.Usage) *LimitVitals {
+	if usage == nil {
+		return nil
+	}
+
+	return &LimitVitals{
+		Used:    fmt.Sprintf("%d", usage.Used),
+		Max:     fmt.Sprintf("%d", usage.Total),
+		Percent: usage.Percent().FormatFractionOf100(1),
+	}
+}
+
 func createMemVitals(memUsage boshstats.Usage) MemoryVitals {
 	return MemoryVitals{
 		Percent: memUsage.Percent().FormatFractionOf100(0),
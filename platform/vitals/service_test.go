@@ -1,6 +1,7 @@
 package vitals_test
 
 import (
+	"errors"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -49,6 +50,7 @@ func buildVitalsService() (statsCollector *fakestats.FakeCollector, service Serv
 				InodeUsage: boshstats.Usage{Used: 3, Total: 4},
 			},
 		},
+		DNSCacheStatsErr: errors.New("fake-dns-cache-not-running"),
 	}
 
 	service = NewService(statsCollector, dirProvider)
@@ -113,6 +115,289 @@ func init() {
 			boshassert.LacksJSONKey(GinkgoT(), vitals.Disk, "ephemeral")
 			boshassert.LacksJSONKey(GinkgoT(), vitals.Disk, "persistent")
 		})
+
+		It("getting vitals omits inode usage of disks without inode total", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.DiskStats = map[string]boshstats.DiskStats{
+				"/": boshstats.DiskStats{
+					DiskUsage: boshstats.Usage{Used: 100, Total: 200},
+				},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vitals.Disk["system"].Percent).To(Equal("50"))
+			boshassert.LacksJSONKey(GinkgoT(), vitals.Disk["system"], "inode_percent")
+		})
+		It("getting vitals includes disk I/O of disks when it was collected", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.DiskIOStats = map[string]boshstats.DiskIOStats{
+				"/": boshstats.DiskIOStats{
+					ReadIOPS:         12.5,
+					WriteIOPS:        40,
+					ReadBytesPerSec:  512 * 1024,
+					WriteBytesPerSec: 2048 * 1024,
+					QueueDepth:       1.257,
+					AwaitMs:          3.25,
+				},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vitals.Disk["system"].IO).To(Equal(&DiskIOVitals{
+				ReadIOPS:      "12.5",
+				WriteIOPS:     "40.0",
+				ReadKbPerSec:  "512.0",
+				WriteKbPerSec: "2048.0",
+				QueueDepth:    "1.26",
+				AwaitMs:       "3.2",
+			}))
+			Expect(vitals.Disk["ephemeral"].IO).To(BeNil())
+		})
+
+		It("getting vitals includes network rates keyed by interface name", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.NetworkStats = map[string]boshstats.NetworkStats{
+				"eth0": boshstats.NetworkStats{
+					RxBytesPerSec:   2048,
+					RxPacketsPerSec: 20,
+					RxErrorsPerSec:  0.1,
+					TxBytesPerSec:   512,
+					TxPacketsPerSec: 5,
+					TxDropsPerSec:   0.5,
+				},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vitals.Network).To(Equal(NetworkVitals{
+				"eth0": SpecificNetworkVitals{
+					RxKbPerSec:      "2.0",
+					RxPacketsPerSec: "20.0",
+					RxErrorsPerSec:  "0.1",
+					RxDropsPerSec:   "0.0",
+					TxKbPerSec:      "0.5",
+					TxPacketsPerSec: "5.0",
+					TxErrorsPerSec:  "0.0",
+					TxDropsPerSec:   "0.5",
+				},
+			}))
+		})
+
+		It("getting vitals omits network before rates were collected", func() {
+			_, service := buildVitalsService()
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.Network).To(BeNil())
+		})
+
+		It("getting vitals includes swap rates", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.SwapIOStats = &boshstats.SwapIOStats{
+				InBytesPerSec:  4096,
+				OutBytesPerSec: 51200,
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.SwapIO).To(Equal(&SwapIOVitals{
+				InKbPerSec:  "4.0",
+				OutKbPerSec: "50.0",
+			}))
+		})
+
+		It("getting vitals omits swap rates before they were collected", func() {
+			_, service := buildVitalsService()
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.SwapIO).To(BeNil())
+		})
+
+		It("getting vitals includes pressure stall information keyed by resource", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.PressureStats = map[string]boshstats.Pressure{
+				"cpu": boshstats.Pressure{
+					Some: boshstats.PressureAverages{Avg10: 1.5, Avg60: 0.75, Avg300: 0.1},
+				},
+				"memory": boshstats.Pressure{
+					Some: boshstats.PressureAverages{Avg10: 30.25},
+					Full: &boshstats.PressureAverages{Avg10: 12.5, Avg60: 4},
+				},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.Pressure).To(Equal(PressureVitals{
+				"cpu": SpecificPressureVitals{
+					Some: PressureAveragesVitals{Avg10: "1.50", Avg60: "0.75", Avg300: "0.10"},
+				},
+				"memory": SpecificPressureVitals{
+					Some: PressureAveragesVitals{Avg10: "30.25", Avg60: "0.00", Avg300: "0.00"},
+					Full: &PressureAveragesVitals{Avg10: "12.50", Avg60: "4.00", Avg300: "0.00"},
+				},
+			}))
+		})
+
+		It("getting vitals omits pressure stall information when kernel does not report it", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.PressureStatsErr = errors.New("fake-pressure-err")
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.Pressure).To(BeNil())
+		})
+
+		It("getting vitals includes GPU readings keyed by index", func() {
+			statsCollector, service := buildVitalsService()
+			utilization, temperature := uint64(35), uint64(45)
+			statsCollector.GPUStats = map[string]boshstats.GPUStats{
+				"0": boshstats.GPUStats{
+					Name:               "Tesla T4",
+					UtilizationPercent: &utilization,
+					MemoryUsage:        &boshstats.Usage{Used: 4 * 1024 * 1024, Total: 16 * 1024 * 1024},
+					TemperatureCelsius: &temperature,
+				},
+				"1": boshstats.GPUStats{Name: "fake-gpu"},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.GPU).To(Equal(GPUVitals{
+				"0": SpecificGPUVitals{
+					Name:               "Tesla T4",
+					UtilizationPercent: "35",
+					Mem:                &MemoryVitals{Kb: "4096", Percent: "25"},
+					TemperatureCelsius: "45",
+				},
+				"1": SpecificGPUVitals{Name: "fake-gpu"},
+			}))
+		})
+
+		It("getting vitals omits GPU when there are no GPUs", func() {
+			_, service := buildVitalsService()
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.GPU).To(BeNil())
+		})
+
+		It("getting vitals includes custom vitals of job plugins keyed by job name", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.PluginStats = map[string]map[string]string{
+				"fake-job": {"queue_depth": "12", "leader": "true"},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.Custom).To(Equal(CustomVitals{
+				"fake-job": {"queue_depth": "12", "leader": "true"},
+			}))
+		})
+
+		It("getting vitals omits custom vitals when no plugin reported any", func() {
+			_, service := buildVitalsService()
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.Custom).To(BeNil())
+		})
+
+		It("getting vitals includes usage of system limits", func() {
+			statsCollector, service := buildVitalsService()
+			entropyAvailable := uint64(256)
+			statsCollector.SystemLimitsStats = boshstats.SystemLimitsStats{
+				EntropyAvailable: &entropyAvailable,
+				Conntrack:        &boshstats.Usage{Used: 60000, Total: 65536},
+				FileDescriptors:  &boshstats.Usage{Used: 1184, Total: 9223372036854775807},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.EntropyAvail).To(Equal("256"))
+			Expect(vitals.Conntrack).To(Equal(&LimitVitals{Used: "60000", Max: "65536", Percent: "91.6"}))
+			Expect(vitals.OpenFDs).To(Equal(&LimitVitals{Used: "1184", Max: "9223372036854775807", Percent: "0.0"}))
+		})
+
+		It("getting vitals omits system limits that were not reported", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.SystemLimitsStatsErr = errors.New("fake-system-limits-err")
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(vitals.EntropyAvail).To(BeEmpty())
+			Expect(vitals.Conntrack).To(BeNil())
+			Expect(vitals.OpenFDs).To(BeNil())
+		})
+
+		It("getting vitals includes disk health keyed by device name", func() {
+			statsCollector, service := buildVitalsService()
+			reallocatedSectors := uint64(8)
+			mediaErrors := uint64(0)
+			percentageUsed := uint64(12)
+			statsCollector.DiskHealth = map[string]boshstats.DiskHealth{
+				"/dev/sda": boshstats.DiskHealth{
+					Healthy:            false,
+					ReallocatedSectors: &reallocatedSectors,
+				},
+				"/dev/nvme0": boshstats.DiskHealth{
+					Healthy:        true,
+					MediaErrors:    &mediaErrors,
+					PercentageUsed: &percentageUsed,
+				},
+			}
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vitals.DiskHealth).To(Equal(DiskHealthVitals{
+				"sda": SpecificDiskHealthVitals{
+					Healthy:            false,
+					ReallocatedSectors: "8",
+				},
+				"nvme0": SpecificDiskHealthVitals{
+					Healthy:     true,
+					MediaErrors: "0",
+					WearPercent: "12",
+				},
+			}))
+		})
+
+		It("getting vitals when disk health cannot be collected", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.DiskHealthErr = errors.New("fake-disk-health-err")
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			boshassert.LacksJSONKey(GinkgoT(), vitals, "disk_health")
+		})
+
+		It("getting vitals includes dns cache counters", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.DNSCacheStats = boshstats.DNSCacheStats{Hits: 75, Misses: 25}
+			statsCollector.DNSCacheStatsErr = nil
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(vitals.DNSCache).To(Equal(&DNSCacheVitals{Hits: "75", Misses: "25", HitPercent: "75"}))
+		})
+
+		It("getting vitals when dns cache is not running", func() {
+			statsCollector, service := buildVitalsService()
+			statsCollector.DNSCacheStatsErr = errors.New("fake-dns-cache-err")
+
+			vitals, err := service.Get()
+			Expect(err).ToNot(HaveOccurred())
+
+			boshassert.LacksJSONKey(GinkgoT(), vitals, "dns_cache")
+		})
+
 		It("get getting vitals on system disk error", func() {
 
 			statsCollector, service := buildVitalsService()
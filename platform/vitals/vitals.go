@@ -6,6 +6,99 @@ type Vitals struct {
 	Load []string     `json:"load,omitempty"`
 	Mem  MemoryVitals `json:"mem"`
 	Swap MemoryVitals `json:"swap"`
+
+	SwapIO *SwapIOVitals `json:"swap_io,omitempty"`
+
+	Pressure PressureVitals `json:"pressure,omitempty"`
+
+	// Bits of entropy available to /dev/random
+	EntropyAvail string `json:"entropy_avail,omitempty"`
+
+	Conntrack *LimitVitals `json:"conntrack,omitempty"`
+	OpenFDs   *LimitVitals `json:"open_fds,omitempty"`
+
+	DiskHealth DiskHealthVitals `json:"disk_health,omitempty"`
+
+	Network NetworkVitals `json:"network,omitempty"`
+
+	GPU GPUVitals `json:"gpu,omitempty"`
+
+	Custom CustomVitals `json:"custom,omitempty"`
+
+	DNSCache *DNSCacheVitals `json:"dns_cache,omitempty"`
+
+	Certificates CertificateVitals `json:"certificates,omitempty"`
+
+	Processes ProcessVitals `json:"processes,omitempty"`
+}
+
+// ProcessVitals are keyed by name of monit process of a job
+type ProcessVitals map[string]SpecificProcessVitals
+
+type SpecificProcessVitals struct {
+	State string `json:"state"`
+	// Percentage of all CPUs including child processes
+	CPU        string       `json:"cpu"`
+	Mem        MemoryVitals `json:"mem"`
+	OpenFDs    string       `json:"open_fds"`
+	UptimeSecs string       `json:"uptime_secs"`
+}
+
+// CertificateVitals are keyed by 'mbus' for https mbus certificate
+// and 'trusted_<fingerprint prefix>' for trusted certificates
+type CertificateVitals map[string]SpecificCertificateVitals
+
+type SpecificCertificateVitals struct {
+	Subject string `json:"subject,omitempty"`
+	// Negative after the certificate expired
+	DaysToExpiry string `json:"days_to_expiry"`
+}
+
+// SwapIOVitals are averages over latest collection interval
+type SwapIOVitals struct {
+	InKbPerSec  string `json:"in_kb_per_sec"`
+	OutKbPerSec string `json:"out_kb_per_sec"`
+}
+
+// LimitVitals are usage of a system-wide kernel limit
+type LimitVitals struct {
+	Used    string `json:"used"`
+	Max     string `json:"max"`
+	Percent string `json:"percent"`
+}
+
+// PressureVitals are keyed by resource, i.e. cpu, memory and io
+type PressureVitals map[string]SpecificPressureVitals
+
+// SpecificPressureVitals are percentages of time some or all non-idle tasks stalled on the resource
+type SpecificPressureVitals struct {
+	Some PressureAveragesVitals  `json:"some"`
+	Full *PressureAveragesVitals `json:"full,omitempty"`
+}
+
+type PressureAveragesVitals struct {
+	Avg10  string `json:"avg10"`
+	Avg60  string `json:"avg60"`
+	Avg300 string `json:"avg300"`
+}
+
+// CustomVitals are reported by job provided plugins and keyed by job name
+type CustomVitals map[string]map[string]string
+
+// GPUVitals are keyed by GPU index
+type GPUVitals map[string]SpecificGPUVitals
+
+type SpecificGPUVitals struct {
+	Name               string        `json:"name"`
+	UtilizationPercent string        `json:"utilization_percent,omitempty"`
+	Mem                *MemoryVitals `json:"mem,omitempty"`
+	TemperatureCelsius string        `json:"temperature_celsius,omitempty"`
+}
+
+type DNSCacheVitals struct {
+	Hits       string `json:"hits"`
+	Misses     string `json:"misses"`
+	HitPercent string `json:"hit_percent"`
 }
 
 type CPUVitals struct {
@@ -19,6 +112,41 @@ type DiskVitals map[string]SpecificDiskVitals
 type SpecificDiskVitals struct {
 	InodePercent string `json:"inode_percent,omitempty"`
 	Percent      string `json:"percent,omitempty"`
+
+	IO *DiskIOVitals `json:"io,omitempty"`
+}
+
+// DiskIOVitals are averages over latest collection interval
+type DiskIOVitals struct {
+	ReadIOPS      string `json:"read_iops"`
+	WriteIOPS     string `json:"write_iops"`
+	ReadKbPerSec  string `json:"read_kb_per_sec"`
+	WriteKbPerSec string `json:"write_kb_per_sec"`
+	QueueDepth    string `json:"queue_depth"`
+	AwaitMs       string `json:"await_ms"`
+}
+
+// NetworkVitals are keyed by interface name; values are averages over latest collection interval
+type NetworkVitals map[string]SpecificNetworkVitals
+
+type SpecificNetworkVitals struct {
+	RxKbPerSec      string `json:"rx_kb_per_sec"`
+	RxPacketsPerSec string `json:"rx_packets_per_sec"`
+	RxErrorsPerSec  string `json:"rx_errors_per_sec"`
+	RxDropsPerSec   string `json:"rx_drops_per_sec"`
+	TxKbPerSec      string `json:"tx_kb_per_sec"`
+	TxPacketsPerSec string `json:"tx_packets_per_sec"`
+	TxErrorsPerSec  string `json:"tx_errors_per_sec"`
+	TxDropsPerSec   string `json:"tx_drops_per_sec"`
+}
+
+type DiskHealthVitals map[string]SpecificDiskHealthVitals
+
+type SpecificDiskHealthVitals struct {
+	Healthy            bool   `json:"healthy"`
+	ReallocatedSectors string `json:"reallocated_sectors,omitempty"`
+	MediaErrors        string `json:"media_errors,omitempty"`
+	WearPercent        string `json:"wear_percent,omitempty"`
 }
 
 type MemoryVitals struct {
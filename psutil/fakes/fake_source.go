@@ -0,0 +1,73 @@
+package fakes
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type FakeSource struct {
+	// CPUTimes are returned in order; last one is repeated
+	CPUTimesStats []cpu.TimesStat
+	CPUTimesErr   error
+	cpuTimesLock  sync.Mutex
+
+	CPUCountNum int
+	CPUCountErr error
+
+	LoadAvgStat load.AvgStat
+	LoadAvgErr  error
+
+	VirtualMemoryStat mem.VirtualMemoryStat
+	VirtualMemoryErr  error
+
+	SwapMemoryStat mem.SwapMemoryStat
+	SwapMemoryErr  error
+
+	DiskUsageStats map[string]disk.UsageStat
+}
+
+func (s *FakeSource) CPUTimes() (cpu.TimesStat, error) {
+	s.cpuTimesLock.Lock()
+	defer s.cpuTimesLock.Unlock()
+
+	if s.CPUTimesErr != nil || len(s.CPUTimesStats) == 0 {
+		return cpu.TimesStat{}, s.CPUTimesErr
+	}
+
+	times := s.CPUTimesStats[0]
+	if len(s.CPUTimesStats) > 1 {
+		s.CPUTimesStats = s.CPUTimesStats[1:]
+	}
+
+	return times, nil
+}
+
+func (s *FakeSource) CPUCount() (int, error) {
+	return s.CPUCountNum, s.CPUCountErr
+}
+
+func (s *FakeSource) LoadAvg() (load.AvgStat, error) {
+	return s.LoadAvgStat, s.LoadAvgErr
+}
+
+func (s *FakeSource) VirtualMemory() (mem.VirtualMemoryStat, error) {
+	return s.VirtualMemoryStat, s.VirtualMemoryErr
+}
+
+func (s *FakeSource) SwapMemory() (mem.SwapMemoryStat, error) {
+	return s.SwapMemoryStat, s.SwapMemoryErr
+}
+
+func (s *FakeSource) DiskUsage(path string) (disk.UsageStat, error) {
+	usage, found := s.DiskUsageStats[path]
+	if !found {
+		return disk.UsageStat{}, errors.New("Disk not found")
+	}
+
+	return usage, nil
+}
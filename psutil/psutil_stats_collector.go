@@ -0,0 +1,396 @@
+package psutil
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// cpuTicksPerSecond converts gopsutil CPU seconds to ticks of USER_HZ
+const cpuTicksPerSecond = 100
+
+type cgroupMemoryFiles struct {
+	limitPath string
+	usagePath string
+	statPath  string
+
+	// Page cache that can be reclaimed, excluded from usage like free does
+	inactiveFileKey string
+}
+
+var cgroupsMemoryFiles = []cgroupMemoryFiles{
+	{
+		limitPath:       "/sys/fs/cgroup/memory.max",
+		usagePath:       "/sys/fs/cgroup/memory.current",
+		statPath:        "/sys/fs/cgroup/memory.stat",
+		inactiveFileKey: "inactive_file",
+	},
+	{
+		limitPath:       "/sys/fs/cgroup/memory/memory.limit_in_bytes",
+		usagePath:       "/sys/fs/cgroup/memory/memory.usage_in_bytes",
+		statPath:        "/sys/fs/cgroup/memory/memory.stat",
+		inactiveFileKey: "total_inactive_file",
+	},
+}
+
+type cgroupCPUFiles struct {
+	statPath  string
+	userKey   string
+	systemKey string
+
+	// Stat values per tick of USER_HZ
+	unitsPerTick uint64
+
+	// Period path is empty when quota file holds both quota and period
+	quotaPath  string
+	periodPath string
+}
+
+var cgroupsCPUFiles = []cgroupCPUFiles{
+	{
+		statPath:     "/sys/fs/cgroup/cpu.stat",
+		userKey:      "user_usec",
+		systemKey:    "system_usec",
+		unitsPerTick: 1000000 / cpuTicksPerSecond,
+		quotaPath:    "/sys/fs/cgroup/cpu.max",
+	},
+	{
+		statPath:     "/sys/fs/cgroup/cpuacct/cpuacct.stat",
+		userKey:      "user",
+		systemKey:    "system",
+		unitsPerTick: 1,
+		quotaPath:    "/sys/fs/cgroup/cpu/cpu.cfs_quota_us",
+		periodPath:   "/sys/fs/cgroup/cpu/cpu.cfs_period_us",
+	},
+}
+
+type cgroupCPUTimes struct {
+	user   uint64
+	system uint64
+
+	// Number of CPUs cgroup may use during each period
+	limit float64
+}
+
+type psutilStatsCollector struct {
+	source             Source
+	fs                 boshsys.FileSystem
+	latestCPUStats     boshstats.CPUStats
+	latestCPUStatsLock sync.RWMutex
+}
+
+func NewPsutilStatsCollector(source Source, fs boshsys.FileSystem) boshstats.Collector {
+	return &psutilStatsCollector{
+		source: source,
+		fs:     fs,
+	}
+}
+
+// StartCollecting reports CPU usage of cgroup limit when agent runs in a container with a limit lower than host CPUs
+func (s *psutilStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
+	hostCPUs, _ := s.source.CPUCount()
+
+	previous, _ := s.source.CPUTimes()
+	previousCgroup, _ := s.cgroupCPUTimes()
+
+	for range time.Tick(collectionInterval) {
+		current, err := s.source.CPUTimes()
+		if err != nil {
+			continue
+		}
+
+		currentCgroup, cgroupFound := s.cgroupCPUTimes()
+
+		stats := boshstats.CPUStats{
+			User:  cpuTicksBetween(previous.User, current.User),
+			Nice:  cpuTicksBetween(previous.Nice, current.Nice),
+			Sys:   cpuTicksBetween(previous.System, current.System),
+			Wait:  cpuTicksBetween(previous.Iowait, current.Iowait),
+			Total: cpuTicksBetween(previous.Total(), current.Total()),
+		}
+
+		if cgroupFound && hostCPUs > 0 && currentCgroup.limit < float64(hostCPUs) {
+			stats = cgroupCPUStats(previousCgroup, currentCgroup, stats.Total, hostCPUs)
+		}
+
+		s.latestCPUStatsLock.Lock()
+		s.latestCPUStats = stats
+		s.latestCPUStatsLock.Unlock()
+
+		previous = current
+		previousCgroup = currentCgroup
+
+		if latestGotUpdated != nil {
+			latestGotUpdated <- struct{}{}
+		}
+	}
+}
+
+func (s *psutilStatsCollector) GetCPULoad() (load boshstats.CPULoad, err error) {
+	avg, err := s.source.LoadAvg()
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting Load Average")
+		return
+	}
+
+	load.One = avg.Load1
+	load.Five = avg.Load5
+	load.Fifteen = avg.Load15
+
+	return
+}
+
+func (s *psutilStatsCollector) GetCPUStats() (boshstats.CPUStats, error) {
+	s.latestCPUStatsLock.RLock()
+	defer s.latestCPUStatsLock.RUnlock()
+
+	return s.latestCPUStats, nil
+}
+
+// GetMemStats returns usage of cgroup memory limit when agent runs in a container with a limit lower than host memory
+func (s *psutilStatsCollector) GetMemStats() (usage boshstats.Usage, err error) {
+	vmem, err := s.source.VirtualMemory()
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting Virtual Memory")
+		return
+	}
+
+	usage.Total = vmem.Total
+
+	// used excludes buffers and cache
+	usage.Used = vmem.Used
+
+	if cgroupUsage, found := s.cgroupMemStats(); found && cgroupUsage.Total < usage.Total {
+		usage = cgroupUsage
+	}
+
+	return
+}
+
+func (s *psutilStatsCollector) GetSwapStats() (usage boshstats.Usage, err error) {
+	swap, err := s.source.SwapMemory()
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting Swap Memory")
+		return
+	}
+
+	usage.Total = swap.Total
+	usage.Used = swap.Used
+
+	return
+}
+
+func (s *psutilStatsCollector) GetDiskStats(mountedPath string) (stats boshstats.DiskStats, err error) {
+	fsUsage, err := s.source.DiskUsage(mountedPath)
+	if err != nil {
+		err = bosherr.WrapError(err, "Getting File System Usage")
+		return
+	}
+
+	stats.DiskUsage.Total = fsUsage.Total / 1024
+	stats.DiskUsage.Used = fsUsage.Used / 1024
+	stats.InodeUsage.Total = fsUsage.InodesTotal
+	stats.InodeUsage.Used = fsUsage.InodesUsed
+
+	return
+}
+
+// GetDiskIOStats fails since disk I/O rates are only collected by disk I/O stats collector
+func (s *psutilStatsCollector) GetDiskIOStats(mountedPath string) (boshstats.DiskIOStats, error) {
+	return boshstats.DiskIOStats{}, bosherr.Error("Disk I/O is not collected")
+}
+
+// GetNetworkStats returns no interfaces since rates are only collected by network stats collector
+func (s *psutilStatsCollector) GetNetworkStats() (map[string]boshstats.NetworkStats, error) {
+	return map[string]boshstats.NetworkStats{}, nil
+}
+
+// GetSwapIOStats fails since swap rates are only collected by pressure stats collector
+func (s *psutilStatsCollector) GetSwapIOStats() (boshstats.SwapIOStats, error) {
+	return boshstats.SwapIOStats{}, bosherr.Error("Swap I/O is not collected")
+}
+
+// GetPressureStats returns no resources since gopsutil cannot read pressure stall information
+func (s *psutilStatsCollector) GetPressureStats() (map[string]boshstats.Pressure, error) {
+	return map[string]boshstats.Pressure{}, nil
+}
+
+// GetDiskHealth returns no devices since gopsutil cannot read SMART data
+func (s *psutilStatsCollector) GetDiskHealth() (map[string]boshstats.DiskHealth, error) {
+	return map[string]boshstats.DiskHealth{}, nil
+}
+
+// GetGPUStats returns no GPUs since readings are only collected by GPU stats collector
+func (s *psutilStatsCollector) GetGPUStats() (map[string]boshstats.GPUStats, error) {
+	return map[string]boshstats.GPUStats{}, nil
+}
+
+// GetPluginStats returns no jobs since plugins are only run by plugin stats collector
+func (s *psutilStatsCollector) GetPluginStats() (map[string]map[string]string, error) {
+	return map[string]map[string]string{}, nil
+}
+
+// GetSystemLimitsStats reports no resources since they are only read by system limits stats collector
+func (s *psutilStatsCollector) GetSystemLimitsStats() (boshstats.SystemLimitsStats, error) {
+	return boshstats.SystemLimitsStats{}, nil
+}
+
+// GetDNSCacheStats fails since dns cache stats are only collected by dns cache stats collector
+func (s *psutilStatsCollector) GetDNSCacheStats() (boshstats.DNSCacheStats, error) {
+	return boshstats.DNSCacheStats{}, bosherr.Error("DNS cache stats are not collected")
+}
+
+// cgroupMemStats reads cgroup v2 or v1 memory controller of the container
+func (s *psutilStatsCollector) cgroupMemStats() (boshstats.Usage, bool) {
+	for _, files := range cgroupsMemoryFiles {
+		limit, err := s.readCgroupValue(files.limitPath)
+		if err != nil {
+			continue
+		}
+
+		used, err := s.readCgroupValue(files.usagePath)
+		if err != nil {
+			continue
+		}
+
+		inactiveFile := s.readCgroupStat(files.statPath, files.inactiveFileKey)
+		if inactiveFile < used {
+			used -= inactiveFile
+		}
+
+		return boshstats.Usage{Used: used, Total: limit}, true
+	}
+
+	return boshstats.Usage{}, false
+}
+
+// cgroupCPUTimes reads cgroup v2 or v1 cpu controller of the container
+func (s *psutilStatsCollector) cgroupCPUTimes() (cgroupCPUTimes, bool) {
+	for _, files := range cgroupsCPUFiles {
+		limit, err := s.readCgroupCPULimit(files)
+		if err != nil {
+			continue
+		}
+
+		if !s.fs.FileExists(files.statPath) {
+			continue
+		}
+
+		return cgroupCPUTimes{
+			user:   s.readCgroupStat(files.statPath, files.userKey) / files.unitsPerTick,
+			system: s.readCgroupStat(files.statPath, files.systemKey) / files.unitsPerTick,
+			limit:  limit,
+		}, true
+	}
+
+	return cgroupCPUTimes{}, false
+}
+
+// readCgroupCPULimit fails for "max" and -1 since unlimited cgroup may use all host CPUs
+func (s *psutilStatsCollector) readCgroupCPULimit(files cgroupCPUFiles) (float64, error) {
+	contents, err := s.fs.ReadFileString(files.quotaPath)
+	if err != nil {
+		return 0, err
+	}
+
+	// e.g. "50000 100000" in cgroup v2 cpu.max
+	fields := strings.Fields(contents)
+	if len(fields) == 0 {
+		return 0, bosherr.Errorf("Reading CPU quota from '%s'", files.quotaPath)
+	}
+
+	quota, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+
+	var period uint64
+
+	if files.periodPath == "" {
+		if len(fields) < 2 {
+			return 0, bosherr.Errorf("Reading CPU period from '%s'", files.quotaPath)
+		}
+
+		period, err = strconv.ParseUint(fields[1], 10, 64)
+	} else {
+		period, err = s.readCgroupValue(files.periodPath)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if period == 0 {
+		return 0, bosherr.Errorf("Reading CPU period of '%s'", files.quotaPath)
+	}
+
+	return float64(quota) / float64(period), nil
+}
+
+// readCgroupValue fails for "max" since unlimited cgroup does not restrict host memory
+func (s *psutilStatsCollector) readCgroupValue(path string) (uint64, error) {
+	contents, err := s.fs.ReadFileString(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+}
+
+func (s *psutilStatsCollector) readCgroupStat(path, key string) uint64 {
+	contents, err := s.fs.ReadFileString(path)
+	if err != nil {
+		return 0
+	}
+
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == key {
+			value, err := strconv.ParseUint(fields[1], 10, 64)
+			if err == nil {
+				return value
+			}
+		}
+	}
+
+	return 0
+}
+
+// cgroupCPUStats scales host ticks down to share of host CPUs the cgroup may use;
+// cgroup does not account nice and iowait separately
+func cgroupCPUStats(previous, current cgroupCPUTimes, hostTotal uint64, hostCPUs int) boshstats.CPUStats {
+	stats := boshstats.CPUStats{
+		User:  uint64Between(previous.user, current.user),
+		Sys:   uint64Between(previous.system, current.system),
+		Total: uint64(math.Round(float64(hostTotal) * current.limit / float64(hostCPUs))),
+	}
+
+	// Usage may slightly exceed limit since cgroup and host times are not read at once
+	if stats.User+stats.Sys > stats.Total {
+		stats.Total = stats.User + stats.Sys
+	}
+
+	return stats
+}
+
+func uint64Between(previous, current uint64) uint64 {
+	if current < previous {
+		return 0
+	}
+
+	return current - previous
+}
+
+func cpuTicksBetween(previous, current float64) uint64 {
+	if current <= previous {
+		return 0
+	}
+
+	return uint64(math.Round((current - previous) * cpuTicksPerSecond))
+}
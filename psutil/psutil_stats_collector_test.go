@@ -0,0 +1,206 @@
+package psutil_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	boshpsutil "github.com/cloudfoundry/bosh-agent/psutil"
+	fakepsutil "github.com/cloudfoundry/bosh-agent/psutil/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("psutilStatsCollector", func() {
+	var (
+		collector Collector
+		source    *fakepsutil.FakeSource
+		fs        *fakesys.FakeFileSystem
+	)
+
+	BeforeEach(func() {
+		source = &fakepsutil.FakeSource{}
+		fs = fakesys.NewFakeFileSystem()
+		collector = boshpsutil.NewPsutilStatsCollector(source, fs)
+	})
+
+	Describe("GetCPULoad", func() {
+		It("returns cpu load", func() {
+			source.LoadAvgStat = load.AvgStat{Load1: 1, Load5: 5, Load15: 15}
+
+			load, err := collector.GetCPULoad()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(load).To(Equal(CPULoad{One: 1, Five: 5, Fifteen: 15}))
+		})
+
+		It("returns an error when load average cannot be read", func() {
+			source.LoadAvgErr = errors.New("fake-load-err")
+
+			_, err := collector.GetCPULoad()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-load-err"))
+		})
+	})
+
+	Describe("StartCollecting", func() {
+		It("updates cpu stats with ticks spent since previous sample", func() {
+			source.CPUTimesStats = []cpu.TimesStat{
+				{User: 1, Nice: 1, System: 1, Iowait: 1, Idle: 6},
+				{User: 1.1, Nice: 1.2, System: 1.3, Iowait: 1.4, Idle: 6},
+				{User: 2.1, Nice: 3.2, System: 4.3, Iowait: 5.4, Idle: 6},
+			}
+
+			latestGotUpdated := make(chan struct{})
+
+			go collector.StartCollecting(1*time.Millisecond, latestGotUpdated)
+			<-latestGotUpdated
+
+			stats, _ := collector.GetCPUStats()
+			Expect(stats).To(Equal(CPUStats{User: 10, Nice: 20, Sys: 30, Wait: 40, Total: 100}))
+
+			<-latestGotUpdated
+
+			stats, _ = collector.GetCPUStats()
+			Expect(stats).To(Equal(CPUStats{User: 100, Nice: 200, Sys: 300, Wait: 400, Total: 1000}))
+		})
+
+		Context("when running in a cgroup", func() {
+			var latestGotUpdated chan struct{}
+
+			BeforeEach(func() {
+				source.CPUCountNum = 4
+				source.CPUTimesStats = []cpu.TimesStat{
+					{User: 1, Idle: 3},
+					{User: 1, Idle: 3},
+					{User: 2, Idle: 7},
+				}
+
+				latestGotUpdated = make(chan struct{})
+			})
+
+			// collectTwice changes cgroup stat between samples so that second update reports the difference
+			collectTwice := func(statPath, nextStat string) CPUStats {
+				go collector.StartCollecting(100*time.Millisecond, latestGotUpdated)
+				<-latestGotUpdated
+
+				fs.WriteFileString(statPath, nextStat)
+				<-latestGotUpdated
+
+				stats, _ := collector.GetCPUStats()
+				return stats
+			}
+
+			It("updates cpu stats with cgroup v2 usage of its quota", func() {
+				fs.WriteFileString("/sys/fs/cgroup/cpu.max", "100000 100000\n")
+				fs.WriteFileString("/sys/fs/cgroup/cpu.stat", "usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n")
+
+				stats := collectTwice("/sys/fs/cgroup/cpu.stat", "usage_usec 2200000\nuser_usec 1500000\nsystem_usec 700000\n")
+				Expect(stats).To(Equal(CPUStats{User: 50, Sys: 20, Total: 125}))
+			})
+
+			It("updates cpu stats with cgroup v1 usage of its quota", func() {
+				source.CPUCountNum = 2
+				fs.WriteFileString("/sys/fs/cgroup/cpu/cpu.cfs_quota_us", "50000\n")
+				fs.WriteFileString("/sys/fs/cgroup/cpu/cpu.cfs_period_us", "100000\n")
+				fs.WriteFileString("/sys/fs/cgroup/cpuacct/cpuacct.stat", "user 100\nsystem 50\n")
+
+				stats := collectTwice("/sys/fs/cgroup/cpuacct/cpuacct.stat", "user 150\nsystem 70\n")
+				Expect(stats).To(Equal(CPUStats{User: 50, Sys: 20, Total: 125}))
+			})
+
+			It("updates cpu stats with host usage when cgroup is not limited", func() {
+				fs.WriteFileString("/sys/fs/cgroup/cpu.max", "max 100000\n")
+				fs.WriteFileString("/sys/fs/cgroup/cpu.stat", "user_usec 1000000\nsystem_usec 500000\n")
+
+				stats := collectTwice("/sys/fs/cgroup/cpu.stat", "user_usec 1500000\nsystem_usec 700000\n")
+				Expect(stats).To(Equal(CPUStats{User: 100, Total: 500}))
+			})
+		})
+	})
+
+	Describe("GetMemStats", func() {
+		BeforeEach(func() {
+			source.VirtualMemoryStat = mem.VirtualMemoryStat{Total: 1000, Used: 800}
+		})
+
+		It("returns mem stats", func() {
+			stats, err := collector.GetMemStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Usage{Used: 800, Total: 1000}))
+		})
+
+		It("returns cgroup v2 mem stats when limit is lower than host memory", func() {
+			fs.WriteFileString("/sys/fs/cgroup/memory.max", "500\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory.current", "400\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory.stat", "anon 200\ninactive_file 150\n")
+
+			stats, err := collector.GetMemStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Usage{Used: 250, Total: 500}))
+		})
+
+		It("returns cgroup v1 mem stats when limit is lower than host memory", func() {
+			fs.WriteFileString("/sys/fs/cgroup/memory/memory.limit_in_bytes", "600\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory/memory.usage_in_bytes", "300\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory/memory.stat", "cache 100\ntotal_inactive_file 50\n")
+
+			stats, err := collector.GetMemStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Usage{Used: 250, Total: 600}))
+		})
+
+		It("returns host mem stats when cgroup is not limited", func() {
+			fs.WriteFileString("/sys/fs/cgroup/memory.max", "max\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory.current", "400\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory/memory.limit_in_bytes", "9223372036854771712\n")
+			fs.WriteFileString("/sys/fs/cgroup/memory/memory.usage_in_bytes", "300\n")
+
+			stats, err := collector.GetMemStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Usage{Used: 800, Total: 1000}))
+		})
+
+		It("returns an error when memory cannot be read", func() {
+			source.VirtualMemoryErr = errors.New("fake-mem-err")
+
+			_, err := collector.GetMemStats()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-mem-err"))
+		})
+	})
+
+	Describe("GetSwapStats", func() {
+		It("returns swap stats", func() {
+			source.SwapMemoryStat = mem.SwapMemoryStat{Total: 100, Used: 80}
+
+			stats, err := collector.GetSwapStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats).To(Equal(Usage{Used: 80, Total: 100}))
+		})
+	})
+
+	Describe("GetDiskStats", func() {
+		It("returns disk stats with usage in kilobytes", func() {
+			source.DiskUsageStats = map[string]disk.UsageStat{
+				"/fake-mount-path": {Total: 100 * 1024, Used: 80 * 1024, InodesTotal: 1200, InodesUsed: 400},
+			}
+
+			stats, err := collector.GetDiskStats("/fake-mount-path")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats.DiskUsage).To(Equal(Usage{Used: 80, Total: 100}))
+			Expect(stats.InodeUsage).To(Equal(Usage{Used: 400, Total: 1200}))
+		})
+
+		It("returns an error when disk usage cannot be read", func() {
+			_, err := collector.GetDiskStats("/fake-mount-path")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
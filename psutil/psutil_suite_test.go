@@ -0,0 +1,13 @@
+package psutil_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"testing"
+)
+
+func TestPsutil(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Psutil Suite")
+}
@@ -0,0 +1,75 @@
+package psutil
+
+import (
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/disk"
+	"github.com/shirou/gopsutil/v3/load"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// Source is the subset of gopsutil used by stats collector
+type Source interface {
+	CPUTimes() (cpu.TimesStat, error)
+	CPUCount() (int, error)
+	LoadAvg() (load.AvgStat, error)
+	VirtualMemory() (mem.VirtualMemoryStat, error)
+	SwapMemory() (mem.SwapMemoryStat, error)
+	DiskUsage(path string) (disk.UsageStat, error)
+}
+
+type ConcreteSource struct{}
+
+// CPUTimes returns times of all CPUs combined
+func (ConcreteSource) CPUTimes() (cpu.TimesStat, error) {
+	times, err := cpu.Times(false)
+	if err != nil {
+		return cpu.TimesStat{}, err
+	}
+
+	if len(times) == 0 {
+		return cpu.TimesStat{}, nil
+	}
+
+	return times[0], nil
+}
+
+// CPUCount returns number of logical CPUs of the host
+func (ConcreteSource) CPUCount() (int, error) {
+	return cpu.Counts(true)
+}
+
+func (ConcreteSource) LoadAvg() (load.AvgStat, error) {
+	avg, err := load.Avg()
+	if err != nil {
+		return load.AvgStat{}, err
+	}
+
+	return *avg, nil
+}
+
+func (ConcreteSource) VirtualMemory() (mem.VirtualMemoryStat, error) {
+	vmem, err := mem.VirtualMemory()
+	if err != nil {
+		return mem.VirtualMemoryStat{}, err
+	}
+
+	return *vmem, nil
+}
+
+func (ConcreteSource) SwapMemory() (mem.SwapMemoryStat, error) {
+	swap, err := mem.SwapMemory()
+	if err != nil {
+		return mem.SwapMemoryStat{}, err
+	}
+
+	return *swap, nil
+}
+
+func (ConcreteSource) DiskUsage(path string) (disk.UsageStat, error) {
+	usage, err := disk.Usage(path)
+	if err != nil {
+		return disk.UsageStat{}, err
+	}
+
+	return *usage, nil
+}
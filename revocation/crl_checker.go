@@ -0,0 +1,196 @@
+// Package revocation rejects registry servers whose certificates were revoked by
+// an internal PKI, using CRLs from CRL distribution points of the certificates.
+// Only CRLs are supported; OCSP responses (stapled or fetched) are not checked.
+package revocation
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock"
+)
+
+const (
+	crlCheckerLogTag = "crlChecker"
+
+	// CRLs without next update are fetched again after this period
+	defaultCRLCacheDuration = 1 * time.Hour
+)
+
+// Options only apply to the registry client; NATS connections are not encrypted
+// and https mbus does not authenticate the director with a client certificate
+type Options struct {
+	// When set to true certificates of registry are checked against CRLs;
+	// OCSP is never consulted, so certificates without CRL distribution points are not checked
+	Enabled bool
+
+	// When set to true connections are allowed when CRLs cannot be fetched;
+	// certificates listed in fetched CRLs are always rejected
+	SoftFail bool
+}
+
+type Checker interface {
+	// VerifyPeerCertificate is used as tls.Config.VerifyPeerCertificate
+	// and runs after the chain was verified against trusted roots
+	VerifyPeerCertificate(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error
+}
+
+type cachedCRL struct {
+	crl       *x509.RevocationList
+	expiresAt time.Time
+}
+
+type crlChecker struct {
+	httpClient  *http.Client
+	softFail    bool
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	cacheLock *sync.Mutex
+	cache     map[string]cachedCRL
+}
+
+func NewCRLChecker(httpClient *http.Client, softFail bool, timeService clock.Clock, logger boshlog.Logger) Checker {
+	return crlChecker{
+		httpClient:  httpClient,
+		softFail:    softFail,
+		timeService: timeService,
+		logger:      logger,
+
+		cacheLock: &sync.Mutex{},
+		cache:     map[string]cachedCRL{},
+	}
+}
+
+// NewHTTPClient returns registry client that checks revocation of server certificates
+// on top of the given TLS config (if any), or the default client when
+// revocation checking is disabled and no TLS config is given
+func NewHTTPClient(options Options, tlsConfig *tls.Config, timeService clock.Clock, logger boshlog.Logger) *http.Client {
+	if !options.Enabled && tlsConfig == nil {
+		return http.DefaultClient
+	}
+
+	if tlsConfig == nil {
+		tlsConfig = &tls.Config{}
+	} else {
+		tlsConfig = tlsConfig.Clone()
+	}
+
+	if options.Enabled {
+		crlClient := &http.Client{Timeout: 10 * time.Second}
+		checker := NewCRLChecker(crlClient, options.SoftFail, timeService, logger)
+		logger.Info(crlCheckerLogTag, "Checking revocation of registry certificates against CRLs only, OCSP is not checked")
+		tlsConfig.VerifyPeerCertificate = checker.VerifyPeerCertificate
+	}
+
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+}
+
+func (c crlChecker) VerifyPeerCertificate(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	if len(verifiedChains) == 0 {
+		return bosherr.Error("Checking revocation requires verified certificate chain")
+	}
+
+	// Roots are trusted explicitly and are not checked
+	chain := verifiedChains[0]
+
+	for i := 0; i < len(chain)-1; i++ {
+		err := c.check(chain[i], chain[i+1])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c crlChecker) check(cert, issuer *x509.Certificate) error {
+	for _, crlURL := range cert.CRLDistributionPoints {
+		if !strings.HasPrefix(crlURL, "http://") && !strings.HasPrefix(crlURL, "https://") {
+			continue
+		}
+
+		crl, err := c.crl(crlURL, issuer)
+		if err != nil {
+			if c.softFail {
+				c.logger.Warn(crlCheckerLogTag, "Ignoring CRL that cannot be fetched: %s", err.Error())
+				continue
+			}
+
+			return bosherr.WrapErrorf(err, "Checking revocation of certificate '%s'", cert.Subject.CommonName)
+		}
+
+		for _, revoked := range crl.RevokedCertificateEntries {
+			if revoked.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+				return bosherr.Errorf("Certificate '%s' with serial number '%s' was revoked", cert.Subject.CommonName, cert.SerialNumber.String())
+			}
+		}
+
+		return nil
+	}
+
+	return nil
+}
+
+// crl returns CRL from cache until its next update
+func (c crlChecker) crl(crlURL string, issuer *x509.Certificate) (*x509.RevocationList, error) {
+	c.cacheLock.Lock()
+	defer c.cacheLock.Unlock()
+
+	now := c.timeService.Now()
+
+	if cached, found := c.cache[crlURL]; found && now.Before(cached.expiresAt) {
+		return cached.crl, nil
+	}
+
+	c.logger.Debug(crlCheckerLogTag, "Fetching CRL from %s", crlURL)
+
+	response, err := c.httpClient.Get(crlURL)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Fetching CRL from %s", crlURL)
+	}
+
+	defer func() {
+		_ = response.Body.Close()
+	}()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, bosherr.Errorf("Fetching CRL from %s: status code %d", crlURL, response.StatusCode)
+	}
+
+	der, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading CRL from %s", crlURL)
+	}
+
+	crl, err := x509.ParseRevocationList(der)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Parsing CRL from %s", crlURL)
+	}
+
+	err = crl.CheckSignatureFrom(issuer)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Verifying signature of CRL from %s", crlURL)
+	}
+
+	expiresAt := now.Add(defaultCRLCacheDuration)
+	if !crl.NextUpdate.IsZero() {
+		expiresAt = crl.NextUpdate
+	}
+
+	c.cache[crlURL] = cachedCRL{crl: crl, expiresAt: expiresAt}
+
+	return crl, nil
+}
@@ -0,0 +1,183 @@
+package revocation_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/revocation"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	"github.com/pivotal-golang/clock/fakeclock"
+)
+
+var _ = Describe("CRLChecker", func() {
+	var (
+		timeService *fakeclock.FakeClock
+		caKey       *ecdsa.PrivateKey
+		caCert      *x509.Certificate
+		leafCert    *x509.Certificate
+		revoked     []x509.RevocationListEntry
+		crlRequests int
+		crlServer   *httptest.Server
+		logger      boshlog.Logger
+	)
+
+	BeforeEach(func() {
+		timeService = fakeclock.NewFakeClock(time.Now())
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		revoked = nil
+		crlRequests = 0
+
+		var err error
+		caKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		caTemplate := &x509.Certificate{
+			SerialNumber:          big.NewInt(1),
+			Subject:               pkix.Name{CommonName: "fake-ca"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			IsCA:                  true,
+			BasicConstraintsValid: true,
+			KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		}
+
+		caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		caCert, err = x509.ParseCertificate(caDER)
+		Expect(err).ToNot(HaveOccurred())
+
+		crlServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			crlRequests++
+
+			crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+				Number:                    big.NewInt(int64(crlRequests)),
+				ThisUpdate:                timeService.Now(),
+				NextUpdate:                timeService.Now().Add(time.Hour),
+				RevokedCertificateEntries: revoked,
+			}, caCert, caKey)
+			Expect(err).ToNot(HaveOccurred())
+
+			_, _ = w.Write(crl)
+		}))
+
+		leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		leafTemplate := &x509.Certificate{
+			SerialNumber:          big.NewInt(42),
+			Subject:               pkix.Name{CommonName: "fake-registry"},
+			NotBefore:             time.Now().Add(-time.Hour),
+			NotAfter:              time.Now().Add(time.Hour),
+			CRLDistributionPoints: []string{crlServer.URL + "/ca.crl"},
+		}
+
+		leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, caCert, leafKey.Public(), caKey)
+		Expect(err).ToNot(HaveOccurred())
+
+		leafCert, err = x509.ParseCertificate(leafDER)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		crlServer.Close()
+	})
+
+	verify := func(checker Checker) error {
+		return checker.VerifyPeerCertificate(nil, [][]*x509.Certificate{{leafCert, caCert}})
+	}
+
+	It("accepts certificates that are not revoked", func() {
+		checker := NewCRLChecker(http.DefaultClient, false, timeService, logger)
+		Expect(verify(checker)).To(Succeed())
+	})
+
+	It("rejects certificates listed in CRL of their issuer", func() {
+		revoked = []x509.RevocationListEntry{{SerialNumber: big.NewInt(42), RevocationTime: time.Now()}}
+
+		checker := NewCRLChecker(http.DefaultClient, false, timeService, logger)
+
+		err := verify(checker)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Certificate 'fake-registry' with serial number '42' was revoked"))
+	})
+
+	It("caches CRL until its next update", func() {
+		checker := NewCRLChecker(http.DefaultClient, false, timeService, logger)
+
+		Expect(verify(checker)).To(Succeed())
+		Expect(verify(checker)).To(Succeed())
+		Expect(crlRequests).To(Equal(1))
+
+		timeService.Increment(2 * time.Hour)
+
+		Expect(verify(checker)).To(Succeed())
+		Expect(crlRequests).To(Equal(2))
+	})
+
+	Context("when CRL cannot be fetched", func() {
+		BeforeEach(func() {
+			crlServer.Close()
+		})
+
+		It("rejects certificates", func() {
+			checker := NewCRLChecker(http.DefaultClient, false, timeService, logger)
+
+			err := verify(checker)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Checking revocation of certificate 'fake-registry'"))
+		})
+
+		It("accepts certificates with soft fail", func() {
+			checker := NewCRLChecker(http.DefaultClient, true, timeService, logger)
+			Expect(verify(checker)).To(Succeed())
+		})
+	})
+
+	It("rejects CRL that is not signed by the issuer", func() {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		Expect(err).ToNot(HaveOccurred())
+
+		caKey = otherKey
+
+		checker := NewCRLChecker(http.DefaultClient, false, timeService, logger)
+
+		err = verify(checker)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Verifying signature of CRL"))
+	})
+})
+
+var _ = Describe("NewHTTPClient", func() {
+	It("returns default client when revocation checking is disabled", func() {
+		client := NewHTTPClient(Options{}, nil, fakeclock.NewFakeClock(time.Now()), boshlog.NewLogger(boshlog.LevelNone))
+		Expect(client == http.DefaultClient).To(BeTrue())
+	})
+
+	It("returns client that checks revocation of server certificates", func() {
+		client := NewHTTPClient(Options{Enabled: true}, nil, fakeclock.NewFakeClock(time.Now()), boshlog.NewLogger(boshlog.LevelNone))
+		Expect(client.Transport.(*http.Transport).TLSClientConfig.VerifyPeerCertificate).ToNot(BeNil())
+	})
+
+	It("returns client restricted to the given TLS config", func() {
+		tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+		client := NewHTTPClient(Options{Enabled: true}, tlsConfig, fakeclock.NewFakeClock(time.Now()), boshlog.NewLogger(boshlog.LevelNone))
+
+		clientTLSConfig := client.Transport.(*http.Transport).TLSClientConfig
+		Expect(clientTLSConfig.MinVersion).To(Equal(uint16(tls.VersionTLS12)))
+		Expect(clientTLSConfig.VerifyPeerCertificate).ToNot(BeNil())
+		Expect(tlsConfig.VerifyPeerCertificate).To(BeNil())
+	})
+})
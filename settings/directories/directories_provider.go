@@ -34,6 +34,31 @@ func (p Provider) StoreMigrationDir() string {
 	return path.Join(p.BaseDir(), "store_migration_target")
 }
 
+// PersistentDiskMountPoint returns where a persistent disk with given
+// mount point hint is mounted; disks without a hint are mounted on the store dir
+// and relative hints are resolved against the base dir
+func (p Provider) PersistentDiskMountPoint(mountPointHint string) string {
+	if mountPointHint == "" {
+		return p.StoreDir()
+	}
+
+	if path.IsAbs(mountPointHint) {
+		return path.Clean(mountPointHint)
+	}
+
+	return path.Join(p.BaseDir(), mountPointHint)
+}
+
+// MigrationDir returns where a new persistent disk is temporarily mounted
+// while data is migrated to it from the disk mounted on mountPoint
+func (p Provider) MigrationDir(mountPoint string) string {
+	if mountPoint == p.StoreDir() {
+		return p.StoreMigrationDir()
+	}
+
+	return mountPoint + "_migration_target"
+}
+
 func (p Provider) PkgDir() string {
 	return path.Join(p.DataDir(), "packages")
 }
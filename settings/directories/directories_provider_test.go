@@ -0,0 +1,40 @@
+package directories_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/settings/directories"
+)
+
+var _ = Describe("Provider", func() {
+	var provider Provider
+
+	BeforeEach(func() {
+		provider = NewProvider("/var/vcap")
+	})
+
+	Describe("PersistentDiskMountPoint", func() {
+		It("returns store dir when there is no mount point hint", func() {
+			Expect(provider.PersistentDiskMountPoint("")).To(Equal("/var/vcap/store"))
+		})
+
+		It("returns absolute mount point hint as is", func() {
+			Expect(provider.PersistentDiskMountPoint("/data/db/")).To(Equal("/data/db"))
+		})
+
+		It("resolves relative mount point hint against base dir", func() {
+			Expect(provider.PersistentDiskMountPoint("store-logs")).To(Equal("/var/vcap/store-logs"))
+		})
+	})
+
+	Describe("MigrationDir", func() {
+		It("returns store migration dir for store dir", func() {
+			Expect(provider.MigrationDir("/var/vcap/store")).To(Equal("/var/vcap/store_migration_target"))
+		})
+
+		It("returns sibling migration dir for other mount points", func() {
+			Expect(provider.MigrationDir("/data/db")).To(Equal("/data/db_migration_target"))
+		})
+	})
+})
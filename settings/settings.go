@@ -2,7 +2,13 @@ package settings
 
 import (
 	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/cloudfoundry/bosh-agent/platform/disk"
+	"github.com/cloudfoundry/bosh-agent/platform/firewall"
 )
 
 const (
@@ -11,6 +17,8 @@ const (
 	AdminGroup          = "admin"
 	SudoersGroup        = "bosh_sudoers"
 	EphemeralUserPrefix = "bosh_"
+
+	sshPort = 22
 )
 
 type Settings struct {
@@ -62,6 +70,59 @@ type DiskSettings struct {
 	VolumeID       string
 	Path           string
 	FileSystemType disk.FileSystemType
+
+	// Additional arguments passed to mkfs when formatting the disk
+	MkfsOptions []string
+	MkfsTuning  disk.MkfsTuning
+
+	// Options passed to mount, e.g. ["noatime", "discard"]. Agent mounts disks
+	// on every boot and on migration itself, so they are not written to fstab.
+	MountOptions []string
+
+	// Mount point hint; disks without one are mounted on the store dir
+	MountPoint string
+
+	// LUKS passphrase; EncryptionKeyRef is resolved to a passphrase
+	// through the configured key provider when no key is given
+	EncryptionKey    EncryptionKey
+	EncryptionKeyRef string
+
+	// Set for disks attached over iSCSI
+	ISCSISettings *ISCSISettings
+
+	// Set by Azure CPI; LUN of the disk on the SCSI controller identified by HostDeviceID
+	Lun          string
+	HostDeviceID string
+}
+
+type ISCSISettings struct {
+	InitiatorName string
+	Target        string
+	IQN           string
+	LUN           string
+
+	// CHAP credentials; no authentication is used when Username is empty
+	Username string
+	Password string
+}
+
+// String omits the CHAP password so disk settings can be logged
+func (s ISCSISettings) String() string {
+	return fmt.Sprintf("{InitiatorName:%s Target:%s IQN:%s LUN:%s Username:%s}", s.InitiatorName, s.Target, s.IQN, s.LUN, s.Username)
+}
+
+func (s DiskSettings) IsEncrypted() bool {
+	return s.EncryptionKey != "" || s.EncryptionKeyRef != ""
+}
+
+// EncryptionKey is redacted when formatted so disk settings can be logged
+type EncryptionKey string
+
+func (k EncryptionKey) String() string {
+	if k == "" {
+		return ""
+	}
+	return "<redacted>"
 }
 
 type VM struct {
@@ -85,6 +146,27 @@ func (s Settings) PersistentDiskSettings(diskID string) (DiskSettings, bool) {
 				if deviceID, ok := hashSettings["id"]; ok {
 					diskSettings.DeviceID = deviceID.(string)
 				}
+				if mountPoint, ok := hashSettings["mount_point"]; ok {
+					diskSettings.MountPoint = mountPoint.(string)
+				}
+				if mountOptions, ok := hashSettings["mount_options"]; ok {
+					diskSettings.MountOptions = parseMountOptions(mountOptions)
+				}
+				if lun, ok := hashSettings["lun"]; ok {
+					diskSettings.Lun = fmt.Sprint(lun)
+				}
+				if hostDeviceID, ok := hashSettings["host_device_id"]; ok {
+					diskSettings.HostDeviceID = hostDeviceID.(string)
+				}
+				if iscsiSettings, ok := hashSettings["iscsi_settings"]; ok {
+					diskSettings.ISCSISettings = parseISCSISettings(iscsiSettings)
+				}
+				if key, ok := hashSettings["encryption_key"]; ok {
+					diskSettings.EncryptionKey = EncryptionKey(key.(string))
+				}
+				if keyRef, ok := hashSettings["encryption_key_ref"]; ok {
+					diskSettings.EncryptionKeyRef = keyRef.(string)
+				}
 			} else {
 				// Old CPIs return disk path (string) or volume id (string) as disk settings
 				diskSettings.Path = settings.(string)
@@ -92,6 +174,11 @@ func (s Settings) PersistentDiskSettings(diskID string) (DiskSettings, bool) {
 			}
 
 			diskSettings.FileSystemType = s.Env.PersistentDiskFS
+			diskSettings.MkfsOptions = s.Env.PersistentDiskMkfsOptions
+			diskSettings.MkfsTuning = s.Env.PersistentDiskMkfsTuning
+			if diskSettings.MountOptions == nil {
+				diskSettings.MountOptions = s.Env.PersistentDiskMountOptions
+			}
 			return diskSettings, true
 		}
 	}
@@ -99,6 +186,45 @@ func (s Settings) PersistentDiskSettings(diskID string) (DiskSettings, bool) {
 	return diskSettings, false
 }
 
+func parseMountOptions(options interface{}) []string {
+	optionValues, ok := options.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	mountOptions := make([]string, 0, len(optionValues))
+	for _, option := range optionValues {
+		mountOptions = append(mountOptions, fmt.Sprint(option))
+	}
+
+	return mountOptions
+}
+
+func parseISCSISettings(settings interface{}) *ISCSISettings {
+	hashSettings, ok := settings.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+
+	// LUN may be given as a number
+	stringValue := func(key string) string {
+		value, found := hashSettings[key]
+		if !found || value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+
+	return &ISCSISettings{
+		InitiatorName: stringValue("initiator_name"),
+		Target:        stringValue("target"),
+		IQN:           stringValue("iqn"),
+		LUN:           stringValue("lun"),
+		Username:      stringValue("username"),
+		Password:      stringValue("password"),
+	}
+}
+
 func (s Settings) EphemeralDiskSettings() DiskSettings {
 	diskSettings := DiskSettings{}
 
@@ -113,6 +239,15 @@ func (s Settings) EphemeralDiskSettings() DiskSettings {
 			if deviceID, ok := hashSettings["id"]; ok {
 				diskSettings.DeviceID = deviceID.(string)
 			}
+			if mountOptions, ok := hashSettings["mount_options"]; ok {
+				diskSettings.MountOptions = parseMountOptions(mountOptions)
+			}
+			if lun, ok := hashSettings["lun"]; ok {
+				diskSettings.Lun = fmt.Sprint(lun)
+			}
+			if hostDeviceID, ok := hashSettings["host_device_id"]; ok {
+				diskSettings.HostDeviceID = hostDeviceID.(string)
+			}
 		} else {
 			// Old CPIs return disk path (string) or volume id (string) as disk settings
 			diskSettings.Path = s.Disks.Ephemeral.(string)
@@ -120,9 +255,104 @@ func (s Settings) EphemeralDiskSettings() DiskSettings {
 		}
 	}
 
+	diskSettings.FileSystemType = s.Env.EphemeralDiskFS
+	diskSettings.MkfsOptions = s.Env.EphemeralDiskMkfsOptions
+	diskSettings.MkfsTuning = s.Env.EphemeralDiskMkfsTuning
+	if diskSettings.MountOptions == nil {
+		diskSettings.MountOptions = s.Env.EphemeralDiskMountOptions
+	}
+
 	return diskSettings
 }
 
+// ProxySettings returns proxy from env with localhost, NATS and blobstore hosts added to no_proxy
+// so that agent and jobs keep reaching them directly; director is expected in configured no_proxy
+func (s Settings) ProxySettings() Proxy {
+	proxy := s.Env.GetProxy()
+	if proxy.IsEmpty() {
+		return proxy
+	}
+
+	hosts := append([]string{}, proxy.NoProxy...)
+	hosts = append(hosts, "localhost", "127.0.0.1", proxyBypassHost(s.Mbus))
+
+	// e.g. dav blobstore "endpoint" URL or s3 blobstore "host"
+	for _, option := range []string{"endpoint", "host"} {
+		if value, ok := s.Blobstore.Options[option].(string); ok {
+			hosts = append(hosts, proxyBypassHost(value))
+		}
+	}
+
+	proxy.NoProxy = []string{}
+	seenHosts := map[string]bool{}
+
+	for _, host := range hosts {
+		if host == "" || seenHosts[host] {
+			continue
+		}
+		seenHosts[host] = true
+		proxy.NoProxy = append(proxy.NoProxy, host)
+	}
+
+	return proxy
+}
+
+// FirewallSettings returns firewall from env with SSH, https mbus and metrics ports added to allowed ports
+// so that enabling the firewall never locks the director, operators or scrapers out of the VM
+func (s Settings) FirewallSettings() Firewall {
+	fw := s.Env.GetFirewall()
+	if !fw.Enabled {
+		return fw
+	}
+
+	requiredPorts := []firewall.Port{{Protocol: "tcp", Port: sshPort}}
+
+	mbusURL, err := url.Parse(s.Mbus)
+	if err == nil && mbusURL.Scheme == "https" {
+		if port, err := strconv.Atoi(mbusURL.Port()); err == nil {
+			requiredPorts = append(requiredPorts, firewall.Port{Protocol: "tcp", Port: port})
+		}
+	}
+
+	if metrics := s.Env.GetMetrics(); metrics.Enabled {
+		requiredPorts = append(requiredPorts, firewall.Port{Protocol: "tcp", Port: metrics.ListenPort()})
+	}
+
+	allowedPorts := append([]firewall.Port{}, fw.AllowedPorts...)
+
+	for _, requiredPort := range requiredPorts {
+		allowed := false
+		for _, port := range fw.AllowedPorts {
+			if port.Port == requiredPort.Port && (port.Protocol == "" || port.Protocol == requiredPort.Protocol) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			allowedPorts = append(allowedPorts, requiredPort)
+		}
+	}
+
+	fw.AllowedPorts = allowedPorts
+
+	return fw
+}
+
+// proxyBypassHost returns host of an URL or of a host with optional port
+func proxyBypassHost(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "//" + address
+	}
+
+	parsedURL, err := url.Parse(address)
+	if err != nil {
+		return ""
+	}
+
+	return parsedURL.Hostname()
+}
+
 func (s Settings) RawEphemeralDiskSettings() (devices []DiskSettings) {
 	return s.Disks.RawEphemeral
 }
@@ -130,6 +360,19 @@ func (s Settings) RawEphemeralDiskSettings() (devices []DiskSettings) {
 type Env struct {
 	Bosh             BoshEnv             `json:"bosh"`
 	PersistentDiskFS disk.FileSystemType `json:"persistent_disk_fs"`
+	EphemeralDiskFS  disk.FileSystemType `json:"ephemeral_disk_fs"`
+
+	// e.g. ["-m", "1"] for ext4, ["-i", "size=512"] for xfs
+	PersistentDiskMkfsOptions []string `json:"persistent_disk_mkfs_options"`
+	EphemeralDiskMkfsOptions  []string `json:"ephemeral_disk_mkfs_options"`
+
+	// e.g. {"reserved_blocks_percent": 0, "bytes_per_inode": 8192, "lazy_init": false}
+	PersistentDiskMkfsTuning disk.MkfsTuning `json:"persistent_disk_mkfs_tuning"`
+	EphemeralDiskMkfsTuning  disk.MkfsTuning `json:"ephemeral_disk_mkfs_tuning"`
+
+	// Default mount options for disks that do not specify their own, e.g. ["noatime", "discard"]
+	PersistentDiskMountOptions []string `json:"persistent_disk_mount_options"`
+	EphemeralDiskMountOptions  []string `json:"ephemeral_disk_mount_options"`
 }
 
 func (e Env) GetPassword() string {
@@ -144,17 +387,300 @@ func (e Env) GetRemoveDevTools() bool {
 	return e.Bosh.RemoveDevTools
 }
 
+func (e Env) GetHugePages() HugePages {
+	return e.Bosh.HugePages
+}
+
+func (e Env) GetTimezone() string {
+	return e.Bosh.Timezone
+}
+
+func (e Env) GetLocale() string {
+	return e.Bosh.Locale
+}
+
+func (e Env) GetKernelArgs() KernelArgs {
+	return e.Bosh.KernelArgs
+}
+
+func (e Env) GetTuningProfile() TuningProfile {
+	return e.Bosh.TuningProfile
+}
+
+func (e Env) GetHostsEntries() []HostsEntry {
+	return e.Bosh.HostsEntries
+}
+
+func (e Env) GetFIPS() bool {
+	return e.Bosh.FIPS
+}
+
+func (e Env) GetDiskUsageThresholds() DiskUsageThresholds {
+	return e.Bosh.DiskUsageThresholds
+}
+
+func (e Env) GetFirewall() Firewall {
+	return e.Bosh.Firewall
+}
+
+func (e Env) GetNetworkValidation() NetworkValidation {
+	return e.Bosh.NetworkValidation
+}
+
+func (e Env) GetProxy() Proxy {
+	return e.Bosh.Proxy
+}
+
+func (e Env) GetDNSCache() DNSCache {
+	return e.Bosh.DNSCache
+}
+
+func (e Env) GetMetrics() Metrics {
+	return e.Bosh.Metrics
+}
+
+func (e Env) GetTelemetry() Telemetry {
+	return e.Bosh.Telemetry
+}
+
+// GetJobSupervisor returns empty string when agent's default job supervisor should be used
+func (e Env) GetJobSupervisor() string {
+	return e.Bosh.JobSupervisor
+}
+
+func (e Env) GetIdentityKey() IdentityKey {
+	return e.Bosh.IdentityKey
+}
+
+// GetCertificateExpiryWarningDays defaults to 30 days; negative values disable expiry alerts
+func (e Env) GetCertificateExpiryWarningDays() int {
+	if e.Bosh.CertificateExpiryWarningDays == 0 {
+		return defaultCertificateExpiryWarningDays
+	}
+
+	return e.Bosh.CertificateExpiryWarningDays
+}
+
 type BoshEnv struct {
-	Password         string `json:"password"`
-	KeepRootPassword bool   `json:"keep_root_password"`
-	RemoveDevTools   bool   `json:"remove_dev_tools"`
+	Password         string    `json:"password"`
+	KeepRootPassword bool      `json:"keep_root_password"`
+	RemoveDevTools   bool      `json:"remove_dev_tools"`
+	HugePages        HugePages `json:"huge_pages"`
+
+	// e.g. "Etc/UTC", "America/Los_Angeles"
+	Timezone string `json:"timezone"`
+
+	// e.g. "en_US.UTF-8"
+	Locale string `json:"locale"`
+
+	KernelArgs KernelArgs `json:"kernel_args"`
+
+	TuningProfile TuningProfile `json:"tuning_profile"`
+
+	HostsEntries []HostsEntry `json:"hosts"`
+
+	// Restricts TLS and blob digests to FIPS 140-2 approved algorithms;
+	// NATS message bus is refused since it does not use TLS
+	FIPS bool `json:"fips"`
+
+	DiskUsageThresholds DiskUsageThresholds `json:"disk_usage_thresholds"`
+
+	Firewall Firewall `json:"firewall"`
+
+	NetworkValidation NetworkValidation `json:"network_validation"`
+
+	Proxy Proxy `json:"proxy"`
+
+	DNSCache DNSCache `json:"dns_cache"`
+
+	// Keeps private key of agent identity used by https mbus in a hardware device
+	IdentityKey IdentityKey `json:"identity_key"`
+
+	// Agent alerts when mbus certificate or trusted certificates expire in fewer days
+	CertificateExpiryWarningDays int `json:"certificate_expiry_warning_days"`
+
+	Metrics Metrics `json:"metrics"`
+
+	Telemetry Telemetry `json:"telemetry"`
+
+	// Possible values: monit, systemd, runit (defaults to job supervisor agent was started with)
+	JobSupervisor string `json:"job_supervisor"`
+}
+
+const defaultCertificateExpiryWarningDays = 30
+
+const (
+	IdentityKeyTypeFile   = "file"
+	IdentityKeyTypeTPM    = "tpm"
+	IdentityKeyTypePKCS11 = "pkcs11"
+)
+
+type IdentityKey struct {
+	// Possible values: file, tpm, pkcs11 (defaults to file, i.e. agent.key next to agent.cert)
+	Type string `json:"type"`
+
+	// Reference to the key in the device, e.g. TPM persistent handle "0x81010001" or PKCS#11 key id
+	KeyID string `json:"key_id"`
+
+	// Program and its arguments that signs digest read from stdin with the key in the device
+	// and prints base64 encoded signature; key id, hash (e.g. sha256) and signature scheme
+	// (pkcs1v15, pss or ecdsa) are appended as arguments, e.g. ["/var/vcap/bosh/bin/tpm-sign"]
+	SignCommand []string `json:"sign_command"`
+}
+
+// DeviceBacked returns true when signing is delegated to a device
+// instead of using the private key from disk
+func (k IdentityKey) DeviceBacked() bool {
+	return k.Type != "" && k.Type != IdentityKeyTypeFile
+}
+
+type DNSCache struct {
+	// Resolves through local dnsmasq that caches answers of dns servers of the default dns network
+	Enabled bool `json:"enabled"`
+
+	// Number of cached names, defaults to 10000
+	CacheSize int `json:"cache_size"`
+}
+
+type Metrics struct {
+	// Serves vitals and disk/network counters in Prometheus format;
+	// port is allowed through the firewall when it is enabled
+	Enabled bool `json:"enabled"`
+
+	// Defaults to 9100, port of Prometheus node_exporter
+	Port int `json:"port"`
+
+	// PEM encoded; metrics are served over plain http when not set
+	Certificate string `json:"certificate"`
+	PrivateKey  string `json:"private_key"`
+
+	// Basic auth credentials required from scrapers when set; require certificate
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// DefaultMetricsPort is the port of Prometheus node_exporter
+const DefaultMetricsPort = 9100
+
+// ListenPort returns configured port or the default port
+func (m Metrics) ListenPort() int {
+	if m.Port == 0 {
+		return DefaultMetricsPort
+	}
+
+	return m.Port
+}
+
+type Telemetry struct {
+	// OTLP/HTTP collector endpoint, e.g. "https://otel-collector.internal:4318";
+	// metrics and traces are exported only when set
+	Endpoint string `json:"endpoint"`
+
+	// Sent with every export, e.g. {"Authorization": "Bearer ..."}
+	Headers map[string]string `json:"headers"`
+
+	// PEM encoded; system CAs are used to verify collector when not set
+	CACert string `json:"ca_cert"`
+
+	// Defaults to 60
+	ExportIntervalInSeconds int `json:"export_interval_in_seconds"`
+}
+
+type Proxy struct {
+	// e.g. "http://proxy.internal:3128"
+	HTTPProxy  string `json:"http_proxy"`
+	HTTPSProxy string `json:"https_proxy"`
+
+	// Hosts, domains and networks reached directly, e.g. ["director.internal", ".internal", "10.0.0.0/8"]
+	NoProxy []string `json:"no_proxy"`
+}
+
+func (p Proxy) IsEmpty() bool {
+	return p.HTTPProxy == "" && p.HTTPSProxy == ""
+}
+
+type NetworkValidation struct {
+	// Fails bootstrap when default gateway is not reachable or probe hostname does not resolve
+	// after networking is set up instead of timing out on connecting to the director later
+	Enabled bool `json:"enabled"`
+
+	// Hostname resolved through configured dns servers, e.g. "director.internal"; dns is not checked when empty
+	ProbeHostname string `json:"probe_hostname"`
+}
+
+type Firewall struct {
+	// Drops inbound traffic except to allowed ports and ports that jobs declare in the apply spec
+	Enabled bool `json:"enabled"`
+
+	// Ports allowed regardless of jobs, e.g. [{"protocol": "udp", "port": 123}];
+	// SSH and https mbus ports are always allowed
+	AllowedPorts []firewall.Port `json:"allowed_ports"`
+}
+
+// DiskUsageThresholds are usage percentages keyed by disk (system, ephemeral or persistent)
+// above which the agent sends an alert, e.g. {"persistent": 90}
+type DiskUsageThresholds map[string]int
+
+type HostsEntry struct {
+	IP        string   `json:"ip"`
+	Hostnames []string `json:"hostnames"`
+}
+
+type KernelArgs struct {
+	// e.g. ["transparent_hugepage=never", "isolcpus=2,3"]
+	Add []string `json:"add"`
+
+	// Either exact parameters or parameter names, e.g. ["quiet", "isolcpus"]
+	Remove []string `json:"remove"`
+}
+
+func (a KernelArgs) IsEmpty() bool {
+	return len(a.Add) == 0 && len(a.Remove) == 0
+}
+
+type TuningProfile struct {
+	// Informational name of the profile, e.g. "low-latency"
+	Name string `json:"name"`
+
+	// cpufreq scaling governor applied to all CPUs, e.g. "performance"
+	CPUGovernor string `json:"cpu_governor"`
+
+	// Transparent huge pages mode: "always", "madvise" or "never"
+	TransparentHugePages string `json:"transparent_hugepages"`
+
+	// IRQ number to CPU list, e.g. {"24": "0-1"}
+	IRQAffinity map[string]string `json:"irq_affinity"`
+}
+
+func (t TuningProfile) IsEmpty() bool {
+	return t.CPUGovernor == "" && t.TransparentHugePages == "" && len(t.IRQAffinity) == 0
+}
+
+type HugePages struct {
+	// Number of huge pages to reserve; zero leaves the kernel default untouched
+	Count int `json:"count"`
+
+	// e.g. "2M", "1G"; empty uses the kernel default huge page size
+	PageSize string `json:"page_size"`
+
+	// Where hugetlbfs should be mounted (defaults to /dev/hugepages)
+	MountPath string `json:"mount_path"`
 }
 
 type NetworkType string
 
 const (
-	NetworkTypeDynamic NetworkType = "dynamic"
-	NetworkTypeVIP     NetworkType = "vip"
+	NetworkTypeDynamic   NetworkType = "dynamic"
+	NetworkTypeVIP       NetworkType = "vip"
+	NetworkTypeWireGuard NetworkType = "wireguard"
+)
+
+// IPv6Mode is a way a dynamic network acquires its IPv6 address
+type IPv6Mode string
+
+const (
+	IPv6ModeDHCPv6 IPv6Mode = "dhcpv6"
+	IPv6ModeSLAAC  IPv6Mode = "slaac"
 )
 
 type Network struct {
@@ -166,14 +692,140 @@ type Network struct {
 	Resolved bool   `json:"resolved"` // was resolved via DHCP
 	UseDHCP  bool   `json:"use_dhcp"`
 
+	// IPv6Mode acquires IPv6 address of dynamic network in addition to IPv4 one
+	IPv6Mode IPv6Mode `json:"ipv6_mode"`
+
 	Default []string `json:"default"`
 	DNS     []string `json:"dns"`
+	// Domains are dns search domains used together with dns servers of the network
+	Domains []string `json:"domains"`
 
 	Mac string `json:"mac"`
+	// VLANID tags traffic of the network on the interface with the above MAC address
+	VLANID int `json:"vlan_id"`
+
+	// Bond places the network on a bonded interface instead of the interface with the above MAC address
+	Bond *Bond `json:"bond"`
+
+	// Bridge is a name of the Linux bridge (e.g. br0) that gets the interface attached and carries its address
+	Bridge string `json:"bridge"`
+
+	// MTU overrides maximum transmission unit of the interface (e.g. 9000 for jumbo frames)
+	MTU int `json:"mtu"`
+
+	// Routes are static routes added in addition to the default route of the network
+	Routes []Route `json:"routes"`
+
+	// SRIOV creates virtual functions on the interface with the above MAC address
+	SRIOV *SRIOV `json:"sriov"`
+
+	// AddressAnnouncement overrides how addresses of the network are announced to neighbors
+	AddressAnnouncement AddressAnnouncement `json:"address_announcement"`
+
+	// WireGuard configures interface of a wireguard network, IP and Netmask are its overlay address
+	WireGuard *WireGuard `json:"wireguard"`
+
+	// Tuning overrides kernel and driver parameters of the interface
+	Tuning InterfaceTuning `json:"tuning"`
+
+	// Aliases are secondary addresses of the interface that carries IP of the network,
+	// either in CIDR notation or plain addresses that use netmask of the network
+	Aliases []string `json:"aliases"`
 
 	Preconfigured bool `json:"preconfigured"`
 }
 
+type Route struct {
+	// Destination is a network in CIDR notation (e.g. 10.0.0.0/8)
+	Destination string `json:"destination"`
+	// Gateway may be omitted for destinations reachable directly on the interface
+	Gateway string `json:"gateway"`
+	// Interface defaults to the interface of the network
+	Interface string `json:"interface"`
+}
+
+type Bond struct {
+	// Mode is a bonding mode (e.g. 802.3ad for LACP, active-backup)
+	Mode string `json:"mode"`
+	// Miimon is a link monitoring frequency in milliseconds
+	Miimon int `json:"miimon"`
+	// Slaves are MAC addresses of physical interfaces that are bonded
+	Slaves []string `json:"slaves"`
+}
+
+// AddressAnnouncementStrategy is a way addresses are announced to neighbors after they are configured
+type AddressAnnouncementStrategy string
+
+const (
+	// AddressAnnouncementArping sends gratuitous ARP for IPv4 addresses
+	AddressAnnouncementArping AddressAnnouncementStrategy = "arping"
+	// AddressAnnouncementNdisc6 additionally solicits IPv6 gateway from IPv6 addresses
+	AddressAnnouncementNdisc6 AddressAnnouncementStrategy = "ndisc6"
+	// AddressAnnouncementNone does not announce addresses
+	AddressAnnouncementNone AddressAnnouncementStrategy = "none"
+)
+
+type AddressAnnouncement struct {
+	// Strategy defaults to arping
+	Strategy AddressAnnouncementStrategy `json:"strategy"`
+	// Iterations is a number of announcements of each address, defaults to 20
+	Iterations int `json:"iterations"`
+	// Delay between iterations in milliseconds, defaults to 5000
+	Delay int `json:"delay"`
+}
+
+func (a AddressAnnouncement) IsEmpty() bool {
+	return a.Strategy == "" && a.Iterations == 0 && a.Delay == 0
+}
+
+// InterfaceTuning is left unset to keep defaults of the kernel and driver
+type InterfaceTuning struct {
+	// RPFilter is a reverse path filtering mode: 0 disables it, 1 is strict and 2 is loose
+	RPFilter *int `json:"rp_filter"`
+	// GRO enables or disables generic receive offload
+	GRO *bool `json:"gro"`
+	// TxQueueLen is a length of the transmit queue of the interface
+	TxQueueLen int `json:"txqueuelen"`
+}
+
+func (t InterfaceTuning) IsEmpty() bool {
+	return t.RPFilter == nil && t.GRO == nil && t.TxQueueLen == 0
+}
+
+type SRIOV struct {
+	// NumVFs is a number of virtual functions created on the physical function
+	NumVFs int `json:"num_vfs"`
+	// VFs are assignments of virtual functions in order of their index
+	VFs []SRIOVVirtualFunction `json:"vfs"`
+}
+
+type SRIOVVirtualFunction struct {
+	// MAC is left empty to keep MAC address assigned by the driver
+	MAC string `json:"mac"`
+	// VLANID tags traffic of the virtual function transparently to its user
+	VLANID int `json:"vlan_id"`
+}
+
+type WireGuard struct {
+	// InterfaceName defaults to wg0, wg1, ... in order of network names
+	InterfaceName string `json:"interface_name"`
+	PrivateKey    string `json:"private_key"`
+	// ListenPort is left zero to pick random port when all peers connect to this instance
+	ListenPort int             `json:"listen_port"`
+	Peers      []WireGuardPeer `json:"peers"`
+}
+
+type WireGuardPeer struct {
+	PublicKey    string `json:"public_key"`
+	PresharedKey string `json:"preshared_key"`
+	// Endpoint is left empty for peers that connect to this instance, e.g. "10.0.0.7:51820"
+	Endpoint string `json:"endpoint"`
+	// AllowedIPs are overlay addresses routed to the peer, e.g. ["10.255.0.7/32"]
+	AllowedIPs []string `json:"allowed_ips"`
+	// PersistentKeepalive is an interval in seconds keeping connection through NAT open
+	PersistentKeepalive int `json:"persistent_keepalive"`
+}
+
 type Networks map[string]Network
 
 func (n Network) IsDefaultFor(category string) bool {
@@ -182,7 +834,7 @@ func (n Network) IsDefaultFor(category string) bool {
 
 func (n Networks) NetworkForMac(mac string) (Network, bool) {
 	for i := range n {
-		if n[i].Mac == mac {
+		if n[i].Mac == mac && n[i].Bond == nil {
 			return n[i], true
 		}
 	}
@@ -190,6 +842,26 @@ func (n Networks) NetworkForMac(mac string) (Network, bool) {
 	return Network{}, false
 }
 
+// NetworksForMac returns all networks configured for the MAC address
+// (e.g. IPv4 and IPv6 networks of a dual-stack interface) ordered by network name
+func (n Networks) NetworksForMac(mac string) []Network {
+	names := []string{}
+	for name := range n {
+		if n[name].Mac == mac && n[name].Bond == nil {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	networks := []Network{}
+	for _, name := range names {
+		networks = append(networks, n[name])
+	}
+
+	return networks
+}
+
 func (n Networks) DefaultNetworkFor(category string) (Network, bool) {
 	if len(n) == 1 {
 		for _, net := range n {
@@ -247,6 +919,11 @@ func (n Networks) IsPreconfigured() bool {
 			continue
 		}
 
+		if network.IsWireGuard() {
+			// Skip WireGuard networks since agent always maintains their interfaces
+			continue
+		}
+
 		if !network.Preconfigured {
 			return false
 		}
@@ -263,7 +940,7 @@ func (n Network) String() string {
 }
 
 func (n Network) IsDHCP() bool {
-	if n.IsVIP() {
+	if n.IsVIP() || n.IsWireGuard() {
 		return false
 	}
 
@@ -290,6 +967,10 @@ func (n Network) IsVIP() bool {
 	return n.Type == NetworkTypeVIP
 }
 
+func (n Network) IsWireGuard() bool {
+	return n.Type == NetworkTypeWireGuard
+}
+
 //{
 //	"agent_id": "bm-xxxxxxxx",
 //	"blobstore": {
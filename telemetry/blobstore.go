@@ -0,0 +1,85 @@
+package telemetry
+
+import (
+	"os"
+
+	"github.com/pivotal-golang/clock"
+
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type measuredBlobstore struct {
+	blobstore   boshblob.Blobstore
+	telemetry   Telemetry
+	fs          boshsys.FileSystem
+	timeService clock.Clock
+}
+
+// NewMeasuredBlobstore wraps a blobstore so that sizes and durations of downloads and uploads are recorded
+func NewMeasuredBlobstore(
+	blobstore boshblob.Blobstore,
+	telemetry Telemetry,
+	fs boshsys.FileSystem,
+	timeService clock.Clock,
+) boshblob.Blobstore {
+	return measuredBlobstore{
+		blobstore:   blobstore,
+		telemetry:   telemetry,
+		fs:          fs,
+		timeService: timeService,
+	}
+}
+
+func (b measuredBlobstore) Get(blobID, fingerprint string) (string, error) {
+	startTime := b.timeService.Now()
+
+	fileName, err := b.blobstore.Get(blobID, fingerprint)
+
+	b.telemetry.RecordBlobTransfer(BlobTransferDownload, b.fileSize(fileName, err), b.timeService.Now().Sub(startTime), err)
+
+	return fileName, err
+}
+
+func (b measuredBlobstore) Create(fileName string) (string, string, error) {
+	startTime := b.timeService.Now()
+
+	blobID, fingerprint, err := b.blobstore.Create(fileName)
+
+	b.telemetry.RecordBlobTransfer(BlobTransferUpload, b.fileSize(fileName, err), b.timeService.Now().Sub(startTime), err)
+
+	return blobID, fingerprint, err
+}
+
+func (b measuredBlobstore) CleanUp(fileName string) error {
+	return b.blobstore.CleanUp(fileName)
+}
+
+func (b measuredBlobstore) Delete(blobID string) error {
+	return b.blobstore.Delete(blobID)
+}
+
+func (b measuredBlobstore) Validate() error {
+	return b.blobstore.Validate()
+}
+
+// fileSize is not known for failed transfers
+func (b measuredBlobstore) fileSize(fileName string, err error) int64 {
+	if err != nil {
+		return 0
+	}
+
+	file, err := b.fs.OpenFile(fileName, os.O_RDONLY, 0)
+	if err != nil {
+		return 0
+	}
+
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0
+	}
+
+	return info.Size()
+}
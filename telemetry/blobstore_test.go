@@ -0,0 +1,73 @@
+package telemetry_test
+
+import (
+	"errors"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/telemetry"
+	faketelemetry "github.com/cloudfoundry/bosh-agent/telemetry/fakes"
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
+	fakeblob "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("measuredBlobstore", func() {
+	var (
+		innerBlobstore *fakeblob.FakeBlobstore
+		telemetry      *faketelemetry.FakeExporter
+		fs             *fakesys.FakeFileSystem
+		timeService    *fakeclock.FakeClock
+		blobstore      boshblob.Blobstore
+	)
+
+	BeforeEach(func() {
+		innerBlobstore = &fakeblob.FakeBlobstore{}
+		telemetry = &faketelemetry.FakeExporter{}
+		fs = fakesys.NewFakeFileSystem()
+		timeService = fakeclock.NewFakeClock(time.Now())
+		blobstore = NewMeasuredBlobstore(innerBlobstore, telemetry, fs, timeService)
+	})
+
+	It("records size of downloaded blob", func() {
+		fs.WriteFileString("/fake-blob", "fake-contents")
+		innerBlobstore.GetFileName = "/fake-blob"
+
+		fileName, err := blobstore.Get("fake-blob-id", "fake-fingerprint")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fileName).To(Equal("/fake-blob"))
+
+		Expect(innerBlobstore.GetBlobIDs).To(Equal([]string{"fake-blob-id"}))
+		Expect(telemetry.BlobTransfers).To(Equal([]faketelemetry.FakeBlobTransfer{
+			{Direction: BlobTransferDownload, Bytes: 13},
+		}))
+	})
+
+	It("records failed download", func() {
+		innerBlobstore.GetError = errors.New("fake-get-err")
+
+		_, err := blobstore.Get("fake-blob-id", "fake-fingerprint")
+		Expect(err).To(Equal(innerBlobstore.GetError))
+
+		Expect(telemetry.BlobTransfers).To(Equal([]faketelemetry.FakeBlobTransfer{
+			{Direction: BlobTransferDownload, Err: innerBlobstore.GetError},
+		}))
+	})
+
+	It("records size of uploaded file", func() {
+		fs.WriteFileString("/fake-file", "fake")
+		innerBlobstore.CreateBlobID = "fake-blob-id"
+
+		blobID, _, err := blobstore.Create("/fake-file")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(blobID).To(Equal("fake-blob-id"))
+
+		Expect(telemetry.BlobTransfers).To(Equal([]faketelemetry.FakeBlobTransfer{
+			{Direction: BlobTransferUpload, Bytes: 4},
+		}))
+	})
+})
@@ -0,0 +1,414 @@
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock"
+
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	exporterLogTag = "telemetryExporter"
+
+	scopeName = "github.com/cloudfoundry/bosh-agent"
+
+	defaultExportInterval = 60 * time.Second
+	exportTimeout         = 30 * time.Second
+
+	// Spans are dropped once collector has been unreachable for a while
+	maxBufferedSpans = 1000
+)
+
+const (
+	BlobTransferDownload = "download"
+	BlobTransferUpload   = "upload"
+)
+
+// Telemetry records agent activity that is exported to OTLP collector
+type Telemetry interface {
+	// StartAction traces execution of named action; span has to be ended once action finishes
+	StartAction(name string) Span
+
+	// RecordBlobTransfer counts bytes and time spent transferring blobs in given direction
+	RecordBlobTransfer(direction string, bytes int64, duration time.Duration, err error)
+}
+
+type Span interface {
+	End(err error)
+}
+
+type Exporter interface {
+	Telemetry
+
+	// Start exports periodically until exporter is stopped; returns right away when no endpoint is configured
+	Start() error
+	Stop() error
+
+	// Export sends vitals, counters and spans recorded since previous export
+	Export() error
+}
+
+type counter struct {
+	name       string
+	help       string
+	attributes map[string]string
+	value      float64
+}
+
+type concreteExporter struct {
+	options          boshsettings.Telemetry
+	agentID          string
+	metricsCollector boshmetrics.Collector
+	timeService      clock.Clock
+	logger           boshlog.Logger
+
+	startTime time.Time
+	client    *http.Client
+
+	spans    []otlpSpan
+	counters map[string]*counter
+	lock     sync.Mutex
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func NewExporter(
+	options boshsettings.Telemetry,
+	agentID string,
+	metricsCollector boshmetrics.Collector,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) Exporter {
+	return &concreteExporter{
+		options:          options,
+		agentID:          agentID,
+		metricsCollector: metricsCollector,
+		timeService:      timeService,
+		logger:           logger,
+
+		startTime: timeService.Now(),
+		counters:  map[string]*counter{},
+		stopCh:    make(chan struct{}),
+	}
+}
+
+func (e *concreteExporter) Start() error {
+	if !e.enabled() {
+		return nil
+	}
+
+	interval := defaultExportInterval
+	if e.options.ExportIntervalInSeconds > 0 {
+		interval = time.Duration(e.options.ExportIntervalInSeconds) * time.Second
+	}
+
+	e.logger.Info(exporterLogTag, "Exporting telemetry to %s every %s", e.options.Endpoint, interval)
+
+	for {
+		timer := e.timeService.NewTimer(interval)
+
+		select {
+		case <-e.stopCh:
+			timer.Stop()
+			return e.Export()
+
+		case <-timer.C():
+			err := e.Export()
+			if err != nil {
+				e.logger.Warn(exporterLogTag, "Exporting telemetry: %s", err.Error())
+			}
+		}
+	}
+}
+
+func (e *concreteExporter) Stop() error {
+	e.stopOnce.Do(func() { close(e.stopCh) })
+	return nil
+}
+
+func (e *concreteExporter) StartAction(name string) Span {
+	if !e.enabled() {
+		return noopSpan{}
+	}
+
+	return &actionSpan{
+		exporter:  e,
+		name:      name,
+		startTime: e.timeService.Now(),
+	}
+}
+
+func (e *concreteExporter) RecordBlobTransfer(direction string, bytes int64, duration time.Duration, err error) {
+	if !e.enabled() {
+		return
+	}
+
+	attributes := map[string]string{"direction": direction, "result": resultOf(err)}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.addToCounter("bosh_agent_blobstore_transfers_total", "Blobstore transfers.", attributes, 1)
+	e.addToCounter("bosh_agent_blobstore_transfer_duration_seconds_total", "Time spent transferring blobs.", attributes, duration.Seconds())
+
+	if err == nil {
+		e.addToCounter("bosh_agent_blobstore_transfer_bytes_total", "Bytes transferred to and from blobstore.", attributes, float64(bytes))
+	}
+}
+
+func (e *concreteExporter) Export() error {
+	if !e.enabled() {
+		return nil
+	}
+
+	now := e.timeService.Now()
+	resource := e.resource()
+
+	var metrics []otlpMetric
+
+	// Vitals are best effort so that counters and spans are still exported
+	vitalsMetrics, err := e.metricsCollector.Collect()
+	if err != nil {
+		e.logger.Warn(exporterLogTag, "Collecting vitals metrics: %s", err.Error())
+	}
+
+	for _, metric := range vitalsMetrics {
+		metrics = append(metrics, otlpMetricOf(metric, e.startTime, now))
+	}
+
+	e.lock.Lock()
+	for _, metric := range e.counterMetrics() {
+		metrics = append(metrics, otlpMetricOf(metric, e.startTime, now))
+	}
+
+	spans := e.spans
+	e.spans = nil
+	e.lock.Unlock()
+
+	err = e.post("/v1/metrics", exportMetricsRequest{
+		ResourceMetrics: []resourceMetrics{{
+			Resource:     resource,
+			ScopeMetrics: []scopeMetrics{{Scope: scope{Name: scopeName}, Metrics: metrics}},
+		}},
+	})
+	if err != nil {
+		return bosherr.WrapError(err, "Exporting metrics")
+	}
+
+	if len(spans) == 0 {
+		return nil
+	}
+
+	err = e.post("/v1/traces", exportTraceRequest{
+		ResourceSpans: []resourceSpans{{
+			Resource:   resource,
+			ScopeSpans: []scopeSpans{{Scope: scope{Name: scopeName}, Spans: spans}},
+		}},
+	})
+	if err != nil {
+		return bosherr.WrapError(err, "Exporting traces")
+	}
+
+	return nil
+}
+
+func (e *concreteExporter) enabled() bool {
+	return len(e.options.Endpoint) > 0
+}
+
+func (e *concreteExporter) resource() resource {
+	return resource{
+		Attributes: attributesOf(map[string]string{
+			"service.name":        "bosh-agent",
+			"service.instance.id": e.agentID,
+		}),
+	}
+}
+
+func (e *concreteExporter) endAction(span *actionSpan, err error) {
+	endTime := e.timeService.Now()
+	attributes := map[string]string{"action": span.name, "result": resultOf(err)}
+
+	spanStatus := status{Code: statusCodeOk}
+	if err != nil {
+		spanStatus = status{Code: statusCodeError, Message: err.Error()}
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.addToCounter("bosh_agent_actions_total", "Actions run.", attributes, 1)
+	e.addToCounter("bosh_agent_action_duration_seconds_total", "Time spent running actions.", attributes, endTime.Sub(span.startTime).Seconds())
+
+	if len(e.spans) >= maxBufferedSpans {
+		return
+	}
+
+	e.spans = append(e.spans, otlpSpan{
+		TraceID:           randomID(16),
+		SpanID:            randomID(8),
+		Name:              span.name,
+		Kind:              spanKindServer,
+		StartTimeUnixNano: unixNano(span.startTime),
+		EndTimeUnixNano:   unixNano(endTime),
+		Attributes:        attributesOf(map[string]string{"bosh.agent.action": span.name}),
+		Status:            spanStatus,
+	})
+}
+
+// addToCounter requires lock to be held
+func (e *concreteExporter) addToCounter(name, help string, attributes map[string]string, value float64) {
+	key := name
+	for _, attribute := range attributesOf(attributes) {
+		key += "," + attribute.Key + "=" + attribute.Value.StringValue
+	}
+
+	c, found := e.counters[key]
+	if !found {
+		c = &counter{name: name, help: help, attributes: attributes}
+		e.counters[key] = c
+	}
+
+	c.value += value
+}
+
+// counterMetrics requires lock to be held
+func (e *concreteExporter) counterMetrics() []boshmetrics.Metric {
+	keys := make([]string, 0, len(e.counters))
+	for key := range e.counters {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var metrics []boshmetrics.Metric
+
+	for _, key := range keys {
+		c := e.counters[key]
+		sample := boshmetrics.Sample{Labels: c.attributes, Value: c.value}
+
+		if len(metrics) > 0 && metrics[len(metrics)-1].Name == c.name {
+			metrics[len(metrics)-1].Samples = append(metrics[len(metrics)-1].Samples, sample)
+			continue
+		}
+
+		metrics = append(metrics, boshmetrics.Metric{
+			Name:    c.name,
+			Help:    c.help,
+			Type:    boshmetrics.TypeCounter,
+			Samples: []boshmetrics.Sample{sample},
+		})
+	}
+
+	return metrics
+}
+
+func (e *concreteExporter) post(path string, request interface{}) error {
+	client, err := e.httpClient()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling export request")
+	}
+
+	httpRequest, err := http.NewRequest("POST", strings.TrimSuffix(e.options.Endpoint, "/")+path, bytes.NewReader(body))
+	if err != nil {
+		return bosherr.WrapError(err, "Building export request")
+	}
+
+	httpRequest.Header.Set("Content-Type", "application/json")
+
+	for name, value := range e.options.Headers {
+		httpRequest.Header.Set(name, value)
+	}
+
+	response, err := client.Do(httpRequest)
+	if err != nil {
+		return bosherr.WrapError(err, "Sending export request")
+	}
+
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return bosherr.Errorf("Collector responded with status %d", response.StatusCode)
+	}
+
+	return nil
+}
+
+func (e *concreteExporter) httpClient() (*http.Client, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if len(e.options.CACert) > 0 {
+		certPool := x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM([]byte(e.options.CACert)) {
+			return nil, bosherr.Error("Parsing collector CA certificate")
+		}
+
+		tlsConfig.RootCAs = certPool
+	}
+
+	e.client = &http.Client{
+		Timeout: exportTimeout,
+		Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: tlsConfig,
+		},
+	}
+
+	return e.client, nil
+}
+
+type actionSpan struct {
+	exporter  *concreteExporter
+	name      string
+	startTime time.Time
+	endOnce   sync.Once
+}
+
+func (s *actionSpan) End(err error) {
+	s.endOnce.Do(func() { s.exporter.endAction(s, err) })
+}
+
+type noopSpan struct{}
+
+func (noopSpan) End(error) {}
+
+func resultOf(err error) string {
+	if err != nil {
+		return "failure"
+	}
+
+	return "success"
+}
+
+func randomID(size int) string {
+	bytes := make([]byte, size)
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
@@ -0,0 +1,265 @@
+package telemetry_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	"github.com/pivotal-golang/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	. "github.com/cloudfoundry/bosh-agent/telemetry"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type fakeMetricsCollector struct {
+	metrics []boshmetrics.Metric
+	err     error
+}
+
+func (c fakeMetricsCollector) Collect() ([]boshmetrics.Metric, error) {
+	return c.metrics, c.err
+}
+
+var _ = Describe("Exporter", func() {
+	var (
+		server           *httptest.Server
+		responseStatus   int
+		metricsCollector fakeMetricsCollector
+		timeService      *fakeclock.FakeClock
+		options          boshsettings.Telemetry
+		logger           boshlog.Logger
+
+		receivedBodies     map[string][]map[string]interface{}
+		receivedBodiesLock sync.Mutex
+	)
+
+	receive := func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+
+		Expect(r.Method).To(Equal("POST"))
+		Expect(r.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(r.Header.Get("Authorization")).To(Equal("Bearer fake-token"))
+
+		body, err := ioutil.ReadAll(r.Body)
+		Expect(err).ToNot(HaveOccurred())
+
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(body, &decoded)).To(Succeed())
+
+		receivedBodiesLock.Lock()
+		receivedBodies[r.URL.Path] = append(receivedBodies[r.URL.Path], decoded)
+		receivedBodiesLock.Unlock()
+
+		w.WriteHeader(responseStatus)
+	}
+
+	receivedCount := func(path string) int {
+		receivedBodiesLock.Lock()
+		defer receivedBodiesLock.Unlock()
+
+		return len(receivedBodies[path])
+	}
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(receive))
+		responseStatus = http.StatusOK
+
+		receivedBodies = map[string][]map[string]interface{}{}
+
+		metricsCollector = fakeMetricsCollector{
+			metrics: []boshmetrics.Metric{
+				{Name: "bosh_node_load1", Help: "1m load average.", Type: boshmetrics.TypeGauge, Samples: []boshmetrics.Sample{{Value: 0.5}}},
+			},
+		}
+		timeService = fakeclock.NewFakeClock(time.Unix(1000, 0))
+		options = boshsettings.Telemetry{
+			Endpoint: server.URL,
+			Headers:  map[string]string{"Authorization": "Bearer fake-token"},
+		}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	newExporter := func() Exporter {
+		return NewExporter(options, "fake-agent-id", metricsCollector, timeService, logger)
+	}
+
+	Describe("Export", func() {
+		It("exports vitals and action counters as metrics of agent resource", func() {
+			exporter := newExporter()
+
+			span := exporter.StartAction("apply")
+			timeService.Increment(2 * time.Second)
+			span.End(nil)
+
+			Expect(exporter.Export()).To(Succeed())
+			Expect(receivedCount("/v1/metrics")).To(Equal(1))
+
+			resourceMetrics := receivedBodies["/v1/metrics"][0]["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+			Expect(resourceMetrics["resource"]).To(Equal(map[string]interface{}{
+				"attributes": []interface{}{
+					map[string]interface{}{"key": "service.instance.id", "value": map[string]interface{}{"stringValue": "fake-agent-id"}},
+					map[string]interface{}{"key": "service.name", "value": map[string]interface{}{"stringValue": "bosh-agent"}},
+				},
+			}))
+
+			metrics := resourceMetrics["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{})
+			Expect(metrics).To(HaveLen(3))
+
+			Expect(metrics[0]).To(Equal(map[string]interface{}{
+				"name":        "bosh_node_load1",
+				"description": "1m load average.",
+				"gauge": map[string]interface{}{
+					"dataPoints": []interface{}{
+						map[string]interface{}{"timeUnixNano": "1002000000000", "asDouble": 0.5},
+					},
+				},
+			}))
+
+			Expect(metrics[1].(map[string]interface{})["name"]).To(Equal("bosh_agent_action_duration_seconds_total"))
+			Expect(metrics[2]).To(Equal(map[string]interface{}{
+				"name":        "bosh_agent_actions_total",
+				"description": "Actions run.",
+				"sum": map[string]interface{}{
+					"aggregationTemporality": float64(2),
+					"isMonotonic":            true,
+					"dataPoints": []interface{}{
+						map[string]interface{}{
+							"attributes": []interface{}{
+								map[string]interface{}{"key": "action", "value": map[string]interface{}{"stringValue": "apply"}},
+								map[string]interface{}{"key": "result", "value": map[string]interface{}{"stringValue": "success"}},
+							},
+							"startTimeUnixNano": "1000000000000",
+							"timeUnixNano":      "1002000000000",
+							"asDouble":          float64(1),
+						},
+					},
+				},
+			}))
+		})
+
+		It("exports spans of actions ended since previous export", func() {
+			exporter := newExporter()
+
+			span := exporter.StartAction("compile_package")
+			timeService.Increment(3 * time.Second)
+			span.End(errors.New("fake-compile-err"))
+
+			Expect(exporter.Export()).To(Succeed())
+			Expect(receivedCount("/v1/traces")).To(Equal(1))
+
+			resourceSpans := receivedBodies["/v1/traces"][0]["resourceSpans"].([]interface{})[0].(map[string]interface{})
+			spans := resourceSpans["scopeSpans"].([]interface{})[0].(map[string]interface{})["spans"].([]interface{})
+			Expect(spans).To(HaveLen(1))
+
+			exportedSpan := spans[0].(map[string]interface{})
+			Expect(exportedSpan["name"]).To(Equal("compile_package"))
+			Expect(exportedSpan["traceId"]).To(HaveLen(32))
+			Expect(exportedSpan["spanId"]).To(HaveLen(16))
+			Expect(exportedSpan["startTimeUnixNano"]).To(Equal("1000000000000"))
+			Expect(exportedSpan["endTimeUnixNano"]).To(Equal("1003000000000"))
+			Expect(exportedSpan["status"]).To(Equal(map[string]interface{}{
+				"code":    float64(2),
+				"message": "fake-compile-err",
+			}))
+
+			Expect(exporter.Export()).To(Succeed())
+			Expect(receivedCount("/v1/traces")).To(Equal(1))
+		})
+
+		It("exports blobstore transfer counters", func() {
+			exporter := newExporter()
+			exporter.RecordBlobTransfer(BlobTransferDownload, 1024, time.Second, nil)
+			exporter.RecordBlobTransfer(BlobTransferDownload, 2048, time.Second, nil)
+
+			Expect(exporter.Export()).To(Succeed())
+
+			resourceMetrics := receivedBodies["/v1/metrics"][0]["resourceMetrics"].([]interface{})[0].(map[string]interface{})
+			metrics := resourceMetrics["scopeMetrics"].([]interface{})[0].(map[string]interface{})["metrics"].([]interface{})
+
+			var names []string
+			for _, metric := range metrics {
+				names = append(names, metric.(map[string]interface{})["name"].(string))
+			}
+
+			Expect(names).To(Equal([]string{
+				"bosh_node_load1",
+				"bosh_agent_blobstore_transfer_bytes_total",
+				"bosh_agent_blobstore_transfer_duration_seconds_total",
+				"bosh_agent_blobstore_transfers_total",
+			}))
+
+			bytesDataPoint := metrics[1].(map[string]interface{})["sum"].(map[string]interface{})["dataPoints"].([]interface{})[0]
+			Expect(bytesDataPoint.(map[string]interface{})["asDouble"]).To(Equal(float64(3072)))
+		})
+
+		It("exports counters and spans when vitals cannot be collected", func() {
+			metricsCollector.err = errors.New("fake-collect-err")
+			exporter := newExporter()
+			exporter.StartAction("ping").End(nil)
+
+			Expect(exporter.Export()).To(Succeed())
+			Expect(receivedCount("/v1/metrics")).To(Equal(1))
+			Expect(receivedCount("/v1/traces")).To(Equal(1))
+		})
+
+		It("returns an error when collector rejects export", func() {
+			responseStatus = http.StatusBadRequest
+
+			err := newExporter().Export()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("status 400"))
+		})
+
+		It("returns an error when CA certificate cannot be parsed", func() {
+			options.CACert = "fake-ca-cert"
+
+			err := newExporter().Export()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Parsing collector CA certificate"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("exports at configured interval until stopped", func() {
+			options.ExportIntervalInSeconds = 30
+			exporter := newExporter()
+
+			errCh := make(chan error)
+			go func() { errCh <- exporter.Start() }()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			timeService.Increment(30 * time.Second)
+			Eventually(func() int { return receivedCount("/v1/metrics") }).Should(Equal(1))
+
+			Expect(exporter.Stop()).To(Succeed())
+			Eventually(errCh).Should(Receive(BeNil()))
+
+			// Recorded telemetry is flushed once exporter stops
+			Expect(receivedCount("/v1/metrics")).To(Equal(2))
+		})
+
+		It("returns right away without exporting when endpoint is not configured", func() {
+			options.Endpoint = ""
+			exporter := newExporter()
+
+			exporter.StartAction("ping").End(nil)
+
+			Expect(exporter.Start()).To(Succeed())
+			Expect(exporter.Export()).To(Succeed())
+			Expect(receivedBodies).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,86 @@
+package fakes
+
+import (
+	"sync"
+	"time"
+
+	boshtelemetry "github.com/cloudfoundry/bosh-agent/telemetry"
+)
+
+type FakeExporter struct {
+	StartErr  error
+	StopErr   error
+	ExportErr error
+
+	StartedActions []string
+	EndedActions   []FakeEndedAction
+
+	BlobTransfers []FakeBlobTransfer
+
+	lock sync.Mutex
+}
+
+type FakeEndedAction struct {
+	Name string
+	Err  error
+}
+
+type FakeBlobTransfer struct {
+	Direction string
+	Bytes     int64
+	Duration  time.Duration
+	Err       error
+}
+
+func (e *FakeExporter) Start() error {
+	return e.StartErr
+}
+
+func (e *FakeExporter) Stop() error {
+	return e.StopErr
+}
+
+func (e *FakeExporter) Export() error {
+	return e.ExportErr
+}
+
+func (e *FakeExporter) StartAction(name string) boshtelemetry.Span {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.StartedActions = append(e.StartedActions, name)
+
+	return fakeSpan{exporter: e, name: name}
+}
+
+func (e *FakeExporter) RecordBlobTransfer(direction string, bytes int64, duration time.Duration, err error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	e.BlobTransfers = append(e.BlobTransfers, FakeBlobTransfer{
+		Direction: direction,
+		Bytes:     bytes,
+		Duration:  duration,
+		Err:       err,
+	})
+}
+
+// EndedActionsCopy is safe to call while actions are running in background
+func (e *FakeExporter) EndedActionsCopy() []FakeEndedAction {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	return append([]FakeEndedAction{}, e.EndedActions...)
+}
+
+type fakeSpan struct {
+	exporter *FakeExporter
+	name     string
+}
+
+func (s fakeSpan) End(err error) {
+	s.exporter.lock.Lock()
+	defer s.exporter.lock.Unlock()
+
+	s.exporter.EndedActions = append(s.exporter.EndedActions, FakeEndedAction{Name: s.name, Err: err})
+}
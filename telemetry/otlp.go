@@ -0,0 +1,162 @@
+package telemetry
+
+import (
+	"sort"
+	"time"
+
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
+)
+
+// Types below follow JSON encoding of OTLP/HTTP export requests;
+// 64 bit integers are encoded as strings and ids as hex strings
+
+const (
+	aggregationTemporalityCumulative = 2
+
+	spanKindServer = 2
+
+	statusCodeOk    = 1
+	statusCodeError = 2
+)
+
+type exportMetricsRequest struct {
+	ResourceMetrics []resourceMetrics `json:"resourceMetrics"`
+}
+
+type resourceMetrics struct {
+	Resource     resource       `json:"resource"`
+	ScopeMetrics []scopeMetrics `json:"scopeMetrics"`
+}
+
+type scopeMetrics struct {
+	Scope   scope        `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+
+	Gauge *gauge `json:"gauge,omitempty"`
+	Sum   *sum   `json:"sum,omitempty"`
+}
+
+type gauge struct {
+	DataPoints []numberDataPoint `json:"dataPoints"`
+}
+
+type sum struct {
+	DataPoints             []numberDataPoint `json:"dataPoints"`
+	AggregationTemporality int               `json:"aggregationTemporality"`
+	IsMonotonic            bool              `json:"isMonotonic"`
+}
+
+type numberDataPoint struct {
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string,omitempty"`
+	TimeUnixNano      uint64     `json:"timeUnixNano,string"`
+	AsDouble          float64    `json:"asDouble"`
+}
+
+type exportTraceRequest struct {
+	ResourceSpans []resourceSpans `json:"resourceSpans"`
+}
+
+type resourceSpans struct {
+	Resource   resource     `json:"resource"`
+	ScopeSpans []scopeSpans `json:"scopeSpans"`
+}
+
+type scopeSpans struct {
+	Scope scope      `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpSpan struct {
+	TraceID           string     `json:"traceId"`
+	SpanID            string     `json:"spanId"`
+	Name              string     `json:"name"`
+	Kind              int        `json:"kind"`
+	StartTimeUnixNano uint64     `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   uint64     `json:"endTimeUnixNano,string"`
+	Attributes        []keyValue `json:"attributes,omitempty"`
+	Status            status     `json:"status"`
+}
+
+type status struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue string `json:"stringValue"`
+}
+
+// attributesOf sorts attributes by key so that exports are stable
+func attributesOf(attributes map[string]string) []keyValue {
+	if len(attributes) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(attributes))
+	for key := range attributes {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	keyValues := make([]keyValue, len(keys))
+	for i, key := range keys {
+		keyValues[i] = keyValue{Key: key, Value: anyValue{StringValue: attributes[key]}}
+	}
+
+	return keyValues
+}
+
+func unixNano(t time.Time) uint64 {
+	return uint64(t.UnixNano())
+}
+
+// otlpMetricOf converts Prometheus style gauges and counters; counters are cumulative since agent started
+func otlpMetricOf(metric boshmetrics.Metric, startTime, now time.Time) otlpMetric {
+	dataPoints := make([]numberDataPoint, len(metric.Samples))
+
+	for i, sample := range metric.Samples {
+		dataPoints[i] = numberDataPoint{
+			Attributes:   attributesOf(sample.Labels),
+			TimeUnixNano: unixNano(now),
+			AsDouble:     sample.Value,
+		}
+
+		if metric.Type == boshmetrics.TypeCounter {
+			dataPoints[i].StartTimeUnixNano = unixNano(startTime)
+		}
+	}
+
+	converted := otlpMetric{Name: metric.Name, Description: metric.Help}
+
+	if metric.Type == boshmetrics.TypeCounter {
+		converted.Sum = &sum{
+			DataPoints:             dataPoints,
+			AggregationTemporality: aggregationTemporalityCumulative,
+			IsMonotonic:            true,
+		}
+	} else {
+		converted.Gauge = &gauge{DataPoints: dataPoints}
+	}
+
+	return converted
+}